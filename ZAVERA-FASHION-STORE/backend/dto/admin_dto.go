@@ -16,12 +16,19 @@ type CreateProductRequest struct {
 	Length      int      `json:"length"` // in cm, default 30
 	Width       int      `json:"width"`  // in cm, default 20
 	Height      int      `json:"height"` // in cm, default 5
-	Category    string   `json:"category" binding:"required"`
+	Category    string   `json:"category"` // Legacy root slug; required unless category_id is set
 	Subcategory string   `json:"subcategory"`
+	CategoryID  *int     `json:"category_id"` // Category tree node; overrides category/subcategory
 	Brand       string   `json:"brand"`    // Product brand (e.g., Nike, Adidas)
 	Material    string   `json:"material"` // Product material (e.g., Cotton, Polyester)
 	IsActive    *bool    `json:"is_active"`
 	Images      []string `json:"images"` // URLs
+	Tags        []string `json:"tags"`   // Merchandising tags used by collection rules
+	// SEO overrides (optional)
+	MetaTitle       string `json:"meta_title" binding:"max=70"`
+	MetaDescription string `json:"meta_description" binding:"max=160"`
+	CanonicalURL    string `json:"canonical_url"`
+	OGImageURL      string `json:"og_image_url"`
 }
 
 type UpdateProductRequest struct {
@@ -36,9 +43,17 @@ type UpdateProductRequest struct {
 	Height      *int     `json:"height"`
 	Category    *string  `json:"category"`
 	Subcategory *string  `json:"subcategory"`
+	CategoryID  *int     `json:"category_id"`
 	Brand       *string  `json:"brand"`
 	Material    *string  `json:"material"`
 	IsActive    *bool    `json:"is_active"`
+	IsLimitedDrop *bool  `json:"is_limited_drop"` // Cart holds stock for a few minutes
+	Tags        []string `json:"tags"` // Replaces all tags when present; [] clears
+	// SEO overrides - send "" to clear and fall back to product content
+	MetaTitle       *string `json:"meta_title" binding:"omitempty,max=70"`
+	MetaDescription *string `json:"meta_description" binding:"omitempty,max=160"`
+	CanonicalURL    *string `json:"canonical_url"`
+	OGImageURL      *string `json:"og_image_url"`
 }
 
 type UpdateStockRequest struct {
@@ -58,6 +73,7 @@ type AdminProductResponse struct {
 	Slug        string                `json:"slug"`
 	Description string                `json:"description"`
 	Price       float64               `json:"price"`
+	CompareAtPrice *float64           `json:"compare_at_price,omitempty"`
 	Stock       int                   `json:"stock"`
 	Weight      int                   `json:"weight"`
 	Length      int                   `json:"length"`
@@ -65,9 +81,17 @@ type AdminProductResponse struct {
 	Height      int                   `json:"height"`
 	Category    string                `json:"category"`
 	Subcategory string                `json:"subcategory"`
+	CategoryID  *int                  `json:"category_id,omitempty"`
 	Brand       string                `json:"brand"`
 	Material    string                `json:"material"`
 	IsActive    bool                  `json:"is_active"`
+	IsLimitedDrop bool                `json:"is_limited_drop"`
+	Tags        []string              `json:"tags"`
+	MetaTitle       string            `json:"meta_title"`
+	MetaDescription string            `json:"meta_description"`
+	CanonicalURL    string            `json:"canonical_url"`
+	OGImageURL      string            `json:"og_image_url"`
+	PreviousSlugs   []string          `json:"previous_slugs,omitempty"`
 	Images      []ProductImageResponse `json:"images"`
 	CreatedAt   string                `json:"created_at"`
 	UpdatedAt   string                `json:"updated_at"`
@@ -102,6 +126,8 @@ type AdminOrderResponse struct {
 	Subtotal      float64                `json:"subtotal"`
 	ShippingCost  float64                `json:"shipping_cost"`
 	Tax           float64                `json:"tax"`
+	TaxRate       float64                `json:"tax_rate"`
+	PricesIncludeTax bool                `json:"prices_include_tax"`
 	Discount      float64                `json:"discount"`
 	TotalAmount   float64                `json:"total_amount"`
 	Status        string                 `json:"status"`
@@ -113,6 +139,9 @@ type AdminOrderResponse struct {
 	UpdatedAt     string                 `json:"updated_at"`
 	PaidAt        *string                `json:"paid_at,omitempty"`
 	ShippedAt     *string                `json:"shipped_at,omitempty"`
+	// Pre-order/backorder: cannot be packed until the stock arrives
+	AwaitingStock    bool    `json:"awaiting_stock"`
+	ExpectedShipDate *string `json:"expected_ship_date,omitempty"`
 }
 
 type AdminPaymentInfo struct {
@@ -215,6 +244,14 @@ func FormatTimePtr(t *time.Time) *string {
 	return &s
 }
 
+func FormatDatePtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format("2006-01-02")
+	return &s
+}
+
 
 // ============================================
 // DASHBOARD METRICS DTOs
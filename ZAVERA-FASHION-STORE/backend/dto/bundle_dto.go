@@ -0,0 +1,29 @@
+package dto
+
+import "zavera/models"
+
+// SetBundleRequest replaces the components of a bundle product, in display order
+type SetBundleRequest struct {
+	Components []BundleComponentRequest `json:"components" binding:"required,min=1,dive"`
+}
+
+// BundleComponentRequest is a variant and how many of it go in one bundle
+type BundleComponentRequest struct {
+	VariantID int `json:"variant_id" binding:"required"`
+	Quantity  int `json:"quantity" binding:"min=0"` // Defaults to 1
+}
+
+// BundleResponse is a bundle product with its components
+type BundleResponse struct {
+	ProductID   int                       `json:"product_id"`
+	ProductName string                    `json:"product_name"`
+	Price       float64                   `json:"price"`
+	Available   int                       `json:"available"` // Complete bundles in stock
+	Components  []BundleComponentResponse `json:"components"`
+}
+
+// BundleComponentResponse is a component with its share of the bundle price
+type BundleComponentResponse struct {
+	models.BundleComponent
+	AllocatedPrice float64 `json:"allocated_price"`
+}
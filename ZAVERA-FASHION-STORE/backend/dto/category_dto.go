@@ -0,0 +1,37 @@
+package dto
+
+// CategoryResponse represents a category tree node in API responses
+type CategoryResponse struct {
+	ID        int                `json:"id"`
+	ParentID  *int               `json:"parent_id,omitempty"`
+	Name      string             `json:"name"`
+	Slug      string             `json:"slug"`
+	Path      string             `json:"path"`
+	Depth     int                `json:"depth"`
+	SortOrder int                `json:"sort_order"`
+	ImageURL  string             `json:"image_url,omitempty"`
+	IsActive  bool               `json:"is_active"`
+	Children  []CategoryResponse `json:"children,omitempty"`
+}
+
+// CreateCategoryRequest represents the request to create a category
+type CreateCategoryRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	Slug      string `json:"slug"` // Generated from name if empty
+	ParentID  *int   `json:"parent_id"`
+	SortOrder int    `json:"sort_order"`
+	ImageURL  string `json:"image_url"`
+	IsActive  *bool  `json:"is_active"`
+}
+
+// UpdateCategoryRequest represents a partial category update
+// Set move_to_root to detach a category from its parent
+type UpdateCategoryRequest struct {
+	Name       *string `json:"name" binding:"omitempty,max=100"`
+	Slug       *string `json:"slug"`
+	ParentID   *int    `json:"parent_id"`
+	MoveToRoot bool    `json:"move_to_root"`
+	SortOrder  *int    `json:"sort_order"`
+	ImageURL   *string `json:"image_url"`
+	IsActive   *bool   `json:"is_active"`
+}
@@ -0,0 +1,81 @@
+package dto
+
+import "time"
+
+// CollectionRuleDTO is one condition of a rule-based collection
+// e.g. {"field": "price", "operator": "lte", "value": 250000}
+type CollectionRuleDTO struct {
+	Field    string      `json:"field" binding:"required"`
+	Operator string      `json:"operator" binding:"required"`
+	Value    interface{} `json:"value"`
+}
+
+// CreateCollectionRequest represents the request to create a collection
+type CreateCollectionRequest struct {
+	Name           string              `json:"name" binding:"required,max=150"`
+	Slug           string              `json:"slug"` // Generated from name if empty
+	Description    string              `json:"description"`
+	ImageURL       string              `json:"image_url"`
+	CollectionType string              `json:"collection_type" binding:"required,oneof=MANUAL RULE"`
+	Rules          []CollectionRuleDTO `json:"rules"`      // RULE collections only
+	MatchType      string              `json:"match_type"` // ALL (default) or ANY
+	SortBy         string              `json:"sort_by"`    // RULE collections only, default newest
+	SortOrder      int                 `json:"sort_order"`
+	IsActive       *bool               `json:"is_active"`
+	StartsAt       *time.Time          `json:"starts_at"`
+	EndsAt         *time.Time          `json:"ends_at"`
+	ProductIDs     []int               `json:"product_ids"` // MANUAL collections only, in display order
+}
+
+// UpdateCollectionRequest represents a partial collection update
+// Set clear_starts_at / clear_ends_at to remove a visibility bound
+type UpdateCollectionRequest struct {
+	Name          *string             `json:"name" binding:"omitempty,max=150"`
+	Slug          *string             `json:"slug"`
+	Description   *string             `json:"description"`
+	ImageURL      *string             `json:"image_url"`
+	Rules         []CollectionRuleDTO `json:"rules"` // Replaces all rules when present
+	MatchType     *string             `json:"match_type"`
+	SortBy        *string             `json:"sort_by"`
+	SortOrder     *int                `json:"sort_order"`
+	IsActive      *bool               `json:"is_active"`
+	StartsAt      *time.Time          `json:"starts_at"`
+	EndsAt        *time.Time          `json:"ends_at"`
+	ClearStartsAt bool                `json:"clear_starts_at"`
+	ClearEndsAt   bool                `json:"clear_ends_at"`
+}
+
+// SetCollectionProductsRequest replaces the products of a manual collection
+type SetCollectionProductsRequest struct {
+	ProductIDs []int `json:"product_ids" binding:"required"`
+}
+
+// CollectionResponse represents a collection in API responses
+type CollectionResponse struct {
+	ID             int                 `json:"id"`
+	Name           string              `json:"name"`
+	Slug           string              `json:"slug"`
+	Description    string              `json:"description,omitempty"`
+	ImageURL       string              `json:"image_url,omitempty"`
+	CollectionType string              `json:"collection_type"`
+	Rules          []CollectionRuleDTO `json:"rules,omitempty"`
+	MatchType      string              `json:"match_type,omitempty"`
+	SortBy         string              `json:"sort_by,omitempty"`
+	SortOrder      int                 `json:"sort_order"`
+	IsActive       bool                `json:"is_active"`
+	IsVisible      bool                `json:"is_visible"` // Active and inside its visibility window
+	StartsAt       *string             `json:"starts_at,omitempty"`
+	EndsAt         *string             `json:"ends_at,omitempty"`
+	ProductCount   *int                `json:"product_count,omitempty"` // Manual collections only
+	CreatedAt      string              `json:"created_at"`
+	UpdatedAt      string              `json:"updated_at"`
+}
+
+// CollectionProductsResponse is a page of products in a collection
+type CollectionProductsResponse struct {
+	Collection CollectionResponse `json:"collection"`
+	Products   []ProductResponse  `json:"products"`
+	TotalCount int                `json:"total_count"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"time"
+	"zavera/models"
+)
+
 // ProductResponse represents the product API response
 type ProductResponse struct {
 	ID             int      `json:"id"`
@@ -7,6 +12,7 @@ type ProductResponse struct {
 	Slug           string   `json:"slug"`
 	Description    string   `json:"description"`
 	Price          float64  `json:"price"`
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"` // Regular price while on sale
 	Stock          int      `json:"stock"`
 	Weight         int      `json:"weight"` // Weight in grams
 	Length         int      `json:"length"` // Length in cm
@@ -16,9 +22,25 @@ type ProductResponse struct {
 	Images         []string `json:"images,omitempty"`
 	Category       string   `json:"category"`
 	Subcategory    string   `json:"subcategory,omitempty"`
+	CategoryID     *int     `json:"category_id,omitempty"`
 	Brand          string   `json:"brand,omitempty"`          // Product brand (e.g., Nike, Adidas)
 	Material       string   `json:"material,omitempty"`       // Product material (e.g., Cotton, Polyester)
 	AvailableSizes []string `json:"available_sizes,omitempty"` // Sizes from active variants
+	MinPrice       float64  `json:"min_price,omitempty"`       // Lowest active variant price
+	MaxPrice       float64  `json:"max_price,omitempty"`       // Highest active variant price
+	SEO            *ProductSEOResponse `json:"seo,omitempty"`     // Only on product detail
+	Rating         *ProductRatingResponse `json:"rating,omitempty"` // Approved reviews only; absent when unreviewed
+	IsBundle       bool     `json:"is_bundle,omitempty"` // Stock is complete sets; components at /products/:id/bundle (detail only)
+	IsGiftCard     bool     `json:"is_gift_card,omitempty"` // Each unit bought is emailed as a code redeemable into store credit (detail only)
+}
+
+// ProductSEOResponse holds resolved SEO metadata for a product page
+// Admin overrides win; otherwise values are derived from the product itself
+type ProductSEOResponse struct {
+	MetaTitle       string `json:"meta_title"`
+	MetaDescription string `json:"meta_description"`
+	CanonicalURL    string `json:"canonical_url"`
+	OGImage         string `json:"og_image,omitempty"`
 }
 
 // AddToCartRequest represents the request to add item to cart
@@ -40,6 +62,13 @@ type CartResponse struct {
 	Items     []CartItemResponse `json:"items"`
 	Subtotal  float64          `json:"subtotal"`
 	ItemCount int              `json:"item_count"`
+	// Automatic promotions: each one applied with its savings per item; Total is Subtotal less
+	// Discount. Free shipping the cart qualifies for is listed with at_checkout.
+	Promotions []models.AppliedPromotion `json:"promotions"`
+	Discount   float64                   `json:"discount"`
+	Total      float64                   `json:"total"`
+	// HoldExpiresAt is the earliest limited-drop hold expiry in the cart, for a cart-wide countdown
+	HoldExpiresAt *time.Time `json:"hold_expires_at,omitempty"`
 }
 
 // CartItemResponse represents a cart item in API response
@@ -53,6 +82,13 @@ type CartItemResponse struct {
 	Subtotal      float64                `json:"subtotal"`
 	Stock         int                    `json:"stock"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	// Promotion savings on this item
+	Discount           float64 `json:"discount"`
+	DiscountedSubtotal float64 `json:"discounted_subtotal"`
+	// Limited-drop hold: stock kept aside for this item until HoldExpiresAt
+	HeldQuantity    int        `json:"held_quantity,omitempty"`
+	HoldExpiresAt   *time.Time `json:"hold_expires_at,omitempty"`
+	HoldSecondsLeft int        `json:"hold_seconds_left,omitempty"`
 }
 
 // CheckoutRequest represents the checkout request
@@ -61,12 +97,15 @@ type CheckoutRequest struct {
 	CustomerEmail string `json:"customer_email" binding:"required,email"`
 	CustomerPhone string `json:"customer_phone" binding:"required"`
 	Notes         string `json:"notes,omitempty"`
+	VoucherCode   string `json:"voucher_code,omitempty"`
 }
 
 // CheckoutResponse represents the checkout response
 type CheckoutResponse struct {
 	OrderID      int     `json:"order_id"`
 	OrderCode    string  `json:"order_code"`
+	Discount     float64 `json:"discount"`
+	Tax          float64 `json:"tax"` // Contained in the total when prices include tax
 	TotalAmount  float64 `json:"total_amount"`
 	Status       string  `json:"status"`
 }
@@ -82,6 +121,8 @@ type OrderResponse struct {
 	Subtotal      float64             `json:"subtotal"`
 	ShippingCost  float64             `json:"shipping_cost"`
 	Tax           float64             `json:"tax"`
+	TaxRate       float64             `json:"tax_rate"`
+	PricesIncludeTax bool             `json:"prices_include_tax"` // Tax is contained in the subtotal
 	Discount      float64             `json:"discount"`
 	TotalAmount   float64             `json:"total_amount"`
 	Status        string              `json:"status"`
@@ -91,6 +132,9 @@ type OrderResponse struct {
 	PaidAt        string              `json:"paid_at,omitempty"`
 	ShippedAt     string              `json:"shipped_at,omitempty"`
 	DeliveredAt   string              `json:"delivered_at,omitempty"`
+	// Pre-order/backorder: ships once the stock arrives
+	AwaitingStock    bool    `json:"awaiting_stock"`
+	ExpectedShipDate *string `json:"expected_ship_date,omitempty"`
 	// Shipping details
 	Shipment *ShipmentResponse `json:"shipment,omitempty"`
 }
@@ -103,6 +147,25 @@ type OrderItemResponse struct {
 	Quantity     int     `json:"quantity"`
 	PricePerUnit float64 `json:"price_per_unit"`
 	Subtotal     float64 `json:"subtotal"`
+	// PPN breakdown: the line's discount, and the rate and tax on what is paid after it
+	DiscountAmount float64 `json:"discount_amount"`
+	TaxRate        float64 `json:"tax_rate"`
+	TaxAmount      float64 `json:"tax_amount"`
+	// Units still waiting for stock (pre-order/backorder)
+	BackorderedQuantity int     `json:"backordered_quantity,omitempty"`
+	ExpectedShipDate    *string `json:"expected_ship_date,omitempty"`
+	// What a bundle is made of
+	Components []OrderItemComponentResponse `json:"components,omitempty"`
+}
+
+// OrderItemComponentResponse is one component of a bundle order line
+type OrderItemComponentResponse struct {
+	ID             int     `json:"id"`
+	ProductID      int     `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	VariantName    string  `json:"variant_name,omitempty"`
+	Quantity       int     `json:"quantity"`        // Units across the whole line
+	AllocatedPrice float64 `json:"allocated_price"` // Share of one bundle's price
 }
 
 // ErrorResponse represents an error response
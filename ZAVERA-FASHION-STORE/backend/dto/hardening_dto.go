@@ -14,6 +14,7 @@ type RefundRequest struct {
 	ReasonDetail   string              `json:"reason_detail,omitempty"`
 	Amount         *float64            `json:"amount,omitempty"`         // For partial refunds
 	Items          []RefundItemRequest `json:"items,omitempty"`          // For item-specific refunds
+	Destination    string              `json:"destination,omitempty" binding:"omitempty,oneof=ORIGINAL_PAYMENT STORE_CREDIT"` // Default ORIGINAL_PAYMENT
 	IdempotencyKey string              `json:"idempotency_key,omitempty"`
 }
 
@@ -22,6 +23,8 @@ type RefundItemRequest struct {
 	OrderItemID int    `json:"order_item_id" binding:"required"`
 	Quantity    int    `json:"quantity" binding:"required,min=1"`
 	Reason      string `json:"reason,omitempty"`
+	// Refunds one component of a bundle line; Quantity is then in units of the component
+	ComponentID *int `json:"component_id,omitempty"`
 }
 
 // RefundResponse represents a refund response with complete details
@@ -38,6 +41,8 @@ type RefundResponse struct {
 	RefundAmount    float64                 `json:"refund_amount"`
 	ShippingRefund  float64                 `json:"shipping_refund"`
 	ItemsRefund     float64                 `json:"items_refund"`
+	Destination     string                  `json:"destination"`
+	StoreCreditAmount float64               `json:"store_credit_amount"` // Part of RefundAmount returned as store credit
 	Status          string                  `json:"status"`
 	GatewayRefundID *string                 `json:"gateway_refund_id,omitempty"`
 	GatewayStatus   *string                 `json:"gateway_status,omitempty"`
@@ -121,6 +126,7 @@ type CustomerRefundResponse struct {
 	RefundAmount   float64              `json:"refund_amount"`
 	ShippingRefund float64              `json:"shipping_refund"`
 	ItemsRefund    float64              `json:"items_refund"`
+	StoreCreditAmount float64           `json:"store_credit_amount"` // Credited to the customer's store credit
 	Status         string               `json:"status"`
 	StatusLabel    string               `json:"status_label"`
 	Timeline       string               `json:"timeline"`
@@ -154,6 +160,7 @@ type ForceRefundRequest struct {
 	Amount         *float64            `json:"amount,omitempty"`
 	Items          []RefundItemRequest `json:"items,omitempty"`
 	SkipGateway    bool                `json:"skip_gateway"`    // For manual reconciliation
+	Destination    string              `json:"destination,omitempty" binding:"omitempty,oneof=ORIGINAL_PAYMENT STORE_CREDIT"`
 	IdempotencyKey string              `json:"idempotency_key,omitempty"`
 }
 
@@ -0,0 +1,8 @@
+package dto
+
+// SaveTaxProfileRequest sets the NPWP printed on the customer's invoices; an empty NPWP clears it
+type SaveTaxProfileRequest struct {
+	NPWP    string `json:"npwp" binding:"max=25"`
+	Name    string `json:"npwp_name" binding:"max=255"`
+	Address string `json:"npwp_address" binding:"max=1000"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+// ProductImportReport is the result of a bulk product import.
+// With dry_run the diff is computed but nothing is written; otherwise the whole file
+// is applied in one transaction, or not at all when any row has an error.
+type ProductImportReport struct {
+	DryRun    bool                 `json:"dry_run"`
+	Applied   bool                 `json:"applied"`
+	TotalRows int                  `json:"total_rows"` // Data rows, excluding the header
+	Summary   ProductImportSummary `json:"summary"`
+	Products  []ProductImportDiff  `json:"products"`
+	Errors    []ImportRowError     `json:"errors"`
+}
+
+// ProductImportSummary counts planned (dry run) or applied changes
+type ProductImportSummary struct {
+	ProductsCreated   int `json:"products_created"`
+	ProductsUpdated   int `json:"products_updated"`
+	ProductsUnchanged int `json:"products_unchanged"`
+	VariantsCreated   int `json:"variants_created"`
+	VariantsUpdated   int `json:"variants_updated"`
+	VariantsUnchanged int `json:"variants_unchanged"`
+}
+
+// ProductImportDiff describes what the import does to one product
+// Action is create, update or unchanged
+type ProductImportDiff struct {
+	Row       int                 `json:"row"` // First sheet row of the product
+	Slug      string              `json:"slug"`
+	Name      string              `json:"name"`
+	Action    string              `json:"action"`
+	ProductID *int                `json:"product_id,omitempty"`
+	Changes   []ImportFieldChange `json:"changes,omitempty"`
+	Variants  []VariantImportDiff `json:"variants,omitempty"`
+}
+
+// VariantImportDiff describes what the import does to one variant (matched by SKU)
+type VariantImportDiff struct {
+	Row       int                 `json:"row"`
+	SKU       string              `json:"sku"`
+	Action    string              `json:"action"`
+	VariantID *int                `json:"variant_id,omitempty"`
+	Changes   []ImportFieldChange `json:"changes,omitempty"`
+}
+
+// ImportFieldChange is one column whose value differs from the catalog
+type ImportFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ImportRowError reports a problem with a sheet row (row 1 is the header)
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
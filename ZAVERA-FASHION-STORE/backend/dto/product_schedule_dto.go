@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// CreateProductScheduleRequest schedules a future change to a product
+// PUBLISH/UNPUBLISH need only run_at; PRICE_CHANGE needs new_price; SALE needs new_price and ends_at
+type CreateProductScheduleRequest struct {
+	Action   string     `json:"action" binding:"required,oneof=PUBLISH UNPUBLISH PRICE_CHANGE SALE"`
+	RunAt    time.Time  `json:"run_at" binding:"required"`
+	EndsAt   *time.Time `json:"ends_at"`
+	NewPrice *float64   `json:"new_price"`
+	Note     string     `json:"note" binding:"max=500"`
+}
+
+// ProductScheduleResponse represents a schedule entry in API responses
+type ProductScheduleResponse struct {
+	ID                     int      `json:"id"`
+	ProductID              int      `json:"product_id"`
+	ProductName            string   `json:"product_name"`
+	ProductSlug            string   `json:"product_slug"`
+	Action                 string   `json:"action"`
+	Status                 string   `json:"status"`
+	RunAt                  string   `json:"run_at"`
+	EndsAt                 *string  `json:"ends_at,omitempty"`
+	NewPrice               *float64 `json:"new_price,omitempty"`
+	PreviousPrice          *float64 `json:"previous_price,omitempty"`
+	PreviousCompareAtPrice *float64 `json:"previous_compare_at_price,omitempty"`
+	Note                   string   `json:"note,omitempty"`
+	ResultNote             string   `json:"result_note,omitempty"`
+	CreatedBy              string   `json:"created_by,omitempty"`
+	AppliedAt              *string  `json:"applied_at,omitempty"`
+	CompletedAt            *string  `json:"completed_at,omitempty"`
+	CreatedAt              string   `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+// ProductSearchResponse is returned by GET /api/products when search or filter params are given
+type ProductSearchResponse struct {
+	Products   []ProductResponse      `json:"products"`
+	TotalCount int                    `json:"total_count"`
+	Limit      int                    `json:"limit"`
+	Offset     int                    `json:"offset"`
+	Facets     *ProductFacetsResponse `json:"facets,omitempty"`
+}
+
+// ProductPageResponse is one keyset-paginated page of the catalog
+// Pass NextCursor back as ?cursor= to fetch the following page
+type ProductPageResponse struct {
+	Products   []ProductResponse `json:"products"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	HasMore    bool              `json:"has_more"`
+	Sort       string            `json:"sort"`
+	Limit      int               `json:"limit"`
+}
+
+// ProductFacetsResponse holds facet counts for the filter sidebar
+type ProductFacetsResponse struct {
+	Brands       []FacetCountResponse  `json:"brands"`
+	Sizes        []FacetCountResponse  `json:"sizes"`
+	Colors       []FacetCountResponse  `json:"colors"`
+	PriceBuckets []PriceBucketResponse `json:"price_buckets"`
+}
+
+// FacetCountResponse is the number of matching products for one facet value
+type FacetCountResponse struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucketResponse is a price range facet (max omitted = open-ended)
+type PriceBucketResponse struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// ProductSlugRedirectResponse is returned with 301 when a product was reached by a retired slug
+type ProductSlugRedirectResponse struct {
+	Redirect bool   `json:"redirect"`
+	Slug     string `json:"slug"`
+	Location string `json:"location"`
+}
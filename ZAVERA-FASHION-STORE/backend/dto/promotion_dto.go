@@ -0,0 +1,40 @@
+package dto
+
+import "time"
+
+// SavePromotionRequest creates or replaces an automatic promotion. Which settings are used
+// depends on the type:
+//   - QUANTITY_DISCOUNT: min_quantity, percent (e.g. buy 2, get 10% off)
+//   - SPEND_TIER: tiers, max_discount for percentage tiers
+//   - FREE_SHIPPING: min_spend, max_discount, provinces (empty = everywhere)
+//   - FLASH_SALE: variant_prices
+type SavePromotionRequest struct {
+	Name          string                    `json:"name" binding:"required,max=150"`
+	Description   string                    `json:"description"`
+	Type          string                    `json:"type" binding:"required"`
+	MinQuantity   int                       `json:"min_quantity" binding:"gte=0"`
+	Percent       float64                   `json:"percent" binding:"gte=0,lte=100"`
+	MinSpend      float64                   `json:"min_spend" binding:"gte=0"`
+	MaxDiscount   *float64                  `json:"max_discount" binding:"omitempty,gt=0"`
+	Tiers         []PromotionTierRequest    `json:"tiers" binding:"dive"`
+	VariantPrices []PromotionVariantRequest `json:"variant_prices" binding:"dive"`
+	CategoryID    *int                      `json:"category_id"`
+	Brand         *string                   `json:"brand" binding:"omitempty,max=100"`
+	Provinces     []string                  `json:"provinces"`
+	StartsAt      *time.Time                `json:"starts_at"`
+	EndsAt        *time.Time                `json:"ends_at"`
+	IsActive      *bool                     `json:"is_active"` // Defaults to true
+}
+
+// PromotionTierRequest is one spend tier: a percentage or an amount off
+type PromotionTierRequest struct {
+	MinSpend float64 `json:"min_spend" binding:"gt=0"`
+	Percent  float64 `json:"percent" binding:"gte=0,lte=100"`
+	Amount   float64 `json:"amount" binding:"gte=0"`
+}
+
+// PromotionVariantRequest is the flash-sale price of a variant
+type PromotionVariantRequest struct {
+	VariantID int     `json:"variant_id" binding:"required"`
+	SalePrice float64 `json:"sale_price" binding:"gte=0"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// SupplierRequest creates or replaces a supplier
+type SupplierRequest struct {
+	Code         string `json:"code" binding:"required,max=20"`
+	Name         string `json:"name" binding:"required,max=150"`
+	ContactName  string `json:"contact_name" binding:"max=100"`
+	Email        string `json:"email" binding:"omitempty,email"`
+	Phone        string `json:"phone" binding:"max=30"`
+	Address      string `json:"address"`
+	LeadTimeDays int    `json:"lead_time_days" binding:"min=0"`
+	Notes        string `json:"notes"`
+	IsActive     *bool  `json:"is_active"` // Defaults to true
+}
+
+// PurchaseOrderRequest creates or replaces a draft purchase order
+type PurchaseOrderRequest struct {
+	SupplierID  int                        `json:"supplier_id" binding:"required"`
+	WarehouseID int                        `json:"warehouse_id"` // Receiving warehouse; 0 = default
+	ExpectedAt  *time.Time                 `json:"expected_at"`  // Defaults to the supplier lead time when sent
+	Notes       string                     `json:"notes"`
+	Items       []PurchaseOrderItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+type PurchaseOrderItemRequest struct {
+	VariantID   int        `json:"variant_id" binding:"required"`
+	Quantity    int        `json:"quantity" binding:"required,min=1"`
+	CostPerItem float64    `json:"cost_per_item" binding:"min=0"`
+	ExpectedAt  *time.Time `json:"expected_at"` // Overrides the PO date for this line
+}
+
+// UpdatePurchaseOrderStatusRequest sends or cancels a purchase order
+type UpdatePurchaseOrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=SENT CANCELLED"`
+}
+
+// ReceivePurchaseOrderRequest books a (partial) delivery
+type ReceivePurchaseOrderRequest struct {
+	Lines []ReceivePurchaseOrderLine `json:"lines" binding:"required,min=1,dive"`
+	Notes string                     `json:"notes"`
+}
+
+type ReceivePurchaseOrderLine struct {
+	PurchaseOrderItemID int `json:"purchase_order_item_id" binding:"required"`
+	Quantity            int `json:"quantity" binding:"required,min=1"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+// ProductRecommendationsResponse holds the cross-sell sections of a product page
+type ProductRecommendationsResponse struct {
+	ProductID                int               `json:"product_id"`
+	FrequentlyBoughtTogether []ProductResponse `json:"frequently_bought_together"`
+	SimilarItems             []ProductResponse `json:"similar_items"`
+	CompleteTheLook          []ProductResponse `json:"complete_the_look"`
+}
+
+// SetLookLinksRequest replaces the curated "complete the look" products, in display order
+type SetLookLinksRequest struct {
+	ProductIDs []int `json:"product_ids" binding:"required"`
+}
+
+// LookLinksResponse lists the curated "complete the look" products of a product
+type LookLinksResponse struct {
+	ProductID int               `json:"product_id"`
+	Products  []ProductResponse `json:"products"`
+}
+
+// RecommendationRefreshResponse reports a manual co-purchase refresh
+type RecommendationRefreshResponse struct {
+	PairsStored int `json:"pairs_stored"`
+}
@@ -0,0 +1,82 @@
+package dto
+
+// ProductRatingResponse is the aggregate rating shown with a product
+type ProductRatingResponse struct {
+	Average float64            `json:"average"` // 1 decimal
+	Count   int                `json:"count"`
+	Fit     ProductFitResponse `json:"fit"`
+}
+
+// ProductFitResponse summarises "how does it fit" answers
+// Verdict is runs_small, true_to_size, runs_large, or empty until enough answers exist
+type ProductFitResponse struct {
+	RunsSmall  int    `json:"runs_small"`
+	TrueToSize int    `json:"true_to_size"`
+	RunsLarge  int    `json:"runs_large"`
+	Verdict    string `json:"verdict,omitempty"`
+}
+
+// CreateReviewRequest represents a customer review of a delivered order item
+type CreateReviewRequest struct {
+	OrderItemID int      `json:"order_item_id" binding:"required"`
+	Rating      int      `json:"rating" binding:"required,min=1,max=5"`
+	Title       string   `json:"title" binding:"max=150"`
+	Body        string   `json:"body" binding:"max=2000"`
+	Fit         string   `json:"fit" binding:"omitempty,oneof=SMALL TRUE LARGE"`
+	PhotoURLs   []string `json:"photo_urls" binding:"max=5"` // From POST /api/user/reviews/photos
+}
+
+// ModerateReviewRequest approves or rejects a review
+type ModerateReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=APPROVED REJECTED"`
+	Note   string `json:"note"`
+}
+
+// ReviewResponse represents a review in API responses
+type ReviewResponse struct {
+	ID                 int      `json:"id"`
+	ProductID          int      `json:"product_id"`
+	ProductName        string   `json:"product_name,omitempty"`
+	VariantName        string   `json:"variant_name,omitempty"`
+	ReviewerName       string   `json:"reviewer_name"`
+	Rating             int      `json:"rating"`
+	Title              string   `json:"title,omitempty"`
+	Body               string   `json:"body,omitempty"`
+	Fit                string   `json:"fit,omitempty"`
+	IsVerifiedPurchase bool     `json:"is_verified_purchase"`
+	Photos             []string `json:"photos"`
+	CreatedAt          string   `json:"created_at"`
+	// Customer and admin views only
+	Status         string  `json:"status,omitempty"`
+	ModerationNote string  `json:"moderation_note,omitempty"`
+	ModeratedBy    string  `json:"moderated_by,omitempty"`
+	ModeratedAt    *string `json:"moderated_at,omitempty"`
+	OrderID        *int    `json:"order_id,omitempty"`
+	UserID         *int    `json:"user_id,omitempty"`
+}
+
+// ProductReviewsResponse is a page of approved reviews with the rating breakdown
+type ProductReviewsResponse struct {
+	ProductID    int                    `json:"product_id"`
+	Rating       *ProductRatingResponse `json:"rating,omitempty"`
+	Distribution map[string]int         `json:"distribution"` // "1".."5" -> count
+	Reviews      []ReviewResponse       `json:"reviews"`
+	TotalCount   int                    `json:"total_count"`
+	Limit        int                    `json:"limit"`
+	Offset       int                    `json:"offset"`
+}
+
+// ReviewableItemResponse is a delivered order item waiting for a review
+type ReviewableItemResponse struct {
+	OrderItemID  int    `json:"order_item_id"`
+	OrderCode    string `json:"order_code"`
+	ProductID    int    `json:"product_id"`
+	ProductName  string `json:"product_name"`
+	ProductImage string `json:"product_image,omitempty"`
+	DeliveredAt  string `json:"delivered_at"`
+}
+
+// ReviewPhotoUploadResponse returns the URL to pass in CreateReviewRequest.PhotoURLs
+type ReviewPhotoUploadResponse struct {
+	ImageURL string `json:"image_url"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "zavera/models"
+
 // ============================================
 // SHIPPING CATEGORY TYPES
 // ============================================
@@ -294,6 +296,13 @@ type CheckoutWithShippingRequest struct {
 	// Legacy fields (kept for backward compatibility)
 	ProviderCode    string `json:"provider_code"`
 	ServiceCode     string `json:"service_code"`
+
+	VoucherCode string `json:"voucher_code,omitempty"`
+
+	// Pay with store credit (signed-in customers): up to StoreCreditAmount, or as much as the
+	// balance covers when it is 0. Midtrans charges the rest.
+	UseStoreCredit    bool    `json:"use_store_credit,omitempty"`
+	StoreCreditAmount float64 `json:"store_credit_amount,omitempty" binding:"gte=0"`
 }
 
 // CheckoutWithShippingResponse represents checkout response with shipping details
@@ -302,7 +311,15 @@ type CheckoutWithShippingResponse struct {
 	OrderCode       string                 `json:"order_code"`
 	Subtotal        float64                `json:"subtotal"`
 	ShippingCost    float64                `json:"shipping_cost"`
+	Discount        float64                `json:"discount"` // Promotions and voucher
+	Tax             float64                `json:"tax"`
+	TaxRate         float64                `json:"tax_rate"`
+	PricesIncludeTax bool                  `json:"prices_include_tax"` // Tax is contained in the subtotal, not added
+	Promotions      []models.AppliedPromotion `json:"promotions"`
+	VoucherCode     string                 `json:"voucher_code,omitempty"`
 	TotalAmount     float64                `json:"total_amount"`
+	StoreCreditAmount float64              `json:"store_credit_amount"` // Paid with store credit
+	AmountDue       float64                `json:"amount_due"`          // Left to pay through Midtrans; 0 means the order is paid
 	Status          string                 `json:"status"`
 	ShippingLocked  bool                   `json:"shipping_locked"`
 	Provider        string                 `json:"provider"`
@@ -323,7 +340,7 @@ type CartShippingPreviewResponse struct {
 	CartSubtotal    float64                           `json:"cart_subtotal"`
 	TotalWeight     int                               `json:"total_weight"`      // in grams
 	TotalWeightKg   string                            `json:"total_weight_kg"`   // "1.2 kg"
-	OriginCity      string                            `json:"origin_city"`       // "Semarang"
+	OriginCity      string                            `json:"origin_city"`       // City of the warehouse the order would ship from
 	DestinationCity string                            `json:"destination_city"`  // From address
 	GroupedRates    map[string][]ShippingRateResponse `json:"grouped_rates"`     // Grouped by category: REGULER, EXPRESS, SAME DAY
 	Rates           []ShippingRateResponse            `json:"rates"`             // Flat list, sorted by priority
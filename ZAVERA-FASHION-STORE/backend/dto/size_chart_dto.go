@@ -0,0 +1,48 @@
+package dto
+
+import "zavera/models"
+
+// SaveSizeChartRequest creates or replaces a size chart. Rows are listed smallest size first;
+// measurements are body measurements in cm (weight in kg) and may be left open.
+type SaveSizeChartRequest struct {
+	Name       string                `json:"name" binding:"required,max=100"`
+	CategoryID *int                  `json:"category_id"`
+	Brand      *string               `json:"brand" binding:"omitempty,max=100"`
+	Notes      string                `json:"notes"`
+	IsActive   *bool                 `json:"is_active"` // Defaults to true
+	Rows       []SizeChartRowRequest `json:"rows" binding:"required,min=1,dive"`
+}
+
+// SizeChartRowRequest is one size of a chart
+type SizeChartRowRequest struct {
+	SizeLabel   string   `json:"size_label" binding:"required,max=20"`
+	ChestMinCm  *float64 `json:"chest_min_cm"`
+	ChestMaxCm  *float64 `json:"chest_max_cm"`
+	WaistMinCm  *float64 `json:"waist_min_cm"`
+	WaistMaxCm  *float64 `json:"waist_max_cm"`
+	HipMinCm    *float64 `json:"hip_min_cm"`
+	HipMaxCm    *float64 `json:"hip_max_cm"`
+	HeightMinCm *float64 `json:"height_min_cm"`
+	HeightMaxCm *float64 `json:"height_max_cm"`
+	WeightMinKg *float64 `json:"weight_min_kg"`
+	WeightMaxKg *float64 `json:"weight_max_kg"`
+	LengthCm    *float64 `json:"length_cm"`
+}
+
+// SaveFitProfileRequest replaces the customer's fit profile
+type SaveFitProfileRequest struct {
+	HeightCm   *float64          `json:"height_cm" binding:"omitempty,gt=0,lt=300"`
+	WeightKg   *float64          `json:"weight_kg" binding:"omitempty,gt=0,lt=500"`
+	ChestCm    *float64          `json:"chest_cm" binding:"omitempty,gt=0,lt=300"`
+	WaistCm    *float64          `json:"waist_cm" binding:"omitempty,gt=0,lt=300"`
+	HipCm      *float64          `json:"hip_cm" binding:"omitempty,gt=0,lt=300"`
+	UsualSizes map[string]string `json:"usual_sizes"` // Brand -> size, e.g. {"Zavera": "M"}
+}
+
+// SizeRecommendationResponse is the suggested size of a product with the variants in that size
+type SizeRecommendationResponse struct {
+	models.SizeRecommendation
+	ProductID  int   `json:"product_id"`
+	ChartID    int   `json:"chart_id"`
+	VariantIDs []int `json:"variant_ids"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+// StartStockTakeRequest opens a count of one warehouse
+type StartStockTakeRequest struct {
+	WarehouseID int    `json:"warehouse_id"` // 0 = default
+	ProductIDs  []int  `json:"product_ids"`  // Count only these products; empty = every active variant
+	Notes       string `json:"notes"`
+}
+
+// StockTakeCountsRequest records typed counts; each replaces the line's counted quantity
+type StockTakeCountsRequest struct {
+	Counts []StockTakeCountRequest `json:"counts" binding:"required,min=1,dive"`
+}
+
+// StockTakeCountRequest identifies the variant by variant_id, barcode or sku
+type StockTakeCountRequest struct {
+	VariantID       int    `json:"variant_id"`
+	Barcode         string `json:"barcode"`
+	SKU             string `json:"sku"`
+	CountedQuantity int    `json:"counted_quantity" binding:"min=0"`
+}
+
+// StockTakeScanRequest adds a barcode scan to the line's count
+type StockTakeScanRequest struct {
+	Barcode  string `json:"barcode" binding:"required"`
+	Quantity int    `json:"quantity" binding:"min=0"` // Defaults to 1
+}
+
+// StockTakeApprovalRequest includes or excludes variances from posting
+type StockTakeApprovalRequest struct {
+	VariantIDs []int `json:"variant_ids" binding:"required,min=1"`
+	Approved   *bool `json:"approved" binding:"required"`
+}
+
+// UpdateStockTakeStatusRequest submits a count for review, reopens it, or cancels it
+type UpdateStockTakeStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=REVIEW COUNTING CANCELLED"`
+}
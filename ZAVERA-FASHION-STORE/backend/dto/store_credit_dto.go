@@ -0,0 +1,39 @@
+package dto
+
+import "zavera/models"
+
+// StoreCreditResponse is a customer's store credit balance with their latest ledger entries
+type StoreCreditResponse struct {
+	Balance float64                   `json:"balance"`
+	Entries []models.StoreCreditEntry `json:"entries"`
+}
+
+// RedeemGiftCardRequest adds a gift card to the customer's store credit
+type RedeemGiftCardRequest struct {
+	Code string `json:"code" binding:"required,max=40"`
+}
+
+// RedeemGiftCardResponse shows what a redeemed gift card added
+type RedeemGiftCardResponse struct {
+	Amount  float64 `json:"amount"`
+	Balance float64 `json:"balance"`
+}
+
+// AdjustStoreCreditRequest credits (positive) or debits (negative) a customer's store credit
+type AdjustStoreCreditRequest struct {
+	Amount float64 `json:"amount" binding:"required"`
+	Note   string  `json:"note" binding:"required,max=500"`
+}
+
+// GiftCardListResponse is a page of gift cards for admins; codes are masked
+type GiftCardListResponse struct {
+	GiftCards  []models.GiftCard `json:"gift_cards"`
+	TotalCount int               `json:"total_count"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+}
+
+// SetGiftCardProductRequest makes a product a gift card, or an ordinary product again
+type SetGiftCardProductRequest struct {
+	IsGiftCard *bool `json:"is_gift_card" binding:"required"`
+}
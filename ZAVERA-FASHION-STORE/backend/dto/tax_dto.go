@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+	"zavera/models"
+)
+
+// ScheduleTaxSettingRequest schedules a PPN rate change. Settings in effect are never edited,
+// so a change is a new setting from a future date.
+type ScheduleTaxSettingRequest struct {
+	Rate             *float64  `json:"rate" binding:"required,gte=0,lte=100"`
+	PricesIncludeTax *bool     `json:"prices_include_tax" binding:"required"`
+	EffectiveFrom    time.Time `json:"effective_from" binding:"required"`
+	Notes            string    `json:"notes"`
+}
+
+// SetTaxExemptRequest exempts a product from PPN, or makes it taxable again
+type SetTaxExemptRequest struct {
+	TaxExempt *bool `json:"tax_exempt" binding:"required"`
+}
+
+// TaxSettingsResponse lists the PPN settings with the one in effect now
+type TaxSettingsResponse struct {
+	Current  *models.TaxSetting  `json:"current"`
+	Settings []models.TaxSetting `json:"settings"`
+}
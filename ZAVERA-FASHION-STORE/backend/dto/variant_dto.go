@@ -29,6 +29,10 @@ type CreateVariantRequest struct {
 	Height            *int                   `json:"height"` // Alias for height_cm
 	Barcode           *string                `json:"barcode"`
 	Position          int                    `json:"position"`
+	// Pre-order/backorder: STOCK (default), BACKORDER or PREORDER
+	InventoryPolicy  string  `json:"inventory_policy"`
+	BackorderLimit   *int    `json:"backorder_limit"`
+	ExpectedShipDate *string `json:"expected_ship_date"` // YYYY-MM-DD
 }
 
 type UpdateVariantRequest struct {
@@ -59,18 +63,40 @@ type UpdateVariantRequest struct {
 	Height            *int                   `json:"height"` // Alias for height_cm
 	Barcode           *string                `json:"barcode"`
 	Position          int                    `json:"position"`
+	// Pre-order/backorder: STOCK (default), BACKORDER or PREORDER
+	InventoryPolicy  string  `json:"inventory_policy"`
+	BackorderLimit   *int    `json:"backorder_limit"`
+	ExpectedShipDate *string `json:"expected_ship_date"` // YYYY-MM-DD
 }
 
+// BulkGenerateVariantsRequest creates a variant for every combination of the options.
+// Sizes and colors are shorthand for the size and color options and come first.
 type BulkGenerateVariantsRequest struct {
-	ProductID       int      `json:"product_id" binding:"required"`
-	Sizes           []string `json:"sizes" binding:"required"`
-	Colors          []string `json:"colors" binding:"required"`
-	BasePrice       float64  `json:"base_price"`
-	StockPerVariant int      `json:"stock_per_variant"`
-	Weight          int      `json:"weight"`           // Default weight in grams
-	Length          int      `json:"length"`           // Default length in cm
-	Width           int      `json:"width"`            // Default width in cm
-	Height          int      `json:"height"`           // Default height in cm
+	ProductID       int                   `json:"product_id" binding:"required"`
+	Sizes           []string              `json:"sizes"`
+	Colors          []string              `json:"colors"`
+	Options         []VariantOptionValues `json:"options" binding:"dive"` // e.g. [{"name": "length", "values": ["Regular", "Long"]}]
+	BasePrice       float64               `json:"base_price"`
+	StockPerVariant int                   `json:"stock_per_variant"`
+	Weight          int                   `json:"weight"` // Default weight in grams
+	Length          int                   `json:"length"` // Default length in cm
+	Width           int                   `json:"width"`  // Default width in cm
+	Height          int                   `json:"height"` // Default height in cm
+}
+
+// VariantOptionValues is one option of a bulk generation and its values
+type VariantOptionValues struct {
+	Name   string   `json:"name" binding:"required"` // A variant attribute name
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+// CreateVariantAttributeRequest adds an option variants can differ on, e.g. length
+type CreateVariantAttributeRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	DisplayName string   `json:"display_name" binding:"required,max=100"`
+	Type        string   `json:"type" binding:"required,oneof=size color text select"`
+	Options     []string `json:"options"` // Suggested values
+	SortOrder   int      `json:"sort_order"`
 }
 
 type AddVariantImageRequest struct {
@@ -93,18 +119,21 @@ type SetPrimaryImageRequest struct {
 }
 
 type UpdateVariantStockRequest struct {
-	Quantity int `json:"quantity" binding:"required,min=0"`
+	Quantity int    `json:"quantity" binding:"required,min=0"`
+	Reason   string `json:"reason"` // Noted on the stock ledger entry
 }
 
 type AdjustStockRequest struct {
-	Delta int `json:"delta" binding:"required"`
+	Delta  int    `json:"delta" binding:"required"`
+	Reason string `json:"reason"` // Noted on the stock ledger entry
 }
 
 type ReserveStockRequest struct {
-	VariantID  int    `json:"variant_id" binding:"required"`
-	Quantity   int    `json:"quantity" binding:"required,min=1"`
-	CustomerID int    `json:"customer_id"`
-	SessionID  string `json:"session_id"`
+	VariantID   int    `json:"variant_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,min=1"`
+	CustomerID  int    `json:"customer_id"`
+	SessionID   string `json:"session_id"`
+	WarehouseID *int   `json:"warehouse_id"` // Optional; defaults to the active warehouse with the most available stock
 }
 
 type ReserveStockResponse struct {
@@ -122,12 +151,17 @@ type CheckAvailabilityResponse struct {
 	Available      bool `json:"available"`
 	AvailableStock int  `json:"available_stock"`
 	RequestedStock int  `json:"requested_stock"`
+	// Pre-order/backorder variants
+	InventoryPolicy   string  `json:"inventory_policy"`
+	BackorderQuantity int     `json:"backorder_quantity,omitempty"`
+	ExpectedShipDate  *string `json:"expected_ship_date,omitempty"`
 }
 
 type FindVariantRequest struct {
-	ProductID int     `json:"product_id" binding:"required"`
-	Size      *string `json:"size"`
-	Color     *string `json:"color"`
+	ProductID int               `json:"product_id" binding:"required"`
+	Size      *string           `json:"size"`
+	Color     *string           `json:"color"`
+	Options   map[string]string `json:"options"` // Any attribute, e.g. {"length": "Long"}
 }
 
 type VariantSearchRequest struct {
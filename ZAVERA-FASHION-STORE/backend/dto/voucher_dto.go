@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"time"
+	"zavera/models"
+)
+
+// SaveVoucherRequest creates or replaces a voucher. Value is a percentage for PERCENTAGE and
+// BUY_X_GET_Y (100 makes the Y items free) and rupiah for FIXED_AMOUNT.
+type SaveVoucherRequest struct {
+	Code           string     `json:"code" binding:"required,max=50"`
+	Name           string     `json:"name" binding:"required,max=150"`
+	Description    string     `json:"description"`
+	Type           string     `json:"type" binding:"required"`
+	Value          float64    `json:"value" binding:"gte=0"`
+	MaxDiscount    *float64   `json:"max_discount" binding:"omitempty,gt=0"`
+	MinSpend       float64    `json:"min_spend" binding:"gte=0"`
+	BuyQuantity    int        `json:"buy_quantity" binding:"gte=0"`
+	GetQuantity    int        `json:"get_quantity" binding:"gte=0"`
+	CategoryID     *int       `json:"category_id"`
+	Brand          *string    `json:"brand" binding:"omitempty,max=100"`
+	UsageLimit     *int       `json:"usage_limit" binding:"omitempty,gt=0"`
+	PerUserLimit   *int       `json:"per_user_limit" binding:"omitempty,gt=0"`
+	FirstOrderOnly bool       `json:"first_order_only"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	IsActive       *bool      `json:"is_active"` // Defaults to true
+}
+
+// ValidateVoucherRequest previews a voucher against the current cart. Guests pass their email
+// so per-customer and first-order conditions can be checked.
+type ValidateVoucherRequest struct {
+	Code          string  `json:"code" binding:"required"`
+	ShippingCost  float64 `json:"shipping_cost" binding:"gte=0"`
+	CustomerEmail string  `json:"customer_email" binding:"omitempty,email"`
+}
+
+// VoucherPreviewResponse is what the voucher would take off the cart
+type VoucherPreviewResponse struct {
+	models.VoucherDiscount
+	Code              string  `json:"code"`
+	Name              string  `json:"name"`
+	Type              string  `json:"type"`
+	Subtotal          float64 `json:"subtotal"`
+	PromotionDiscount float64 `json:"promotion_discount"` // Automatic promotions, applied first
+	Discount          float64 `json:"discount"`           // The voucher
+	TotalAmount       float64 `json:"total_amount"`       // Subtotal plus shipping, less both discounts
+}
+
+// VoucherDetailResponse is a voucher with its redemptions
+type VoucherDetailResponse struct {
+	models.Voucher
+	Redemptions []models.VoucherRedemption `json:"redemptions"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+// WarehouseRequest creates or replaces a warehouse
+type WarehouseRequest struct {
+	Code         string `json:"code" binding:"required,max=20"`
+	Name         string `json:"name" binding:"required,max=100"`
+	Address      string `json:"address" binding:"required"`
+	CityName     string `json:"city_name" binding:"required,max=100"`
+	CityID       string `json:"city_id" binding:"max=20"` // Legacy city ID recorded on shipments
+	PostalCode   string `json:"postal_code" binding:"required,numeric,len=5"`
+	AreaID       string `json:"area_id"` // Biteship area ID; improves routing to nearby destinations
+	ContactName  string `json:"contact_name" binding:"required,max=100"`
+	ContactPhone string `json:"contact_phone" binding:"required,max=30"`
+	Priority     int    `json:"priority"`
+	IsDefault    bool   `json:"is_default"`
+	IsActive     *bool  `json:"is_active"` // Defaults to true
+}
+
+// SetWarehouseStockRequest sets the on-hand quantity of a variant at a warehouse
+type SetWarehouseStockRequest struct {
+	VariantID int `json:"variant_id" binding:"required"`
+	Quantity  int `json:"quantity" binding:"min=0"`
+}
+
+// TransferStockRequest moves stock of a variant from one warehouse to another
+type TransferStockRequest struct {
+	FromWarehouseID int `json:"from_warehouse_id" binding:"required"`
+	ToWarehouseID   int `json:"to_warehouse_id" binding:"required"`
+	VariantID       int `json:"variant_id" binding:"required"`
+	Quantity        int `json:"quantity" binding:"required,min=1"`
+}
@@ -27,3 +27,8 @@ type AddToWishlistRequest struct {
 type MoveToCartRequest struct {
 	ProductID int `json:"product_id" binding:"required"`
 }
+
+// StockAlertRequest subscribes to a sold-out variant
+type StockAlertRequest struct {
+	VariantID int `json:"variant_id" binding:"required"`
+}
@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 	"zavera/dto"
 	"zavera/service"
 
@@ -152,7 +158,7 @@ func (h *AdminProductHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.UpdateStock(id, req)
+	product, err := h.productService.UpdateStock(id, req, c.GetString("user_email"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
 			Error:   "stock_update_failed",
@@ -295,3 +301,258 @@ func (h *AdminProductHandler) DeleteProductImage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
 }
+
+// maxImportFileSize limits bulk import uploads
+const maxImportFileSize = 10 << 20
+
+// ImportProducts bulk creates/updates products and variants from a CSV or XLSX file
+// (multipart field "file"). With ?dry_run=true only the diff and row errors are returned.
+// POST /api/admin/products/import
+func (h *AdminProductHandler) ImportProducts(c *gin.Context) {
+	file, fileHeader, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_file",
+			Message: "No file uploaded",
+		})
+		return
+	}
+	defer file.Close()
+
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_file",
+			Message: "File must be 10MB or smaller",
+		})
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_file",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	report, err := h.productService.ImportProducts(format, data, c.Query("dry_run") == "true")
+	if err != nil {
+		if err == service.ErrUnsupportedSheetFormat || errors.Is(err, service.ErrInvalidImportFile) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_file", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "import_failed", Message: err.Error()})
+		return
+	}
+
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportProducts downloads the catalog in the import layout
+// GET /api/admin/products/export?format=csv|xlsx
+func (h *AdminProductHandler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", service.SheetFormatCSV)
+
+	data, err := h.productService.ExportProducts(format)
+	if err != nil {
+		if err == service.ErrUnsupportedSheetFormat {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_format", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "export_failed", Message: err.Error()})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.SheetFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	filename := fmt.Sprintf("zavera-products-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ListCollections returns all collections including inactive and scheduled ones
+// GET /api/admin/collections
+func (h *AdminProductHandler) ListCollections(c *gin.Context) {
+	collections, err := h.productService.ListCollections()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "server_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"collections": collections,
+		"total_count": len(collections),
+	})
+}
+
+// GetCollection returns one collection
+// GET /api/admin/collections/:id
+func (h *AdminProductHandler) GetCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	collection, err := h.productService.GetCollection(id)
+	if err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// CreateCollection creates a manual or rule-based collection
+// POST /api/admin/collections
+func (h *AdminProductHandler) CreateCollection(c *gin.Context) {
+	var req dto.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.productService.CreateCollection(req)
+	if err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, collection)
+}
+
+// UpdateCollection updates collection settings, rules and visibility window
+// PUT /api/admin/collections/:id
+func (h *AdminProductHandler) UpdateCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.productService.UpdateCollection(id, req)
+	if err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection deletes a collection (products are not affected)
+// DELETE /api/admin/collections/:id
+func (h *AdminProductHandler) DeleteCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.DeleteCollection(id); err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted"})
+}
+
+// SetCollectionProducts replaces the products of a manual collection, in display order
+// PUT /api/admin/collections/:id/products
+func (h *AdminProductHandler) SetCollectionProducts(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SetCollectionProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.productService.SetCollectionProducts(id, req.ProductIDs)
+	if err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// PreviewCollection lists the products a collection resolves to, ignoring its visibility window
+// GET /api/admin/collections/:id/preview
+func (h *AdminProductHandler) PreviewCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	preview, err := h.productService.PreviewCollection(id, limit, offset)
+	if err != nil {
+		h.handleCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+func parseCollectionID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid collection ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *AdminProductHandler) handleCollectionError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrCollectionNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrDuplicateCollection:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "duplicate_collection", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidCollection), err == service.ErrInvalidSlug, err == service.ErrInvalidProductSort:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -405,6 +405,8 @@ func (h *AdminRefundHandler) formatRefundResponse(refund *models.Refund) dto.Ref
 		RefundAmount:    refund.RefundAmount,
 		ShippingRefund:  refund.ShippingRefund,
 		ItemsRefund:     refund.ItemsRefund,
+		Destination:     string(refund.Destination),
+		StoreCreditAmount: refund.StoreCreditAmount,
 		Status:          string(refund.Status),
 		GatewayRefundID: refund.GatewayRefundID,
 		GatewayStatus:   refund.GatewayStatus,
@@ -494,6 +496,15 @@ func (h *AdminRefundHandler) handleError(c *gin.Context, err error, orderCode st
 			},
 		})
 	
+	case err == service.ErrRefundGiftCardRedeemed:
+		c.JSON(http.StatusConflict, dto.RefundErrorResponse{
+			Error:   "GIFT_CARD_REDEEMED",
+			Message: err.Error(),
+			Details: map[string]interface{}{
+				"order_code": orderCode,
+			},
+		})
+	
 	case err == service.ErrIdempotencyConflict:
 		c.JSON(http.StatusConflict, dto.RefundErrorResponse{
 			Error:   "IDEMPOTENCY_CONFLICT",
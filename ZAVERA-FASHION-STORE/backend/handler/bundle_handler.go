@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BundleHandler struct {
+	bundleService service.BundleService
+}
+
+func NewBundleHandler(bundleService service.BundleService) *BundleHandler {
+	return &BundleHandler{
+		bundleService: bundleService,
+	}
+}
+
+// GetBundle returns the components of a bundle and how many complete bundles are in stock
+// GET /api/products/:id/bundle
+func (h *BundleHandler) GetBundle(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.bundleService.GetBundle(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// SetBundle makes the product a bundle of the given variants, replacing any previous components
+// PUT /api/admin/products/:id/bundle
+func (h *BundleHandler) SetBundle(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SetBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	bundle, err := h.bundleService.SetComponents(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// DeleteBundle turns a bundle back into a regular product
+// DELETE /api/admin/products/:id/bundle
+func (h *BundleHandler) DeleteBundle(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.bundleService.Unbundle(id, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bundle removed"})
+}
+
+func (h *BundleHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid product ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *BundleHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrProductNotFound, err == service.ErrBundleNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidBundle):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_bundle", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryService service.CategoryService
+}
+
+func NewCategoryHandler(categoryService service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// GetCategoryTree returns the active category tree
+// GET /api/categories
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.categoryService.GetTree(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "internal_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetCategory returns one category with its active subcategories, by ID or path
+// GET /api/categories/12, GET /api/categories/wanita/dress
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	ref := strings.Trim(c.Param("ref"), "/")
+
+	category, err := h.categoryService.GetCategory(ref)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// GetAdminCategoryTree returns the full tree including inactive categories
+// GET /api/admin/categories
+func (h *CategoryHandler) GetAdminCategoryTree(c *gin.Context) {
+	tree, err := h.categoryService.GetTree(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "server_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": tree,
+	})
+}
+
+// CreateCategory creates a category
+// POST /api/admin/categories
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req dto.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory renames, moves or toggles a category
+// PUT /api/admin/categories/:id
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid category ID",
+		})
+		return
+	}
+
+	var req dto.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory deletes an empty category
+// DELETE /api/admin/categories/:id
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid category ID",
+		})
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Category deleted successfully",
+	})
+}
+
+func (h *CategoryHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrCategoryNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case service.ErrDuplicateCategory:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "duplicate_category", Message: err.Error()})
+	case service.ErrCategoryHasChildren, service.ErrCategoryHasProducts:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "category_not_empty", Message: err.Error()})
+	case service.ErrCategoryCycle, service.ErrInvalidSlug:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
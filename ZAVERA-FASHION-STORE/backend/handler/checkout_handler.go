@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"zavera/dto"
@@ -94,6 +95,13 @@ func (h *CheckoutHandler) CheckoutWithShipping(c *gin.Context) {
 	response, err := h.checkoutService.CheckoutWithShipping(sessionID, req, userID)
 	if err != nil {
 		log.Printf("❌ Checkout error: %v", err)
+		if errors.Is(err, service.ErrVoucherNotApplicable) {
+			c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+				Error:   "voucher_not_applicable",
+				Message: err.Error(),
+			})
+			return
+		}
 		switch err {
 		case service.ErrCartEmpty:
 			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -115,6 +123,26 @@ func (h *CheckoutHandler) CheckoutWithShipping(c *gin.Context) {
 				Error:   "invalid_courier",
 				Message: "Selected courier service is not available",
 			})
+		case service.ErrVoucherNotFound:
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "voucher_not_found",
+				Message: "Voucher code not found",
+			})
+		case service.ErrStoreCreditRequiresLogin:
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error:   "login_required",
+				Message: "Sign in to pay with store credit",
+			})
+		case service.ErrInsufficientStoreCredit:
+			c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+				Error:   "insufficient_store_credit",
+				Message: "Your store credit balance is not enough for this order",
+			})
+		case service.ErrNoWarehouseCanFulfil:
+			c.JSON(http.StatusConflict, dto.ErrorResponse{
+				Error:   "stock_split_across_warehouses",
+				Message: "Some items are only in stock at different locations and cannot ship together. Please place them as separate orders.",
+			})
 		default:
 			// Check for insufficient stock
 			if err.Error() != "" {
@@ -91,6 +91,9 @@ func (h *CorePaymentHandler) CreateVAPayment(c *gin.Context) {
 		case service.ErrOrderNotPendingPayment:
 			status = http.StatusBadRequest
 			errorCode = "order_not_pending"
+		case service.ErrNothingToPay:
+			status = http.StatusBadRequest
+			errorCode = "nothing_to_pay"
 		case service.ErrPaymentMethodInvalid:
 			status = http.StatusBadRequest
 			errorCode = "invalid_payment_method"
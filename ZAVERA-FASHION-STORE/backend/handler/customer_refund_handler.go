@@ -196,6 +196,7 @@ func (h *CustomerRefundHandler) toCustomerRefundResponse(refund *models.Refund)
 		RefundAmount:   refund.RefundAmount,
 		ShippingRefund: refund.ShippingRefund,
 		ItemsRefund:    refund.ItemsRefund,
+		StoreCreditAmount: refund.StoreCreditAmount,
 		Status:         string(refund.Status),
 		StatusLabel:    statusLabel,
 		Timeline:       timeline,
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	feedService service.FeedService
+}
+
+func NewFeedHandler(feedService service.FeedService) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+	}
+}
+
+// GetSitemap serves the XML sitemap of active products and categories
+// GET /sitemap.xml, GET /api/feeds/sitemap.xml
+func (h *FeedHandler) GetSitemap(c *gin.Context) {
+	h.serveFeed(c, service.FeedSitemap)
+}
+
+// GetMerchantFeedXML serves the Google Merchant / Meta catalog feed as RSS 2.0
+// GET /api/feeds/products.xml
+func (h *FeedHandler) GetMerchantFeedXML(c *gin.Context) {
+	h.serveFeed(c, service.FeedMerchantXML)
+}
+
+// GetMerchantFeedTSV serves the Google Merchant / Meta catalog feed as tab-separated values
+// GET /api/feeds/products.tsv
+func (h *FeedHandler) GetMerchantFeedTSV(c *gin.Context) {
+	h.serveFeed(c, service.FeedMerchantTSV)
+}
+
+func (h *FeedHandler) serveFeed(c *gin.Context, kind string) {
+	feed, err := h.feedService.GetFeed(kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "feed_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", feed.ETag)
+	c.Header("Last-Modified", feed.GeneratedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=900")
+
+	if match := c.GetHeader("If-None-Match"); match != "" && match == feed.ETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, feed.ContentType, feed.Body)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandler struct {
+	invoiceService service.InvoiceService
+}
+
+func NewInvoiceHandler(invoiceService service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// GetTaxProfile returns the NPWP printed on the customer's invoices
+// GET /api/user/tax-profile
+func (h *InvoiceHandler) GetTaxProfile(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.invoiceService.GetTaxProfile(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// SaveTaxProfile sets or clears the NPWP printed on the customer's invoices from now on
+// PUT /api/user/tax-profile
+func (h *InvoiceHandler) SaveTaxProfile(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SaveTaxProfileRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	profile, err := h.invoiceService.SaveTaxProfile(userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetOrderDocuments lists the invoice and credit notes of one of the customer's orders
+// GET /api/user/orders/:code/invoices
+func (h *InvoiceHandler) GetOrderDocuments(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.invoiceService.ListOrderDocuments(c.Param("code"), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+// DownloadInvoice downloads an invoice or credit note of one of the customer's orders
+// GET /api/user/invoices/:id/pdf
+func (h *InvoiceHandler) DownloadInvoice(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	invoice, pdf, err := h.invoiceService.RenderForUser(id, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.sendPDF(c, invoice, pdf)
+}
+
+// ListPeriod lists the invoices and credit notes issued in a tax period
+// GET /api/admin/invoices?period=2026-10
+func (h *InvoiceHandler) ListPeriod(c *gin.Context) {
+	invoices, err := h.invoiceService.ListPeriod(c.Query("period"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+// ExportPeriod downloads a ZIP of the tax period's PDFs with a CSV summary for the tax return
+// GET /api/admin/invoices/export?period=2026-10
+func (h *InvoiceHandler) ExportPeriod(c *gin.Context) {
+	filename, data, err := h.invoiceService.ExportPeriod(c.Query("period"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// AdminDownloadInvoice downloads any invoice or credit note
+// GET /api/admin/invoices/:id/pdf
+func (h *InvoiceHandler) AdminDownloadInvoice(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	invoice, pdf, err := h.invoiceService.Render(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.sendPDF(c, invoice, pdf)
+}
+
+func (h *InvoiceHandler) sendPDF(c *gin.Context, invoice *models.Invoice, pdf []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, invoice.FileName()))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+func (h *InvoiceHandler) getUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if exists {
+		switch v := userID.(type) {
+		case int:
+			return v, true
+		case int64:
+			return int(v), true
+		case float64:
+			return int(v), true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		Error:   "unauthorized",
+		Message: "User not authenticated",
+	})
+	return 0, false
+}
+
+func (h *InvoiceHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid invoice ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *InvoiceHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *InvoiceHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrInvoiceNotFound, err == service.ErrOrderNotFound, err == service.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidTaxProfile):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_tax_profile", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidTaxPeriod):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_tax_period", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"zavera/dto"
 	"zavera/models"
@@ -71,8 +72,10 @@ func (h *OrderHandler) Checkout(c *gin.Context) {
 	checkoutResp, err := h.orderService.CreateOrder(sessionID, req, userID)
 	if err != nil {
 		status := http.StatusInternalServerError
-		if err.Error() == "cart is empty" {
+		if err.Error() == "cart is empty" || err == service.ErrVoucherNotFound {
 			status = http.StatusBadRequest
+		} else if errors.Is(err, service.ErrVoucherNotApplicable) {
+			status = http.StatusUnprocessableEntity
 		}
 
 		c.JSON(status, dto.ErrorResponse{
@@ -51,6 +51,8 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 			status = http.StatusNotFound
 		case "order is not in pending status":
 			status = http.StatusConflict
+		case "order has nothing left to pay":
+			status = http.StatusConflict
 		case "payment already exists for this order":
 			status = http.StatusConflict
 		}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"zavera/dto"
+	"zavera/models"
 	"zavera/service"
 
 	"github.com/gin-gonic/gin"
@@ -21,18 +24,83 @@ func NewProductHandler(productService service.ProductService) *ProductHandler {
 
 // GetProducts godoc
 // @Summary Get all products
-// @Description Get list of all active products, optionally filtered by category
+// @Description Get list of all active products, optionally filtered by category.
+// @Description When any search/filter param is given, returns a search result with total count and facets.
+// @Description When cursor, sort or limit is given without filters, returns a keyset-paginated page with next_cursor.
 // @Tags products
 // @Accept json
 // @Produce json
-// @Param category query string false "Filter by category (wanita, pria, anak, sports, luxury, beauty)"
+// @Param category query string false "Filter by category path (e.g. wanita or wanita/dress); includes subcategories"
+// @Param category_id query int false "Filter by category ID; includes subcategories"
+// @Param q query string false "Full-text search on name, brand, material and description"
+// @Param subcategory query string false "Filter by subcategory"
+// @Param brand query string false "Filter by brand (comma-separated or repeated)"
+// @Param size query string false "Filter by variant size (comma-separated or repeated)"
+// @Param color query string false "Filter by variant color (comma-separated or repeated)"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param in_stock query bool false "Only products with available stock"
+// @Param sort query string false "Sort: newest, price_asc, price_desc, best_selling, most_wishlisted"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Offset (search only)"
+// @Param facets query bool false "Include facet counts (default true)"
 // @Success 200 {array} dto.ProductResponse
+// @Success 200 {object} dto.ProductSearchResponse
+// @Success 200 {object} dto.ProductPageResponse
 // @Router /api/products [get]
 func (h *ProductHandler) GetProducts(c *gin.Context) {
-	category := c.Query("category")
+	filter, err := parseProductSearchFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_filter",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if filter.HasCriteria() {
+		includeFacets := c.DefaultQuery("facets", "true") != "false"
+		result, err := h.productService.SearchProducts(filter, includeFacets)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "internal_error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if c.Query("cursor") != "" || c.Query("sort") != "" || c.Query("limit") != "" {
+		page, err := h.productService.ListProducts(models.ProductListQuery{
+			Category: filter.Category,
+			Sort:     filter.Sort,
+			Cursor:   c.Query("cursor"),
+			Limit:    filter.Limit,
+		})
+		if err != nil {
+			if err == service.ErrInvalidProductSort || err == service.ErrInvalidProductCursor {
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+					Error:   "invalid_pagination",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "internal_error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	category := filter.Category
 	
 	var products []dto.ProductResponse
-	var err error
 	
 	if category != "" {
 		products, err = h.productService.GetProductsByCategory(category)
@@ -51,6 +119,66 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 	c.JSON(http.StatusOK, products)
 }
 
+// parseProductSearchFilter reads search and facet filter params from the query string
+func parseProductSearchFilter(c *gin.Context) (models.ProductSearchFilter, error) {
+	filter := models.ProductSearchFilter{
+		Query:       strings.TrimSpace(c.Query("q")),
+		Category:    c.Query("category"),
+		Subcategory: c.Query("subcategory"),
+		Brands:      queryList(c, "brand"),
+		Sizes:       queryList(c, "size"),
+		Colors:      queryList(c, "color"),
+		InStock:     c.Query("in_stock") == "true" || c.Query("in_stock") == "1",
+		Sort:        models.ProductSort(c.Query("sort")),
+	}
+
+	// category_id and category path resolve the same way downstream
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		if _, err := strconv.Atoi(categoryID); err != nil {
+			return filter, fmt.Errorf("category_id must be a number")
+		}
+		filter.Category = categoryID
+	}
+
+	if filter.Sort != "" && !filter.Sort.IsValid() {
+		return filter, fmt.Errorf("sort must be one of newest, price_asc, price_desc, best_selling, most_wishlisted")
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		if v := c.Query(p.name); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil || price < 0 {
+				return filter, fmt.Errorf("%s must be a non-negative number", p.name)
+			}
+			*p.dst = &price
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, fmt.Errorf("min_price cannot be greater than max_price")
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	return filter, nil
+}
+
+// queryList accepts both ?size=M&size=L and ?size=M,L
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
 // GetProductByID godoc
 // @Summary Get product by ID
 // @Description Get product details by ID
@@ -82,3 +210,99 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, product)
 }
+
+// GetProductBySlug godoc
+// @Summary Get product by slug
+// @Description Get product details and SEO metadata by slug. Retired slugs answer 301 with the current slug.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param slug path string true "Product slug"
+// @Success 200 {object} dto.ProductResponse
+// @Success 301 {object} dto.ProductSlugRedirectResponse
+// @Router /api/products/slug/{slug} [get]
+func (h *ProductHandler) GetProductBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+
+	product, currentSlug, err := h.productService.GetProductBySlug(slug)
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error:   "not_found",
+				Message: "Product not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "internal_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if product == nil {
+		location := "/api/products/slug/" + currentSlug
+		c.Header("Location", location)
+		c.JSON(http.StatusMovedPermanently, dto.ProductSlugRedirectResponse{
+			Redirect: true,
+			Slug:     currentSlug,
+			Location: location,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// GetCollections godoc
+// @Summary List collections
+// @Description List merchandising collections that are active and inside their visibility window
+// @Tags collections
+// @Produce json
+// @Success 200 {array} dto.CollectionResponse
+// @Router /api/collections [get]
+func (h *ProductHandler) GetCollections(c *gin.Context) {
+	collections, err := h.productService.GetCollections()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "internal_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+
+// GetCollectionProducts godoc
+// @Summary Get collection products
+// @Description Get a visible collection and a page of its active products
+// @Tags collections
+// @Produce json
+// @Param slug path string true "Collection slug"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} dto.CollectionProductsResponse
+// @Router /api/collections/{slug} [get]
+func (h *ProductHandler) GetCollectionProducts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	response, err := h.productService.GetCollectionProducts(c.Param("slug"), limit, offset)
+	if err != nil {
+		if err == service.ErrCollectionNotFound {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error:   "not_found",
+				Message: "Collection not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "internal_error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
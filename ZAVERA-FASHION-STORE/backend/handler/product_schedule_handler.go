@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductScheduleHandler struct {
+	scheduleService service.ProductScheduleService
+}
+
+func NewProductScheduleHandler(scheduleService service.ProductScheduleService) *ProductScheduleHandler {
+	return &ProductScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// CreateSchedule schedules a publish, unpublish, price change or sale for a product
+// POST /api/admin/products/:id/schedules
+func (h *ProductScheduleHandler) CreateSchedule(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid product ID",
+		})
+		return
+	}
+
+	var req dto.CreateProductScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(productID, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// GetProductSchedules lists schedule entries for one product
+// GET /api/admin/products/:id/schedules?status=PENDING&page=1&page_size=20
+func (h *ProductScheduleHandler) GetProductSchedules(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid product ID",
+		})
+		return
+	}
+
+	h.listSchedules(c, productID)
+}
+
+// ListSchedules lists schedule entries across all products
+// GET /api/admin/product-schedules?status=PENDING&product_id=1&page=1&page_size=20
+func (h *ProductScheduleHandler) ListSchedules(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Query("product_id"))
+	h.listSchedules(c, productID)
+}
+
+func (h *ProductScheduleHandler) listSchedules(c *gin.Context, productID int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := models.ProductScheduleFilter{
+		ProductID: productID,
+		Status:    models.ProductScheduleStatus(strings.ToUpper(c.Query("status"))),
+		Limit:     pageSize,
+		Offset:    (page - 1) * pageSize,
+	}
+
+	schedules, total, err := h.scheduleService.ListSchedules(filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules":   schedules,
+		"total_count": total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// CancelSchedule withdraws a pending entry or ends a running sale immediately
+// POST /api/admin/product-schedules/:id/cancel
+func (h *ProductScheduleHandler) CancelSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid schedule ID",
+		})
+		return
+	}
+
+	schedule, err := h.scheduleService.CancelSchedule(id, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *ProductScheduleHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrProductScheduleNotFound, err == service.ErrProductNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrProductScheduleConflict:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "schedule_conflict", Message: err.Error()})
+	case err == service.ErrScheduleNotCancellable:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "not_cancellable", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidProductSchedule):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandler struct {
+	promotionService service.PromotionService
+}
+
+func NewPromotionHandler(promotionService service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: promotionService,
+	}
+}
+
+// ListPromotions returns every automatic promotion
+// GET /api/admin/promotions
+func (h *PromotionHandler) ListPromotions(c *gin.Context) {
+	promotions, err := h.promotionService.ListPromotions()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+}
+
+// GetPromotion returns a promotion with its flash-sale prices
+// GET /api/admin/promotions/:id
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	promotion, err := h.promotionService.GetPromotion(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// CreatePromotion creates a promotion; it applies to carts as soon as it is running
+// POST /api/admin/promotions
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var req dto.SavePromotionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	promotion, err := h.promotionService.CreatePromotion(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// UpdatePromotion replaces a promotion and its flash-sale prices
+// PUT /api/admin/promotions/:id
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SavePromotionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	promotion, err := h.promotionService.UpdatePromotion(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion deletes a promotion; orders keep the savings they were placed with
+// DELETE /api/admin/promotions/:id
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.promotionService.DeletePromotion(id, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted"})
+}
+
+func (h *PromotionHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid promotion ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *PromotionHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *PromotionHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrPromotionNotFound, err == service.ErrCategoryNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidPromotion):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_promotion", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PurchaseOrderHandler struct {
+	poService service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(poService service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{
+		poService: poService,
+	}
+}
+
+// ListSuppliers lists suppliers by name
+// GET /api/admin/suppliers?active_only=true
+func (h *PurchaseOrderHandler) ListSuppliers(c *gin.Context) {
+	suppliers, err := h.poService.ListSuppliers(c.Query("active_only") == "true")
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suppliers": suppliers})
+}
+
+// CreateSupplier adds a supplier
+// POST /api/admin/suppliers
+func (h *PurchaseOrderHandler) CreateSupplier(c *gin.Context) {
+	var req dto.SupplierRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	supplier, err := h.poService.CreateSupplier(req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, supplier)
+}
+
+// UpdateSupplier replaces a supplier's details
+// PUT /api/admin/suppliers/:id
+func (h *PurchaseOrderHandler) UpdateSupplier(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	var req dto.SupplierRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	supplier, err := h.poService.UpdateSupplier(id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// ListPurchaseOrders lists purchase orders, newest first
+// GET /api/admin/purchase-orders?status=SENT&supplier_id=&variant_id=&page=1&page_size=20
+func (h *PurchaseOrderHandler) ListPurchaseOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := models.PurchaseOrderFilter{
+		Status: models.PurchaseOrderStatus(strings.ToUpper(c.Query("status"))),
+	}
+	filter.SupplierID, _ = strconv.Atoi(c.Query("supplier_id"))
+	filter.VariantID, _ = strconv.Atoi(c.Query("variant_id"))
+
+	orders, total, err := h.poService.ListPurchaseOrders(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"purchase_orders": orders,
+		"total_count":     total,
+		"page":            page,
+		"page_size":       pageSize,
+	})
+}
+
+// GetPurchaseOrder returns a purchase order with its lines and receipts
+// GET /api/admin/purchase-orders/:id
+func (h *PurchaseOrderHandler) GetPurchaseOrder(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	po, err := h.poService.GetPurchaseOrder(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, po)
+}
+
+// CreatePurchaseOrder drafts a purchase order
+// POST /api/admin/purchase-orders
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(c *gin.Context) {
+	var req dto.PurchaseOrderRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	po, err := h.poService.CreatePurchaseOrder(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, po)
+}
+
+// UpdatePurchaseOrder replaces a draft's supplier, warehouse, dates and lines
+// PUT /api/admin/purchase-orders/:id
+func (h *PurchaseOrderHandler) UpdatePurchaseOrder(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	var req dto.PurchaseOrderRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	po, err := h.poService.UpdatePurchaseOrder(id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, po)
+}
+
+// UpdatePurchaseOrderStatus sends or cancels a purchase order
+// PATCH /api/admin/purchase-orders/:id/status
+func (h *PurchaseOrderHandler) UpdatePurchaseOrderStatus(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdatePurchaseOrderStatusRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	po, err := h.poService.UpdateStatus(id, models.PurchaseOrderStatus(req.Status), c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, po)
+}
+
+// ReceivePurchaseOrder books a full or partial delivery; stock is added to the PO's warehouse
+// POST /api/admin/purchase-orders/:id/receipts
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	var req dto.ReceivePurchaseOrderRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	po, err := h.poService.Receive(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, po)
+}
+
+func (h *PurchaseOrderHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *PurchaseOrderHandler) parseID(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *PurchaseOrderHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrSupplierNotFound, err == service.ErrPurchaseOrderNotFound,
+		err == service.ErrWarehouseNotFound, err == service.ErrVariantNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrDuplicateSupplier:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "duplicate_supplier", Message: err.Error()})
+	case errors.Is(err, service.ErrPurchaseOrderStatus):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "invalid_status", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidPurchaseOrder):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
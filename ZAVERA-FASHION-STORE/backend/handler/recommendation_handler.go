@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationHandler struct {
+	recommendationService service.RecommendationService
+}
+
+func NewRecommendationHandler(recommendationService service.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{
+		recommendationService: recommendationService,
+	}
+}
+
+// GetRecommendations returns frequently bought together, similar items and complete the look
+// GET /api/products/:id/recommendations?limit=8
+func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
+	id, ok := parseRecommendationProductID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "8"))
+
+	recommendations, err := h.recommendationService.GetRecommendations(id, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendations)
+}
+
+// GetLookLinks returns the curated "complete the look" products
+// GET /api/admin/products/:id/look-links
+func (h *RecommendationHandler) GetLookLinks(c *gin.Context) {
+	id, ok := parseRecommendationProductID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.recommendationService.GetLookLinks(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// SetLookLinks replaces the curated "complete the look" products, in display order
+// PUT /api/admin/products/:id/look-links
+func (h *RecommendationHandler) SetLookLinks(c *gin.Context) {
+	id, ok := parseRecommendationProductID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SetLookLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	links, err := h.recommendationService.SetLookLinks(id, req.ProductIDs)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RefreshCoPurchases rebuilds the "frequently bought together" matrix now instead of waiting for the job
+// POST /api/admin/recommendations/refresh
+func (h *RecommendationHandler) RefreshCoPurchases(c *gin.Context) {
+	pairs, err := h.recommendationService.RefreshCoPurchases()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecommendationRefreshResponse{PairsStored: pairs})
+}
+
+func parseRecommendationProductID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid product ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *RecommendationHandler) handleError(c *gin.Context, err error) {
+	if err == service.ErrProductNotFound {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: "Product not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	reviewService service.ReviewService
+}
+
+func NewReviewHandler(reviewService service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+	}
+}
+
+// getUserID gets user ID from context (JWT stores it as float64)
+func (h *ReviewHandler) getUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+
+	switch v := userID.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// GetProductReviews returns approved reviews with the rating and fit breakdown
+// GET /api/products/:id/reviews?sort=newest|highest|lowest&rating=5&with_photos=true&limit=10&offset=0
+func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid product ID",
+		})
+		return
+	}
+
+	filter := models.ReviewListFilter{
+		Sort:       c.DefaultQuery("sort", "newest"),
+		WithPhotos: c.Query("with_photos") == "true",
+	}
+	filter.Rating, _ = strconv.Atoi(c.Query("rating"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	response, err := h.reviewService.GetProductReviews(productID, filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPendingReviews lists delivered items the customer can still review
+// GET /api/user/reviews/pending
+func (h *ReviewHandler) GetPendingReviews(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "unauthorized", Message: "User not authenticated"})
+		return
+	}
+
+	items, err := h.reviewService.GetReviewableItems(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// GetMyReviews lists the customer's reviews with their moderation status
+// GET /api/user/reviews
+func (h *ReviewHandler) GetMyReviews(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "unauthorized", Message: "User not authenticated"})
+		return
+	}
+
+	reviews, err := h.reviewService.GetUserReviews(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// CreateReview submits a review for a delivered order item
+// POST /api/user/reviews
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "unauthorized", Message: "User not authenticated"})
+		return
+	}
+
+	var req dto.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	review, err := h.reviewService.CreateReview(userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// UploadReviewPhoto uploads one review photo to Cloudinary (multipart field "image")
+// POST /api/user/reviews/photos
+func (h *ReviewHandler) UploadReviewPhoto(c *gin.Context) {
+	file, fileHeader, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_file",
+			Message: "No file uploaded",
+		})
+		return
+	}
+	defer file.Close()
+
+	imageURL, err := h.reviewService.UploadReviewPhoto(file, fileHeader)
+	if err != nil {
+		if err == service.ErrPhotoUploadUnavailable {
+			c.JSON(http.StatusServiceUnavailable, dto.ErrorResponse{Error: "upload_unavailable", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "upload_failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ReviewPhotoUploadResponse{ImageURL: imageURL})
+}
+
+// ListReviews returns reviews for moderation (default: pending, oldest first)
+// GET /api/admin/reviews?status=PENDING&product_id=1&page=1&page_size=20
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	filter := models.ReviewListFilter{
+		Status: models.ReviewStatus(strings.ToUpper(c.DefaultQuery("status", string(models.ReviewStatusPending)))),
+		Sort:   c.Query("sort"),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+	if filter.Status == "ALL" {
+		filter.Status = ""
+	}
+	filter.ProductID, _ = strconv.Atoi(c.Query("product_id"))
+	filter.Rating, _ = strconv.Atoi(c.Query("rating"))
+
+	reviews, total, err := h.reviewService.ListReviewsAdmin(filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":     reviews,
+		"total_count": total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// ModerateReview approves or rejects a review
+// PUT /api/admin/reviews/:id/moderate
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid review ID",
+		})
+		return
+	}
+
+	var req dto.ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	review, err := h.reviewService.ModerateReview(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *ReviewHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrReviewNotFound, service.ErrProductNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case service.ErrReviewNotAllowed:
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{Error: "review_not_allowed", Message: err.Error()})
+	case service.ErrAlreadyReviewed:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "already_reviewed", Message: err.Error()})
+	case service.ErrInvalidReviewPhoto:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SizeChartHandler struct {
+	sizeChartService service.SizeChartService
+}
+
+func NewSizeChartHandler(sizeChartService service.SizeChartService) *SizeChartHandler {
+	return &SizeChartHandler{
+		sizeChartService: sizeChartService,
+	}
+}
+
+// GetProductSizeChart returns the size chart that applies to a product
+// GET /api/products/:id/size-chart
+func (h *SizeChartHandler) GetProductSizeChart(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	chart, err := h.sizeChartService.GetProductChart(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chart)
+}
+
+// GetSizeRecommendation recommends a size of a product from the customer's fit profile
+// GET /api/user/size-recommendations/:productId
+func (h *SizeChartHandler) GetSizeRecommendation(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	productID, ok := h.parseID(c, "productId", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	recommendation, err := h.sizeChartService.Recommend(userID, productID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendation)
+}
+
+// GetFitProfile returns the customer's fit profile
+// GET /api/user/fit-profile
+func (h *SizeChartHandler) GetFitProfile(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.sizeChartService.GetFitProfile(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// SaveFitProfile replaces the customer's fit profile
+// PUT /api/user/fit-profile
+func (h *SizeChartHandler) SaveFitProfile(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SaveFitProfileRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	profile, err := h.sizeChartService.SaveFitProfile(userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListSizeCharts returns every size chart with its rows
+// GET /api/admin/size-charts
+func (h *SizeChartHandler) ListSizeCharts(c *gin.Context) {
+	charts, err := h.sizeChartService.ListCharts()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"size_charts": charts})
+}
+
+// GetSizeChart returns a size chart with its rows
+// GET /api/admin/size-charts/:id
+func (h *SizeChartHandler) GetSizeChart(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "Invalid size chart ID")
+	if !ok {
+		return
+	}
+
+	chart, err := h.sizeChartService.GetChart(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chart)
+}
+
+// CreateSizeChart creates a size chart and links matching variant sizes to it
+// POST /api/admin/size-charts
+func (h *SizeChartHandler) CreateSizeChart(c *gin.Context) {
+	var req dto.SaveSizeChartRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	chart, err := h.sizeChartService.CreateChart(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, chart)
+}
+
+// UpdateSizeChart replaces a size chart and its rows
+// PUT /api/admin/size-charts/:id
+func (h *SizeChartHandler) UpdateSizeChart(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "Invalid size chart ID")
+	if !ok {
+		return
+	}
+
+	var req dto.SaveSizeChartRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	chart, err := h.sizeChartService.UpdateChart(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chart)
+}
+
+// DeleteSizeChart deletes a size chart; its variants fall back to a broader chart if any
+// DELETE /api/admin/size-charts/:id
+func (h *SizeChartHandler) DeleteSizeChart(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "Invalid size chart ID")
+	if !ok {
+		return
+	}
+
+	if err := h.sizeChartService.DeleteChart(id, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Size chart deleted"})
+}
+
+func (h *SizeChartHandler) getUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if exists {
+		switch v := userID.(type) {
+		case int:
+			return v, true
+		case int64:
+			return int(v), true
+		case float64:
+			return int(v), true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		Error:   "unauthorized",
+		Message: "User not authenticated",
+	})
+	return 0, false
+}
+
+func (h *SizeChartHandler) parseID(c *gin.Context, param, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *SizeChartHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *SizeChartHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrProductNotFound, err == service.ErrSizeChartNotFound,
+		err == service.ErrFitProfileNotFound, err == service.ErrCategoryNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrNoSizeRecommendation:
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{Error: "no_recommendation", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidSizeChart):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_size_chart", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StockLedgerHandler struct {
+	stockLedgerService service.StockLedgerService
+}
+
+func NewStockLedgerHandler(stockLedgerService service.StockLedgerService) *StockLedgerHandler {
+	return &StockLedgerHandler{
+		stockLedgerService: stockLedgerService,
+	}
+}
+
+// ListLedger lists stock ledger entries, newest first
+// GET /api/admin/stock-ledger?variant_id=&product_id=&warehouse_id=&order_id=&type=&page=1&page_size=50
+func (h *StockLedgerHandler) ListLedger(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	filter := models.StockLedgerFilter{
+		MovementType: models.StockMovementType(strings.ToUpper(c.Query("type"))),
+	}
+	filter.VariantID, _ = strconv.Atoi(c.Query("variant_id"))
+	filter.ProductID, _ = strconv.Atoi(c.Query("product_id"))
+	filter.WarehouseID, _ = strconv.Atoi(c.Query("warehouse_id"))
+	filter.OrderID, _ = strconv.Atoi(c.Query("order_id"))
+
+	entries, total, err := h.stockLedgerService.ListLedger(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total_count": total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// GetReconciliation reports where on-hand counts disagree with the ledger (dry run; use
+// tools/reconcile_stock --apply to rebuild)
+// GET /api/admin/stock-ledger/reconciliation
+func (h *StockLedgerHandler) GetReconciliation(c *gin.Context) {
+	result, err := h.stockLedgerService.Reconcile(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
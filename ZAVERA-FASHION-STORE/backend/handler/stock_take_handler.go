@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StockTakeHandler struct {
+	stockTakeService service.StockTakeService
+}
+
+func NewStockTakeHandler(stockTakeService service.StockTakeService) *StockTakeHandler {
+	return &StockTakeHandler{
+		stockTakeService: stockTakeService,
+	}
+}
+
+// ListStockTakes lists count sessions, newest first
+// GET /api/admin/stock-takes?warehouse_id=&status=REVIEW&page=1&page_size=20
+func (h *StockTakeHandler) ListStockTakes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	warehouseID, _ := strconv.Atoi(c.Query("warehouse_id"))
+	status := models.StockTakeStatus(strings.ToUpper(c.Query("status")))
+
+	takes, total, err := h.stockTakeService.List(warehouseID, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock_takes": takes,
+		"total_count": total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// StartStockTake freezes expected quantities and opens a count
+// POST /api/admin/stock-takes
+func (h *StockTakeHandler) StartStockTake(c *gin.Context) {
+	var req dto.StartStockTakeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	report, err := h.stockTakeService.Start(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetStockTake returns the variance report of a count
+// GET /api/admin/stock-takes/:id?variances_only=true
+func (h *StockTakeHandler) GetStockTake(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.stockTakeService.GetReport(id, c.Query("variances_only") == "true")
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RecordCounts sets counted quantities by variant, SKU or barcode
+// PUT /api/admin/stock-takes/:id/counts
+func (h *StockTakeHandler) RecordCounts(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.StockTakeCountsRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	lines, err := h.stockTakeService.RecordCounts(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lines": lines})
+}
+
+// ScanBarcode adds a scanned unit to a count
+// POST /api/admin/stock-takes/:id/scans
+func (h *StockTakeHandler) ScanBarcode(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.StockTakeScanRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	line, err := h.stockTakeService.Scan(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, line)
+}
+
+// SetApproval includes or excludes variances from posting
+// PUT /api/admin/stock-takes/:id/approvals
+func (h *StockTakeHandler) SetApproval(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.StockTakeApprovalRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	updated, err := h.stockTakeService.SetApproval(id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// UpdateStockTakeStatus submits a count for review, reopens it or cancels it
+// PATCH /api/admin/stock-takes/:id/status
+func (h *StockTakeHandler) UpdateStockTakeStatus(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateStockTakeStatusRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	take, err := h.stockTakeService.UpdateStatus(id, models.StockTakeStatus(req.Status), c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, take)
+}
+
+// PostStockTake writes the approved variances to the stock ledger
+// POST /api/admin/stock-takes/:id/post
+func (h *StockTakeHandler) PostStockTake(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.stockTakeService.Post(id, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *StockTakeHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *StockTakeHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid stock take ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *StockTakeHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrStockTakeNotFound, err == service.ErrWarehouseNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrStockTakeOpen:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "stock_take_open", Message: err.Error()})
+	case errors.Is(err, service.ErrStockTakeStatus):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "invalid_status", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidStockTake):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
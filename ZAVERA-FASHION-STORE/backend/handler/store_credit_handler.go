@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StoreCreditHandler struct {
+	storeCreditService service.StoreCreditService
+}
+
+func NewStoreCreditHandler(storeCreditService service.StoreCreditService) *StoreCreditHandler {
+	return &StoreCreditHandler{
+		storeCreditService: storeCreditService,
+	}
+}
+
+// GetWallet returns the customer's store credit balance and latest ledger entries
+// GET /api/user/store-credit
+func (h *StoreCreditHandler) GetWallet(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	wallet, err := h.storeCreditService.GetWallet(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wallet)
+}
+
+// RedeemGiftCard adds a gift card's amount to the customer's store credit
+// POST /api/user/store-credit/redeem
+func (h *StoreCreditHandler) RedeemGiftCard(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RedeemGiftCardRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	result, err := h.storeCreditService.RedeemGiftCard(userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// AdjustBalance credits or debits a customer's store credit, with a note for the ledger
+// POST /api/admin/users/:id/store-credit
+func (h *StoreCreditHandler) AdjustBalance(c *gin.Context) {
+	userID, ok := h.parseID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	var req dto.AdjustStoreCreditRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	entry, err := h.storeCreditService.AdjustBalance(userID, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// ListGiftCards returns a page of issued gift cards, optionally by status
+// GET /api/admin/gift-cards?status=ACTIVE&page=1&page_size=20
+func (h *StoreCreditHandler) ListGiftCards(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	cards, err := h.storeCreditService.ListGiftCards(c.Query("status"), page, pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cards)
+}
+
+// VoidGiftCard stops an unredeemed gift card from being redeemed
+// POST /api/admin/gift-cards/:id/void
+func (h *StoreCreditHandler) VoidGiftCard(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid gift card ID")
+	if !ok {
+		return
+	}
+
+	if err := h.storeCreditService.VoidGiftCard(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "status": "VOID"})
+}
+
+// SetGiftCardProduct makes a product a gift card, or an ordinary product again
+// PUT /api/admin/products/:id/gift-card
+func (h *StoreCreditHandler) SetGiftCardProduct(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req dto.SetGiftCardProductRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.storeCreditService.SetGiftCardProduct(id, *req.IsGiftCard, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product_id": id, "is_gift_card": *req.IsGiftCard})
+}
+
+func (h *StoreCreditHandler) getUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if exists {
+		switch v := userID.(type) {
+		case int:
+			return v, true
+		case int64:
+			return int(v), true
+		case float64:
+			return int(v), true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		Error:   "unauthorized",
+		Message: "User not authenticated",
+	})
+	return 0, false
+}
+
+func (h *StoreCreditHandler) parseID(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *StoreCreditHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *StoreCreditHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrUserNotFound, err == service.ErrGiftCardNotFound, err == service.ErrGiftCardProduct:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrGiftCardUnavailable):
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{Error: "gift_card_unavailable", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidAdjustment):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_adjustment", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	taxService service.TaxService
+}
+
+func NewTaxHandler(taxService service.TaxService) *TaxHandler {
+	return &TaxHandler{
+		taxService: taxService,
+	}
+}
+
+// GetSettings returns the PPN setting in effect and every past and scheduled one
+// GET /api/admin/tax/settings
+func (h *TaxHandler) GetSettings(c *gin.Context) {
+	settings, err := h.taxService.GetSettings()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ScheduleSetting schedules a new PPN rate or pricing mode from a future date
+// POST /api/admin/tax/settings
+func (h *TaxHandler) ScheduleSetting(c *gin.Context) {
+	var req dto.ScheduleTaxSettingRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	setting, err := h.taxService.ScheduleSetting(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, setting)
+}
+
+// DeleteSetting cancels a setting that has not taken effect yet
+// DELETE /api/admin/tax/settings/:id
+func (h *TaxHandler) DeleteSetting(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid tax setting ID")
+	if !ok {
+		return
+	}
+
+	if err := h.taxService.DeleteSetting(id, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax setting deleted"})
+}
+
+// ListExemptProducts returns the products sold without PPN
+// GET /api/admin/tax/exempt-products
+func (h *TaxHandler) ListExemptProducts(c *gin.Context) {
+	products, err := h.taxService.ListExemptProducts()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// SetProductExempt exempts a product from PPN on new orders, or makes it taxable again
+// PUT /api/admin/products/:id/tax-exempt
+func (h *TaxHandler) SetProductExempt(c *gin.Context) {
+	id, ok := h.parseID(c, "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req dto.SetTaxExemptRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.taxService.SetProductExempt(id, *req.TaxExempt, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product_id": id, "tax_exempt": *req.TaxExempt})
+}
+
+func (h *TaxHandler) parseID(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *TaxHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *TaxHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrTaxSettingNotFound, err == service.ErrProductNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidTaxSetting):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_tax_setting", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
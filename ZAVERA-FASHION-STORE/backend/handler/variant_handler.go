@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/service"
@@ -80,6 +84,11 @@ func (h *VariantHandler) CreateVariant(c *gin.Context) {
 		variant.HeightCm = req.Height
 	}
 
+	if err := applyInventoryPolicy(variant, req.InventoryPolicy, req.BackorderLimit, req.ExpectedShipDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	log.Printf("🔧 Creating variant in database...")
 	if err := h.variantService.CreateVariant(variant); err != nil {
 		log.Printf("❌ Variant creation failed: %v", err)
@@ -155,6 +164,14 @@ func (h *VariantHandler) UpdateVariant(c *gin.Context) {
 		variant.HeightCm = req.Height
 	}
 
+	// Older clients do not send the policy; keep what the variant has
+	if req.InventoryPolicy != "" {
+		if err := applyInventoryPolicy(variant, req.InventoryPolicy, req.BackorderLimit, req.ExpectedShipDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	if err := h.variantService.UpdateVariant(variant); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -163,6 +180,21 @@ func (h *VariantHandler) UpdateVariant(c *gin.Context) {
 	c.JSON(http.StatusOK, variant)
 }
 
+// applyInventoryPolicy sets the pre-order/backorder settings of a create or update request
+func applyInventoryPolicy(variant *models.ProductVariant, policy string, backorderLimit *int, expectedShipDate *string) error {
+	variant.InventoryPolicy = models.InventoryPolicy(strings.ToUpper(strings.TrimSpace(policy)))
+	variant.BackorderLimit = backorderLimit
+	variant.ExpectedShipDate = nil
+	if expectedShipDate != nil && *expectedShipDate != "" {
+		date, err := time.Parse("2006-01-02", *expectedShipDate)
+		if err != nil {
+			return fmt.Errorf("invalid expected_ship_date, use YYYY-MM-DD")
+		}
+		variant.ExpectedShipDate = &date
+	}
+	return nil
+}
+
 func (h *VariantHandler) GetVariant(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -239,9 +271,21 @@ func (h *VariantHandler) BulkGenerateVariants(c *gin.Context) {
 		return
 	}
 
-	if len(req.Sizes) == 0 || len(req.Colors) == 0 {
-		log.Printf("❌ BulkGenerate validation error: sizes=%v, colors=%v", req.Sizes, req.Colors)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Sizes and colors are required"})
+	// Sizes and colors are the first two options when given
+	dimensions := []models.VariantOptionDimension{}
+	if len(req.Sizes) > 0 {
+		dimensions = append(dimensions, models.VariantOptionDimension{Name: "size", Values: req.Sizes})
+	}
+	if len(req.Colors) > 0 {
+		dimensions = append(dimensions, models.VariantOptionDimension{Name: "color", Values: req.Colors})
+	}
+	for _, option := range req.Options {
+		dimensions = append(dimensions, models.VariantOptionDimension{Name: option.Name, Values: option.Values})
+	}
+
+	if len(dimensions) == 0 {
+		log.Printf("❌ BulkGenerate validation error: no options")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one option (sizes, colors or options) is required"})
 		return
 	}
 
@@ -256,13 +300,12 @@ func (h *VariantHandler) BulkGenerateVariants(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🔧 BulkGenerate request: product_id=%d, sizes=%v, colors=%v, price=%.2f, stock=%d, weight=%d, length=%d, width=%d, height=%d",
-		req.ProductID, req.Sizes, req.Colors, req.BasePrice, req.StockPerVariant, req.Weight, req.Length, req.Width, req.Height)
+	log.Printf("🔧 BulkGenerate request: product_id=%d, options=%v, price=%.2f, stock=%d, weight=%d, length=%d, width=%d, height=%d",
+		req.ProductID, dimensions, req.BasePrice, req.StockPerVariant, req.Weight, req.Length, req.Width, req.Height)
 
 	err := h.variantService.BulkGenerateVariants(
 		req.ProductID,
-		req.Sizes,
-		req.Colors,
+		dimensions,
 		req.BasePrice,
 		req.StockPerVariant,
 		req.Weight,
@@ -273,7 +316,11 @@ func (h *VariantHandler) BulkGenerateVariants(c *gin.Context) {
 
 	if err != nil {
 		log.Printf("❌ BulkGenerate service error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidVariantOptions) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -392,22 +439,19 @@ func (h *VariantHandler) CheckAvailability(c *gin.Context) {
 		return
 	}
 
-	available, err := h.variantService.CheckAvailability(req.VariantID, req.Quantity)
+	availability, err := h.variantService.CheckAvailability(req.VariantID, req.Quantity)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	variant, _ := h.variantService.GetVariant(req.VariantID)
-	availableStock := 0
-	if variant != nil && variant.AvailableStock != nil {
-		availableStock = *variant.AvailableStock
-	}
-
 	c.JSON(http.StatusOK, dto.CheckAvailabilityResponse{
-		Available:      available,
-		AvailableStock: availableStock,
-		RequestedStock: req.Quantity,
+		Available:         availability.Available,
+		AvailableStock:    availability.AvailableStock,
+		RequestedStock:    req.Quantity,
+		InventoryPolicy:   string(availability.InventoryPolicy),
+		BackorderQuantity: availability.BackorderQuantity,
+		ExpectedShipDate:  dto.FormatDatePtr(availability.ExpectedShipDate),
 	})
 }
 
@@ -423,6 +467,7 @@ func (h *VariantHandler) ReserveStock(c *gin.Context) {
 		req.CustomerID,
 		req.SessionID,
 		req.Quantity,
+		req.WarehouseID,
 	)
 
 	if err != nil {
@@ -450,7 +495,7 @@ func (h *VariantHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 
-	if err := h.variantService.UpdateStock(variantID, req.Quantity); err != nil {
+	if err := h.variantService.UpdateStock(variantID, req.Quantity, req.Reason, c.GetString("user_email")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -475,7 +520,7 @@ func (h *VariantHandler) AdjustStock(c *gin.Context) {
 		return
 	}
 
-	if err := h.variantService.AdjustStock(variantID, req.Delta); err != nil {
+	if err := h.variantService.AdjustStock(variantID, req.Delta, req.Reason, c.GetString("user_email")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -523,6 +568,28 @@ func (h *VariantHandler) GetVariantAttributes(c *gin.Context) {
 	c.JSON(http.StatusOK, attributes)
 }
 
+// CreateVariantAttribute adds an option variants can differ on, e.g. length
+// POST /api/admin/variants/attributes
+func (h *VariantHandler) CreateVariantAttribute(c *gin.Context) {
+	var req dto.CreateVariantAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute, err := h.variantService.CreateVariantAttribute(req.Name, req.DisplayName, req.Type, req.Options, req.SortOrder)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidVariantOptions) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attribute)
+}
+
 func (h *VariantHandler) GetProductWithVariants(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -546,13 +613,32 @@ func (h *VariantHandler) GetAvailableOptions(c *gin.Context) {
 		return
 	}
 
-	options, err := h.variantService.GetAvailableOptions(productID)
+	matrix, err := h.variantService.GetAvailableOptions(productID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, options)
+	// The size chart rides along so the picker can show measurements per size
+	chart, err := h.variantService.GetSizeChart(productID)
+	if err != nil {
+		log.Printf("⚠️ Size chart lookup failed for product %d: %v", productID, err)
+	}
+
+	// Each option's values stay top-level ("size": [...]) for existing clients; dimensions gives
+	// their display order and unavailable the combinations to grey out
+	response := gin.H{
+		"dimensions":  matrix.Dimensions,
+		"unavailable": matrix.Unavailable,
+	}
+	for _, d := range matrix.Dimensions {
+		response[d.Name] = d.Values
+	}
+	if chart != nil {
+		response["size_chart"] = chart
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *VariantHandler) FindVariant(c *gin.Context) {
@@ -562,16 +648,20 @@ func (h *VariantHandler) FindVariant(c *gin.Context) {
 		return
 	}
 
-	size := ""
-	color := ""
-	if req.Size != nil {
-		size = *req.Size
+	options := map[string]string{}
+	for name, value := range req.Options {
+		if value != "" {
+			options[name] = value
+		}
+	}
+	if req.Size != nil && *req.Size != "" {
+		options["size"] = *req.Size
 	}
-	if req.Color != nil {
-		color = *req.Color
+	if req.Color != nil && *req.Color != "" {
+		options["color"] = *req.Color
 	}
 
-	variant, err := h.variantService.FindVariantByAttributes(req.ProductID, size, color)
+	variant, err := h.variantService.FindVariantByAttributes(req.ProductID, options)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
 		return
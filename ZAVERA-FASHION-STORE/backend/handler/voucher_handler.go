@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VoucherHandler struct {
+	voucherService service.VoucherService
+}
+
+func NewVoucherHandler(voucherService service.VoucherService) *VoucherHandler {
+	return &VoucherHandler{
+		voucherService: voucherService,
+	}
+}
+
+// ValidateVoucher previews a voucher code against the current cart without redeeming it
+// POST /api/vouchers/validate
+func (h *VoucherHandler) ValidateVoucher(c *gin.Context) {
+	var req dto.ValidateVoucherRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	sessionID, err := c.Cookie("session_id")
+	if err != nil || sessionID == "" {
+		sessionID = c.GetHeader("X-Session-ID")
+	}
+
+	preview, err := h.voucherService.Preview(sessionID, h.optionalUserID(c), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// ListVouchers returns every voucher
+// GET /api/admin/vouchers
+func (h *VoucherHandler) ListVouchers(c *gin.Context) {
+	vouchers, err := h.voucherService.ListVouchers()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vouchers": vouchers})
+}
+
+// GetVoucher returns a voucher with its redemptions
+// GET /api/admin/vouchers/:id
+func (h *VoucherHandler) GetVoucher(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	voucher, err := h.voucherService.GetVoucher(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, voucher)
+}
+
+// CreateVoucher creates a voucher
+// POST /api/admin/vouchers
+func (h *VoucherHandler) CreateVoucher(c *gin.Context) {
+	var req dto.SaveVoucherRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	voucher, err := h.voucherService.CreateVoucher(req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, voucher)
+}
+
+// UpdateVoucher replaces a voucher's settings; its usage count is kept
+// PUT /api/admin/vouchers/:id
+func (h *VoucherHandler) UpdateVoucher(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SaveVoucherRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	voucher, err := h.voucherService.UpdateVoucher(id, req, c.GetString("user_email"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, voucher)
+}
+
+// optionalUserID returns the signed-in customer, or nil for guests
+func (h *VoucherHandler) optionalUserID(c *gin.Context) *int {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	var id int
+	switch v := userID.(type) {
+	case int:
+		id = v
+	case int64:
+		id = int(v)
+	case float64:
+		id = int(v)
+	default:
+		return nil
+	}
+	return &id
+}
+
+func (h *VoucherHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid voucher ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *VoucherHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (h *VoucherHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrVoucherNotFound, err == service.ErrCategoryNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrCartEmpty:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "cart_empty", Message: "Your cart is empty"})
+	case err == service.ErrVoucherCodeTaken:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "voucher_code_taken", Message: err.Error()})
+	case errors.Is(err, service.ErrVoucherNotApplicable):
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{Error: "voucher_not_applicable", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidVoucher):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_voucher", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
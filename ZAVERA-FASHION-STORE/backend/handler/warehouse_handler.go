@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zavera/dto"
+	"zavera/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WarehouseHandler struct {
+	warehouseService service.WarehouseService
+}
+
+func NewWarehouseHandler(warehouseService service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{
+		warehouseService: warehouseService,
+	}
+}
+
+// ListWarehouses lists all warehouses, default first
+// GET /api/admin/warehouses?active_only=true
+func (h *WarehouseHandler) ListWarehouses(c *gin.Context) {
+	warehouses, err := h.warehouseService.ListWarehouses(c.Query("active_only") == "true")
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warehouses": warehouses})
+}
+
+// CreateWarehouse adds a stock location
+// POST /api/admin/warehouses
+func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
+	var req dto.WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	warehouse, err := h.warehouseService.CreateWarehouse(req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+// UpdateWarehouse replaces a warehouse's details
+// PUT /api/admin/warehouses/:id
+func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	warehouse, err := h.warehouseService.UpdateWarehouse(id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// GetStock lists variant stock at a warehouse
+// GET /api/admin/warehouses/:id/stock?search=&page=1&page_size=50
+func (h *WarehouseHandler) GetStock(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	stock, total, err := h.warehouseService.ListStock(id, c.Query("search"), pageSize, (page-1)*pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock":       stock,
+		"total_count": total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// SetStock sets the on-hand quantity of a variant at a warehouse
+// PUT /api/admin/warehouses/:id/stock
+func (h *WarehouseHandler) SetStock(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SetWarehouseStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.warehouseService.SetStock(id, req, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock updated"})
+}
+
+// TransferStock moves stock between warehouses
+// POST /api/admin/warehouses/transfers
+func (h *WarehouseHandler) TransferStock(c *gin.Context) {
+	var req dto.TransferStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.warehouseService.TransferStock(req, c.GetString("user_email")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock transferred"})
+}
+
+func (h *WarehouseHandler) parseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid warehouse ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *WarehouseHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err == service.ErrWarehouseNotFound, err == service.ErrVariantNotFound:
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found", Message: err.Error()})
+	case err == service.ErrDuplicateWarehouse:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "duplicate_warehouse", Message: err.Error()})
+	case err == service.ErrInsufficientWarehouseStock:
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: "insufficient_stock", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidWarehouse):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error", Message: err.Error()})
+	}
+}
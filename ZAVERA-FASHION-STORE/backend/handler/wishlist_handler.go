@@ -243,3 +243,124 @@ func (h *WishlistHandler) MoveToCart(c *gin.Context) {
 	log.Printf("✅ MoveToCart success - Cart has %d items", len(cart.Items))
 	c.JSON(http.StatusOK, cart)
 }
+
+// GetStockAlerts godoc
+// @Summary Get back-in-stock subscriptions
+// @Description List the sizes/colors the user asked to be emailed about (requires authentication)
+// @Tags wishlist
+// @Produce json
+// @Success 200 {array} models.StockAlert
+// @Router /api/wishlist/alerts [get]
+func (h *WishlistHandler) GetStockAlerts(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Please login to view stock alerts",
+		})
+		return
+	}
+
+	alerts, err := h.wishlistService.GetStockAlerts(*userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// SubscribeToVariant godoc
+// @Summary Subscribe to a sold-out variant
+// @Description Email the user when a sold-out size/color is available again (requires authentication)
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Param request body dto.StockAlertRequest true "Stock alert request"
+// @Success 201 {object} models.StockAlert
+// @Router /api/wishlist/alerts [post]
+func (h *WishlistHandler) SubscribeToVariant(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Please login to get stock alerts",
+		})
+		return
+	}
+
+	var req dto.StockAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	log.Printf("🔔 SubscribeToVariant - UserID: %d, VariantID: %d", *userID, req.VariantID)
+
+	alert, err := h.wishlistService.SubscribeToVariant(*userID, req.VariantID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrVariantNotFound {
+			status = http.StatusNotFound
+		} else if err == service.ErrVariantInStock {
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, dto.ErrorResponse{
+			Error:   "subscribe_failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// CancelStockAlert godoc
+// @Summary Cancel a back-in-stock subscription
+// @Description Stop a pending back-in-stock subscription (requires authentication)
+// @Tags wishlist
+// @Produce json
+// @Param id path int true "Stock alert ID"
+// @Success 200 {object} map[string]string
+// @Router /api/wishlist/alerts/{id} [delete]
+func (h *WishlistHandler) CancelStockAlert(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Please login to manage stock alerts",
+		})
+		return
+	}
+
+	alertID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid stock alert ID",
+		})
+		return
+	}
+
+	if err := h.wishlistService.CancelStockAlert(*userID, alertID); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrStockAlertNotFound {
+			status = http.StatusNotFound
+		}
+
+		c.JSON(status, dto.ErrorResponse{
+			Error:   "cancel_failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock alert cancelled"})
+}
@@ -54,8 +54,9 @@ func main() {
 		cartRepo := repository.NewCartRepository(db)
 		productRepo := repository.NewProductRepository(db)
 		orderRepo := repository.NewOrderRepository(db)
+		warehouseService := service.NewWarehouseService(repository.NewWarehouseRepository(db), repository.NewVariantRepository(db), repository.NewBundleRepository(db))
 		
-		trackingJob := service.NewTrackingJobRunner(shippingRepo, cartRepo, productRepo, orderRepo)
+		trackingJob := service.NewTrackingJobRunner(shippingRepo, cartRepo, productRepo, orderRepo, warehouseService)
 		trackingJob.Start()
 		defer trackingJob.Stop()
 		log.Println("📦 Tracking job scheduler enabled")
@@ -77,6 +78,45 @@ func main() {
 		defer paymentExpiryJob.Stop()
 	}
 
+	// Start recommendation refresh job (rebuilds "frequently bought together" pairs)
+	{
+		productRepo := repository.NewProductRepository(db)
+		recommendationRepo := repository.NewRecommendationRepository(db)
+		recommendationJob := service.NewRecommendationRefreshJob(service.NewRecommendationService(recommendationRepo, productRepo))
+		recommendationJob.Start()
+		defer recommendationJob.Stop()
+	}
+
+	// Start product schedule job (scheduled publishing, price changes and sales)
+	{
+		productRepo := repository.NewProductRepository(db)
+		scheduleService := service.NewProductScheduleService(db, repository.NewProductScheduleRepository(db), productRepo, repository.NewAdminAuditRepository(db))
+		scheduleJob := service.NewProductScheduleJob(scheduleService)
+		scheduleJob.Start()
+		defer scheduleJob.Stop()
+	}
+
+	// Start reservation sweeper (releases expired cart holds and stock reservations)
+	{
+		sweeperJob := service.NewReservationSweeperJob(repository.NewReservationRepository(db))
+		sweeperJob.Start()
+		defer sweeperJob.Stop()
+	}
+
+	// Start stock alert job (back-in-stock and price-drop emails for wishlists)
+	{
+		alertJob := service.NewStockAlertJob(repository.NewStockAlertRepository(db), service.NewEmailService(repository.NewEmailRepository(db)))
+		alertJob.Start()
+		defer alertJob.Stop()
+	}
+
+	// Start gift card job (issues and emails codes for paid gift card orders)
+	{
+		giftCardJob := service.NewGiftCardJob(repository.NewStoreCreditRepository(db), service.NewEmailService(repository.NewEmailRepository(db)))
+		giftCardJob.Start()
+		defer giftCardJob.Stop()
+	}
+
 	// Start server
 	log.Println("🚀 Server starting on :8080...")
 	if err := router.Run(":8080"); err != nil {
@@ -15,6 +15,7 @@ const (
 	AdminActionVoidRefund       AdminActionType = "VOID_REFUND"
 	AdminActionOverridePayment  AdminActionType = "OVERRIDE_PAYMENT"
 	AdminActionManualAdjustment AdminActionType = "MANUAL_ADJUSTMENT"
+	AdminActionProductSchedule  AdminActionType = "PRODUCT_SCHEDULE"
 )
 
 // AdminAuditLog represents an immutable admin action log entry
@@ -0,0 +1,90 @@
+package models
+
+import "math"
+
+// BundleComponent is a variant that is part of a bundle product
+type BundleComponent struct {
+	ID              int     `json:"id"`
+	BundleProductID int     `json:"bundle_product_id"`
+	VariantID       int     `json:"variant_id"`
+	ProductID       int     `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	VariantName     string  `json:"variant_name"`
+	SKU             string  `json:"sku"`
+	Quantity        int     `json:"quantity"` // Units in one bundle
+	Price           float64 `json:"price"`    // The variant's own price, used to split the bundle price
+	Stock           int     `json:"stock"`
+	IsActive        bool    `json:"is_active"`
+	Position        int     `json:"position"`
+}
+
+// BundleAvailable is how many complete bundles the components' stock makes up.
+// A bundle without components, or with an inactive one, is not available.
+func BundleAvailable(components []BundleComponent) int {
+	if len(components) == 0 {
+		return 0
+	}
+	available := math.MaxInt32
+	for _, c := range components {
+		if !c.IsActive || c.Quantity <= 0 || c.Stock <= 0 {
+			return 0
+		}
+		if sets := c.Stock / c.Quantity; sets < available {
+			available = sets
+		}
+	}
+	return available
+}
+
+// AllocateBundlePrice splits the price of one bundle across its components in proportion to
+// their own value (price × quantity), in whole rupiah. Rounding is absorbed by the last component
+// so the shares always add up to the bundle price. Components without a price split it by quantity.
+func AllocateBundlePrice(bundlePrice float64, components []BundleComponent) []float64 {
+	shares := make([]float64, len(components))
+	if len(components) == 0 {
+		return shares
+	}
+
+	weight := func(c BundleComponent) float64 { return c.Price * float64(c.Quantity) }
+	var total float64
+	for _, c := range components {
+		total += weight(c)
+	}
+	if total <= 0 {
+		weight = func(c BundleComponent) float64 { return float64(c.Quantity) }
+		total = 0
+		for _, c := range components {
+			total += weight(c)
+		}
+	}
+
+	allocated := 0.0
+	last := len(components) - 1
+	for i, c := range components[:last] {
+		shares[i] = math.Round(bundlePrice * weight(c) / total)
+		allocated += shares[i]
+	}
+	shares[last] = bundlePrice - allocated
+	return shares
+}
+
+// OrderItemComponent is a bundle component as sold on an order line
+type OrderItemComponent struct {
+	ID             int     `json:"id"`
+	OrderItemID    int     `json:"order_item_id"`
+	VariantID      int     `json:"variant_id"`
+	ProductID      int     `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	VariantName    string  `json:"variant_name"`
+	Quantity       int     `json:"quantity"`        // Units in one bundle
+	AllocatedPrice float64 `json:"allocated_price"` // Share of one bundle's price
+}
+
+// RefundAmount is what refunding units of the component pays out: its share of the bundle price
+// per unit, in whole rupiah
+func (c OrderItemComponent) RefundAmount(units int) float64 {
+	if c.Quantity <= 0 {
+		return 0
+	}
+	return math.Round(float64(units) * c.AllocatedPrice / float64(c.Quantity))
+}
@@ -0,0 +1,88 @@
+package models
+
+import "testing"
+
+func TestBundleAvailable(t *testing.T) {
+	top := BundleComponent{VariantID: 1, Quantity: 1, Stock: 7, IsActive: true}
+	socks := BundleComponent{VariantID: 2, Quantity: 2, Stock: 9, IsActive: true}
+	belt := BundleComponent{VariantID: 3, Quantity: 1, Stock: 0, IsActive: true}
+	retired := BundleComponent{VariantID: 4, Quantity: 1, Stock: 50, IsActive: false}
+
+	tests := []struct {
+		name       string
+		components []BundleComponent
+		expected   int
+	}{
+		{"no components", nil, 0},
+		{"single component", []BundleComponent{top}, 7},
+		{"limited by the scarcest component", []BundleComponent{top, socks}, 4},
+		{"one component sold out", []BundleComponent{top, socks, belt}, 0},
+		{"inactive component", []BundleComponent{top, retired}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := BundleAvailable(tt.components); got != tt.expected {
+			t.Errorf("%s: got %d, expected %d", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestAllocateBundlePrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64
+		components []BundleComponent
+		expected   []float64
+	}{
+		{
+			"proportional to component value",
+			450000,
+			[]BundleComponent{{Price: 300000, Quantity: 1}, {Price: 200000, Quantity: 1}, {Price: 50000, Quantity: 2}},
+			[]float64{225000, 150000, 75000},
+		},
+		{
+			"rounding lands on the last component",
+			100000,
+			[]BundleComponent{{Price: 10000, Quantity: 1}, {Price: 10000, Quantity: 1}, {Price: 10000, Quantity: 1}},
+			[]float64{33333, 33333, 33334},
+		},
+		{
+			"no prices split by quantity",
+			90000,
+			[]BundleComponent{{Quantity: 1}, {Quantity: 2}},
+			[]float64{30000, 60000},
+		},
+		{"no components", 50000, nil, []float64{}},
+	}
+
+	for _, tt := range tests {
+		got := AllocateBundlePrice(tt.price, tt.components)
+		if len(got) != len(tt.expected) {
+			t.Fatalf("%s: got %d shares, expected %d", tt.name, len(got), len(tt.expected))
+		}
+		sum := 0.0
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%s: share %d got %.0f, expected %.0f", tt.name, i, got[i], tt.expected[i])
+			}
+			sum += got[i]
+		}
+		if len(got) > 0 && sum != tt.price {
+			t.Errorf("%s: shares add up to %.0f, expected %.0f", tt.name, sum, tt.price)
+		}
+	}
+}
+
+func TestOrderItemComponentRefundAmount(t *testing.T) {
+	socks := OrderItemComponent{Quantity: 3, AllocatedPrice: 100000}
+
+	if got := socks.RefundAmount(3); got != 100000 {
+		t.Errorf("whole bundle share: got %.0f, expected 100000", got)
+	}
+	if got := socks.RefundAmount(6); got != 200000 {
+		t.Errorf("two bundles: got %.0f, expected 200000", got)
+	}
+	if got := socks.RefundAmount(1); got != 33333 {
+		t.Errorf("single unit: got %.0f, expected 33333", got)
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Category is a node in the product category tree
+// Path is the slash-joined slug chain from the root (e.g. "wanita/dress")
+type Category struct {
+	ID        int        `json:"id" db:"id"`
+	ParentID  *int       `json:"parent_id,omitempty" db:"parent_id"`
+	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
+	Path      string     `json:"path" db:"path"`
+	Depth     int        `json:"depth" db:"depth"`
+	SortOrder int        `json:"sort_order" db:"sort_order"`
+	ImageURL  *string    `json:"image_url,omitempty" db:"image_url"`
+	IsActive  bool       `json:"is_active" db:"is_active"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	Children  []Category `json:"children,omitempty" db:"-"`
+}
+
+// RootSlug returns the top-level slug of the path ("wanita" for "wanita/dress")
+func (c Category) RootSlug() string {
+	for i := 0; i < len(c.Path); i++ {
+		if c.Path[i] == '/' {
+			return c.Path[:i]
+		}
+	}
+	return c.Path
+}
+
+// BuildCategoryTree nests a flat, ordered category list under its parents.
+// Categories whose parent is missing from the list are treated as roots.
+func BuildCategoryTree(categories []Category) []Category {
+	byParent := make(map[int][]Category)
+	present := make(map[int]bool, len(categories))
+	for _, c := range categories {
+		present[c.ID] = true
+	}
+
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID != nil && present[*c.ParentID] {
+			byParent[*c.ParentID] = append(byParent[*c.ParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			nodes[i].Children = attach(byParent[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots)
+}
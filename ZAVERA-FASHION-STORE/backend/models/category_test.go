@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestBuildCategoryTree(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	flat := []Category{
+		{ID: 1, Name: "Wanita", Path: "wanita"},
+		{ID: 2, Name: "Pria", Path: "pria"},
+		{ID: 3, ParentID: intPtr(1), Name: "Dress", Path: "wanita/dress"},
+		{ID: 4, ParentID: intPtr(1), Name: "Atasan", Path: "wanita/atasan"},
+		{ID: 5, ParentID: intPtr(3), Name: "Mini", Path: "wanita/dress/mini"},
+		// Parent filtered out (e.g. inactive) - surfaces as a root instead of disappearing
+		{ID: 6, ParentID: intPtr(99), Name: "Orphan", Path: "hidden/orphan"},
+	}
+
+	tree := BuildCategoryTree(flat)
+
+	if len(tree) != 3 {
+		t.Fatalf("expected 3 roots, got %d", len(tree))
+	}
+	if tree[0].ID != 1 || len(tree[0].Children) != 2 {
+		t.Fatalf("expected Wanita with 2 children, got %+v", tree[0])
+	}
+	if tree[0].Children[0].ID != 3 || len(tree[0].Children[0].Children) != 1 || tree[0].Children[0].Children[0].ID != 5 {
+		t.Errorf("expected Dress > Mini nesting, got %+v", tree[0].Children[0])
+	}
+	if tree[0].Children[1].ID != 4 {
+		t.Errorf("expected input order to be kept among siblings, got %+v", tree[0].Children)
+	}
+	if tree[2].ID != 6 {
+		t.Errorf("expected orphan to be treated as root, got %+v", tree[2])
+	}
+}
+
+func TestCategoryRootSlug(t *testing.T) {
+	tests := map[string]string{
+		"wanita":           "wanita",
+		"wanita/dress":     "wanita",
+		"pria/kemeja/slim": "pria",
+	}
+	for path, expected := range tests {
+		if got := (Category{Path: path}).RootSlug(); got != expected {
+			t.Errorf("RootSlug(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
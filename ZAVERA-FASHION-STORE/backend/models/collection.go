@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CollectionType distinguishes hand-picked from rule-evaluated collections
+type CollectionType string
+
+const (
+	CollectionTypeManual CollectionType = "MANUAL"
+	CollectionTypeRule   CollectionType = "RULE"
+)
+
+// Collection match types
+const (
+	CollectionMatchAll = "ALL"
+	CollectionMatchAny = "ANY"
+)
+
+// Collection rule fields
+const (
+	CollectionFieldCategory  = "category"
+	CollectionFieldBrand     = "brand"
+	CollectionFieldPrice     = "price"
+	CollectionFieldTag       = "tag"
+	CollectionFieldCreatedAt = "created_at"
+)
+
+// collectionRuleOperators lists the operators allowed per field
+var collectionRuleOperators = map[string][]string{
+	CollectionFieldCategory:  {"eq", "in"},
+	CollectionFieldBrand:     {"eq", "neq", "in"},
+	CollectionFieldPrice:     {"lt", "lte", "gt", "gte"},
+	CollectionFieldTag:       {"eq", "in"},
+	CollectionFieldCreatedAt: {"within_days", "after", "before"},
+}
+
+// Collection is a merchandising list of products
+type Collection struct {
+	ID           int             `json:"id" db:"id"`
+	Name         string          `json:"name" db:"name"`
+	Slug         string          `json:"slug" db:"slug"`
+	Description  string          `json:"description" db:"description"`
+	ImageURL     string          `json:"image_url" db:"image_url"`
+	Type         CollectionType  `json:"collection_type" db:"collection_type"`
+	Rules        CollectionRules `json:"rules" db:"rules"`
+	MatchType    string          `json:"match_type" db:"match_type"`
+	SortBy       ProductSort     `json:"sort_by" db:"sort_by"`
+	SortOrder    int             `json:"sort_order" db:"sort_order"`
+	IsActive     bool            `json:"is_active" db:"is_active"`
+	StartsAt     *time.Time      `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt       *time.Time      `json:"ends_at,omitempty" db:"ends_at"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+	ProductCount int             `json:"product_count" db:"-"`
+}
+
+// IsVisibleAt reports whether the collection is active and inside its visibility window
+func (c Collection) IsVisibleAt(t time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	if c.StartsAt != nil && t.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !t.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// CollectionRule is one condition of a rule-based collection
+// Value is a string, number or list depending on field and operator
+type CollectionRule struct {
+	Field    string      `json:"field"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+// CollectionRules is stored as JSONB
+type CollectionRules []CollectionRule
+
+func (r CollectionRules) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *CollectionRules) Scan(value interface{}) error {
+	if value == nil {
+		*r = CollectionRules{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// Validate checks field/operator combinations and value types
+func (rule CollectionRule) Validate() error {
+	operators, ok := collectionRuleOperators[rule.Field]
+	if !ok {
+		return fmt.Errorf("unsupported rule field %q", rule.Field)
+	}
+
+	allowed := false
+	for _, op := range operators {
+		if op == rule.Operator {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("operator %q is not supported for %s (use %s)", rule.Operator, rule.Field, strings.Join(operators, ", "))
+	}
+
+	switch {
+	case rule.Operator == "in":
+		if len(rule.StringValues()) == 0 {
+			return fmt.Errorf("%s %s needs a non-empty list of values", rule.Field, rule.Operator)
+		}
+	case rule.Field == CollectionFieldPrice || rule.Operator == "within_days":
+		if n, ok := rule.NumberValue(); !ok || n < 0 {
+			return fmt.Errorf("%s %s needs a non-negative number", rule.Field, rule.Operator)
+		}
+	case rule.Operator == "after" || rule.Operator == "before":
+		if _, ok := rule.TimeValue(); !ok {
+			return fmt.Errorf("%s %s needs a date (YYYY-MM-DD or RFC3339)", rule.Field, rule.Operator)
+		}
+	default:
+		if len(rule.StringValues()) != 1 {
+			return fmt.Errorf("%s %s needs a single value", rule.Field, rule.Operator)
+		}
+	}
+	return nil
+}
+
+// StringValues returns the rule value as trimmed, non-empty strings
+func (rule CollectionRule) StringValues() []string {
+	var raw []interface{}
+	switch v := rule.Value.(type) {
+	case []interface{}:
+		raw = v
+	case []string:
+		for _, s := range v {
+			raw = append(raw, s)
+		}
+	default:
+		raw = []interface{}{v}
+	}
+
+	var values []string
+	for _, item := range raw {
+		s, ok := item.(string)
+		if !ok {
+			continue
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// NumberValue returns the rule value as a number
+func (rule CollectionRule) NumberValue() (float64, bool) {
+	switch v := rule.Value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// TimeValue parses the rule value as a date or timestamp
+func (rule CollectionRule) TimeValue() (time.Time, bool) {
+	s, ok := rule.Value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
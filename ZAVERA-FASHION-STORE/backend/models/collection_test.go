@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCollectionRuleValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		valid bool
+	}{
+		{"category eq", `{"field":"category","operator":"eq","value":"wanita/dress"}`, true},
+		{"brand in", `{"field":"brand","operator":"in","value":["Nike","Adidas"]}`, true},
+		{"brand in empty", `{"field":"brand","operator":"in","value":[]}`, false},
+		{"price lte", `{"field":"price","operator":"lte","value":250000}`, true},
+		{"price as string", `{"field":"price","operator":"lte","value":"250000"}`, false},
+		{"price eq unsupported", `{"field":"price","operator":"eq","value":100}`, false},
+		{"tag eq", `{"field":"tag","operator":"eq","value":"ramadan"}`, true},
+		{"tag eq list", `{"field":"tag","operator":"eq","value":["a","b"]}`, false},
+		{"new arrivals", `{"field":"created_at","operator":"within_days","value":30}`, true},
+		{"created after date", `{"field":"created_at","operator":"after","value":"2026-01-01"}`, true},
+		{"created before garbage", `{"field":"created_at","operator":"before","value":"soon"}`, false},
+		{"unknown field", `{"field":"color","operator":"eq","value":"red"}`, false},
+	}
+
+	for _, tt := range tests {
+		var rule CollectionRule
+		if err := json.Unmarshal([]byte(tt.rule), &rule); err != nil {
+			t.Fatalf("%s: bad fixture: %v", tt.name, err)
+		}
+		err := rule.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+}
+
+func TestCollectionIsVisibleAt(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		collection Collection
+		visible    bool
+	}{
+		{"active without window", Collection{IsActive: true}, true},
+		{"inactive", Collection{IsActive: false}, false},
+		{"inside window", Collection{IsActive: true, StartsAt: &before, EndsAt: &after}, true},
+		{"not started", Collection{IsActive: true, StartsAt: &after}, false},
+		{"ended", Collection{IsActive: true, EndsAt: &before}, false},
+		{"ends exactly now", Collection{IsActive: true, EndsAt: &now}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.collection.IsVisibleAt(now); got != tt.visible {
+			t.Errorf("%s: IsVisibleAt = %v, expected %v", tt.name, got, tt.visible)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type DocumentType string
+
+const (
+	DocumentTypeInvoice    DocumentType = "INVOICE"     // Issued when an order is paid
+	DocumentTypeCreditNote DocumentType = "CREDIT_NOTE" // Issued when a refund of the order completes
+)
+
+// Invoice is a numbered invoice or credit note. The buyer and amounts are as on the day of
+// issue; a credit note's amounts are what was returned.
+type Invoice struct {
+	ID               int          `json:"id"`
+	InvoiceNumber    string       `json:"invoice_number"` // INV/2026/000001, CN/2026/000001
+	DocumentType     DocumentType `json:"document_type"`
+	OrderID          int          `json:"order_id"`
+	OrderCode        string       `json:"order_code"`
+	RefundID         *int         `json:"refund_id,omitempty"`
+	RelatedInvoiceID *int         `json:"related_invoice_id,omitempty"` // Invoice a credit note corrects
+	RelatedNumber    string       `json:"related_invoice_number,omitempty"`
+	TaxPeriod        string       `json:"tax_period"` // YYYY-MM
+	IssuedAt         time.Time    `json:"issued_at"`
+	BuyerName        string       `json:"buyer_name"`
+	BuyerEmail       string       `json:"buyer_email"`
+	BuyerNPWP        string       `json:"buyer_npwp,omitempty"`
+	BuyerNPWPName    string       `json:"buyer_npwp_name,omitempty"`
+	BuyerNPWPAddress string       `json:"buyer_npwp_address,omitempty"`
+	Subtotal         float64      `json:"subtotal"`
+	Discount         float64      `json:"discount"`
+	ShippingCost     float64      `json:"shipping_cost"`
+	TaxRate          float64      `json:"tax_rate"`
+	Tax              float64      `json:"tax"`
+	TotalAmount      float64      `json:"total_amount"`
+	PricesIncludeTax bool         `json:"prices_include_tax"`
+}
+
+// TaxBase is the DPP (dasar pengenaan pajak): what was paid for the items, without the PPN.
+// Shipping is not taxed.
+func (i Invoice) TaxBase() float64 {
+	return i.TotalAmount - i.ShippingCost - i.Tax
+}
+
+// FileName is the name of the document's PDF, the number with its slashes replaced
+func (i Invoice) FileName() string {
+	return strings.ReplaceAll(i.InvoiceNumber, "/", "-") + ".pdf"
+}
+
+// TaxProfile is the NPWP a customer wants on their invoices
+type TaxProfile struct {
+	UserID  int    `json:"user_id"`
+	NPWP    string `json:"npwp"`
+	Name    string `json:"npwp_name"`
+	Address string `json:"npwp_address"`
+}
+
+// NormalizeNPWP strips the punctuation of an NPWP. It is valid with 15 digits, or 16 for the
+// NIK-based NPWP.
+func NormalizeNPWP(npwp string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range npwp {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '.' || r == '-' || r == ' ':
+		default:
+			return "", false
+		}
+	}
+	normalized := digits.String()
+	return normalized, len(normalized) == 15 || len(normalized) == 16
+}
+
+// FormatNPWP writes a 15-digit NPWP as 99.999.999.9-999.999; other lengths are left as they are
+func FormatNPWP(npwp string) string {
+	if len(npwp) != 15 {
+		return npwp
+	}
+	return fmt.Sprintf("%s.%s.%s.%s-%s.%s", npwp[0:2], npwp[2:5], npwp[5:8], npwp[8:9], npwp[9:12], npwp[12:15])
+}
+
+// ParseTaxPeriod checks a YYYY-MM tax period and returns it with its first day
+func ParseTaxPeriod(period string) (string, time.Time, error) {
+	start, err := time.Parse("2006-01", strings.TrimSpace(period))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("tax period must be YYYY-MM")
+	}
+	return start.Format("2006-01"), start, nil
+}
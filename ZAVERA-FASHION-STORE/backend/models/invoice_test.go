@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+func TestNormalizeNPWP(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		valid bool
+	}{
+		{"01.234.567.8-901.000", "012345678901000", true},
+		{"012345678901000", "012345678901000", true},
+		{"3174 0123 4567 8901", "3174012345678901", true}, // NIK-based
+		{"01.234.567.8-901", "012345678901", false},
+		{"01.234.567.8-901.00A", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, valid := NormalizeNPWP(tt.input)
+		if got != tt.want || valid != tt.valid {
+			t.Errorf("NormalizeNPWP(%q) = %q, %v; want %q, %v", tt.input, got, valid, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestFormatNPWP(t *testing.T) {
+	if got := FormatNPWP("012345678901000"); got != "01.234.567.8-901.000" {
+		t.Errorf("15 digits: got %s", got)
+	}
+	if got := FormatNPWP("3174012345678901"); got != "3174012345678901" {
+		t.Errorf("16 digits should be left as they are: got %s", got)
+	}
+}
+
+func TestParseTaxPeriod(t *testing.T) {
+	period, start, err := ParseTaxPeriod(" 2026-10 ")
+	if err != nil || period != "2026-10" || start.Day() != 1 || start.Month() != 10 {
+		t.Errorf("got %s, %v, %v", period, start, err)
+	}
+	for _, bad := range []string{"2026-13", "10-2026", "2026/10", ""} {
+		if _, _, err := ParseTaxPeriod(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestInvoiceTaxBase(t *testing.T) {
+	// Prices include 11% PPN: 222,000 of items (22,000 PPN) and 20,000 shipping
+	inclusive := Invoice{TotalAmount: 242000, ShippingCost: 20000, Tax: 22000, PricesIncludeTax: true}
+	if got := inclusive.TaxBase(); got != 200000 {
+		t.Errorf("inclusive: got %.0f, want 200000", got)
+	}
+
+	// 12% on top: 200,000 of items, 24,000 PPN and 20,000 shipping
+	exclusive := Invoice{TotalAmount: 244000, ShippingCost: 20000, Tax: 24000}
+	if got := exclusive.TaxBase(); got != 200000 {
+		t.Errorf("exclusive: got %.0f, want 200000", got)
+	}
+}
+
+func TestInvoiceFileName(t *testing.T) {
+	if got := (Invoice{InvoiceNumber: "CN/2026/000042"}).FileName(); got != "CN-2026-000042.pdf" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -142,7 +142,16 @@ type Product struct {
 	Slug        string         `json:"slug" db:"slug"`
 	Description string         `json:"description" db:"description"`
 	Price       float64        `json:"price" db:"price"`
+	CompareAtPrice *float64    `json:"compare_at_price,omitempty" db:"compare_at_price"` // Strike-through price while on sale
+	// Deprecated: read-only mirror of the active variants' stock, kept for existing clients.
+	// Stock lives per variant and warehouse in the stock ledger.
 	Stock       int            `json:"stock" db:"stock"`
+	// An active variant is on pre-order/backorder, so Stock does not cap the quantity (FindByID only)
+	AllowsBackorder bool       `json:"allows_backorder" db:"-"`
+	// Sold as a set of component variants; Stock is the number of complete sets
+	IsBundle    bool           `json:"is_bundle" db:"is_bundle"`
+	// Sold as a gift card: each unit paid for issues a code worth its price in store credit
+	IsGiftCard  bool           `json:"is_gift_card" db:"is_gift_card"`
 	Weight      int            `json:"weight" db:"weight"` // Weight in grams
 	Length      int            `json:"length" db:"length"` // Length in cm (for shipping)
 	Width       int            `json:"width" db:"width"`   // Width in cm (for shipping)
@@ -150,8 +159,15 @@ type Product struct {
 	IsActive    bool           `json:"is_active" db:"is_active"`
 	Category    string         `json:"category" db:"category"`
 	Subcategory string         `json:"subcategory" db:"subcategory"`
+	CategoryID  *int           `json:"category_id,omitempty" db:"category_id"` // Deepest node in the category tree
 	Brand       string         `json:"brand" db:"brand"`          // Product brand (e.g., Nike, Adidas)
 	Material    string         `json:"material" db:"material"`    // Product material (e.g., Cotton, Polyester)
+	Tags        []string       `json:"tags" db:"tags"`            // Lowercase merchandising tags
+	// SEO overrides (empty = derived from name/description/images)
+	MetaTitle       string     `json:"meta_title" db:"meta_title"`
+	MetaDescription string     `json:"meta_description" db:"meta_description"`
+	CanonicalURL    string     `json:"canonical_url" db:"canonical_url"`
+	OGImageURL      string     `json:"og_image_url" db:"og_image_url"`
 	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
 	Images      []ProductImage `json:"images" db:"-"`
@@ -189,6 +205,7 @@ type CartItem struct {
 	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
 	Product       *Product        `json:"product,omitempty" db:"-"`
+	Hold          *StockReservation `json:"hold,omitempty" db:"-"` // Active limited-drop hold
 }
 
 // Order represents a customer order
@@ -202,13 +219,22 @@ type Order struct {
 	Subtotal        float64        `json:"subtotal" db:"subtotal"`
 	ShippingCost    float64        `json:"shipping_cost" db:"shipping_cost"`
 	Tax             float64        `json:"tax" db:"tax"`
+	TaxRate         float64        `json:"tax_rate" db:"tax_rate"`                     // PPN rate when the order was placed
+	PricesIncludeTax bool          `json:"prices_include_tax" db:"prices_include_tax"` // Tax is contained in the subtotal, not added to the total
 	Discount        float64        `json:"discount" db:"discount"`
 	TotalAmount     float64        `json:"total_amount" db:"total_amount"`
+	StoreCreditAmount float64      `json:"store_credit_amount" db:"store_credit_amount"` // Part of the total paid with store credit
 	Status          OrderStatus    `json:"status" db:"status"`
 	StockReserved   bool           `json:"stock_reserved" db:"stock_reserved"`
 	Resi            string         `json:"resi,omitempty" db:"resi"`
 	OriginCity      string         `json:"origin_city,omitempty" db:"origin_city"`
 	DestinationCity string         `json:"destination_city,omitempty" db:"destination_city"`
+	WarehouseID     *int           `json:"warehouse_id,omitempty" db:"warehouse_id"` // Fulfilling warehouse, set at checkout
+	CartID          *int           `json:"-" db:"-"`                                 // Cart checked out; its holds pass to the order
+	Voucher         *VoucherRedemption `json:"-" db:"-"`                             // Voucher used; redeemed with the order
+	// Pre-order/backorder: lines waiting for stock hold the order from packing
+	AwaitingStock    bool       `json:"awaiting_stock" db:"awaiting_stock"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty" db:"expected_ship_date"`
 	Notes           string         `json:"notes,omitempty" db:"notes"`
 	Metadata        map[string]any `json:"metadata,omitempty" db:"metadata"`
 	// Refund tracking fields
@@ -236,8 +262,17 @@ type OrderItem struct {
 	Quantity     int            `json:"quantity" db:"quantity"`
 	PricePerUnit float64        `json:"price_per_unit" db:"price_per_unit"`
 	Subtotal     float64        `json:"subtotal" db:"subtotal"`
+	// Promotion and voucher discount on the line; PPN is charged on Subtotal - DiscountAmount
+	DiscountAmount float64      `json:"discount_amount" db:"discount_amount"`
+	TaxRate        float64      `json:"tax_rate" db:"tax_rate"` // 0 for exempt products
+	TaxAmount      float64      `json:"tax_amount" db:"tax_amount"`
 	Metadata     map[string]any `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	// Units not yet reserved from stock (pre-order/backorder)
+	BackorderedQuantity int        `json:"backordered_quantity" db:"backordered_quantity"`
+	ExpectedShipDate    *time.Time `json:"expected_ship_date,omitempty" db:"expected_ship_date"`
+	// What a bundle line is made of; empty for other products
+	Components []OrderItemComponent `json:"components,omitempty" db:"-"`
 }
 
 // Payment represents a payment transaction
@@ -266,13 +301,21 @@ type Payment struct {
 type StockMovementType string
 
 const (
-	StockMovementReserve    StockMovementType = "RESERVE"    // Stock reserved at checkout
-	StockMovementRelease    StockMovementType = "RELEASE"    // Stock released on cancel/expire
-	StockMovementDeduct     StockMovementType = "DEDUCT"     // Stock permanently deducted
-	StockMovementAdjustment StockMovementType = "ADJUSTMENT" // Manual adjustment
+	StockMovementReserve     StockMovementType = "RESERVE"      // Stock reserved at checkout
+	StockMovementRelease     StockMovementType = "RELEASE"      // Stock released on cancel/expire
+	StockMovementDeduct      StockMovementType = "DEDUCT"       // Stock permanently deducted (legacy stock_movements only)
+	StockMovementAdjustment  StockMovementType = "ADJUSTMENT"   // Manual adjustment
+	StockMovementRestock     StockMovementType = "RESTOCK"      // Refunded items back on the shelf
+	StockMovementTransferIn  StockMovementType = "TRANSFER_IN"  // Received from another warehouse
+	StockMovementTransferOut StockMovementType = "TRANSFER_OUT" // Sent to another warehouse
+	StockMovementOpening     StockMovementType = "OPENING"      // Balance carried over when the ledger started
+	StockMovementReceipt     StockMovementType = "RECEIPT"      // Received against a purchase order
 )
 
 // StockMovement represents a stock operation for audit
+//
+// Deprecated: stock_movements is keyed by product only and is no longer written.
+// Use StockLedgerEntry.
 type StockMovement struct {
 	ID           int               `json:"id" db:"id"`
 	ProductID    int               `json:"product_id" db:"product_id"`
@@ -349,6 +392,7 @@ type EmailLog struct {
 	Status         EmailLogStatus `json:"status" db:"status"`
 	ErrorMessage   string         `json:"error_message,omitempty" db:"error_message"`
 	SentAt         *time.Time     `json:"sent_at,omitempty" db:"sent_at"`
+	Reference      string         `json:"reference,omitempty" db:"reference"` // What a non-order email was about
 	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
 }
 
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProductScheduleAction is what a schedule entry does when it runs
+type ProductScheduleAction string
+
+const (
+	ScheduleActionPublish     ProductScheduleAction = "PUBLISH"      // Set is_active (drop launch)
+	ScheduleActionUnpublish   ProductScheduleAction = "UNPUBLISH"    // Clear is_active
+	ScheduleActionPriceChange ProductScheduleAction = "PRICE_CHANGE" // Permanent new price
+	ScheduleActionSale        ProductScheduleAction = "SALE"         // Sale price between run_at and ends_at
+)
+
+// IsValid checks if the action is known
+func (a ProductScheduleAction) IsValid() bool {
+	switch a {
+	case ScheduleActionPublish, ScheduleActionUnpublish, ScheduleActionPriceChange, ScheduleActionSale:
+		return true
+	}
+	return false
+}
+
+// NeedsPrice reports whether the action carries a new price
+func (a ProductScheduleAction) NeedsPrice() bool {
+	return a == ScheduleActionPriceChange || a == ScheduleActionSale
+}
+
+// ProductScheduleStatus is the lifecycle state of a schedule entry
+type ProductScheduleStatus string
+
+const (
+	ScheduleStatusPending   ProductScheduleStatus = "PENDING"   // Waiting for run_at
+	ScheduleStatusActive    ProductScheduleStatus = "ACTIVE"    // Sale running, waiting for ends_at
+	ScheduleStatusApplied   ProductScheduleStatus = "APPLIED"   // One-off change done
+	ScheduleStatusCompleted ProductScheduleStatus = "COMPLETED" // Sale ended (or skipped)
+	ScheduleStatusCancelled ProductScheduleStatus = "CANCELLED"
+	ScheduleStatusFailed    ProductScheduleStatus = "FAILED"
+)
+
+// ProductSchedule is a future-dated change to a product
+type ProductSchedule struct {
+	ID                     int                   `json:"id" db:"id"`
+	ProductID              int                   `json:"product_id" db:"product_id"`
+	Action                 ProductScheduleAction `json:"action" db:"action"`
+	RunAt                  time.Time             `json:"run_at" db:"run_at"`
+	EndsAt                 *time.Time            `json:"ends_at,omitempty" db:"ends_at"`
+	NewPrice               *float64              `json:"new_price,omitempty" db:"new_price"`
+	Status                 ProductScheduleStatus `json:"status" db:"status"`
+	PreviousPrice          *float64              `json:"previous_price,omitempty" db:"previous_price"`
+	PreviousCompareAtPrice *float64              `json:"previous_compare_at_price,omitempty" db:"previous_compare_at_price"`
+	Note                   string                `json:"note,omitempty" db:"note"`
+	ResultNote             string                `json:"result_note,omitempty" db:"result_note"`
+	CreatedBy              string                `json:"created_by,omitempty" db:"created_by"`
+	AppliedAt              *time.Time            `json:"applied_at,omitempty" db:"applied_at"`
+	CompletedAt            *time.Time            `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt              time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at" db:"updated_at"`
+	// Joined for listings
+	ProductName string `json:"product_name,omitempty" db:"-"`
+	ProductSlug string `json:"product_slug,omitempty" db:"-"`
+}
+
+// ProductScheduleFilter selects schedule entries for admin listings
+type ProductScheduleFilter struct {
+	ProductID int                   // 0 = all products
+	Status    ProductScheduleStatus // Empty = any status
+	Limit     int
+	Offset    int
+}
+
+// ProductPricingState is the part of a product a schedule entry can change
+type ProductPricingState struct {
+	Price          float64
+	CompareAtPrice *float64
+	IsActive       bool
+}
+
+// Advance applies the entry to a product's state if it is due at now.
+// It returns the new state, the entry's next status and a note explaining anything unusual.
+// A PENDING entry runs its action; an ACTIVE sale whose end has passed reverts to the prices
+// captured when it started, unless the price was changed by hand during the sale.
+// ok is false when nothing is due yet.
+func (s *ProductSchedule) Advance(state ProductPricingState, now time.Time) (next ProductPricingState, status ProductScheduleStatus, note string, ok bool) {
+	next = state
+
+	switch s.Status {
+	case ScheduleStatusPending:
+		if now.Before(s.RunAt) {
+			return state, s.Status, "", false
+		}
+
+		switch s.Action {
+		case ScheduleActionPublish:
+			next.IsActive = true
+			return next, ScheduleStatusApplied, "", true
+		case ScheduleActionUnpublish:
+			next.IsActive = false
+			return next, ScheduleStatusApplied, "", true
+		case ScheduleActionPriceChange:
+			if s.NewPrice == nil {
+				return state, ScheduleStatusFailed, "no new price set", true
+			}
+			s.capture(state)
+			next.Price = *s.NewPrice
+			return next, ScheduleStatusApplied, "", true
+		case ScheduleActionSale:
+			if s.NewPrice == nil || s.EndsAt == nil {
+				return state, ScheduleStatusFailed, "sale needs a price and an end time", true
+			}
+			if !now.Before(*s.EndsAt) {
+				return state, ScheduleStatusCompleted, "sale window had already ended, not applied", true
+			}
+			s.capture(state)
+			regular := state.Price
+			next.Price = *s.NewPrice
+			next.CompareAtPrice = &regular
+			return next, ScheduleStatusActive, "", true
+		}
+		return state, ScheduleStatusFailed, fmt.Sprintf("unknown action %s", s.Action), true
+
+	case ScheduleStatusActive:
+		if s.EndsAt == nil || now.Before(*s.EndsAt) {
+			return state, s.Status, "", false
+		}
+		if s.NewPrice == nil || state.Price != *s.NewPrice {
+			// Someone changed the price during the sale; keep it and only drop our strike-through
+			if s.PreviousPrice != nil && state.CompareAtPrice != nil && *state.CompareAtPrice == *s.PreviousPrice {
+				next.CompareAtPrice = s.PreviousCompareAtPrice
+			}
+			return next, ScheduleStatusCompleted, fmt.Sprintf("price was changed to %.2f during the sale, not reverted", state.Price), true
+		}
+		if s.PreviousPrice != nil {
+			next.Price = *s.PreviousPrice
+		}
+		next.CompareAtPrice = s.PreviousCompareAtPrice
+		return next, ScheduleStatusCompleted, "", true
+	}
+
+	return state, s.Status, "", false
+}
+
+func (s *ProductSchedule) capture(state ProductPricingState) {
+	price := state.Price
+	s.PreviousPrice = &price
+	s.PreviousCompareAtPrice = state.CompareAtPrice
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestProductScheduleAdvancePublish(t *testing.T) {
+	now := time.Now()
+	s := &ProductSchedule{Action: ScheduleActionPublish, Status: ScheduleStatusPending, RunAt: now.Add(time.Hour)}
+
+	if _, _, _, ok := s.Advance(ProductPricingState{Price: 100}, now); ok {
+		t.Fatal("entry should not be due before run_at")
+	}
+
+	next, status, _, ok := s.Advance(ProductPricingState{Price: 100}, now.Add(2*time.Hour))
+	if !ok || status != ScheduleStatusApplied || !next.IsActive {
+		t.Errorf("publish: got status %s, active %v, ok %v", status, next.IsActive, ok)
+	}
+}
+
+func TestProductScheduleAdvancePriceChange(t *testing.T) {
+	now := time.Now()
+	s := &ProductSchedule{Action: ScheduleActionPriceChange, Status: ScheduleStatusPending, RunAt: now, NewPrice: floatPtr(80)}
+
+	next, status, _, ok := s.Advance(ProductPricingState{Price: 100, IsActive: true}, now)
+	if !ok || status != ScheduleStatusApplied || next.Price != 80 || !next.IsActive {
+		t.Errorf("price change: got %+v, status %s", next, status)
+	}
+	if s.PreviousPrice == nil || *s.PreviousPrice != 100 {
+		t.Errorf("previous price not captured: %v", s.PreviousPrice)
+	}
+}
+
+func TestProductScheduleAdvanceSaleStartsAndReverts(t *testing.T) {
+	now := time.Now()
+	end := now.Add(24 * time.Hour)
+	s := &ProductSchedule{Action: ScheduleActionSale, Status: ScheduleStatusPending, RunAt: now, EndsAt: &end, NewPrice: floatPtr(70)}
+
+	onSale, status, _, ok := s.Advance(ProductPricingState{Price: 100, IsActive: true}, now)
+	if !ok || status != ScheduleStatusActive {
+		t.Fatalf("sale start: status %s, ok %v", status, ok)
+	}
+	if onSale.Price != 70 || onSale.CompareAtPrice == nil || *onSale.CompareAtPrice != 100 {
+		t.Fatalf("sale start: got %+v", onSale)
+	}
+
+	s.Status = status
+	if _, _, _, ok := s.Advance(onSale, now.Add(time.Hour)); ok {
+		t.Fatal("sale should keep running before ends_at")
+	}
+
+	reverted, status, note, ok := s.Advance(onSale, end)
+	if !ok || status != ScheduleStatusCompleted || note != "" {
+		t.Fatalf("sale end: status %s, note %q, ok %v", status, note, ok)
+	}
+	if reverted.Price != 100 || reverted.CompareAtPrice != nil {
+		t.Errorf("sale end: got %+v", reverted)
+	}
+}
+
+func TestProductScheduleAdvanceSaleKeepsManualPriceChange(t *testing.T) {
+	now := time.Now()
+	end := now.Add(time.Hour)
+	s := &ProductSchedule{
+		Action: ScheduleActionSale, Status: ScheduleStatusActive, RunAt: now, EndsAt: &end, NewPrice: floatPtr(70),
+		PreviousPrice: floatPtr(100),
+	}
+
+	// Admin lowered the price further by hand during the sale
+	state := ProductPricingState{Price: 60, CompareAtPrice: floatPtr(100), IsActive: true}
+	next, status, note, ok := s.Advance(state, end)
+	if !ok || status != ScheduleStatusCompleted || note == "" {
+		t.Fatalf("status %s, note %q, ok %v", status, note, ok)
+	}
+	if next.Price != 60 || next.CompareAtPrice != nil {
+		t.Errorf("manual price should stay and strike-through be cleared, got %+v", next)
+	}
+}
+
+func TestProductScheduleAdvanceMissedSaleWindow(t *testing.T) {
+	now := time.Now()
+	end := now.Add(-time.Hour)
+	s := &ProductSchedule{Action: ScheduleActionSale, Status: ScheduleStatusPending, RunAt: now.Add(-2 * time.Hour), EndsAt: &end, NewPrice: floatPtr(70)}
+
+	next, status, note, ok := s.Advance(ProductPricingState{Price: 100}, now)
+	if !ok || status != ScheduleStatusCompleted || note == "" || next.Price != 100 {
+		t.Errorf("missed window: got %+v, status %s, note %q", next, status, note)
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+// ProductSort is a catalog sort option
+type ProductSort string
+
+const (
+	ProductSortNewest         ProductSort = "newest"
+	ProductSortPriceAsc       ProductSort = "price_asc"
+	ProductSortPriceDesc      ProductSort = "price_desc"
+	ProductSortBestSelling    ProductSort = "best_selling"
+	ProductSortMostWishlisted ProductSort = "most_wishlisted"
+)
+
+// IsValid checks if the sort option is supported
+func (s ProductSort) IsValid() bool {
+	switch s {
+	case ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc,
+		ProductSortBestSelling, ProductSortMostWishlisted:
+		return true
+	}
+	return false
+}
+
+// ProductListQuery is a keyset-paginated catalog listing request
+// Cursor is the opaque next_cursor returned by the previous page (empty = first page)
+type ProductListQuery struct {
+	Category    string
+	CategoryIDs []int // Resolved category subtree; takes precedence over Category
+	Sort        ProductSort
+	Cursor      string
+	Limit       int
+}
+
+// ProductVariantSummary aggregates active variants of a product for listing cards
+type ProductVariantSummary struct {
+	ProductID      int
+	MinPrice       float64
+	MaxPrice       float64
+	AvailableSizes []string
+}
+
+// ProductSearchFilter holds the public catalog search criteria
+// Empty fields are ignored; multi-value fields match any of the given values
+type ProductSearchFilter struct {
+	Query       string      `json:"q,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	CategoryIDs []int       `json:"category_ids,omitempty"` // Resolved category subtree; takes precedence over Category
+	Subcategory string      `json:"subcategory,omitempty"`
+	Brands      []string    `json:"brands,omitempty"`
+	Sizes       []string    `json:"sizes,omitempty"`
+	Colors      []string    `json:"colors,omitempty"`
+	MinPrice    *float64    `json:"min_price,omitempty"`
+	MaxPrice    *float64    `json:"max_price,omitempty"`
+	InStock     bool        `json:"in_stock,omitempty"`
+	Sort        ProductSort `json:"sort,omitempty"` // Ignored when Query is set; results are ranked by relevance
+	Limit       int         `json:"limit,omitempty"`
+	Offset      int         `json:"offset,omitempty"`
+}
+
+// HasCriteria reports whether any search or filter field is set
+func (f ProductSearchFilter) HasCriteria() bool {
+	return f.Query != "" || f.Subcategory != "" ||
+		len(f.Brands) > 0 || len(f.Sizes) > 0 || len(f.Colors) > 0 ||
+		f.MinPrice != nil || f.MaxPrice != nil || f.InStock
+}
+
+// FacetCount is the number of matching products for one facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket is a price range facet (Max nil = open-ended)
+type PriceBucket struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// ProductFacets holds facet counts for the filter sidebar
+type ProductFacets struct {
+	Brands       []FacetCount  `json:"brands"`
+	Sizes        []FacetCount  `json:"sizes"`
+	Colors       []FacetCount  `json:"colors"`
+	PriceBuckets []PriceBucket `json:"price_buckets"`
+}
+
+// DefaultPriceBuckets returns the storefront price ranges (IDR)
+func DefaultPriceBuckets() []PriceBucket {
+	bound := func(v float64) *float64 { return &v }
+	return []PriceBucket{
+		{Label: "< Rp100rb", Min: 0, Max: bound(100000)},
+		{Label: "Rp100rb - Rp200rb", Min: 100000, Max: bound(200000)},
+		{Label: "Rp200rb - Rp500rb", Min: 200000, Max: bound(500000)},
+		{Label: "Rp500rb - Rp1jt", Min: 500000, Max: bound(1000000)},
+		{Label: "> Rp1jt", Min: 1000000},
+	}
+}
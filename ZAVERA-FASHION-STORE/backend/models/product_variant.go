@@ -24,6 +24,57 @@ func (va *VariantAttributes) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, va)
 }
 
+// InventoryPolicy decides whether a variant can be sold beyond its stock
+type InventoryPolicy string
+
+const (
+	InventoryPolicyStock     InventoryPolicy = "STOCK"     // Only what is on the shelf
+	InventoryPolicyBackorder InventoryPolicy = "BACKORDER" // Sold out items ship when restocked
+	InventoryPolicyPreorder  InventoryPolicy = "PREORDER"  // Upcoming drop, nothing in stock yet
+)
+
+func (p InventoryPolicy) IsValid() bool {
+	switch p {
+	case InventoryPolicyStock, InventoryPolicyBackorder, InventoryPolicyPreorder:
+		return true
+	}
+	return false
+}
+
+// AllowsBackorder reports whether orders may exceed the available stock
+func (p InventoryPolicy) AllowsBackorder() bool {
+	return p == InventoryPolicyBackorder || p == InventoryPolicyPreorder
+}
+
+// SplitBackorder splits a requested quantity into what ships from stock and what is backordered.
+// outstanding is what is already backordered on open orders and counts against limit (nil = no
+// cap). ok is false when the request cannot be accepted.
+func SplitBackorder(policy InventoryPolicy, requested, available, outstanding int, limit *int) (fromStock, backordered int, ok bool) {
+	if available < 0 {
+		available = 0
+	}
+	if requested <= available {
+		return requested, 0, true
+	}
+	if !policy.AllowsBackorder() {
+		return available, 0, false
+	}
+	backordered = requested - available
+	if limit != nil && outstanding+backordered > *limit {
+		return available, 0, false
+	}
+	return available, backordered, true
+}
+
+// VariantAvailability answers whether a quantity of a variant can be ordered
+type VariantAvailability struct {
+	Available         bool
+	AvailableStock    int
+	BackorderQuantity int // Units that would wait for stock
+	InventoryPolicy   InventoryPolicy
+	ExpectedShipDate  *time.Time
+}
+
 type ProductVariant struct {
 	ID                 int                `json:"id"`
 	ProductID          int                `json:"product_id"`
@@ -51,6 +102,10 @@ type ProductVariant struct {
 	HeightCm           *int               `json:"height_cm,omitempty"`
 	Barcode            *string            `json:"barcode,omitempty"`
 	Position           int                `json:"position"`
+	InventoryPolicy    InventoryPolicy    `json:"inventory_policy"`
+	BackorderLimit     *int               `json:"backorder_limit,omitempty"` // Max units sold beyond stock; nil = no cap
+	ExpectedShipDate   *time.Time         `json:"expected_ship_date,omitempty"`
+	SizeChartRowID     *int               `json:"size_chart_row_id,omitempty"` // Row of the product's size chart for Size
 	CreatedAt          time.Time          `json:"created_at"`
 	UpdatedAt          time.Time          `json:"updated_at"`
 	Images             []VariantImage     `json:"images,omitempty"`
@@ -91,6 +146,31 @@ type StockReservation struct {
 	ExpiresAt  time.Time `json:"expires_at"`
 	Status     string    `json:"status"`
 	OrderID    *int      `json:"order_id,omitempty"`
+
+	WarehouseID  *int       `json:"warehouse_id,omitempty"`
+	CartItemID   *int       `json:"cart_item_id,omitempty"`
+	HeldInLedger bool       `json:"held_in_ledger"` // Units are off the shelf until released
+	ReleasedAt   *time.Time `json:"released_at,omitempty"`
+}
+
+// SecondsLeft is the hold countdown at now, never negative
+func (r StockReservation) SecondsLeft(now time.Time) int {
+	left := int(r.ExpiresAt.Sub(now).Seconds())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// ReservationRelease is an expired hold that was put back on the shelf
+type ReservationRelease struct {
+	ReservationID int    `json:"reservation_id"`
+	VariantID     int    `json:"variant_id"`
+	SKU           string `json:"sku"`
+	WarehouseID   *int   `json:"warehouse_id,omitempty"`
+	Quantity      int    `json:"quantity"`
+	CartItemID    *int   `json:"cart_item_id,omitempty"`
+	LedgerID      *int64 `json:"ledger_id,omitempty"` // nil when the hold never left the shelf
 }
 
 type LowStockVariant struct {
@@ -115,6 +195,9 @@ type VariantStockSummary struct {
 	StockQuantity     int     `json:"stock_quantity"`
 	ReservedQuantity  int     `json:"reserved_quantity"`
 	AvailableQuantity int     `json:"available_quantity"`
+	// Incoming: still to arrive on sent or partially received purchase orders
+	IncomingQuantity int        `json:"incoming_quantity"`
+	NextExpectedAt   *time.Time `json:"next_expected_at,omitempty"`
 }
 
 type ProductWithVariants struct {
@@ -0,0 +1,54 @@
+package models
+
+import "testing"
+
+func TestInventoryPolicyAllowsBackorder(t *testing.T) {
+	tests := []struct {
+		policy   InventoryPolicy
+		valid    bool
+		expected bool
+	}{
+		{InventoryPolicyStock, true, false},
+		{InventoryPolicyBackorder, true, true},
+		{InventoryPolicyPreorder, true, true},
+		{InventoryPolicy("DROPSHIP"), false, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.IsValid(); got != tt.valid {
+			t.Errorf("%s: IsValid got %v, expected %v", tt.policy, got, tt.valid)
+		}
+		if got := tt.policy.AllowsBackorder(); got != tt.expected {
+			t.Errorf("%s: AllowsBackorder got %v, expected %v", tt.policy, got, tt.expected)
+		}
+	}
+}
+
+func TestSplitBackorder(t *testing.T) {
+	tests := []struct {
+		name                   string
+		policy                 InventoryPolicy
+		requested, available   int
+		outstanding            int
+		limit                  *int
+		fromStock, backordered int
+		ok                     bool
+	}{
+		{"in stock", InventoryPolicyStock, 2, 5, 0, nil, 2, 0, true},
+		{"stock only, short", InventoryPolicyStock, 6, 5, 0, nil, 5, 0, false},
+		{"backorder the rest", InventoryPolicyBackorder, 6, 5, 0, nil, 5, 1, true},
+		{"pre-order, nothing in stock", InventoryPolicyPreorder, 3, 0, 0, nil, 0, 3, true},
+		{"negative availability counts as none", InventoryPolicyPreorder, 3, -2, 0, nil, 0, 3, true},
+		{"within the cap", InventoryPolicyPreorder, 3, 0, 7, intPtr(10), 0, 3, true},
+		{"over the cap", InventoryPolicyPreorder, 4, 0, 7, intPtr(10), 0, 0, false},
+		{"cap does not apply to stock", InventoryPolicyPreorder, 4, 4, 10, intPtr(10), 4, 0, true},
+	}
+
+	for _, tt := range tests {
+		fromStock, backordered, ok := SplitBackorder(tt.policy, tt.requested, tt.available, tt.outstanding, tt.limit)
+		if ok != tt.ok || (ok && (fromStock != tt.fromStock || backordered != tt.backordered)) {
+			t.Errorf("%s: got %d from stock, %d backordered, ok=%v; expected %d, %d, %v",
+				tt.name, fromStock, backordered, ok, tt.fromStock, tt.backordered, tt.ok)
+		}
+	}
+}
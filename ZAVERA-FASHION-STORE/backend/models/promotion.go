@@ -0,0 +1,396 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PromotionType string
+
+const (
+	PromotionTypeQuantityDiscount PromotionType = "QUANTITY_DISCOUNT" // Percent off items in scope once MinQuantity of them are in the cart
+	PromotionTypeSpendTier        PromotionType = "SPEND_TIER"        // Off the items in scope, from the highest tier their total reaches
+	PromotionTypeFreeShipping     PromotionType = "FREE_SHIPPING"     // Shipping, up to MaxDiscount, over MinSpend to the listed provinces
+	PromotionTypeFlashSale        PromotionType = "FLASH_SALE"        // Sale price per variant
+)
+
+func (t PromotionType) IsValid() bool {
+	switch t {
+	case PromotionTypeQuantityDiscount, PromotionTypeSpendTier, PromotionTypeFreeShipping, PromotionTypeFlashSale:
+		return true
+	}
+	return false
+}
+
+// PromotionTier is one step of a SPEND_TIER promotion: Percent off, or else Amount off
+type PromotionTier struct {
+	MinSpend float64 `json:"min_spend"`
+	Percent  float64 `json:"percent,omitempty"`
+	Amount   float64 `json:"amount,omitempty"`
+}
+
+// PromotionTiers is stored as JSONB
+type PromotionTiers []PromotionTier
+
+func (t PromotionTiers) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *PromotionTiers) Scan(value interface{}) error {
+	if value == nil {
+		*t = PromotionTiers{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// PromotionVariantPrice is a flash-sale price of a variant
+type PromotionVariantPrice struct {
+	VariantID int     `json:"variant_id"`
+	SalePrice float64 `json:"sale_price"`
+}
+
+// Promotion is a cart rule that applies on its own, without a code
+type Promotion struct {
+	ID            int                     `json:"id"`
+	Name          string                  `json:"name"`
+	Description   string                  `json:"description,omitempty"`
+	Type          PromotionType           `json:"type"`
+	MinQuantity   int                     `json:"min_quantity,omitempty"`
+	Percent       float64                 `json:"percent,omitempty"`
+	MinSpend      float64                 `json:"min_spend,omitempty"`
+	MaxDiscount   *float64                `json:"max_discount,omitempty"`
+	Tiers         PromotionTiers          `json:"tiers,omitempty"`
+	VariantPrices []PromotionVariantPrice `json:"variant_prices,omitempty"`
+	// Scope: items in the category (or below it) and/or of the brand; none means the whole cart
+	CategoryID *int       `json:"category_id,omitempty"`
+	Brand      *string    `json:"brand,omitempty"`
+	Provinces  []string   `json:"provinces,omitempty"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// ScopeCategoryIDs is CategoryID and every category below it, filled in before evaluating
+	ScopeCategoryIDs []int `json:"-"`
+}
+
+// IsRunning reports whether the promotion is active and within its window at now
+func (p Promotion) IsRunning(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || now.Before(*p.EndsAt)
+}
+
+// InScope reports whether the line matches the promotion's category and brand
+func (p Promotion) InScope(line PromotionLine) bool {
+	if p.CategoryID != nil {
+		if line.CategoryID == nil {
+			return false
+		}
+		found := false
+		for _, id := range p.ScopeCategoryIDs {
+			if id == *line.CategoryID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return p.Brand == nil || strings.EqualFold(strings.TrimSpace(line.Brand), *p.Brand)
+}
+
+// ShipsTo reports whether the destination region (a province or a full area name such as
+// "Bandung, Jawa Barat. 40111") is one of the promotion's provinces
+func (p Promotion) ShipsTo(region string) bool {
+	if len(p.Provinces) == 0 {
+		return true
+	}
+	region = strings.ToLower(region)
+	for _, province := range p.Provinces {
+		if province = strings.ToLower(strings.TrimSpace(province)); province != "" && strings.Contains(region, province) {
+			return true
+		}
+	}
+	return false
+}
+
+// tierFor returns the highest tier the spend reaches, or nil
+func (p Promotion) tierFor(spend float64) *PromotionTier {
+	var best *PromotionTier
+	for i := range p.Tiers {
+		tier := &p.Tiers[i]
+		if spend >= tier.MinSpend && (best == nil || tier.MinSpend > best.MinSpend) {
+			best = tier
+		}
+	}
+	return best
+}
+
+// PromotionLine is a cart or order line as promotions see it
+type PromotionLine struct {
+	Key        int // Cart item ID, to match savings back to the line
+	ProductID  int
+	VariantID  *int
+	CategoryID *int
+	Brand      string
+	Quantity   int
+	UnitPrice  float64
+}
+
+// PromotionShipping is the shipping of an order; the cart has none yet
+type PromotionShipping struct {
+	Cost   float64
+	Region string // Province, or area name, of the destination
+}
+
+// PromotionLineSaving is what a promotion takes off one line
+type PromotionLineSaving struct {
+	ItemID    int     `json:"item_id"`
+	ProductID int     `json:"product_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// AppliedPromotion is a promotion that applies, with its savings line by line
+type AppliedPromotion struct {
+	PromotionID     int                   `json:"promotion_id"`
+	Name            string                `json:"name"`
+	Type            PromotionType         `json:"type"`
+	Savings         float64               `json:"savings"`
+	ShippingSavings float64               `json:"shipping_savings,omitempty"`
+	Lines           []PromotionLineSaving `json:"lines,omitempty"`
+	// AtCheckout marks free shipping the cart qualifies for, applied once the destination is known
+	AtCheckout bool `json:"at_checkout,omitempty"`
+}
+
+// PromotionResult is every promotion that applies to a cart or order
+type PromotionResult struct {
+	Applied         []AppliedPromotion `json:"applied"`
+	ItemSavings     float64            `json:"item_savings"`
+	ShippingSavings float64            `json:"shipping_savings"`
+	LineSavings     map[int]float64    `json:"-"` // By PromotionLine.Key
+}
+
+func (r PromotionResult) Total() float64 {
+	return r.ItemSavings + r.ShippingSavings
+}
+
+// EvaluatePromotions works out the promotions that apply to the lines, in whole rupiah:
+//
+//  1. Flash sales: the lowest sale price of a variant wins. Flash-sale lines take no other
+//     item promotion, but count towards free shipping.
+//  2. Quantity discounts: a line gets the highest percent of the promotions it qualifies for.
+//  3. Spend tiers: the single largest discount, on what the lines in scope cost after 2, spread
+//     over those lines by value.
+//  4. Free shipping: the largest saving of the promotions whose spend (after item savings) is
+//     reached and that ship to the destination. Without shipping (the cart) they are listed
+//     AtCheckout with no saving.
+//
+// The cart and checkout both call this, so they always agree.
+func EvaluatePromotions(promotions []Promotion, lines []PromotionLine, shipping *PromotionShipping, now time.Time) PromotionResult {
+	result := PromotionResult{Applied: []AppliedPromotion{}, LineSavings: map[int]float64{}}
+
+	var running []Promotion
+	for _, p := range promotions {
+		if p.IsRunning(now) {
+			running = append(running, p)
+		}
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].ID < running[j].ID })
+
+	net := make([]float64, len(lines)) // Line total after the savings so far
+	for i, line := range lines {
+		if line.Quantity > 0 {
+			net[i] = line.UnitPrice * float64(line.Quantity)
+		}
+	}
+
+	applied := map[int]int{} // Promotion ID -> index in result.Applied
+	entry := func(p Promotion) *AppliedPromotion {
+		if idx, ok := applied[p.ID]; ok {
+			return &result.Applied[idx]
+		}
+		applied[p.ID] = len(result.Applied)
+		result.Applied = append(result.Applied, AppliedPromotion{PromotionID: p.ID, Name: p.Name, Type: p.Type})
+		return &result.Applied[len(result.Applied)-1]
+	}
+	save := func(p Promotion, i int, amount float64) {
+		amount = math.Min(amount, net[i])
+		if amount <= 0 {
+			return
+		}
+		e := entry(p)
+		e.Savings += amount
+		e.Lines = append(e.Lines, PromotionLineSaving{ItemID: lines[i].Key, ProductID: lines[i].ProductID, Amount: amount})
+		net[i] -= amount
+		result.LineSavings[lines[i].Key] += amount
+		result.ItemSavings += amount
+	}
+
+	// 1. Flash sales
+	flash := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.VariantID == nil || net[i] <= 0 {
+			continue
+		}
+		var best *Promotion
+		bestPrice := line.UnitPrice
+		for j := range running {
+			if running[j].Type != PromotionTypeFlashSale {
+				continue
+			}
+			for _, vp := range running[j].VariantPrices {
+				if vp.VariantID == *line.VariantID && vp.SalePrice < bestPrice {
+					best, bestPrice = &running[j], vp.SalePrice
+				}
+			}
+		}
+		if best != nil {
+			flash[i] = true
+			save(*best, i, math.Round((line.UnitPrice-bestPrice)*float64(line.Quantity)))
+		}
+	}
+
+	// 2. Quantity discounts
+	bestPercent := make([]float64, len(lines))
+	bestQuantity := make([]*Promotion, len(lines))
+	for j := range running {
+		p := &running[j]
+		if p.Type != PromotionTypeQuantityDiscount || p.Percent <= 0 {
+			continue
+		}
+		quantity := 0
+		for i, line := range lines {
+			if !flash[i] && net[i] > 0 && p.InScope(line) {
+				quantity += line.Quantity
+			}
+		}
+		if quantity == 0 || quantity < p.MinQuantity {
+			continue
+		}
+		for i, line := range lines {
+			if !flash[i] && net[i] > 0 && p.InScope(line) && p.Percent > bestPercent[i] {
+				bestPercent[i], bestQuantity[i] = p.Percent, p
+			}
+		}
+	}
+	for i := range lines {
+		if bestQuantity[i] != nil {
+			save(*bestQuantity[i], i, math.Round(net[i]*bestPercent[i]/100))
+		}
+	}
+
+	// 3. Spend tiers
+	var bestTier *Promotion
+	var bestTierAmount float64
+	var tierLines []int
+	for j := range running {
+		p := &running[j]
+		if p.Type != PromotionTypeSpendTier {
+			continue
+		}
+		var spend float64
+		var eligible []int
+		for i, line := range lines {
+			if !flash[i] && net[i] > 0 && p.InScope(line) {
+				spend += net[i]
+				eligible = append(eligible, i)
+			}
+		}
+		tier := p.tierFor(spend)
+		if spend <= 0 || tier == nil {
+			continue
+		}
+		amount := tier.Amount
+		if tier.Percent > 0 {
+			amount = math.Round(spend * tier.Percent / 100)
+			if p.MaxDiscount != nil && amount > *p.MaxDiscount {
+				amount = *p.MaxDiscount
+			}
+		}
+		amount = math.Min(amount, spend)
+		if amount > bestTierAmount {
+			bestTier, bestTierAmount, tierLines = p, amount, eligible
+		}
+	}
+	if bestTier != nil {
+		var spend float64
+		for _, i := range tierLines {
+			spend += net[i]
+		}
+		// Shares are worked out before any is taken off; the last line gets the rounding
+		shares := make([]float64, len(tierLines))
+		remaining := bestTierAmount
+		for k, i := range tierLines {
+			if k == len(tierLines)-1 {
+				shares[k] = remaining
+			} else {
+				shares[k] = math.Round(bestTierAmount * net[i] / spend)
+				remaining -= shares[k]
+			}
+		}
+		for k, i := range tierLines {
+			save(*bestTier, i, shares[k])
+		}
+	}
+
+	// 4. Free shipping
+	var bestShipping *Promotion
+	var bestShippingAmount float64
+	for j := range running {
+		p := &running[j]
+		if p.Type != PromotionTypeFreeShipping {
+			continue
+		}
+		var spend float64
+		for i, line := range lines {
+			if net[i] > 0 && p.InScope(line) {
+				spend += net[i]
+			}
+		}
+		if spend <= 0 || spend < p.MinSpend {
+			continue
+		}
+		if shipping == nil {
+			entry(*p).AtCheckout = true
+			continue
+		}
+		if shipping.Cost <= 0 || !p.ShipsTo(shipping.Region) {
+			continue
+		}
+		amount := shipping.Cost
+		if p.MaxDiscount != nil && amount > *p.MaxDiscount {
+			amount = *p.MaxDiscount
+		}
+		if amount > bestShippingAmount {
+			bestShipping, bestShippingAmount = p, amount
+		}
+	}
+	if bestShipping != nil {
+		e := entry(*bestShipping)
+		e.ShippingSavings = bestShippingAmount
+		e.Savings += bestShippingAmount
+		result.ShippingSavings = bestShippingAmount
+	}
+
+	return result
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluatePromotions(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tops, shoes := 1, 2
+	lines := []PromotionLine{
+		{Key: 11, ProductID: 1, VariantID: intPtr(101), CategoryID: &tops, Brand: "Zavera", Quantity: 2, UnitPrice: 200000},
+		{Key: 12, ProductID: 2, VariantID: intPtr(201), CategoryID: &tops, Brand: "Zavera", Quantity: 1, UnitPrice: 100000},
+		{Key: 13, ProductID: 3, VariantID: intPtr(301), CategoryID: &shoes, Brand: "Other", Quantity: 1, UnitPrice: 300000},
+	}
+
+	promotions := []Promotion{
+		{ID: 1, Name: "Flash sale", Type: PromotionTypeFlashSale, IsActive: true,
+			VariantPrices: []PromotionVariantPrice{{VariantID: 301, SalePrice: 250000}}},
+		{ID: 2, Name: "Buy 2 tops, get 10% off", Type: PromotionTypeQuantityDiscount, IsActive: true,
+			MinQuantity: 2, Percent: 10, CategoryID: &tops, ScopeCategoryIDs: []int{tops}},
+		{ID: 3, Name: "Spend more, save more", Type: PromotionTypeSpendTier, IsActive: true,
+			Tiers: PromotionTiers{{MinSpend: 200000, Amount: 20000}, {MinSpend: 400000, Amount: 40000}}},
+		{ID: 4, Name: "Free shipping over Rp500k to Java", Type: PromotionTypeFreeShipping, IsActive: true,
+			MinSpend: 500000, MaxDiscount: floatPtr(30000), Provinces: []string{"Jawa Barat", "DKI Jakarta"}},
+		{ID: 5, Name: "Ended", Type: PromotionTypeQuantityDiscount, IsActive: true,
+			Percent: 50, EndsAt: timePtr(now)},
+	}
+
+	shipping := &PromotionShipping{Cost: 45000, Region: "Bandung, Jawa Barat. 40111"}
+	result := EvaluatePromotions(promotions, lines, shipping, now)
+
+	// Flash sale 50k on the shoes; 10% off 500k of tops (50k); tier on the remaining 450k of
+	// tops (40k, split 32k/8k); shipping capped at 30k
+	if result.ItemSavings != 140000 || result.ShippingSavings != 30000 {
+		t.Fatalf("got items %.0f shipping %.0f, expected 140000 and 30000", result.ItemSavings, result.ShippingSavings)
+	}
+	if len(result.Applied) != 4 {
+		t.Fatalf("expected 4 promotions applied, got %d", len(result.Applied))
+	}
+	expected := map[int]float64{11: 40000 + 32000, 12: 10000 + 8000, 13: 50000}
+	for key, amount := range expected {
+		if result.LineSavings[key] != amount {
+			t.Errorf("line %d: got savings %.0f, expected %.0f", key, result.LineSavings[key], amount)
+		}
+	}
+
+	// Outside Java the shipping promotion does not apply
+	result = EvaluatePromotions(promotions, lines, &PromotionShipping{Cost: 45000, Region: "Bali"}, now)
+	if result.ShippingSavings != 0 {
+		t.Errorf("expected no shipping savings outside Java, got %.0f", result.ShippingSavings)
+	}
+
+	// The cart has no destination yet: free shipping is listed for checkout without a saving
+	result = EvaluatePromotions(promotions, lines, nil, now)
+	last := result.Applied[len(result.Applied)-1]
+	if last.PromotionID != 4 || !last.AtCheckout || last.Savings != 0 {
+		t.Errorf("expected free shipping listed for checkout, got %+v", last)
+	}
+}
+
+func TestEvaluatePromotionsThresholds(t *testing.T) {
+	now := time.Now()
+	lines := []PromotionLine{{Key: 1, ProductID: 1, Quantity: 1, UnitPrice: 150000}}
+	promotions := []Promotion{
+		{ID: 1, Type: PromotionTypeQuantityDiscount, IsActive: true, MinQuantity: 2, Percent: 10},
+		{ID: 2, Type: PromotionTypeSpendTier, IsActive: true, Tiers: PromotionTiers{{MinSpend: 200000, Percent: 5}}},
+		{ID: 3, Type: PromotionTypeFreeShipping, IsActive: false},
+	}
+
+	result := EvaluatePromotions(promotions, lines, &PromotionShipping{Cost: 20000}, now)
+	if result.Total() != 0 || len(result.Applied) != 0 {
+		t.Errorf("expected no promotion below its thresholds, got %+v", result)
+	}
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Supplier is a vendor purchase orders are sent to
+type Supplier struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code"`
+	Name         string    `json:"name"`
+	ContactName  string    `json:"contact_name,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	Phone        string    `json:"phone,omitempty"`
+	Address      string    `json:"address,omitempty"`
+	LeadTimeDays int       `json:"lead_time_days"` // Default expected arrival after sending
+	Notes        string    `json:"notes,omitempty"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "DRAFT"
+	PurchaseOrderSent              PurchaseOrderStatus = "SENT"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "PARTIALLY_RECEIVED"
+	PurchaseOrderReceived          PurchaseOrderStatus = "RECEIVED"
+	PurchaseOrderCancelled         PurchaseOrderStatus = "CANCELLED"
+)
+
+// CanTransitionTo reports whether an admin may move a PO to next by hand.
+// PARTIALLY_RECEIVED and RECEIVED are only reached by recording receipts.
+func (s PurchaseOrderStatus) CanTransitionTo(next PurchaseOrderStatus) bool {
+	switch next {
+	case PurchaseOrderSent:
+		return s == PurchaseOrderDraft
+	case PurchaseOrderCancelled:
+		return s == PurchaseOrderDraft || s == PurchaseOrderSent || s == PurchaseOrderPartiallyReceived
+	}
+	return false
+}
+
+// IsIncoming reports whether the PO's outstanding quantities count as incoming stock
+func (s PurchaseOrderStatus) IsIncoming() bool {
+	return s == PurchaseOrderSent || s == PurchaseOrderPartiallyReceived
+}
+
+// IsEditable reports whether lines, supplier and warehouse may still change
+func (s PurchaseOrderStatus) IsEditable() bool {
+	return s == PurchaseOrderDraft
+}
+
+// PurchaseOrder is an order for stock from a supplier, received into one warehouse
+type PurchaseOrder struct {
+	ID            int                 `json:"id"`
+	PONumber      string              `json:"po_number"`
+	SupplierID    int                 `json:"supplier_id"`
+	SupplierName  string              `json:"supplier_name"`
+	WarehouseID   int                 `json:"warehouse_id"`
+	WarehouseCode string              `json:"warehouse_code"`
+	Status        PurchaseOrderStatus `json:"status"`
+	ExpectedAt    *time.Time          `json:"expected_at,omitempty"`
+	Notes         string              `json:"notes,omitempty"`
+	CreatedBy     string              `json:"created_by"`
+	SentAt        *time.Time          `json:"sent_at,omitempty"`
+	ReceivedAt    *time.Time          `json:"received_at,omitempty"`
+	CancelledAt   *time.Time          `json:"cancelled_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+
+	Items    []PurchaseOrderItem    `json:"items,omitempty"`
+	Receipts []PurchaseOrderReceipt `json:"receipts,omitempty"`
+}
+
+// TotalCost is the cost of everything ordered
+func (po *PurchaseOrder) TotalCost() float64 {
+	total := 0.0
+	for _, item := range po.Items {
+		total += item.CostPerItem * float64(item.QuantityOrdered)
+	}
+	return total
+}
+
+// PurchaseOrderItem is one variant on a purchase order
+type PurchaseOrderItem struct {
+	ID               int        `json:"id"`
+	PurchaseOrderID  int        `json:"purchase_order_id"`
+	VariantID        int        `json:"variant_id"`
+	SKU              string     `json:"sku"`
+	ProductName      string     `json:"product_name"`
+	VariantName      string     `json:"variant_name"`
+	QuantityOrdered  int        `json:"quantity_ordered"`
+	QuantityReceived int        `json:"quantity_received"`
+	CostPerItem      float64    `json:"cost_per_item"`
+	ExpectedAt       *time.Time `json:"expected_at,omitempty"` // nil = the PO's expected date
+}
+
+// Outstanding is what has not arrived yet
+func (i PurchaseOrderItem) Outstanding() int {
+	if i.QuantityReceived >= i.QuantityOrdered {
+		return 0
+	}
+	return i.QuantityOrdered - i.QuantityReceived
+}
+
+// PurchaseOrderReceipt is one delivery booked against a purchase order
+type PurchaseOrderReceipt struct {
+	ID              int                        `json:"id"`
+	PurchaseOrderID int                        `json:"purchase_order_id"`
+	ReceivedBy      string                     `json:"received_by"`
+	Notes           string                     `json:"notes,omitempty"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	Lines           []PurchaseOrderReceiptLine `json:"lines"`
+}
+
+// PurchaseOrderReceiptLine is the quantity of one PO line in a receipt and its ledger entry
+type PurchaseOrderReceiptLine struct {
+	PurchaseOrderItemID int   `json:"purchase_order_item_id"`
+	VariantID           int   `json:"variant_id"`
+	Quantity            int   `json:"quantity"`
+	LedgerID            int64 `json:"ledger_id"`
+}
+
+// PurchaseOrderFilter narrows a PO listing; zero values are ignored
+type PurchaseOrderFilter struct {
+	Status     PurchaseOrderStatus
+	SupplierID int
+	VariantID  int
+}
+
+// MovingAverageCost blends received units at cost into the current cost of the units on hand.
+// Without a current cost (or with nothing on hand) the receipt cost is used as is.
+func MovingAverageCost(onHand int, currentCost *float64, received int, cost float64) float64 {
+	if currentCost == nil || onHand <= 0 {
+		return cost
+	}
+	total := float64(onHand)*(*currentCost) + float64(received)*cost
+	return math.Round(total/float64(onHand+received)*100) / 100
+}
@@ -0,0 +1,59 @@
+package models
+
+import "testing"
+
+func TestPurchaseOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to PurchaseOrderStatus
+		allowed  bool
+	}{
+		{PurchaseOrderDraft, PurchaseOrderSent, true},
+		{PurchaseOrderDraft, PurchaseOrderCancelled, true},
+		{PurchaseOrderSent, PurchaseOrderCancelled, true},
+		{PurchaseOrderPartiallyReceived, PurchaseOrderCancelled, true},
+		{PurchaseOrderSent, PurchaseOrderSent, false},
+		{PurchaseOrderSent, PurchaseOrderReceived, false}, // only by receipts
+		{PurchaseOrderReceived, PurchaseOrderCancelled, false},
+		{PurchaseOrderCancelled, PurchaseOrderSent, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.allowed {
+			t.Errorf("%s -> %s: got %v, expected %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestPurchaseOrderItemOutstanding(t *testing.T) {
+	item := PurchaseOrderItem{QuantityOrdered: 10, QuantityReceived: 4}
+	if got := item.Outstanding(); got != 6 {
+		t.Errorf("got %d outstanding, expected 6", got)
+	}
+
+	item.QuantityReceived = 10
+	if got := item.Outstanding(); got != 0 {
+		t.Errorf("got %d outstanding for a received line, expected 0", got)
+	}
+}
+
+func TestMovingAverageCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		onHand   int
+		current  *float64
+		received int
+		cost     float64
+		expected float64
+	}{
+		{"no cost yet", 10, nil, 5, 50000, 50000},
+		{"nothing on hand", 0, floatPtr(40000), 5, 50000, 50000},
+		{"blended", 10, floatPtr(40000), 10, 50000, 45000},
+		{"rounded to cents", 2, floatPtr(10), 1, 11, 10.33},
+	}
+
+	for _, tt := range tests {
+		if got := MovingAverageCost(tt.onHand, tt.current, tt.received, tt.cost); got != tt.expected {
+			t.Errorf("%s: got %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
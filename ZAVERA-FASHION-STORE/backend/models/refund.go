@@ -41,6 +41,16 @@ const (
 	RefundReasonOther           RefundReason = "OTHER"
 )
 
+// RefundDestination is where refunded money goes
+type RefundDestination string
+
+const (
+	// Back through Midtrans, except what the order paid with store credit
+	RefundDestinationOriginal RefundDestination = "ORIGINAL_PAYMENT"
+	// All of it to the customer's store credit, instantly
+	RefundDestinationStoreCredit RefundDestination = "STORE_CREDIT"
+)
+
 // Refund represents a refund request
 type Refund struct {
 	ID               int            `json:"id" db:"id"`
@@ -54,6 +64,9 @@ type Refund struct {
 	RefundAmount     float64        `json:"refund_amount" db:"refund_amount"`
 	ShippingRefund   float64        `json:"shipping_refund" db:"shipping_refund"`
 	ItemsRefund      float64        `json:"items_refund" db:"items_refund"`
+	TaxRefund        float64        `json:"tax_refund" db:"tax_refund"` // PPN contained in RefundAmount
+	Destination      RefundDestination `json:"destination" db:"refund_destination"`
+	StoreCreditAmount float64       `json:"store_credit_amount" db:"store_credit_amount"` // Part of RefundAmount returned as store credit
 	Status           RefundStatus   `json:"status" db:"status"`
 	GatewayRefundID  *string        `json:"gateway_refund_id,omitempty" db:"gateway_refund_id"`
 	GatewayStatus    *string        `json:"gateway_status,omitempty" db:"gateway_status"`
@@ -74,11 +87,14 @@ type RefundItem struct {
 	ID              int        `json:"id" db:"id"`
 	RefundID        int        `json:"refund_id" db:"refund_id"`
 	OrderItemID     int        `json:"order_item_id" db:"order_item_id"`
+	// Set for a bundle component; Quantity is then in units of the component
+	OrderItemComponentID *int  `json:"order_item_component_id,omitempty" db:"order_item_component_id"`
 	ProductID       int        `json:"product_id" db:"product_id"`
 	ProductName     string     `json:"product_name" db:"product_name"`
 	Quantity        int        `json:"quantity" db:"quantity"`
 	PricePerUnit    float64    `json:"price_per_unit" db:"price_per_unit"`
 	RefundAmount    float64    `json:"refund_amount" db:"refund_amount"`
+	TaxAmount       float64    `json:"tax_amount" db:"tax_amount"` // PPN contained in RefundAmount
 	ItemReason      string     `json:"item_reason,omitempty" db:"item_reason"`
 	StockRestored   bool       `json:"stock_restored" db:"stock_restored"`
 	StockRestoredAt *time.Time `json:"stock_restored_at,omitempty" db:"stock_restored_at"`
@@ -114,3 +130,8 @@ func (s RefundStatus) CanProcess() bool {
 func (s RefundStatus) CanCancel() bool {
 	return s == RefundStatusPending || s == RefundStatusProcessing
 }
+
+// GatewayAmount is the part of the refund paid back through Midtrans
+func (r *Refund) GatewayAmount() float64 {
+	return r.RefundAmount - r.StoreCreditAmount
+}
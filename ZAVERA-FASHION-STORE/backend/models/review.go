@@ -0,0 +1,140 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ReviewStatus is the moderation state of a review
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "PENDING"
+	ReviewStatusApproved ReviewStatus = "APPROVED"
+	ReviewStatusRejected ReviewStatus = "REJECTED"
+)
+
+// ReviewFit is the customer's size feedback
+type ReviewFit string
+
+const (
+	ReviewFitSmall ReviewFit = "SMALL" // Runs small
+	ReviewFitTrue  ReviewFit = "TRUE"  // True to size
+	ReviewFitLarge ReviewFit = "LARGE" // Runs large
+)
+
+// IsValid checks if the fit value is known
+func (f ReviewFit) IsValid() bool {
+	switch f {
+	case ReviewFitSmall, ReviewFitTrue, ReviewFitLarge:
+		return true
+	}
+	return false
+}
+
+// ProductReview is a customer review of a purchased order item
+type ProductReview struct {
+	ID                 int          `json:"id" db:"id"`
+	ProductID          int          `json:"product_id" db:"product_id"`
+	VariantID          *int         `json:"variant_id,omitempty" db:"variant_id"`
+	OrderID            *int         `json:"order_id,omitempty" db:"order_id"`
+	OrderItemID        *int         `json:"order_item_id,omitempty" db:"order_item_id"`
+	UserID             *int         `json:"user_id,omitempty" db:"user_id"`
+	ReviewerName       string       `json:"reviewer_name" db:"reviewer_name"`
+	Rating             int          `json:"rating" db:"rating"`
+	Title              string       `json:"title" db:"title"`
+	Body               string       `json:"body" db:"body"`
+	Fit                *ReviewFit   `json:"fit,omitempty" db:"fit"`
+	IsVerifiedPurchase bool         `json:"is_verified_purchase" db:"is_verified_purchase"`
+	Status             ReviewStatus `json:"status" db:"status"`
+	ModerationNote     string       `json:"moderation_note,omitempty" db:"moderation_note"`
+	ModeratedBy        string       `json:"moderated_by,omitempty" db:"moderated_by"`
+	ModeratedAt        *time.Time   `json:"moderated_at,omitempty" db:"moderated_at"`
+	CreatedAt          time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at" db:"updated_at"`
+	Photos             []string     `json:"photos" db:"-"`
+	// Joined for admin and customer listings
+	ProductName string `json:"product_name,omitempty" db:"-"`
+	VariantName string `json:"variant_name,omitempty" db:"-"`
+}
+
+// ReviewableItem is a delivered order item the customer has not reviewed yet
+type ReviewableItem struct {
+	OrderItemID  int       `json:"order_item_id"`
+	OrderID      int       `json:"order_id"`
+	OrderCode    string    `json:"order_code"`
+	ProductID    int       `json:"product_id"`
+	VariantID    *int      `json:"variant_id,omitempty"`
+	ProductName  string    `json:"product_name"`
+	ProductImage string    `json:"product_image"`
+	DeliveredAt  time.Time `json:"delivered_at"`
+}
+
+// ReviewListFilter selects and orders reviews
+type ReviewListFilter struct {
+	ProductID  int
+	Status     ReviewStatus // Empty = any status (admin)
+	Rating     int          // 0 = any
+	WithPhotos bool
+	Sort       string // newest (default), highest, lowest
+	Limit      int
+	Offset     int
+}
+
+// ProductRatingSummary aggregates approved reviews of a product
+type ProductRatingSummary struct {
+	ProductID    int
+	Count        int
+	Average      float64
+	Distribution [5]int // Index 0 = 1 star ... index 4 = 5 stars
+	FitSmall     int
+	FitTrue      int
+	FitLarge     int
+}
+
+// Fit verdicts
+const (
+	FitVerdictRunsSmall  = "runs_small"
+	FitVerdictTrueToSize = "true_to_size"
+	FitVerdictRunsLarge  = "runs_large"
+)
+
+// minFitVotes is the number of fit answers needed before a verdict is shown
+const minFitVotes = 3
+
+// FitVotes returns the number of reviews that answered the fit question
+func (s ProductRatingSummary) FitVotes() int {
+	return s.FitSmall + s.FitTrue + s.FitLarge
+}
+
+// FitVerdict summarises fit feedback. A product runs small or large only when that answer
+// outnumbers the other two combined; anything less decisive counts as true to size.
+// Returns "" until enough customers have answered.
+func (s ProductRatingSummary) FitVerdict() string {
+	votes := s.FitVotes()
+	if votes < minFitVotes {
+		return ""
+	}
+	switch {
+	case s.FitSmall*2 > votes:
+		return FitVerdictRunsSmall
+	case s.FitLarge*2 > votes:
+		return FitVerdictRunsLarge
+	default:
+		return FitVerdictTrueToSize
+	}
+}
+
+// MaskReviewerName shortens a full name for public display ("Rina Sari Dewi" -> "Rina D.")
+func MaskReviewerName(name string) string {
+	parts := strings.Fields(name)
+	switch len(parts) {
+	case 0:
+		return "Pelanggan ZAVERA"
+	case 1:
+		return parts[0]
+	default:
+		last := []rune(parts[len(parts)-1])
+		return parts[0] + " " + strings.ToUpper(string(last[0])) + "."
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestProductRatingSummaryFitVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  ProductRatingSummary
+		expected string
+	}{
+		{"too few answers", ProductRatingSummary{FitSmall: 2}, ""},
+		{"clear majority small", ProductRatingSummary{FitSmall: 6, FitTrue: 3, FitLarge: 1}, FitVerdictRunsSmall},
+		{"clear majority large", ProductRatingSummary{FitSmall: 0, FitTrue: 1, FitLarge: 3}, FitVerdictRunsLarge},
+		{"split opinions", ProductRatingSummary{FitSmall: 2, FitTrue: 1, FitLarge: 1}, FitVerdictTrueToSize},
+		{"mostly true", ProductRatingSummary{FitSmall: 1, FitTrue: 8, FitLarge: 1}, FitVerdictTrueToSize},
+	}
+
+	for _, tt := range tests {
+		if got := tt.summary.FitVerdict(); got != tt.expected {
+			t.Errorf("%s: FitVerdict() = %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestMaskReviewerName(t *testing.T) {
+	tests := map[string]string{
+		"":                "Pelanggan ZAVERA",
+		"Rina":            "Rina",
+		"Rina Sari Dewi":  "Rina D.",
+		"  budi  santoso": "budi S.",
+	}
+
+	for input, expected := range tests {
+		if got := MaskReviewerName(input); got != expected {
+			t.Errorf("MaskReviewerName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SizeChart holds body measurements per size for a category, a brand, or a brand within a
+// category. The most specific active chart applies to a product (brand first, then the deepest
+// category).
+type SizeChart struct {
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	CategoryID *int           `json:"category_id,omitempty"`
+	Brand      *string        `json:"brand,omitempty"`
+	Notes      string         `json:"notes,omitempty"`
+	IsActive   bool           `json:"is_active"`
+	Rows       []SizeChartRow `json:"rows"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// SizeChartRow is one size of a chart. Ranges are body measurements in cm (weight in kg);
+// nil bounds are open.
+type SizeChartRow struct {
+	ID          int      `json:"id"`
+	ChartID     int      `json:"chart_id"`
+	SizeLabel   string   `json:"size_label"`
+	Position    int      `json:"position"`
+	ChestMinCm  *float64 `json:"chest_min_cm,omitempty"`
+	ChestMaxCm  *float64 `json:"chest_max_cm,omitempty"`
+	WaistMinCm  *float64 `json:"waist_min_cm,omitempty"`
+	WaistMaxCm  *float64 `json:"waist_max_cm,omitempty"`
+	HipMinCm    *float64 `json:"hip_min_cm,omitempty"`
+	HipMaxCm    *float64 `json:"hip_max_cm,omitempty"`
+	HeightMinCm *float64 `json:"height_min_cm,omitempty"`
+	HeightMaxCm *float64 `json:"height_max_cm,omitempty"`
+	WeightMinKg *float64 `json:"weight_min_kg,omitempty"`
+	WeightMaxKg *float64 `json:"weight_max_kg,omitempty"`
+	LengthCm    *float64 `json:"length_cm,omitempty"`
+	// Variants of the requested product in this size (product size charts only)
+	VariantIDs []int `json:"variant_ids,omitempty"`
+}
+
+// FitProfile is a customer's body measurements and usual size per brand (brand in lowercase)
+type FitProfile struct {
+	UserID     int               `json:"user_id"`
+	HeightCm   *float64          `json:"height_cm,omitempty"`
+	WeightKg   *float64          `json:"weight_kg,omitempty"`
+	ChestCm    *float64          `json:"chest_cm,omitempty"`
+	WaistCm    *float64          `json:"waist_cm,omitempty"`
+	HipCm      *float64          `json:"hip_cm,omitempty"`
+	UsualSizes map[string]string `json:"usual_sizes"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// UsualSizeFor returns the size the customer usually wears in the brand
+func (p FitProfile) UsualSizeFor(brand string) string {
+	return p.UsualSizes[strings.ToLower(strings.TrimSpace(brand))]
+}
+
+type SizeConfidence string
+
+const (
+	SizeConfidenceHigh   SizeConfidence = "HIGH"
+	SizeConfidenceMedium SizeConfidence = "MEDIUM"
+	SizeConfidenceLow    SizeConfidence = "LOW"
+)
+
+// sizeNearMissCm is how far outside the ranges a profile may be for a MEDIUM recommendation
+const sizeNearMissCm = 3
+
+// SizeRecommendation is the suggested size of a product for a fit profile
+type SizeRecommendation struct {
+	SizeLabel  string         `json:"size_label"`
+	RowID      int            `json:"row_id"`
+	Confidence SizeConfidence `json:"confidence"`
+	Reason     string         `json:"reason"`
+}
+
+// RecommendSize picks a size from the chart for the profile. The usual size in the product's
+// brand wins when the chart has it; otherwise the row whose ranges the measurements miss by the
+// least, preferring the larger size between two equally close ones. Returns nil when the profile
+// shares no measurement with the chart and has no usual size for the brand.
+func RecommendSize(rows []SizeChartRow, profile FitProfile, brand string) *SizeRecommendation {
+	if usual := profile.UsualSizeFor(brand); usual != "" {
+		for _, row := range rows {
+			if strings.EqualFold(row.SizeLabel, usual) {
+				return &SizeRecommendation{
+					SizeLabel:  row.SizeLabel,
+					RowID:      row.ID,
+					Confidence: SizeConfidenceHigh,
+					Reason:     fmt.Sprintf("Your usual size in %s", brand),
+				}
+			}
+		}
+	}
+
+	var best *SizeChartRow
+	bestMiss, bestCompared := 0.0, 0
+	for i := range rows {
+		miss, compared := rows[i].fitMiss(profile)
+		if compared == 0 {
+			continue
+		}
+		// Rows are smallest first, so ties go to the larger size
+		if best == nil || compared > bestCompared || (compared == bestCompared && miss <= bestMiss) {
+			best, bestMiss, bestCompared = &rows[i], miss, compared
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	recommendation := &SizeRecommendation{SizeLabel: best.SizeLabel, RowID: best.ID}
+	switch {
+	case bestMiss == 0 && bestCompared >= 2:
+		recommendation.Confidence = SizeConfidenceHigh
+		recommendation.Reason = "All your measurements fall within this size"
+	case bestMiss <= sizeNearMissCm:
+		recommendation.Confidence = SizeConfidenceMedium
+		recommendation.Reason = "Closest size to your measurements"
+	default:
+		recommendation.Confidence = SizeConfidenceLow
+		recommendation.Reason = "Your measurements are outside this chart; this is the closest size"
+	}
+	return recommendation
+}
+
+// fitMiss adds up how far the profile's measurements fall outside the row's ranges and counts
+// the measurements both have
+func (r SizeChartRow) fitMiss(p FitProfile) (float64, int) {
+	measurements := []struct {
+		value    *float64
+		min, max *float64
+	}{
+		{p.ChestCm, r.ChestMinCm, r.ChestMaxCm},
+		{p.WaistCm, r.WaistMinCm, r.WaistMaxCm},
+		{p.HipCm, r.HipMinCm, r.HipMaxCm},
+		{p.HeightCm, r.HeightMinCm, r.HeightMaxCm},
+		{p.WeightKg, r.WeightMinKg, r.WeightMaxKg},
+	}
+
+	miss, compared := 0.0, 0
+	for _, m := range measurements {
+		if m.value == nil || (m.min == nil && m.max == nil) {
+			continue
+		}
+		compared++
+		if m.min != nil && *m.value < *m.min {
+			miss += *m.min - *m.value
+		} else if m.max != nil && *m.value > *m.max {
+			miss += *m.value - *m.max
+		}
+	}
+	return miss, compared
+}
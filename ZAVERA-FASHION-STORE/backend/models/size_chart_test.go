@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+func testSizeChartRows() []SizeChartRow {
+	return []SizeChartRow{
+		{ID: 1, SizeLabel: "S", ChestMinCm: floatPtr(84), ChestMaxCm: floatPtr(88), WaistMinCm: floatPtr(66), WaistMaxCm: floatPtr(70)},
+		{ID: 2, SizeLabel: "M", ChestMinCm: floatPtr(89), ChestMaxCm: floatPtr(94), WaistMinCm: floatPtr(71), WaistMaxCm: floatPtr(76)},
+		{ID: 3, SizeLabel: "L", ChestMinCm: floatPtr(95), ChestMaxCm: floatPtr(100), WaistMinCm: floatPtr(77), WaistMaxCm: floatPtr(82)},
+	}
+}
+
+func TestRecommendSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    FitProfile
+		brand      string
+		size       string
+		confidence SizeConfidence
+	}{
+		{
+			"within ranges",
+			FitProfile{ChestCm: floatPtr(92), WaistCm: floatPtr(74)},
+			"Zavera", "M", SizeConfidenceHigh,
+		},
+		{
+			"usual size in the brand wins",
+			FitProfile{ChestCm: floatPtr(92), WaistCm: floatPtr(74), UsualSizes: map[string]string{"zavera": "l"}},
+			" Zavera", "L", SizeConfidenceHigh,
+		},
+		{
+			"usual size missing from the chart falls back to measurements",
+			FitProfile{ChestCm: floatPtr(86), UsualSizes: map[string]string{"zavera": "XXL"}},
+			"Zavera", "S", SizeConfidenceMedium,
+		},
+		{
+			"between sizes goes up",
+			FitProfile{ChestCm: floatPtr(88.5)},
+			"", "M", SizeConfidenceMedium,
+		},
+		{
+			"far outside the chart",
+			FitProfile{ChestCm: floatPtr(120), WaistCm: floatPtr(100)},
+			"", "L", SizeConfidenceLow,
+		},
+	}
+
+	for _, tt := range tests {
+		got := RecommendSize(testSizeChartRows(), tt.profile, tt.brand)
+		if got == nil {
+			t.Errorf("%s: got no recommendation", tt.name)
+			continue
+		}
+		if got.SizeLabel != tt.size || got.Confidence != tt.confidence {
+			t.Errorf("%s: got %s (%s), expected %s (%s)", tt.name, got.SizeLabel, got.Confidence, tt.size, tt.confidence)
+		}
+	}
+}
+
+func TestRecommendSizeWithoutData(t *testing.T) {
+	// Only height, which the chart does not measure
+	profile := FitProfile{HeightCm: floatPtr(170), UsualSizes: map[string]string{"other": "M"}}
+	if got := RecommendSize(testSizeChartRows(), profile, "Zavera"); got != nil {
+		t.Errorf("expected no recommendation, got %s", got.SizeLabel)
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+type StockAlertStatus string
+
+const (
+	StockAlertActive    StockAlertStatus = "ACTIVE"
+	StockAlertNotified  StockAlertStatus = "NOTIFIED"
+	StockAlertCancelled StockAlertStatus = "CANCELLED"
+)
+
+// StockAlert is a user's one-shot subscription to a sold-out variant (size/color)
+type StockAlert struct {
+	ID          int              `json:"id"`
+	UserID      int              `json:"user_id"`
+	VariantID   int              `json:"variant_id"`
+	ProductID   int              `json:"product_id"`
+	ProductName string           `json:"product_name"`
+	VariantName string           `json:"variant_name"`
+	SKU         string           `json:"sku"`
+	Status      StockAlertStatus `json:"status"`
+	Available   int              `json:"available"`
+	NotifiedAt  *time.Time       `json:"notified_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// AlertRecipient is a user to email about a product
+type AlertRecipient struct {
+	UserID int
+	Email  string
+	Name   string
+}
+
+// DueStockAlert is an active subscription whose variant is available again
+type DueStockAlert struct {
+	StockAlert
+	Recipient AlertRecipient
+	Price     float64
+}
+
+// VariantAvailabilityChange is a variant whose available quantity changed since the last check
+type VariantAvailabilityChange struct {
+	VariantID   int
+	ProductID   int
+	ProductName string
+	VariantName string
+	Price       float64
+	Previous    *int // nil the first time the variant is seen
+	Available   int
+}
+
+// IsRestock reports whether the variant went from sold out to available.
+// A variant seen for the first time is never a restock.
+func (c VariantAvailabilityChange) IsRestock() bool {
+	return c.Previous != nil && *c.Previous <= 0 && c.Available > 0
+}
+
+// DueWishlistRestock is a queued back-in-stock alert for a wishlist whose variant is available
+type DueWishlistRestock struct {
+	ID          int
+	Recipient   AlertRecipient
+	ProductID   int
+	ProductName string
+	VariantName string
+	Price       float64
+}
+
+// PriceDrop is a wishlisted product now cheaper than the price the user saw
+type PriceDrop struct {
+	WishlistID  int
+	Recipient   AlertRecipient
+	ProductID   int
+	ProductName string
+	PriceSeen   float64
+	Price       float64
+}
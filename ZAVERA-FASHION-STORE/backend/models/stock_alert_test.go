@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestVariantAvailabilityChangeIsRestock(t *testing.T) {
+	tests := []struct {
+		name      string
+		previous  *int
+		available int
+		expected  bool
+	}{
+		{"sold out to available", intPtr(0), 3, true},
+		{"still sold out", intPtr(0), 0, false},
+		{"more stock", intPtr(2), 5, false},
+		{"sold out now", intPtr(2), 0, false},
+		{"first seen", nil, 4, false},
+	}
+
+	for _, tt := range tests {
+		change := VariantAvailabilityChange{Previous: tt.previous, Available: tt.available}
+		if got := change.IsRestock(); got != tt.expected {
+			t.Errorf("%s: got %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
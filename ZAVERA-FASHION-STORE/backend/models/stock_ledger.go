@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// StockLedgerEntry is one append-only change to the on-hand stock of a variant at a warehouse.
+// On-hand stock is the sum of Quantity per warehouse and variant; BalanceAfter is that running sum.
+type StockLedgerEntry struct {
+	ID           int64             `json:"id"`
+	VariantID    int               `json:"variant_id"`
+	ProductID    int               `json:"product_id"`
+	WarehouseID  *int              `json:"warehouse_id"` // nil when writing = default warehouse
+	MovementType StockMovementType `json:"movement_type"`
+	Quantity     int               `json:"quantity"` // Signed
+	BalanceAfter int               `json:"balance_after"`
+	OrderID      *int              `json:"order_id,omitempty"`
+	Reference    string            `json:"reference,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	Actor        string            `json:"actor"`
+	CreatedAt    time.Time         `json:"created_at"`
+
+	// Joined for listings
+	SKU           string `json:"sku,omitempty"`
+	ProductName   string `json:"product_name,omitempty"`
+	WarehouseCode string `json:"warehouse_code,omitempty"`
+}
+
+// StockLedgerFilter narrows a ledger listing; zero values are ignored
+type StockLedgerFilter struct {
+	VariantID    int
+	ProductID    int
+	WarehouseID  int
+	OrderID      int
+	MovementType StockMovementType
+}
+
+// StockDiscrepancy is a warehouse_stock row that does not match the ledger
+type StockDiscrepancy struct {
+	WarehouseID   int    `json:"warehouse_id"`
+	VariantID     int    `json:"variant_id"`
+	SKU           string `json:"sku"`
+	OnHand        int    `json:"on_hand"`
+	LedgerBalance int    `json:"ledger_balance"`
+}
+
+// LedgerBalanceBreak is a ledger entry whose balance_after differs from the running sum before it
+type LedgerBalanceBreak struct {
+	EntryID      int64 `json:"entry_id"`
+	WarehouseID  int   `json:"warehouse_id"`
+	VariantID    int   `json:"variant_id"`
+	BalanceAfter int   `json:"balance_after"`
+	RunningTotal int   `json:"running_total"`
+}
+
+// StockReconciliation is the result of comparing on-hand counts with the ledger
+type StockReconciliation struct {
+	Discrepancies   []StockDiscrepancy   `json:"discrepancies"`
+	BalanceBreaks   []LedgerBalanceBreak `json:"balance_breaks"`
+	Applied         bool                 `json:"applied"`
+	RowsRebuilt     int                  `json:"rows_rebuilt"`
+	ProductsUpdated int                  `json:"products_updated"`
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+type StockTakeStatus string
+
+const (
+	StockTakeCounting  StockTakeStatus = "COUNTING"
+	StockTakeReview    StockTakeStatus = "REVIEW"
+	StockTakePosted    StockTakeStatus = "POSTED"
+	StockTakeCancelled StockTakeStatus = "CANCELLED"
+)
+
+// CanTransitionTo reports whether an admin may move a stock take to next by hand.
+// POSTED is only reached by posting the variances.
+func (s StockTakeStatus) CanTransitionTo(next StockTakeStatus) bool {
+	switch next {
+	case StockTakeReview:
+		return s == StockTakeCounting
+	case StockTakeCounting:
+		return s == StockTakeReview // reopen for recounts
+	case StockTakeCancelled:
+		return s == StockTakeCounting || s == StockTakeReview
+	}
+	return false
+}
+
+// StockTake is a count session of one warehouse
+type StockTake struct {
+	ID            int             `json:"id"`
+	Code          string          `json:"code"`
+	WarehouseID   int             `json:"warehouse_id"`
+	WarehouseCode string          `json:"warehouse_code"`
+	Status        StockTakeStatus `json:"status"`
+	Notes         string          `json:"notes,omitempty"`
+	CreatedBy     string          `json:"created_by"`
+	SubmittedAt   *time.Time      `json:"submitted_at,omitempty"`
+	PostedBy      string          `json:"posted_by,omitempty"`
+	PostedAt      *time.Time      `json:"posted_at,omitempty"`
+	CancelledAt   *time.Time      `json:"cancelled_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// StockTakeLine is the frozen expectation and the count of one variant
+type StockTakeLine struct {
+	ID               int        `json:"id"`
+	StockTakeID      int        `json:"stock_take_id"`
+	VariantID        int        `json:"variant_id"`
+	SKU              string     `json:"sku"`
+	Barcode          string     `json:"barcode,omitempty"`
+	ProductName      string     `json:"product_name"`
+	VariantName      string     `json:"variant_name"`
+	ExpectedQuantity int        `json:"expected_quantity"`
+	CountedQuantity  *int       `json:"counted_quantity"`
+	UnitCost         *float64   `json:"unit_cost"`
+	Approved         bool       `json:"approved"`
+	CountedBy        string     `json:"counted_by,omitempty"`
+	CountedAt        *time.Time `json:"counted_at,omitempty"`
+	LedgerID         *int64     `json:"ledger_id,omitempty"`
+
+	// Derived for the variance report
+	Variance    *int     `json:"variance"`     // counted - expected, nil until counted
+	ValueImpact *float64 `json:"value_impact"` // variance x unit cost, nil without both
+}
+
+// Calculate fills Variance and ValueImpact
+func (l *StockTakeLine) Calculate() {
+	l.Variance, l.ValueImpact = nil, nil
+	if l.CountedQuantity == nil {
+		return
+	}
+	variance := *l.CountedQuantity - l.ExpectedQuantity
+	l.Variance = &variance
+	if l.UnitCost != nil {
+		value := math.Round(float64(variance)*(*l.UnitCost)*100) / 100
+		l.ValueImpact = &value
+	}
+}
+
+// NeedsAdjustment reports whether posting the line changes stock
+func (l *StockTakeLine) NeedsAdjustment() bool {
+	return l.Approved && l.CountedQuantity != nil && *l.CountedQuantity != l.ExpectedQuantity
+}
+
+// StockTakeSummary totals a variance report
+type StockTakeSummary struct {
+	Lines        int     `json:"lines"`
+	Counted      int     `json:"counted"`
+	Uncounted    int     `json:"uncounted"`
+	WithVariance int     `json:"with_variance"`
+	UnitsOver    int     `json:"units_over"`
+	UnitsShort   int     `json:"units_short"`
+	ValueOver    float64 `json:"value_over"`
+	ValueShort   float64 `json:"value_short"`
+	NetValue     float64 `json:"net_value"`
+	// Lines with a variance but no cost_per_item, left out of the value figures
+	Unvalued int `json:"unvalued"`
+}
+
+// SummarizeStockTake calculates every line and totals them
+func SummarizeStockTake(lines []StockTakeLine) StockTakeSummary {
+	summary := StockTakeSummary{Lines: len(lines)}
+	for i := range lines {
+		line := &lines[i]
+		line.Calculate()
+		if line.Variance == nil {
+			summary.Uncounted++
+			continue
+		}
+		summary.Counted++
+
+		variance := *line.Variance
+		if variance == 0 {
+			continue
+		}
+		summary.WithVariance++
+		if variance > 0 {
+			summary.UnitsOver += variance
+		} else {
+			summary.UnitsShort -= variance
+		}
+
+		if line.ValueImpact == nil {
+			summary.Unvalued++
+			continue
+		}
+		if *line.ValueImpact > 0 {
+			summary.ValueOver += *line.ValueImpact
+		} else {
+			summary.ValueShort -= *line.ValueImpact
+		}
+	}
+	summary.ValueOver = math.Round(summary.ValueOver*100) / 100
+	summary.ValueShort = math.Round(summary.ValueShort*100) / 100
+	summary.NetValue = math.Round((summary.ValueOver-summary.ValueShort)*100) / 100
+	return summary
+}
+
+// StockTakeReport is a stock take with its lines and variance totals
+type StockTakeReport struct {
+	StockTake
+	Summary StockTakeSummary `json:"summary"`
+	Lines   []StockTakeLine  `json:"lines"`
+}
+
+// StockTakeCount is one counted quantity; Barcode or SKU may stand in for VariantID
+type StockTakeCount struct {
+	VariantID int
+	Barcode   string
+	SKU       string
+	Quantity  int
+	Add       bool // Scans add to the count, typed counts replace it
+}
@@ -0,0 +1,76 @@
+package models
+
+import "testing"
+
+func intPtr(v int) *int { return &v }
+
+func TestStockTakeStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to StockTakeStatus
+		allowed  bool
+	}{
+		{StockTakeCounting, StockTakeReview, true},
+		{StockTakeReview, StockTakeCounting, true},
+		{StockTakeCounting, StockTakeCancelled, true},
+		{StockTakeReview, StockTakeCancelled, true},
+		{StockTakeReview, StockTakePosted, false}, // only by posting
+		{StockTakePosted, StockTakeCancelled, false},
+		{StockTakeCancelled, StockTakeCounting, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.allowed {
+			t.Errorf("%s -> %s: got %v, expected %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestStockTakeLineNeedsAdjustment(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     StockTakeLine
+		expected bool
+	}{
+		{"uncounted", StockTakeLine{ExpectedQuantity: 5, Approved: true}, false},
+		{"matches", StockTakeLine{ExpectedQuantity: 5, CountedQuantity: intPtr(5), Approved: true}, false},
+		{"short", StockTakeLine{ExpectedQuantity: 5, CountedQuantity: intPtr(3), Approved: true}, true},
+		{"not approved", StockTakeLine{ExpectedQuantity: 5, CountedQuantity: intPtr(3)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.line.NeedsAdjustment(); got != tt.expected {
+			t.Errorf("%s: got %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSummarizeStockTake(t *testing.T) {
+	lines := []StockTakeLine{
+		{ExpectedQuantity: 10, CountedQuantity: intPtr(8), UnitCost: floatPtr(50000)}, // 2 short
+		{ExpectedQuantity: 4, CountedQuantity: intPtr(5), UnitCost: floatPtr(120000)}, // 1 over
+		{ExpectedQuantity: 3, CountedQuantity: intPtr(3), UnitCost: floatPtr(75000)},  // matches
+		{ExpectedQuantity: 6, CountedQuantity: intPtr(2)},                             // 4 short, no cost
+		{ExpectedQuantity: 7}, // not counted
+	}
+
+	summary := SummarizeStockTake(lines)
+
+	if summary.Lines != 5 || summary.Counted != 4 || summary.Uncounted != 1 || summary.WithVariance != 3 {
+		t.Errorf("got lines=%d counted=%d uncounted=%d with_variance=%d, expected 5/4/1/3",
+			summary.Lines, summary.Counted, summary.Uncounted, summary.WithVariance)
+	}
+	if summary.UnitsOver != 1 || summary.UnitsShort != 6 {
+		t.Errorf("got %d over and %d short, expected 1 and 6", summary.UnitsOver, summary.UnitsShort)
+	}
+	if summary.ValueOver != 120000 || summary.ValueShort != 100000 || summary.NetValue != 20000 {
+		t.Errorf("got value over %.2f, short %.2f, net %.2f; expected 120000, 100000, 20000",
+			summary.ValueOver, summary.ValueShort, summary.NetValue)
+	}
+	if summary.Unvalued != 1 {
+		t.Errorf("got %d unvalued, expected 1", summary.Unvalued)
+	}
+
+	if lines[0].Variance == nil || *lines[0].Variance != -2 || lines[0].ValueImpact == nil || *lines[0].ValueImpact != -100000 {
+		t.Errorf("first line was not calculated: variance %v, value impact %v", lines[0].Variance, lines[0].ValueImpact)
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+type StoreCreditEntryType string
+
+const (
+	StoreCreditEntryGiftCard     StoreCreditEntryType = "GIFT_CARD"     // Gift card redeemed into the balance
+	StoreCreditEntryOrderPayment StoreCreditEntryType = "ORDER_PAYMENT" // Spent at checkout (negative)
+	StoreCreditEntryOrderRelease StoreCreditEntryType = "ORDER_RELEASE" // Returned when the order was cancelled, expired or failed
+	StoreCreditEntryRefund       StoreCreditEntryType = "REFUND"        // Refund returned as store credit
+	StoreCreditEntryAdjustment   StoreCreditEntryType = "ADJUSTMENT"    // Set by an admin, either sign
+)
+
+// StoreCreditEntry is a line of a customer's store credit ledger. Amount is signed: credits
+// are positive, debits negative.
+type StoreCreditEntry struct {
+	ID           int                  `json:"id"`
+	UserID       int                  `json:"user_id"`
+	EntryType    StoreCreditEntryType `json:"entry_type"`
+	Amount       float64              `json:"amount"`
+	BalanceAfter float64              `json:"balance_after"`
+	OrderID      *int                 `json:"order_id,omitempty"`
+	OrderCode    string               `json:"order_code,omitempty"`
+	RefundID     *int                 `json:"refund_id,omitempty"`
+	GiftCardID   *int                 `json:"gift_card_id,omitempty"`
+	Note         string               `json:"note,omitempty"`
+	CreatedBy    string               `json:"created_by,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+}
+
+type GiftCardStatus string
+
+const (
+	GiftCardStatusActive   GiftCardStatus = "ACTIVE"
+	GiftCardStatusRedeemed GiftCardStatus = "REDEEMED"
+	GiftCardStatusVoid     GiftCardStatus = "VOID"
+)
+
+// GiftCard is a code issued for a unit of a gift card product once its order is paid,
+// redeemable once into store credit
+type GiftCard struct {
+	ID              int            `json:"id"`
+	Code            string         `json:"code"`
+	Amount          float64        `json:"amount"`
+	OrderID         *int           `json:"order_id,omitempty"`
+	OrderCode       string         `json:"order_code,omitempty"`
+	OrderItemID     *int           `json:"order_item_id,omitempty"`
+	ProductName     string         `json:"product_name,omitempty"`
+	PurchaserUserID *int           `json:"purchaser_user_id,omitempty"`
+	PurchaserName   string         `json:"purchaser_name,omitempty"`
+	RecipientEmail  string         `json:"recipient_email"`
+	Status          GiftCardStatus `json:"status"`
+	RedeemedBy      *int           `json:"redeemed_by,omitempty"`
+	RedeemedAt      *time.Time     `json:"redeemed_at,omitempty"`
+	DeliveredAt     *time.Time     `json:"delivered_at,omitempty"`
+	ExpiresAt       *time.Time     `json:"expires_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// IsExpired reports whether the card can no longer be redeemed at the given time
+func (g GiftCard) IsExpired(at time.Time) bool {
+	return g.ExpiresAt != nil && !at.Before(*g.ExpiresAt)
+}
+
+// NormalizeGiftCardCode uppercases a code and drops the separators customers type or paste
+func NormalizeGiftCardCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FormatGiftCardCode groups a code by four characters for reading: ABCD-EFGH-JKLM-NPQR
+func FormatGiftCardCode(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// MaskGiftCardCode hides all but the last four characters of a code
+func MaskGiftCardCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return FormatGiftCardCode(strings.Repeat("*", len(code)-4) + code[len(code)-4:])
+}
+
+// StoreCreditToApply is how much of an order total is paid with store credit: the requested
+// amount, or as much as possible when none is requested, within the balance and the total
+func StoreCreditToApply(total, balance, requested float64) float64 {
+	amount := math.Min(total, balance)
+	if requested > 0 {
+		amount = math.Min(amount, requested)
+	}
+	return math.Max(0, amount)
+}
+
+// StoreCreditRefundShare is the part of a refund to the original payment that goes back as
+// store credit: what the order paid with store credit and has not had back yet comes first
+func StoreCreditRefundShare(refundAmount, paidWithCredit, creditReturned float64) float64 {
+	return math.Max(0, math.Min(refundAmount, paidWithCredit-creditReturned))
+}
+
+// AmountDue is what is left to pay through Midtrans after store credit
+func (o Order) AmountDue() float64 {
+	return math.Max(0, o.TotalAmount-o.StoreCreditAmount)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreCreditToApply(t *testing.T) {
+	tests := []struct {
+		name                      string
+		total, balance, requested float64
+		want                      float64
+	}{
+		{"as much as possible covers the order", 150000, 200000, 0, 150000},
+		{"as much as possible uses the balance", 150000, 50000, 0, 50000},
+		{"requested within the balance", 150000, 200000, 40000, 40000},
+		{"requested above the balance", 150000, 30000, 40000, 30000},
+		{"requested above the total", 150000, 500000, 400000, 150000},
+		{"empty balance", 150000, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := StoreCreditToApply(tt.total, tt.balance, tt.requested); got != tt.want {
+			t.Errorf("%s: got %.0f, expected %.0f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreCreditRefundShare(t *testing.T) {
+	tests := []struct {
+		name                                   string
+		refundAmount, paidWithCredit, returned float64
+		want                                   float64
+	}{
+		{"order paid without credit", 100000, 0, 0, 0},
+		{"credit comes back first", 100000, 60000, 0, 60000},
+		{"refund smaller than the credit paid", 40000, 60000, 0, 40000},
+		{"rest of the credit on a second refund", 50000, 60000, 40000, 20000},
+		{"credit already returned", 50000, 60000, 60000, 0},
+	}
+	for _, tt := range tests {
+		if got := StoreCreditRefundShare(tt.refundAmount, tt.paidWithCredit, tt.returned); got != tt.want {
+			t.Errorf("%s: got %.0f, expected %.0f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGiftCardCodes(t *testing.T) {
+	if got := NormalizeGiftCardCode(" abcd-efgh 2345-wxyz "); got != "ABCDEFGH2345WXYZ" {
+		t.Errorf("normalize: got %q", got)
+	}
+	if got := FormatGiftCardCode("ABCDEFGH2345WXYZ"); got != "ABCD-EFGH-2345-WXYZ" {
+		t.Errorf("format: got %q", got)
+	}
+	if got := FormatGiftCardCode("ABCDEF"); got != "ABCD-EF" {
+		t.Errorf("format short: got %q", got)
+	}
+	if got := MaskGiftCardCode("ABCDEFGH2345WXYZ"); got != "****-****-****-WXYZ" {
+		t.Errorf("mask: got %q", got)
+	}
+	if got := MaskGiftCardCode("WXYZ"); got != "WXYZ" {
+		t.Errorf("mask short: got %q", got)
+	}
+}
+
+func TestGiftCardIsExpired(t *testing.T) {
+	expires := time.Date(2027, 10, 16, 0, 0, 0, 0, time.UTC)
+	card := GiftCard{ExpiresAt: &expires}
+
+	if card.IsExpired(expires.Add(-time.Second)) {
+		t.Error("expected the card to be redeemable before it expires")
+	}
+	if !card.IsExpired(expires) {
+		t.Error("expected the card to be expired at its expiry")
+	}
+	if (GiftCard{}).IsExpired(expires) {
+		t.Error("expected a card without expiry never to expire")
+	}
+}
+
+func TestAmountDueAndGatewayAmount(t *testing.T) {
+	order := Order{TotalAmount: 250000, StoreCreditAmount: 100000}
+	if got := order.AmountDue(); got != 150000 {
+		t.Errorf("split payment: got amount due %.0f, expected 150000", got)
+	}
+	order.StoreCreditAmount = 250000
+	if got := order.AmountDue(); got != 0 {
+		t.Errorf("paid with store credit: got amount due %.0f, expected 0", got)
+	}
+
+	refund := Refund{RefundAmount: 120000, StoreCreditAmount: 100000}
+	if got := refund.GatewayAmount(); got != 20000 {
+		t.Errorf("got gateway amount %.0f, expected 20000", got)
+	}
+}
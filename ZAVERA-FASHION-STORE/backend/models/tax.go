@@ -0,0 +1,134 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// TaxSetting is the PPN rate and pricing mode from a date on. Rate changes are new settings,
+// so orders keep the rate they were charged.
+type TaxSetting struct {
+	ID               int       `json:"id"`
+	Rate             float64   `json:"rate"`               // Percent, e.g. 11
+	PricesIncludeTax bool      `json:"prices_include_tax"` // Catalog prices already contain PPN
+	EffectiveFrom    time.Time `json:"effective_from"`
+	Notes            string    `json:"notes,omitempty"`
+	CreatedBy        string    `json:"created_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TaxExemptProduct is a product sold without PPN
+type TaxExemptProduct struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+}
+
+// TaxSettingAt returns the setting in effect at t: the latest one that started on or before it.
+// It is nil when none has started, and no tax applies.
+func TaxSettingAt(settings []TaxSetting, t time.Time) *TaxSetting {
+	var current *TaxSetting
+	for i := range settings {
+		s := &settings[i]
+		if s.EffectiveFrom.After(t) {
+			continue
+		}
+		if current == nil || s.EffectiveFrom.After(current.EffectiveFrom) {
+			current = s
+		}
+	}
+	return current
+}
+
+// LineTax is the PPN on an amount, in whole rupiah. When prices include tax it is the rate's
+// share of the amount; otherwise it is added on top.
+func (s TaxSetting) LineTax(amount float64) float64 {
+	if s.Rate <= 0 || amount <= 0 {
+		return 0
+	}
+	if s.PricesIncludeTax {
+		return math.Round(amount * s.Rate / (100 + s.Rate))
+	}
+	return math.Round(amount * s.Rate / 100)
+}
+
+// ApplyTax sets the PPN of every line, on what is paid for it after its discount, and returns
+// the order's tax: the sum of the lines. Exempt products carry no tax at a zero rate.
+func (s TaxSetting) ApplyTax(items []OrderItem, exempt map[int]bool) float64 {
+	var total float64
+	for i := range items {
+		item := &items[i]
+		item.TaxRate = 0
+		item.TaxAmount = 0
+		if exempt[item.ProductID] {
+			continue
+		}
+		item.TaxRate = s.Rate
+		item.TaxAmount = s.LineTax(item.NetAmount())
+		total += item.TaxAmount
+	}
+	return total
+}
+
+// AllocateAmount splits an amount over the weights in proportion, in whole rupiah. The rounding
+// remainder goes to the last weighted part so the parts add up to the amount.
+func AllocateAmount(amount float64, weights []float64) []float64 {
+	parts := make([]float64, len(weights))
+	var sum float64
+	last := -1
+	for i, w := range weights {
+		if w > 0 {
+			sum += w
+			last = i
+		}
+	}
+	if last < 0 || amount == 0 {
+		return parts
+	}
+
+	allocated := 0.0
+	for i, w := range weights {
+		if w <= 0 || i == last {
+			continue
+		}
+		parts[i] = math.Round(amount * w / sum)
+		allocated += parts[i]
+	}
+	parts[last] = amount - allocated
+	return parts
+}
+
+// NetAmount is what the line costs after its promotion and voucher discount
+func (i OrderItem) NetAmount() float64 {
+	return i.Subtotal - i.DiscountAmount
+}
+
+// PaidShare returns what was paid for part of the line worth gross at list price, and the PPN
+// in it: the part's share of the line after its discount, plus PPN when prices exclude it.
+func (i OrderItem) PaidShare(gross float64, pricesIncludeTax bool) (paid, tax float64) {
+	line := i.Subtotal
+	if line <= 0 {
+		line = i.PricePerUnit * float64(i.Quantity)
+	}
+	if line <= 0 {
+		return gross, 0
+	}
+
+	share := gross / line
+	tax = math.Round(i.TaxAmount * share)
+	paid = math.Round((line - i.DiscountAmount) * share)
+	if !pricesIncludeTax {
+		paid += tax
+	}
+	return paid, tax
+}
+
+// ExpectedTotal is what the customer pays for the order: items and shipping less discounts,
+// plus PPN when prices exclude it
+func (o Order) ExpectedTotal() float64 {
+	total := o.Subtotal + o.ShippingCost - o.Discount
+	if !o.PricesIncludeTax {
+		total += o.Tax
+	}
+	return total
+}
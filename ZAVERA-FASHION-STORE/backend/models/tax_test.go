@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaxSettingAt(t *testing.T) {
+	settings := []TaxSetting{
+		{ID: 2, Rate: 12, EffectiveFrom: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 1, Rate: 11, EffectiveFrom: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC), 1},
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), 2},
+	}
+	for _, tt := range tests {
+		got := TaxSettingAt(settings, tt.at)
+		if (got == nil && tt.want != 0) || (got != nil && got.ID != tt.want) {
+			t.Errorf("at %s: got %+v, expected setting %d", tt.at.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestTaxSettingApplyTax(t *testing.T) {
+	items := []OrderItem{
+		{ProductID: 1, Quantity: 2, PricePerUnit: 111000, Subtotal: 222000, DiscountAmount: 22200},
+		{ProductID: 2, Quantity: 1, PricePerUnit: 55500, Subtotal: 55500},
+		{ProductID: 3, Quantity: 1, PricePerUnit: 100000, Subtotal: 100000},
+	}
+	exempt := map[int]bool{3: true}
+
+	// Inclusive: the tax is 11/111 of what is paid for each line
+	inclusive := TaxSetting{Rate: 11, PricesIncludeTax: true}
+	if total := inclusive.ApplyTax(items, exempt); total != 19800+5500 {
+		t.Errorf("inclusive: got tax %.0f, expected %d", total, 19800+5500)
+	}
+	if items[2].TaxAmount != 0 || items[2].TaxRate != 0 || items[0].TaxRate != 11 {
+		t.Errorf("inclusive: unexpected line rates %+v", items)
+	}
+
+	// Exclusive: 12% on top of what is paid
+	exclusive := TaxSetting{Rate: 12}
+	if total := exclusive.ApplyTax(items, exempt); total != 23976+6660 {
+		t.Errorf("exclusive: got tax %.0f, expected %d", total, 23976+6660)
+	}
+}
+
+func TestAllocateAmount(t *testing.T) {
+	parts := AllocateAmount(10000, []float64{100000, 0, 200000})
+	if parts[0] != 3333 || parts[1] != 0 || parts[2] != 6667 {
+		t.Errorf("got %v, expected [3333 0 6667]", parts)
+	}
+	if parts := AllocateAmount(10000, []float64{0, 0}); parts[0] != 0 || parts[1] != 0 {
+		t.Errorf("expected nothing allocated without weights, got %v", parts)
+	}
+}
+
+func TestOrderItemPaidShare(t *testing.T) {
+	item := OrderItem{Quantity: 3, PricePerUnit: 111000, Subtotal: 333000, DiscountAmount: 33300, TaxAmount: 29700}
+
+	// One of three units: a third of the discounted line and of its tax
+	if paid, tax := item.PaidShare(111000, true); paid != 99900 || tax != 9900 {
+		t.Errorf("inclusive: got paid %.0f tax %.0f, expected 99900 and 9900", paid, tax)
+	}
+	if paid, tax := item.PaidShare(111000, false); paid != 99900+9900 || tax != 9900 {
+		t.Errorf("exclusive: got paid %.0f tax %.0f, expected %d and 9900", paid, tax, 99900+9900)
+	}
+
+	// Lines without a stored subtotal fall back to the unit price
+	legacy := OrderItem{Quantity: 3, PricePerUnit: 20000}
+	if paid, tax := legacy.PaidShare(40000, false); paid != 40000 || tax != 0 {
+		t.Errorf("legacy: got paid %.0f tax %.0f, expected 40000 and 0", paid, tax)
+	}
+}
+
+func TestOrderExpectedTotal(t *testing.T) {
+	order := Order{Subtotal: 300000, ShippingCost: 20000, Discount: 30000, Tax: 26757, PricesIncludeTax: true}
+	if got := order.ExpectedTotal(); got != 290000 {
+		t.Errorf("inclusive: got %.0f, expected 290000", got)
+	}
+	order.PricesIncludeTax = false
+	if got := order.ExpectedTotal(); got != 316757 {
+		t.Errorf("exclusive: got %.0f, expected 316757", got)
+	}
+}
+
+func TestVoucherApplyLineDiscounts(t *testing.T) {
+	lines := []VoucherLine{
+		{ProductID: 1, Quantity: 2, UnitPrice: 150000, InScope: true},
+		{ProductID: 2, Quantity: 1, UnitPrice: 90000, InScope: true},
+		{ProductID: 3, Quantity: 1, UnitPrice: 500000, InScope: false},
+	}
+
+	percent := Voucher{Type: VoucherTypePercentage, Value: 10, IsActive: true}
+	got, _ := percent.Apply(lines, 0, time.Now())
+	if got.LineDiscounts[0] != 30000 || got.LineDiscounts[1] != 9000 || got.LineDiscounts[2] != 0 {
+		t.Errorf("percentage: got %v, expected [30000 9000 0]", got.LineDiscounts)
+	}
+
+	// Only the line with the free unit carries the discount
+	buyTwo := Voucher{Type: VoucherTypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Value: 100, IsActive: true}
+	got, _ = buyTwo.Apply(lines, 0, time.Now())
+	if got.LineDiscounts[0] != 0 || got.LineDiscounts[1] != 90000 {
+		t.Errorf("buy x get y: got %v, expected [0 90000 0]", got.LineDiscounts)
+	}
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VariantOptionColumns are the options stored in their own product_variants columns, in their
+// default display order. Any other option (e.g. length) lives in CustomAttributes.
+var VariantOptionColumns = []string{"size", "color", "material", "pattern", "fit", "sleeve"}
+
+// MaxVariantCombinations caps how many variants one option matrix may generate or report
+const MaxVariantCombinations = 500
+
+// VariantOptionDimension is one axis of a product's option matrix, e.g. size: S, M, L
+type VariantOptionDimension struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantOptionMatrix lists a product's option dimensions and the combinations a shopper
+// cannot buy, either because no active variant exists or because it is sold out
+type VariantOptionMatrix struct {
+	Dimensions  []VariantOptionDimension `json:"dimensions"`
+	Unavailable []map[string]string      `json:"unavailable"`
+}
+
+// NormalizeOptionName lowercases and trims an option name so "Length" and "length " match
+func NormalizeOptionName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (v *ProductVariant) optionColumn(name string) **string {
+	switch name {
+	case "size":
+		return &v.Size
+	case "color":
+		return &v.Color
+	case "material":
+		return &v.Material
+	case "pattern":
+		return &v.Pattern
+	case "fit":
+		return &v.Fit
+	case "sleeve":
+		return &v.Sleeve
+	}
+	return nil
+}
+
+// OptionValues returns every option the variant has a value for, keyed by option name.
+// Scalar custom attributes count as options; nested values are ignored.
+func (v ProductVariant) OptionValues() map[string]string {
+	values := make(map[string]string)
+	for name, value := range v.CustomAttributes {
+		switch value.(type) {
+		case string, float64, int, bool:
+			if s := strings.TrimSpace(fmt.Sprint(value)); s != "" {
+				values[NormalizeOptionName(name)] = s
+			}
+		}
+	}
+	for _, name := range VariantOptionColumns {
+		if column := v.optionColumn(name); *column != nil && strings.TrimSpace(**column) != "" {
+			values[name] = **column
+		}
+	}
+	return values
+}
+
+// OrderedOptionValues lists the variant's option values with their names in order (see
+// BuildOptionMatrix)
+func (v ProductVariant) OrderedOptionValues(order []string) []string {
+	values := v.OptionValues()
+	present := make(map[string]bool, len(values))
+	for name := range values {
+		present[name] = true
+	}
+
+	result := make([]string, 0, len(values))
+	for _, name := range orderOptionNames(present, order) {
+		result = append(result, values[name])
+	}
+	return result
+}
+
+// orderOptionNames sorts option names by order, with names missing from it after, alphabetically
+func orderOptionNames(names map[string]bool, order []string) []string {
+	result := make([]string, 0, len(names))
+	ranked := make(map[string]bool)
+	for _, name := range order {
+		name = NormalizeOptionName(name)
+		if names[name] && !ranked[name] {
+			ranked[name] = true
+			result = append(result, name)
+		}
+	}
+	var rest []string
+	for name := range names {
+		if !ranked[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(result, rest...)
+}
+
+// SetOption stores an option value in its column, or in CustomAttributes for other options
+func (v *ProductVariant) SetOption(name, value string) {
+	name = NormalizeOptionName(name)
+	if column := v.optionColumn(name); column != nil {
+		*column = &value
+		return
+	}
+	if v.CustomAttributes == nil {
+		v.CustomAttributes = make(VariantAttributes)
+	}
+	v.CustomAttributes[name] = value
+}
+
+// MatchesOptions reports whether the variant has every selected value (case-insensitive).
+// Options left out of the selection match anything.
+func (v ProductVariant) MatchesOptions(selected map[string]string) bool {
+	values := v.OptionValues()
+	for name, want := range selected {
+		if !strings.EqualFold(values[NormalizeOptionName(name)], strings.TrimSpace(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSellable reports whether a shopper can add the variant to the cart
+func (v ProductVariant) IsSellable() bool {
+	if !v.IsActive {
+		return false
+	}
+	if v.InventoryPolicy.AllowsBackorder() {
+		return true
+	}
+	return v.AvailableStock != nil && *v.AvailableStock > 0
+}
+
+// CombinationCount is the size of the cartesian product of the dimensions
+func CombinationCount(dimensions []VariantOptionDimension) int {
+	if len(dimensions) == 0 {
+		return 0
+	}
+	count := 1
+	for _, d := range dimensions {
+		count *= len(d.Values)
+		if count > MaxVariantCombinations {
+			return count
+		}
+	}
+	return count
+}
+
+// CartesianOptions lists every combination of the dimensions, varying the last dimension fastest
+func CartesianOptions(dimensions []VariantOptionDimension) []map[string]string {
+	if CombinationCount(dimensions) == 0 {
+		return nil
+	}
+
+	combinations := []map[string]string{{}}
+	for _, d := range dimensions {
+		next := make([]map[string]string, 0, len(combinations)*len(d.Values))
+		for _, combination := range combinations {
+			for _, value := range d.Values {
+				extended := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					extended[k] = v
+				}
+				extended[d.Name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// BuildOptionMatrix collects the option dimensions of the active variants and the combinations
+// that cannot be bought. Values keep the order of the variants' positions; dimensions follow
+// order (option names by display order), with any others after it alphabetically. Unavailable
+// is left empty when the matrix has more than MaxVariantCombinations combinations.
+func BuildOptionMatrix(variants []ProductVariant, order []string) VariantOptionMatrix {
+	values := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, v := range variants {
+		if !v.IsActive {
+			continue
+		}
+		for name, value := range v.OptionValues() {
+			key := name + "\x00" + strings.ToUpper(value)
+			if !seen[key] {
+				seen[key] = true
+				values[name] = append(values[name], value)
+			}
+		}
+	}
+
+	present := make(map[string]bool, len(values))
+	for name := range values {
+		present[name] = true
+	}
+	names := orderOptionNames(present, order)
+
+	matrix := VariantOptionMatrix{
+		Dimensions:  make([]VariantOptionDimension, 0, len(names)),
+		Unavailable: []map[string]string{},
+	}
+	for _, name := range names {
+		matrix.Dimensions = append(matrix.Dimensions, VariantOptionDimension{Name: name, Values: values[name]})
+	}
+	if CombinationCount(matrix.Dimensions) > MaxVariantCombinations {
+		return matrix
+	}
+
+	for _, combination := range CartesianOptions(matrix.Dimensions) {
+		available := false
+		for _, v := range variants {
+			if v.IsSellable() && v.MatchesOptions(combination) {
+				available = true
+				break
+			}
+		}
+		if !available {
+			matrix.Unavailable = append(matrix.Unavailable, combination)
+		}
+	}
+	return matrix
+}
@@ -0,0 +1,87 @@
+package models
+
+import "testing"
+
+func testOptionVariant(id int, size, color, length string, stock int) ProductVariant {
+	v := ProductVariant{ID: id, IsActive: true, InventoryPolicy: InventoryPolicyStock, AvailableStock: intPtr(stock)}
+	v.SetOption("size", size)
+	v.SetOption("color", color)
+	v.SetOption("Length", length)
+	return v
+}
+
+func TestCartesianOptions(t *testing.T) {
+	dimensions := []VariantOptionDimension{
+		{Name: "size", Values: []string{"S", "M"}},
+		{Name: "color", Values: []string{"Black", "White"}},
+		{Name: "length", Values: []string{"Regular", "Long", "Cropped"}},
+	}
+
+	combinations := CartesianOptions(dimensions)
+	if len(combinations) != 12 {
+		t.Fatalf("expected 12 combinations, got %d", len(combinations))
+	}
+	first, last := combinations[0], combinations[11]
+	if first["size"] != "S" || first["color"] != "Black" || first["length"] != "Regular" {
+		t.Errorf("unexpected first combination %v", first)
+	}
+	if last["size"] != "M" || last["color"] != "White" || last["length"] != "Cropped" {
+		t.Errorf("unexpected last combination %v", last)
+	}
+
+	if got := CartesianOptions(append(dimensions, VariantOptionDimension{Name: "fit"})); got != nil {
+		t.Errorf("a dimension without values should produce no combinations, got %d", len(got))
+	}
+}
+
+func TestMatchesOptions(t *testing.T) {
+	v := testOptionVariant(1, "M", "Black", "Long", 3)
+
+	if v.CustomAttributes["length"] != "Long" || v.Size == nil || *v.Size != "M" {
+		t.Fatalf("options stored in the wrong place: size=%v custom=%v", v.Size, v.CustomAttributes)
+	}
+	if !v.MatchesOptions(map[string]string{"size": "m", "LENGTH": "long"}) {
+		t.Error("expected a case-insensitive match on a partial selection")
+	}
+	if v.MatchesOptions(map[string]string{"size": "M", "length": "Regular"}) {
+		t.Error("expected no match on a different length")
+	}
+	if v.MatchesOptions(map[string]string{"fit": "Slim"}) {
+		t.Error("expected no match on an option the variant does not have")
+	}
+}
+
+func TestBuildOptionMatrix(t *testing.T) {
+	variants := []ProductVariant{
+		testOptionVariant(1, "S", "Black", "Regular", 2),
+		testOptionVariant(2, "S", "Black", "Long", 0), // Sold out
+		testOptionVariant(3, "M", "Black", "Regular", 5),
+		testOptionVariant(4, "M", "Black", "Long", 1),
+		testOptionVariant(5, "M", "White", "Long", 0),
+	}
+	variants[4].InventoryPolicy = InventoryPolicyBackorder // Sellable while sold out
+
+	matrix := BuildOptionMatrix(variants, []string{"color", "size"})
+
+	var names []string
+	for _, d := range matrix.Dimensions {
+		names = append(names, d.Name)
+	}
+	if len(names) != 3 || names[0] != "color" || names[1] != "size" || names[2] != "length" {
+		t.Fatalf("unexpected dimension order %v", names)
+	}
+	if sizes := matrix.Dimensions[1].Values; len(sizes) != 2 || sizes[0] != "S" || sizes[1] != "M" {
+		t.Errorf("expected sizes in variant order, got %v", sizes)
+	}
+
+	// 2 colors x 2 sizes x 2 lengths = 8; sellable: S/Black/Regular, M/Black/Regular,
+	// M/Black/Long, M/White/Long
+	if len(matrix.Unavailable) != 4 {
+		t.Fatalf("expected 4 unavailable combinations, got %d: %v", len(matrix.Unavailable), matrix.Unavailable)
+	}
+	for _, combination := range matrix.Unavailable {
+		if combination["size"] == "M" && combination["color"] == "White" && combination["length"] == "Long" {
+			t.Error("backorderable variant reported as unavailable")
+		}
+	}
+}
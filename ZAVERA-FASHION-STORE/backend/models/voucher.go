@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type VoucherType string
+
+const (
+	VoucherTypePercentage   VoucherType = "PERCENTAGE"    // Value percent off the items in scope
+	VoucherTypeFixedAmount  VoucherType = "FIXED_AMOUNT"  // Value rupiah off the items in scope
+	VoucherTypeFreeShipping VoucherType = "FREE_SHIPPING" // Shipping cost, up to MaxDiscount
+	VoucherTypeBuyXGetY     VoucherType = "BUY_X_GET_Y"   // Value percent off the cheapest GetQuantity of every BuyQuantity+GetQuantity units
+)
+
+func (t VoucherType) IsValid() bool {
+	switch t {
+	case VoucherTypePercentage, VoucherTypeFixedAmount, VoucherTypeFreeShipping, VoucherTypeBuyXGetY:
+		return true
+	}
+	return false
+}
+
+// Voucher is a promo code customers enter at checkout
+type Voucher struct {
+	ID          int         `json:"id"`
+	Code        string      `json:"code"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Type        VoucherType `json:"type"`
+	Value       float64     `json:"value"`
+	MaxDiscount *float64    `json:"max_discount,omitempty"`
+	MinSpend    float64     `json:"min_spend"` // On the items in scope
+	BuyQuantity int         `json:"buy_quantity,omitempty"`
+	GetQuantity int         `json:"get_quantity,omitempty"`
+	// Scope: items in the category (or below it) and/or of the brand; none means the whole cart
+	CategoryID     *int       `json:"category_id,omitempty"`
+	Brand          *string    `json:"brand,omitempty"`
+	UsageLimit     *int       `json:"usage_limit,omitempty"`
+	PerUserLimit   *int       `json:"per_user_limit,omitempty"`
+	UsedCount      int        `json:"used_count"`
+	FirstOrderOnly bool       `json:"first_order_only"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// NormalizeVoucherCode trims and uppercases a code as customers type it
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// VoucherLine is a cart or order line as the voucher sees it
+type VoucherLine struct {
+	ProductID int
+	Quantity  int
+	UnitPrice float64
+	InScope   bool // Matches the voucher's category/brand scope
+}
+
+// VoucherDiscount is what a voucher takes off an order, in whole rupiah
+type VoucherDiscount struct {
+	ItemDiscount     float64 `json:"item_discount"`
+	ShippingDiscount float64 `json:"shipping_discount"`
+	ScopeSubtotal    float64 `json:"scope_subtotal"` // Items the voucher applies to
+	FreeUnits        int     `json:"free_units,omitempty"`
+	// ItemDiscount by line, in the order of the lines given to Apply
+	LineDiscounts []float64 `json:"-"`
+}
+
+func (d VoucherDiscount) Total() float64 {
+	return d.ItemDiscount + d.ShippingDiscount
+}
+
+// Unavailable returns why the voucher cannot be used at now, or "" when it can.
+// Usage limits and first-order checks need the order history and are not covered.
+func (v Voucher) Unavailable(now time.Time) string {
+	switch {
+	case !v.IsActive:
+		return "This voucher is no longer available"
+	case v.StartsAt != nil && now.Before(*v.StartsAt):
+		return fmt.Sprintf("This voucher can be used from %s", v.StartsAt.Format("02 Jan 2006 15:04"))
+	case v.EndsAt != nil && !now.Before(*v.EndsAt):
+		return "This voucher has expired"
+	case v.UsageLimit != nil && v.UsedCount >= *v.UsageLimit:
+		return "This voucher has been fully redeemed"
+	}
+	return ""
+}
+
+// Apply works out the discount on the lines and shipping cost. The reason is non-empty when the
+// voucher does not apply; the discount is then zero.
+func (v Voucher) Apply(lines []VoucherLine, shippingCost float64, now time.Time) (VoucherDiscount, string) {
+	if reason := v.Unavailable(now); reason != "" {
+		return VoucherDiscount{}, reason
+	}
+
+	var result VoucherDiscount
+	type unit struct {
+		price float64
+		line  int
+	}
+	var units []unit
+	weights := make([]float64, len(lines))
+	for l, line := range lines {
+		if !line.InScope || line.Quantity <= 0 {
+			continue
+		}
+		weights[l] = line.UnitPrice * float64(line.Quantity)
+		result.ScopeSubtotal += weights[l]
+		for i := 0; i < line.Quantity; i++ {
+			units = append(units, unit{price: line.UnitPrice, line: l})
+		}
+	}
+
+	if result.ScopeSubtotal == 0 {
+		return VoucherDiscount{}, "No items in your cart are eligible for this voucher"
+	}
+	if result.ScopeSubtotal < v.MinSpend {
+		return VoucherDiscount{}, fmt.Sprintf("Spend at least Rp %.0f on eligible items to use this voucher", v.MinSpend)
+	}
+
+	switch v.Type {
+	case VoucherTypePercentage:
+		result.ItemDiscount = math.Round(result.ScopeSubtotal * v.Value / 100)
+		if v.MaxDiscount != nil && result.ItemDiscount > *v.MaxDiscount {
+			result.ItemDiscount = *v.MaxDiscount
+		}
+	case VoucherTypeFixedAmount:
+		result.ItemDiscount = math.Min(v.Value, result.ScopeSubtotal)
+	case VoucherTypeFreeShipping:
+		if shippingCost <= 0 {
+			return VoucherDiscount{}, "This order has no shipping cost to discount"
+		}
+		result.ShippingDiscount = shippingCost
+		if v.MaxDiscount != nil && result.ShippingDiscount > *v.MaxDiscount {
+			result.ShippingDiscount = *v.MaxDiscount
+		}
+	case VoucherTypeBuyXGetY:
+		group := v.BuyQuantity + v.GetQuantity
+		if v.BuyQuantity <= 0 || v.GetQuantity <= 0 || len(units) < group {
+			return VoucherDiscount{}, fmt.Sprintf("Add %d eligible items to use this voucher", group)
+		}
+		// The cheapest units are the free ones, and only their lines share the discount
+		sort.SliceStable(units, func(i, j int) bool { return units[i].price < units[j].price })
+		result.FreeUnits = len(units) / group * v.GetQuantity
+		weights = make([]float64, len(lines))
+		for _, u := range units[:result.FreeUnits] {
+			result.ItemDiscount += u.price
+			weights[u.line] += u.price
+		}
+		result.ItemDiscount = math.Round(result.ItemDiscount * v.Value / 100)
+	default:
+		return VoucherDiscount{}, "This voucher is no longer available"
+	}
+
+	result.LineDiscounts = AllocateAmount(result.ItemDiscount, weights)
+	return result, ""
+}
+
+type VoucherRedemptionStatus string
+
+const (
+	VoucherRedemptionApplied  VoucherRedemptionStatus = "APPLIED"
+	VoucherRedemptionReleased VoucherRedemptionStatus = "RELEASED" // Order cancelled, expired or failed
+)
+
+// VoucherRedemption is a voucher used on an order. It is recorded with the order and counts
+// towards the usage limits until released.
+type VoucherRedemption struct {
+	ID             int                     `json:"id"`
+	VoucherID      int                     `json:"voucher_id"`
+	OrderID        int                     `json:"order_id"`
+	UserID         *int                    `json:"user_id,omitempty"`
+	CustomerEmail  string                  `json:"customer_email"`
+	Code           string                  `json:"code"`
+	DiscountAmount float64                 `json:"discount_amount"`
+	Status         VoucherRedemptionStatus `json:"status"`
+	CreatedAt      time.Time               `json:"created_at"`
+	ReleasedAt     *time.Time              `json:"released_at,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func timePtr(v time.Time) *time.Time { return &v }
+
+func TestVoucherApply(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	lines := []VoucherLine{
+		{ProductID: 1, Quantity: 2, UnitPrice: 150000, InScope: true},
+		{ProductID: 2, Quantity: 1, UnitPrice: 90000, InScope: true},
+		{ProductID: 3, Quantity: 1, UnitPrice: 500000, InScope: false},
+	}
+
+	tests := []struct {
+		name     string
+		voucher  Voucher
+		items    float64
+		shipping float64
+		reason   bool
+	}{
+		{"percentage of items in scope", Voucher{Type: VoucherTypePercentage, Value: 10}, 39000, 0, false},
+		{"percentage capped", Voucher{Type: VoucherTypePercentage, Value: 50, MaxDiscount: floatPtr(50000)}, 50000, 0, false},
+		{"fixed amount", Voucher{Type: VoucherTypeFixedAmount, Value: 25000}, 25000, 0, false},
+		{"fixed amount above scope subtotal", Voucher{Type: VoucherTypeFixedAmount, Value: 1000000}, 390000, 0, false},
+		{"free shipping capped", Voucher{Type: VoucherTypeFreeShipping, MaxDiscount: floatPtr(10000)}, 0, 10000, false},
+		{"buy 2 get 1 free takes the cheapest", Voucher{Type: VoucherTypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Value: 100}, 90000, 0, false},
+		{"buy 1 get 1 half price", Voucher{Type: VoucherTypeBuyXGetY, BuyQuantity: 1, GetQuantity: 1, Value: 50}, 45000, 0, false},
+		{"buy 3 get 1 needs 4 units", Voucher{Type: VoucherTypeBuyXGetY, BuyQuantity: 3, GetQuantity: 1, Value: 100}, 0, 0, true},
+		{"minimum spend on scope only", Voucher{Type: VoucherTypeFixedAmount, Value: 25000, MinSpend: 400000}, 0, 0, true},
+		{"inactive", Voucher{Type: VoucherTypeFixedAmount, Value: 25000}, 0, 0, true},
+		{"not started", Voucher{Type: VoucherTypeFixedAmount, Value: 25000, StartsAt: timePtr(now.Add(time.Hour))}, 0, 0, true},
+		{"ended", Voucher{Type: VoucherTypeFixedAmount, Value: 25000, EndsAt: timePtr(now)}, 0, 0, true},
+		{"fully redeemed", Voucher{Type: VoucherTypeFixedAmount, Value: 25000, UsageLimit: intPtr(5), UsedCount: 5}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		tt.voucher.IsActive = tt.name != "inactive"
+		got, reason := tt.voucher.Apply(lines, 20000, now)
+		if (reason != "") != tt.reason {
+			t.Errorf("%s: unexpected reason %q", tt.name, reason)
+			continue
+		}
+		if got.ItemDiscount != tt.items || got.ShippingDiscount != tt.shipping {
+			t.Errorf("%s: got items %.0f shipping %.0f, expected %.0f and %.0f",
+				tt.name, got.ItemDiscount, got.ShippingDiscount, tt.items, tt.shipping)
+		}
+	}
+}
+
+func TestVoucherApplyWithoutItemsInScope(t *testing.T) {
+	v := Voucher{Type: VoucherTypePercentage, Value: 10, IsActive: true}
+	lines := []VoucherLine{{ProductID: 1, Quantity: 1, UnitPrice: 100000}}
+
+	if _, reason := v.Apply(lines, 15000, time.Now()); reason == "" {
+		t.Error("expected a voucher scoped away from every item not to apply")
+	}
+}
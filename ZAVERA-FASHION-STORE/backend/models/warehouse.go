@@ -0,0 +1,188 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Warehouse is a stock location orders can ship from
+type Warehouse struct {
+	ID           int       `json:"id" db:"id"`
+	Code         string    `json:"code" db:"code"`
+	Name         string    `json:"name" db:"name"`
+	Address      string    `json:"address" db:"address"`
+	CityName     string    `json:"city_name" db:"city_name"`
+	CityID       string    `json:"city_id,omitempty" db:"city_id"` // Legacy city ID recorded on shipments
+	PostalCode   string    `json:"postal_code" db:"postal_code"`
+	AreaID       string    `json:"area_id,omitempty" db:"area_id"` // Biteship area ID
+	ContactName  string    `json:"contact_name" db:"contact_name"`
+	ContactPhone string    `json:"contact_phone" db:"contact_phone"`
+	Priority     int       `json:"priority" db:"priority"` // Lower wins when equally near
+	IsDefault    bool      `json:"is_default" db:"is_default"`
+	IsActive     bool      `json:"is_active" db:"is_active"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WarehouseStock is the stock of one variant at one warehouse
+type WarehouseStock struct {
+	WarehouseID   int       `json:"warehouse_id"`
+	WarehouseCode string    `json:"warehouse_code"`
+	VariantID     int       `json:"variant_id"`
+	ProductID     int       `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	SKU           string    `json:"sku"`
+	VariantName   string    `json:"variant_name"`
+	Quantity      int       `json:"quantity"`
+	Reserved      int       `json:"reserved"` // Active stock_reservations at this warehouse
+	Available     int       `json:"available"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// StockLine is a quantity of one variant that has to ship together with the rest of an order
+type StockLine struct {
+	VariantID int
+	Quantity  int
+	// Lines without a variant name a product instead, which may be a bundle of variants
+	ProductID int
+}
+
+// WarehouseAvailability maps warehouse ID -> variant ID -> available quantity
+type WarehouseAvailability map[int]map[int]int
+
+// Biteship area IDs look like IDNP10IDNC393IDND4700IDZ50113 (province, city, district, postal code)
+var biteshipAreaPattern = regexp.MustCompile(`^IDNP(\d+)(?:IDNC(\d+))?(?:IDND(\d+))?`)
+
+// areaLevels splits a Biteship area ID into province, city and district codes
+func areaLevels(areaID string) []string {
+	m := biteshipAreaPattern.FindStringSubmatch(areaID)
+	if m == nil {
+		return nil
+	}
+	levels := []string{}
+	for _, code := range m[1:] {
+		if code == "" {
+			break
+		}
+		levels = append(levels, code)
+	}
+	return levels
+}
+
+// AreaMatch counts how many administrative levels (province, city, district) the warehouse
+// shares with the destination area, 0-3
+func (w Warehouse) AreaMatch(destinationAreaID string) int {
+	origin, dest := areaLevels(w.AreaID), areaLevels(destinationAreaID)
+	match := 0
+	for i := 0; i < len(origin) && i < len(dest); i++ {
+		if origin[i] != dest[i] {
+			break
+		}
+		match++
+	}
+	return match
+}
+
+// PostalDistance is how far apart two postal codes are numerically. Indonesian postal codes are
+// assigned by region (1 Jakarta, 5 Central Java, 6 East Java, 8 Bali/Nusa Tenggara, ...), so this is
+// a usable proxy for distance when the area IDs do not match. -1 when either code is unknown.
+func (w Warehouse) PostalDistance(destinationPostalCode string) int {
+	origin, err1 := strconv.Atoi(w.PostalCode)
+	dest, err2 := strconv.Atoi(destinationPostalCode)
+	if err1 != nil || err2 != nil || origin == 0 || dest == 0 {
+		return -1
+	}
+	if origin > dest {
+		return origin - dest
+	}
+	return dest - origin
+}
+
+// CanFulfil reports whether the warehouse has every line in full
+func (a WarehouseAvailability) CanFulfil(warehouseID int, lines []StockLine) bool {
+	stock := a[warehouseID]
+	for _, line := range lines {
+		if stock[line.VariantID] < line.Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// AddHeld counts units a cart already holds at a warehouse as available to that cart
+func (a WarehouseAvailability) AddHeld(warehouseID, variantID, quantity int) {
+	if a[warehouseID] == nil {
+		a[warehouseID] = map[int]int{}
+	}
+	a[warehouseID][variantID] += quantity
+}
+
+// MergeStockLines adds up lines for the same variant and drops lines without one
+func MergeStockLines(lines []StockLine) []StockLine {
+	totals := map[int]int{}
+	order := []int{}
+	for _, line := range lines {
+		if line.VariantID <= 0 || line.Quantity <= 0 {
+			continue
+		}
+		if _, seen := totals[line.VariantID]; !seen {
+			order = append(order, line.VariantID)
+		}
+		totals[line.VariantID] += line.Quantity
+	}
+
+	merged := make([]StockLine, 0, len(order))
+	for _, variantID := range order {
+		merged = append(merged, StockLine{VariantID: variantID, Quantity: totals[variantID]})
+	}
+	return merged
+}
+
+// RouteWarehouse picks the warehouse an order ships from: the nearest active warehouse to the
+// destination that holds every line in full. Nearest means most shared area levels, then closest
+// postal code, then lowest priority. Returns nil when no single warehouse can fulfil the order.
+// With no lines (only products without variants) the default warehouse is used.
+func RouteWarehouse(warehouses []Warehouse, available WarehouseAvailability, lines []StockLine, destinationAreaID, destinationPostalCode string) *Warehouse {
+	lines = MergeStockLines(lines)
+
+	candidates := []Warehouse{}
+	for _, w := range warehouses {
+		if !w.IsActive {
+			continue
+		}
+		if len(lines) == 0 {
+			if w.IsDefault {
+				return &w
+			}
+			continue
+		}
+		if available.CanFulfil(w.ID, lines) {
+			candidates = append(candidates, w)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if ma, mb := a.AreaMatch(destinationAreaID), b.AreaMatch(destinationAreaID); ma != mb {
+			return ma > mb
+		}
+		da, db := a.PostalDistance(destinationPostalCode), b.PostalDistance(destinationPostalCode)
+		if da != db {
+			// Unknown distance sorts last
+			if da < 0 || db < 0 {
+				return db < 0
+			}
+			return da < db
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.ID < b.ID
+	})
+	return &candidates[0]
+}
@@ -0,0 +1,84 @@
+package models
+
+import "testing"
+
+func testWarehouses() []Warehouse {
+	return []Warehouse{
+		{ID: 1, Code: "SMG", PostalCode: "50113", AreaID: "IDNP10IDNC393IDND4700", IsDefault: true, IsActive: true},
+		{ID: 2, Code: "SBY", PostalCode: "60271", AreaID: "IDNP11IDNC444IDND5316", IsActive: true},
+	}
+}
+
+func TestRouteWarehouseNearestWithFullStock(t *testing.T) {
+	available := WarehouseAvailability{
+		1: {10: 5, 11: 5},
+		2: {10: 5, 11: 5},
+	}
+	lines := []StockLine{{VariantID: 10, Quantity: 1}, {VariantID: 11, Quantity: 2}}
+
+	tests := []struct {
+		name       string
+		areaID     string
+		postalCode string
+		expected   string
+	}{
+		{"same city as Surabaya", "IDNP11IDNC444IDND5320IDZ60119", "60119", "SBY"},
+		{"same province as Semarang", "IDNP10IDNC400IDND1000", "57126", "SMG"},
+		{"Bali by postal code", "", "80361", "SBY"},
+		{"Jakarta by postal code", "", "12190", "SMG"},
+	}
+
+	for _, tt := range tests {
+		got := RouteWarehouse(testWarehouses(), available, lines, tt.areaID, tt.postalCode)
+		if got == nil || got.Code != tt.expected {
+			t.Errorf("%s: got %v, expected %s", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRouteWarehouseSkipsPartialStock(t *testing.T) {
+	available := WarehouseAvailability{
+		1: {10: 5, 11: 5},
+		2: {10: 5, 11: 1},
+	}
+	// Merged quantity for variant 11 is 2, which Surabaya does not have
+	lines := []StockLine{{VariantID: 10, Quantity: 1}, {VariantID: 11, Quantity: 1}, {VariantID: 11, Quantity: 1}}
+
+	got := RouteWarehouse(testWarehouses(), available, lines, "IDNP11IDNC444IDND5316", "60271")
+	if got == nil || got.Code != "SMG" {
+		t.Errorf("got %v, expected SMG", got)
+	}
+
+	available[1][11] = 0
+	if got := RouteWarehouse(testWarehouses(), available, lines, "", "60271"); got != nil {
+		t.Errorf("expected no warehouse when stock is split, got %s", got.Code)
+	}
+}
+
+func TestRouteWarehouseSkipsInactiveAndDefaultsWithoutLines(t *testing.T) {
+	warehouses := testWarehouses()
+	warehouses[1].IsActive = false
+	available := WarehouseAvailability{1: {10: 1}, 2: {10: 1}}
+
+	got := RouteWarehouse(warehouses, available, []StockLine{{VariantID: 10, Quantity: 1}}, "IDNP11IDNC444IDND5316", "60271")
+	if got == nil || got.Code != "SMG" {
+		t.Errorf("inactive warehouse was chosen: %v", got)
+	}
+
+	got = RouteWarehouse(testWarehouses(), available, nil, "IDNP11IDNC444IDND5316", "60271")
+	if got == nil || !got.IsDefault {
+		t.Errorf("expected default warehouse for orders without variant lines, got %v", got)
+	}
+}
+
+func TestRouteWarehouseCountsCartHolds(t *testing.T) {
+	// The cart holds the last 2 units at Surabaya; they are off the shelf but belong to this order
+	available := WarehouseAvailability{1: {10: 5}}
+	available.AddHeld(2, 10, 2)
+	lines := []StockLine{{VariantID: 10, Quantity: 2}}
+
+	got := RouteWarehouse(testWarehouses(), available, lines, "IDNP11IDNC444IDND5316", "60271")
+	if got == nil || got.Code != "SBY" {
+		t.Errorf("got %v, expected SBY", got)
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrBundleNotFound = errors.New("bundle not found")
+
+// BundleRepository stores the component variants of bundle products.
+// A bundle's products.stock mirrors the complete sets its components make up; the database keeps
+// it current on every stock movement of a component (refresh_product_stock).
+type BundleRepository interface {
+	// GetComponents returns the components of a bundle in display order
+	GetComponents(bundleProductID int) ([]models.BundleComponent, error)
+	GetComponentsByBundleIDs(bundleProductIDs []int) (map[int][]models.BundleComponent, error)
+	// SetComponents replaces the components and marks the product as a bundle
+	SetComponents(bundleProductID int, components []models.BundleComponent) error
+	// Unbundle removes the components; the product goes back to its own variants' stock
+	Unbundle(bundleProductID int) error
+	// IsComponent reports whether any bundle sells a variant of the product
+	IsComponent(productID int) (bool, error)
+}
+
+type bundleRepository struct {
+	db *sql.DB
+}
+
+func NewBundleRepository(db *sql.DB) BundleRepository {
+	return &bundleRepository{db: db}
+}
+
+const bundleComponentsQuery = `
+	SELECT bc.id, bc.bundle_product_id, bc.variant_id, pv.product_id, p.name,
+	       COALESCE(pv.variant_name, ''), pv.sku, bc.quantity, COALESCE(pv.price, p.price),
+	       pv.stock_quantity, pv.is_active AND p.is_active, bc.position
+	FROM product_bundle_components bc
+	JOIN product_variants pv ON pv.id = bc.variant_id
+	JOIN products p ON p.id = pv.product_id
+`
+
+func scanBundleComponents(rows *sql.Rows) ([]models.BundleComponent, error) {
+	defer rows.Close()
+
+	components := []models.BundleComponent{}
+	for rows.Next() {
+		var c models.BundleComponent
+		err := rows.Scan(
+			&c.ID, &c.BundleProductID, &c.VariantID, &c.ProductID, &c.ProductName,
+			&c.VariantName, &c.SKU, &c.Quantity, &c.Price,
+			&c.Stock, &c.IsActive, &c.Position,
+		)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+func (r *bundleRepository) GetComponents(bundleProductID int) ([]models.BundleComponent, error) {
+	rows, err := r.db.Query(bundleComponentsQuery+`
+		WHERE bc.bundle_product_id = $1
+		ORDER BY bc.position, bc.id
+	`, bundleProductID)
+	if err != nil {
+		return nil, err
+	}
+	return scanBundleComponents(rows)
+}
+
+func (r *bundleRepository) GetComponentsByBundleIDs(bundleProductIDs []int) (map[int][]models.BundleComponent, error) {
+	result := make(map[int][]models.BundleComponent)
+	if len(bundleProductIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(bundleComponentsQuery+`
+		WHERE bc.bundle_product_id = ANY($1)
+		ORDER BY bc.bundle_product_id, bc.position, bc.id
+	`, pq.Array(bundleProductIDs))
+	if err != nil {
+		return nil, err
+	}
+	components, err := scanBundleComponents(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range components {
+		result[c.BundleProductID] = append(result[c.BundleProductID], c)
+	}
+	return result, nil
+}
+
+func (r *bundleRepository) SetComponents(bundleProductID int, components []models.BundleComponent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE products SET is_bundle = true, updated_at = NOW() WHERE id = $1`, bundleProductID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrBundleNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_bundle_components WHERE bundle_product_id = $1`, bundleProductID); err != nil {
+		return err
+	}
+	for i := range components {
+		c := &components[i]
+		err := tx.QueryRow(`
+			INSERT INTO product_bundle_components (bundle_product_id, variant_id, quantity, position)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, bundleProductID, c.VariantID, c.Quantity, c.Position).Scan(&c.ID)
+		if err != nil {
+			return err
+		}
+		c.BundleProductID = bundleProductID
+	}
+
+	if _, err := tx.Exec(`SELECT refresh_bundle_stock($1)`, bundleProductID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *bundleRepository) Unbundle(bundleProductID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE products SET is_bundle = false, updated_at = NOW() WHERE id = $1 AND is_bundle = true
+	`, bundleProductID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrBundleNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_bundle_components WHERE bundle_product_id = $1`, bundleProductID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`SELECT refresh_product_stock($1)`, bundleProductID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *bundleRepository) IsComponent(productID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM product_bundle_components bc
+			JOIN product_variants pv ON pv.id = bc.variant_id
+			WHERE pv.product_id = $1
+		)
+	`, productID).Scan(&exists)
+	return exists, err
+}
@@ -91,8 +91,10 @@ func (r *cartRepository) FindByID(id int) (*models.Cart, error) {
 func (r *cartRepository) FindItemsByCartID(cartID int) ([]models.CartItem, error) {
 	query := `
 		SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, ci.quantity, 
-		       ci.price_snapshot, ci.metadata, ci.created_at, ci.updated_at
+		       ci.price_snapshot, ci.metadata, ci.created_at, ci.updated_at,
+		       sr.id, sr.variant_id, sr.quantity, sr.warehouse_id, sr.reserved_at, sr.expires_at
 		FROM cart_items ci
+		LEFT JOIN stock_reservations sr ON sr.cart_item_id = ci.id AND sr.status = 'active'
 		WHERE ci.cart_id = $1
 		ORDER BY ci.created_at DESC
 	`
@@ -107,15 +109,34 @@ func (r *cartRepository) FindItemsByCartID(cartID int) ([]models.CartItem, error
 	for rows.Next() {
 		var item models.CartItem
 		var metadataJSON []byte
+		var holdID, holdVariantID, holdQuantity, holdWarehouseID sql.NullInt64
+		var holdReservedAt, holdExpiresAt sql.NullTime
 
 		err := rows.Scan(
 			&item.ID, &item.CartID, &item.ProductID, &item.VariantID, &item.Quantity,
 			&item.PriceSnapshot, &metadataJSON, &item.CreatedAt, &item.UpdatedAt,
+			&holdID, &holdVariantID, &holdQuantity, &holdWarehouseID, &holdReservedAt, &holdExpiresAt,
 		)
 		if err != nil {
 			continue
 		}
 
+		// Limited-drop hold (at most one active per item)
+		if holdID.Valid {
+			itemID := item.ID
+			item.Hold = &models.StockReservation{
+				ID:           int(holdID.Int64),
+				VariantID:    int(holdVariantID.Int64),
+				Quantity:     int(holdQuantity.Int64),
+				ReservedAt:   holdReservedAt.Time,
+				ExpiresAt:    holdExpiresAt.Time,
+				Status:       "active",
+				WarehouseID:  nullIntPtr(holdWarehouseID),
+				CartItemID:   &itemID,
+				HeldInLedger: true,
+			}
+		}
+
 		// Parse metadata JSON
 		if len(metadataJSON) > 0 {
 			json.Unmarshal(metadataJSON, &item.Metadata)
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"zavera/models"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type CategoryRepository interface {
+	FindAll(includeInactive bool) ([]models.Category, error)
+	FindByID(id int) (*models.Category, error)
+	FindByPath(path string) (*models.Category, error)
+	FindDescendantIDs(id int, includeInactive bool) ([]int, error)
+	HasInactiveAncestor(path string) (bool, error)
+	Create(category *models.Category) error
+	Update(category *models.Category, oldPath string) error
+	Delete(id int) error
+	CountChildren(id int) (int, error)
+	CountProducts(id int) (int, error)
+}
+
+type categoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+const categoryColumns = `id, parent_id, name, slug, path, depth, sort_order, image_url, is_active, created_at, updated_at`
+
+func scanCategory(scanner interface{ Scan(...interface{}) error }) (*models.Category, error) {
+	var c models.Category
+	var parentID sql.NullInt64
+	var imageURL sql.NullString
+
+	err := scanner.Scan(
+		&c.ID, &parentID, &c.Name, &c.Slug, &c.Path, &c.Depth,
+		&c.SortOrder, &imageURL, &c.IsActive, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	if imageURL.Valid {
+		c.ImageURL = &imageURL.String
+	}
+	return &c, nil
+}
+
+// FindAll returns categories ordered for tree building (parents before children, then sort_order).
+// Inactive categories hide their whole subtree unless includeInactive is set.
+func (r *categoryRepository) FindAll(includeInactive bool) ([]models.Category, error) {
+	query := fmt.Sprintf(`SELECT %s FROM categories ORDER BY depth ASC, sort_order ASC, name ASC`, categoryColumns)
+	if !includeInactive {
+		query = fmt.Sprintf(`
+			SELECT %s FROM categories c
+			WHERE c.is_active = true
+			  AND NOT EXISTS (
+			      SELECT 1 FROM categories a
+			      WHERE a.is_active = false AND c.path LIKE a.path || '/%%'
+			  )
+			ORDER BY depth ASC, sort_order ASC, name ASC`, categoryColumns)
+	}
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *categoryRepository) FindByID(id int) (*models.Category, error) {
+	query := fmt.Sprintf(`SELECT %s FROM categories WHERE id = $1`, categoryColumns)
+
+	c, err := scanCategory(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	return c, err
+}
+
+// FindByPath looks up a category by its slug path, case-insensitively ("Wanita/Dress" matches "wanita/dress")
+func (r *categoryRepository) FindByPath(path string) (*models.Category, error) {
+	query := fmt.Sprintf(`SELECT %s FROM categories WHERE path = LOWER($1)`, categoryColumns)
+
+	c, err := scanCategory(r.db.QueryRow(query, strings.Trim(path, "/")))
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	return c, err
+}
+
+// HasInactiveAncestor reports whether a category above the path is inactive, which hides the
+// whole subtree from FindAll(false)
+func (r *categoryRepository) HasInactiveAncestor(path string) (bool, error) {
+	var hidden bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM categories a
+			WHERE a.is_active = false AND $1 LIKE a.path || '/%'
+		)
+	`, path).Scan(&hidden)
+	return hidden, err
+}
+
+// FindDescendantIDs returns the category and all categories below it
+func (r *categoryRepository) FindDescendantIDs(id int, includeInactive bool) ([]int, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, is_active FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.is_active
+			FROM categories c
+			JOIN subtree s ON c.parent_id = s.id
+			WHERE $2 OR c.is_active = true
+		)
+		SELECT id FROM subtree
+	`
+
+	rows, err := r.db.Query(query, id, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var descendantID int
+		if err := rows.Scan(&descendantID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, descendantID)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *categoryRepository) Create(c *models.Category) error {
+	query := `
+		INSERT INTO categories (parent_id, name, slug, path, depth, sort_order, image_url, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRow(
+		query, c.ParentID, c.Name, c.Slug, c.Path, c.Depth, c.SortOrder, c.ImageURL, c.IsActive,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// Update saves the category. When its path changed (rename or move), the paths and depths
+// of the whole subtree are rewritten in the same transaction, along with the legacy
+// category/subcategory strings of linked products.
+func (r *categoryRepository) Update(c *models.Category, oldPath string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE categories
+		SET parent_id = $1, name = $2, slug = $3, path = $4, depth = $5,
+		    sort_order = $6, image_url = $7, is_active = $8, updated_at = NOW()
+		WHERE id = $9
+	`, c.ParentID, c.Name, c.Slug, c.Path, c.Depth, c.SortOrder, c.ImageURL, c.IsActive, c.ID)
+	if err != nil {
+		return err
+	}
+
+	if oldPath != c.Path {
+		_, err = tx.Exec(`
+			UPDATE categories
+			SET path = $1 || SUBSTRING(path FROM $3),
+			    depth = array_length(string_to_array($1 || SUBSTRING(path FROM $3), '/'), 1) - 1,
+			    updated_at = NOW()
+			WHERE path LIKE $2 || '/%'
+		`, c.Path, oldPath, len(oldPath)+1)
+		if err != nil {
+			return fmt.Errorf("failed to move subtree: %w", err)
+		}
+	}
+
+	// Name or path changes both show up in the legacy strings
+	if err := syncProductCategoryStrings(tx, c.Path); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// syncProductCategoryStrings rewrites products.category/subcategory for products linked to the
+// subtree at path, so code still reading the strings sees the new tree
+func syncProductCategoryStrings(tx *sql.Tx, path string) error {
+	_, err := tx.Exec(`
+		UPDATE products p
+		SET category = split_part(c.path, '/', 1),
+		    subcategory = CASE WHEN c.depth > 0 THEN c.name ELSE '' END,
+		    updated_at = NOW()
+		FROM categories c
+		WHERE p.category_id = c.id
+		  AND (c.path = $1 OR c.path LIKE $1 || '/%')
+	`, path)
+	if err != nil {
+		return fmt.Errorf("failed to sync product categories: %w", err)
+	}
+	return nil
+}
+
+func (r *categoryRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (r *categoryRepository) CountChildren(id int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM categories WHERE parent_id = $1", id).Scan(&count)
+	return count, err
+}
+
+func (r *categoryRepository) CountProducts(id int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM products WHERE category_id = $1", id).Scan(&count)
+	return count, err
+}
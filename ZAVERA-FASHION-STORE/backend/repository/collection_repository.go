@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCollectionNotFound  = errors.New("collection not found")
+	ErrDuplicateCollection = errors.New("collection slug already exists")
+)
+
+type CollectionRepository interface {
+	FindAll(visibleOnly bool) ([]models.Collection, error)
+	FindByID(id int) (*models.Collection, error)
+	FindBySlug(slug string) (*models.Collection, error)
+	Create(collection *models.Collection) error
+	Update(collection *models.Collection) error
+	Delete(id int) error
+	SetProducts(collectionID int, productIDs []int) error
+}
+
+type collectionRepository struct {
+	db *sql.DB
+}
+
+func NewCollectionRepository(db *sql.DB) CollectionRepository {
+	return &collectionRepository{db: db}
+}
+
+const collectionColumns = `c.id, c.name, c.slug, COALESCE(c.description, ''), COALESCE(c.image_url, ''),
+	c.collection_type, c.rules, c.match_type, c.sort_by, c.sort_order, c.is_active,
+	c.starts_at, c.ends_at, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM collection_products cp WHERE cp.collection_id = c.id) as product_count`
+
+// visibleCollectionCondition is the public visibility window check
+const visibleCollectionCondition = `c.is_active = true
+	AND (c.starts_at IS NULL OR c.starts_at <= NOW())
+	AND (c.ends_at IS NULL OR c.ends_at > NOW())`
+
+func scanCollection(scanner interface{ Scan(...interface{}) error }) (*models.Collection, error) {
+	var c models.Collection
+	var startsAt, endsAt sql.NullTime
+
+	err := scanner.Scan(
+		&c.ID, &c.Name, &c.Slug, &c.Description, &c.ImageURL,
+		&c.Type, &c.Rules, &c.MatchType, &c.SortBy, &c.SortOrder, &c.IsActive,
+		&startsAt, &endsAt, &c.CreatedAt, &c.UpdatedAt, &c.ProductCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if startsAt.Valid {
+		c.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		c.EndsAt = &endsAt.Time
+	}
+	return &c, nil
+}
+
+func (r *collectionRepository) FindAll(visibleOnly bool) ([]models.Collection, error) {
+	where := ""
+	if visibleOnly {
+		where = "WHERE " + visibleCollectionCondition
+	}
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM collections c
+		%s
+		ORDER BY c.sort_order ASC, c.created_at DESC
+	`, collectionColumns, where)
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *c)
+	}
+
+	return collections, rows.Err()
+}
+
+func (r *collectionRepository) FindByID(id int) (*models.Collection, error) {
+	query := fmt.Sprintf(`SELECT %s FROM collections c WHERE c.id = $1`, collectionColumns)
+
+	c, err := scanCollection(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+func (r *collectionRepository) FindBySlug(slug string) (*models.Collection, error) {
+	query := fmt.Sprintf(`SELECT %s FROM collections c WHERE c.slug = $1`, collectionColumns)
+
+	c, err := scanCollection(r.db.QueryRow(query, slug))
+	if err == sql.ErrNoRows {
+		return nil, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+func (r *collectionRepository) Create(c *models.Collection) error {
+	query := `
+		INSERT INTO collections (name, slug, description, image_url, collection_type, rules, match_type,
+		                         sort_by, sort_order, is_active, starts_at, ends_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		query, c.Name, c.Slug, c.Description, c.ImageURL, c.Type, c.Rules, c.MatchType,
+		c.SortBy, c.SortOrder, c.IsActive, c.StartsAt, c.EndsAt,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateCollection
+	}
+	return err
+}
+
+func (r *collectionRepository) Update(c *models.Collection) error {
+	query := `
+		UPDATE collections
+		SET name = $1, slug = $2, description = NULLIF($3, ''), image_url = NULLIF($4, ''),
+		    collection_type = $5, rules = $6, match_type = $7, sort_by = $8, sort_order = $9,
+		    is_active = $10, starts_at = $11, ends_at = $12, updated_at = NOW()
+		WHERE id = $13
+	`
+
+	result, err := r.db.Exec(
+		query, c.Name, c.Slug, c.Description, c.ImageURL, c.Type, c.Rules, c.MatchType,
+		c.SortBy, c.SortOrder, c.IsActive, c.StartsAt, c.EndsAt, c.ID,
+	)
+	if isUniqueViolation(err) {
+		return ErrDuplicateCollection
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+func (r *collectionRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM collections WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// SetProducts replaces the members of a manual collection; list order becomes position
+func (r *collectionRepository) SetProducts(collectionID int, productIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM collection_products WHERE collection_id = $1", collectionID); err != nil {
+		return err
+	}
+
+	if len(productIDs) > 0 {
+		// unnest WITH ORDINALITY keeps the given order; unknown product IDs are skipped
+		_, err = tx.Exec(`
+			INSERT INTO collection_products (collection_id, product_id, position)
+			SELECT $1, ids.product_id, ids.position
+			FROM unnest($2::int[]) WITH ORDINALITY AS ids(product_id, position)
+			JOIN products p ON p.id = ids.product_id
+			ON CONFLICT (collection_id, product_id) DO NOTHING
+		`, collectionID, pq.Array(productIDs))
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE collections SET updated_at = NOW() WHERE id = $1", collectionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"zavera/models"
+)
+
+func TestBuildCollectionRuleWhere(t *testing.T) {
+	rules := models.CollectionRules{
+		{Field: models.CollectionFieldCategory, Operator: "eq", Value: "Wanita/Dress"},
+		{Field: models.CollectionFieldBrand, Operator: "neq", Value: "Zara"},
+		{Field: models.CollectionFieldPrice, Operator: "lt", Value: 200000.0},
+		{Field: models.CollectionFieldTag, Operator: "in", Value: []interface{}{"Sale", "ramadan"}},
+		{Field: models.CollectionFieldCreatedAt, Operator: "within_days", Value: 30.0},
+	}
+
+	where, args := buildCollectionRuleWhere(rules, true)
+
+	for _, fragment := range []string{"c.path LIKE want.path || '/%'", "<> ALL($2)", "p.price < $3", "p.tags && $4", "make_interval(days => $5)"} {
+		if !strings.Contains(where, fragment) {
+			t.Errorf("expected where to contain %q, got %s", fragment, where)
+		}
+	}
+	if strings.Count(where, " AND ") < len(rules)-1 {
+		t.Errorf("expected rules joined with AND, got %s", where)
+	}
+	if len(args) != 5 {
+		t.Fatalf("expected 5 args, got %d", len(args))
+	}
+	if args[4] != 30 {
+		t.Errorf("expected within_days as int arg, got %#v", args[4])
+	}
+}
+
+func TestBuildCollectionRuleWhere_MatchAny(t *testing.T) {
+	rules := models.CollectionRules{
+		{Field: models.CollectionFieldTag, Operator: "eq", Value: "sale"},
+		{Field: models.CollectionFieldPrice, Operator: "gte", Value: 1000000.0},
+	}
+
+	where, _ := buildCollectionRuleWhere(rules, false)
+	if !strings.Contains(where, " OR ") {
+		t.Errorf("expected rules joined with OR, got %s", where)
+	}
+}
+
+func TestBuildCollectionRuleWhere_NeverMatchesEverything(t *testing.T) {
+	if where, _ := buildCollectionRuleWhere(nil, true); where != "false" {
+		t.Errorf("expected no rules to match nothing, got %s", where)
+	}
+
+	// A malformed rule (e.g. saved before validation existed) must not widen the collection
+	rules := models.CollectionRules{{Field: models.CollectionFieldPrice, Operator: "lt", Value: "cheap"}}
+	where, args := buildCollectionRuleWhere(rules, false)
+	if where != "(false)" || len(args) != 0 {
+		t.Errorf("expected malformed rule to match nothing, got %s %v", where, args)
+	}
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 	"zavera/models"
+
+	"github.com/lib/pq"
 )
 
 // EmailRepository handles email template and log operations
@@ -21,6 +24,10 @@ type EmailRepository interface {
 	
 	// Duplicate check
 	HasSentEmail(orderID int, templateKey string) (bool, error)
+
+	// Non-order emails (alerts): dedup by reference and rate limit per user
+	HasSentReference(userID int, templateKey, reference string, since time.Time) (bool, error)
+	CountSentToUser(userID int, templateKeys []string, since time.Time) (int, error)
 }
 
 type emailRepository struct {
@@ -99,15 +106,15 @@ func (r *emailRepository) UpdateTemplate(template *models.EmailTemplate) error {
 // CreateEmailLog creates a new email log entry
 func (r *emailRepository) CreateEmailLog(log *models.EmailLog) error {
 	query := `
-		INSERT INTO email_logs (order_id, user_id, template_key, recipient_email, subject, status, error_message, sent_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO email_logs (order_id, user_id, template_key, recipient_email, subject, status, error_message, sent_at, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
 		RETURNING id, created_at
 	`
 
 	err := r.db.QueryRow(
 		query,
 		log.OrderID, log.UserID, log.TemplateKey, log.RecipientEmail,
-		log.Subject, log.Status, log.ErrorMessage, log.SentAt,
+		log.Subject, log.Status, log.ErrorMessage, log.SentAt, log.Reference,
 	).Scan(&log.ID, &log.CreatedAt)
 
 	return err
@@ -236,3 +243,35 @@ func (r *emailRepository) HasSentEmail(orderID int, templateKey string) (bool, e
 
 	return exists, nil
 }
+
+// HasSentReference checks if a user was already sent this email about the same thing since a time
+func (r *emailRepository) HasSentReference(userID int, templateKey, reference string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM email_logs
+			WHERE user_id = $1 AND template_key = $2 AND reference = $3
+			AND status = 'SENT' AND created_at >= $4
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(query, userID, templateKey, reference, since).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// CountSentToUser counts emails of the given types sent to a user since a time
+func (r *emailRepository) CountSentToUser(userID int, templateKeys []string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM email_logs
+		WHERE user_id = $1 AND template_key = ANY($2)
+		AND status = 'SENT' AND created_at >= $3
+	`
+
+	var count int
+	err := r.db.QueryRow(query, userID, pq.Array(templateKeys), since).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+)
+
+var (
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrUserNotFound    = errors.New("user not found")
+)
+
+// InvoiceRepository reads the invoices and credit notes issued by the database when orders are
+// paid and refunds complete, and stores the NPWP customers want on them
+type InvoiceRepository interface {
+	FindByID(id int) (*models.Invoice, error)
+	// FindByOrderID returns the order's invoice followed by its credit notes
+	FindByOrderID(orderID int) ([]models.Invoice, error)
+	// FindByPeriod returns the documents issued in a YYYY-MM tax period, by number
+	FindByPeriod(period string) ([]models.Invoice, error)
+
+	GetTaxProfile(userID int) (*models.TaxProfile, error)
+	SaveTaxProfile(profile *models.TaxProfile) error
+}
+
+type invoiceRepository struct {
+	db *sql.DB
+}
+
+func NewInvoiceRepository(db *sql.DB) InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+const invoiceSelectQuery = `
+	SELECT i.id, i.invoice_number, i.document_type, i.order_id, o.order_code, i.refund_id,
+	       i.related_invoice_id, COALESCE(ri.invoice_number, ''), i.tax_period, i.issued_at,
+	       i.buyer_name, i.buyer_email, COALESCE(i.buyer_npwp, ''), COALESCE(i.buyer_npwp_name, ''),
+	       COALESCE(i.buyer_npwp_address, ''), i.subtotal, i.discount, i.shipping_cost, i.tax_rate,
+	       i.tax, i.total_amount, i.prices_include_tax
+	FROM invoices i
+	JOIN orders o ON o.id = i.order_id
+	LEFT JOIN invoices ri ON ri.id = i.related_invoice_id
+`
+
+func (r *invoiceRepository) FindByID(id int) (*models.Invoice, error) {
+	invoices, err := r.query(invoiceSelectQuery+" WHERE i.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, ErrInvoiceNotFound
+	}
+	return &invoices[0], nil
+}
+
+func (r *invoiceRepository) FindByOrderID(orderID int) ([]models.Invoice, error) {
+	return r.query(invoiceSelectQuery+`
+		WHERE i.order_id = $1
+		ORDER BY i.document_type = 'CREDIT_NOTE', i.issued_at, i.id
+	`, orderID)
+}
+
+func (r *invoiceRepository) FindByPeriod(period string) ([]models.Invoice, error) {
+	return r.query(invoiceSelectQuery+`
+		WHERE i.tax_period = $1
+		ORDER BY i.document_type, i.invoice_number
+	`, period)
+}
+
+func (r *invoiceRepository) query(query string, args ...any) ([]models.Invoice, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		var inv models.Invoice
+		var refundID, relatedID sql.NullInt64
+		err := rows.Scan(
+			&inv.ID, &inv.InvoiceNumber, &inv.DocumentType, &inv.OrderID, &inv.OrderCode, &refundID,
+			&relatedID, &inv.RelatedNumber, &inv.TaxPeriod, &inv.IssuedAt,
+			&inv.BuyerName, &inv.BuyerEmail, &inv.BuyerNPWP, &inv.BuyerNPWPName,
+			&inv.BuyerNPWPAddress, &inv.Subtotal, &inv.Discount, &inv.ShippingCost, &inv.TaxRate,
+			&inv.Tax, &inv.TotalAmount, &inv.PricesIncludeTax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if refundID.Valid {
+			id := int(refundID.Int64)
+			inv.RefundID = &id
+		}
+		if relatedID.Valid {
+			id := int(relatedID.Int64)
+			inv.RelatedInvoiceID = &id
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+func (r *invoiceRepository) GetTaxProfile(userID int) (*models.TaxProfile, error) {
+	profile := models.TaxProfile{UserID: userID}
+	err := r.db.QueryRow(`
+		SELECT COALESCE(npwp, ''), COALESCE(npwp_name, ''), COALESCE(npwp_address, '')
+		FROM users WHERE id = $1
+	`, userID).Scan(&profile.NPWP, &profile.Name, &profile.Address)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *invoiceRepository) SaveTaxProfile(profile *models.TaxProfile) error {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET npwp = NULLIF($2, ''), npwp_name = NULLIF($3, ''), npwp_address = NULLIF($4, ''),
+		    updated_at = NOW()
+		WHERE id = $1
+	`, profile.UserID, profile.NPWP, profile.Name, profile.Address)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -34,16 +34,28 @@ type OrderRepository interface {
 	UpdateResi(orderID int, resi string) error
 	RestoreStock(orderID int) error
 	RestoreStockTx(tx *sql.Tx, orderID int) error
+	RestockRefundedOrder(orderID int) error
+	// AllocateBackorders reserves stock that has arrived for the order's pre-order/backorder lines.
+	// Returns true while some units are still waiting.
+	AllocateBackorders(orderID int) (bool, error)
 	RecordStatusChange(orderID int, fromStatus, toStatus models.OrderStatus, changedBy, reason string) error
 	IsResiExists(resi string) (bool, error)
 }
 
 type orderRepository struct {
-	db *sql.DB
+	db          *sql.DB
+	stock       StockRepository
+	vouchers    VoucherRepository
+	storeCredit StoreCreditRepository
 }
 
 func NewOrderRepository(db *sql.DB) OrderRepository {
-	return &orderRepository{db: db}
+	return &orderRepository{
+		db:          db,
+		stock:       NewStockRepository(db),
+		vouchers:    NewVoucherRepository(db),
+		storeCredit: NewStoreCreditRepository(db),
+	}
 }
 
 func (r *orderRepository) Create(order *models.Order, items []models.OrderItem) error {
@@ -57,77 +69,16 @@ func (r *orderRepository) Create(order *models.Order, items []models.OrderItem)
 	order.OrderCode = r.generateOrderCode()
 	order.StockReserved = true // Stock will be reserved
 
-	// Step 1: Validate and reserve stock within transaction
-	for _, item := range items {
-		log.Printf("🔍 Checking stock for item: product_id=%d, variant_id=%v, quantity=%d", 
-			item.ProductID, item.VariantID, item.Quantity)
-		
-		// Check if this is a variant product
-		if item.VariantID != nil && *item.VariantID > 0 {
-			log.Printf("✅ Variant product detected: variant_id=%d", *item.VariantID)
-			// Variant product - check and reserve variant stock
-			var currentStock int
-			stockQuery := `
-				SELECT stock_quantity FROM product_variants WHERE id = $1 FOR UPDATE
-			`
-			err := tx.QueryRow(stockQuery, *item.VariantID).Scan(&currentStock)
-			if err != nil {
-				return fmt.Errorf("failed to check stock for variant %d: %w", *item.VariantID, err)
-			}
-			
-			log.Printf("📦 Variant stock: variant_id=%d, stock=%d, requested=%d", 
-				*item.VariantID, currentStock, item.Quantity)
-
-			if currentStock < item.Quantity {
-				return fmt.Errorf("insufficient stock for product %s: available %d, requested %d",
-					item.ProductName, currentStock, item.Quantity)
-			}
-
-			// Reserve variant stock (deduct)
-			reserveQuery := `
-				UPDATE product_variants SET stock_quantity = stock_quantity - $1 WHERE id = $2
-			`
-			_, err = tx.Exec(reserveQuery, item.Quantity, *item.VariantID)
-			if err != nil {
-				return fmt.Errorf("failed to reserve stock for variant %d: %w", *item.VariantID, err)
-			}
-		} else {
-			log.Printf("⚠️ Simple product (no variant_id): product_id=%d", item.ProductID)
-			// Simple product - check and reserve product stock
-			var currentStock int
-			stockQuery := `
-				SELECT stock FROM products WHERE id = $1 FOR UPDATE
-			`
-			err := tx.QueryRow(stockQuery, item.ProductID).Scan(&currentStock)
-			if err != nil {
-				return fmt.Errorf("failed to check stock for product %d: %w", item.ProductID, err)
-			}
-
-			if currentStock < item.Quantity {
-				return fmt.Errorf("insufficient stock for product %s: available %d, requested %d",
-					item.ProductName, currentStock, item.Quantity)
-			}
-
-			// Reserve stock (deduct)
-			reserveQuery := `
-				UPDATE products SET stock = stock - $1 WHERE id = $2
-			`
-			_, err = tx.Exec(reserveQuery, item.Quantity, item.ProductID)
-			if err != nil {
-				return fmt.Errorf("failed to reserve stock for product %d: %w", item.ProductID, err)
-			}
-		}
-	}
-
-	// Step 2: Insert order
+	// Step 1: Insert order
 	metadataJSON, _ := json.Marshal(order.Metadata)
 	orderQuery := `
 		INSERT INTO orders (
 			order_code, user_id, customer_name, customer_email, customer_phone,
 			subtotal, shipping_cost, tax, discount, total_amount, status, 
-			stock_reserved, notes, metadata, origin_city, destination_city
+			stock_reserved, notes, metadata, origin_city, destination_city, warehouse_id,
+			tax_rate, prices_include_tax, store_credit_amount
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -136,56 +87,335 @@ func (r *orderRepository) Create(order *models.Order, items []models.OrderItem)
 		order.OrderCode, order.UserID, order.CustomerName, order.CustomerEmail, order.CustomerPhone,
 		order.Subtotal, order.ShippingCost, order.Tax, order.Discount, order.TotalAmount,
 		order.Status, order.StockReserved, order.Notes, metadataJSON,
-		order.OriginCity, order.DestinationCity,
+		order.OriginCity, order.DestinationCity, order.WarehouseID,
+		order.TaxRate, order.PricesIncludeTax, order.StoreCreditAmount,
 	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
 
 	if err != nil {
 		return err
 	}
 
-	// Step 3: Insert order items and record stock movements
-	itemQuery := `
-		INSERT INTO order_items (
-			order_id, product_id, variant_id, product_name, quantity, price_per_unit, subtotal, metadata
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`
+	// The voucher is redeemed with the order so its usage limits cannot be overrun; the
+	// order is not created when the voucher ran out in the meantime
+	if order.Voucher != nil {
+		order.Voucher.OrderID = order.ID
+		if err := r.vouchers.RedeemTx(tx, order.Voucher); err != nil {
+			return err
+		}
+	}
 
-	stockMovementQuery := `
-		INSERT INTO stock_movements (product_id, order_id, movement_type, quantity, balance_after, notes)
-		SELECT $1, $2, 'RESERVE', $3, stock, 'Stock reserved at checkout'
-		FROM products WHERE id = $1
-	`
+	// Store credit is spent with the order, so the balance cannot pay for two orders at once
+	if order.StoreCreditAmount > 0 {
+		if order.UserID == nil {
+			return ErrInsufficientStoreCredit
+		}
+		err := r.storeCredit.AddEntryTx(tx, &models.StoreCreditEntry{
+			UserID:    *order.UserID,
+			EntryType: models.StoreCreditEntryOrderPayment,
+			Amount:    -order.StoreCreditAmount,
+			OrderID:   &order.ID,
+			Note:      "Order " + order.OrderCode,
+			CreatedBy: "system",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The cart's limited-drop holds go back on the shelf so the order can take them below
+	if order.CartID != nil {
+		_, err = tx.Exec(`
+			SELECT release_reservation(sr.id, 'completed', $2)
+			FROM stock_reservations sr
+			JOIN cart_items ci ON ci.id = sr.cart_item_id
+			WHERE ci.cart_id = $1 AND sr.status = 'active'
+		`, *order.CartID, order.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 2: Insert order items and reserve their stock on the ledger. Pre-order/backorder
+	// variants reserve what is on the shelf; the rest waits for stock and holds the order.
+	for i := range items {
+		item := &items[i]
+		log.Printf("🔍 Reserving stock for item: product_id=%d, variant_id=%v, quantity=%d",
+			item.ProductID, item.VariantID, item.Quantity)
+
+		// Bundles have no stock of their own; each component is reserved instead
+		components, err := r.bundleComponentsTx(tx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to load bundle %d: %w", item.ProductID, err)
+		}
+		if len(components) > 0 {
+			if err := r.createBundleItemTx(tx, order, item, components); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Products without variants keep their stock on a default variant
+		if item.VariantID == nil || *item.VariantID <= 0 {
+			variantID, err := r.stock.DefaultVariantTx(tx, item.ProductID)
+			if err != nil {
+				return fmt.Errorf("failed to resolve stock for product %d: %w", item.ProductID, err)
+			}
+			item.VariantID = &variantID
+		}
+
+		state, err := r.lockVariantStockTx(tx, *item.VariantID, order.WarehouseID)
+		if err != nil {
+			return fmt.Errorf("failed to check stock for variant %d: %w", *item.VariantID, err)
+		}
+		fromStock, backordered, ok := models.SplitBackorder(state.policy, item.Quantity,
+			state.onHand-state.waiting, state.outstanding, state.limit)
+		if !ok {
+			if state.policy.AllowsBackorder() {
+				return fmt.Errorf("pre-order limit reached for product %s: requested %d", item.ProductName, item.Quantity)
+			}
+			return fmt.Errorf("insufficient stock for product %s: requested %d", item.ProductName, item.Quantity)
+		}
+		item.BackorderedQuantity = backordered
+		item.ExpectedShipDate = nil
+		if backordered > 0 {
+			item.ExpectedShipDate = state.expectedShipDate
+		}
 
-	for _, item := range items {
 		itemMetadataJSON, _ := json.Marshal(item.Metadata)
-		_, err := tx.Exec(
-			itemQuery,
+		err = tx.QueryRow(
+			orderItemInsertQuery,
 			order.ID, item.ProductID, item.VariantID, item.ProductName, item.Quantity,
 			item.PricePerUnit, item.Subtotal, itemMetadataJSON,
-		)
+			item.BackorderedQuantity, item.ExpectedShipDate,
+			item.DiscountAmount, item.TaxRate, item.TaxAmount,
+		).Scan(&item.ID)
 		if err != nil {
 			return err
 		}
+		item.OrderID = order.ID
 
-		// Record stock movement for audit
-		_, err = tx.Exec(stockMovementQuery, item.ProductID, order.ID, item.Quantity)
+		if backordered > 0 {
+			order.AwaitingStock = true
+			if item.ExpectedShipDate != nil && (order.ExpectedShipDate == nil || item.ExpectedShipDate.After(*order.ExpectedShipDate)) {
+				order.ExpectedShipDate = item.ExpectedShipDate
+			}
+			log.Printf("⏳ %d of %d units of variant %d backordered", backordered, item.Quantity, *item.VariantID)
+		}
+		if fromStock == 0 {
+			continue
+		}
+
+		// Routed orders deduct from the fulfilling warehouse, others from the default one
+		orderID := order.ID
+		err = r.stock.ApplyMovementTx(tx, &models.StockLedgerEntry{
+			VariantID:    *item.VariantID,
+			WarehouseID:  order.WarehouseID,
+			MovementType: models.StockMovementReserve,
+			Quantity:     -fromStock,
+			OrderID:      &orderID,
+			Reference:    order.OrderCode,
+			Notes:        "Stock reserved at checkout",
+			Actor:        "system",
+		})
+		if err == ErrInsufficientStock {
+			return fmt.Errorf("insufficient stock for product %s: requested %d", item.ProductName, item.Quantity)
+		}
 		if err != nil {
-			// Non-critical, log but don't fail
-			fmt.Printf("Warning: failed to record stock movement for product %d: %v\n", item.ProductID, err)
+			return fmt.Errorf("failed to reserve stock for variant %d: %w", *item.VariantID, err)
 		}
 	}
 
-	// Step 4: Record initial status
+	if order.AwaitingStock {
+		_, err = tx.Exec(`
+			UPDATE orders SET awaiting_stock = true, expected_ship_date = $2 WHERE id = $1
+		`, order.ID, order.ExpectedShipDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 3: Record initial status
 	historyQuery := `
 		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
 		VALUES ($1, NULL, $2, 'system', 'Order created')
 	`
 	tx.Exec(historyQuery, order.ID, order.Status) // Ignore error, non-critical
 
+	// An order with nothing left to pay (store credit or a 100% voucher) is paid as soon as it
+	// exists; Midtrans has nothing to charge
+	if order.AmountDue() == 0 {
+		if err := r.MarkAsPaidTx(tx, order.ID); err != nil {
+			return fmt.Errorf("failed to mark order paid: %w", err)
+		}
+		reason := "Nothing left to pay"
+		if order.StoreCreditAmount > 0 {
+			reason = "Paid with store credit"
+		}
+		_, err = tx.Exec(`
+			INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+			VALUES ($1, $2, $3, 'system', $4)
+		`, order.ID, order.Status, models.OrderStatusPaid, reason)
+		if err != nil {
+			return err
+		}
+		order.Status = models.OrderStatusPaid
+	}
+
 	return tx.Commit()
 }
 
+const orderItemInsertQuery = `
+	INSERT INTO order_items (
+		order_id, product_id, variant_id, product_name, quantity, price_per_unit, subtotal, metadata,
+		backordered_quantity, expected_ship_date, discount_amount, tax_rate, tax_amount
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING id
+`
+
+func (r *orderRepository) bundleComponentsTx(tx *sql.Tx, productID int) ([]models.BundleComponent, error) {
+	rows, err := tx.Query(bundleComponentsQuery+`
+		WHERE bc.bundle_product_id = $1
+		ORDER BY bc.position, bc.id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	return scanBundleComponents(rows)
+}
+
+// createBundleItemTx inserts a bundle line, snapshots its components with their share of the
+// bundle price and reserves every component. Bundles are sold from stock only; backorders
+// waiting for a component keep their claim on it.
+func (r *orderRepository) createBundleItemTx(tx *sql.Tx, order *models.Order, item *models.OrderItem, components []models.BundleComponent) error {
+	item.VariantID = nil
+	item.BackorderedQuantity = 0
+	item.ExpectedShipDate = nil
+
+	itemMetadataJSON, _ := json.Marshal(item.Metadata)
+	err := tx.QueryRow(
+		orderItemInsertQuery,
+		order.ID, item.ProductID, nil, item.ProductName, item.Quantity,
+		item.PricePerUnit, item.Subtotal, itemMetadataJSON, 0, nil,
+		item.DiscountAmount, item.TaxRate, item.TaxAmount,
+	).Scan(&item.ID)
+	if err != nil {
+		return err
+	}
+	item.OrderID = order.ID
+
+	shares := models.AllocateBundlePrice(item.PricePerUnit, components)
+	item.Components = make([]models.OrderItemComponent, 0, len(components))
+	orderID := order.ID
+	for i, c := range components {
+		needed := c.Quantity * item.Quantity
+		if !c.IsActive {
+			return fmt.Errorf("insufficient stock for product %s: %s is no longer sold", item.ProductName, c.ProductName)
+		}
+		state, err := r.lockVariantStockTx(tx, c.VariantID, order.WarehouseID)
+		if err != nil {
+			return fmt.Errorf("failed to check stock for variant %d: %w", c.VariantID, err)
+		}
+		if state.onHand-state.waiting < needed {
+			return fmt.Errorf("insufficient stock for product %s: requested %d", item.ProductName, item.Quantity)
+		}
+
+		component := models.OrderItemComponent{
+			OrderItemID:    item.ID,
+			VariantID:      c.VariantID,
+			ProductID:      c.ProductID,
+			ProductName:    c.ProductName,
+			VariantName:    c.VariantName,
+			Quantity:       c.Quantity,
+			AllocatedPrice: shares[i],
+		}
+		err = tx.QueryRow(`
+			INSERT INTO order_item_components (
+				order_item_id, variant_id, product_id, product_name, variant_name, quantity, allocated_price
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, component.OrderItemID, component.VariantID, component.ProductID, component.ProductName,
+			component.VariantName, component.Quantity, component.AllocatedPrice,
+		).Scan(&component.ID)
+		if err != nil {
+			return err
+		}
+
+		err = r.stock.ApplyMovementTx(tx, &models.StockLedgerEntry{
+			VariantID:    c.VariantID,
+			WarehouseID:  order.WarehouseID,
+			MovementType: models.StockMovementReserve,
+			Quantity:     -needed,
+			OrderID:      &orderID,
+			Reference:    order.OrderCode,
+			Notes:        "Bundle component reserved at checkout",
+			Actor:        "system",
+		})
+		if err == ErrInsufficientStock {
+			return fmt.Errorf("insufficient stock for product %s: requested %d", item.ProductName, item.Quantity)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reserve stock for variant %d: %w", c.VariantID, err)
+		}
+		item.Components = append(item.Components, component)
+	}
+	return nil
+}
+
+// variantStockState is what order creation needs to split a line into stock and backorder
+type variantStockState struct {
+	policy           models.InventoryPolicy
+	limit            *int
+	expectedShipDate *time.Time
+	onHand           int // At the warehouse
+	waiting          int // Backordered at the warehouse; has first claim on arriving stock
+	outstanding      int // Backordered anywhere; counts against limit
+}
+
+// lockVariantStockTx locks the variant row so concurrent checkouts cannot both take the last
+// backorder slots. A nil warehouseID means the default warehouse.
+func (r *orderRepository) lockVariantStockTx(tx *sql.Tx, variantID int, warehouseID *int) (*variantStockState, error) {
+	var state variantStockState
+	var limit sql.NullInt64
+	var expected sql.NullTime
+	err := tx.QueryRow(`
+		WITH default_warehouse AS (
+			SELECT id FROM warehouses WHERE is_default = true
+		), target AS (
+			SELECT COALESCE($2::int, (SELECT id FROM default_warehouse)) as warehouse_id
+		)
+		SELECT pv.inventory_policy, pv.backorder_limit, pv.expected_ship_date,
+		       COALESCE((
+		           SELECT ws.quantity FROM warehouse_stock ws, target t
+		           WHERE ws.variant_id = pv.id AND ws.warehouse_id = t.warehouse_id
+		       ), 0),
+		       COALESCE((
+		           SELECT SUM(oi.backordered_quantity) FROM order_items oi
+		           JOIN orders o ON o.id = oi.order_id, target t
+		           WHERE oi.variant_id = pv.id AND oi.backordered_quantity > 0
+		           AND COALESCE(o.warehouse_id, (SELECT id FROM default_warehouse)) = t.warehouse_id
+		       ), 0),
+		       COALESCE((
+		           SELECT SUM(oi.backordered_quantity) FROM order_items oi
+		           WHERE oi.variant_id = pv.id AND oi.backordered_quantity > 0
+		       ), 0)
+		FROM product_variants pv
+		WHERE pv.id = $1
+		FOR UPDATE OF pv
+	`, variantID, warehouseID).Scan(
+		&state.policy, &limit, &expected, &state.onHand, &state.waiting, &state.outstanding,
+	)
+	if err != nil {
+		return nil, err
+	}
+	state.limit = nullIntPtr(limit)
+	if expected.Valid {
+		state.expectedShipDate = &expected.Time
+	}
+	return &state, nil
+}
+
 func (r *orderRepository) FindByID(id int) (*models.Order, error) {
 	query := `
 		SELECT id, order_code, user_id, customer_name, customer_email, customer_phone,
@@ -195,7 +425,10 @@ func (r *orderRepository) FindByID(id int) (*models.Order, error) {
 		       COALESCE(origin_city, 'Semarang') as origin_city,
 		       COALESCE(destination_city, '') as destination_city,
 		       notes, metadata, created_at, updated_at, 
-		       paid_at, shipped_at, delivered_at, completed_at, cancelled_at
+		       paid_at, shipped_at, delivered_at, completed_at, cancelled_at,
+		       COALESCE(awaiting_stock, false), expected_ship_date,
+		       COALESCE(tax_rate, 0), COALESCE(prices_include_tax, true),
+		       COALESCE(store_credit_amount, 0)
 		FROM orders
 		WHERE id = $1
 	`
@@ -209,6 +442,9 @@ func (r *orderRepository) FindByID(id int) (*models.Order, error) {
 		&order.Resi, &order.OriginCity, &order.DestinationCity,
 		&order.Notes, &metadataJSON, &order.CreatedAt, &order.UpdatedAt,
 		&order.PaidAt, &order.ShippedAt, &order.DeliveredAt, &order.CompletedAt, &order.CancelledAt,
+		&order.AwaitingStock, &order.ExpectedShipDate,
+		&order.TaxRate, &order.PricesIncludeTax,
+		&order.StoreCreditAmount,
 	)
 
 	if err != nil {
@@ -236,7 +472,10 @@ func (r *orderRepository) FindByOrderCode(orderCode string) (*models.Order, erro
 		       COALESCE(origin_city, 'Semarang') as origin_city,
 		       COALESCE(destination_city, '') as destination_city,
 		       notes, metadata, created_at, updated_at, 
-		       paid_at, shipped_at, delivered_at, completed_at, cancelled_at
+		       paid_at, shipped_at, delivered_at, completed_at, cancelled_at,
+		       COALESCE(awaiting_stock, false), expected_ship_date,
+		       COALESCE(tax_rate, 0), COALESCE(prices_include_tax, true),
+		       COALESCE(store_credit_amount, 0)
 		FROM orders
 		WHERE order_code = $1
 	`
@@ -250,6 +489,9 @@ func (r *orderRepository) FindByOrderCode(orderCode string) (*models.Order, erro
 		&order.Resi, &order.OriginCity, &order.DestinationCity,
 		&order.Notes, &metadataJSON, &order.CreatedAt, &order.UpdatedAt,
 		&order.PaidAt, &order.ShippedAt, &order.DeliveredAt, &order.CompletedAt, &order.CancelledAt,
+		&order.AwaitingStock, &order.ExpectedShipDate,
+		&order.TaxRate, &order.PricesIncludeTax,
+		&order.StoreCreditAmount,
 	)
 
 	if err != nil {
@@ -284,7 +526,8 @@ func (r *orderRepository) FindByOrderCodeForUpdate(orderCode string) (*models.Or
 		SELECT id, order_code, user_id, customer_name, customer_email, customer_phone,
 		       subtotal, shipping_cost, tax, discount, total_amount, status,
 		       COALESCE(stock_reserved, true) as stock_reserved, notes,
-		       metadata, created_at, updated_at, paid_at, shipped_at, completed_at, cancelled_at
+		       metadata, created_at, updated_at, paid_at, shipped_at, completed_at, cancelled_at,
+		       COALESCE(store_credit_amount, 0)
 		FROM orders
 		WHERE order_code = $1
 		FOR UPDATE
@@ -298,6 +541,7 @@ func (r *orderRepository) FindByOrderCodeForUpdate(orderCode string) (*models.Or
 		&order.Tax, &order.Discount, &order.TotalAmount, &order.Status, &order.StockReserved,
 		&order.Notes, &metadataJSON, &order.CreatedAt, &order.UpdatedAt,
 		&order.PaidAt, &order.ShippedAt, &order.CompletedAt, &order.CancelledAt,
+		&order.StoreCreditAmount,
 	)
 
 	if err != nil {
@@ -477,7 +721,7 @@ func (r *orderRepository) RestoreStock(orderID int) error {
 	}
 	defer tx.Rollback()
 
-	err = r.restoreStockInternal(tx, orderID)
+	err = r.restoreStockInternal(tx, orderID, models.StockMovementRelease, "Stock released on cancel/expire")
 	if err != nil {
 		return err
 	}
@@ -487,14 +731,144 @@ func (r *orderRepository) RestoreStock(orderID int) error {
 
 // RestoreStockTx restores stock within an existing transaction
 func (r *orderRepository) RestoreStockTx(tx *sql.Tx, orderID int) error {
-	return r.restoreStockInternal(tx, orderID)
+	return r.restoreStockInternal(tx, orderID, models.StockMovementRelease, "Stock released on cancel/expire")
+}
+
+// RestockRefundedOrder puts every item of a fully refunded order back on the shelf
+func (r *orderRepository) RestockRefundedOrder(orderID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = r.restoreStockInternal(tx, orderID, models.StockMovementRestock, "Refunded order restocked")
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *orderRepository) AllocateBackorders(orderID int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var awaiting bool
+	var warehouseID sql.NullInt64
+	var reference string
+	err = tx.QueryRow(`
+		SELECT COALESCE(awaiting_stock, false), warehouse_id, order_code FROM orders WHERE id = $1 FOR UPDATE
+	`, orderID).Scan(&awaiting, &warehouseID, &reference)
+	if err != nil {
+		return false, err
+	}
+	if !awaiting {
+		return false, nil
+	}
+
+	// Older orders waiting at the same warehouse are served first
+	rows, err := tx.Query(`
+		WITH default_warehouse AS (
+			SELECT id FROM warehouses WHERE is_default = true
+		)
+		SELECT oi.id, oi.variant_id, oi.backordered_quantity,
+		       COALESCE((
+		           SELECT ws.quantity FROM warehouse_stock ws
+		           WHERE ws.variant_id = oi.variant_id
+		           AND ws.warehouse_id = COALESCE(o.warehouse_id, (SELECT id FROM default_warehouse))
+		       ), 0) - COALESCE((
+		           SELECT SUM(older.backordered_quantity) FROM order_items older
+		           JOIN orders oo ON oo.id = older.order_id
+		           WHERE older.variant_id = oi.variant_id AND older.backordered_quantity > 0
+		           AND oo.id < o.id
+		           AND COALESCE(oo.warehouse_id, (SELECT id FROM default_warehouse)) =
+		               COALESCE(o.warehouse_id, (SELECT id FROM default_warehouse))
+		       ), 0)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.order_id = $1 AND oi.backordered_quantity > 0 AND oi.variant_id IS NOT NULL
+		ORDER BY oi.id
+		FOR UPDATE OF oi
+	`, orderID)
+	if err != nil {
+		return false, err
+	}
+
+	type line struct {
+		itemID, variantID, backordered, available int
+	}
+	var lines []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.itemID, &l.variantID, &l.backordered, &l.available); err != nil {
+			rows.Close()
+			return false, err
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	remaining := 0
+	taken := map[int]int{} // Per variant, for orders with the same variant on several lines
+	for _, l := range lines {
+		take := l.available - taken[l.variantID]
+		if take > l.backordered {
+			take = l.backordered
+		}
+		if take <= 0 {
+			remaining += l.backordered
+			continue
+		}
+
+		err := r.stock.ApplyMovementTx(tx, &models.StockLedgerEntry{
+			VariantID:    l.variantID,
+			WarehouseID:  nullIntPtr(warehouseID),
+			MovementType: models.StockMovementReserve,
+			Quantity:     -take,
+			OrderID:      &orderID,
+			Reference:    reference,
+			Notes:        "Backordered stock allocated",
+			Actor:        "system",
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to allocate stock for variant %d: %w", l.variantID, err)
+		}
+		_, err = tx.Exec(`
+			UPDATE order_items SET backordered_quantity = backordered_quantity - $2 WHERE id = $1
+		`, l.itemID, take)
+		if err != nil {
+			return false, err
+		}
+		taken[l.variantID] += take
+		remaining += l.backordered - take
+	}
+
+	if remaining == 0 {
+		if _, err := tx.Exec(`UPDATE orders SET awaiting_stock = false WHERE id = $1`, orderID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return remaining > 0, nil
 }
 
-func (r *orderRepository) restoreStockInternal(tx *sql.Tx, orderID int) error {
+func (r *orderRepository) restoreStockInternal(tx *sql.Tx, orderID int, movementType models.StockMovementType, notes string) error {
 	// Check if stock was already restored (no lock needed, already locked by parent)
 	var stockReserved bool
-	checkQuery := `SELECT COALESCE(stock_reserved, true) FROM orders WHERE id = $1`
-	err := tx.QueryRow(checkQuery, orderID).Scan(&stockReserved)
+	var warehouseID sql.NullInt64
+	var reference string
+	checkQuery := `SELECT COALESCE(stock_reserved, true), warehouse_id, order_code FROM orders WHERE id = $1`
+	err := tx.QueryRow(checkQuery, orderID).Scan(&stockReserved, &warehouseID, &reference)
 	if err != nil {
 		return err
 	}
@@ -504,9 +878,19 @@ func (r *orderRepository) restoreStockInternal(tx *sql.Tx, orderID int) error {
 		return nil
 	}
 
-	// Get order items
+	// Get order items; bundle lines are returned as their components. Variants deleted since
+	// come back as NULL and fall back to the product's default variant.
 	itemsQuery := `
-		SELECT product_id, quantity FROM order_items WHERE order_id = $1
+		SELECT oi.product_id, oi.variant_id, oi.quantity - COALESCE(oi.backordered_quantity, 0)
+		FROM order_items oi
+		WHERE oi.order_id = $1
+		AND NOT EXISTS (SELECT 1 FROM order_item_components c WHERE c.order_item_id = oi.id)
+		UNION ALL
+		SELECT c.product_id, pv.id, c.quantity * oi.quantity
+		FROM order_item_components c
+		JOIN order_items oi ON oi.id = c.order_item_id
+		LEFT JOIN product_variants pv ON pv.id = c.variant_id
+		WHERE oi.order_id = $1
 	`
 	rows, err := tx.Query(itemsQuery, orderID)
 	if err != nil {
@@ -516,17 +900,18 @@ func (r *orderRepository) restoreStockInternal(tx *sql.Tx, orderID int) error {
 	// Collect items first, then close rows before doing updates
 	type item struct {
 		productID int
+		variantID sql.NullInt64
 		quantity  int
 	}
 	var items []item
 	
 	for rows.Next() {
-		var productID, quantity int
-		if err := rows.Scan(&productID, &quantity); err != nil {
+		var itm item
+		if err := rows.Scan(&itm.productID, &itm.variantID, &itm.quantity); err != nil {
 			rows.Close()
 			return err
 		}
-		items = append(items, item{productID, quantity})
+		items = append(items, itm)
 	}
 	rows.Close() // Close rows before doing updates in same transaction
 
@@ -535,32 +920,39 @@ func (r *orderRepository) restoreStockInternal(tx *sql.Tx, orderID int) error {
 		return err
 	}
 
-	// Restore stock for each item and record movement
-	stockMovementQuery := `
-		INSERT INTO stock_movements (product_id, order_id, movement_type, quantity, balance_after, notes)
-		SELECT $1, $2, 'RELEASE', $3, stock, 'Stock released on cancel/expire'
-		FROM products WHERE id = $1
-	`
-
+	// Return each item to the warehouse it was taken from (the default one for unrouted orders).
+	// Backordered units were never taken.
 	for _, itm := range items {
-		restoreQuery := `
-			UPDATE products SET stock = stock + $1 WHERE id = $2
-		`
-		_, err = tx.Exec(restoreQuery, itm.quantity, itm.productID)
-		if err != nil {
+		if itm.quantity <= 0 {
+			continue
+		}
+		entry := &models.StockLedgerEntry{
+			MovementType: movementType,
+			Quantity:     itm.quantity,
+			WarehouseID:  nullIntPtr(warehouseID),
+			OrderID:      &orderID,
+			Reference:    reference,
+			Notes:        notes,
+			Actor:        "system",
+		}
+		if itm.variantID.Valid {
+			entry.VariantID = int(itm.variantID.Int64)
+		} else if entry.VariantID, err = r.stock.DefaultVariantTx(tx, itm.productID); err != nil {
 			return err
 		}
-
-		// Record stock movement for audit
-		_, err = tx.Exec(stockMovementQuery, itm.productID, orderID, itm.quantity)
-		if err != nil {
-			// Non-critical, log but don't fail
-			fmt.Printf("Warning: failed to record stock release movement for product %d: %v\n", itm.productID, err)
+		if err := r.stock.ApplyMovementTx(tx, entry); err != nil {
+			return fmt.Errorf("failed to restore stock for product %d: %w", itm.productID, err)
 		}
 	}
 
+	// The order no longer waits for stock either
+	_, err = tx.Exec(`UPDATE order_items SET backordered_quantity = 0 WHERE order_id = $1 AND backordered_quantity > 0`, orderID)
+	if err != nil {
+		return err
+	}
+
 	// Mark stock as restored
-	markQuery := `UPDATE orders SET stock_reserved = false WHERE id = $1`
+	markQuery := `UPDATE orders SET stock_reserved = false, awaiting_stock = false WHERE id = $1`
 	_, err = tx.Exec(markQuery, orderID)
 	return err
 }
@@ -579,6 +971,8 @@ func (r *orderRepository) findItemsByOrderID(orderID int) ([]models.OrderItem, e
 	query := `
 		SELECT oi.id, oi.order_id, oi.product_id, oi.product_name, oi.quantity,
 		       oi.price_per_unit, oi.subtotal, oi.metadata, oi.created_at,
+		       COALESCE(oi.backordered_quantity, 0), oi.expected_ship_date,
+		       COALESCE(oi.discount_amount, 0), COALESCE(oi.tax_rate, 0), COALESCE(oi.tax_amount, 0),
 		       COALESCE(
 		           (SELECT image_url FROM product_images WHERE product_id = oi.product_id ORDER BY is_primary DESC, display_order ASC LIMIT 1),
 		           ''
@@ -601,7 +995,9 @@ func (r *orderRepository) findItemsByOrderID(orderID int) ([]models.OrderItem, e
 		err := rows.Scan(
 			&item.ID, &item.OrderID, &item.ProductID, &item.ProductName,
 			&item.Quantity, &item.PricePerUnit, &item.Subtotal,
-			&metadataJSON, &item.CreatedAt, &item.ProductImage,
+			&metadataJSON, &item.CreatedAt,
+			&item.BackorderedQuantity, &item.ExpectedShipDate,
+			&item.DiscountAmount, &item.TaxRate, &item.TaxAmount, &item.ProductImage,
 		)
 		if err != nil {
 			continue
@@ -614,10 +1010,52 @@ func (r *orderRepository) findItemsByOrderID(orderID int) ([]models.OrderItem, e
 
 		items = append(items, item)
 	}
+	rows.Close()
+
+	if err := r.attachItemComponents(orderID, items); err != nil {
+		return nil, err
+	}
 
 	return items, nil
 }
 
+// attachItemComponents loads the components of the order's bundle lines
+func (r *orderRepository) attachItemComponents(orderID int, items []models.OrderItem) error {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.order_item_id, c.variant_id, c.product_id, c.product_name, c.variant_name,
+		       c.quantity, c.allocated_price
+		FROM order_item_components c
+		JOIN order_items oi ON oi.id = c.order_item_id
+		WHERE oi.order_id = $1
+		ORDER BY c.id
+	`, orderID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byItem := make(map[int][]models.OrderItemComponent)
+	for rows.Next() {
+		var c models.OrderItemComponent
+		err := rows.Scan(
+			&c.ID, &c.OrderItemID, &c.VariantID, &c.ProductID, &c.ProductName, &c.VariantName,
+			&c.Quantity, &c.AllocatedPrice,
+		)
+		if err != nil {
+			return err
+		}
+		byItem[c.OrderItemID] = append(byItem[c.OrderItemID], c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].Components = byItem[items[i].ID]
+	}
+	return nil
+}
+
 func (r *orderRepository) generateOrderCode() string {
 	// Generate a unique, payment-gateway-safe order code
 	// Format: ZVR-YYYYMMDD-XXXXXXXX (brand prefix + date + random hex)
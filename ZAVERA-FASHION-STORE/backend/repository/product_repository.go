@@ -2,17 +2,40 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
 	"zavera/models"
+
+	"github.com/lib/pq"
 )
 
 type ProductRepository interface {
 	FindAll() ([]models.Product, error)
 	FindByCategory(category string) ([]models.Product, error)
+	FindByCategoryIDs(categoryIDs []int) ([]models.Product, error)
+	FindByIDs(ids []int) ([]models.Product, error)
 	FindByID(id int) (*models.Product, error)
 	FindBySlug(slug string) (*models.Product, error)
+	FindSlugRedirect(oldSlug string) (string, error)
+	CatalogFingerprint() (string, error)
 	UpdateStock(productID int, quantity int) error
+	Search(filter models.ProductSearchFilter) ([]models.Product, int, error)
+	SearchFacets(filter models.ProductSearchFilter) (*models.ProductFacets, error)
+	ListPage(query models.ProductListQuery) ([]models.Product, string, error)
+	FindVariantSummaries(productIDs []int) (map[int]models.ProductVariantSummary, error)
+	FindRatingSummaries(productIDs []int) (map[int]models.ProductRatingSummary, error)
+	FindCollectionProducts(collection *models.Collection, limit, offset int) ([]models.Product, int, error)
 }
 
+// ErrInvalidProductCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort
+var ErrInvalidProductCursor = errors.New("invalid product cursor")
+
 type productRepository struct {
 	db *sql.DB
 }
@@ -23,13 +46,21 @@ func NewProductRepository(db *sql.DB) ProductRepository {
 
 func (r *productRepository) FindAll() ([]models.Product, error) {
 	query := `
-		SELECT p.id, p.name, p.slug, p.description, p.price, p.stock, 
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
 		       COALESCE(p.weight, 500) as weight,
 		       COALESCE(p.length, 30) as length,
 		       COALESCE(p.width, 20) as width,
 		       COALESCE(p.height, 5) as height,
 		       p.is_active, COALESCE(p.category, 'wanita') as category, 
-		       COALESCE(p.subcategory, '') as subcategory, p.created_at, p.updated_at
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       COALESCE(p.meta_title, '') as meta_title,
+		       COALESCE(p.meta_description, '') as meta_description,
+		       COALESCE(p.canonical_url, '') as canonical_url,
+		       COALESCE(p.og_image_url, '') as og_image_url,
+		       p.created_at, p.updated_at
 		FROM products p
 		WHERE p.is_active = true
 		ORDER BY p.created_at DESC
@@ -45,26 +76,27 @@ func (r *productRepository) FindAll() ([]models.Product, error) {
 	for rows.Next() {
 		var p models.Product
 		err := rows.Scan(
-			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price,
-			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.MetaTitle, &p.MetaDescription, &p.CanonicalURL, &p.OGImageURL,
+			&p.CreatedAt, &p.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		// Load images for product
-		images, _ := r.findImagesByProductID(p.ID)
-		p.Images = images
-
 		products = append(products, p)
 	}
 
+	// Load images for all products in one query
+	r.attachImages(products)
+
 	return products, nil
 }
 
 func (r *productRepository) FindByCategory(category string) ([]models.Product, error) {
 	query := `
-		SELECT p.id, p.name, p.slug, p.description, p.price, p.stock, 
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
 		       COALESCE(p.weight, 500) as weight,
 		       COALESCE(p.length, 30) as length,
 		       COALESCE(p.width, 20) as width,
@@ -86,40 +118,153 @@ func (r *productRepository) FindByCategory(category string) ([]models.Product, e
 	for rows.Next() {
 		var p models.Product
 		err := rows.Scan(
-			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price,
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
 			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CreatedAt, &p.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		// Load images for product
-		images, _ := r.findImagesByProductID(p.ID)
-		p.Images = images
+		products = append(products, p)
+	}
+
+	// Load images for all products in one query
+	r.attachImages(products)
+
+	return products, nil
+}
+
+// FindByCategoryIDs returns active products linked to any of the given categories
+func (r *productRepository) FindByCategoryIDs(categoryIDs []int) ([]models.Product, error) {
+	query := `
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
+		       COALESCE(p.weight, 500) as weight,
+		       COALESCE(p.length, 30) as length,
+		       COALESCE(p.width, 20) as width,
+		       COALESCE(p.height, 5) as height,
+		       p.is_active, COALESCE(p.category, 'wanita') as category, 
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       p.created_at, p.updated_at
+		FROM products p
+		WHERE p.is_active = true AND p.category_id = ANY($1)
+		ORDER BY p.created_at DESC
+	`
+
+	rows, err := r.db.Query(query, pq.Array(categoryIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
 
 		products = append(products, p)
 	}
 
+	r.attachImages(products)
+
+	return products, nil
+}
+
+// FindByIDs loads active products in the order of ids; unknown or inactive IDs are skipped
+func (r *productRepository) FindByIDs(ids []int) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+
+	query := `
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
+		       COALESCE(p.weight, 500) as weight,
+		       COALESCE(p.length, 30) as length,
+		       COALESCE(p.width, 20) as width,
+		       COALESCE(p.height, 5) as height,
+		       p.is_active, COALESCE(p.category, 'wanita') as category, 
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       p.created_at, p.updated_at
+		FROM unnest($1::int[]) WITH ORDINALITY AS ids(id, position)
+		JOIN products p ON p.id = ids.id
+		WHERE p.is_active = true
+		ORDER BY ids.position
+	`
+
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var p models.Product
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	r.attachImages(products)
+
 	return products, nil
 }
 
 func (r *productRepository) FindByID(id int) (*models.Product, error) {
 	query := `
-		SELECT id, name, slug, description, price, stock, 
+		SELECT id, name, slug, description, price, compare_at_price, stock, 
 		       COALESCE(weight, 500) as weight,
 		       COALESCE(length, 30) as length,
 		       COALESCE(width, 20) as width,
 		       COALESCE(height, 5) as height,
 		       is_active, COALESCE(category, 'wanita') as category, 
-		       COALESCE(subcategory, '') as subcategory, created_at, updated_at
+		       COALESCE(subcategory, '') as subcategory,
+		       category_id,
+		       COALESCE(brand, '') as brand,
+		       COALESCE(material, '') as material,
+		       COALESCE(meta_title, '') as meta_title,
+		       COALESCE(meta_description, '') as meta_description,
+		       COALESCE(canonical_url, '') as canonical_url,
+		       COALESCE(og_image_url, '') as og_image_url,
+		       created_at, updated_at,
+		       EXISTS (
+		           SELECT 1 FROM product_variants pv
+		           WHERE pv.product_id = products.id AND pv.is_active = true
+		           AND pv.inventory_policy <> 'STOCK'
+		       ) as allows_backorder,
+		       COALESCE(is_bundle, false), COALESCE(is_gift_card, false)
 		FROM products
 		WHERE id = $1
 	`
 
 	var p models.Product
 	err := r.db.QueryRow(query, id).Scan(
-		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price,
-		&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+		&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+		&p.Brand, &p.Material, &p.MetaTitle, &p.MetaDescription, &p.CanonicalURL, &p.OGImageURL,
+		&p.CreatedAt, &p.UpdatedAt, &p.AllowsBackorder, &p.IsBundle, &p.IsGiftCard,
 	)
 	if err != nil {
 		return nil, err
@@ -134,21 +279,31 @@ func (r *productRepository) FindByID(id int) (*models.Product, error) {
 
 func (r *productRepository) FindBySlug(slug string) (*models.Product, error) {
 	query := `
-		SELECT id, name, slug, description, price, stock, 
+		SELECT id, name, slug, description, price, compare_at_price, stock, 
 		       COALESCE(weight, 500) as weight,
 		       COALESCE(length, 30) as length,
 		       COALESCE(width, 20) as width,
 		       COALESCE(height, 5) as height,
 		       is_active, COALESCE(category, 'wanita') as category, 
-		       COALESCE(subcategory, '') as subcategory, created_at, updated_at
+		       COALESCE(subcategory, '') as subcategory,
+		       category_id,
+		       COALESCE(brand, '') as brand,
+		       COALESCE(material, '') as material,
+		       COALESCE(meta_title, '') as meta_title,
+		       COALESCE(meta_description, '') as meta_description,
+		       COALESCE(canonical_url, '') as canonical_url,
+		       COALESCE(og_image_url, '') as og_image_url,
+		       created_at, updated_at, COALESCE(is_bundle, false), COALESCE(is_gift_card, false)
 		FROM products
 		WHERE slug = $1
 	`
 
 	var p models.Product
 	err := r.db.QueryRow(query, slug).Scan(
-		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price,
-		&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+		&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+		&p.Brand, &p.Material, &p.MetaTitle, &p.MetaDescription, &p.CanonicalURL, &p.OGImageURL,
+		&p.CreatedAt, &p.UpdatedAt, &p.IsBundle, &p.IsGiftCard,
 	)
 	if err != nil {
 		return nil, err
@@ -161,6 +316,47 @@ func (r *productRepository) FindBySlug(slug string) (*models.Product, error) {
 	return &p, nil
 }
 
+// FindSlugRedirect resolves a retired slug to the product's current slug.
+// Returns sql.ErrNoRows when the slug was never used or the product is inactive.
+func (r *productRepository) FindSlugRedirect(oldSlug string) (string, error) {
+	query := `
+		SELECT p.slug
+		FROM product_slug_history h
+		JOIN products p ON p.id = h.product_id
+		WHERE h.old_slug = $1 AND p.is_active = true
+	`
+
+	var currentSlug string
+	err := r.db.QueryRow(query, oldSlug).Scan(&currentSlug)
+	if err != nil {
+		return "", err
+	}
+	return currentSlug, nil
+}
+
+// CatalogFingerprint summarizes the public catalog so cached feeds can tell when products,
+// images, variants or stock changed. Variant updates bump products.updated_at via trigger.
+func (r *productRepository) CatalogFingerprint() (string, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) || ':' || COALESCE(MAX(updated_at)::text, '') || ':' || COALESCE(SUM(stock), 0)
+			 FROM products WHERE is_active = true),
+			(SELECT COUNT(*) || ':' || COALESCE(MAX(id), 0) FROM product_images),
+			(SELECT COUNT(*) || ':' || COALESCE(SUM(stock_quantity - reserved_stock), 0)
+			 FROM product_variants WHERE is_active = true)
+	`
+
+	var products, images, variants string
+	if err := r.db.QueryRow(query).Scan(&products, &images, &variants); err != nil {
+		return "", err
+	}
+	return products + "|" + images + "|" + variants, nil
+}
+
+// UpdateStock shifts the product stock counter by quantity.
+//
+// Deprecated: products.stock mirrors the variants; the database records this write as a ledger
+// adjustment on the default variant. Use StockRepository.ApplyMovement.
 func (r *productRepository) UpdateStock(productID int, quantity int) error {
 	query := `
 		UPDATE products 
@@ -171,6 +367,471 @@ func (r *productRepository) UpdateStock(productID int, quantity int) error {
 	return err
 }
 
+// productSortSpec describes how a catalog sort maps to SQL.
+// key is the sort expression; join adds any aggregate it needs.
+type productSortSpec struct {
+	sort    models.ProductSort
+	key     string
+	keyType string // Postgres type the cursor key is cast back to
+	join    string
+	desc    bool
+}
+
+const (
+	bestSellingJoin = `LEFT JOIN (
+			SELECT oi.product_id, SUM(oi.quantity) AS sold
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ('PAID', 'PACKING', 'SHIPPED', 'DELIVERED', 'COMPLETED')
+			GROUP BY oi.product_id
+		) sales ON sales.product_id = p.id`
+	mostWishlistedJoin = `LEFT JOIN (
+			SELECT product_id, COUNT(*) AS wishlisted
+			FROM wishlists
+			GROUP BY product_id
+		) wl ON wl.product_id = p.id`
+)
+
+func productSortSpecFor(sort models.ProductSort) productSortSpec {
+	switch sort {
+	case models.ProductSortPriceAsc:
+		return productSortSpec{sort: sort, key: "p.price", keyType: "numeric"}
+	case models.ProductSortPriceDesc:
+		return productSortSpec{sort: sort, key: "p.price", keyType: "numeric", desc: true}
+	case models.ProductSortBestSelling:
+		return productSortSpec{sort: sort, key: "COALESCE(sales.sold, 0)::numeric", keyType: "numeric", join: bestSellingJoin, desc: true}
+	case models.ProductSortMostWishlisted:
+		return productSortSpec{sort: sort, key: "COALESCE(wl.wishlisted, 0)::numeric", keyType: "numeric", join: mostWishlistedJoin, desc: true}
+	default:
+		return productSortSpec{sort: models.ProductSortNewest, key: "p.created_at", keyType: "timestamp", desc: true}
+	}
+}
+
+func (s productSortSpec) direction() string {
+	if s.desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// orderBy always breaks ties on id so the ordering is total
+func (s productSortSpec) orderBy() string {
+	return fmt.Sprintf("%s %s, p.id %s", s.key, s.direction(), s.direction())
+}
+
+// productCursor is the decoded form of next_cursor: the last row's sort key and id
+type productCursor struct {
+	Sort models.ProductSort `json:"s"`
+	Key  string             `json:"k"`
+	ID   int                `json:"id"`
+}
+
+func encodeProductCursor(c productCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(raw string, sort models.ProductSort) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidProductCursor
+	}
+	var c productCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || c.Key == "" || c.Sort != sort {
+		return nil, ErrInvalidProductCursor
+	}
+	return &c, nil
+}
+
+// ListPage returns one keyset-paginated page of active products and the cursor for the next page.
+// The next cursor is empty on the last page.
+func (r *productRepository) ListPage(q models.ProductListQuery) ([]models.Product, string, error) {
+	spec := productSortSpecFor(q.Sort)
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	whereConditions := []string{"p.is_active = true"}
+	args := []interface{}{}
+	argCount := 0
+
+	if len(q.CategoryIDs) > 0 {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("p.category_id = ANY($%d)", argCount))
+		args = append(args, pq.Array(q.CategoryIDs))
+	} else if q.Category != "" {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("LOWER(p.category) = LOWER($%d)", argCount))
+		args = append(args, q.Category)
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeProductCursor(q.Cursor, spec.sort)
+		if err != nil {
+			return nil, "", err
+		}
+		op := ">"
+		if spec.desc {
+			op = "<"
+		}
+		whereConditions = append(whereConditions, fmt.Sprintf(
+			"(%s, p.id) %s ($%d::%s, $%d)", spec.key, op, argCount+1, spec.keyType, argCount+2,
+		))
+		args = append(args, cursor.Key, cursor.ID)
+		argCount += 2
+	}
+
+	// Fetch one extra row to know whether another page exists
+	argCount++
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
+		       COALESCE(p.weight, 500) as weight,
+		       COALESCE(p.length, 30) as length,
+		       COALESCE(p.width, 20) as width,
+		       COALESCE(p.height, 5) as height,
+		       p.is_active, COALESCE(p.category, 'wanita') as category, 
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       p.created_at, p.updated_at,
+		       (%s)::text as sort_key
+		FROM products p
+		%s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, spec.key, spec.join, strings.Join(whereConditions, " AND "), spec.orderBy(), argCount)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	var sortKeys []string
+	for rows.Next() {
+		var p models.Product
+		var sortKey string
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.CreatedAt, &p.UpdatedAt, &sortKey,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		products = append(products, p)
+		sortKeys = append(sortKeys, sortKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(products) > limit {
+		products = products[:limit]
+		last := products[limit-1]
+		nextCursor = encodeProductCursor(productCursor{Sort: spec.sort, Key: sortKeys[limit-1], ID: last.ID})
+	}
+
+	r.attachImages(products)
+
+	return products, nextCursor, nil
+}
+
+// FindVariantSummaries loads price range and in-stock sizes of active variants for many products in one query.
+// Products without active variants are absent from the map.
+func (r *productRepository) FindVariantSummaries(productIDs []int) (map[int]models.ProductVariantSummary, error) {
+	summaries := make(map[int]models.ProductVariantSummary)
+	if len(productIDs) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT v.product_id,
+		       MIN(COALESCE(v.price, p.price)) as min_price,
+		       MAX(COALESCE(v.price, p.price)) as max_price,
+		       COALESCE(array_agg(DISTINCT v.size) FILTER (
+		           WHERE v.size IS NOT NULL AND v.size <> '' AND v.stock_quantity > 0
+		       ), '{}') as sizes
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.product_id = ANY($1) AND v.is_active = true
+		GROUP BY v.product_id
+	`
+
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.ProductVariantSummary
+		var sizes pq.StringArray
+		if err := rows.Scan(&s.ProductID, &s.MinPrice, &s.MaxPrice, &sizes); err != nil {
+			return nil, err
+		}
+		s.AvailableSizes = []string(sizes)
+		summaries[s.ProductID] = s
+	}
+
+	return summaries, rows.Err()
+}
+
+// FindRatingSummaries aggregates approved reviews per product; products without reviews are absent
+func (r *productRepository) FindRatingSummaries(productIDs []int) (map[int]models.ProductRatingSummary, error) {
+	summaries := make(map[int]models.ProductRatingSummary)
+	if len(productIDs) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT product_id,
+		       COUNT(*),
+		       COALESCE(AVG(rating), 0),
+		       COUNT(*) FILTER (WHERE rating = 1),
+		       COUNT(*) FILTER (WHERE rating = 2),
+		       COUNT(*) FILTER (WHERE rating = 3),
+		       COUNT(*) FILTER (WHERE rating = 4),
+		       COUNT(*) FILTER (WHERE rating = 5),
+		       COUNT(*) FILTER (WHERE fit = 'SMALL'),
+		       COUNT(*) FILTER (WHERE fit = 'TRUE'),
+		       COUNT(*) FILTER (WHERE fit = 'LARGE')
+		FROM product_reviews
+		WHERE product_id = ANY($1) AND status = 'APPROVED'
+		GROUP BY product_id
+	`
+
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.ProductRatingSummary
+		err := rows.Scan(
+			&s.ProductID, &s.Count, &s.Average,
+			&s.Distribution[0], &s.Distribution[1], &s.Distribution[2], &s.Distribution[3], &s.Distribution[4],
+			&s.FitSmall, &s.FitTrue, &s.FitLarge,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summaries[s.ProductID] = s
+	}
+
+	return summaries, rows.Err()
+}
+
+// FindCollectionProducts returns active products of a collection: manual collections in their
+// curated order, rule-based collections evaluated against the live catalog
+func (r *productRepository) FindCollectionProducts(collection *models.Collection, limit, offset int) ([]models.Product, int, error) {
+	var from, where, orderBy string
+	var args []interface{}
+
+	if collection.Type == models.CollectionTypeManual {
+		from = "products p JOIN collection_products cp ON cp.product_id = p.id"
+		where = "p.is_active = true AND cp.collection_id = $1"
+		orderBy = "cp.position ASC, p.id ASC"
+		args = []interface{}{collection.ID}
+	} else {
+		ruleWhere, ruleArgs := buildCollectionRuleWhere(collection.Rules, collection.MatchType == models.CollectionMatchAll)
+		spec := productSortSpecFor(collection.SortBy)
+		from = "products p " + spec.join
+		where = "p.is_active = true AND " + ruleWhere
+		orderBy = spec.orderBy()
+		args = ruleArgs
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", from, where)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
+		       COALESCE(p.weight, 500) as weight,
+		       COALESCE(p.length, 30) as length,
+		       COALESCE(p.width, 20) as width,
+		       COALESCE(p.height, 5) as height,
+		       p.is_active, COALESCE(p.category, 'wanita') as category, 
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       p.created_at, p.updated_at
+		FROM %s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, from, where, orderBy, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	r.attachImages(products)
+
+	return products, total, nil
+}
+
+// buildCollectionRuleWhere translates collection rules to SQL, joined with AND (matchAll) or OR.
+// Rules are validated on save; anything unrecognised here matches nothing rather than everything.
+func buildCollectionRuleWhere(rules models.CollectionRules, matchAll bool) (string, []interface{}) {
+	if len(rules) == 0 {
+		return "false", nil
+	}
+
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	comparison := map[string]string{"lt": "<", "lte": "<=", "gt": ">", "gte": ">="}
+
+	var conditions []string
+	for _, rule := range rules {
+		condition := "false"
+
+		switch rule.Field {
+		case models.CollectionFieldCategory:
+			// Category paths include their subcategories; legacy root strings still match
+			paths := lowerAll(rule.StringValues())
+			if len(paths) > 0 {
+				ph := arg(pq.Array(paths))
+				condition = fmt.Sprintf(
+					"(EXISTS (SELECT 1 FROM categories c, unnest(%s::text[]) AS want(path) WHERE c.id = p.category_id AND (c.path = want.path OR c.path LIKE want.path || '/%%')) OR LOWER(p.category) = ANY(%s))",
+					ph, ph,
+				)
+			}
+		case models.CollectionFieldBrand:
+			brands := lowerAll(rule.StringValues())
+			if len(brands) > 0 {
+				op := "= ANY"
+				if rule.Operator == "neq" {
+					op = "<> ALL"
+				}
+				condition = fmt.Sprintf("LOWER(COALESCE(p.brand, '')) %s(%s)", op, arg(pq.Array(brands)))
+			}
+		case models.CollectionFieldPrice:
+			if n, ok := rule.NumberValue(); ok {
+				if op, ok := comparison[rule.Operator]; ok {
+					condition = fmt.Sprintf("p.price %s %s", op, arg(n))
+				}
+			}
+		case models.CollectionFieldTag:
+			tags := lowerAll(rule.StringValues())
+			if len(tags) > 0 {
+				condition = fmt.Sprintf("p.tags && %s::text[]", arg(pq.Array(tags)))
+			}
+		case models.CollectionFieldCreatedAt:
+			switch rule.Operator {
+			case "within_days":
+				if n, ok := rule.NumberValue(); ok {
+					condition = fmt.Sprintf("p.created_at >= NOW() - make_interval(days => %s)", arg(int(n)))
+				}
+			case "after", "before":
+				if t, ok := rule.TimeValue(); ok {
+					op := ">="
+					if rule.Operator == "before" {
+						op = "<"
+					}
+					condition = fmt.Sprintf("p.created_at %s %s", op, arg(t))
+				}
+			}
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	joiner := " OR "
+	if matchAll {
+		joiner = " AND "
+	}
+	return "(" + strings.Join(conditions, joiner) + ")", args
+}
+
+// attachImages batch-loads images for a page of products.
+// Image load failures are non-fatal, matching the single-product lookups.
+func (r *productRepository) attachImages(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	images, err := r.findImagesByProductIDs(ids)
+	if err != nil {
+		return
+	}
+	for i := range products {
+		products[i].Images = images[products[i].ID]
+	}
+}
+
+func (r *productRepository) findImagesByProductIDs(productIDs []int) (map[int][]models.ProductImage, error) {
+	query := `
+		SELECT id, product_id, image_url, is_primary, display_order, created_at
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, display_order ASC
+	`
+
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make(map[int][]models.ProductImage)
+	for rows.Next() {
+		var img models.ProductImage
+		err := rows.Scan(
+			&img.ID, &img.ProductID, &img.ImageURL,
+			&img.IsPrimary, &img.DisplayOrder, &img.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		images[img.ProductID] = append(images[img.ProductID], img)
+	}
+
+	return images, rows.Err()
+}
+
 func (r *productRepository) findImagesByProductID(productID int) ([]models.ProductImage, error) {
 	query := `
 		SELECT id, product_id, image_url, is_primary, display_order, created_at
@@ -200,3 +861,292 @@ func (r *productRepository) findImagesByProductID(productID int) ([]models.Produ
 
 	return images, nil
 }
+
+// Search returns active products matching the filter.
+// Results are ranked by text relevance when a query is given, newest first otherwise.
+func (r *productRepository) Search(filter models.ProductSearchFilter) ([]models.Product, int, error) {
+	whereClause, args := buildProductSearchWhere(filter, "")
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products p WHERE %s", whereClause)
+	var total int
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := productSortSpecFor(filter.Sort).orderBy()
+	if tsQuery := buildPrefixTSQuery(filter.Query); tsQuery != "" {
+		args = append(args, tsQuery, strings.TrimSpace(filter.Query))
+		orderBy = fmt.Sprintf(
+			"ts_rank(p.search_vector, to_tsquery('simple', $%d)) DESC, similarity(p.name, $%d) DESC, p.created_at DESC, p.id DESC",
+			len(args)-1, len(args),
+		)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_at_price, p.stock, 
+		       COALESCE(p.weight, 500) as weight,
+		       COALESCE(p.length, 30) as length,
+		       COALESCE(p.width, 20) as width,
+		       COALESCE(p.height, 5) as height,
+		       p.is_active, COALESCE(p.category, 'wanita') as category, 
+		       COALESCE(p.subcategory, '') as subcategory,
+		       p.category_id,
+		       COALESCE(p.brand, '') as brand,
+		       COALESCE(p.material, '') as material,
+		       p.created_at, p.updated_at
+		FROM products p
+		%s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, productSortSpecFor(filter.Sort).join, whereClause, orderBy, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice,
+			&p.Stock, &p.Weight, &p.Length, &p.Width, &p.Height, &p.IsActive, &p.Category, &p.Subcategory, &p.CategoryID,
+			&p.Brand, &p.Material, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		products = append(products, p)
+	}
+
+	r.attachImages(products)
+
+	return products, total, nil
+}
+
+// SearchFacets returns brand, size, color and price bucket counts for the filter.
+// Each facet ignores its own filter so the sidebar keeps showing the alternatives.
+func (r *productRepository) SearchFacets(filter models.ProductSearchFilter) (*models.ProductFacets, error) {
+	facets := &models.ProductFacets{
+		Brands:       []models.FacetCount{},
+		Sizes:        []models.FacetCount{},
+		Colors:       []models.FacetCount{},
+		PriceBuckets: models.DefaultPriceBuckets(),
+	}
+
+	// Brand facet
+	whereClause, args := buildProductSearchWhere(filter, "brand")
+	brandQuery := fmt.Sprintf(`
+		SELECT p.brand, COUNT(*)
+		FROM products p
+		WHERE %s AND COALESCE(p.brand, '') <> ''
+		GROUP BY p.brand
+		ORDER BY COUNT(*) DESC, p.brand ASC
+		LIMIT 50
+	`, whereClause)
+	brands, err := r.queryFacetCounts(brandQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	facets.Brands = brands
+
+	// Size and color facets count products having an active variant with that value
+	for _, field := range []string{"size", "color"} {
+		whereClause, args := buildProductSearchWhere(filter, field)
+		variantQuery := fmt.Sprintf(`
+			SELECT v.%s, COUNT(DISTINCT p.id)
+			FROM products p
+			JOIN product_variants v ON v.product_id = p.id AND v.is_active = true
+			WHERE %s AND COALESCE(v.%s, '') <> ''
+			GROUP BY v.%s
+			ORDER BY COUNT(DISTINCT p.id) DESC, v.%s ASC
+		`, field, whereClause, field, field, field)
+		counts, err := r.queryFacetCounts(variantQuery, args...)
+		if err != nil {
+			return nil, err
+		}
+		if field == "size" {
+			sortSizeFacets(counts)
+			facets.Sizes = counts
+		} else {
+			facets.Colors = counts
+		}
+	}
+
+	// Price buckets in a single pass
+	whereClause, args = buildProductSearchWhere(filter, "price")
+	var selects []string
+	for _, bucket := range facets.PriceBuckets {
+		args = append(args, bucket.Min)
+		cond := fmt.Sprintf("p.price >= $%d", len(args))
+		if bucket.Max != nil {
+			args = append(args, *bucket.Max)
+			cond += fmt.Sprintf(" AND p.price < $%d", len(args))
+		}
+		selects = append(selects, fmt.Sprintf("COUNT(*) FILTER (WHERE %s)", cond))
+	}
+	priceQuery := fmt.Sprintf("SELECT %s FROM products p WHERE %s", strings.Join(selects, ", "), whereClause)
+
+	counts := make([]interface{}, len(facets.PriceBuckets))
+	for i := range facets.PriceBuckets {
+		counts[i] = &facets.PriceBuckets[i].Count
+	}
+	if err := r.db.QueryRow(priceQuery, args...).Scan(counts...); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+func (r *productRepository) queryFacetCounts(query string, args ...interface{}) ([]models.FacetCount, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var fc models.FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, fc)
+	}
+	return counts, rows.Err()
+}
+
+// buildProductSearchWhere builds the WHERE clause for a search filter.
+// exclude names one facet ("brand", "size", "color", "price") whose filter is skipped.
+func buildProductSearchWhere(filter models.ProductSearchFilter, exclude string) (string, []interface{}) {
+	conditions := []string{"p.is_active = true"}
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		if tsQuery := buildPrefixTSQuery(q); tsQuery != "" {
+			// Full-text match on name/brand/material/description, trigram fallback for typos
+			conditions = append(conditions, fmt.Sprintf(
+				"(p.search_vector @@ to_tsquery('simple', %s) OR p.name %% %s OR p.brand %% %s)",
+				arg(tsQuery), arg(q), arg(q),
+			))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("p.name ILIKE %s", arg("%"+q+"%")))
+		}
+	}
+
+	if len(filter.CategoryIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("p.category_id = ANY(%s)", arg(pq.Array(filter.CategoryIDs))))
+	} else if filter.Category != "" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(p.category) = LOWER(%s)", arg(filter.Category)))
+	}
+	if filter.Subcategory != "" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(p.subcategory) = LOWER(%s)", arg(filter.Subcategory)))
+	}
+	if len(filter.Brands) > 0 && exclude != "brand" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(p.brand) = ANY(%s)", arg(pq.Array(lowerAll(filter.Brands)))))
+	}
+	if exclude != "price" {
+		if filter.MinPrice != nil {
+			conditions = append(conditions, fmt.Sprintf("p.price >= %s", arg(*filter.MinPrice)))
+		}
+		if filter.MaxPrice != nil {
+			conditions = append(conditions, fmt.Sprintf("p.price <= %s", arg(*filter.MaxPrice)))
+		}
+	}
+
+	// Size, color and stock are matched on a single variant so "M in Black" means one sellable SKU
+	var variantConditions []string
+	if len(filter.Sizes) > 0 && exclude != "size" {
+		variantConditions = append(variantConditions, fmt.Sprintf("UPPER(v.size) = ANY(%s)", arg(pq.Array(upperAll(filter.Sizes)))))
+	}
+	if len(filter.Colors) > 0 && exclude != "color" {
+		variantConditions = append(variantConditions, fmt.Sprintf("LOWER(v.color) = ANY(%s)", arg(pq.Array(lowerAll(filter.Colors)))))
+	}
+
+	const inStockVariant = "v.stock_quantity - v.reserved_stock > 0"
+	if len(variantConditions) > 0 {
+		if filter.InStock {
+			variantConditions = append(variantConditions, inStockVariant)
+		}
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.is_active = true AND %s)",
+			strings.Join(variantConditions, " AND "),
+		))
+	} else if filter.InStock {
+		conditions = append(conditions, fmt.Sprintf(
+			"(p.stock > 0 OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.is_active = true AND %s))",
+			inStockVariant,
+		))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// buildPrefixTSQuery turns free text into a prefix tsquery ("kemeja lin" -> "kemeja:* & lin:*")
+// so results update while the customer is still typing
+func buildPrefixTSQuery(input string) string {
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	const maxTerms = 8
+	if len(words) > maxTerms {
+		words = words[:maxTerms]
+	}
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// sortSizeFacets orders apparel sizes XS..XXXL first, then other values (e.g. shoe sizes) naturally
+func sortSizeFacets(counts []models.FacetCount) {
+	rank := map[string]int{"XS": 1, "S": 2, "M": 3, "L": 4, "XL": 5, "XXL": 6, "XXXL": 7}
+	sort.SliceStable(counts, func(i, j int) bool {
+		ri, iok := rank[strings.ToUpper(counts[i].Value)]
+		rj, jok := rank[strings.ToUpper(counts[j].Value)]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		default:
+			return counts[i].Value < counts[j].Value
+		}
+	})
+}
+
+func lowerAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return out
+}
+
+func upperAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	return out
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"zavera/models"
+)
+
+var (
+	ErrProductScheduleNotFound = errors.New("product schedule not found")
+	ErrProductScheduleChanged  = errors.New("product schedule is no longer in the expected status")
+)
+
+type ProductScheduleRepository interface {
+	Create(schedule *models.ProductSchedule) error
+	FindByID(id int) (*models.ProductSchedule, error)
+	List(filter models.ProductScheduleFilter) ([]models.ProductSchedule, int, error)
+	HasPriceConflict(productID int, start time.Time, end *time.Time) (bool, error)
+	Cancel(id int) error
+	EndSaleAt(id int, at time.Time) error
+	FindDueIDs(now time.Time, limit int) ([]int, error)
+
+	// Used by ProductScheduleJob inside one transaction per entry
+	LockForUpdateTx(tx *sql.Tx, id int) (*models.ProductSchedule, error)
+	LockProductStateTx(tx *sql.Tx, productID int) (*models.ProductPricingState, string, error)
+	UpdateProductStateTx(tx *sql.Tx, productID int, state models.ProductPricingState) error
+	SaveProgressTx(tx *sql.Tx, schedule *models.ProductSchedule) error
+}
+
+type productScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewProductScheduleRepository(db *sql.DB) ProductScheduleRepository {
+	return &productScheduleRepository{db: db}
+}
+
+const productScheduleColumns = `s.id, s.product_id, s.action, s.run_at, s.ends_at, s.new_price, s.status,
+	s.previous_price, s.previous_compare_at_price, COALESCE(s.note, ''), COALESCE(s.result_note, ''),
+	COALESCE(s.created_by, ''), s.applied_at, s.completed_at, s.created_at, s.updated_at,
+	p.name, p.slug`
+
+func scanProductSchedule(scanner interface{ Scan(...interface{}) error }) (*models.ProductSchedule, error) {
+	var s models.ProductSchedule
+	var endsAt, appliedAt, completedAt sql.NullTime
+	var newPrice, previousPrice, previousCompareAt sql.NullFloat64
+
+	err := scanner.Scan(
+		&s.ID, &s.ProductID, &s.Action, &s.RunAt, &endsAt, &newPrice, &s.Status,
+		&previousPrice, &previousCompareAt, &s.Note, &s.ResultNote,
+		&s.CreatedBy, &appliedAt, &completedAt, &s.CreatedAt, &s.UpdatedAt,
+		&s.ProductName, &s.ProductSlug,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.EndsAt = nullTimePtr(endsAt)
+	s.AppliedAt = nullTimePtr(appliedAt)
+	s.CompletedAt = nullTimePtr(completedAt)
+	s.NewPrice = nullFloatPtr(newPrice)
+	s.PreviousPrice = nullFloatPtr(previousPrice)
+	s.PreviousCompareAtPrice = nullFloatPtr(previousCompareAt)
+	return &s, nil
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+func (r *productScheduleRepository) Create(s *models.ProductSchedule) error {
+	query := `
+		INSERT INTO product_schedules (product_id, action, run_at, ends_at, new_price, status, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(query,
+		s.ProductID, s.Action, s.RunAt, s.EndsAt, s.NewPrice, s.Status, s.Note, s.CreatedBy,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+func (r *productScheduleRepository) FindByID(id int) (*models.ProductSchedule, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM product_schedules s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.id = $1
+	`, productScheduleColumns)
+
+	s, err := scanProductSchedule(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrProductScheduleNotFound
+	}
+	return s, err
+}
+
+// List returns entries newest run time first
+func (r *productScheduleRepository) List(filter models.ProductScheduleFilter) ([]models.ProductSchedule, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.ProductID > 0 {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("s.product_id = $%d", argCount))
+		args = append(args, filter.ProductID)
+	}
+	if filter.Status != "" {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("s.status = $%d", argCount))
+		args = append(args, filter.Status)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM product_schedules s %s", whereClause)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM product_schedules s
+		JOIN products p ON p.id = s.product_id
+		%s
+		ORDER BY s.run_at DESC, s.id DESC
+		LIMIT $%d OFFSET $%d
+	`, productScheduleColumns, whereClause, argCount+1, argCount+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	schedules := []models.ProductSchedule{}
+	for rows.Next() {
+		s, err := scanProductSchedule(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, total, rows.Err()
+}
+
+// HasPriceConflict reports whether a pending or running sale overlaps the given period.
+// A nil end means a single instant (a permanent price change).
+func (r *productScheduleRepository) HasPriceConflict(productID int, start time.Time, end *time.Time) (bool, error) {
+	rangeEnd := start
+	if end != nil {
+		rangeEnd = *end
+	}
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM product_schedules
+			WHERE product_id = $1
+			  AND action = 'SALE'
+			  AND status IN ('PENDING', 'ACTIVE')
+			  AND run_at <= $3 AND ends_at > $2
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, productID, start, rangeEnd).Scan(&exists)
+	return exists, err
+}
+
+// Cancel withdraws an entry that has not run yet
+func (r *productScheduleRepository) Cancel(id int) error {
+	result, err := r.db.Exec(`
+		UPDATE product_schedules
+		SET status = 'CANCELLED', completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'
+	`, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrProductScheduleChanged
+	}
+	return nil
+}
+
+// EndSaleAt moves the end of a running sale; the job reverts it once the time has passed
+func (r *productScheduleRepository) EndSaleAt(id int, at time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE product_schedules
+		SET ends_at = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'ACTIVE'
+	`, id, at)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrProductScheduleChanged
+	}
+	return nil
+}
+
+// FindDueIDs lists pending entries whose run time has passed and sales whose end has passed,
+// oldest first so changes to the same product apply in order
+func (r *productScheduleRepository) FindDueIDs(now time.Time, limit int) ([]int, error) {
+	query := `
+		SELECT id FROM (
+			SELECT id, run_at AS due_at FROM product_schedules WHERE status = 'PENDING' AND run_at <= $1
+			UNION ALL
+			SELECT id, ends_at AS due_at FROM product_schedules WHERE status = 'ACTIVE' AND ends_at <= $1
+		) due
+		ORDER BY due_at, id
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *productScheduleRepository) LockForUpdateTx(tx *sql.Tx, id int) (*models.ProductSchedule, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM product_schedules s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.id = $1
+		FOR UPDATE OF s SKIP LOCKED
+	`, productScheduleColumns)
+
+	s, err := scanProductSchedule(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		// Deleted, or being applied by another instance
+		return nil, ErrProductScheduleNotFound
+	}
+	return s, err
+}
+
+// LockProductStateTx reads the schedulable fields of a product and locks its row
+func (r *productScheduleRepository) LockProductStateTx(tx *sql.Tx, productID int) (*models.ProductPricingState, string, error) {
+	var state models.ProductPricingState
+	var compareAt sql.NullFloat64
+	var slug string
+
+	err := tx.QueryRow(
+		"SELECT price, compare_at_price, is_active, slug FROM products WHERE id = $1 FOR UPDATE", productID,
+	).Scan(&state.Price, &compareAt, &state.IsActive, &slug)
+	if err != nil {
+		return nil, "", err
+	}
+	state.CompareAtPrice = nullFloatPtr(compareAt)
+	return &state, slug, nil
+}
+
+func (r *productScheduleRepository) UpdateProductStateTx(tx *sql.Tx, productID int, state models.ProductPricingState) error {
+	_, err := tx.Exec(`
+		UPDATE products
+		SET price = $2, compare_at_price = $3, is_active = $4, updated_at = NOW()
+		WHERE id = $1
+	`, productID, state.Price, state.CompareAtPrice, state.IsActive)
+	return err
+}
+
+// SaveProgressTx stores the status and captured prices after the job handled an entry
+func (r *productScheduleRepository) SaveProgressTx(tx *sql.Tx, s *models.ProductSchedule) error {
+	_, err := tx.Exec(`
+		UPDATE product_schedules
+		SET status = $2, previous_price = $3, previous_compare_at_price = $4,
+		    result_note = NULLIF($5, ''), applied_at = $6, completed_at = $7, updated_at = NOW()
+		WHERE id = $1
+	`, s.ID, s.Status, s.PreviousPrice, s.PreviousCompareAtPrice, s.ResultNote, s.AppliedAt, s.CompletedAt)
+	return err
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"zavera/models"
+)
+
+func TestBuildPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"Kemeja", "kemeja:*"},
+		{"kemeja  lin", "kemeja:* & lin:*"},
+		{"t-shirt", "t:* & shirt:*"},
+		{"kaos & (polos)!", "kaos:* & polos:*"},
+		{"a b c d e f g h i j", "a:* & b:* & c:* & d:* & e:* & f:* & g:* & h:*"},
+	}
+
+	for _, tt := range tests {
+		if got := buildPrefixTSQuery(tt.input); got != tt.expected {
+			t.Errorf("buildPrefixTSQuery(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestBuildProductSearchWhere_ExcludesOwnFacet(t *testing.T) {
+	minPrice := 100000.0
+	filter := models.ProductSearchFilter{
+		Query:    "kemeja",
+		Brands:   []string{"Nike"},
+		Sizes:    []string{"m"},
+		MinPrice: &minPrice,
+	}
+
+	where, args := buildProductSearchWhere(filter, "")
+	for _, fragment := range []string{"search_vector", "p.brand) = ANY", "UPPER(v.size)", "p.price >="} {
+		if !strings.Contains(where, fragment) {
+			t.Errorf("expected WHERE to contain %q, got %s", fragment, where)
+		}
+	}
+	if strings.Count(where, "$") != len(args) {
+		t.Errorf("placeholder count %d does not match args %d", strings.Count(where, "$"), len(args))
+	}
+
+	where, _ = buildProductSearchWhere(filter, "brand")
+	if strings.Contains(where, "p.brand) = ANY") {
+		t.Errorf("brand facet should not filter by brand: %s", where)
+	}
+
+	where, _ = buildProductSearchWhere(filter, "size")
+	if strings.Contains(where, "v.size") {
+		t.Errorf("size facet should not filter by size: %s", where)
+	}
+}
+
+func TestSortSizeFacets(t *testing.T) {
+	counts := []models.FacetCount{{Value: "XL"}, {Value: "42"}, {Value: "S"}, {Value: "M"}, {Value: "40"}}
+	sortSizeFacets(counts)
+
+	var got []string
+	for _, c := range counts {
+		got = append(got, c.Value)
+	}
+	if strings.Join(got, ",") != "S,M,XL,40,42" {
+		t.Errorf("unexpected size order: %v", got)
+	}
+}
+
+func TestProductCursor_RoundTrip(t *testing.T) {
+	original := productCursor{Sort: models.ProductSortPriceAsc, Key: "149000.00", ID: 42}
+	encoded := encodeProductCursor(original)
+
+	decoded, err := decodeProductCursor(encoded, models.ProductSortPriceAsc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *decoded != original {
+		t.Errorf("decoded %+v, expected %+v", *decoded, original)
+	}
+
+	// A cursor from one sort must not be reused with another
+	if _, err := decodeProductCursor(encoded, models.ProductSortNewest); err != ErrInvalidProductCursor {
+		t.Errorf("expected ErrInvalidProductCursor for mismatched sort, got %v", err)
+	}
+
+	for _, raw := range []string{"not-base64!", encodeProductCursor(productCursor{Sort: models.ProductSortNewest})} {
+		if _, err := decodeProductCursor(raw, models.ProductSortNewest); err != ErrInvalidProductCursor {
+			t.Errorf("expected ErrInvalidProductCursor for %q, got %v", raw, err)
+		}
+	}
+}
+
+func TestProductSortSpec_OrderBy(t *testing.T) {
+	tests := []struct {
+		sort     models.ProductSort
+		expected string
+	}{
+		{"", "p.created_at DESC, p.id DESC"},
+		{models.ProductSortPriceAsc, "p.price ASC, p.id ASC"},
+		{models.ProductSortPriceDesc, "p.price DESC, p.id DESC"},
+		{models.ProductSortBestSelling, "COALESCE(sales.sold, 0)::numeric DESC, p.id DESC"},
+	}
+
+	for _, tt := range tests {
+		if got := productSortSpecFor(tt.sort).orderBy(); got != tt.expected {
+			t.Errorf("orderBy(%q) = %q, expected %q", tt.sort, got, tt.expected)
+		}
+	}
+}
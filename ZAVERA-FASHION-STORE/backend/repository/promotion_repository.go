@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrPromotionNotFound = errors.New("promotion not found")
+
+// PromotionRepository stores automatic cart promotions and their flash-sale prices
+type PromotionRepository interface {
+	List() ([]models.Promotion, error)
+	// ListRunning returns the active promotions whose window includes now
+	ListRunning() ([]models.Promotion, error)
+	FindByID(id int) (*models.Promotion, error)
+	Create(promotion *models.Promotion) error
+	Update(promotion *models.Promotion) error
+	Delete(id int) error
+}
+
+type promotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) PromotionRepository {
+	return &promotionRepository{db: db}
+}
+
+const promotionColumns = `id, name, COALESCE(description, ''), type, min_quantity, percent, min_spend,
+	max_discount, tiers, category_id, brand, provinces, starts_at, ends_at, is_active,
+	COALESCE(created_by, ''), created_at, updated_at`
+
+func (r *promotionRepository) List() ([]models.Promotion, error) {
+	return r.query("SELECT " + promotionColumns + " FROM promotions ORDER BY created_at DESC, id DESC")
+}
+
+func (r *promotionRepository) ListRunning() ([]models.Promotion, error) {
+	return r.query(`
+		SELECT ` + promotionColumns + ` FROM promotions
+		WHERE is_active = true
+		AND (starts_at IS NULL OR starts_at <= NOW())
+		AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY id
+	`)
+}
+
+func (r *promotionRepository) FindByID(id int) (*models.Promotion, error) {
+	promotions, err := r.query("SELECT "+promotionColumns+" FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return nil, ErrPromotionNotFound
+	}
+	return &promotions[0], nil
+}
+
+// query loads the promotions with their flash-sale prices
+func (r *promotionRepository) query(query string, args ...interface{}) ([]models.Promotion, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []models.Promotion{}
+	index := map[int]int{}
+	var ids []int64
+	for rows.Next() {
+		var p models.Promotion
+		var maxDiscount sql.NullFloat64
+		var categoryID sql.NullInt64
+		var brand sql.NullString
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Description, &p.Type, &p.MinQuantity, &p.Percent, &p.MinSpend,
+			&maxDiscount, &p.Tiers, &categoryID, &brand, pq.Array(&p.Provinces), &p.StartsAt, &p.EndsAt, &p.IsActive,
+			&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if maxDiscount.Valid {
+			p.MaxDiscount = &maxDiscount.Float64
+		}
+		if brand.Valid {
+			p.Brand = &brand.String
+		}
+		p.CategoryID = nullIntPtr(categoryID)
+		index[p.ID] = len(promotions)
+		ids = append(ids, int64(p.ID))
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return promotions, nil
+	}
+
+	priceRows, err := r.db.Query(`
+		SELECT promotion_id, variant_id, sale_price
+		FROM promotion_variant_prices
+		WHERE promotion_id = ANY($1)
+		ORDER BY promotion_id, variant_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer priceRows.Close()
+
+	for priceRows.Next() {
+		var promotionID int
+		var price models.PromotionVariantPrice
+		if err := priceRows.Scan(&promotionID, &price.VariantID, &price.SalePrice); err != nil {
+			return nil, err
+		}
+		p := &promotions[index[promotionID]]
+		p.VariantPrices = append(p.VariantPrices, price)
+	}
+	return promotions, priceRows.Err()
+}
+
+func (r *promotionRepository) Create(p *models.Promotion) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO promotions (
+			name, description, type, min_quantity, percent, min_spend, max_discount, tiers,
+			category_id, brand, provinces, starts_at, ends_at, is_active, created_by
+		)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''))
+		RETURNING id, created_at, updated_at
+	`, p.Name, p.Description, p.Type, p.MinQuantity, p.Percent, p.MinSpend, p.MaxDiscount, p.Tiers,
+		p.CategoryID, p.Brand, pq.Array(p.Provinces), p.StartsAt, p.EndsAt, p.IsActive, p.CreatedBy,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPromotionPricesTx(tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *promotionRepository) Update(p *models.Promotion) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE promotions SET
+			name = $2, description = NULLIF($3, ''), type = $4, min_quantity = $5, percent = $6,
+			min_spend = $7, max_discount = $8, tiers = $9, category_id = $10, brand = $11,
+			provinces = $12, starts_at = $13, ends_at = $14, is_active = $15,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING COALESCE(created_by, ''), created_at, updated_at
+	`, p.ID, p.Name, p.Description, p.Type, p.MinQuantity, p.Percent,
+		p.MinSpend, p.MaxDiscount, p.Tiers, p.CategoryID, p.Brand,
+		pq.Array(p.Provinces), p.StartsAt, p.EndsAt, p.IsActive,
+	).Scan(&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrPromotionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM promotion_variant_prices WHERE promotion_id = $1", p.ID); err != nil {
+		return err
+	}
+	if err := insertPromotionPricesTx(tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertPromotionPricesTx(tx *sql.Tx, p *models.Promotion) error {
+	for _, price := range p.VariantPrices {
+		_, err := tx.Exec(`
+			INSERT INTO promotion_variant_prices (promotion_id, variant_id, sale_price)
+			VALUES ($1, $2, $3)
+		`, p.ID, price.VariantID, price.SalePrice)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *promotionRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
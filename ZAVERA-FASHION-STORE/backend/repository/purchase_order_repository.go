@@ -0,0 +1,437 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"zavera/models"
+)
+
+var (
+	ErrPurchaseOrderNotFound      = errors.New("purchase order not found")
+	ErrPurchaseOrderItemNotFound  = errors.New("purchase order line not found")
+	ErrPurchaseOrderConflict      = errors.New("purchase order status changed")
+	ErrDuplicatePurchaseOrderLine = errors.New("variant is already on the purchase order")
+	ErrOverReceipt                = errors.New("received quantity exceeds what is outstanding")
+)
+
+// PurchaseOrderRepository stores purchase orders and books their receipts. Receiving adds stock
+// through the stock ledger (RECEIPT) and updates the variant's moving average cost.
+type PurchaseOrderRepository interface {
+	// Create inserts a draft with its lines and assigns the PO number
+	Create(po *models.PurchaseOrder) error
+	// Update replaces the header and lines of a draft. Returns ErrPurchaseOrderConflict when
+	// the PO is no longer a draft.
+	Update(po *models.PurchaseOrder) error
+	FindByID(id int) (*models.PurchaseOrder, error)
+	List(filter models.PurchaseOrderFilter, limit, offset int) ([]models.PurchaseOrder, int, error)
+
+	// UpdateStatus moves a PO from one status to another, stamping sent_at or cancelled_at.
+	// Sending fills a missing expected date from the supplier's lead time.
+	UpdateStatus(id int, from, to models.PurchaseOrderStatus) error
+
+	// Receive books a delivery against a sent PO. Returns ErrOverReceipt when a line would
+	// receive more than is outstanding.
+	Receive(poID int, lines []models.PurchaseOrderReceiptLine, actor, notes string) (*models.PurchaseOrderReceipt, error)
+}
+
+type purchaseOrderRepository struct {
+	db    *sql.DB
+	stock StockRepository
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: db, stock: NewStockRepository(db)}
+}
+
+const purchaseOrderColumns = `po.id, COALESCE(po.po_number, ''), po.supplier_id, s.name, po.warehouse_id, w.code,
+	po.status, po.expected_at, COALESCE(po.notes, ''), po.created_by,
+	po.sent_at, po.received_at, po.cancelled_at, po.created_at, po.updated_at`
+
+const purchaseOrderFrom = `FROM purchase_orders po
+	JOIN suppliers s ON s.id = po.supplier_id
+	JOIN warehouses w ON w.id = po.warehouse_id`
+
+func scanPurchaseOrder(scanner interface{ Scan(...interface{}) error }) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	err := scanner.Scan(
+		&po.ID, &po.PONumber, &po.SupplierID, &po.SupplierName, &po.WarehouseID, &po.WarehouseCode,
+		&po.Status, &po.ExpectedAt, &po.Notes, &po.CreatedBy,
+		&po.SentAt, &po.ReceivedAt, &po.CancelledAt, &po.CreatedAt, &po.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+func (r *purchaseOrderRepository) Create(po *models.PurchaseOrder) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO purchase_orders (supplier_id, warehouse_id, status, expected_at, notes, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at, updated_at
+	`, po.SupplierID, po.WarehouseID, models.PurchaseOrderDraft, po.ExpectedAt, po.Notes, po.CreatedBy,
+	).Scan(&po.ID, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// PO-20261016-00042
+	err = tx.QueryRow(`
+		UPDATE purchase_orders
+		SET po_number = 'PO-' || to_char(created_at, 'YYYYMMDD') || '-' || lpad(id::text, 5, '0')
+		WHERE id = $1
+		RETURNING po_number
+	`, po.ID).Scan(&po.PONumber)
+	if err != nil {
+		return err
+	}
+
+	if err := r.insertItemsTx(tx, po); err != nil {
+		return err
+	}
+
+	po.Status = models.PurchaseOrderDraft
+	return tx.Commit()
+}
+
+func (r *purchaseOrderRepository) Update(po *models.PurchaseOrder) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE purchase_orders
+		SET supplier_id = $2, warehouse_id = $3, expected_at = $4, notes = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $1 AND status = $6
+		RETURNING updated_at
+	`, po.ID, po.SupplierID, po.WarehouseID, po.ExpectedAt, po.Notes, models.PurchaseOrderDraft,
+	).Scan(&po.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrPurchaseOrderConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", po.ID); err != nil {
+		return err
+	}
+	if err := r.insertItemsTx(tx, po); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *purchaseOrderRepository) insertItemsTx(tx *sql.Tx, po *models.PurchaseOrder) error {
+	for i := range po.Items {
+		item := &po.Items[i]
+		item.PurchaseOrderID = po.ID
+		err := tx.QueryRow(`
+			INSERT INTO purchase_order_items (purchase_order_id, variant_id, quantity_ordered, cost_per_item, expected_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, po.ID, item.VariantID, item.QuantityOrdered, item.CostPerItem, item.ExpectedAt).Scan(&item.ID)
+		if isUniqueViolation(err) {
+			return ErrDuplicatePurchaseOrderLine
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *purchaseOrderRepository) FindByID(id int) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(r.db.QueryRow("SELECT "+purchaseOrderColumns+" "+purchaseOrderFrom+" WHERE po.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if po.Items, err = r.findItems(id); err != nil {
+		return nil, err
+	}
+	if po.Receipts, err = r.findReceipts(id); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func (r *purchaseOrderRepository) findItems(poID int) ([]models.PurchaseOrderItem, error) {
+	rows, err := r.db.Query(`
+		SELECT poi.id, poi.purchase_order_id, poi.variant_id, pv.sku, p.name, COALESCE(pv.variant_name, ''),
+		       poi.quantity_ordered, poi.quantity_received, poi.cost_per_item, poi.expected_at
+		FROM purchase_order_items poi
+		JOIN product_variants pv ON pv.id = poi.variant_id
+		JOIN products p ON p.id = pv.product_id
+		WHERE poi.purchase_order_id = $1
+		ORDER BY poi.id
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.PurchaseOrderItem{}
+	for rows.Next() {
+		var item models.PurchaseOrderItem
+		err := rows.Scan(
+			&item.ID, &item.PurchaseOrderID, &item.VariantID, &item.SKU, &item.ProductName, &item.VariantName,
+			&item.QuantityOrdered, &item.QuantityReceived, &item.CostPerItem, &item.ExpectedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *purchaseOrderRepository) findReceipts(poID int) ([]models.PurchaseOrderReceipt, error) {
+	rows, err := r.db.Query(`
+		SELECT r.id, r.purchase_order_id, r.received_by, COALESCE(r.notes, ''), r.created_at,
+		       ri.purchase_order_item_id, poi.variant_id, ri.quantity, ri.ledger_id
+		FROM purchase_order_receipts r
+		JOIN purchase_order_receipt_items ri ON ri.receipt_id = r.id
+		JOIN purchase_order_items poi ON poi.id = ri.purchase_order_item_id
+		WHERE r.purchase_order_id = $1
+		ORDER BY r.id, ri.id
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []models.PurchaseOrderReceipt{}
+	for rows.Next() {
+		var receipt models.PurchaseOrderReceipt
+		var line models.PurchaseOrderReceiptLine
+		err := rows.Scan(
+			&receipt.ID, &receipt.PurchaseOrderID, &receipt.ReceivedBy, &receipt.Notes, &receipt.CreatedAt,
+			&line.PurchaseOrderItemID, &line.VariantID, &line.Quantity, &line.LedgerID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(receipts); n > 0 && receipts[n-1].ID == receipt.ID {
+			receipts[n-1].Lines = append(receipts[n-1].Lines, line)
+			continue
+		}
+		receipt.Lines = []models.PurchaseOrderReceiptLine{line}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, rows.Err()
+}
+
+func (r *purchaseOrderRepository) List(filter models.PurchaseOrderFilter, limit, offset int) ([]models.PurchaseOrder, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.Status != "" {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("po.status = $%d", argCount))
+		args = append(args, filter.Status)
+	}
+	if filter.SupplierID > 0 {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("po.supplier_id = $%d", argCount))
+		args = append(args, filter.SupplierID)
+	}
+	if filter.VariantID > 0 {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM purchase_order_items poi WHERE poi.purchase_order_id = po.id AND poi.variant_id = $%d)", argCount))
+		args = append(args, filter.VariantID)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) "+purchaseOrderFrom+" "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s %s %s ORDER BY po.created_at DESC, po.id DESC LIMIT $%d OFFSET $%d",
+		purchaseOrderColumns, purchaseOrderFrom, whereClause, argCount+1, argCount+2)
+	rows, err := r.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []models.PurchaseOrder{}
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *po)
+	}
+	return orders, total, rows.Err()
+}
+
+func (r *purchaseOrderRepository) UpdateStatus(id int, from, to models.PurchaseOrderStatus) error {
+	set := "status = $3, updated_at = NOW()"
+	switch to {
+	case models.PurchaseOrderSent:
+		set += `, sent_at = NOW(),
+			expected_at = COALESCE(expected_at, CURRENT_DATE + (SELECT lead_time_days FROM suppliers WHERE id = supplier_id))`
+	case models.PurchaseOrderCancelled:
+		set += ", cancelled_at = NOW()"
+	}
+
+	result, err := r.db.Exec("UPDATE purchase_orders SET "+set+" WHERE id = $1 AND status = $2", id, from, to)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPurchaseOrderConflict
+	}
+	return nil
+}
+
+func (r *purchaseOrderRepository) Receive(poID int, lines []models.PurchaseOrderReceiptLine, actor, notes string) (*models.PurchaseOrderReceipt, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status models.PurchaseOrderStatus
+	var warehouseID int
+	var poNumber string
+	err = tx.QueryRow(
+		"SELECT status, warehouse_id, po_number FROM purchase_orders WHERE id = $1 FOR UPDATE", poID,
+	).Scan(&status, &warehouseID, &poNumber)
+	if err == sql.ErrNoRows {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsIncoming() {
+		return nil, ErrPurchaseOrderConflict
+	}
+
+	receipt := &models.PurchaseOrderReceipt{PurchaseOrderID: poID, ReceivedBy: actor, Notes: notes}
+	err = tx.QueryRow(`
+		INSERT INTO purchase_order_receipts (purchase_order_id, received_by, notes)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id, created_at
+	`, poID, actor, notes).Scan(&receipt.ID, &receipt.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		var item models.PurchaseOrderItem
+		err := tx.QueryRow(`
+			SELECT variant_id, quantity_ordered, quantity_received, cost_per_item
+			FROM purchase_order_items
+			WHERE id = $1 AND purchase_order_id = $2
+			FOR UPDATE
+		`, line.PurchaseOrderItemID, poID).Scan(&item.VariantID, &item.QuantityOrdered, &item.QuantityReceived, &item.CostPerItem)
+		if err == sql.ErrNoRows {
+			return nil, ErrPurchaseOrderItemNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line.Quantity > item.Outstanding() {
+			return nil, ErrOverReceipt
+		}
+
+		// Blend the PO cost into the cost of what is already on hand
+		var onHand int
+		var currentCost sql.NullFloat64
+		err = tx.QueryRow(
+			"SELECT stock_quantity, cost_per_item FROM product_variants WHERE id = $1 FOR UPDATE", item.VariantID,
+		).Scan(&onHand, &currentCost)
+		if err != nil {
+			return nil, err
+		}
+		var current *float64
+		if currentCost.Valid {
+			current = &currentCost.Float64
+		}
+		cost := models.MovingAverageCost(onHand, current, line.Quantity, item.CostPerItem)
+		if _, err := tx.Exec("UPDATE product_variants SET cost_per_item = $2 WHERE id = $1", item.VariantID, cost); err != nil {
+			return nil, err
+		}
+
+		entry := &models.StockLedgerEntry{
+			VariantID:    item.VariantID,
+			WarehouseID:  &warehouseID,
+			MovementType: models.StockMovementReceipt,
+			Quantity:     line.Quantity,
+			Reference:    poNumber,
+			Notes:        fmt.Sprintf("Receipt #%d", receipt.ID),
+			Actor:        actor,
+		}
+		if err := r.stock.ApplyMovementTx(tx, entry); err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE purchase_order_items SET quantity_received = quantity_received + $2 WHERE id = $1",
+			line.PurchaseOrderItemID, line.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO purchase_order_receipt_items (receipt_id, purchase_order_item_id, quantity, ledger_id)
+			VALUES ($1, $2, $3, $4)
+		`, receipt.ID, line.PurchaseOrderItemID, line.Quantity, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		line.VariantID = item.VariantID
+		line.LedgerID = entry.ID
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	// RECEIVED once nothing is outstanding
+	var outstanding int
+	err = tx.QueryRow(
+		"SELECT COALESCE(SUM(quantity_ordered - quantity_received), 0) FROM purchase_order_items WHERE purchase_order_id = $1", poID,
+	).Scan(&outstanding)
+	if err != nil {
+		return nil, err
+	}
+	next := models.PurchaseOrderPartiallyReceived
+	if outstanding == 0 {
+		next = models.PurchaseOrderReceived
+	}
+	_, err = tx.Exec(`
+		UPDATE purchase_orders
+		SET status = $2, received_at = CASE WHEN $2 = 'RECEIVED' THEN NOW() ELSE received_at END, updated_at = NOW()
+		WHERE id = $1
+	`, poID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+type RecommendationRepository interface {
+	RefreshCoPurchases(windowDays, minOrders, perProduct int) (int, error)
+	FindFrequentlyBoughtTogetherIDs(productID, limit int) ([]int, error)
+	FindSimilarProductIDs(product *models.Product, limit int) ([]int, error)
+	FindLookLinkIDs(productID int) ([]int, error)
+	SetLookLinks(productID int, linkedIDs []int) error
+}
+
+type recommendationRepository struct {
+	db *sql.DB
+}
+
+func NewRecommendationRepository(db *sql.DB) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+// RefreshCoPurchases rebuilds the co-purchase matrix from paid orders of the last windowDays.
+// Pairs bought together in fewer than minOrders orders are dropped; each product keeps its
+// top perProduct partners by cosine score. Returns the number of pairs stored.
+func (r *recommendationRepository) RefreshCoPurchases(windowDays, minOrders, perProduct int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM product_co_purchases"); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		WITH sold AS (
+			SELECT DISTINCT oi.order_id, oi.product_id
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ('PAID', 'PACKING', 'SHIPPED', 'DELIVERED', 'COMPLETED')
+			  AND o.created_at >= NOW() - make_interval(days => $1)
+		),
+		product_orders AS (
+			SELECT product_id, COUNT(*) AS orders FROM sold GROUP BY product_id
+		),
+		pairs AS (
+			SELECT a.product_id, b.product_id AS related_product_id, COUNT(*) AS order_count
+			FROM sold a
+			JOIN sold b ON a.order_id = b.order_id AND a.product_id <> b.product_id
+			GROUP BY a.product_id, b.product_id
+			HAVING COUNT(*) >= $2
+		),
+		scored AS (
+			SELECT p.product_id, p.related_product_id, p.order_count,
+			       p.order_count / SQRT(pa.orders::numeric * pb.orders) AS score
+			FROM pairs p
+			JOIN product_orders pa ON pa.product_id = p.product_id
+			JOIN product_orders pb ON pb.product_id = p.related_product_id
+		),
+		ranked AS (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY product_id ORDER BY score DESC, order_count DESC, related_product_id
+			) AS rank
+			FROM scored
+		)
+		INSERT INTO product_co_purchases (product_id, related_product_id, order_count, score, refreshed_at)
+		SELECT product_id, related_product_id, order_count, ROUND(score, 6), NOW()
+		FROM ranked
+		WHERE rank <= $3
+	`, windowDays, minOrders, perProduct)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	stored, _ := result.RowsAffected()
+	return int(stored), nil
+}
+
+// FindFrequentlyBoughtTogetherIDs returns active co-purchased products, best score first
+func (r *recommendationRepository) FindFrequentlyBoughtTogetherIDs(productID, limit int) ([]int, error) {
+	return r.queryIDs(`
+		SELECT cp.related_product_id
+		FROM product_co_purchases cp
+		JOIN products p ON p.id = cp.related_product_id
+		WHERE cp.product_id = $1 AND p.is_active = true
+		ORDER BY cp.score DESC, cp.order_count DESC, cp.related_product_id
+		LIMIT $2
+	`, productID, limit)
+}
+
+// FindSimilarProductIDs ranks active products by attribute overlap with product:
+// same category node 4, same root category 2, same brand 2, same material 1,
+// plus up to 1 for price proximity. Candidates must share the category or the brand.
+func (r *recommendationRepository) FindSimilarProductIDs(product *models.Product, limit int) ([]int, error) {
+	return r.queryIDs(`
+		SELECT p.id
+		FROM products p
+		WHERE p.is_active = true
+		  AND p.id <> $1
+		  AND (LOWER(p.category) = LOWER($3) OR (p.brand <> '' AND LOWER(p.brand) = LOWER($4)))
+		ORDER BY (
+		      CASE WHEN $2::int IS NOT NULL AND p.category_id = $2::int THEN 4 ELSE 0 END
+		    + CASE WHEN LOWER(p.category) = LOWER($3) THEN 2 ELSE 0 END
+		    + CASE WHEN $4 <> '' AND LOWER(COALESCE(p.brand, '')) = LOWER($4) THEN 2 ELSE 0 END
+		    + CASE WHEN $5 <> '' AND LOWER(COALESCE(p.material, '')) = LOWER($5) THEN 1 ELSE 0 END
+		    + GREATEST(0, 1 - ABS(p.price - $6) / GREATEST($6, 1))
+		) DESC, p.created_at DESC, p.id
+		LIMIT $7
+	`, product.ID, product.CategoryID, product.Category, product.Brand, product.Material, product.Price, limit)
+}
+
+// FindLookLinkIDs returns the curated "complete the look" products in display order
+func (r *recommendationRepository) FindLookLinkIDs(productID int) ([]int, error) {
+	return r.queryIDs(`
+		SELECT linked_product_id
+		FROM product_look_links
+		WHERE product_id = $1
+		ORDER BY position, linked_product_id
+	`, productID)
+}
+
+// SetLookLinks replaces the curated links of a product; list order becomes position
+func (r *recommendationRepository) SetLookLinks(productID int, linkedIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM product_look_links WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+
+	if len(linkedIDs) > 0 {
+		// Unknown IDs and self-links are skipped
+		_, err = tx.Exec(`
+			INSERT INTO product_look_links (product_id, linked_product_id, position)
+			SELECT $1, ids.product_id, ids.position
+			FROM unnest($2::int[]) WITH ORDINALITY AS ids(product_id, position)
+			JOIN products p ON p.id = ids.product_id
+			WHERE ids.product_id <> $1
+			ON CONFLICT (product_id, linked_product_id) DO NOTHING
+		`, productID, pq.Array(linkedIDs))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *recommendationRepository) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -53,13 +53,18 @@ type dbExecutor interface {
 }
 
 func (r *refundRepository) createInternal(db dbExecutor, refund *models.Refund) error {
+	if refund.Destination == "" {
+		refund.Destination = models.RefundDestinationOriginal
+	}
+
 	query := `
 		INSERT INTO refunds (
 			refund_code, order_id, payment_id, refund_type, reason, reason_detail,
 			original_amount, refund_amount, shipping_refund, items_refund,
-			status, idempotency_key, requested_by, requested_at
+			status, idempotency_key, requested_by, requested_at, tax_refund,
+			refund_destination, store_credit_amount
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -68,7 +73,8 @@ func (r *refundRepository) createInternal(db dbExecutor, refund *models.Refund)
 		refund.RefundCode, refund.OrderID, refund.PaymentID, refund.RefundType,
 		refund.Reason, refund.ReasonDetail, refund.OriginalAmount, refund.RefundAmount,
 		refund.ShippingRefund, refund.ItemsRefund, refund.Status, refund.IdempotencyKey,
-		refund.RequestedBy, time.Now(),
+		refund.RequestedBy, time.Now(), refund.TaxRefund,
+		refund.Destination, refund.StoreCreditAmount,
 	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
 	
 	if err != nil {
@@ -80,7 +86,7 @@ func (r *refundRepository) createInternal(db dbExecutor, refund *models.Refund)
 func (r *refundRepository) FindByID(id int) (*models.Refund, error) {
 	query := `
 		SELECT id, refund_code, order_id, payment_id, refund_type, reason, reason_detail,
-		       original_amount, refund_amount, shipping_refund, items_refund, status,
+		       original_amount, refund_amount, shipping_refund, items_refund, tax_refund, refund_destination, store_credit_amount, status,
 		       gateway_refund_id, gateway_status, gateway_response, idempotency_key,
 		       processed_by, processed_at, requested_by, requested_at,
 		       created_at, updated_at, completed_at
@@ -99,7 +105,7 @@ func (r *refundRepository) FindByID(id int) (*models.Refund, error) {
 func (r *refundRepository) FindByCode(code string) (*models.Refund, error) {
 	query := `
 		SELECT id, refund_code, order_id, payment_id, refund_type, reason, reason_detail,
-		       original_amount, refund_amount, shipping_refund, items_refund, status,
+		       original_amount, refund_amount, shipping_refund, items_refund, tax_refund, refund_destination, store_credit_amount, status,
 		       gateway_refund_id, gateway_status, gateway_response, idempotency_key,
 		       processed_by, processed_at, requested_by, requested_at,
 		       created_at, updated_at, completed_at
@@ -118,7 +124,7 @@ func (r *refundRepository) FindByCode(code string) (*models.Refund, error) {
 func (r *refundRepository) FindByOrderID(orderID int) ([]*models.Refund, error) {
 	query := `
 		SELECT id, refund_code, order_id, payment_id, refund_type, reason, reason_detail,
-		       original_amount, refund_amount, shipping_refund, items_refund, status,
+		       original_amount, refund_amount, shipping_refund, items_refund, tax_refund, refund_destination, store_credit_amount, status,
 		       gateway_refund_id, gateway_status, gateway_response, idempotency_key,
 		       processed_by, processed_at, requested_by, requested_at,
 		       created_at, updated_at, completed_at
@@ -150,7 +156,7 @@ func (r *refundRepository) FindAll(page, pageSize int, status, orderCode string)
 	// Build query with filters
 	baseQuery := `
 		SELECT r.id, r.refund_code, r.order_id, r.payment_id, r.refund_type, r.reason, r.reason_detail,
-		       r.original_amount, r.refund_amount, r.shipping_refund, r.items_refund, r.status,
+		       r.original_amount, r.refund_amount, r.shipping_refund, r.items_refund, r.tax_refund, r.refund_destination, r.store_credit_amount, r.status,
 		       r.gateway_refund_id, r.gateway_status, r.gateway_response, r.idempotency_key,
 		       r.processed_by, r.processed_at, r.requested_by, r.requested_at,
 		       r.created_at, r.updated_at, r.completed_at
@@ -238,7 +244,7 @@ func (r *refundRepository) FindAll(page, pageSize int, status, orderCode string)
 func (r *refundRepository) FindByIdempotencyKey(key string) (*models.Refund, error) {
 	query := `
 		SELECT id, refund_code, order_id, payment_id, refund_type, reason, reason_detail,
-		       original_amount, refund_amount, shipping_refund, items_refund, status,
+		       original_amount, refund_amount, shipping_refund, items_refund, tax_refund, refund_destination, store_credit_amount, status,
 		       gateway_refund_id, gateway_status, gateway_response, idempotency_key,
 		       processed_by, processed_at, requested_by, requested_at,
 		       created_at, updated_at, completed_at
@@ -262,7 +268,8 @@ func (r *refundRepository) scanRefund(row *sql.Row) (*models.Refund, error) {
 		&refund.ID, &refund.RefundCode, &refund.OrderID, &refund.PaymentID,
 		&refund.RefundType, &refund.Reason, &refund.ReasonDetail,
 		&refund.OriginalAmount, &refund.RefundAmount, &refund.ShippingRefund,
-		&refund.ItemsRefund, &refund.Status, &refund.GatewayRefundID,
+		&refund.ItemsRefund, &refund.TaxRefund, &refund.Destination, &refund.StoreCreditAmount,
+		&refund.Status, &refund.GatewayRefundID,
 		&refund.GatewayStatus, &gatewayResponseJSON, &refund.IdempotencyKey,
 		&refund.ProcessedBy, &refund.ProcessedAt, &refund.RequestedBy,
 		&refund.RequestedAt, &refund.CreatedAt, &refund.UpdatedAt, &refund.CompletedAt,
@@ -286,7 +293,8 @@ func (r *refundRepository) scanRefundFromRows(rows *sql.Rows) (*models.Refund, e
 		&refund.ID, &refund.RefundCode, &refund.OrderID, &refund.PaymentID,
 		&refund.RefundType, &refund.Reason, &refund.ReasonDetail,
 		&refund.OriginalAmount, &refund.RefundAmount, &refund.ShippingRefund,
-		&refund.ItemsRefund, &refund.Status, &refund.GatewayRefundID,
+		&refund.ItemsRefund, &refund.TaxRefund, &refund.Destination, &refund.StoreCreditAmount,
+		&refund.Status, &refund.GatewayRefundID,
 		&refund.GatewayStatus, &gatewayResponseJSON, &refund.IdempotencyKey,
 		&refund.ProcessedBy, &refund.ProcessedAt, &refund.RequestedBy,
 		&refund.RequestedAt, &refund.CreatedAt, &refund.UpdatedAt, &refund.CompletedAt,
@@ -370,15 +378,16 @@ func (r *refundRepository) createRefundItemInternal(db dbExecutor, item *models.
 	query := `
 		INSERT INTO refund_items (
 			refund_id, order_item_id, product_id, product_name,
-			quantity, price_per_unit, refund_amount, item_reason
+			quantity, price_per_unit, refund_amount, item_reason, order_item_component_id, tax_amount
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at
 	`
 	err := db.QueryRow(
 		query,
 		item.RefundID, item.OrderItemID, item.ProductID, item.ProductName,
-		item.Quantity, item.PricePerUnit, item.RefundAmount, item.ItemReason,
+		item.Quantity, item.PricePerUnit, item.RefundAmount, item.ItemReason, item.OrderItemComponentID,
+		item.TaxAmount,
 	).Scan(&item.ID, &item.CreatedAt)
 	
 	if err != nil {
@@ -391,7 +400,7 @@ func (r *refundRepository) FindItemsByRefundID(refundID int) ([]models.RefundIte
 	query := `
 		SELECT id, refund_id, order_item_id, product_id, product_name,
 		       quantity, price_per_unit, refund_amount, item_reason,
-		       stock_restored, stock_restored_at, created_at
+		       stock_restored, stock_restored_at, created_at, order_item_component_id, tax_amount
 		FROM refund_items WHERE refund_id = $1
 	`
 	rows, err := r.db.Query(query, refundID)
@@ -407,7 +416,7 @@ func (r *refundRepository) FindItemsByRefundID(refundID int) ([]models.RefundIte
 			&item.ID, &item.RefundID, &item.OrderItemID, &item.ProductID,
 			&item.ProductName, &item.Quantity, &item.PricePerUnit,
 			&item.RefundAmount, &item.ItemReason, &item.StockRestored,
-			&item.StockRestoredAt, &item.CreatedAt,
+			&item.StockRestoredAt, &item.CreatedAt, &item.OrderItemComponentID, &item.TaxAmount,
 		)
 		if err != nil {
 			continue
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"zavera/models"
+)
+
+// ReservationRepository manages stock holds. A hold takes its units off the shelf with a RESERVE
+// ledger entry and puts them back with a RELEASE entry when it expires or is cancelled
+// (see release_reservation() in migrate_cart_holds.sql).
+type ReservationRepository interface {
+	// IsLimitedDrop reports whether adding the product to a cart should hold its stock
+	IsLimitedDrop(productID int) (bool, error)
+
+	// HoldCartItem replaces the hold of a cart item with a new one for its current quantity,
+	// expiring after minutes. Returns ErrInsufficientStock (keeping the old hold) when the
+	// stock is not there.
+	HoldCartItem(item *models.CartItem, customerID *int, sessionID string, minutes int) (*models.StockReservation, error)
+
+	// ExpireReservations releases up to limit holds that are past their expiry
+	ExpireReservations(limit int) ([]models.ReservationRelease, error)
+}
+
+type reservationRepository struct {
+	db *sql.DB
+}
+
+// NewReservationRepository creates a new reservation repository
+func NewReservationRepository(db *sql.DB) ReservationRepository {
+	return &reservationRepository{db: db}
+}
+
+func (r *reservationRepository) IsLimitedDrop(productID int) (bool, error) {
+	var limited bool
+	err := r.db.QueryRow("SELECT is_limited_drop FROM products WHERE id = $1", productID).Scan(&limited)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return limited, err
+}
+
+func (r *reservationRepository) HoldCartItem(item *models.CartItem, customerID *int, sessionID string, minutes int) (*models.StockReservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Give the old hold back first so the item can count its own units
+	_, err = tx.Exec(`
+		SELECT release_reservation(id, 'cancelled')
+		FROM stock_reservations
+		WHERE cart_item_id = $1 AND status = 'active'
+	`, item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservationID int
+	err = tx.QueryRow(`
+		SELECT reserve_stock(COALESCE($1, ensure_default_variant($2)), $3, NULLIF($4, ''), $5, $6, NULL, $7)
+	`, item.VariantID, item.ProductID, customerID, sessionID, item.Quantity, minutes, item.ID).Scan(&reservationID)
+	if isInsufficientStock(err) {
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hold := &models.StockReservation{ID: reservationID, Status: "active", HeldInLedger: true}
+	var warehouseID, cartItemID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT variant_id, quantity, warehouse_id, cart_item_id, reserved_at, expires_at
+		FROM stock_reservations
+		WHERE id = $1
+	`, reservationID).Scan(&hold.VariantID, &hold.Quantity, &warehouseID, &cartItemID, &hold.ReservedAt, &hold.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	hold.WarehouseID = nullIntPtr(warehouseID)
+	hold.CartItemID = nullIntPtr(cartItemID)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (r *reservationRepository) ExpireReservations(limit int) ([]models.ReservationRelease, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED leaves holds that a checkout is handing over to its order
+	rows, err := tx.Query(`
+		SELECT sr.id, sr.variant_id, COALESCE(pv.sku, ''), sr.warehouse_id, sr.quantity, sr.cart_item_id
+		FROM stock_reservations sr
+		LEFT JOIN product_variants pv ON pv.id = sr.variant_id
+		WHERE sr.status = 'active' AND sr.expires_at < CURRENT_TIMESTAMP
+		ORDER BY sr.expires_at, sr.id
+		LIMIT $1
+		FOR UPDATE OF sr SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []models.ReservationRelease{}
+	for rows.Next() {
+		var rel models.ReservationRelease
+		var warehouseID, cartItemID sql.NullInt64
+		if err := rows.Scan(&rel.ReservationID, &rel.VariantID, &rel.SKU, &warehouseID, &rel.Quantity, &cartItemID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rel.WarehouseID = nullIntPtr(warehouseID)
+		rel.CartItemID = nullIntPtr(cartItemID)
+		releases = append(releases, rel)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range releases {
+		var ledgerID sql.NullInt64
+		err := tx.QueryRow("SELECT id FROM release_reservation($1, 'expired')", releases[i].ReservationID).Scan(&ledgerID)
+		if err != nil {
+			return nil, err
+		}
+		if ledgerID.Valid {
+			releases[i].LedgerID = &ledgerID.Int64
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
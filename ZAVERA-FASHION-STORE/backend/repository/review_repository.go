@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrReviewNotFound     = errors.New("review not found")
+	ErrOrderItemReviewed  = errors.New("order item already reviewed")
+	ErrReviewItemNotFound = errors.New("order item not found")
+)
+
+type ReviewRepository interface {
+	FindOrderItemForReview(orderItemID int) (*models.ReviewableItem, *int, models.OrderStatus, error)
+	FindReviewableItems(userID int) ([]models.ReviewableItem, error)
+	Create(review *models.ProductReview) error
+	FindByID(id int) (*models.ProductReview, error)
+	FindByUser(userID int) ([]models.ProductReview, error)
+	List(filter models.ReviewListFilter) ([]models.ProductReview, int, error)
+	Moderate(id int, status models.ReviewStatus, note, moderatedBy string) error
+}
+
+type reviewRepository struct {
+	db *sql.DB
+}
+
+func NewReviewRepository(db *sql.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+// reviewableStatuses are the order statuses that unlock reviews
+var reviewableStatuses = []string{string(models.OrderStatusDelivered), string(models.OrderStatusCompleted)}
+
+const reviewColumns = `r.id, r.product_id, r.variant_id, r.order_id, r.order_item_id, r.user_id,
+	r.reviewer_name, r.rating, COALESCE(r.title, ''), COALESCE(r.body, ''), r.fit,
+	r.is_verified_purchase, r.status, COALESCE(r.moderation_note, ''), COALESCE(r.moderated_by, ''),
+	r.moderated_at, r.created_at, r.updated_at,
+	COALESCE(p.name, ''), COALESCE(pv.variant_name, '')`
+
+const reviewJoins = `LEFT JOIN products p ON p.id = r.product_id
+	LEFT JOIN product_variants pv ON pv.id = r.variant_id`
+
+func scanReview(scanner interface{ Scan(...interface{}) error }) (*models.ProductReview, error) {
+	var r models.ProductReview
+	var variantID, orderID, orderItemID, userID sql.NullInt64
+	var fit sql.NullString
+	var moderatedAt sql.NullTime
+
+	err := scanner.Scan(
+		&r.ID, &r.ProductID, &variantID, &orderID, &orderItemID, &userID,
+		&r.ReviewerName, &r.Rating, &r.Title, &r.Body, &fit,
+		&r.IsVerifiedPurchase, &r.Status, &r.ModerationNote, &r.ModeratedBy,
+		&moderatedAt, &r.CreatedAt, &r.UpdatedAt,
+		&r.ProductName, &r.VariantName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.VariantID = nullIntPtr(variantID)
+	r.OrderID = nullIntPtr(orderID)
+	r.OrderItemID = nullIntPtr(orderItemID)
+	r.UserID = nullIntPtr(userID)
+	if fit.Valid {
+		f := models.ReviewFit(fit.String)
+		r.Fit = &f
+	}
+	if moderatedAt.Valid {
+		r.ModeratedAt = &moderatedAt.Time
+	}
+	return &r, nil
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// FindOrderItemForReview returns the order item with its order owner and status, so the
+// service can decide whether the caller may review it
+func (r *reviewRepository) FindOrderItemForReview(orderItemID int) (*models.ReviewableItem, *int, models.OrderStatus, error) {
+	var item models.ReviewableItem
+	var variantID, ownerID sql.NullInt64
+	var status models.OrderStatus
+	var reviewed bool
+
+	err := r.db.QueryRow(`
+		SELECT oi.id, o.id, o.order_code, oi.product_id, oi.variant_id, oi.product_name,
+		       COALESCE(oi.product_image, ''),
+		       COALESCE(o.delivered_at, o.completed_at, o.updated_at),
+		       o.user_id, o.status,
+		       EXISTS(SELECT 1 FROM product_reviews pr WHERE pr.order_item_id = oi.id)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.id = $1
+	`, orderItemID).Scan(
+		&item.OrderItemID, &item.OrderID, &item.OrderCode, &item.ProductID, &variantID, &item.ProductName,
+		&item.ProductImage, &item.DeliveredAt, &ownerID, &status, &reviewed,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil, "", ErrReviewItemNotFound
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if reviewed {
+		return nil, nil, "", ErrOrderItemReviewed
+	}
+
+	item.VariantID = nullIntPtr(variantID)
+	return &item, nullIntPtr(ownerID), status, nil
+}
+
+// FindReviewableItems lists the user's delivered order items that have no review yet, newest first
+func (r *reviewRepository) FindReviewableItems(userID int) ([]models.ReviewableItem, error) {
+	rows, err := r.db.Query(`
+		SELECT oi.id, o.id, o.order_code, oi.product_id, oi.variant_id, oi.product_name,
+		       COALESCE(oi.product_image, ''),
+		       COALESCE(o.delivered_at, o.completed_at, o.updated_at) AS delivered_at
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.user_id = $1
+		  AND o.status = ANY($2)
+		  AND NOT EXISTS (SELECT 1 FROM product_reviews pr WHERE pr.order_item_id = oi.id)
+		ORDER BY delivered_at DESC, oi.id
+	`, userID, pq.Array(reviewableStatuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ReviewableItem{}
+	for rows.Next() {
+		var item models.ReviewableItem
+		var variantID sql.NullInt64
+		err := rows.Scan(
+			&item.OrderItemID, &item.OrderID, &item.OrderCode, &item.ProductID, &variantID,
+			&item.ProductName, &item.ProductImage, &item.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.VariantID = nullIntPtr(variantID)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Create stores a review and its photos in one transaction
+func (r *reviewRepository) Create(review *models.ProductReview) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO product_reviews (product_id, variant_id, order_id, order_item_id, user_id, reviewer_name,
+		                             rating, title, body, fit, is_verified_purchase, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`, review.ProductID, review.VariantID, review.OrderID, review.OrderItemID, review.UserID, review.ReviewerName,
+		review.Rating, review.Title, review.Body, review.Fit, review.IsVerifiedPurchase, review.Status,
+	).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrOrderItemReviewed
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, url := range review.Photos {
+		_, err := tx.Exec(`
+			INSERT INTO product_review_photos (review_id, image_url, position) VALUES ($1, $2, $3)
+		`, review.ID, url, i)
+		if err != nil {
+			return fmt.Errorf("failed to save review photo: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *reviewRepository) FindByID(id int) (*models.ProductReview, error) {
+	query := fmt.Sprintf(`SELECT %s FROM product_reviews r %s WHERE r.id = $1`, reviewColumns, reviewJoins)
+
+	review, err := scanReview(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachPhotos([]*models.ProductReview{review}); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// FindByUser returns all reviews written by a user, any status
+func (r *reviewRepository) FindByUser(userID int) ([]models.ProductReview, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM product_reviews r %s
+		WHERE r.user_id = $1
+		ORDER BY r.created_at DESC
+	`, reviewColumns, reviewJoins)
+
+	return r.queryReviews(query, userID)
+}
+
+// List returns a filtered page of reviews with the total match count
+func (r *reviewRepository) List(filter models.ReviewListFilter) ([]models.ProductReview, int, error) {
+	whereConditions := []string{"1=1"}
+	args := []interface{}{}
+	argCount := 1
+
+	if filter.ProductID > 0 {
+		whereConditions = append(whereConditions, fmt.Sprintf("r.product_id = $%d", argCount))
+		args = append(args, filter.ProductID)
+		argCount++
+	}
+	if filter.Status != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("r.status = $%d", argCount))
+		args = append(args, filter.Status)
+		argCount++
+	}
+	if filter.Rating > 0 {
+		whereConditions = append(whereConditions, fmt.Sprintf("r.rating = $%d", argCount))
+		args = append(args, filter.Rating)
+		argCount++
+	}
+	if filter.WithPhotos {
+		whereConditions = append(whereConditions, "EXISTS (SELECT 1 FROM product_review_photos ph WHERE ph.review_id = r.id)")
+	}
+	where := strings.Join(whereConditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM product_reviews r WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := "r.created_at DESC, r.id DESC"
+	switch filter.Sort {
+	case "highest":
+		orderBy = "r.rating DESC, r.created_at DESC, r.id DESC"
+	case "lowest":
+		orderBy = "r.rating ASC, r.created_at DESC, r.id DESC"
+	case "oldest":
+		orderBy = "r.created_at ASC, r.id ASC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM product_reviews r %s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, reviewColumns, reviewJoins, where, orderBy, argCount, argCount+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	reviews, err := r.queryReviews(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// Moderate sets the review status and records who decided
+func (r *reviewRepository) Moderate(id int, status models.ReviewStatus, note, moderatedBy string) error {
+	result, err := r.db.Exec(`
+		UPDATE product_reviews
+		SET status = $1, moderation_note = NULLIF($2, ''), moderated_by = NULLIF($3, ''),
+		    moderated_at = NOW(), updated_at = NOW()
+		WHERE id = $4
+	`, status, note, moderatedBy, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+func (r *reviewRepository) queryReviews(query string, args ...interface{}) ([]models.ProductReview, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ptrs []*models.ProductReview
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		ptrs = append(ptrs, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachPhotos(ptrs); err != nil {
+		return nil, err
+	}
+
+	reviews := make([]models.ProductReview, 0, len(ptrs))
+	for _, review := range ptrs {
+		reviews = append(reviews, *review)
+	}
+	return reviews, nil
+}
+
+// attachPhotos batch-loads photo URLs for the given reviews
+func (r *reviewRepository) attachPhotos(reviews []*models.ProductReview) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(reviews))
+	byID := make(map[int]*models.ProductReview, len(reviews))
+	for i, review := range reviews {
+		ids[i] = review.ID
+		review.Photos = []string{}
+		byID[review.ID] = review
+	}
+
+	rows, err := r.db.Query(`
+		SELECT review_id, image_url FROM product_review_photos
+		WHERE review_id = ANY($1)
+		ORDER BY review_id, position, id
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reviewID int
+		var url string
+		if err := rows.Scan(&reviewID, &url); err != nil {
+			return err
+		}
+		byID[reviewID].Photos = append(byID[reviewID].Photos, url)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"zavera/models"
+)
+
+var (
+	ErrSizeChartNotFound  = errors.New("size chart not found")
+	ErrFitProfileNotFound = errors.New("fit profile not found")
+)
+
+// SizeChartRepository stores size charts and customer fit profiles.
+// Saving a chart relinks every variant's size to the row of the chart that now applies to it
+// (link_variant_sizes); variant and product triggers keep the link current otherwise.
+type SizeChartRepository interface {
+	List(activeOnly bool) ([]models.SizeChart, error)
+	FindByID(id int) (*models.SizeChart, error)
+	// FindForProduct returns the chart that applies to the product, with the product's variants
+	// on their rows. Returns ErrSizeChartNotFound when none applies.
+	FindForProduct(productID int) (*models.SizeChart, error)
+	Create(chart *models.SizeChart) error
+	Update(chart *models.SizeChart) error
+	Delete(id int) error
+
+	GetFitProfile(userID int) (*models.FitProfile, error)
+	SaveFitProfile(profile *models.FitProfile) error
+}
+
+type sizeChartRepository struct {
+	db *sql.DB
+}
+
+func NewSizeChartRepository(db *sql.DB) SizeChartRepository {
+	return &sizeChartRepository{db: db}
+}
+
+const sizeChartColumns = `id, name, category_id, brand, COALESCE(notes, ''), is_active, created_at, updated_at`
+
+const sizeChartRowColumns = `id, chart_id, size_label, position,
+	chest_min_cm, chest_max_cm, waist_min_cm, waist_max_cm, hip_min_cm, hip_max_cm,
+	height_min_cm, height_max_cm, weight_min_kg, weight_max_kg, length_cm`
+
+func scanSizeChart(scanner interface{ Scan(...interface{}) error }) (*models.SizeChart, error) {
+	var c models.SizeChart
+	var categoryID sql.NullInt64
+	var brand sql.NullString
+	err := scanner.Scan(&c.ID, &c.Name, &categoryID, &brand, &c.Notes, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.CategoryID = nullIntPtr(categoryID)
+	if brand.Valid {
+		c.Brand = &brand.String
+	}
+	c.Rows = []models.SizeChartRow{}
+	return &c, nil
+}
+
+func (r *sizeChartRepository) List(activeOnly bool) ([]models.SizeChart, error) {
+	query := "SELECT " + sizeChartColumns + " FROM size_charts"
+	if activeOnly {
+		query += " WHERE is_active = true"
+	}
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charts := []models.SizeChart{}
+	for rows.Next() {
+		c, err := scanSizeChart(rows)
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range charts {
+		if charts[i].Rows, err = r.findRows(charts[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return charts, nil
+}
+
+func (r *sizeChartRepository) FindByID(id int) (*models.SizeChart, error) {
+	chart, err := scanSizeChart(r.db.QueryRow("SELECT "+sizeChartColumns+" FROM size_charts WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSizeChartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if chart.Rows, err = r.findRows(chart.ID); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+func (r *sizeChartRepository) FindForProduct(productID int) (*models.SizeChart, error) {
+	var chartID sql.NullInt64
+	if err := r.db.QueryRow("SELECT resolve_size_chart($1)", productID).Scan(&chartID); err != nil {
+		return nil, err
+	}
+	if !chartID.Valid {
+		return nil, ErrSizeChartNotFound
+	}
+
+	chart, err := r.FindByID(int(chartID.Int64))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, size_chart_row_id FROM product_variants
+		WHERE product_id = $1 AND is_active = true AND size_chart_row_id IS NOT NULL
+		ORDER BY position, id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variantsByRow := make(map[int][]int)
+	for rows.Next() {
+		var variantID, rowID int
+		if err := rows.Scan(&variantID, &rowID); err != nil {
+			return nil, err
+		}
+		variantsByRow[rowID] = append(variantsByRow[rowID], variantID)
+	}
+	for i := range chart.Rows {
+		chart.Rows[i].VariantIDs = variantsByRow[chart.Rows[i].ID]
+	}
+	return chart, rows.Err()
+}
+
+func (r *sizeChartRepository) findRows(chartID int) ([]models.SizeChartRow, error) {
+	rows, err := r.db.Query(
+		"SELECT "+sizeChartRowColumns+" FROM size_chart_rows WHERE chart_id = $1 ORDER BY position, id",
+		chartID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.SizeChartRow{}
+	for rows.Next() {
+		var row models.SizeChartRow
+		err := rows.Scan(
+			&row.ID, &row.ChartID, &row.SizeLabel, &row.Position,
+			&row.ChestMinCm, &row.ChestMaxCm, &row.WaistMinCm, &row.WaistMaxCm, &row.HipMinCm, &row.HipMaxCm,
+			&row.HeightMinCm, &row.HeightMaxCm, &row.WeightMinKg, &row.WeightMaxKg, &row.LengthCm,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (r *sizeChartRepository) Create(chart *models.SizeChart) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO size_charts (name, category_id, brand, notes, is_active)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id, created_at, updated_at
+	`, chart.Name, chart.CategoryID, chart.Brand, chart.Notes, chart.IsActive,
+	).Scan(&chart.ID, &chart.CreatedAt, &chart.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := r.saveRowsTx(tx, chart); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sizeChartRepository) Update(chart *models.SizeChart) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE size_charts
+		SET name = $2, category_id = $3, brand = $4, notes = NULLIF($5, ''), is_active = $6,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`, chart.ID, chart.Name, chart.CategoryID, chart.Brand, chart.Notes, chart.IsActive,
+	).Scan(&chart.CreatedAt, &chart.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrSizeChartNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM size_chart_rows WHERE chart_id = $1", chart.ID); err != nil {
+		return err
+	}
+	if err := r.saveRowsTx(tx, chart); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveRowsTx inserts the chart's rows in order and relinks variant sizes
+func (r *sizeChartRepository) saveRowsTx(tx *sql.Tx, chart *models.SizeChart) error {
+	for i := range chart.Rows {
+		row := &chart.Rows[i]
+		row.ChartID = chart.ID
+		row.Position = i
+		err := tx.QueryRow(`
+			INSERT INTO size_chart_rows (
+				chart_id, size_label, position,
+				chest_min_cm, chest_max_cm, waist_min_cm, waist_max_cm, hip_min_cm, hip_max_cm,
+				height_min_cm, height_max_cm, weight_min_kg, weight_max_kg, length_cm
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING id
+		`, row.ChartID, row.SizeLabel, row.Position,
+			row.ChestMinCm, row.ChestMaxCm, row.WaistMinCm, row.WaistMaxCm, row.HipMinCm, row.HipMaxCm,
+			row.HeightMinCm, row.HeightMaxCm, row.WeightMinKg, row.WeightMaxKg, row.LengthCm,
+		).Scan(&row.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("SELECT link_variant_sizes(NULL)")
+	return err
+}
+
+func (r *sizeChartRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM size_charts WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSizeChartNotFound
+	}
+
+	// Variants of the deleted chart may fall under a broader one
+	if _, err := tx.Exec("SELECT link_variant_sizes(NULL)"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sizeChartRepository) GetFitProfile(userID int) (*models.FitProfile, error) {
+	var p models.FitProfile
+	var usualSizes []byte
+	err := r.db.QueryRow(`
+		SELECT user_id, height_cm, weight_kg, chest_cm, waist_cm, hip_cm, usual_sizes, updated_at
+		FROM customer_fit_profiles WHERE user_id = $1
+	`, userID).Scan(&p.UserID, &p.HeightCm, &p.WeightKg, &p.ChestCm, &p.WaistCm, &p.HipCm, &usualSizes, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrFitProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.UsualSizes = map[string]string{}
+	if len(usualSizes) > 0 {
+		if err := json.Unmarshal(usualSizes, &p.UsualSizes); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func (r *sizeChartRepository) SaveFitProfile(profile *models.FitProfile) error {
+	usualSizes, err := json.Marshal(profile.UsualSizes)
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRow(`
+		INSERT INTO customer_fit_profiles (user_id, height_cm, weight_kg, chest_cm, waist_cm, hip_cm, usual_sizes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			height_cm = EXCLUDED.height_cm,
+			weight_kg = EXCLUDED.weight_kg,
+			chest_cm = EXCLUDED.chest_cm,
+			waist_cm = EXCLUDED.waist_cm,
+			hip_cm = EXCLUDED.hip_cm,
+			usual_sizes = EXCLUDED.usual_sizes,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`, profile.UserID, profile.HeightCm, profile.WeightKg, profile.ChestCm, profile.WaistCm, profile.HipCm, usualSizes,
+	).Scan(&profile.UpdatedAt)
+}
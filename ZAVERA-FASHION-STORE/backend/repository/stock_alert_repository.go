@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrStockAlertNotFound = errors.New("stock alert not found")
+
+// StockAlertRepository finds who to tell when wishlisted or subscribed items come back in stock
+// or get cheaper. Sending (and its dedup/rate limit) is the email service's job.
+type StockAlertRepository interface {
+	// Subscriptions to sold-out variants
+	Subscribe(userID, variantID int) (*models.StockAlert, error)
+	FindByUser(userID int) ([]models.StockAlert, error)
+	Cancel(id, userID int) error
+	// FindDue returns active subscriptions whose variant is available again
+	FindDue(limit int) ([]models.DueStockAlert, error)
+	MarkNotified(id int) error
+
+	// SyncAvailability records every active variant's available quantity and returns those that
+	// changed since the previous call. Wishlists of a variant that came back in stock get a
+	// restock alert queued, except for users subscribed to the variant (they hear about it
+	// through FindDue).
+	SyncAvailability() ([]models.VariantAvailabilityChange, error)
+	// FindDueWishlistRestocks returns queued wishlist alerts whose variant is still available
+	FindDueWishlistRestocks(limit int) ([]models.DueWishlistRestock, error)
+	MarkWishlistRestockSent(id int) error
+
+	// FindPriceDrops returns wishlist entries whose product is now below the price seen
+	FindPriceDrops(limit int) ([]models.PriceDrop, error)
+	UpdatePriceSeen(wishlistID int, price float64) error
+}
+
+type stockAlertRepository struct {
+	db *sql.DB
+}
+
+func NewStockAlertRepository(db *sql.DB) StockAlertRepository {
+	return &stockAlertRepository{db: db}
+}
+
+// recipientName prefers the first name for the greeting
+const recipientName = "COALESCE(NULLIF(u.first_name, ''), NULLIF(u.name, ''), u.email)"
+
+const stockAlertColumns = `
+	sa.id, sa.user_id, sa.variant_id, pv.product_id, p.name, pv.variant_name, pv.sku,
+	sa.status, get_available_stock(pv.id), sa.notified_at, sa.created_at`
+
+func scanStockAlert(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	var notifiedAt sql.NullTime
+	dest := []interface{}{
+		&alert.ID, &alert.UserID, &alert.VariantID, &alert.ProductID, &alert.ProductName,
+		&alert.VariantName, &alert.SKU, &alert.Status, &alert.Available, &notifiedAt, &alert.CreatedAt,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if notifiedAt.Valid {
+		alert.NotifiedAt = &notifiedAt.Time
+	}
+	return &alert, nil
+}
+
+// Subscribe is idempotent: an existing active subscription is returned as is
+func (r *stockAlertRepository) Subscribe(userID, variantID int) (*models.StockAlert, error) {
+	_, err := r.db.Exec(`
+		INSERT INTO stock_alerts (user_id, variant_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, variant_id) WHERE status = 'ACTIVE' DO NOTHING
+	`, userID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRow(`
+		SELECT `+stockAlertColumns+`
+		FROM stock_alerts sa
+		JOIN product_variants pv ON pv.id = sa.variant_id
+		JOIN products p ON p.id = pv.product_id
+		WHERE sa.user_id = $1 AND sa.variant_id = $2 AND sa.status = 'ACTIVE'
+	`, userID, variantID)
+	return scanStockAlert(row)
+}
+
+func (r *stockAlertRepository) FindByUser(userID int) ([]models.StockAlert, error) {
+	rows, err := r.db.Query(`
+		SELECT `+stockAlertColumns+`
+		FROM stock_alerts sa
+		JOIN product_variants pv ON pv.id = sa.variant_id
+		JOIN products p ON p.id = pv.product_id
+		WHERE sa.user_id = $1 AND sa.status <> 'CANCELLED'
+		ORDER BY sa.created_at DESC
+		LIMIT 100
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.StockAlert{}
+	for rows.Next() {
+		alert, err := scanStockAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, *alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *stockAlertRepository) Cancel(id, userID int) error {
+	result, err := r.db.Exec(`
+		UPDATE stock_alerts SET status = 'CANCELLED'
+		WHERE id = $1 AND user_id = $2 AND status = 'ACTIVE'
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrStockAlertNotFound
+	}
+	return nil
+}
+
+func (r *stockAlertRepository) FindDue(limit int) ([]models.DueStockAlert, error) {
+	rows, err := r.db.Query(`
+		SELECT `+stockAlertColumns+`, u.email, `+recipientName+`, COALESCE(pv.price, p.price)
+		FROM stock_alerts sa
+		JOIN product_variants pv ON pv.id = sa.variant_id
+		JOIN products p ON p.id = pv.product_id
+		JOIN users u ON u.id = sa.user_id
+		WHERE sa.status = 'ACTIVE'
+		AND pv.is_active = true AND p.is_active = true
+		AND get_available_stock(pv.id) > 0
+		ORDER BY sa.created_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueStockAlert
+	for rows.Next() {
+		var d models.DueStockAlert
+		alert, err := scanStockAlert(rows, &d.Recipient.Email, &d.Recipient.Name, &d.Price)
+		if err != nil {
+			return nil, err
+		}
+		d.StockAlert = *alert
+		d.Recipient.UserID = alert.UserID
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+func (r *stockAlertRepository) MarkNotified(id int) error {
+	_, err := r.db.Exec(`
+		UPDATE stock_alerts SET status = 'NOTIFIED', notified_at = NOW()
+		WHERE id = $1 AND status = 'ACTIVE'
+	`, id)
+	return err
+}
+
+func (r *stockAlertRepository) SyncAvailability() ([]models.VariantAvailabilityChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		WITH current AS (
+			SELECT pv.id, pv.product_id, p.name, pv.variant_name,
+			       COALESCE(pv.price, p.price) as price, get_available_stock(pv.id) as available
+			FROM product_variants pv
+			JOIN products p ON p.id = pv.product_id
+			WHERE pv.is_active = true AND p.is_active = true
+		)
+		SELECT c.id, c.product_id, c.name, c.variant_name, c.price, s.last_available, c.available
+		FROM current c
+		LEFT JOIN variant_availability_state s ON s.variant_id = c.id
+		WHERE s.last_available IS DISTINCT FROM c.available
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []models.VariantAvailabilityChange
+	var variantIDs, available, restocked []int64
+	for rows.Next() {
+		var c models.VariantAvailabilityChange
+		var previous sql.NullInt64
+		if err := rows.Scan(&c.VariantID, &c.ProductID, &c.ProductName, &c.VariantName, &c.Price, &previous, &c.Available); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Previous = nullIntPtr(previous)
+		changes = append(changes, c)
+		variantIDs = append(variantIDs, int64(c.VariantID))
+		available = append(available, int64(c.Available))
+		if c.IsRestock() {
+			restocked = append(restocked, int64(c.VariantID))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO variant_availability_state (variant_id, last_available)
+		SELECT * FROM unnest($1::int[], $2::int[])
+		ON CONFLICT (variant_id) DO UPDATE
+		SET last_available = EXCLUDED.last_available, checked_at = NOW()
+	`, pq.Array(variantIDs), pq.Array(available))
+	if err != nil {
+		return nil, err
+	}
+
+	// Queued with the new state, so a restock is never recorded without its alerts. A wishlist
+	// already waiting keeps one alert, for the variant restocked last.
+	if len(restocked) > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO wishlist_restock_alerts (wishlist_id, variant_id)
+			SELECT DISTINCT ON (w.id) w.id, pv.id
+			FROM product_variants pv
+			JOIN wishlists w ON w.product_id = pv.product_id
+			WHERE pv.id = ANY($1)
+			AND NOT EXISTS (
+				SELECT 1 FROM stock_alerts sa
+				WHERE sa.user_id = w.user_id AND sa.variant_id = pv.id AND sa.status = 'ACTIVE'
+			)
+			ORDER BY w.id, pv.id
+			ON CONFLICT (wishlist_id) DO UPDATE
+			SET variant_id = EXCLUDED.variant_id, queued_at = NOW()
+		`, pq.Array(restocked))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *stockAlertRepository) FindDueWishlistRestocks(limit int) ([]models.DueWishlistRestock, error) {
+	rows, err := r.db.Query(`
+		SELECT wra.id, u.id, u.email, `+recipientName+`, p.id, p.name, pv.variant_name, COALESCE(pv.price, p.price)
+		FROM wishlist_restock_alerts wra
+		JOIN wishlists w ON w.id = wra.wishlist_id
+		JOIN users u ON u.id = w.user_id
+		JOIN product_variants pv ON pv.id = wra.variant_id
+		JOIN products p ON p.id = pv.product_id
+		WHERE pv.is_active = true AND p.is_active = true
+		AND get_available_stock(pv.id) > 0
+		ORDER BY wra.queued_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueWishlistRestock
+	for rows.Next() {
+		var d models.DueWishlistRestock
+		err := rows.Scan(&d.ID, &d.Recipient.UserID, &d.Recipient.Email, &d.Recipient.Name,
+			&d.ProductID, &d.ProductName, &d.VariantName, &d.Price)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+func (r *stockAlertRepository) MarkWishlistRestockSent(id int) error {
+	_, err := r.db.Exec("DELETE FROM wishlist_restock_alerts WHERE id = $1", id)
+	return err
+}
+
+func (r *stockAlertRepository) FindPriceDrops(limit int) ([]models.PriceDrop, error) {
+	rows, err := r.db.Query(`
+		SELECT w.id, u.id, u.email, `+recipientName+`, p.id, p.name, w.price_seen, p.price
+		FROM wishlists w
+		JOIN products p ON p.id = w.product_id
+		JOIN users u ON u.id = w.user_id
+		WHERE p.is_active = true
+		AND w.price_seen IS NOT NULL
+		AND p.price < w.price_seen
+		ORDER BY w.id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drops []models.PriceDrop
+	for rows.Next() {
+		var d models.PriceDrop
+		err := rows.Scan(&d.WishlistID, &d.Recipient.UserID, &d.Recipient.Email, &d.Recipient.Name,
+			&d.ProductID, &d.ProductName, &d.PriceSeen, &d.Price)
+		if err != nil {
+			return nil, err
+		}
+		drops = append(drops, d)
+	}
+	return drops, rows.Err()
+}
+
+func (r *stockAlertRepository) UpdatePriceSeen(wishlistID int, price float64) error {
+	_, err := r.db.Exec("UPDATE wishlists SET price_seen = $2 WHERE id = $1", wishlistID, price)
+	return err
+}
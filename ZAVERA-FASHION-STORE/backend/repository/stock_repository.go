@@ -2,25 +2,48 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInsufficientStock    = errors.New("insufficient stock")
+	ErrStockAlreadyRestored = errors.New("stock already restored")
 )
 
-// StockRepository handles stock movement operations
+// StockRepository writes and reads the variant/warehouse stock ledger.
+// Every on-hand change goes through apply_stock_movement(), which appends the ledger entry and
+// refreshes warehouse_stock, product_variants.stock_quantity and the deprecated products.stock.
 type StockRepository interface {
-	// RecordMovement records a stock movement and returns the new balance
-	RecordMovement(productID int, orderID *int, movementType models.StockMovementType, quantity int, notes string) (*models.StockMovement, error)
-	
-	// RecordMovementTx records a stock movement within a transaction
-	RecordMovementTx(tx *sql.Tx, productID int, orderID *int, movementType models.StockMovementType, quantity int, notes string) (*models.StockMovement, error)
-	
-	// GetMovementsByProduct returns all movements for a product
-	GetMovementsByProduct(productID int) ([]models.StockMovement, error)
-	
-	// GetMovementsByOrder returns all movements for an order
-	GetMovementsByOrder(orderID int) ([]models.StockMovement, error)
-	
-	// GetCurrentStock returns the current stock for a product
-	GetCurrentStock(productID int) (int, error)
+	// ApplyMovement records a ledger entry and moves on-hand stock by entry.Quantity.
+	// A nil WarehouseID means the default warehouse. ID, ProductID, WarehouseID, BalanceAfter and
+	// CreatedAt are filled in. Returns ErrInsufficientStock when the warehouse would go negative.
+	ApplyMovement(entry *models.StockLedgerEntry) error
+
+	// ApplyMovementTx records a ledger entry within a transaction
+	ApplyMovementTx(tx *sql.Tx, entry *models.StockLedgerEntry) error
+
+	// DefaultVariantTx returns the variant that carries the stock of a product when only the
+	// product is known, creating a 'Default' variant for products without any
+	DefaultVariantTx(tx *sql.Tx, productID int) (int, error)
+
+	// RestockRefundItem puts a refunded order item back on the shelf it shipped from and marks it
+	// restored. Returns ErrStockAlreadyRestored when it was done before.
+	RestockRefundItem(refundItemID int, actor string) error
+
+	// ListLedger returns ledger entries, newest first
+	ListLedger(filter models.StockLedgerFilter, limit, offset int) ([]models.StockLedgerEntry, int, error)
+
+	// Reconciliation
+	FindDiscrepancies() ([]models.StockDiscrepancy, error)
+	FindBalanceBreaks(limit int) ([]models.LedgerBalanceBreak, error)
+	// RebuildOnHand sets warehouse_stock from the ledger sums, then the variant totals and product
+	// mirror from warehouse_stock. Returns how many warehouse rows and products changed.
+	RebuildOnHand() (int, int, error)
 }
 
 type stockRepository struct {
@@ -32,145 +55,365 @@ func NewStockRepository(db *sql.DB) StockRepository {
 	return &stockRepository{db: db}
 }
 
-// RecordMovement records a stock movement
-func (r *stockRepository) RecordMovement(productID int, orderID *int, movementType models.StockMovementType, quantity int, notes string) (*models.StockMovement, error) {
-	// Get current stock balance
-	var currentStock int
-	err := r.db.QueryRow("SELECT stock FROM products WHERE id = $1", productID).Scan(&currentStock)
-	if err != nil {
-		return nil, err
+const stockLedgerColumns = `id, variant_id, product_id, warehouse_id, movement_type, quantity, balance_after,
+	order_id, COALESCE(reference, ''), COALESCE(notes, ''), actor, created_at`
+
+func scanStockLedgerEntry(scanner interface{ Scan(...interface{}) error }, e *models.StockLedgerEntry, extra ...interface{}) error {
+	var warehouseID, orderID sql.NullInt64
+	dest := []interface{}{
+		&e.ID, &e.VariantID, &e.ProductID, &warehouseID, &e.MovementType, &e.Quantity, &e.BalanceAfter,
+		&orderID, &e.Reference, &e.Notes, &e.Actor, &e.CreatedAt,
 	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	e.WarehouseID = nullIntPtr(warehouseID)
+	e.OrderID = nullIntPtr(orderID)
+	return nil
+}
+
+// isInsufficientStock matches the check_violation raised by apply_stock_movement()
+func isInsufficientStock(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23514"
+}
 
-	// Calculate balance after movement
-	balanceAfter := currentStock
-	switch movementType {
-	case models.StockMovementReserve, models.StockMovementDeduct:
-		balanceAfter = currentStock - quantity
-	case models.StockMovementRelease:
-		balanceAfter = currentStock + quantity
+func (r *stockRepository) ApplyMovement(entry *models.StockLedgerEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	// Insert movement record
-	query := `
-		INSERT INTO stock_movements (product_id, order_id, movement_type, quantity, balance_after, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
-	`
+	if err := r.ApplyMovementTx(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	movement := &models.StockMovement{
-		ProductID:    productID,
-		OrderID:      orderID,
-		MovementType: movementType,
-		Quantity:     quantity,
-		BalanceAfter: balanceAfter,
-		Notes:        notes,
+func (r *stockRepository) ApplyMovementTx(tx *sql.Tx, entry *models.StockLedgerEntry) error {
+	if entry.Quantity == 0 {
+		return fmt.Errorf("stock movement quantity must not be zero")
 	}
 
-	err = r.db.QueryRow(query, productID, orderID, movementType, quantity, balanceAfter, notes).
-		Scan(&movement.ID, &movement.CreatedAt)
-	if err != nil {
-		return nil, err
+	query := "SELECT " + stockLedgerColumns + " FROM apply_stock_movement($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)"
+	err := scanStockLedgerEntry(tx.QueryRow(query,
+		entry.VariantID, entry.WarehouseID, entry.MovementType, entry.Quantity,
+		entry.OrderID, entry.Reference, entry.Notes, entry.Actor,
+	), entry)
+	if isInsufficientStock(err) {
+		return ErrInsufficientStock
 	}
+	return err
+}
 
-	return movement, nil
+func (r *stockRepository) DefaultVariantTx(tx *sql.Tx, productID int) (int, error) {
+	var variantID int
+	err := tx.QueryRow("SELECT ensure_default_variant($1)", productID).Scan(&variantID)
+	return variantID, err
 }
 
-// RecordMovementTx records a stock movement within a transaction
-func (r *stockRepository) RecordMovementTx(tx *sql.Tx, productID int, orderID *int, movementType models.StockMovementType, quantity int, notes string) (*models.StockMovement, error) {
-	// Get current stock balance (after any updates in this transaction)
-	var currentStock int
-	err := tx.QueryRow("SELECT stock FROM products WHERE id = $1", productID).Scan(&currentStock)
+func (r *stockRepository) RestockRefundItem(refundItemID int, actor string) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	// Balance after is the current stock (which already reflects the change)
-	balanceAfter := currentStock
+	var restored bool
+	var quantity, backordered, productID, orderItemID int
+	var variantID, warehouseID sql.NullInt64
+	var orderID int
+	var refundCode string
+	// A bundle component goes back as its own variant; bundles are never backordered
+	err = tx.QueryRow(`
+		SELECT COALESCE(ri.stock_restored, false), ri.quantity,
+		       CASE WHEN c.id IS NULL THEN COALESCE(oi.backordered_quantity, 0) ELSE 0 END,
+		       oi.id, COALESCE(c.product_id, oi.product_id),
+		       CASE WHEN c.id IS NULL THEN oi.variant_id ELSE cv.id END,
+		       o.id, o.warehouse_id, r.refund_code
+		FROM refund_items ri
+		JOIN refunds r ON r.id = ri.refund_id
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN order_item_components c ON c.id = ri.order_item_component_id
+		LEFT JOIN product_variants cv ON cv.id = c.variant_id
+		WHERE ri.id = $1
+		FOR UPDATE OF ri, oi
+	`, refundItemID).Scan(&restored, &quantity, &backordered, &orderItemID, &productID, &variantID,
+		&orderID, &warehouseID, &refundCode)
+	if err != nil {
+		return err
+	}
+	if restored {
+		return ErrStockAlreadyRestored
+	}
 
-	// Insert movement record
-	query := `
-		INSERT INTO stock_movements (product_id, order_id, movement_type, quantity, balance_after, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
-	`
+	// Refunded units come off the backorder first; those never left the shelf
+	if backordered > 0 {
+		cancelled := backordered
+		if quantity < cancelled {
+			cancelled = quantity
+		}
+		quantity -= cancelled
+		_, err = tx.Exec(`
+			UPDATE order_items SET backordered_quantity = backordered_quantity - $2 WHERE id = $1
+		`, orderItemID, cancelled)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE orders SET awaiting_stock = EXISTS (
+				SELECT 1 FROM order_items WHERE order_id = $1 AND backordered_quantity > 0
+			)
+			WHERE id = $1
+		`, orderID)
+		if err != nil {
+			return err
+		}
+	}
 
-	movement := &models.StockMovement{
-		ProductID:    productID,
-		OrderID:      orderID,
-		MovementType: movementType,
+	entry := &models.StockLedgerEntry{
+		MovementType: models.StockMovementRestock,
 		Quantity:     quantity,
-		BalanceAfter: balanceAfter,
-		Notes:        notes,
+		WarehouseID:  nullIntPtr(warehouseID),
+		OrderID:      &orderID,
+		Reference:    refundCode,
+		Notes:        "Refunded item restocked",
+		Actor:        actor,
+	}
+	if variantID.Valid {
+		entry.VariantID = int(variantID.Int64)
+	} else if entry.VariantID, err = r.DefaultVariantTx(tx, productID); err != nil {
+		return err
+	}
+	if quantity > 0 {
+		if err := r.ApplyMovementTx(tx, entry); err != nil {
+			return err
+		}
 	}
 
-	err = tx.QueryRow(query, productID, orderID, movementType, quantity, balanceAfter, notes).
-		Scan(&movement.ID, &movement.CreatedAt)
+	_, err = tx.Exec(`
+		UPDATE refund_items SET stock_restored = true, stock_restored_at = NOW()
+		WHERE id = $1
+	`, refundItemID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *stockRepository) ListLedger(filter models.StockLedgerFilter, limit, offset int) ([]models.StockLedgerEntry, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+	argCount := 0
+
+	addCondition := func(column string, value interface{}) {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("%s = $%d", column, argCount))
+		args = append(args, value)
+	}
+	if filter.VariantID > 0 {
+		addCondition("sl.variant_id", filter.VariantID)
+	}
+	if filter.ProductID > 0 {
+		addCondition("sl.product_id", filter.ProductID)
+	}
+	if filter.WarehouseID > 0 {
+		addCondition("sl.warehouse_id", filter.WarehouseID)
+	}
+	if filter.OrderID > 0 {
+		addCondition("sl.order_id", filter.OrderID)
+	}
+	if filter.MovementType != "" {
+		addCondition("sl.movement_type", filter.MovementType)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM stock_ledger sl "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Variants may have been deleted since; the ledger keeps their entries
+	query := fmt.Sprintf(`
+		SELECT sl.id, sl.variant_id, sl.product_id, sl.warehouse_id, sl.movement_type, sl.quantity, sl.balance_after,
+		       sl.order_id, COALESCE(sl.reference, ''), COALESCE(sl.notes, ''), sl.actor, sl.created_at,
+		       COALESCE(pv.sku, ''), COALESCE(p.name, ''), w.code
+		FROM stock_ledger sl
+		JOIN warehouses w ON w.id = sl.warehouse_id
+		LEFT JOIN product_variants pv ON pv.id = sl.variant_id
+		LEFT JOIN products p ON p.id = sl.product_id
+		%s
+		ORDER BY sl.id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argCount+1, argCount+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer rows.Close()
 
-	return movement, nil
+	entries := []models.StockLedgerEntry{}
+	for rows.Next() {
+		var e models.StockLedgerEntry
+		if err := scanStockLedgerEntry(rows, &e, &e.SKU, &e.ProductName, &e.WarehouseCode); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
 }
 
-// GetMovementsByProduct returns all movements for a product
-func (r *stockRepository) GetMovementsByProduct(productID int) ([]models.StockMovement, error) {
+// ledgerBalancesQuery is the on-hand stock per warehouse and variant according to the ledger,
+// for variants that still exist
+const ledgerBalancesQuery = `
+	SELECT sl.warehouse_id, sl.variant_id, SUM(sl.quantity)::INT AS balance
+	FROM stock_ledger sl
+	JOIN product_variants pv ON pv.id = sl.variant_id
+	GROUP BY sl.warehouse_id, sl.variant_id
+`
+
+func (r *stockRepository) FindDiscrepancies() ([]models.StockDiscrepancy, error) {
 	query := `
-		SELECT id, product_id, order_id, movement_type, quantity, balance_after, notes, created_at
-		FROM stock_movements
-		WHERE product_id = $1
-		ORDER BY created_at DESC
+		WITH ledger AS (` + ledgerBalancesQuery + `)
+		SELECT COALESCE(ws.warehouse_id, l.warehouse_id), COALESCE(ws.variant_id, l.variant_id),
+		       pv.sku, COALESCE(ws.quantity, 0), COALESCE(l.balance, 0)
+		FROM warehouse_stock ws
+		FULL OUTER JOIN ledger l ON l.warehouse_id = ws.warehouse_id AND l.variant_id = ws.variant_id
+		JOIN product_variants pv ON pv.id = COALESCE(ws.variant_id, l.variant_id)
+		WHERE COALESCE(ws.quantity, 0) <> COALESCE(l.balance, 0)
+		ORDER BY 1, 2
 	`
-
-	rows, err := r.db.Query(query, productID)
+	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var movements []models.StockMovement
+	discrepancies := []models.StockDiscrepancy{}
 	for rows.Next() {
-		var m models.StockMovement
-		err := rows.Scan(&m.ID, &m.ProductID, &m.OrderID, &m.MovementType, &m.Quantity, &m.BalanceAfter, &m.Notes, &m.CreatedAt)
-		if err != nil {
-			continue
+		var d models.StockDiscrepancy
+		if err := rows.Scan(&d.WarehouseID, &d.VariantID, &d.SKU, &d.OnHand, &d.LedgerBalance); err != nil {
+			return nil, err
 		}
-		movements = append(movements, m)
+		discrepancies = append(discrepancies, d)
 	}
-
-	return movements, nil
+	return discrepancies, rows.Err()
 }
 
-// GetMovementsByOrder returns all movements for an order
-func (r *stockRepository) GetMovementsByOrder(orderID int) ([]models.StockMovement, error) {
+func (r *stockRepository) FindBalanceBreaks(limit int) ([]models.LedgerBalanceBreak, error) {
 	query := `
-		SELECT id, product_id, order_id, movement_type, quantity, balance_after, notes, created_at
-		FROM stock_movements
-		WHERE order_id = $1
-		ORDER BY created_at DESC
+		SELECT id, warehouse_id, variant_id, balance_after, running_total
+		FROM (
+			SELECT id, warehouse_id, variant_id, balance_after,
+			       SUM(quantity) OVER (PARTITION BY warehouse_id, variant_id ORDER BY id)::INT AS running_total
+			FROM stock_ledger
+		) l
+		WHERE balance_after <> running_total
+		ORDER BY id
+		LIMIT $1
 	`
-
-	rows, err := r.db.Query(query, orderID)
+	rows, err := r.db.Query(query, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var movements []models.StockMovement
+	breaks := []models.LedgerBalanceBreak{}
 	for rows.Next() {
-		var m models.StockMovement
-		err := rows.Scan(&m.ID, &m.ProductID, &m.OrderID, &m.MovementType, &m.Quantity, &m.BalanceAfter, &m.Notes, &m.CreatedAt)
-		if err != nil {
-			continue
+		var b models.LedgerBalanceBreak
+		if err := rows.Scan(&b.EntryID, &b.WarehouseID, &b.VariantID, &b.BalanceAfter, &b.RunningTotal); err != nil {
+			return nil, err
 		}
-		movements = append(movements, m)
+		breaks = append(breaks, b)
 	}
-
-	return movements, nil
+	return breaks, rows.Err()
 }
 
-// GetCurrentStock returns the current stock for a product
-func (r *stockRepository) GetCurrentStock(productID int) (int, error) {
-	var stock int
-	err := r.db.QueryRow("SELECT stock FROM products WHERE id = $1", productID).Scan(&stock)
-	return stock, err
+func (r *stockRepository) RebuildOnHand() (int, int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Writes below are derived from the ledger; keep the legacy triggers from re-recording them
+	if _, err := tx.Exec("SELECT set_config('zavera.stock_ledger', 'on', true)"); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec("LOCK TABLE warehouse_stock IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return 0, 0, err
+	}
+
+	result, err := tx.Exec(`
+		WITH ledger AS (` + ledgerBalancesQuery + `)
+		INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+		SELECT warehouse_id, variant_id, balance FROM ledger
+		ON CONFLICT (warehouse_id, variant_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+		WHERE warehouse_stock.quantity <> EXCLUDED.quantity
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	rebuilt, _ := result.RowsAffected()
+
+	// Rows the ledger knows nothing about hold no stock
+	result, err = tx.Exec(`
+		UPDATE warehouse_stock ws SET quantity = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE ws.quantity <> 0
+		AND NOT EXISTS (
+			SELECT 1 FROM stock_ledger sl WHERE sl.warehouse_id = ws.warehouse_id AND sl.variant_id = ws.variant_id
+		)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	zeroed, _ := result.RowsAffected()
+
+	_, err = tx.Exec(`
+		UPDATE product_variants pv
+		SET stock_quantity = t.total, updated_at = CURRENT_TIMESTAMP
+		FROM (
+			SELECT pv2.id, COALESCE(SUM(ws.quantity), 0)::INT AS total
+			FROM product_variants pv2
+			LEFT JOIN warehouse_stock ws ON ws.variant_id = pv2.id
+			GROUP BY pv2.id
+		) t
+		WHERE pv.id = t.id AND pv.stock_quantity <> t.total
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Bundles mirror the complete sets their components make up
+	result, err = tx.Exec(`
+		UPDATE products p
+		SET stock = t.total
+		FROM (
+			SELECT p2.id, CASE WHEN p2.is_bundle THEN get_bundle_available_stock(p2.id)
+			       ELSE COALESCE(SUM(pv.stock_quantity) FILTER (WHERE pv.is_active), 0)::INT END AS total
+			FROM products p2
+			LEFT JOIN product_variants pv ON pv.product_id = p2.id
+			GROUP BY p2.id
+		) t
+		WHERE p.id = t.id AND p.stock <> t.total
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	products, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return int(rebuilt + zeroed), int(products), nil
 }
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+	"zavera/models"
+)
+
+// createLedgerTestVariant creates a product without stock and returns its default variant
+func createLedgerTestVariant(t *testing.T, db *sql.DB) (productID, variantID int) {
+	slug := "ledger-test-" + generateRandomString(8)
+	err := db.QueryRow(`INSERT INTO products (name, slug, price, stock) VALUES ($1, $2, 100000, 0) RETURNING id`,
+		"Ledger Test", slug).Scan(&productID)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if err := db.QueryRow("SELECT ensure_default_variant($1)", productID).Scan(&variantID); err != nil {
+		t.Fatalf("Failed to create variant: %v", err)
+	}
+	return productID, variantID
+}
+
+// cleanupLedgerTestProduct removes the test product; its ledger entries stay, as history does
+func cleanupLedgerTestProduct(t *testing.T, db *sql.DB, productID int) {
+	if _, err := db.Exec("DELETE FROM product_variants WHERE product_id = $1", productID); err != nil {
+		t.Logf("Warning: Failed to cleanup variants: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM products WHERE id = $1", productID); err != nil {
+		t.Logf("Warning: Failed to cleanup product: %v", err)
+	}
+}
+
+func defaultWarehouseStock(t *testing.T, db *sql.DB, variantID int) (onHand, variantTotal int) {
+	err := db.QueryRow(`
+		SELECT COALESCE((SELECT ws.quantity FROM warehouse_stock ws
+		                 JOIN warehouses w ON w.id = ws.warehouse_id AND w.is_default
+		                 WHERE ws.variant_id = pv.id), 0),
+		       pv.stock_quantity
+		FROM product_variants pv WHERE pv.id = $1
+	`, variantID).Scan(&onHand, &variantTotal)
+	if err != nil {
+		t.Fatalf("Failed to read stock: %v", err)
+	}
+	return onHand, variantTotal
+}
+
+func TestStockRepository_ApplyMovement(t *testing.T) {
+	db := getTestDB(t)
+	if db == nil {
+		t.Skip("Skipping test: no test database available")
+	}
+	defer db.Close()
+
+	repo := NewStockRepository(db)
+	productID, variantID := createLedgerTestVariant(t, db)
+	defer cleanupLedgerTestProduct(t, db, productID)
+
+	entry := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     5,
+		Notes:        "test stock",
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(entry); err != nil {
+		t.Fatalf("Failed to apply movement: %v", err)
+	}
+	if entry.ID == 0 || entry.ProductID != productID || entry.WarehouseID == nil {
+		t.Errorf("Expected entry to be filled in, got %+v", entry)
+	}
+	if entry.BalanceAfter != 5 {
+		t.Errorf("Expected balance after 5, got %d", entry.BalanceAfter)
+	}
+
+	reserve := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementReserve,
+		Quantity:     -2,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(reserve); err != nil {
+		t.Fatalf("Failed to reserve: %v", err)
+	}
+	if reserve.BalanceAfter != 3 {
+		t.Errorf("Expected balance after 3, got %d", reserve.BalanceAfter)
+	}
+
+	onHand, total := defaultWarehouseStock(t, db, variantID)
+	if onHand != 3 || total != 3 {
+		t.Errorf("Expected on hand and variant total 3, got %d and %d", onHand, total)
+	}
+
+	// Going below zero is rejected and changes nothing
+	oversell := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementReserve,
+		Quantity:     -4,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(oversell); err != ErrInsufficientStock {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+	if err := repo.ApplyMovement(&models.StockLedgerEntry{VariantID: variantID, MovementType: models.StockMovementAdjustment}); err == nil {
+		t.Error("Expected a zero quantity movement to be rejected")
+	}
+	if onHand, _ := defaultWarehouseStock(t, db, variantID); onHand != 3 {
+		t.Errorf("Expected on hand to stay 3, got %d", onHand)
+	}
+
+	// Bring the product back to zero so the ledger balances after cleanup
+	release := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     -3,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(release); err != nil {
+		t.Fatalf("Failed to zero stock: %v", err)
+	}
+}
+
+func TestStockRepository_ApplyMovementTx_RollsBack(t *testing.T) {
+	db := getTestDB(t)
+	if db == nil {
+		t.Skip("Skipping test: no test database available")
+	}
+	defer db.Close()
+
+	repo := NewStockRepository(db)
+	productID, variantID := createLedgerTestVariant(t, db)
+	defer cleanupLedgerTestProduct(t, db, productID)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	entry := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     7,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovementTx(tx, entry); err != nil {
+		tx.Rollback()
+		t.Fatalf("Failed to apply movement: %v", err)
+	}
+	tx.Rollback()
+
+	var entries int
+	db.QueryRow("SELECT COUNT(*) FROM stock_ledger WHERE variant_id = $1", variantID).Scan(&entries)
+	if entries != 0 {
+		t.Errorf("Expected no ledger entries after rollback, got %d", entries)
+	}
+	if onHand, total := defaultWarehouseStock(t, db, variantID); onHand != 0 || total != 0 {
+		t.Errorf("Expected no stock after rollback, got %d and %d", onHand, total)
+	}
+}
+
+func TestStockRepository_Reconcile(t *testing.T) {
+	db := getTestDB(t)
+	if db == nil {
+		t.Skip("Skipping test: no test database available")
+	}
+	defer db.Close()
+
+	repo := NewStockRepository(db)
+	productID, variantID := createLedgerTestVariant(t, db)
+	defer cleanupLedgerTestProduct(t, db, productID)
+
+	entry := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     4,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(entry); err != nil {
+		t.Fatalf("Failed to apply movement: %v", err)
+	}
+
+	// Drift on-hand stock away from the ledger behind its back
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	tx.Exec("SELECT set_config('zavera.stock_ledger', 'on', true)")
+	_, err = tx.Exec("UPDATE warehouse_stock SET quantity = 9 WHERE warehouse_id = $1 AND variant_id = $2",
+		*entry.WarehouseID, variantID)
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("Failed to drift stock: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit drift: %v", err)
+	}
+
+	found := findDiscrepancy(t, repo, variantID)
+	if found == nil {
+		t.Fatal("Expected a discrepancy for the drifted variant")
+	}
+	if found.OnHand != 9 || found.LedgerBalance != 4 {
+		t.Errorf("Expected on hand 9 against ledger 4, got %d against %d", found.OnHand, found.LedgerBalance)
+	}
+
+	rebuilt, _, err := repo.RebuildOnHand()
+	if err != nil {
+		t.Fatalf("Failed to rebuild: %v", err)
+	}
+	if rebuilt < 1 {
+		t.Errorf("Expected at least one warehouse row rebuilt, got %d", rebuilt)
+	}
+	if findDiscrepancy(t, repo, variantID) != nil {
+		t.Error("Expected no discrepancy after the rebuild")
+	}
+	if onHand, total := defaultWarehouseStock(t, db, variantID); onHand != 4 || total != 4 {
+		t.Errorf("Expected on hand and variant total 4 after the rebuild, got %d and %d", onHand, total)
+	}
+
+	// The rebuild only touches derived counts; the ledger itself stays consistent
+	breaks, err := repo.FindBalanceBreaks(100)
+	if err != nil {
+		t.Fatalf("Failed to find balance breaks: %v", err)
+	}
+	for _, b := range breaks {
+		if b.VariantID == variantID {
+			t.Errorf("Unexpected balance break %+v", b)
+		}
+	}
+
+	release := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     -4,
+		Actor:        "test",
+	}
+	if err := repo.ApplyMovement(release); err != nil {
+		t.Fatalf("Failed to zero stock: %v", err)
+	}
+}
+
+func findDiscrepancy(t *testing.T, repo StockRepository, variantID int) *models.StockDiscrepancy {
+	discrepancies, err := repo.FindDiscrepancies()
+	if err != nil {
+		t.Fatalf("Failed to find discrepancies: %v", err)
+	}
+	for i := range discrepancies {
+		if discrepancies[i].VariantID == variantID {
+			return &discrepancies[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrStockTakeNotFound     = errors.New("stock take not found")
+	ErrStockTakeOpen         = errors.New("warehouse already has an open stock take")
+	ErrStockTakeConflict     = errors.New("stock take status changed")
+	ErrStockTakeLineNotFound = errors.New("variant is not on this stock take")
+)
+
+// StockTakeRepository stores count sessions. Posting runs in a transaction owned by the caller
+// so the ledger entries and audit entries commit together with the session.
+type StockTakeRepository interface {
+	// Create starts a COUNTING session and freezes the expected quantity of every active variant
+	// (only those of productIDs when given) at the warehouse
+	Create(take *models.StockTake, productIDs []int) error
+	FindByID(id int) (*models.StockTake, error)
+	List(warehouseID int, status models.StockTakeStatus, limit, offset int) ([]models.StockTake, int, error)
+	FindLines(id int, variancesOnly bool) ([]models.StockTakeLine, error)
+
+	// RecordCounts sets (or, for scans, adds to) counted quantities while the session is
+	// COUNTING. Returns the lines as counted.
+	RecordCounts(id int, counts []models.StockTakeCount, actor string) ([]models.StockTakeLine, error)
+	// SetApproval marks lines for posting (or not) while the session is in REVIEW
+	SetApproval(id int, variantIDs []int, approved bool) (int, error)
+	UpdateStatus(id int, from, to models.StockTakeStatus) error
+
+	// Posting
+	LockForPostingTx(tx *sql.Tx, id int) (*models.StockTake, error)
+	MarkLinePostedTx(tx *sql.Tx, lineID int, ledgerID int64) error
+	MarkPostedTx(tx *sql.Tx, id int, actor string) error
+}
+
+type stockTakeRepository struct {
+	db *sql.DB
+}
+
+func NewStockTakeRepository(db *sql.DB) StockTakeRepository {
+	return &stockTakeRepository{db: db}
+}
+
+const stockTakeColumns = `st.id, COALESCE(st.code, ''), st.warehouse_id, w.code, st.status, COALESCE(st.notes, ''),
+	st.created_by, st.submitted_at, COALESCE(st.posted_by, ''), st.posted_at, st.cancelled_at, st.created_at, st.updated_at`
+
+const stockTakeFrom = `FROM stock_takes st JOIN warehouses w ON w.id = st.warehouse_id`
+
+func scanStockTake(scanner interface{ Scan(...interface{}) error }) (*models.StockTake, error) {
+	var t models.StockTake
+	err := scanner.Scan(
+		&t.ID, &t.Code, &t.WarehouseID, &t.WarehouseCode, &t.Status, &t.Notes,
+		&t.CreatedBy, &t.SubmittedAt, &t.PostedBy, &t.PostedAt, &t.CancelledAt, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+const stockTakeLineColumns = `l.id, l.stock_take_id, l.variant_id, pv.sku, COALESCE(pv.barcode, ''), p.name,
+	COALESCE(pv.variant_name, ''), l.expected_quantity, l.counted_quantity, l.unit_cost, l.approved,
+	COALESCE(l.counted_by, ''), l.counted_at, l.ledger_id`
+
+const stockTakeLineFrom = `FROM stock_take_lines l
+	JOIN product_variants pv ON pv.id = l.variant_id
+	JOIN products p ON p.id = pv.product_id`
+
+func scanStockTakeLine(scanner interface{ Scan(...interface{}) error }) (*models.StockTakeLine, error) {
+	var l models.StockTakeLine
+	var counted sql.NullInt64
+	var unitCost sql.NullFloat64
+	var ledgerID sql.NullInt64
+	err := scanner.Scan(
+		&l.ID, &l.StockTakeID, &l.VariantID, &l.SKU, &l.Barcode, &l.ProductName,
+		&l.VariantName, &l.ExpectedQuantity, &counted, &unitCost, &l.Approved,
+		&l.CountedBy, &l.CountedAt, &ledgerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	l.CountedQuantity = nullIntPtr(counted)
+	if unitCost.Valid {
+		l.UnitCost = &unitCost.Float64
+	}
+	if ledgerID.Valid {
+		l.LedgerID = &ledgerID.Int64
+	}
+	return &l, nil
+}
+
+func (r *stockTakeRepository) Create(take *models.StockTake, productIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO stock_takes (warehouse_id, status, notes, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id, created_at, updated_at
+	`, take.WarehouseID, models.StockTakeCounting, take.Notes, take.CreatedBy).Scan(&take.ID, &take.CreatedAt, &take.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrStockTakeOpen
+	}
+	if err != nil {
+		return err
+	}
+
+	// ST-20261016-00007
+	err = tx.QueryRow(`
+		UPDATE stock_takes
+		SET code = 'ST-' || to_char(created_at, 'YYYYMMDD') || '-' || lpad(id::text, 5, '0')
+		WHERE id = $1
+		RETURNING code
+	`, take.ID).Scan(&take.Code)
+	if err != nil {
+		return err
+	}
+
+	// Expected is what should physically be on the shelf: on hand plus units that are
+	// already off the ledger but not yet shipped (cart holds and unshipped orders)
+	var scope interface{}
+	if len(productIDs) > 0 {
+		scope = pq.Array(productIDs)
+	}
+	_, err = tx.Exec(`
+		WITH held AS (
+			SELECT variant_id, SUM(quantity) AS quantity
+			FROM stock_reservations
+			WHERE status = 'active' AND held_in_ledger = true AND warehouse_id = $2
+			GROUP BY variant_id
+		), unshipped AS (
+			SELECT oi.variant_id, SUM(oi.quantity) AS quantity
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ('PENDING', 'PAID', 'PACKING', 'PROCESSING')
+			AND o.stock_reserved = true
+			AND COALESCE(o.warehouse_id, (SELECT id FROM warehouses WHERE is_default = true)) = $2
+			GROUP BY oi.variant_id
+		)
+		INSERT INTO stock_take_lines (stock_take_id, variant_id, expected_quantity, unit_cost)
+		SELECT $1, pv.id,
+		       COALESCE(ws.quantity, 0) + COALESCE(held.quantity, 0) + COALESCE(unshipped.quantity, 0),
+		       pv.cost_per_item
+		FROM product_variants pv
+		LEFT JOIN warehouse_stock ws ON ws.variant_id = pv.id AND ws.warehouse_id = $2
+		LEFT JOIN held ON held.variant_id = pv.id
+		LEFT JOIN unshipped ON unshipped.variant_id = pv.id
+		WHERE (pv.is_active = true OR COALESCE(ws.quantity, 0) <> 0)
+		AND ($3::int[] IS NULL OR pv.product_id = ANY($3::int[]))
+	`, take.ID, take.WarehouseID, scope)
+	if err != nil {
+		return err
+	}
+
+	take.Status = models.StockTakeCounting
+	return tx.Commit()
+}
+
+func (r *stockTakeRepository) FindByID(id int) (*models.StockTake, error) {
+	t, err := scanStockTake(r.db.QueryRow("SELECT "+stockTakeColumns+" "+stockTakeFrom+" WHERE st.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrStockTakeNotFound
+	}
+	return t, err
+}
+
+func (r *stockTakeRepository) List(warehouseID int, status models.StockTakeStatus, limit, offset int) ([]models.StockTake, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+	argCount := 0
+
+	if warehouseID > 0 {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("st.warehouse_id = $%d", argCount))
+		args = append(args, warehouseID)
+	}
+	if status != "" {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("st.status = $%d", argCount))
+		args = append(args, status)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) "+stockTakeFrom+" "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s %s %s ORDER BY st.created_at DESC, st.id DESC LIMIT $%d OFFSET $%d",
+		stockTakeColumns, stockTakeFrom, whereClause, argCount+1, argCount+2)
+	rows, err := r.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	takes := []models.StockTake{}
+	for rows.Next() {
+		t, err := scanStockTake(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		takes = append(takes, *t)
+	}
+	return takes, total, rows.Err()
+}
+
+func (r *stockTakeRepository) FindLines(id int, variancesOnly bool) ([]models.StockTakeLine, error) {
+	query := "SELECT " + stockTakeLineColumns + " " + stockTakeLineFrom + " WHERE l.stock_take_id = $1"
+	if variancesOnly {
+		query += " AND l.counted_quantity IS NOT NULL AND l.counted_quantity <> l.expected_quantity"
+	}
+	query += " ORDER BY p.name, pv.sku"
+
+	return r.queryLines(r.db, query, id)
+}
+
+func (r *stockTakeRepository) queryLines(db interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]models.StockTakeLine, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.StockTakeLine{}
+	for rows.Next() {
+		l, err := scanStockTakeLine(rows)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, *l)
+	}
+	return lines, rows.Err()
+}
+
+func (r *stockTakeRepository) RecordCounts(id int, counts []models.StockTakeCount, actor string) ([]models.StockTakeLine, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := r.lockStatusTx(tx, id, models.StockTakeCounting); err != nil {
+		return nil, err
+	}
+
+	lineIDs := []int{}
+	for _, count := range counts {
+		var lineID int
+		err := tx.QueryRow(`
+			SELECT l.id
+			FROM stock_take_lines l
+			JOIN product_variants pv ON pv.id = l.variant_id
+			WHERE l.stock_take_id = $1
+			AND (l.variant_id = $2 OR ($3 <> '' AND pv.barcode = $3) OR ($4 <> '' AND pv.sku = $4))
+			ORDER BY (l.variant_id = $2) DESC
+			LIMIT 1
+		`, id, count.VariantID, count.Barcode, count.SKU).Scan(&lineID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrStockTakeLineNotFound, describeCount(count))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		counted := "$2"
+		if count.Add {
+			counted = "COALESCE(counted_quantity, 0) + $2"
+		}
+		_, err = tx.Exec(`
+			UPDATE stock_take_lines
+			SET counted_quantity = `+counted+`, counted_by = $3, counted_at = NOW()
+			WHERE id = $1
+		`, lineID, count.Quantity, actor)
+		if err != nil {
+			return nil, err
+		}
+		lineIDs = append(lineIDs, lineID)
+	}
+
+	lines, err := r.queryLines(tx,
+		"SELECT "+stockTakeLineColumns+" "+stockTakeLineFrom+" WHERE l.id = ANY($1) ORDER BY l.id", pq.Array(lineIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE stock_takes SET updated_at = NOW() WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func describeCount(count models.StockTakeCount) string {
+	switch {
+	case count.Barcode != "":
+		return "barcode " + count.Barcode
+	case count.SKU != "":
+		return "SKU " + count.SKU
+	}
+	return fmt.Sprintf("variant %d", count.VariantID)
+}
+
+func (r *stockTakeRepository) SetApproval(id int, variantIDs []int, approved bool) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := r.lockStatusTx(tx, id, models.StockTakeReview); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"UPDATE stock_take_lines SET approved = $3 WHERE stock_take_id = $1 AND variant_id = ANY($2)",
+		id, pq.Array(variantIDs), approved,
+	)
+	if err != nil {
+		return 0, err
+	}
+	updated, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(updated), nil
+}
+
+// lockStatusTx locks the session row and checks it is in the given status
+func (r *stockTakeRepository) lockStatusTx(tx *sql.Tx, id int, status models.StockTakeStatus) error {
+	var current models.StockTakeStatus
+	err := tx.QueryRow("SELECT status FROM stock_takes WHERE id = $1 FOR UPDATE", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrStockTakeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current != status {
+		return ErrStockTakeConflict
+	}
+	return nil
+}
+
+func (r *stockTakeRepository) UpdateStatus(id int, from, to models.StockTakeStatus) error {
+	set := "status = $3, updated_at = NOW()"
+	switch to {
+	case models.StockTakeReview:
+		set += ", submitted_at = NOW()"
+	case models.StockTakeCancelled:
+		set += ", cancelled_at = NOW()"
+	}
+
+	result, err := r.db.Exec("UPDATE stock_takes SET "+set+" WHERE id = $1 AND status = $2", id, from, to)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrStockTakeConflict
+	}
+	return nil
+}
+
+func (r *stockTakeRepository) LockForPostingTx(tx *sql.Tx, id int) (*models.StockTake, error) {
+	if err := r.lockStatusTx(tx, id, models.StockTakeReview); err != nil {
+		return nil, err
+	}
+	t, err := scanStockTake(tx.QueryRow("SELECT "+stockTakeColumns+" "+stockTakeFrom+" WHERE st.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrStockTakeNotFound
+	}
+	return t, err
+}
+
+func (r *stockTakeRepository) MarkLinePostedTx(tx *sql.Tx, lineID int, ledgerID int64) error {
+	_, err := tx.Exec("UPDATE stock_take_lines SET ledger_id = $2 WHERE id = $1", lineID, ledgerID)
+	return err
+}
+
+func (r *stockTakeRepository) MarkPostedTx(tx *sql.Tx, id int, actor string) error {
+	_, err := tx.Exec(
+		"UPDATE stock_takes SET status = $2, posted_by = $3, posted_at = NOW(), updated_at = NOW() WHERE id = $1",
+		id, models.StockTakePosted, actor,
+	)
+	return err
+}
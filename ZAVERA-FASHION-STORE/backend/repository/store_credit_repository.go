@@ -0,0 +1,521 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInsufficientStoreCredit = errors.New("insufficient store credit")
+	ErrStoreCreditEntryExists  = errors.New("store credit entry already recorded")
+	ErrGiftCardNotFound        = errors.New("gift card not found")
+	ErrGiftCardUnavailable     = errors.New("gift card has already been redeemed or voided")
+	ErrGiftCardExpired         = errors.New("gift card has expired")
+	ErrGiftCardRedeemed        = errors.New("gift card has already been redeemed")
+	ErrGiftCardProduct         = errors.New("product not found or is a bundle")
+)
+
+// StoreCreditRepository keeps customers' store credit balances with their ledger, and the gift
+// cards redeemed into them. Credit spent on an order is given back by the database when the
+// order is cancelled, expires or fails (release_order_store_credit).
+type StoreCreditRepository interface {
+	GetBalance(userID int) (float64, error)
+	// ListEntries returns the user's most recent ledger entries first
+	ListEntries(userID, limit int) ([]models.StoreCreditEntry, error)
+	// AddEntry moves the balance by entry.Amount and records the entry
+	AddEntry(entry *models.StoreCreditEntry) error
+	// AddEntryTx is AddEntry within tx. The user's row is locked, so concurrent debits cannot
+	// overdraw it: ErrInsufficientStoreCredit when the balance would go negative, and
+	// ErrStoreCreditEntryExists when the order, refund or gift card already moved it.
+	AddEntryTx(tx *sql.Tx, entry *models.StoreCreditEntry) error
+
+	// IssuePendingGiftCards creates a code per paid unit of gift card products that has none yet
+	IssuePendingGiftCards(validFor time.Duration) ([]models.GiftCard, error)
+	// FindUndeliveredGiftCards returns active cards whose code has not been emailed, oldest first
+	FindUndeliveredGiftCards(limit int) ([]models.GiftCard, error)
+	MarkGiftCardDelivered(id int) error
+	// RedeemGiftCard adds an active card's amount to the user's balance
+	RedeemGiftCard(code string, userID int) (*models.GiftCard, *models.StoreCreditEntry, error)
+	ListGiftCards(status string, page, pageSize int) ([]models.GiftCard, int, error)
+	// VoidGiftCard stops an active card from being redeemed
+	VoidGiftCard(id int) error
+	// VoidRefundedGiftCardsTx voids the codes of units of a gift card order line refunded within
+	// tx. Units not issued yet get a void code so they never are. Returns ErrGiftCardRedeemed when
+	// a refunded unit's code was already redeemed; lines of other products are left alone.
+	VoidRefundedGiftCardsTx(tx *sql.Tx, orderItemID, units int) error
+	// SetGiftCardProduct marks a product as a gift card, whose paid units are issued as codes
+	SetGiftCardProduct(productID int, isGiftCard bool) error
+}
+
+type storeCreditRepository struct {
+	db *sql.DB
+}
+
+func NewStoreCreditRepository(db *sql.DB) StoreCreditRepository {
+	return &storeCreditRepository{db: db}
+}
+
+func (r *storeCreditRepository) GetBalance(userID int) (float64, error) {
+	var balance float64
+	err := r.db.QueryRow(`SELECT store_credit_balance FROM users WHERE id = $1`, userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	return balance, err
+}
+
+func (r *storeCreditRepository) ListEntries(userID, limit int) ([]models.StoreCreditEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT e.id, e.user_id, e.entry_type, e.amount, e.balance_after, e.order_id,
+		       COALESCE(o.order_code, ''), e.refund_id, e.gift_card_id, COALESCE(e.note, ''),
+		       COALESCE(e.created_by, ''), e.created_at
+		FROM store_credit_entries e
+		LEFT JOIN orders o ON o.id = e.order_id
+		WHERE e.user_id = $1
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.StoreCreditEntry{}
+	for rows.Next() {
+		var e models.StoreCreditEntry
+		var orderID, refundID, giftCardID sql.NullInt64
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.EntryType, &e.Amount, &e.BalanceAfter, &orderID,
+			&e.OrderCode, &refundID, &giftCardID, &e.Note,
+			&e.CreatedBy, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.OrderID = nullIntPtr(orderID)
+		e.RefundID = nullIntPtr(refundID)
+		e.GiftCardID = nullIntPtr(giftCardID)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *storeCreditRepository) AddEntry(entry *models.StoreCreditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.AddEntryTx(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *storeCreditRepository) AddEntryTx(tx *sql.Tx, entry *models.StoreCreditEntry) error {
+	err := tx.QueryRow(`
+		UPDATE users
+		SET store_credit_balance = store_credit_balance + $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND store_credit_balance + $2 >= 0
+		RETURNING store_credit_balance
+	`, entry.UserID, entry.Amount).Scan(&entry.BalanceAfter)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, entry.UserID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+		return ErrInsufficientStoreCredit
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO store_credit_entries (
+			user_id, entry_type, amount, balance_after, order_id, refund_id, gift_card_id, note, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, created_at
+	`, entry.UserID, entry.EntryType, entry.Amount, entry.BalanceAfter, entry.OrderID, entry.RefundID,
+		entry.GiftCardID, entry.Note, entry.CreatedBy,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrStoreCreditEntryExists
+	}
+	return err
+}
+
+const giftCardSelectQuery = `
+	SELECT g.id, g.code, g.amount, g.order_id, COALESCE(o.order_code, ''), g.order_item_id,
+	       COALESCE(oi.product_name, ''), g.purchaser_user_id, COALESCE(o.customer_name, ''),
+	       g.recipient_email, g.status, g.redeemed_by, g.redeemed_at, g.delivered_at, g.expires_at,
+	       g.created_at
+	FROM gift_cards g
+	LEFT JOIN orders o ON o.id = g.order_id
+	LEFT JOIN order_items oi ON oi.id = g.order_item_id
+`
+
+func scanGiftCard(scanner interface{ Scan(...interface{}) error }) (*models.GiftCard, error) {
+	var g models.GiftCard
+	var orderID, orderItemID, purchaserID, redeemedBy sql.NullInt64
+	var redeemedAt, deliveredAt, expiresAt sql.NullTime
+	err := scanner.Scan(
+		&g.ID, &g.Code, &g.Amount, &orderID, &g.OrderCode, &orderItemID,
+		&g.ProductName, &purchaserID, &g.PurchaserName,
+		&g.RecipientEmail, &g.Status, &redeemedBy, &redeemedAt, &deliveredAt, &expiresAt,
+		&g.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	g.OrderID = nullIntPtr(orderID)
+	g.OrderItemID = nullIntPtr(orderItemID)
+	g.PurchaserUserID = nullIntPtr(purchaserID)
+	g.RedeemedBy = nullIntPtr(redeemedBy)
+	g.RedeemedAt = nullTimePtr(redeemedAt)
+	g.DeliveredAt = nullTimePtr(deliveredAt)
+	g.ExpiresAt = nullTimePtr(expiresAt)
+	return &g, nil
+}
+
+func (r *storeCreditRepository) queryGiftCards(query string, args ...any) ([]models.GiftCard, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []models.GiftCard{}
+	for rows.Next() {
+		card, err := scanGiftCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, *card)
+	}
+	return cards, rows.Err()
+}
+
+func (r *storeCreditRepository) IssuePendingGiftCards(validFor time.Duration) ([]models.GiftCard, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lines are locked so two runs cannot issue the same unit twice. Each code is worth what was
+	// paid for its unit, after the line's promotion and voucher discount.
+	rows, err := tx.Query(`
+		SELECT oi.id, oi.order_id, ROUND((oi.subtotal - COALESCE(oi.discount_amount, 0)) / oi.quantity, 2),
+		       o.user_id, o.customer_email,
+		       oi.quantity - (SELECT COUNT(*) FROM gift_cards g WHERE g.order_item_id = oi.id)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products p ON p.id = oi.product_id
+		WHERE p.is_gift_card
+		  AND o.status::text IN ('PAID', 'PACKING', 'PROCESSING', 'SHIPPED', 'DELIVERED', 'COMPLETED')
+		  AND (SELECT COUNT(*) FROM gift_cards g WHERE g.order_item_id = oi.id) < oi.quantity
+		ORDER BY oi.id
+		FOR UPDATE OF oi
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	type pendingLine struct {
+		itemID, orderID int
+		amount          float64
+		userID          sql.NullInt64
+		email           string
+		missing         int
+	}
+	var lines []pendingLine
+	for rows.Next() {
+		var l pendingLine
+		if err := rows.Scan(&l.itemID, &l.orderID, &l.amount, &l.userID, &l.email, &l.missing); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if validFor > 0 {
+		t := time.Now().Add(validFor)
+		expiresAt = &t
+	}
+
+	var ids []int
+	for _, l := range lines {
+		for i := 0; i < l.missing; i++ {
+			id, err := insertGiftCardTx(tx, l.amount, l.orderID, l.itemID, nullIntPtr(l.userID), l.email, expiresAt)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []models.GiftCard{}, nil
+	}
+
+	cards := make([]models.GiftCard, 0, len(ids))
+	for _, id := range ids {
+		card, err := scanGiftCard(r.db.QueryRow(giftCardSelectQuery+" WHERE g.id = $1", id))
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, *card)
+	}
+	return cards, nil
+}
+
+// insertGiftCardTx stores a card under a fresh random code, drawing again on the rare clash
+func insertGiftCardTx(tx *sql.Tx, amount float64, orderID, itemID int, purchaserID *int, email string, expiresAt *time.Time) (int, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateGiftCardCode()
+		if err != nil {
+			return 0, err
+		}
+		var id int
+		err = tx.QueryRow(`
+			INSERT INTO gift_cards (code, amount, order_id, order_item_id, purchaser_user_id, recipient_email, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (code) DO NOTHING
+			RETURNING id
+		`, code, amount, orderID, itemID, purchaserID, email, expiresAt).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		return id, err
+	}
+	return 0, fmt.Errorf("could not generate a unique gift card code for order item %d", itemID)
+}
+
+// giftCardAlphabet leaves out 0/O and 1/I; its 32 letters keep byte%32 unbiased
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateGiftCardCode draws 16 characters (80 bits) from crypto/rand
+func generateGiftCardCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = giftCardAlphabet[int(b[i])%len(giftCardAlphabet)]
+	}
+	return string(b), nil
+}
+
+func (r *storeCreditRepository) FindUndeliveredGiftCards(limit int) ([]models.GiftCard, error) {
+	return r.queryGiftCards(giftCardSelectQuery+`
+		WHERE g.delivered_at IS NULL AND g.status = 'ACTIVE'
+		ORDER BY g.created_at, g.id
+		LIMIT $1
+	`, limit)
+}
+
+func (r *storeCreditRepository) MarkGiftCardDelivered(id int) error {
+	_, err := r.db.Exec(`UPDATE gift_cards SET delivered_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	return err
+}
+
+func (r *storeCreditRepository) RedeemGiftCard(code string, userID int) (*models.GiftCard, *models.StoreCreditEntry, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	card, err := scanGiftCard(tx.QueryRow(giftCardSelectQuery+" WHERE g.code = $1 FOR UPDATE OF g", code))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrGiftCardNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if card.Status != models.GiftCardStatusActive {
+		return nil, nil, ErrGiftCardUnavailable
+	}
+	if card.IsExpired(time.Now()) {
+		return nil, nil, ErrGiftCardExpired
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		UPDATE gift_cards SET status = 'REDEEMED', redeemed_by = $2, redeemed_at = $3 WHERE id = $1
+	`, card.ID, userID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	card.Status = models.GiftCardStatusRedeemed
+	card.RedeemedBy = &userID
+	card.RedeemedAt = &now
+
+	entry := &models.StoreCreditEntry{
+		UserID:     userID,
+		EntryType:  models.StoreCreditEntryGiftCard,
+		Amount:     card.Amount,
+		GiftCardID: &card.ID,
+		Note:       "Gift card " + models.MaskGiftCardCode(card.Code),
+	}
+	if err := r.AddEntryTx(tx, entry); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return card, entry, nil
+}
+
+func (r *storeCreditRepository) ListGiftCards(status string, page, pageSize int) ([]models.GiftCard, int, error) {
+	where := ""
+	args := []any{}
+	if status != "" {
+		where = " WHERE g.status = $1"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM gift_cards g"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, pageSize, (page-1)*pageSize)
+	cards, err := r.queryGiftCards(giftCardSelectQuery+where+fmt.Sprintf(
+		" ORDER BY g.created_at DESC, g.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args),
+	), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cards, total, nil
+}
+
+func (r *storeCreditRepository) VoidGiftCard(id int) error {
+	result, err := r.db.Exec(`UPDATE gift_cards SET status = 'VOID' WHERE id = $1 AND status = 'ACTIVE'`, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM gift_cards WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrGiftCardNotFound
+	}
+	return ErrGiftCardUnavailable
+}
+
+func (r *storeCreditRepository) VoidRefundedGiftCardsTx(tx *sql.Tx, orderItemID, units int) error {
+	// The line is locked as IssuePendingGiftCards locks it, so no code is issued meanwhile
+	var quantity, orderID int
+	var amount float64
+	var userID sql.NullInt64
+	var email string
+	var isGiftCard bool
+	err := tx.QueryRow(`
+		SELECT oi.quantity, oi.order_id, ROUND((oi.subtotal - COALESCE(oi.discount_amount, 0)) / oi.quantity, 2),
+		       o.user_id, o.customer_email, COALESCE(p.is_gift_card, false)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.id = $1
+		FOR UPDATE OF oi
+	`, orderItemID).Scan(&quantity, &orderID, &amount, &userID, &email, &isGiftCard)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, status FROM gift_cards WHERE order_item_id = $1 ORDER BY id FOR UPDATE`, orderItemID)
+	if err != nil {
+		return err
+	}
+	var active []int64
+	issued, redeemed := 0, 0
+	for rows.Next() {
+		var id int64
+		var status models.GiftCardStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return err
+		}
+		issued++
+		switch status {
+		case models.GiftCardStatusActive:
+			active = append(active, id)
+		case models.GiftCardStatusRedeemed:
+			redeemed++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !isGiftCard && issued == 0 {
+		return nil
+	}
+
+	var void []int64
+	for unissued := quantity - issued; units > 0 && unissued > 0; unissued-- {
+		id, err := insertGiftCardTx(tx, amount, orderID, orderItemID, nullIntPtr(userID), email, nil)
+		if err != nil {
+			return err
+		}
+		void = append(void, int64(id))
+		units--
+	}
+	for ; units > 0 && len(active) > 0; units-- {
+		void = append(void, active[0])
+		active = active[1:]
+	}
+	// Whatever is left is covered by codes already redeemed or voided
+	if units > 0 && redeemed > 0 {
+		return ErrGiftCardRedeemed
+	}
+
+	if len(void) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(`UPDATE gift_cards SET status = 'VOID' WHERE id = ANY($1)`, pq.Array(void))
+	return err
+}
+
+func (r *storeCreditRepository) SetGiftCardProduct(productID int, isGiftCard bool) error {
+	result, err := r.db.Exec(`
+		UPDATE products SET is_gift_card = $2, updated_at = NOW()
+		WHERE id = $1 AND NOT COALESCE(is_bundle, false)
+	`, productID, isGiftCard)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGiftCardProduct
+	}
+	return nil
+}
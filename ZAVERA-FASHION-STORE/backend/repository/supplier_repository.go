@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+)
+
+var (
+	ErrSupplierNotFound  = errors.New("supplier not found")
+	ErrDuplicateSupplier = errors.New("supplier code already exists")
+)
+
+type SupplierRepository interface {
+	FindAll(activeOnly bool) ([]models.Supplier, error)
+	FindByID(id int) (*models.Supplier, error)
+	Create(supplier *models.Supplier) error
+	Update(supplier *models.Supplier) error
+}
+
+type supplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) SupplierRepository {
+	return &supplierRepository{db: db}
+}
+
+const supplierColumns = `id, code, name, COALESCE(contact_name, ''), COALESCE(email, ''), COALESCE(phone, ''),
+	COALESCE(address, ''), lead_time_days, COALESCE(notes, ''), is_active, created_at, updated_at`
+
+func scanSupplier(scanner interface{ Scan(...interface{}) error }) (*models.Supplier, error) {
+	var s models.Supplier
+	err := scanner.Scan(
+		&s.ID, &s.Code, &s.Name, &s.ContactName, &s.Email, &s.Phone,
+		&s.Address, &s.LeadTimeDays, &s.Notes, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *supplierRepository) FindAll(activeOnly bool) ([]models.Supplier, error) {
+	query := "SELECT " + supplierColumns + " FROM suppliers"
+	if activeOnly {
+		query += " WHERE is_active = true"
+	}
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []models.Supplier{}
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, *s)
+	}
+	return suppliers, rows.Err()
+}
+
+func (r *supplierRepository) FindByID(id int) (*models.Supplier, error) {
+	s, err := scanSupplier(r.db.QueryRow("SELECT "+supplierColumns+" FROM suppliers WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSupplierNotFound
+	}
+	return s, err
+}
+
+func (r *supplierRepository) Create(s *models.Supplier) error {
+	query := `
+		INSERT INTO suppliers (code, name, contact_name, email, phone, address, lead_time_days, notes, is_active)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), $9)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(query,
+		s.Code, s.Name, s.ContactName, s.Email, s.Phone, s.Address, s.LeadTimeDays, s.Notes, s.IsActive,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateSupplier
+	}
+	return err
+}
+
+func (r *supplierRepository) Update(s *models.Supplier) error {
+	query := `
+		UPDATE suppliers
+		SET code = $2, name = $3, contact_name = NULLIF($4, ''), email = NULLIF($5, ''), phone = NULLIF($6, ''),
+		    address = NULLIF($7, ''), lead_time_days = $8, notes = NULLIF($9, ''), is_active = $10,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(query,
+		s.ID, s.Code, s.Name, s.ContactName, s.Email, s.Phone, s.Address, s.LeadTimeDays, s.Notes, s.IsActive,
+	).Scan(&s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrSupplierNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrDuplicateSupplier
+	}
+	return err
+}
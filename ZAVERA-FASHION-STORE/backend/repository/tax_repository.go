@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTaxSettingNotFound  = errors.New("tax setting not found")
+	ErrTaxSettingDateTaken = errors.New("a tax setting already starts at that time")
+	ErrTaxProductNotFound  = errors.New("product not found")
+)
+
+// TaxRepository stores the PPN settings and the products exempt from PPN
+type TaxRepository interface {
+	// ListSettings returns every setting, the latest effective date first
+	ListSettings() ([]models.TaxSetting, error)
+	CreateSetting(setting *models.TaxSetting) error
+	// DeleteSetting deletes a setting that has not taken effect yet
+	DeleteSetting(id int) error
+
+	// ExemptProductIDs returns which of the products are exempt
+	ExemptProductIDs(productIDs []int) (map[int]bool, error)
+	ListExemptProducts() ([]models.TaxExemptProduct, error)
+	SetProductExempt(productID int, exempt bool) error
+}
+
+type taxRepository struct {
+	db *sql.DB
+}
+
+func NewTaxRepository(db *sql.DB) TaxRepository {
+	return &taxRepository{db: db}
+}
+
+func (r *taxRepository) ListSettings() ([]models.TaxSetting, error) {
+	rows, err := r.db.Query(`
+		SELECT id, rate, prices_include_tax, effective_from, COALESCE(notes, ''),
+		       COALESCE(created_by, ''), created_at
+		FROM tax_settings
+		ORDER BY effective_from DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := []models.TaxSetting{}
+	for rows.Next() {
+		var s models.TaxSetting
+		err := rows.Scan(&s.ID, &s.Rate, &s.PricesIncludeTax, &s.EffectiveFrom, &s.Notes, &s.CreatedBy, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (r *taxRepository) CreateSetting(s *models.TaxSetting) error {
+	err := r.db.QueryRow(`
+		INSERT INTO tax_settings (rate, prices_include_tax, effective_from, notes, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING id, created_at
+	`, s.Rate, s.PricesIncludeTax, s.EffectiveFrom, s.Notes, s.CreatedBy).Scan(&s.ID, &s.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrTaxSettingDateTaken
+	}
+	return err
+}
+
+func (r *taxRepository) DeleteSetting(id int) error {
+	result, err := r.db.Exec("DELETE FROM tax_settings WHERE id = $1 AND effective_from > NOW()", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrTaxSettingNotFound
+	}
+	return nil
+}
+
+func (r *taxRepository) ExemptProductIDs(productIDs []int) (map[int]bool, error) {
+	exempt := map[int]bool{}
+	if len(productIDs) == 0 {
+		return exempt, nil
+	}
+
+	ids := make([]int64, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := r.db.Query("SELECT id FROM products WHERE id = ANY($1) AND tax_exempt = true", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		exempt[id] = true
+	}
+	return exempt, rows.Err()
+}
+
+func (r *taxRepository) ListExemptProducts() ([]models.TaxExemptProduct, error) {
+	rows, err := r.db.Query("SELECT id, name, slug FROM products WHERE tax_exempt = true ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.TaxExemptProduct{}
+	for rows.Next() {
+		var p models.TaxExemptProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Slug); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (r *taxRepository) SetProductExempt(productID int, exempt bool) error {
+	result, err := r.db.Exec(`
+		UPDATE products SET tax_exempt = $2, updated_at = NOW() WHERE id = $1
+	`, productID, exempt)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrTaxProductNotFound
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"zavera/models"
+
+	"github.com/lib/pq"
 )
 
 type VariantRepository struct {
@@ -23,9 +25,9 @@ func (r *VariantRepository) Create(variant *models.ProductVariant) error {
 			price, compare_at_price, cost_per_item,
 			stock_quantity, reserved_stock, low_stock_threshold,
 			is_active, is_default, weight_grams, length_cm, width_cm, height_cm,
-			barcode, position
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
-		RETURNING id, created_at, updated_at`
+			barcode, position, inventory_policy, backorder_limit, expected_ship_date
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
+		RETURNING id, size_chart_row_id, created_at, updated_at`
 
 	return r.db.QueryRow(
 		query,
@@ -37,7 +39,8 @@ func (r *VariantRepository) Create(variant *models.ProductVariant) error {
 		variant.LowStockThreshold, variant.IsActive, variant.IsDefault,
 		variant.WeightGrams, variant.LengthCm, variant.WidthCm, variant.HeightCm,
 		variant.Barcode, variant.Position,
-	).Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt)
+		variant.InventoryPolicy, variant.BackorderLimit, variant.ExpectedShipDate,
+	).Scan(&variant.ID, &variant.SizeChartRowID, &variant.CreatedAt, &variant.UpdatedAt)
 }
 
 func (r *VariantRepository) Update(variant *models.ProductVariant) error {
@@ -49,9 +52,11 @@ func (r *VariantRepository) Update(variant *models.ProductVariant) error {
 			stock_quantity = $14, low_stock_threshold = $15,
 			is_active = $16, is_default = $17, weight_grams = $18, 
 			length_cm = $19, width_cm = $20, height_cm = $21,
-			barcode = $22, position = $23, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $24
-		RETURNING updated_at`
+			barcode = $22, position = $23,
+			inventory_policy = $24, backorder_limit = $25, expected_ship_date = $26,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $27
+		RETURNING size_chart_row_id, updated_at`
 
 	return r.db.QueryRow(
 		query,
@@ -61,8 +66,9 @@ func (r *VariantRepository) Update(variant *models.ProductVariant) error {
 		variant.CostPerItem, variant.StockQuantity, variant.LowStockThreshold,
 		variant.IsActive, variant.IsDefault, variant.WeightGrams,
 		variant.LengthCm, variant.WidthCm, variant.HeightCm,
-		variant.Barcode, variant.Position, variant.ID,
-	).Scan(&variant.UpdatedAt)
+		variant.Barcode, variant.Position,
+		variant.InventoryPolicy, variant.BackorderLimit, variant.ExpectedShipDate, variant.ID,
+	).Scan(&variant.SizeChartRowID, &variant.UpdatedAt)
 }
 
 func (r *VariantRepository) GetByID(id int) (*models.ProductVariant, error) {
@@ -73,8 +79,8 @@ func (r *VariantRepository) GetByID(id int) (*models.ProductVariant, error) {
 			price, compare_at_price, cost_per_item,
 			stock_quantity, reserved_stock, low_stock_threshold,
 			is_active, is_default, weight_grams, length_cm, width_cm, height_cm,
-			barcode, position,
-			created_at, updated_at,
+			barcode, position, inventory_policy, backorder_limit, expected_ship_date,
+			size_chart_row_id, created_at, updated_at,
 			get_available_stock(id) as available_stock
 		FROM product_variants
 		WHERE id = $1`
@@ -88,6 +94,7 @@ func (r *VariantRepository) GetByID(id int) (*models.ProductVariant, error) {
 		&variant.LowStockThreshold, &variant.IsActive, &variant.IsDefault,
 		&variant.WeightGrams, &variant.LengthCm, &variant.WidthCm, &variant.HeightCm,
 		&variant.Barcode, &variant.Position,
+		&variant.InventoryPolicy, &variant.BackorderLimit, &variant.ExpectedShipDate, &variant.SizeChartRowID,
 		&variant.CreatedAt, &variant.UpdatedAt, &variant.AvailableStock,
 	)
 
@@ -107,8 +114,8 @@ func (r *VariantRepository) GetBySKU(sku string) (*models.ProductVariant, error)
 			price, compare_at_price, cost_per_item,
 			stock_quantity, reserved_stock, low_stock_threshold,
 			is_active, is_default, weight_grams, length_cm, width_cm, height_cm,
-			barcode, position,
-			created_at, updated_at,
+			barcode, position, inventory_policy, backorder_limit, expected_ship_date,
+			size_chart_row_id, created_at, updated_at,
 			get_available_stock(id) as available_stock
 		FROM product_variants
 		WHERE sku = $1`
@@ -122,6 +129,7 @@ func (r *VariantRepository) GetBySKU(sku string) (*models.ProductVariant, error)
 		&variant.LowStockThreshold, &variant.IsActive, &variant.IsDefault,
 		&variant.WeightGrams, &variant.LengthCm, &variant.WidthCm, &variant.HeightCm,
 		&variant.Barcode, &variant.Position,
+		&variant.InventoryPolicy, &variant.BackorderLimit, &variant.ExpectedShipDate, &variant.SizeChartRowID,
 		&variant.CreatedAt, &variant.UpdatedAt, &variant.AvailableStock,
 	)
 
@@ -140,8 +148,8 @@ func (r *VariantRepository) GetByProductID(productID int) ([]models.ProductVaria
 			price, compare_at_price, cost_per_item,
 			stock_quantity, reserved_stock, low_stock_threshold,
 			is_active, is_default, weight_grams, length_cm, width_cm, height_cm, 
-			barcode, position,
-			created_at, updated_at,
+			barcode, position, inventory_policy, backorder_limit, expected_ship_date,
+			size_chart_row_id, created_at, updated_at,
 			get_available_stock(id) as available_stock
 		FROM product_variants
 		WHERE product_id = $1
@@ -165,6 +173,7 @@ func (r *VariantRepository) GetByProductID(productID int) ([]models.ProductVaria
 			&v.LowStockThreshold, &v.IsActive, &v.IsDefault,
 			&v.WeightGrams, &v.LengthCm, &v.WidthCm, &v.HeightCm,
 			&v.Barcode, &v.Position,
+			&v.InventoryPolicy, &v.BackorderLimit, &v.ExpectedShipDate, &v.SizeChartRowID,
 			&v.CreatedAt, &v.UpdatedAt, &v.AvailableStock,
 		)
 		if err != nil {
@@ -177,6 +186,56 @@ func (r *VariantRepository) GetByProductID(productID int) ([]models.ProductVaria
 	return variants, nil
 }
 
+// GetActiveByProductIDs loads active variants for many products in one query, keyed by product ID.
+// Variant images are not loaded; AvailableStock is stock_quantity - reserved_stock.
+func (r *VariantRepository) GetActiveByProductIDs(productIDs []int) (map[int][]models.ProductVariant, error) {
+	result := make(map[int][]models.ProductVariant)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT id, product_id, sku, variant_name, size, color, color_hex,
+			material, pattern, fit, sleeve, custom_attributes,
+			price, compare_at_price, cost_per_item,
+			stock_quantity, reserved_stock, low_stock_threshold,
+			is_active, is_default, weight_grams, length_cm, width_cm, height_cm, 
+			barcode, position, inventory_policy, backorder_limit, expected_ship_date,
+			size_chart_row_id, created_at, updated_at,
+			GREATEST(stock_quantity - reserved_stock, 0) as available_stock
+		FROM product_variants
+		WHERE product_id = ANY($1) AND is_active = true
+		ORDER BY product_id ASC, position ASC, id ASC`
+
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.ProductVariant
+		err := rows.Scan(
+			&v.ID, &v.ProductID, &v.SKU, &v.VariantName,
+			&v.Size, &v.Color, &v.ColorHex,
+			&v.Material, &v.Pattern, &v.Fit, &v.Sleeve,
+			&v.CustomAttributes, &v.Price, &v.CompareAtPrice,
+			&v.CostPerItem, &v.StockQuantity, &v.ReservedStock,
+			&v.LowStockThreshold, &v.IsActive, &v.IsDefault,
+			&v.WeightGrams, &v.LengthCm, &v.WidthCm, &v.HeightCm,
+			&v.Barcode, &v.Position,
+			&v.InventoryPolicy, &v.BackorderLimit, &v.ExpectedShipDate, &v.SizeChartRowID,
+			&v.CreatedAt, &v.UpdatedAt, &v.AvailableStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result[v.ProductID] = append(result[v.ProductID], v)
+	}
+
+	return result, rows.Err()
+}
+
 func (r *VariantRepository) Delete(id int) error {
 	// Check if variant has orders
 	var orderCount int
@@ -322,10 +381,10 @@ func (r *VariantRepository) SetPrimaryImage(variantID, imageID int) error {
 }
 
 // Stock Management
-func (r *VariantRepository) ReserveStock(variantID, customerID int, sessionID string, quantity, timeoutMinutes int) (int, error) {
+func (r *VariantRepository) ReserveStock(variantID, customerID int, sessionID string, quantity, timeoutMinutes int, warehouseID *int) (int, error) {
 	var reservationID int
-	query := `SELECT reserve_stock($1, $2, $3, $4, $5)`
-	err := r.db.QueryRow(query, variantID, customerID, sessionID, quantity, timeoutMinutes).Scan(&reservationID)
+	query := `SELECT reserve_stock($1, $2, $3, $4, $5, $6)`
+	err := r.db.QueryRow(query, variantID, customerID, sessionID, quantity, timeoutMinutes, warehouseID).Scan(&reservationID)
 	return reservationID, err
 }
 
@@ -350,26 +409,95 @@ func (r *VariantRepository) GetAvailableStock(variantID int) (int, error) {
 	return stock, err
 }
 
-func (r *VariantRepository) UpdateStock(variantID, quantity int) error {
-	query := `UPDATE product_variants SET stock_quantity = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	_, err := r.db.Exec(query, quantity, variantID)
-	return err
+// GetBackorderedQuantity is how many units of the variant open orders are still waiting for
+func (r *VariantRepository) GetBackorderedQuantity(variantID int) (int, error) {
+	var quantity int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(backordered_quantity), 0) FROM order_items
+		WHERE variant_id = $1 AND backordered_quantity > 0
+	`, variantID).Scan(&quantity)
+	return quantity, err
 }
 
-func (r *VariantRepository) AdjustStock(variantID, delta int) error {
-	query := `
-		UPDATE product_variants 
-		SET stock_quantity = stock_quantity + $1, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = $2 AND stock_quantity + $1 >= 0`
-	result, err := r.db.Exec(query, delta, variantID)
+// FindBackorderable returns which of the variants may be ordered beyond their stock
+func (r *VariantRepository) FindBackorderable(variantIDs []int) (map[int]bool, error) {
+	result := make(map[int]bool)
+	if len(variantIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id FROM product_variants
+		WHERE id = ANY($1) AND inventory_policy <> 'STOCK'
+	`, pq.Array(variantIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result[id] = true
+	}
+	return result, rows.Err()
+}
+
+// UpdateStock sets the variant total to quantity; the difference is recorded as an adjustment at
+// the default warehouse
+func (r *VariantRepository) UpdateStock(variantID, quantity int, actor, notes string) error {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow(`SELECT stock_quantity FROM product_variants WHERE id = $1 FOR UPDATE`, variantID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("variant not found")
+	}
+	if err != nil {
+		return err
+	}
+	if quantity == current {
+		return nil
+	}
+
+	if err := r.applyAdjustment(tx, variantID, quantity-current, actor, notes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AdjustStock records a manual adjustment of delta at the default warehouse
+func (r *VariantRepository) AdjustStock(variantID, delta int, actor, notes string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.applyAdjustment(tx, variantID, delta, actor, notes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *VariantRepository) applyAdjustment(tx *sql.Tx, variantID, delta int, actor, notes string) error {
+	err := NewStockRepository(r.db).ApplyMovementTx(tx, &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     delta,
+		Notes:        notes,
+		Actor:        actor,
+	})
+	if err == ErrInsufficientStock {
 		return fmt.Errorf("insufficient stock or variant not found")
 	}
-	return nil
+	return err
 }
 
 // Low Stock
@@ -402,7 +530,8 @@ func (r *VariantRepository) GetLowStockVariants() ([]models.LowStockVariant, err
 
 func (r *VariantRepository) GetStockSummary(productID int) ([]models.VariantStockSummary, error) {
 	query := `SELECT variant_id, product_id, product_name, sku, variant_name,
-		stock_quantity, reserved_quantity, available_quantity
+		stock_quantity, reserved_quantity, available_quantity,
+		incoming_quantity, next_expected_at
 		FROM variant_stock_summary
 		WHERE product_id = $1`
 
@@ -418,6 +547,7 @@ func (r *VariantRepository) GetStockSummary(productID int) ([]models.VariantStoc
 		err := rows.Scan(
 			&s.VariantID, &s.ProductID, &s.ProductName, &s.SKU, &s.VariantName,
 			&s.StockQuantity, &s.ReservedQuantity, &s.AvailableQuantity,
+			&s.IncomingQuantity, &s.NextExpectedAt,
 		)
 		if err != nil {
 			continue
@@ -457,6 +587,22 @@ func (r *VariantRepository) GetAttributes() ([]models.VariantAttribute, error) {
 	return attributes, nil
 }
 
+// CreateAttribute adds a variant attribute. Returns false when the name is taken.
+func (r *VariantRepository) CreateAttribute(attr *models.VariantAttribute) (bool, error) {
+	query := `INSERT INTO variant_attributes (name, display_name, type, options, sort_order)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, is_active, created_at`
+
+	err := r.db.QueryRow(
+		query, attr.Name, attr.DisplayName, attr.Type, []byte(attr.Options), attr.SortOrder,
+	).Scan(&attr.ID, &attr.IsActive, &attr.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 // Search and Filter
 func (r *VariantRepository) Search(filters map[string]interface{}) ([]models.ProductVariant, error) {
 	query := `
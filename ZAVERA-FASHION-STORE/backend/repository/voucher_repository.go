@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"zavera/models"
+)
+
+var (
+	ErrVoucherNotFound       = errors.New("voucher not found")
+	ErrVoucherCodeTaken      = errors.New("voucher code already exists")
+	ErrVoucherUnavailable    = errors.New("voucher is no longer available")
+	ErrVoucherUsageLimit     = errors.New("voucher has been fully redeemed")
+	ErrVoucherPerUserLimit   = errors.New("voucher usage limit reached for this customer")
+	ErrVoucherFirstOrderOnly = errors.New("voucher is only valid on a first order")
+)
+
+// VoucherRepository stores vouchers and their redemptions. Redemptions are released by the
+// database when their order is cancelled, expires or fails (release_order_voucher).
+type VoucherRepository interface {
+	List() ([]models.Voucher, error)
+	FindByID(id int) (*models.Voucher, error)
+	FindByCode(code string) (*models.Voucher, error)
+	Create(voucher *models.Voucher) error
+	Update(voucher *models.Voucher) error
+	ListRedemptions(voucherID int) ([]models.VoucherRedemption, error)
+
+	// CountCustomerRedemptions counts applied redemptions by the account, or by email for guests
+	CountCustomerRedemptions(voucherID int, userID *int, email string) (int, error)
+	// HasPlacedOrder reports whether the account or email has an order that was not cancelled,
+	// expired or failed, other than excludeOrderID
+	HasPlacedOrder(userID *int, email string, excludeOrderID int) (bool, error)
+	// RedeemTx records the redemption of a just-created order. The voucher row is locked so
+	// usage limits hold under concurrent checkouts.
+	RedeemTx(tx *sql.Tx, redemption *models.VoucherRedemption) error
+}
+
+type voucherRepository struct {
+	db *sql.DB
+}
+
+func NewVoucherRepository(db *sql.DB) VoucherRepository {
+	return &voucherRepository{db: db}
+}
+
+const voucherColumns = `id, code, name, COALESCE(description, ''), type, value, max_discount, min_spend,
+	COALESCE(buy_quantity, 0), COALESCE(get_quantity, 0), category_id, brand,
+	usage_limit, per_user_limit, used_count, first_order_only, starts_at, ends_at, is_active,
+	COALESCE(created_by, ''), created_at, updated_at`
+
+func scanVoucher(scanner interface{ Scan(...interface{}) error }) (*models.Voucher, error) {
+	var v models.Voucher
+	var categoryID, usageLimit, perUserLimit sql.NullInt64
+	var brand sql.NullString
+	var maxDiscount sql.NullFloat64
+	err := scanner.Scan(
+		&v.ID, &v.Code, &v.Name, &v.Description, &v.Type, &v.Value, &maxDiscount, &v.MinSpend,
+		&v.BuyQuantity, &v.GetQuantity, &categoryID, &brand,
+		&usageLimit, &perUserLimit, &v.UsedCount, &v.FirstOrderOnly, &v.StartsAt, &v.EndsAt, &v.IsActive,
+		&v.CreatedBy, &v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if maxDiscount.Valid {
+		v.MaxDiscount = &maxDiscount.Float64
+	}
+	if brand.Valid {
+		v.Brand = &brand.String
+	}
+	v.CategoryID = nullIntPtr(categoryID)
+	v.UsageLimit = nullIntPtr(usageLimit)
+	v.PerUserLimit = nullIntPtr(perUserLimit)
+	return &v, nil
+}
+
+func (r *voucherRepository) List() ([]models.Voucher, error) {
+	rows, err := r.db.Query("SELECT " + voucherColumns + " FROM vouchers ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vouchers := []models.Voucher{}
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, *v)
+	}
+	return vouchers, rows.Err()
+}
+
+func (r *voucherRepository) FindByID(id int) (*models.Voucher, error) {
+	v, err := scanVoucher(r.db.QueryRow("SELECT "+voucherColumns+" FROM vouchers WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrVoucherNotFound
+	}
+	return v, err
+}
+
+func (r *voucherRepository) FindByCode(code string) (*models.Voucher, error) {
+	v, err := scanVoucher(r.db.QueryRow(
+		"SELECT "+voucherColumns+" FROM vouchers WHERE UPPER(code) = $1",
+		models.NormalizeVoucherCode(code),
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrVoucherNotFound
+	}
+	return v, err
+}
+
+func (r *voucherRepository) Create(v *models.Voucher) error {
+	err := r.db.QueryRow(`
+		INSERT INTO vouchers (
+			code, name, description, type, value, max_discount, min_spend, buy_quantity, get_quantity,
+			category_id, brand, usage_limit, per_user_limit, first_order_only, starts_at, ends_at,
+			is_active, created_by
+		)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, 0),
+			$10, $11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''))
+		RETURNING id, used_count, created_at, updated_at
+	`, v.Code, v.Name, v.Description, v.Type, v.Value, v.MaxDiscount, v.MinSpend, v.BuyQuantity, v.GetQuantity,
+		v.CategoryID, v.Brand, v.UsageLimit, v.PerUserLimit, v.FirstOrderOnly, v.StartsAt, v.EndsAt,
+		v.IsActive, v.CreatedBy,
+	).Scan(&v.ID, &v.UsedCount, &v.CreatedAt, &v.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrVoucherCodeTaken
+	}
+	return err
+}
+
+func (r *voucherRepository) Update(v *models.Voucher) error {
+	err := r.db.QueryRow(`
+		UPDATE vouchers SET
+			code = $2, name = $3, description = NULLIF($4, ''), type = $5, value = $6, max_discount = $7,
+			min_spend = $8, buy_quantity = NULLIF($9, 0), get_quantity = NULLIF($10, 0),
+			category_id = $11, brand = $12, usage_limit = $13, per_user_limit = $14,
+			first_order_only = $15, starts_at = $16, ends_at = $17, is_active = $18,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING used_count, COALESCE(created_by, ''), created_at, updated_at
+	`, v.ID, v.Code, v.Name, v.Description, v.Type, v.Value, v.MaxDiscount,
+		v.MinSpend, v.BuyQuantity, v.GetQuantity,
+		v.CategoryID, v.Brand, v.UsageLimit, v.PerUserLimit,
+		v.FirstOrderOnly, v.StartsAt, v.EndsAt, v.IsActive,
+	).Scan(&v.UsedCount, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrVoucherNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrVoucherCodeTaken
+	}
+	return err
+}
+
+func (r *voucherRepository) ListRedemptions(voucherID int) ([]models.VoucherRedemption, error) {
+	rows, err := r.db.Query(`
+		SELECT id, voucher_id, order_id, user_id, customer_email, code, discount_amount, status,
+		       created_at, released_at
+		FROM voucher_redemptions
+		WHERE voucher_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, voucherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := []models.VoucherRedemption{}
+	for rows.Next() {
+		var rd models.VoucherRedemption
+		var userID sql.NullInt64
+		err := rows.Scan(
+			&rd.ID, &rd.VoucherID, &rd.OrderID, &userID, &rd.CustomerEmail, &rd.Code,
+			&rd.DiscountAmount, &rd.Status, &rd.CreatedAt, &rd.ReleasedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rd.UserID = nullIntPtr(userID)
+		redemptions = append(redemptions, rd)
+	}
+	return redemptions, rows.Err()
+}
+
+func (r *voucherRepository) CountCustomerRedemptions(voucherID int, userID *int, email string) (int, error) {
+	return countCustomerRedemptions(r.db, voucherID, userID, email)
+}
+
+func (r *voucherRepository) HasPlacedOrder(userID *int, email string, excludeOrderID int) (bool, error) {
+	return hasPlacedOrder(r.db, userID, email, excludeOrderID)
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func countCustomerRedemptions(q queryRower, voucherID int, userID *int, email string) (int, error) {
+	var count int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM voucher_redemptions
+		WHERE voucher_id = $1 AND status = 'APPLIED'
+		AND (($2::int IS NOT NULL AND user_id = $2) OR ($2::int IS NULL AND LOWER(customer_email) = $3))
+	`, voucherID, userID, strings.ToLower(strings.TrimSpace(email))).Scan(&count)
+	return count, err
+}
+
+func hasPlacedOrder(q queryRower, userID *int, email string, excludeOrderID int) (bool, error) {
+	var exists bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM orders
+			WHERE id <> $3
+			AND status::text NOT IN ('CANCELLED', 'EXPIRED', 'FAILED', 'KADALUARSA', 'DIBATALKAN')
+			AND (($1::int IS NOT NULL AND user_id = $1) OR LOWER(customer_email) = $2)
+		)
+	`, userID, strings.ToLower(strings.TrimSpace(email)), excludeOrderID).Scan(&exists)
+	return exists, err
+}
+
+func (r *voucherRepository) RedeemTx(tx *sql.Tx, rd *models.VoucherRedemption) error {
+	var isActive, firstOrderOnly bool
+	var usageLimit, perUserLimit sql.NullInt64
+	var usedCount int
+	err := tx.QueryRow(`
+		SELECT is_active AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW()),
+		       usage_limit, per_user_limit, used_count, first_order_only
+		FROM vouchers WHERE id = $1
+		FOR UPDATE
+	`, rd.VoucherID).Scan(&isActive, &usageLimit, &perUserLimit, &usedCount, &firstOrderOnly)
+	if err == sql.ErrNoRows {
+		return ErrVoucherNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !isActive {
+		return ErrVoucherUnavailable
+	}
+	if usageLimit.Valid && int64(usedCount) >= usageLimit.Int64 {
+		return ErrVoucherUsageLimit
+	}
+	if perUserLimit.Valid {
+		count, err := countCustomerRedemptions(tx, rd.VoucherID, rd.UserID, rd.CustomerEmail)
+		if err != nil {
+			return err
+		}
+		if int64(count) >= perUserLimit.Int64 {
+			return ErrVoucherPerUserLimit
+		}
+	}
+	if firstOrderOnly {
+		placed, err := hasPlacedOrder(tx, rd.UserID, rd.CustomerEmail, rd.OrderID)
+		if err != nil {
+			return err
+		}
+		if placed {
+			return ErrVoucherFirstOrderOnly
+		}
+	}
+
+	rd.Status = models.VoucherRedemptionApplied
+	err = tx.QueryRow(`
+		INSERT INTO voucher_redemptions (voucher_id, order_id, user_id, customer_email, code, discount_amount, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, rd.VoucherID, rd.OrderID, rd.UserID, rd.CustomerEmail, rd.Code, rd.DiscountAmount, rd.Status,
+	).Scan(&rd.ID, &rd.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE vouchers SET used_count = used_count + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, rd.VoucherID)
+	return err
+}
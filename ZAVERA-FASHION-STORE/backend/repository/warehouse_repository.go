@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"zavera/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrWarehouseNotFound          = errors.New("warehouse not found")
+	ErrDuplicateWarehouse         = errors.New("warehouse code already exists")
+	ErrInsufficientWarehouseStock = errors.New("insufficient stock at warehouse")
+)
+
+type WarehouseRepository interface {
+	FindAll(activeOnly bool) ([]models.Warehouse, error)
+	FindByID(id int) (*models.Warehouse, error)
+	FindDefault() (*models.Warehouse, error)
+	Create(warehouse *models.Warehouse) error
+	Update(warehouse *models.Warehouse) error
+
+	// Stock per location. Writes are stock ledger entries (see StockRepository), which keep
+	// product_variants.stock_quantity equal to the sum over warehouses.
+	GetAvailability(variantIDs []int) (models.WarehouseAvailability, error)
+	ListStock(warehouseID int, search string, limit, offset int) ([]models.WarehouseStock, int, error)
+	SetStock(warehouseID, variantID, quantity int, actor string) error
+	TransferStock(fromWarehouseID, toWarehouseID, variantID, quantity int, actor string) error
+}
+
+type warehouseRepository struct {
+	db    *sql.DB
+	stock StockRepository
+}
+
+func NewWarehouseRepository(db *sql.DB) WarehouseRepository {
+	return &warehouseRepository{db: db, stock: NewStockRepository(db)}
+}
+
+const warehouseColumns = `id, code, name, address, city_name, COALESCE(city_id, ''), postal_code, COALESCE(area_id, ''),
+	contact_name, contact_phone, priority, is_default, is_active, created_at, updated_at`
+
+func scanWarehouse(scanner interface{ Scan(...interface{}) error }) (*models.Warehouse, error) {
+	var w models.Warehouse
+	err := scanner.Scan(
+		&w.ID, &w.Code, &w.Name, &w.Address, &w.CityName, &w.CityID, &w.PostalCode, &w.AreaID,
+		&w.ContactName, &w.ContactPhone, &w.Priority, &w.IsDefault, &w.IsActive, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *warehouseRepository) FindAll(activeOnly bool) ([]models.Warehouse, error) {
+	query := "SELECT " + warehouseColumns + " FROM warehouses"
+	if activeOnly {
+		query += " WHERE is_active = true"
+	}
+	query += " ORDER BY is_default DESC, priority, id"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []models.Warehouse{}
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, *w)
+	}
+	return warehouses, rows.Err()
+}
+
+func (r *warehouseRepository) FindByID(id int) (*models.Warehouse, error) {
+	w, err := scanWarehouse(r.db.QueryRow("SELECT "+warehouseColumns+" FROM warehouses WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWarehouseNotFound
+	}
+	return w, err
+}
+
+func (r *warehouseRepository) FindDefault() (*models.Warehouse, error) {
+	w, err := scanWarehouse(r.db.QueryRow("SELECT " + warehouseColumns + " FROM warehouses WHERE is_default = true"))
+	if err == sql.ErrNoRows {
+		return nil, ErrWarehouseNotFound
+	}
+	return w, err
+}
+
+func (r *warehouseRepository) Create(w *models.Warehouse) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if w.IsDefault {
+		if _, err := tx.Exec("UPDATE warehouses SET is_default = false, updated_at = NOW() WHERE is_default = true"); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO warehouses (
+			code, name, address, city_name, postal_code, area_id,
+			contact_name, contact_phone, priority, is_default, is_active, city_id
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, NULLIF($12, ''))
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query,
+		w.Code, w.Name, w.Address, w.CityName, w.PostalCode, w.AreaID,
+		w.ContactName, w.ContactPhone, w.Priority, w.IsDefault, w.IsActive, w.CityID,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateWarehouse
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *warehouseRepository) Update(w *models.Warehouse) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if w.IsDefault {
+		if _, err := tx.Exec("UPDATE warehouses SET is_default = false, updated_at = NOW() WHERE is_default = true AND id <> $1", w.ID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE warehouses
+		SET code = $2, name = $3, address = $4, city_name = $5, postal_code = $6, area_id = NULLIF($7, ''),
+		    contact_name = $8, contact_phone = $9, priority = $10, is_default = $11, is_active = $12,
+		    city_id = NULLIF($13, ''), updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err = tx.QueryRow(query,
+		w.ID, w.Code, w.Name, w.Address, w.CityName, w.PostalCode, w.AreaID,
+		w.ContactName, w.ContactPhone, w.Priority, w.IsDefault, w.IsActive, w.CityID,
+	).Scan(&w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrWarehouseNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrDuplicateWarehouse
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAvailability returns on-hand minus active reservations for the given variants at every
+// active warehouse. Variants a warehouse has never stocked are absent (0).
+func (r *warehouseRepository) GetAvailability(variantIDs []int) (models.WarehouseAvailability, error) {
+	available := models.WarehouseAvailability{}
+	if len(variantIDs) == 0 {
+		return available, nil
+	}
+
+	query := `
+		SELECT ws.warehouse_id, ws.variant_id,
+		       GREATEST(ws.quantity - COALESCE((
+		           SELECT SUM(sr.quantity) FROM stock_reservations sr
+		           WHERE sr.warehouse_id = ws.warehouse_id AND sr.variant_id = ws.variant_id
+		             AND sr.status = 'active' AND sr.expires_at > CURRENT_TIMESTAMP
+		       ), 0), 0)
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		WHERE w.is_active = true AND ws.variant_id = ANY($1)
+	`
+	rows, err := r.db.Query(query, pq.Array(variantIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var warehouseID, variantID, quantity int
+		if err := rows.Scan(&warehouseID, &variantID, &quantity); err != nil {
+			return nil, err
+		}
+		if available[warehouseID] == nil {
+			available[warehouseID] = map[int]int{}
+		}
+		available[warehouseID][variantID] = quantity
+	}
+	return available, rows.Err()
+}
+
+// ListStock lists variant stock at one warehouse, searchable by SKU or product name
+func (r *warehouseRepository) ListStock(warehouseID int, search string, limit, offset int) ([]models.WarehouseStock, int, error) {
+	whereConditions := []string{"ws.warehouse_id = $1"}
+	args := []interface{}{warehouseID}
+	argCount := 1
+
+	if search = strings.TrimSpace(search); search != "" {
+		argCount++
+		whereConditions = append(whereConditions, fmt.Sprintf("(pv.sku ILIKE $%d OR p.name ILIKE $%d)", argCount, argCount))
+		args = append(args, "%"+search+"%")
+	}
+	whereClause := "WHERE " + strings.Join(whereConditions, " AND ")
+
+	from := `
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		JOIN product_variants pv ON pv.id = ws.variant_id
+		JOIN products p ON p.id = pv.product_id
+	`
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) "+from+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT ws.warehouse_id, w.code, ws.variant_id, pv.product_id, p.name, pv.sku, pv.variant_name,
+		       ws.quantity,
+		       COALESCE((
+		           SELECT SUM(sr.quantity) FROM stock_reservations sr
+		           WHERE sr.warehouse_id = ws.warehouse_id AND sr.variant_id = ws.variant_id
+		             AND sr.status = 'active' AND sr.expires_at > CURRENT_TIMESTAMP
+		       ), 0),
+		       ws.updated_at
+		%s %s
+		ORDER BY p.name, pv.position, pv.id
+		LIMIT $%d OFFSET $%d
+	`, from, whereClause, argCount+1, argCount+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stock := []models.WarehouseStock{}
+	for rows.Next() {
+		var s models.WarehouseStock
+		err := rows.Scan(
+			&s.WarehouseID, &s.WarehouseCode, &s.VariantID, &s.ProductID, &s.ProductName, &s.SKU, &s.VariantName,
+			&s.Quantity, &s.Reserved, &s.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		s.Available = s.Quantity - s.Reserved
+		if s.Available < 0 {
+			s.Available = 0
+		}
+		stock = append(stock, s)
+	}
+	return stock, total, rows.Err()
+}
+
+// SetStock sets the on-hand quantity of a variant at a warehouse (stock count, receiving); the
+// difference is recorded as an adjustment
+func (r *warehouseRepository) SetStock(warehouseID, variantID, quantity int, actor string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow(`
+		SELECT quantity FROM warehouse_stock WHERE warehouse_id = $1 AND variant_id = $2 FOR UPDATE
+	`, warehouseID, variantID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if quantity == current {
+		return nil
+	}
+
+	err = r.stock.ApplyMovementTx(tx, &models.StockLedgerEntry{
+		VariantID:    variantID,
+		WarehouseID:  &warehouseID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     quantity - current,
+		Notes:        fmt.Sprintf("Stock set to %d", quantity),
+		Actor:        actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TransferStock moves stock between warehouses as a TRANSFER_OUT/TRANSFER_IN pair; the variant
+// total does not change
+func (r *warehouseRepository) TransferStock(fromWarehouseID, toWarehouseID, variantID, quantity int, actor string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	out := &models.StockLedgerEntry{
+		VariantID:    variantID,
+		WarehouseID:  &fromWarehouseID,
+		MovementType: models.StockMovementTransferOut,
+		Quantity:     -quantity,
+		Notes:        fmt.Sprintf("Transfer to warehouse %d", toWarehouseID),
+		Actor:        actor,
+	}
+	err = r.stock.ApplyMovementTx(tx, out)
+	if err == ErrInsufficientStock {
+		return ErrInsufficientWarehouseStock
+	}
+	if err != nil {
+		return err
+	}
+
+	err = r.stock.ApplyMovementTx(tx, &models.StockLedgerEntry{
+		VariantID:    variantID,
+		WarehouseID:  &toWarehouseID,
+		MovementType: models.StockMovementTransferIn,
+		Quantity:     quantity,
+		Reference:    fmt.Sprintf("ledger:%d", out.ID),
+		Notes:        fmt.Sprintf("Transfer from warehouse %d", fromWarehouseID),
+		Actor:        actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
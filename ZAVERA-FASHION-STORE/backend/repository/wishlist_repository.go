@@ -96,10 +96,10 @@ func (r *wishlistRepository) Add(userID int, productID int) (*models.Wishlist, e
 		return existing, nil // Already in wishlist
 	}
 
-	// Insert new wishlist item
+	// Insert new wishlist item, remembering the price for price-drop alerts
 	query := `
-		INSERT INTO wishlists (user_id, product_id)
-		VALUES ($1, $2)
+		INSERT INTO wishlists (user_id, product_id, price_seen)
+		VALUES ($1, $2, (SELECT price FROM products WHERE id = $2))
 		RETURNING id, user_id, product_id, created_at, updated_at
 	`
 
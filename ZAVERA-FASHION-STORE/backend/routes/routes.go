@@ -2,6 +2,7 @@ package routes
 
 import (
 	"database/sql"
+	"log"
 	"os"
 	"zavera/handler"
 	"zavera/repository"
@@ -13,9 +14,28 @@ import (
 func SetupRoutes(router *gin.Engine, db *sql.DB) {
 	// Initialize repositories
 	productRepo := repository.NewProductRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	collectionRepo := repository.NewCollectionRepository(db)
+	recommendationRepo := repository.NewRecommendationRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	productScheduleRepo := repository.NewProductScheduleRepository(db)
+	warehouseRepo := repository.NewWarehouseRepository(db)
+	stockRepo := repository.NewStockRepository(db)
+	reservationRepo := repository.NewReservationRepository(db)
+	supplierRepo := repository.NewSupplierRepository(db)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(db)
+	stockTakeRepo := repository.NewStockTakeRepository(db)
 	variantRepo := repository.NewVariantRepository(db)
+	bundleRepo := repository.NewBundleRepository(db)
+	sizeChartRepo := repository.NewSizeChartRepository(db)
+	voucherRepo := repository.NewVoucherRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	taxRepo := repository.NewTaxRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	storeCreditRepo := repository.NewStoreCreditRepository(db)
 	cartRepo := repository.NewCartRepository(db)
 	wishlistRepo := repository.NewWishlistRepository(db)
+	stockAlertRepo := repository.NewStockAlertRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
 	paymentRepo := repository.NewPaymentRepository(db)
 	userRepo := repository.NewUserRepository(db)
@@ -26,28 +46,53 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 	orderPaymentRepo := repository.NewOrderPaymentRepository(db)
 
 	// Initialize services
-	productService := service.NewProductService(productRepo, variantRepo)
-	variantService := service.NewVariantService(variantRepo, productRepo)
-	cartService := service.NewCartService(cartRepo, productRepo)
-	wishlistService := service.NewWishlistService(wishlistRepo, productRepo, cartRepo)
-	orderService := service.NewOrderService(orderRepo, cartRepo, productRepo)
+	categoryService := service.NewCategoryService(categoryRepo)
+	productService := service.NewProductService(productRepo, variantRepo, categoryService, collectionRepo)
+	variantService := service.NewVariantService(variantRepo, productRepo, sizeChartRepo)
+	bundleService := service.NewBundleService(bundleRepo, productRepo, variantRepo)
+	sizeChartService := service.NewSizeChartService(sizeChartRepo, productRepo, categoryRepo)
+	warehouseService := service.NewWarehouseService(warehouseRepo, variantRepo, bundleRepo)
+	stockLedgerService := service.NewStockLedgerService(stockRepo)
+	purchaseOrderService := service.NewPurchaseOrderService(supplierRepo, purchaseOrderRepo, warehouseRepo, variantRepo)
+	stockTakeService := service.NewStockTakeService(db, stockTakeRepo, warehouseRepo, stockRepo, repository.NewAdminAuditRepository(db))
+	promotionService := service.NewPromotionService(promotionRepo, productRepo, categoryRepo, variantRepo)
+	cartService := service.NewCartService(cartRepo, productRepo, reservationRepo, promotionService)
+	wishlistService := service.NewWishlistService(wishlistRepo, productRepo, cartRepo, variantRepo, stockAlertRepo, promotionService)
+	voucherService := service.NewVoucherService(voucherRepo, cartRepo, productRepo, categoryRepo, promotionService)
+	taxService := service.NewTaxService(taxRepo)
+	invoiceService := service.NewInvoiceService(invoiceRepo, orderRepo, repository.NewRefundRepository(db), shippingRepo)
+	orderService := service.NewOrderService(orderRepo, cartRepo, productRepo, promotionService, voucherService, taxService)
 	paymentService := service.NewPaymentService(paymentRepo, orderRepo, shippingRepo, emailRepo)
 	authService := service.NewAuthService(userRepo, shippingRepo)
-	shippingService := service.NewShippingService(shippingRepo, cartRepo, productRepo, orderRepo)
-	checkoutService := service.NewCheckoutService(orderRepo, cartRepo, productRepo, shippingRepo, emailRepo)
+	shippingService := service.NewShippingService(shippingRepo, cartRepo, productRepo, orderRepo, warehouseService)
+	storeCreditService := service.NewStoreCreditService(storeCreditRepo, orderRepo, service.NewEmailService(emailRepo))
+	checkoutService := service.NewCheckoutService(orderRepo, cartRepo, productRepo, shippingRepo, emailRepo, warehouseService, promotionService, voucherService, taxService, storeCreditService)
+	feedService := service.NewFeedService(productRepo, variantRepo)
+	recommendationService := service.NewRecommendationService(recommendationRepo, productRepo)
 
 	// Initialize Core Payment service (Tokopedia-style VA payments)
 	serverKey := os.Getenv("MIDTRANS_SERVER_KEY")
 	emailService := service.NewEmailService(emailRepo)
+
+	// Review photos go to Cloudinary; reviews still work without it, only uploads are disabled
+	reviewCloudinary, err := service.NewCloudinaryService()
+	if err != nil {
+		log.Printf("⚠️ Review photo upload disabled: %v", err)
+	}
+	reviewService := service.NewReviewService(reviewRepo, productRepo, userRepo, reviewCloudinary)
 	corePaymentService := service.NewCorePaymentService(orderPaymentRepo, orderRepo, serverKey, emailService)
 
 	// Admin services
 	adminProductService := service.NewAdminProductService(db)
 	adminOrderService := service.NewAdminOrderService(db, orderRepo, paymentRepo, shippingRepo, emailRepo, shippingService)
+	productScheduleService := service.NewProductScheduleService(db, productScheduleRepo, productRepo, repository.NewAdminAuditRepository(db))
 
 	// Initialize handlers
 	productHandler := handler.NewProductHandler(productService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
 	variantHandler := handler.NewVariantHandler(variantService)
+	bundleHandler := handler.NewBundleHandler(bundleService)
+	sizeChartHandler := handler.NewSizeChartHandler(sizeChartService)
 	cartHandler := handler.NewCartHandler(cartService)
 	wishlistHandler := handler.NewWishlistHandler(wishlistService)
 	orderHandler := handler.NewOrderHandler(orderService, paymentService, shippingService)
@@ -55,15 +100,31 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 	authHandler := handler.NewAuthHandler(authService, cartService)
 	shippingHandler := handler.NewShippingHandler(shippingService)
 	checkoutHandler := handler.NewCheckoutHandler(checkoutService, shippingService)
+	voucherHandler := handler.NewVoucherHandler(voucherService)
+	promotionHandler := handler.NewPromotionHandler(promotionService)
+	taxHandler := handler.NewTaxHandler(taxService)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceService)
+	storeCreditHandler := handler.NewStoreCreditHandler(storeCreditService)
 	trackingHandler := handler.NewTrackingHandler(shippingService, orderService)
+	feedHandler := handler.NewFeedHandler(feedService)
+	recommendationHandler := handler.NewRecommendationHandler(recommendationService)
+	reviewHandler := handler.NewReviewHandler(reviewService)
 
 	// Admin handlers
 	adminProductHandler := handler.NewAdminProductHandler(adminProductService)
 	adminOrderHandler := handler.NewAdminOrderHandler(adminOrderService)
+	productScheduleHandler := handler.NewProductScheduleHandler(productScheduleService)
+	warehouseHandler := handler.NewWarehouseHandler(warehouseService)
+	stockLedgerHandler := handler.NewStockLedgerHandler(stockLedgerService)
+	purchaseOrderHandler := handler.NewPurchaseOrderHandler(purchaseOrderService)
+	stockTakeHandler := handler.NewStockTakeHandler(stockTakeService)
 
 	// Core Payment handler (Tokopedia-style VA payments)
 	corePaymentHandler := handler.NewCorePaymentHandler(corePaymentService)
 
+	// Sitemap at the conventional root location for crawlers
+	router.GET("/sitemap.xml", feedHandler.GetSitemap)
+
 	// API routes
 	api := router.Group("/api")
 	{
@@ -90,6 +151,23 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			user.PUT("/addresses/:id", shippingHandler.UpdateAddress)
 			user.DELETE("/addresses/:id", shippingHandler.DeleteAddress)
 			user.POST("/addresses/:id/default", shippingHandler.SetDefaultAddress)
+			// Product reviews
+			user.GET("/reviews", reviewHandler.GetMyReviews)
+			user.GET("/reviews/pending", reviewHandler.GetPendingReviews)
+			user.POST("/reviews", reviewHandler.CreateReview)
+			user.POST("/reviews/photos", reviewHandler.UploadReviewPhoto)
+			// Fit profile and size recommendations
+			user.GET("/fit-profile", sizeChartHandler.GetFitProfile)
+			user.PUT("/fit-profile", sizeChartHandler.SaveFitProfile)
+			user.GET("/size-recommendations/:productId", sizeChartHandler.GetSizeRecommendation)
+			// NPWP on invoices, and invoice/credit note downloads
+			user.GET("/tax-profile", invoiceHandler.GetTaxProfile)
+			user.PUT("/tax-profile", invoiceHandler.SaveTaxProfile)
+			user.GET("/orders/:code/invoices", invoiceHandler.GetOrderDocuments)
+			user.GET("/invoices/:id/pdf", invoiceHandler.DownloadInvoice)
+			// Store credit wallet, topped up by redeeming gift cards
+			user.GET("/store-credit", storeCreditHandler.GetWallet)
+			user.POST("/store-credit/redeem", storeCreditHandler.RedeemGiftCard)
 		}
 
 		// Customer refund routes (protected)
@@ -99,7 +177,7 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			// Initialize refund repositories and services for customer endpoints
 			refundRepo := repository.NewRefundRepository(db)
 			auditRepo := repository.NewAdminAuditRepository(db)
-			refundSvc := service.NewRefundService(refundRepo, orderRepo, paymentRepo, auditRepo)
+			refundSvc := service.NewRefundService(refundRepo, orderRepo, paymentRepo, auditRepo, storeCreditRepo)
 			
 			// Initialize customer refund handler
 			customerRefundHandler := handler.NewCustomerRefundHandler(refundSvc, orderService)
@@ -112,13 +190,40 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		products := api.Group("/products")
 		{
 			products.GET("", productHandler.GetProducts)
+			products.GET("/slug/:slug", productHandler.GetProductBySlug)
 			products.GET("/:id", productHandler.GetProductByID)
 			products.GET("/:id/variants", variantHandler.GetProductVariants)
 			products.GET("/:id/with-variants", variantHandler.GetProductWithVariants)
 			products.GET("/:id/options", variantHandler.GetAvailableOptions)
+			products.GET("/:id/bundle", bundleHandler.GetBundle)
+			products.GET("/:id/size-chart", sizeChartHandler.GetProductSizeChart)
+			products.GET("/:id/recommendations", recommendationHandler.GetRecommendations)
+			products.GET("/:id/reviews", reviewHandler.GetProductReviews)
 			products.POST("/variants/find", variantHandler.FindVariant)
 		}
 
+		// Feed routes (public, cached)
+		feeds := api.Group("/feeds")
+		{
+			feeds.GET("/sitemap.xml", feedHandler.GetSitemap)
+			feeds.GET("/products.xml", feedHandler.GetMerchantFeedXML)
+			feeds.GET("/products.tsv", feedHandler.GetMerchantFeedTSV)
+		}
+
+		// Category routes (public)
+		categories := api.Group("/categories")
+		{
+			categories.GET("", categoryHandler.GetCategoryTree)
+			categories.GET("/*ref", categoryHandler.GetCategory)
+		}
+
+		// Collection routes (public, visible collections only)
+		collections := api.Group("/collections")
+		{
+			collections.GET("", productHandler.GetCollections)
+			collections.GET("/:slug", productHandler.GetCollectionProducts)
+		}
+
 		// Variant routes (public)
 		variants := api.Group("/variants")
 		{
@@ -149,6 +254,11 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			wishlist.POST("", wishlistHandler.AddToWishlist)
 			wishlist.DELETE("/:productId", wishlistHandler.RemoveFromWishlist)
 			wishlist.POST("/:productId/move-to-cart", wishlistHandler.MoveToCart)
+
+			// Back-in-stock subscriptions to a sold-out size/color
+			wishlist.GET("/alerts", wishlistHandler.GetStockAlerts)
+			wishlist.POST("/alerts", wishlistHandler.SubscribeToVariant)
+			wishlist.DELETE("/alerts/:id", wishlistHandler.CancelStockAlert)
 		}
 
 		// Shipping routes (public, but with optional auth for user cart lookup)
@@ -172,6 +282,9 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			checkout.POST("/shipping", authHandler.OptionalAuthMiddleware(), checkoutHandler.CheckoutWithShipping)
 		}
 
+		// Voucher preview against the cart (guests and signed-in customers)
+		api.POST("/vouchers/validate", authHandler.OptionalAuthMiddleware(), voucherHandler.ValidateVoucher)
+
 		// Legacy checkout (backward compatibility)
 		api.POST("/checkout", authHandler.OptionalAuthMiddleware(), orderHandler.Checkout)
 		
@@ -243,17 +356,91 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			admin.GET("/products", adminProductHandler.GetAllProducts)
 			admin.POST("/products", adminProductHandler.CreateProduct)
 			admin.POST("/products/upload-image", adminProductHandler.UploadProductImage)
+			admin.POST("/products/import", adminProductHandler.ImportProducts)
+			admin.GET("/products/export", adminProductHandler.ExportProducts)
 			admin.PUT("/products/:id", adminProductHandler.UpdateProduct)
 			admin.PATCH("/products/:id/stock", adminProductHandler.UpdateStock)
 			admin.DELETE("/products/:id", adminProductHandler.DeleteProduct)
 			admin.POST("/products/:id/images", adminProductHandler.AddProductImage)
 			admin.DELETE("/products/:id/images/:imageId", adminProductHandler.DeleteProductImage)
+			admin.GET("/products/:id/look-links", recommendationHandler.GetLookLinks)
+			admin.PUT("/products/:id/look-links", recommendationHandler.SetLookLinks)
+			admin.POST("/recommendations/refresh", recommendationHandler.RefreshCoPurchases)
+
+			// === ADMIN PRODUCT BUNDLES (outfit sets sold from component stock) ===
+			admin.GET("/products/:id/bundle", bundleHandler.GetBundle)
+			admin.PUT("/products/:id/bundle", bundleHandler.SetBundle)
+			admin.DELETE("/products/:id/bundle", bundleHandler.DeleteBundle)
+
+			// === ADMIN PRODUCT SCHEDULES (drop launches, price changes, sales) ===
+			admin.GET("/products/:id/schedules", productScheduleHandler.GetProductSchedules)
+			admin.POST("/products/:id/schedules", productScheduleHandler.CreateSchedule)
+			admin.GET("/product-schedules", productScheduleHandler.ListSchedules)
+			admin.POST("/product-schedules/:id/cancel", productScheduleHandler.CancelSchedule)
+
+			// === ADMIN CATEGORY MANAGEMENT ===
+			admin.GET("/categories", categoryHandler.GetAdminCategoryTree)
+			admin.POST("/categories", categoryHandler.CreateCategory)
+			admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
+			admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+
+			// === ADMIN SIZE CHARTS ===
+			admin.GET("/size-charts", sizeChartHandler.ListSizeCharts)
+			admin.POST("/size-charts", sizeChartHandler.CreateSizeChart)
+			admin.GET("/size-charts/:id", sizeChartHandler.GetSizeChart)
+			admin.PUT("/size-charts/:id", sizeChartHandler.UpdateSizeChart)
+			admin.DELETE("/size-charts/:id", sizeChartHandler.DeleteSizeChart)
+
+			// === ADMIN VOUCHERS ===
+			admin.GET("/vouchers", voucherHandler.ListVouchers)
+			admin.POST("/vouchers", voucherHandler.CreateVoucher)
+			admin.GET("/vouchers/:id", voucherHandler.GetVoucher)
+			admin.PUT("/vouchers/:id", voucherHandler.UpdateVoucher)
+
+			// === ADMIN PROMOTIONS (applied automatically, without a code) ===
+			admin.GET("/promotions", promotionHandler.ListPromotions)
+			admin.POST("/promotions", promotionHandler.CreatePromotion)
+			admin.GET("/promotions/:id", promotionHandler.GetPromotion)
+			admin.PUT("/promotions/:id", promotionHandler.UpdatePromotion)
+			admin.DELETE("/promotions/:id", promotionHandler.DeletePromotion)
+
+			// === ADMIN TAX (PPN) ===
+			admin.GET("/tax/settings", taxHandler.GetSettings)
+			admin.POST("/tax/settings", taxHandler.ScheduleSetting)
+			admin.DELETE("/tax/settings/:id", taxHandler.DeleteSetting)
+			admin.GET("/tax/exempt-products", taxHandler.ListExemptProducts)
+			admin.PUT("/products/:id/tax-exempt", taxHandler.SetProductExempt)
+
+			// === ADMIN INVOICES ===
+			admin.GET("/invoices", invoiceHandler.ListPeriod)
+			admin.GET("/invoices/export", invoiceHandler.ExportPeriod)
+			admin.GET("/invoices/:id/pdf", invoiceHandler.AdminDownloadInvoice)
+
+			// === ADMIN STORE CREDIT & GIFT CARDS ===
+			admin.POST("/users/:id/store-credit", storeCreditHandler.AdjustBalance)
+			admin.GET("/gift-cards", storeCreditHandler.ListGiftCards)
+			admin.POST("/gift-cards/:id/void", storeCreditHandler.VoidGiftCard)
+			admin.PUT("/products/:id/gift-card", storeCreditHandler.SetGiftCardProduct)
+
+			// === ADMIN REVIEW MODERATION ===
+			admin.GET("/reviews", reviewHandler.ListReviews)
+			admin.PUT("/reviews/:id/moderate", reviewHandler.ModerateReview)
+
+			// === ADMIN COLLECTION MANAGEMENT ===
+			admin.GET("/collections", adminProductHandler.ListCollections)
+			admin.POST("/collections", adminProductHandler.CreateCollection)
+			admin.GET("/collections/:id", adminProductHandler.GetCollection)
+			admin.PUT("/collections/:id", adminProductHandler.UpdateCollection)
+			admin.DELETE("/collections/:id", adminProductHandler.DeleteCollection)
+			admin.PUT("/collections/:id/products", adminProductHandler.SetCollectionProducts)
+			admin.GET("/collections/:id/preview", adminProductHandler.PreviewCollection)
 
 			// === ADMIN VARIANT MANAGEMENT ===
 			admin.POST("/variants", variantHandler.CreateVariant)
 			admin.PUT("/variants/:id", variantHandler.UpdateVariant)
 			admin.DELETE("/variants/:id", variantHandler.DeleteVariant)
 			admin.POST("/variants/bulk-generate", variantHandler.BulkGenerateVariants)
+			admin.POST("/variants/attributes", variantHandler.CreateVariantAttribute)
 			admin.POST("/variants/images", variantHandler.AddVariantImage)
 			admin.DELETE("/variants/images/:imageId", variantHandler.DeleteVariantImage)
 			admin.POST("/variants/images/:variantId/primary", variantHandler.SetPrimaryImage)
@@ -264,6 +451,39 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			admin.GET("/variants/stock-summary/:id", variantHandler.GetStockSummary)
 			admin.POST("/variants/reserve-stock", variantHandler.ReserveStock)
 
+			// === ADMIN WAREHOUSES (per-location stock) ===
+			admin.GET("/warehouses", warehouseHandler.ListWarehouses)
+			admin.POST("/warehouses", warehouseHandler.CreateWarehouse)
+			admin.PUT("/warehouses/:id", warehouseHandler.UpdateWarehouse)
+			admin.GET("/warehouses/:id/stock", warehouseHandler.GetStock)
+			admin.PUT("/warehouses/:id/stock", warehouseHandler.SetStock)
+			admin.POST("/warehouses/transfers", warehouseHandler.TransferStock)
+
+			// === ADMIN STOCK LEDGER ===
+			admin.GET("/stock-ledger", stockLedgerHandler.ListLedger)
+			admin.GET("/stock-ledger/reconciliation", stockLedgerHandler.GetReconciliation)
+
+			// === ADMIN SUPPLIERS & PURCHASE ORDERS (inbound stock) ===
+			admin.GET("/suppliers", purchaseOrderHandler.ListSuppliers)
+			admin.POST("/suppliers", purchaseOrderHandler.CreateSupplier)
+			admin.PUT("/suppliers/:id", purchaseOrderHandler.UpdateSupplier)
+			admin.GET("/purchase-orders", purchaseOrderHandler.ListPurchaseOrders)
+			admin.POST("/purchase-orders", purchaseOrderHandler.CreatePurchaseOrder)
+			admin.GET("/purchase-orders/:id", purchaseOrderHandler.GetPurchaseOrder)
+			admin.PUT("/purchase-orders/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+			admin.PATCH("/purchase-orders/:id/status", purchaseOrderHandler.UpdatePurchaseOrderStatus)
+			admin.POST("/purchase-orders/:id/receipts", purchaseOrderHandler.ReceivePurchaseOrder)
+
+			// === ADMIN STOCK TAKES (cycle counts) ===
+			admin.GET("/stock-takes", stockTakeHandler.ListStockTakes)
+			admin.POST("/stock-takes", stockTakeHandler.StartStockTake)
+			admin.GET("/stock-takes/:id", stockTakeHandler.GetStockTake)
+			admin.PUT("/stock-takes/:id/counts", stockTakeHandler.RecordCounts)
+			admin.POST("/stock-takes/:id/scans", stockTakeHandler.ScanBarcode)
+			admin.PUT("/stock-takes/:id/approvals", stockTakeHandler.SetApproval)
+			admin.PATCH("/stock-takes/:id/status", stockTakeHandler.UpdateStockTakeStatus)
+			admin.POST("/stock-takes/:id/post", stockTakeHandler.PostStockTake)
+
 			// === ADMIN ORDER MANAGEMENT ===
 			admin.GET("/orders", adminOrderHandler.GetAllOrders)
 			admin.GET("/orders/stats", adminOrderHandler.GetOrderStats)
@@ -288,7 +508,7 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 			reconciliationRepo := repository.NewReconciliationRepository(db)
 
 			// Initialize hardening services
-			refundSvc := service.NewRefundService(refundRepo, orderRepo, paymentRepo, auditRepo)
+			refundSvc := service.NewRefundService(refundRepo, orderRepo, paymentRepo, auditRepo, storeCreditRepo)
 			adminSvc := service.NewAdminService(orderRepo, paymentRepo, refundRepo, auditRepo, shippingRepo, refundSvc, db)
 			recoverySvc := service.NewPaymentRecoveryService(paymentRepo, orderRepo, syncRepo, db)
 			reconciliationSvc := service.NewReconciliationService(reconciliationRepo, syncRepo, db)
@@ -174,13 +174,17 @@ func (s *adminOrderService) GetOrderDetailAdmin(orderCode string) (*dto.AdminOrd
 		Subtotal:      order.Subtotal,
 		ShippingCost:  order.ShippingCost,
 		Tax:           order.Tax,
+		TaxRate:       order.TaxRate,
+		PricesIncludeTax: order.PricesIncludeTax,
 		Discount:      order.Discount,
 		TotalAmount:   order.TotalAmount,
 		Status:        string(order.Status),
 		Resi:          order.Resi,
 		CreatedAt:     dto.FormatTime(order.CreatedAt),
 		UpdatedAt:     dto.FormatTime(order.UpdatedAt),
+		AwaitingStock: order.AwaitingStock,
 	}
+	response.ExpectedShipDate = dto.FormatDatePtr(order.ExpectedShipDate)
 
 	if order.PaidAt != nil {
 		response.PaidAt = dto.FormatTimePtr(order.PaidAt)
@@ -191,14 +195,23 @@ func (s *adminOrderService) GetOrderDetailAdmin(orderCode string) (*dto.AdminOrd
 
 	// Load items
 	for _, item := range order.Items {
-		response.Items = append(response.Items, dto.OrderItemResponse{
+		itemResponse := dto.OrderItemResponse{
 			ProductID:    item.ProductID,
 			ProductName:  item.ProductName,
 			ProductImage: item.ProductImage,
 			Quantity:     item.Quantity,
 			PricePerUnit: item.PricePerUnit,
 			Subtotal:     item.Subtotal,
-		})
+			DiscountAmount: item.DiscountAmount,
+			TaxRate:        item.TaxRate,
+			TaxAmount:      item.TaxAmount,
+		}
+		if item.BackorderedQuantity > 0 {
+			itemResponse.BackorderedQuantity = item.BackorderedQuantity
+			itemResponse.ExpectedShipDate = dto.FormatDatePtr(item.ExpectedShipDate)
+		}
+		itemResponse.Components = toOrderItemComponents(item)
+		response.Items = append(response.Items, itemResponse)
 	}
 
 	// Load payment info - try order_payments first (Core API), then payments (Snap)
@@ -288,6 +301,12 @@ func (s *adminOrderService) UpdateOrderStatusAdmin(orderCode string, status stri
 			ErrInvalidTransition, order.Status, newStatus)
 	}
 
+	if newStatus == models.OrderStatusPacking {
+		if err := s.allocateBackorders(order); err != nil {
+			return err
+		}
+	}
+
 	// Handle stock restoration for failure states
 	if newStatus.RequiresStockRestore() && order.StockReserved {
 		if err := s.orderRepo.RestoreStock(order.ID); err != nil {
@@ -401,6 +420,7 @@ func (s *adminOrderService) GetOrderStats() (*dto.OrderStatsResponse, error) {
 func (s *adminOrderService) getOrderItems(orderID int) []dto.OrderItemResponse {
 	query := `
 		SELECT oi.product_id, oi.product_name, oi.quantity, oi.price_per_unit, oi.subtotal,
+		       COALESCE(oi.discount_amount, 0), COALESCE(oi.tax_rate, 0), COALESCE(oi.tax_amount, 0),
 		       COALESCE(
 		           (SELECT image_url FROM product_images WHERE product_id = oi.product_id ORDER BY is_primary DESC, display_order ASC LIMIT 1),
 		           ''
@@ -420,7 +440,8 @@ func (s *adminOrderService) getOrderItems(orderID int) []dto.OrderItemResponse {
 		var item dto.OrderItemResponse
 		err := rows.Scan(
 			&item.ProductID, &item.ProductName, &item.Quantity,
-			&item.PricePerUnit, &item.Subtotal, &item.ProductImage,
+			&item.PricePerUnit, &item.Subtotal,
+			&item.DiscountAmount, &item.TaxRate, &item.TaxAmount, &item.ProductImage,
 		)
 		if err != nil {
 			continue
@@ -445,6 +466,10 @@ func (s *adminOrderService) PackOrder(orderCode string, adminEmail string) error
 			ErrInvalidTransition, order.Status)
 	}
 
+	if err := s.allocateBackorders(order); err != nil {
+		return err
+	}
+
 	// Update to PACKING
 	err = s.orderRepo.MarkAsPacking(order.ID)
 	if err != nil {
@@ -461,6 +486,29 @@ func (s *adminOrderService) PackOrder(orderCode string, adminEmail string) error
 	return nil
 }
 
+// allocateBackorders reserves stock that has arrived for a pre-order/backorder order.
+// Orders still waiting for stock cannot be packed.
+func (s *adminOrderService) allocateBackorders(order *models.Order) error {
+	if !order.AwaitingStock {
+		return nil
+	}
+
+	waiting, err := s.orderRepo.AllocateBackorders(order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to allocate backordered stock: %w", err)
+	}
+	if waiting {
+		if order.ExpectedShipDate != nil {
+			return fmt.Errorf("%w: expected %s", ErrAwaitingStock, order.ExpectedShipDate.Format("2006-01-02"))
+		}
+		return ErrAwaitingStock
+	}
+
+	log.Printf("📦 Backordered stock allocated for order %s", order.OrderCode)
+	order.AwaitingStock = false
+	return nil
+}
+
 // GenerateResiOnly generates resi from Biteship WITHOUT shipping the order yet
 // This allows admin to see the resi before confirming shipment
 func (s *adminOrderService) GenerateResiOnly(orderCode string, adminEmail string) (string, error) {
@@ -664,11 +712,15 @@ func (s *adminOrderService) GetOrderActions(orderCode string) ([]dto.OrderAction
 		})
 
 	case models.OrderStatusPaid:
+		packDescription := "Mark order as being packed"
+		if order.AwaitingStock {
+			packDescription = "Waiting for pre-order/backorder stock; packing allocates it once it has arrived"
+		}
 		actions = append(actions, dto.OrderAction{
 			Action:      "pack",
 			Label:       "Pack Order",
 			Enabled:     true,
-			Description: "Mark order as being packed",
+			Description: packDescription,
 		})
 		actions = append(actions, dto.OrderAction{
 			Action:      "cancel",
@@ -4,33 +4,61 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+
+	"github.com/lib/pq"
 )
 
 var (
 	ErrProductNotFound = errors.New("product not found")
 	ErrInvalidSlug     = errors.New("invalid slug format")
 	ErrDuplicateSlug   = errors.New("slug already exists")
+	ErrInvalidSEOURL   = errors.New("canonical_url and og_image_url must be absolute http(s) URLs")
 )
 
 type AdminProductService interface {
 	GetAllProductsAdmin(page, pageSize int, category string, includeInactive bool) ([]dto.AdminProductResponse, int, error)
 	CreateProduct(req dto.CreateProductRequest) (*dto.AdminProductResponse, error)
 	UpdateProduct(id int, req dto.UpdateProductRequest) (*dto.AdminProductResponse, error)
-	UpdateStock(id int, req dto.UpdateStockRequest) (*dto.AdminProductResponse, error)
+	// UpdateStock adjusts the stock of the product's default variant (see repository.StockRepository)
+	UpdateStock(id int, req dto.UpdateStockRequest, adminEmail string) (*dto.AdminProductResponse, error)
 	DeleteProduct(id int) error
 	AddProductImage(productID int, req dto.AddProductImageRequest) (*dto.ProductImageResponse, error)
 	DeleteProductImage(imageID int) error
+
+	// Collections
+	ListCollections() ([]dto.CollectionResponse, error)
+	GetCollection(id int) (*dto.CollectionResponse, error)
+	CreateCollection(req dto.CreateCollectionRequest) (*dto.CollectionResponse, error)
+	UpdateCollection(id int, req dto.UpdateCollectionRequest) (*dto.CollectionResponse, error)
+	DeleteCollection(id int) error
+	SetCollectionProducts(id int, productIDs []int) (*dto.CollectionResponse, error)
+	PreviewCollection(id int, limit, offset int) (*dto.CollectionProductsResponse, error)
+
+	// Bulk import/export (CSV or XLSX)
+	ImportProducts(format string, data []byte, dryRun bool) (*dto.ProductImportReport, error)
+	ExportProducts(format string) ([]byte, error)
 }
 
 type adminProductService struct {
-	db *sql.DB
+	db             *sql.DB
+	productRepo    repository.ProductRepository
+	collectionRepo repository.CollectionRepository
+	stockRepo      repository.StockRepository
 }
 
 func NewAdminProductService(db *sql.DB) AdminProductService {
-	return &adminProductService{db: db}
+	return &adminProductService{
+		db:             db,
+		productRepo:    repository.NewProductRepository(db),
+		collectionRepo: repository.NewCollectionRepository(db),
+		stockRepo:      repository.NewStockRepository(db),
+	}
 }
 
 func (s *adminProductService) GetAllProductsAdmin(page, pageSize int, category string, includeInactive bool) ([]dto.AdminProductResponse, int, error) {
@@ -143,6 +171,18 @@ func (s *adminProductService) CreateProduct(req dto.CreateProductRequest) (*dto.
 		return nil, ErrDuplicateSlug
 	}
 
+	if !isValidSEOURL(req.CanonicalURL) || !isValidSEOURL(req.OGImageURL) {
+		return nil, ErrInvalidSEOURL
+	}
+
+	if req.Category == "" && req.CategoryID == nil {
+		return nil, errors.New("category or category_id is required")
+	}
+	categoryID, category, subcategory, err := s.resolveProductCategory(req.CategoryID, req.Category, req.Subcategory)
+	if err != nil {
+		return nil, err
+	}
+
 	// Default values
 	weight := 500
 	if req.Weight > 0 {
@@ -171,23 +211,43 @@ func (s *adminProductService) CreateProduct(req dto.CreateProductRequest) (*dto.
 
 	// Insert product
 	query := `
-		INSERT INTO products (name, slug, description, price, stock, weight, length, width, height, category, subcategory, brand, material, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		INSERT INTO products (name, slug, description, price, stock, weight, length, width, height, category, subcategory, brand, material, is_active,
+		                      meta_title, meta_description, canonical_url, og_image_url, category_id, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
+		        NULLIF($15, ''), NULLIF($16, ''), NULLIF($17, ''), NULLIF($18, ''), $19, $20)
 		RETURNING id, created_at, updated_at
 	`
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var id int
 	var createdAt, updatedAt sql.NullTime
-	err = s.db.QueryRow(
+	err = tx.QueryRow(
 		query,
 		req.Name, slug, req.Description, req.Price, req.Stock, weight, length, width, height,
-		req.Category, req.Subcategory, req.Brand, req.Material, isActive,
+		category, subcategory, req.Brand, req.Material, isActive,
+		req.MetaTitle, req.MetaDescription, req.CanonicalURL, req.OGImageURL, categoryID,
+		pq.Array(normalizeTags(req.Tags)),
 	).Scan(&id, &createdAt, &updatedAt)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// A new product owning the slug takes precedence over any redirect left by a rename
+	_, err = tx.Exec("DELETE FROM product_slug_history WHERE old_slug = $1", slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clean slug history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Add images if provided
 	for i, imageURL := range req.Images {
 		isPrimary := i == 0
@@ -205,9 +265,11 @@ func (s *adminProductService) CreateProduct(req dto.CreateProductRequest) (*dto.
 
 func (s *adminProductService) UpdateProduct(id int, req dto.UpdateProductRequest) (*dto.AdminProductResponse, error) {
 	// Check product exists
-	var exists bool
-	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", id).Scan(&exists)
-	if err != nil || !exists {
+	var currentSlug, currentCategory, currentSubcategory string
+	err := s.db.QueryRow(
+		"SELECT slug, COALESCE(category, ''), COALESCE(subcategory, '') FROM products WHERE id = $1", id,
+	).Scan(&currentSlug, &currentCategory, &currentSubcategory)
+	if err != nil {
 		return nil, ErrProductNotFound
 	}
 
@@ -221,10 +283,21 @@ func (s *adminProductService) UpdateProduct(id int, req dto.UpdateProductRequest
 		args = append(args, *req.Name)
 		argIndex++
 	}
+	slugChanged := req.Slug != nil && *req.Slug != currentSlug
 	if req.Slug != nil {
 		if !s.isValidSlug(*req.Slug) {
 			return nil, ErrInvalidSlug
 		}
+		if slugChanged {
+			var taken bool
+			err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE slug = $1 AND id <> $2)", *req.Slug, id).Scan(&taken)
+			if err != nil {
+				return nil, err
+			}
+			if taken {
+				return nil, ErrDuplicateSlug
+			}
+		}
 		updates = append(updates, fmt.Sprintf("slug = $%d", argIndex))
 		args = append(args, *req.Slug)
 		argIndex++
@@ -264,14 +337,28 @@ func (s *adminProductService) UpdateProduct(id int, req dto.UpdateProductRequest
 		args = append(args, *req.Height)
 		argIndex++
 	}
-	if req.Category != nil {
+	if req.CategoryID != nil || req.Category != nil || req.Subcategory != nil {
+		category, subcategory := currentCategory, currentSubcategory
+		if req.Category != nil {
+			category = *req.Category
+		}
+		if req.Subcategory != nil {
+			subcategory = *req.Subcategory
+		}
+
+		categoryID, category, subcategory, err := s.resolveProductCategory(req.CategoryID, category, subcategory)
+		if err != nil {
+			return nil, err
+		}
+
 		updates = append(updates, fmt.Sprintf("category = $%d", argIndex))
-		args = append(args, *req.Category)
+		args = append(args, category)
 		argIndex++
-	}
-	if req.Subcategory != nil {
 		updates = append(updates, fmt.Sprintf("subcategory = $%d", argIndex))
-		args = append(args, *req.Subcategory)
+		args = append(args, subcategory)
+		argIndex++
+		updates = append(updates, fmt.Sprintf("category_id = $%d", argIndex))
+		args = append(args, categoryID)
 		argIndex++
 	}
 	if req.Brand != nil {
@@ -289,6 +376,42 @@ func (s *adminProductService) UpdateProduct(id int, req dto.UpdateProductRequest
 		args = append(args, *req.IsActive)
 		argIndex++
 	}
+	if req.IsLimitedDrop != nil {
+		updates = append(updates, fmt.Sprintf("is_limited_drop = $%d", argIndex))
+		args = append(args, *req.IsLimitedDrop)
+		argIndex++
+	}
+	if req.Tags != nil {
+		updates = append(updates, fmt.Sprintf("tags = $%d", argIndex))
+		args = append(args, pq.Array(normalizeTags(req.Tags)))
+		argIndex++
+	}
+	if req.MetaTitle != nil {
+		updates = append(updates, fmt.Sprintf("meta_title = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.MetaTitle)
+		argIndex++
+	}
+	if req.MetaDescription != nil {
+		updates = append(updates, fmt.Sprintf("meta_description = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.MetaDescription)
+		argIndex++
+	}
+	if req.CanonicalURL != nil {
+		if !isValidSEOURL(*req.CanonicalURL) {
+			return nil, ErrInvalidSEOURL
+		}
+		updates = append(updates, fmt.Sprintf("canonical_url = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.CanonicalURL)
+		argIndex++
+	}
+	if req.OGImageURL != nil {
+		if !isValidSEOURL(*req.OGImageURL) {
+			return nil, ErrInvalidSEOURL
+		}
+		updates = append(updates, fmt.Sprintf("og_image_url = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.OGImageURL)
+		argIndex++
+	}
 
 	if len(updates) == 0 {
 		return s.getProductByID(id)
@@ -297,36 +420,90 @@ func (s *adminProductService) UpdateProduct(id int, req dto.UpdateProductRequest
 	updates = append(updates, "updated_at = NOW()")
 	args = append(args, id)
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := fmt.Sprintf("UPDATE products SET %s WHERE id = $%d", strings.Join(updates, ", "), argIndex)
-	_, err = s.db.Exec(query, args...)
+	_, err = tx.Exec(query, args...)
 	if err != nil {
 		return nil, err
 	}
 
+	// Keep the old slug resolvable so existing links and search results redirect
+	if slugChanged {
+		_, err = tx.Exec(`
+			INSERT INTO product_slug_history (product_id, old_slug)
+			VALUES ($1, $2)
+			ON CONFLICT (old_slug) DO UPDATE SET product_id = EXCLUDED.product_id, created_at = NOW()
+		`, id, currentSlug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record slug history: %w", err)
+		}
+
+		// Renaming back to a previous slug must not redirect to itself
+		_, err = tx.Exec("DELETE FROM product_slug_history WHERE old_slug = $1", *req.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clean slug history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return s.getProductByID(id)
 }
 
-func (s *adminProductService) UpdateStock(id int, req dto.UpdateStockRequest) (*dto.AdminProductResponse, error) {
-	// Check product exists and get current stock
-	var currentStock int
-	err := s.db.QueryRow("SELECT stock FROM products WHERE id = $1", id).Scan(&currentStock)
-	if err != nil {
+func (s *adminProductService) UpdateStock(id int, req dto.UpdateStockRequest, adminEmail string) (*dto.AdminProductResponse, error) {
+	var isBundle bool
+	err := s.db.QueryRow("SELECT COALESCE(is_bundle, false) FROM products WHERE id = $1", id).Scan(&isBundle)
+	if err == sql.ErrNoRows {
 		return nil, ErrProductNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+	// A bundle's stock is whatever its components make up
+	if isBundle {
+		return nil, fmt.Errorf("%w: stock of a bundle is managed through its components", ErrInvalidBundle)
+	}
 
-	newStock := currentStock + req.Quantity
-	if newStock < 0 {
-		return nil, errors.New("stock cannot be negative")
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The product-level counter is only a mirror; the change goes to the default variant
+	variantID, err := s.stockRepo.DefaultVariantTx(tx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update stock
-	_, err = s.db.Exec("UPDATE products SET stock = $1, updated_at = NOW() WHERE id = $2", newStock, id)
+	reason := req.Reason
+	if reason == "" {
+		reason = "adjustment"
+	}
+	err = s.stockRepo.ApplyMovementTx(tx, &models.StockLedgerEntry{
+		VariantID:    variantID,
+		MovementType: models.StockMovementAdjustment,
+		Quantity:     req.Quantity,
+		Notes:        reason,
+		Actor:        adminEmail,
+	})
+	if err == repository.ErrInsufficientStock {
+		return nil, errors.New("stock cannot be negative")
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Log stock change (optional - could add stock_history table)
-	// For now, just return updated product
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return s.getProductByID(id)
 }
@@ -426,7 +603,7 @@ func (s *adminProductService) DeleteProductImage(imageID int) error {
 
 func (s *adminProductService) getProductByID(id int) (*dto.AdminProductResponse, error) {
 	query := `
-		SELECT id, name, slug, description, price, stock, 
+		SELECT id, name, slug, description, price, compare_at_price, stock, 
 		       COALESCE(weight, 500) as weight,
 		       COALESCE(length, 30) as length,
 		       COALESCE(width, 20) as width,
@@ -435,7 +612,12 @@ func (s *adminProductService) getProductByID(id int) (*dto.AdminProductResponse,
 		       COALESCE(subcategory, '') as subcategory,
 		       COALESCE(brand, '') as brand,
 		       COALESCE(material, '') as material,
-		       is_active, created_at, updated_at
+		       is_active, is_limited_drop, category_id,
+		       COALESCE(meta_title, '') as meta_title,
+		       COALESCE(meta_description, '') as meta_description,
+		       COALESCE(canonical_url, '') as canonical_url,
+		       COALESCE(og_image_url, '') as og_image_url,
+		       tags, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
@@ -444,14 +626,18 @@ func (s *adminProductService) getProductByID(id int) (*dto.AdminProductResponse,
 	var createdAt, updatedAt sql.NullTime
 
 	err := s.db.QueryRow(query, id).Scan(
-		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.Stock,
+		&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.CompareAtPrice, &p.Stock,
 		&p.Weight, &p.Length, &p.Width, &p.Height, &p.Category, &p.Subcategory,
-		&p.Brand, &p.Material, &p.IsActive,
-		&createdAt, &updatedAt,
+		&p.Brand, &p.Material, &p.IsActive, &p.IsLimitedDrop, &p.CategoryID,
+		&p.MetaTitle, &p.MetaDescription, &p.CanonicalURL, &p.OGImageURL,
+		pq.Array(&p.Tags), &createdAt, &updatedAt,
 	)
 	if err != nil {
 		return nil, ErrProductNotFound
 	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
 
 	if createdAt.Valid {
 		p.CreatedAt = dto.FormatTime(createdAt.Time)
@@ -461,10 +647,74 @@ func (s *adminProductService) getProductByID(id int) (*dto.AdminProductResponse,
 	}
 
 	p.Images = s.getProductImages(id)
+	p.PreviousSlugs = s.getPreviousSlugs(id)
 
 	return &p, nil
 }
 
+// resolveProductCategory links a product to the category tree.
+// An explicit categoryID wins and rewrites the legacy strings from the tree;
+// otherwise the strings are matched to an existing category path (nil if none).
+func (s *adminProductService) resolveProductCategory(categoryID *int, category, subcategory string) (*int, string, string, error) {
+	if categoryID != nil {
+		var path, name string
+		var depth int
+		err := s.db.QueryRow("SELECT path, name, depth FROM categories WHERE id = $1", *categoryID).Scan(&path, &name, &depth)
+		if err == sql.ErrNoRows {
+			return nil, "", "", ErrCategoryNotFound
+		}
+		if err != nil {
+			return nil, "", "", err
+		}
+
+		subcategory = ""
+		if depth > 0 {
+			subcategory = name
+		}
+		return categoryID, models.Category{Path: path}.RootSlug(), subcategory, nil
+	}
+
+	root := strings.ToLower(strings.TrimSpace(category))
+	candidates := []string{root}
+	if sub := slugify(subcategory); sub != "" {
+		candidates = []string{root + "/" + sub, root}
+	}
+
+	for _, path := range candidates {
+		var id int
+		err := s.db.QueryRow("SELECT id FROM categories WHERE path = $1", path).Scan(&id)
+		if err == nil {
+			return &id, category, subcategory, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, "", "", err
+		}
+	}
+
+	return nil, category, subcategory, nil
+}
+
+func (s *adminProductService) getPreviousSlugs(productID int) []string {
+	rows, err := s.db.Query(
+		"SELECT old_slug FROM product_slug_history WHERE product_id = $1 ORDER BY created_at DESC", productID,
+	)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			continue
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs
+}
+
 func (s *adminProductService) getProductImages(productID int) []dto.ProductImageResponse {
 	query := `
 		SELECT id, image_url, is_primary, display_order
@@ -502,6 +752,11 @@ func (s *adminProductService) addImage(productID int, imageURL string, isPrimary
 }
 
 func (s *adminProductService) generateSlug(name string) string {
+	return slugify(name)
+}
+
+// slugify converts a display name to a URL slug ("Kemeja Linen" -> "kemeja-linen")
+func slugify(name string) string {
 	// Convert to lowercase
 	slug := strings.ToLower(name)
 	// Replace spaces with hyphens
@@ -518,9 +773,37 @@ func (s *adminProductService) generateSlug(name string) string {
 }
 
 func (s *adminProductService) isValidSlug(slug string) bool {
+	return isValidSlugFormat(slug)
+}
+
+func isValidSlugFormat(slug string) bool {
 	if slug == "" {
 		return false
 	}
 	matched, _ := regexp.MatchString("^[a-z0-9-]+$", slug)
 	return matched
 }
+
+// isValidSEOURL accepts empty (no override) or an absolute http(s) URL
+func isValidSEOURL(raw string) bool {
+	if raw == "" {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// normalizeTags lowercases, trims and de-duplicates product tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -258,6 +258,7 @@ func (s *adminService) ForceRefund(orderCode string, req *dto.ForceRefundRequest
 		Reason:         req.Reason,
 		Amount:         req.Amount,
 		Items:          req.Items,
+		Destination:    req.Destination,
 		IdempotencyKey: req.IdempotencyKey + "-refund",
 	}
 
@@ -267,8 +268,11 @@ func (s *adminService) ForceRefund(orderCode string, req *dto.ForceRefundRequest
 		return s.logFailedAction(tx, admin, models.AdminActionForceRefund, "order", order.ID, orderCode, req.IdempotencyKey, err.Error())
 	}
 
-	// Process refund (skip gateway if requested or no payment exists)
-	if !skipGateway {
+	// Process refund (skip gateway if requested or no payment exists); store credit refunds
+	// are already complete
+	if refund.Status == models.RefundStatusCompleted {
+		log.Printf("✅ Refund %s completed on creation", refund.RefundCode)
+	} else if !skipGateway {
 		if err := s.refundSvc.ProcessRefund(refund.ID, admin.UserID); err != nil {
 			// Log but don't fail - refund is created
 			log.Printf("⚠️ Gateway refund failed: %v", err)
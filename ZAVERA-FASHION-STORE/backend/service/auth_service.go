@@ -522,6 +522,8 @@ func (s *authService) toOrderResponse(order *models.Order) dto.OrderResponse {
 		Subtotal:      order.Subtotal,
 		ShippingCost:  order.ShippingCost,
 		Tax:           order.Tax,
+		TaxRate:       order.TaxRate,
+		PricesIncludeTax: order.PricesIncludeTax,
 		Discount:      order.Discount,
 		TotalAmount:   order.TotalAmount,
 		Status:        string(order.Status),
@@ -549,7 +551,11 @@ func (s *authService) toOrderResponse(order *models.Order) dto.OrderResponse {
 			Quantity:     item.Quantity,
 			PricePerUnit: item.PricePerUnit,
 			Subtotal:     item.Subtotal,
+			DiscountAmount: item.DiscountAmount,
+			TaxRate:        item.TaxRate,
+			TaxAmount:      item.TaxAmount,
 		}
+		itemResponse.Components = toOrderItemComponents(item)
 		response.Items = append(response.Items, itemResponse)
 	}
 
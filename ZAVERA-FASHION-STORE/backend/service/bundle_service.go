@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrBundleNotFound = errors.New("bundle not found")
+	ErrInvalidBundle  = errors.New("invalid bundle")
+)
+
+// BundleService manages bundle products: products sold at their own price that are made of
+// component variants. Orders reserve the components; a bundle has no stock of its own.
+type BundleService interface {
+	GetBundle(productID int) (*dto.BundleResponse, error)
+	SetComponents(productID int, req dto.SetBundleRequest, adminEmail string) (*dto.BundleResponse, error)
+	Unbundle(productID int, adminEmail string) error
+}
+
+type bundleService struct {
+	bundleRepo  repository.BundleRepository
+	productRepo repository.ProductRepository
+	variantRepo *repository.VariantRepository
+}
+
+func NewBundleService(bundleRepo repository.BundleRepository, productRepo repository.ProductRepository, variantRepo *repository.VariantRepository) BundleService {
+	return &bundleService{
+		bundleRepo:  bundleRepo,
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+	}
+}
+
+func (s *bundleService) GetBundle(productID int) (*dto.BundleResponse, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	if !product.IsBundle {
+		return nil, ErrBundleNotFound
+	}
+
+	components, err := s.bundleRepo.GetComponents(productID)
+	if err != nil {
+		return nil, err
+	}
+	return toBundleResponse(product, components), nil
+}
+
+func (s *bundleService) SetComponents(productID int, req dto.SetBundleRequest, adminEmail string) (*dto.BundleResponse, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	// The bundle's own stock would be stranded, and nested bundles are not supported
+	own, err := s.variantRepo.GetByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range own {
+		if v.IsActive && v.StockQuantity > 0 {
+			return nil, fmt.Errorf("%w: %s still has stock of its own (%s)", ErrInvalidBundle, product.Name, v.SKU)
+		}
+	}
+	if isComponent, err := s.bundleRepo.IsComponent(productID); err != nil {
+		return nil, err
+	} else if isComponent {
+		return nil, fmt.Errorf("%w: %s is part of another bundle", ErrInvalidBundle, product.Name)
+	}
+
+	components := make([]models.BundleComponent, 0, len(req.Components))
+	seen := map[int]bool{}
+	for i, c := range req.Components {
+		if seen[c.VariantID] {
+			return nil, fmt.Errorf("%w: variant %d is listed twice", ErrInvalidBundle, c.VariantID)
+		}
+		seen[c.VariantID] = true
+
+		variant, err := s.variantRepo.GetByID(c.VariantID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: variant %d not found", ErrInvalidBundle, c.VariantID)
+		}
+		if variant.ProductID == productID {
+			return nil, fmt.Errorf("%w: a bundle cannot contain itself", ErrInvalidBundle)
+		}
+		componentProduct, err := s.productRepo.FindByID(variant.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: product of variant %d not found", ErrInvalidBundle, c.VariantID)
+		}
+		if componentProduct.IsBundle {
+			return nil, fmt.Errorf("%w: %s is a bundle itself", ErrInvalidBundle, componentProduct.Name)
+		}
+
+		quantity := c.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		components = append(components, models.BundleComponent{
+			VariantID: c.VariantID,
+			Quantity:  quantity,
+			Position:  i,
+		})
+	}
+
+	if err := s.bundleRepo.SetComponents(productID, components); err != nil {
+		if err == repository.ErrBundleNotFound {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	log.Printf("📦 Bundle %s set to %d components by %s", product.Name, len(components), adminEmail)
+
+	return s.GetBundle(productID)
+}
+
+func (s *bundleService) Unbundle(productID int, adminEmail string) error {
+	err := s.bundleRepo.Unbundle(productID)
+	if err == repository.ErrBundleNotFound {
+		return ErrBundleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("📦 Product %d is no longer a bundle (by %s)", productID, adminEmail)
+	return nil
+}
+
+func toBundleResponse(product *models.Product, components []models.BundleComponent) *dto.BundleResponse {
+	response := &dto.BundleResponse{
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Price:       product.Price,
+		Available:   models.BundleAvailable(components),
+		Components:  make([]dto.BundleComponentResponse, 0, len(components)),
+	}
+	shares := models.AllocateBundlePrice(product.Price, components)
+	for i, c := range components {
+		response.Components = append(response.Components, dto.BundleComponentResponse{
+			BundleComponent: c,
+			AllocatedPrice:  shares[i],
+		})
+	}
+	return response
+}
@@ -3,6 +3,10 @@ package service
 import (
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/repository"
@@ -26,17 +30,78 @@ type CartService interface {
 }
 
 type cartService struct {
-	cartRepo    repository.CartRepository
-	productRepo repository.ProductRepository
+	cartRepo        repository.CartRepository
+	productRepo     repository.ProductRepository
+	reservationRepo repository.ReservationRepository
+	promotions      PromotionService
 }
 
-func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository) CartService {
+func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository, reservationRepo repository.ReservationRepository, promotions PromotionService) CartService {
 	return &cartService{
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		cartRepo:        cartRepo,
+		productRepo:     productRepo,
+		reservationRepo: reservationRepo,
+		promotions:      promotions,
 	}
 }
 
+// cartHoldMinutes is how long a limited-drop item stays held in a cart (CART_HOLD_MINUTES, default 10)
+func cartHoldMinutes() int {
+	if env := os.Getenv("CART_HOLD_MINUTES"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 10
+}
+
+// heldInCart is how many units of a product the cart already holds. Held units are off the
+// shelf, so product.Stock no longer counts them.
+func heldInCart(cart *models.Cart, productID int) int {
+	held := 0
+	for _, item := range cart.Items {
+		if item.ProductID == productID && item.Hold != nil {
+			held += item.Hold.Quantity
+		}
+	}
+	return held
+}
+
+// cartItemQuantity is the quantity of a cart item before a change, 0 when it is new
+func cartItemQuantity(cart *models.Cart, itemID int) int {
+	for _, item := range cart.Items {
+		if item.ID == itemID {
+			return item.Quantity
+		}
+	}
+	return 0
+}
+
+// holdLimitedDrop (re)holds the stock of a limited-drop item for its new quantity. When the
+// stock is not there the item goes back to previousQuantity, or leaves the cart if it was new.
+func (s *cartService) holdLimitedDrop(item *models.CartItem, customerID *int, sessionID string, previousQuantity int) error {
+	limited, err := s.reservationRepo.IsLimitedDrop(item.ProductID)
+	if err != nil || !limited {
+		return err
+	}
+
+	_, err = s.reservationRepo.HoldCartItem(item, customerID, sessionID, cartHoldMinutes())
+	if err == nil {
+		return nil
+	}
+
+	if previousQuantity > 0 {
+		item.Quantity = previousQuantity
+		s.cartRepo.UpdateItem(item)
+	} else {
+		s.cartRepo.DeleteItem(item.ID)
+	}
+	if err == repository.ErrInsufficientStock {
+		return errors.New("insufficient stock")
+	}
+	return err
+}
+
 func (s *cartService) GetCart(sessionID string) (*dto.CartResponse, error) {
 	cart, err := s.cartRepo.FindOrCreateBySessionID(sessionID)
 	if err != nil {
@@ -67,7 +132,7 @@ func (s *cartService) AddToCart(sessionID string, req dto.AddToCartRequest) (*dt
 	// Stock validation:
 	// - If product.Stock > 0: Simple product, check product stock
 	// - If product.Stock = 0: Variant-based product, skip check here (variant stock checked at checkout)
-	if product.Stock > 0 && product.Stock < req.Quantity {
+	if product.Stock > 0 && !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < req.Quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
@@ -86,6 +151,10 @@ func (s *cartService) AddToCart(sessionID string, req dto.AddToCartRequest) (*dt
 		return nil, err
 	}
 
+	if err := s.holdLimitedDrop(cartItem, nil, sessionID, cartItemQuantity(cart, cartItem.ID)); err != nil {
+		return nil, err
+	}
+
 	// Return updated cart
 	return s.GetCart(sessionID)
 }
@@ -124,17 +193,22 @@ func (s *cartService) UpdateCartItem(sessionID string, itemID int, quantity int)
 	// Stock validation:
 	// - If product.Stock > 0: Simple product, check product stock
 	// - If product.Stock = 0: Variant-based product, skip check here
-	if product.Stock > 0 && product.Stock < quantity {
+	if product.Stock > 0 && !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
 	// Update quantity
+	previousQuantity := targetItem.Quantity
 	targetItem.Quantity = quantity
 	err = s.cartRepo.UpdateItem(targetItem)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.holdLimitedDrop(targetItem, nil, sessionID, previousQuantity); err != nil {
+		return nil, err
+	}
+
 	// Return updated cart
 	return s.GetCart(sessionID)
 }
@@ -210,7 +284,7 @@ func (s *cartService) AddToCartForUser(userID int, sessionID string, req dto.Add
 	// Stock validation:
 	// - If product.Stock > 0: Simple product, check product stock
 	// - If product.Stock = 0: Variant-based product, skip check here
-	if product.Stock > 0 && product.Stock < req.Quantity {
+	if product.Stock > 0 && !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < req.Quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
@@ -229,6 +303,10 @@ func (s *cartService) AddToCartForUser(userID int, sessionID string, req dto.Add
 		return nil, err
 	}
 
+	if err := s.holdLimitedDrop(cartItem, &userID, sessionID, cartItemQuantity(cart, cartItem.ID)); err != nil {
+		return nil, err
+	}
+
 	return s.GetCartForUser(userID, sessionID)
 }
 
@@ -265,16 +343,21 @@ func (s *cartService) UpdateCartItemForUser(userID int, sessionID string, itemID
 	// Stock validation:
 	// - If product.Stock > 0: Simple product, check product stock
 	// - If product.Stock = 0: Variant-based product, skip check here
-	if product.Stock > 0 && product.Stock < quantity {
+	if product.Stock > 0 && !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
+	previousQuantity := targetItem.Quantity
 	targetItem.Quantity = quantity
 	err = s.cartRepo.UpdateItem(targetItem)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.holdLimitedDrop(targetItem, &userID, sessionID, previousQuantity); err != nil {
+		return nil, err
+	}
+
 	return s.GetCartForUser(userID, sessionID)
 }
 
@@ -320,6 +403,7 @@ func (s *cartService) toCartResponse(cart *models.Cart) (*dto.CartResponse, erro
 
 	var subtotal float64
 	var itemCount int
+	now := time.Now()
 
 	for _, item := range cart.Items {
 		// Get product details
@@ -350,10 +434,20 @@ func (s *cartService) toCartResponse(cart *models.Cart) (*dto.CartResponse, erro
 			Quantity:     item.Quantity,
 			PricePerUnit: item.PriceSnapshot,
 			Subtotal:     item.PriceSnapshot * float64(item.Quantity),
-			Stock:        product.Stock,
+			Stock:        product.Stock + heldInCart(cart, item.ProductID),
 			Metadata:     item.Metadata,
 		}
 
+		if item.Hold != nil {
+			expiresAt := item.Hold.ExpiresAt
+			itemResponse.HeldQuantity = item.Hold.Quantity
+			itemResponse.HoldExpiresAt = &expiresAt
+			itemResponse.HoldSecondsLeft = item.Hold.SecondsLeft(now)
+			if response.HoldExpiresAt == nil || expiresAt.Before(*response.HoldExpiresAt) {
+				response.HoldExpiresAt = &expiresAt
+			}
+		}
+
 		response.Items = append(response.Items, itemResponse)
 		subtotal += itemResponse.Subtotal
 		itemCount += item.Quantity
@@ -361,6 +455,13 @@ func (s *cartService) toCartResponse(cart *models.Cart) (*dto.CartResponse, erro
 
 	response.Subtotal = subtotal
 	response.ItemCount = itemCount
+	response.Total = subtotal
+	response.Promotions = []models.AppliedPromotion{}
+
+	// Same evaluation as checkout, so the cart shows what the order will charge
+	if err := applyCartPromotions(s.promotions, cart, response); err != nil {
+		log.Printf("⚠️ Failed to evaluate promotions for cart %d: %v", cart.ID, err)
+	}
 
 	return response, nil
 }
@@ -406,15 +507,21 @@ func (s *cartService) ValidateCart(userID int, sessionID string) (*dto.CartValid
 		// - If product.Stock > 0: Simple product, validate stock
 		// - If product.Stock = 0: Variant product, skip validation (stock in variants)
 		if product.Stock > 0 {
-			// Simple product - check stock
-			if product.Stock < item.Quantity {
+			// Simple product - check stock, counting what this cart already holds
+			available := product.Stock
+			for _, other := range cart.Items {
+				if other.ProductID == item.ProductID {
+					available += other.HeldQuantity
+				}
+			}
+			if available < item.Quantity {
 				changes = append(changes, dto.CartItemChange{
 					CartItemID:   item.ID,
 					ProductID:    item.ProductID,
 					ProductName:  item.ProductName,
 					ChangeType:   "stock_insufficient",
-					CurrentStock: product.Stock,
-					Message:      fmt.Sprintf("Only %d items available", product.Stock),
+					CurrentStock: available,
+					Message:      fmt.Sprintf("Only %d items available", available),
 				})
 				hasChanges = true
 			}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryHasChildren = errors.New("category has subcategories; move or delete them first")
+	ErrCategoryHasProducts = errors.New("category still has products; reassign them first")
+	ErrCategoryCycle       = errors.New("category cannot be moved under itself or its descendants")
+	ErrDuplicateCategory   = errors.New("a category with this slug already exists under the same parent")
+)
+
+// errCategoryHidden is ErrCategoryNotFound for a category an admin has deactivated, so product
+// listings can tell it from an unknown ref and show nothing instead of matching legacy strings
+var errCategoryHidden = fmt.Errorf("%w: category is inactive", ErrCategoryNotFound)
+
+type CategoryService interface {
+	GetTree(includeInactive bool) ([]dto.CategoryResponse, error)
+	GetCategory(ref string) (*dto.CategoryResponse, error)
+	ResolveCategoryIDs(ref string) ([]int, error)
+	CreateCategory(req dto.CreateCategoryRequest) (*dto.CategoryResponse, error)
+	UpdateCategory(id int, req dto.UpdateCategoryRequest) (*dto.CategoryResponse, error)
+	DeleteCategory(id int) error
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo}
+}
+
+// GetTree returns the nested category tree ordered by sort_order
+func (s *categoryService) GetTree(includeInactive bool) ([]dto.CategoryResponse, error) {
+	categories, err := s.categoryRepo.FindAll(includeInactive)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := models.BuildCategoryTree(categories)
+	response := make([]dto.CategoryResponse, 0, len(tree))
+	for _, c := range tree {
+		response = append(response, toCategoryResponse(c))
+	}
+	return response, nil
+}
+
+// GetCategory returns an active category subtree by numeric ID or slug path
+func (s *categoryService) GetCategory(ref string) (*dto.CategoryResponse, error) {
+	category, err := s.findByRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.isVisible(category)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrCategoryNotFound
+	}
+
+	// Attach active descendants
+	all, err := s.categoryRepo.FindAll(false)
+	if err != nil {
+		return nil, err
+	}
+	var subtree []models.Category
+	for _, c := range all {
+		if c.ID == category.ID || strings.HasPrefix(c.Path, category.Path+"/") {
+			subtree = append(subtree, c)
+		}
+	}
+	if len(subtree) == 0 {
+		// Deactivated since it was looked up
+		return nil, ErrCategoryNotFound
+	}
+
+	response := toCategoryResponse(models.BuildCategoryTree(subtree)[0])
+	return &response, nil
+}
+
+// ResolveCategoryIDs turns an active category ID or path into the IDs of the category and its active descendants
+func (s *categoryService) ResolveCategoryIDs(ref string) ([]int, error) {
+	category, err := s.findByRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.isVisible(category)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errCategoryHidden
+	}
+	return s.categoryRepo.FindDescendantIDs(category.ID, false)
+}
+
+func (s *categoryService) CreateCategory(req dto.CreateCategoryRequest) (*dto.CategoryResponse, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !isValidSlugFormat(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	category := &models.Category{
+		Name:      strings.TrimSpace(req.Name),
+		Slug:      slug,
+		Path:      slug,
+		SortOrder: req.SortOrder,
+		IsActive:  true,
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+	if req.ImageURL != "" {
+		category.ImageURL = &req.ImageURL
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.categoryRepo.FindByID(*req.ParentID)
+		if err != nil {
+			return nil, s.mapError(err)
+		}
+		category.ParentID = &parent.ID
+		category.Path = parent.Path + "/" + slug
+		category.Depth = parent.Depth + 1
+	}
+
+	if err := s.ensurePathAvailable(category.Path, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Create(category); err != nil {
+		return nil, err
+	}
+
+	response := toCategoryResponse(*category)
+	return &response, nil
+}
+
+func (s *categoryService) UpdateCategory(id int, req dto.UpdateCategoryRequest) (*dto.CategoryResponse, error) {
+	category, err := s.categoryRepo.FindByID(id)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	oldPath := category.Path
+
+	if req.Name != nil {
+		category.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Slug != nil {
+		if !isValidSlugFormat(*req.Slug) {
+			return nil, ErrInvalidSlug
+		}
+		category.Slug = *req.Slug
+	}
+	if req.SortOrder != nil {
+		category.SortOrder = *req.SortOrder
+	}
+	if req.ImageURL != nil {
+		if *req.ImageURL == "" {
+			category.ImageURL = nil
+		} else {
+			category.ImageURL = req.ImageURL
+		}
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	// Re-parent
+	parentPath := ""
+	if req.MoveToRoot {
+		category.ParentID = nil
+		category.Depth = 0
+	} else {
+		parentID := category.ParentID
+		if req.ParentID != nil {
+			parentID = req.ParentID
+		}
+		if parentID != nil {
+			parent, err := s.categoryRepo.FindByID(*parentID)
+			if err != nil {
+				return nil, s.mapError(err)
+			}
+			if parent.ID == category.ID || strings.HasPrefix(parent.Path, oldPath+"/") {
+				return nil, ErrCategoryCycle
+			}
+			category.ParentID = &parent.ID
+			category.Depth = parent.Depth + 1
+			parentPath = parent.Path
+		}
+	}
+
+	category.Path = category.Slug
+	if parentPath != "" {
+		category.Path = parentPath + "/" + category.Slug
+	}
+
+	if category.Path != oldPath {
+		if err := s.ensurePathAvailable(category.Path, category.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.categoryRepo.Update(category, oldPath); err != nil {
+		return nil, err
+	}
+
+	response := toCategoryResponse(*category)
+	return &response, nil
+}
+
+// DeleteCategory removes an empty leaf category
+func (s *categoryService) DeleteCategory(id int) error {
+	if _, err := s.categoryRepo.FindByID(id); err != nil {
+		return s.mapError(err)
+	}
+
+	children, err := s.categoryRepo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	products, err := s.categoryRepo.CountProducts(id)
+	if err != nil {
+		return err
+	}
+	if products > 0 {
+		return ErrCategoryHasProducts
+	}
+
+	return s.mapError(s.categoryRepo.Delete(id))
+}
+
+// isVisible reports whether the storefront shows a category: it and every category above it are active
+func (s *categoryService) isVisible(category *models.Category) (bool, error) {
+	if !category.IsActive {
+		return false, nil
+	}
+	hidden, err := s.categoryRepo.HasInactiveAncestor(category.Path)
+	return !hidden, err
+}
+
+func (s *categoryService) findByRef(ref string) (*models.Category, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, ErrCategoryNotFound
+	}
+
+	var category *models.Category
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil {
+		category, err = s.categoryRepo.FindByID(id)
+	} else {
+		category, err = s.categoryRepo.FindByPath(ref)
+	}
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return category, nil
+}
+
+func (s *categoryService) ensurePathAvailable(path string, selfID int) error {
+	existing, err := s.categoryRepo.FindByPath(path)
+	if err == repository.ErrCategoryNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != selfID {
+		return ErrDuplicateCategory
+	}
+	return nil
+}
+
+func (s *categoryService) mapError(err error) error {
+	if err == repository.ErrCategoryNotFound {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func toCategoryResponse(c models.Category) dto.CategoryResponse {
+	response := dto.CategoryResponse{
+		ID:        c.ID,
+		ParentID:  c.ParentID,
+		Name:      c.Name,
+		Slug:      c.Slug,
+		Path:      c.Path,
+		Depth:     c.Depth,
+		SortOrder: c.SortOrder,
+		IsActive:  c.IsActive,
+	}
+	if c.ImageURL != nil {
+		response.ImageURL = *c.ImageURL
+	}
+	for _, child := range c.Children {
+		response.Children = append(response.Children, toCategoryResponse(child))
+	}
+	return response
+}
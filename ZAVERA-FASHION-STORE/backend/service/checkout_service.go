@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/repository"
@@ -30,6 +31,11 @@ type checkoutService struct {
 	productRepo  repository.ProductRepository
 	shippingRepo repository.ShippingRepository
 	emailRepo    repository.EmailRepository
+	warehouses   WarehouseService
+	promotions   PromotionService
+	vouchers     VoucherService
+	taxes        TaxService
+	storeCredit  StoreCreditService
 	biteship     *BiteshipClient
 	emailService EmailService
 }
@@ -40,6 +46,11 @@ func NewCheckoutService(
 	productRepo repository.ProductRepository,
 	shippingRepo repository.ShippingRepository,
 	emailRepo repository.EmailRepository,
+	warehouses WarehouseService,
+	promotions PromotionService,
+	vouchers VoucherService,
+	taxes TaxService,
+	storeCredit StoreCreditService,
 ) CheckoutService {
 	// Create email service
 	var emailSvc EmailService
@@ -52,6 +63,11 @@ func NewCheckoutService(
 		cartRepo:     cartRepo,
 		productRepo:  productRepo,
 		shippingRepo: shippingRepo,
+		warehouses:   warehouses,
+		promotions:   promotions,
+		vouchers:     vouchers,
+		taxes:        taxes,
+		storeCredit:  storeCredit,
 		biteship:     NewBiteshipClient(),
 		emailService: emailSvc,
 	}
@@ -154,7 +170,8 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		// - If product.Stock > 0: Simple product, validate stock
 		// - If product.Stock = 0: Variant product, skip validation here (stock in variants)
 		// Note: For variant products, stock will be validated and deducted at variant level during order creation
-		if product.Stock > 0 && product.Stock < item.Quantity {
+		// - Pre-order/backorder variants may exceed stock, within their cap (checked at order creation)
+		if product.Stock > 0 && !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < item.Quantity {
 			return nil, fmt.Errorf("%w for product: %s", ErrInsufficientStock, product.Name)
 		}
 
@@ -225,14 +242,21 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		totalWeight = 1000
 	}
 
-	// 4. Get shipping rate from Biteship API using postal_code
+	// 4. Route to the nearest warehouse that has every item, then get the shipping rate
+	// from that warehouse using postal_code
+	warehouse, err := s.warehouses.RouteOrder(cartStockLines(cart.Items), cartHolds(cart.Items), destinationAreaID, destinationPostalCode)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🏬 Order routed to warehouse %s (%s)", warehouse.Code, warehouse.CityName)
+
 	destPostalCode, _ := strconv.Atoi(destinationPostalCode)
 	if destPostalCode == 0 {
 		destPostalCode = 10110 // Default Jakarta if no postal code
 	}
 	
 	biteshipReq := GetRatesRequest{
-		OriginPostalCode:      DefaultOriginPostalCode, // Pedurungan, Semarang 50113
+		OriginPostalCode:      originPostalCode(warehouse),
 		DestinationPostalCode: destPostalCode,
 		Couriers:              courierCode,
 		Items:                 biteshipItems, // Send individual items with dimensions
@@ -276,10 +300,29 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		}
 	}
 
-	// 5. Calculate totals
-	tax := 0.0
-	discount := 0.0
-	totalAmount := subtotal + shippingCost + tax - discount
+	// 5. Calculate totals: the cart's promotions (now with shipping), then a voucher on what is
+	// left; the voucher is checked here and redeemed with the order
+	shippingRegion := addressSnapshot.ProvinceName
+	if shippingRegion == "" {
+		shippingRegion = destinationAreaName
+	}
+	promotions, err := s.promotions.EvaluateCart(cart, &models.PromotionShipping{Cost: shippingCost, Region: shippingRegion})
+	if err != nil {
+		return nil, err
+	}
+
+	discount := promotions.Total()
+	var redemption *models.VoucherRedemption
+	var voucherDiscount models.VoucherDiscount
+	if strings.TrimSpace(req.VoucherCode) != "" {
+		redemption, voucherDiscount, err = s.vouchers.Quote(req.VoucherCode, discountedItems(orderItems, cart, promotions),
+			shippingCost-promotions.ShippingSavings, userID, req.CustomerEmail)
+		if err != nil {
+			return nil, err
+		}
+		discount += voucherDiscount.Total()
+	}
+	setItemDiscounts(orderItems, cart, promotions, voucherDiscount)
 
 	// 6. Create order with shipping locked
 	addressJSON, _ := json.Marshal(addressSnapshot)
@@ -291,10 +334,10 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		CustomerPhone: req.CustomerPhone,
 		Subtotal:      subtotal,
 		ShippingCost:  shippingCost,
-		Tax:           tax,
 		Discount:      discount,
-		TotalAmount:   totalAmount,
 		Status:        models.OrderStatusPending,
+		OriginCity:    warehouse.Name,
+		WarehouseID:   &warehouse.ID,
 		Notes:         req.Notes,
 		Metadata: map[string]any{
 			"shipping_courier_code":     courierCode,
@@ -308,12 +351,38 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 			"destination_city_id":       destinationCityID,
 			"destination_city_name":     destinationCityName,
 			"shipping_source":           "biteship",
+			"warehouse_code":            warehouse.Code,
 		},
 	}
 
+	order.CartID = &cart.ID
+	if len(promotions.Applied) > 0 {
+		order.Metadata["promotions"] = promotions.Applied
+	}
+	if redemption != nil {
+		order.Voucher = redemption
+		order.Metadata["voucher_code"] = redemption.Code
+	}
+
+	// PPN on each line after its discounts, which also sets the total
+	if err := s.taxes.ApplyTax(order, orderItems); err != nil {
+		return nil, err
+	}
+
+	// Store credit pays first; it is debited with the order and Midtrans charges the rest
+	if req.UseStoreCredit {
+		order.StoreCreditAmount, err = s.storeCredit.QuoteCheckout(userID, order.TotalAmount, req.StoreCreditAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = s.orderRepo.Create(order, orderItems)
+	if err == repository.ErrInsufficientStoreCredit {
+		return nil, ErrInsufficientStoreCredit
+	}
 	if err != nil {
-		return nil, err
+		return nil, voucherRedeemError(err)
 	}
 
 	// Send notification to admin dashboard
@@ -324,6 +393,11 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 	NotifyOrderCreated(order.OrderCode, customerName, order.TotalAmount)
 
 	// 7. Create shipment record (status: PENDING until payment)
+	shipmentStatus := models.ShipmentStatusPending
+	if order.Status == models.OrderStatusPaid {
+		// Nothing left to pay after store credit and discounts
+		shipmentStatus = models.ShipmentStatusProcessing
+	}
 	shipment := &models.Shipment{
 		OrderID:             order.ID,
 		ProviderCode:        courierCode,
@@ -333,9 +407,9 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		Cost:                shippingCost,
 		ETD:                 etd,
 		Weight:              totalWeight,
-		Status:              models.ShipmentStatusPending,
-		OriginCityID:        DefaultOriginCityID,
-		OriginCityName:      "Kota Semarang",
+		Status:              shipmentStatus,
+		OriginCityID:        originCityID(warehouse),
+		OriginCityName:      warehouse.CityName,
 		DestinationCityID:   destinationCityID,
 		DestinationCityName: destinationCityName,
 	}
@@ -375,14 +449,14 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		})
 	}
 	
-	// Create draft order params
+	// Create draft order params (origin = fulfilling warehouse)
 	// IMPORTANT: Use postal_code instead of area_id for better compatibility
 	draftParams := CreateDraftOrderParams{
 		OriginAreaID:            "", // Leave empty, use postal_code instead
-		OriginAddress:           "Jl. Pedurungan Tengah, Pedurungan, Semarang",
-		OriginPostalCode:        fmt.Sprintf("%d", DefaultOriginPostalCode),
-		OriginContactName:       "ZAVERA Fashion Store",
-		OriginContactPhone:      "081234567890",
+		OriginAddress:           warehouse.Address,
+		OriginPostalCode:        fmt.Sprintf("%d", originPostalCode(warehouse)),
+		OriginContactName:       warehouse.ContactName,
+		OriginContactPhone:      warehouse.ContactPhone,
 		DestinationAreaID:       "", // Leave empty, use postal_code instead
 		DestinationAddress:      addressSnapshot.FullAddress,
 		DestinationPostalCode:   destinationPostalCode,
@@ -394,7 +468,7 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 	}
 	
 	// Create draft order via shipping service
-	shippingSvc := NewShippingService(s.shippingRepo, s.cartRepo, s.productRepo, s.orderRepo)
+	shippingSvc := NewShippingService(s.shippingRepo, s.cartRepo, s.productRepo, s.orderRepo, s.warehouses)
 	draftResp, err := shippingSvc.CreateDraftOrderForCheckout(order.ID, draftParams)
 	if err != nil {
 		log.Printf("⚠️ Failed to create Biteship draft order: %v (will fallback to manual resi)", err)
@@ -410,11 +484,15 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		Service:               courierServiceCode,
 		Cost:                  shippingCost,
 		ETD:                   etd,
-		OriginCityID:          DefaultOriginCityID,
-		OriginCityName:        "Kota Semarang",
+		OriginCityID:          originCityID(warehouse),
+		OriginCityName:        warehouse.CityName,
+		OriginAreaID:          warehouse.AreaID,
+		OriginAreaName:        warehouse.Name,
 		DestinationCityID:     destinationCityID,
 		DestinationCityName:   destinationCityName,
 		DestinationDistrictID: destinationPostalCode,
+		DestinationAreaID:     destinationAreaID,
+		DestinationAreaName:   destinationAreaName,
 		Weight:                totalWeight,
 		BiteshipRawJSON: map[string]any{
 			"courier_code":   courierCode,
@@ -425,11 +503,17 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 			"etd":            etd,
 			"weight":         totalWeight,
 			"source":         "biteship",
+			"warehouse_code": warehouse.Code,
 			"snapshot_time":  order.CreatedAt,
 		},
 	}
 	s.shippingRepo.CreateShippingSnapshot(shippingSnapshot)
 
+	// 8. An order with nothing left to pay was paid when it was created; Midtrans is skipped
+	if order.Status == models.OrderStatusPaid {
+		s.storeCredit.OrderPaid(order)
+	}
+
 	// 9. Clear cart after successful checkout
 	s.cartRepo.ClearCart(cart.ID)
 	
@@ -443,7 +527,15 @@ func (s *checkoutService) CheckoutWithShipping(sessionID string, req dto.Checkou
 		OrderCode:      order.OrderCode,
 		Subtotal:       subtotal,
 		ShippingCost:   shippingCost,
-		TotalAmount:    totalAmount,
+		Discount:       discount,
+		Tax:            order.Tax,
+		TaxRate:        order.TaxRate,
+		PricesIncludeTax: order.PricesIncludeTax,
+		Promotions:     promotions.Applied,
+		VoucherCode:    voucherCode(redemption),
+		TotalAmount:    order.TotalAmount,
+		StoreCreditAmount: order.StoreCreditAmount,
+		AmountDue:      order.AmountDue(),
 		Status:         string(order.Status),
 		ShippingLocked: true,
 		Provider:       providerName,
@@ -540,7 +632,10 @@ func (s *checkoutService) GetCartShippingOptions(sessionID string, destinationPo
 		return nil, fmt.Errorf("invalid postal code")
 	}
 
-	fmt.Printf("📡 Getting Biteship rates - Origin: %d (Pedurungan, Semarang), Dest: %d, Weight: %d, Items: %d, Courier: %s\n", DefaultOriginPostalCode, destPostalCode, totalWeight, len(biteshipItems), courier)
+	// Quote from the warehouse checkout would route the order to
+	origin := quoteOrigin(s.warehouses, cart.Items, "", destinationPostalCode)
+
+	fmt.Printf("📡 Getting Biteship rates - Origin: %d (%s), Dest: %d, Weight: %d, Items: %d, Courier: %s\n", originPostalCode(origin), origin.CityName, destPostalCode, totalWeight, len(biteshipItems), courier)
 	
 	// Debug: Print each item details
 	for i, item := range biteshipItems {
@@ -549,7 +644,7 @@ func (s *checkoutService) GetCartShippingOptions(sessionID string, destinationPo
 	}
 
 	biteshipReq := GetRatesRequest{
-		OriginPostalCode:      DefaultOriginPostalCode, // Pedurungan, Semarang 50113
+		OriginPostalCode:      originPostalCode(origin),
 		DestinationPostalCode: destPostalCode,
 		Couriers:              courier,
 		Items:                 biteshipItems, // Send individual items with dimensions
@@ -622,7 +717,7 @@ func (s *checkoutService) GetCartShippingOptions(sessionID string, destinationPo
 		CartSubtotal:    subtotal,
 		TotalWeight:     totalWeight,
 		TotalWeightKg:   weightKg,
-		OriginCity:      "Semarang",
+		OriginCity:      origin.CityName,
 		GroupedRates:    groupedRates,
 		Rates:           rateResponses,
 		RegularMinPrice: regularMinPrice,
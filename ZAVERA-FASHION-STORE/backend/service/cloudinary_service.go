@@ -16,9 +16,16 @@ import (
 
 type CloudinaryService interface {
 	UploadImage(file multipart.File, filename string) (string, error)
+	UploadImageToFolder(file multipart.File, filename, folder string) (string, error)
 	DeleteImage(publicID string) error
 }
 
+// Cloudinary folders
+const (
+	CloudinaryFolderProducts = "zavera/products"
+	CloudinaryFolderReviews  = "zavera/reviews"
+)
+
 type cloudinaryService struct {
 	cld *cloudinary.Cloudinary
 }
@@ -40,19 +47,24 @@ func NewCloudinaryService() (CloudinaryService, error) {
 	return &cloudinaryService{cld: cld}, nil
 }
 
-// UploadImage uploads an image to Cloudinary and returns the URL
+// UploadImage uploads a product image to Cloudinary and returns the URL
 func (s *cloudinaryService) UploadImage(file multipart.File, filename string) (string, error) {
+	return s.UploadImageToFolder(file, filename, CloudinaryFolderProducts)
+}
+
+// UploadImageToFolder uploads an image into the given Cloudinary folder and returns the URL
+func (s *cloudinaryService) UploadImageToFolder(file multipart.File, filename, folder string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Generate unique public ID
 	ext := filepath.Ext(filename)
-	publicID := fmt.Sprintf("zavera/products/%d_%s", time.Now().Unix(), strings.TrimSuffix(filename, ext))
+	publicID := fmt.Sprintf("%s/%d_%s", folder, time.Now().Unix(), strings.TrimSuffix(filename, ext))
 
 	// Upload to Cloudinary
 	uploadResult, err := s.cld.Upload.Upload(ctx, file, uploader.UploadParams{
 		PublicID:       publicID,
-		Folder:         "zavera/products",
+		Folder:         folder,
 		ResourceType:   "image",
 		Transformation: "q_auto:good,f_auto",
 	})
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrCollectionNotFound  = errors.New("collection not found")
+	ErrDuplicateCollection = errors.New("collection slug already exists")
+	ErrInvalidCollection   = errors.New("invalid collection")
+)
+
+// ListCollections returns all collections, including inactive and scheduled ones
+func (s *adminProductService) ListCollections() ([]dto.CollectionResponse, error) {
+	collections, err := s.collectionRepo.FindAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.CollectionResponse, 0, len(collections))
+	now := time.Now()
+	for _, c := range collections {
+		response = append(response, toCollectionResponse(c, now))
+	}
+	return response, nil
+}
+
+func (s *adminProductService) GetCollection(id int) (*dto.CollectionResponse, error) {
+	collection, err := s.collectionRepo.FindByID(id)
+	if err != nil {
+		return nil, mapCollectionError(err)
+	}
+
+	response := toCollectionResponse(*collection, time.Now())
+	return &response, nil
+}
+
+func (s *adminProductService) CreateCollection(req dto.CreateCollectionRequest) (*dto.CollectionResponse, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !isValidSlugFormat(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	collection := &models.Collection{
+		Name:        strings.TrimSpace(req.Name),
+		Slug:        slug,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Type:        models.CollectionType(req.CollectionType),
+		Rules:       toCollectionRules(req.Rules),
+		MatchType:   strings.ToUpper(req.MatchType),
+		SortBy:      models.ProductSort(req.SortBy),
+		SortOrder:   req.SortOrder,
+		IsActive:    true,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+	}
+	if req.IsActive != nil {
+		collection.IsActive = *req.IsActive
+	}
+
+	if err := validateCollection(collection); err != nil {
+		return nil, err
+	}
+	if collection.Type == models.CollectionTypeRule && len(req.ProductIDs) > 0 {
+		return nil, fmt.Errorf("%w: product_ids only apply to MANUAL collections", ErrInvalidCollection)
+	}
+
+	if err := s.collectionRepo.Create(collection); err != nil {
+		return nil, mapCollectionError(err)
+	}
+
+	if len(req.ProductIDs) > 0 {
+		if err := s.collectionRepo.SetProducts(collection.ID, req.ProductIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetCollection(collection.ID)
+}
+
+func (s *adminProductService) UpdateCollection(id int, req dto.UpdateCollectionRequest) (*dto.CollectionResponse, error) {
+	collection, err := s.collectionRepo.FindByID(id)
+	if err != nil {
+		return nil, mapCollectionError(err)
+	}
+
+	if req.Name != nil {
+		collection.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Slug != nil {
+		if !isValidSlugFormat(*req.Slug) {
+			return nil, ErrInvalidSlug
+		}
+		collection.Slug = *req.Slug
+	}
+	if req.Description != nil {
+		collection.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		collection.ImageURL = *req.ImageURL
+	}
+	if req.Rules != nil {
+		collection.Rules = toCollectionRules(req.Rules)
+	}
+	if req.MatchType != nil {
+		collection.MatchType = strings.ToUpper(*req.MatchType)
+	}
+	if req.SortBy != nil {
+		collection.SortBy = models.ProductSort(*req.SortBy)
+	}
+	if req.SortOrder != nil {
+		collection.SortOrder = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		collection.IsActive = *req.IsActive
+	}
+	if req.StartsAt != nil {
+		collection.StartsAt = req.StartsAt
+	}
+	if req.ClearStartsAt {
+		collection.StartsAt = nil
+	}
+	if req.EndsAt != nil {
+		collection.EndsAt = req.EndsAt
+	}
+	if req.ClearEndsAt {
+		collection.EndsAt = nil
+	}
+
+	if err := validateCollection(collection); err != nil {
+		return nil, err
+	}
+
+	if err := s.collectionRepo.Update(collection); err != nil {
+		return nil, mapCollectionError(err)
+	}
+
+	return s.GetCollection(id)
+}
+
+func (s *adminProductService) DeleteCollection(id int) error {
+	return mapCollectionError(s.collectionRepo.Delete(id))
+}
+
+// SetCollectionProducts replaces the members of a manual collection in the given order
+func (s *adminProductService) SetCollectionProducts(id int, productIDs []int) (*dto.CollectionResponse, error) {
+	collection, err := s.collectionRepo.FindByID(id)
+	if err != nil {
+		return nil, mapCollectionError(err)
+	}
+	if collection.Type != models.CollectionTypeManual {
+		return nil, fmt.Errorf("%w: products can only be assigned to MANUAL collections", ErrInvalidCollection)
+	}
+
+	if err := s.collectionRepo.SetProducts(id, productIDs); err != nil {
+		return nil, err
+	}
+
+	return s.GetCollection(id)
+}
+
+// PreviewCollection resolves a collection's products regardless of its visibility window,
+// so merchandisers can check rules before publishing
+func (s *adminProductService) PreviewCollection(id int, limit, offset int) (*dto.CollectionProductsResponse, error) {
+	collection, err := s.collectionRepo.FindByID(id)
+	if err != nil {
+		return nil, mapCollectionError(err)
+	}
+
+	products, total, err := s.productRepo.FindCollectionProducts(collection, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &productService{productRepo: s.productRepo}
+	return &dto.CollectionProductsResponse{
+		Collection: toCollectionResponse(*collection, time.Now()),
+		Products:   catalog.toProductResponses(products),
+		TotalCount: total,
+		Limit:      limit,
+		Offset:     offset,
+	}, nil
+}
+
+// validateCollection normalises defaults and checks type-specific settings
+func validateCollection(c *models.Collection) error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if c.EndsAt != nil && c.StartsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCollection)
+	}
+
+	if c.MatchType == "" {
+		c.MatchType = models.CollectionMatchAll
+	}
+	if c.MatchType != models.CollectionMatchAll && c.MatchType != models.CollectionMatchAny {
+		return fmt.Errorf("%w: match_type must be ALL or ANY", ErrInvalidCollection)
+	}
+	if c.SortBy == "" {
+		c.SortBy = models.ProductSortNewest
+	}
+	if !c.SortBy.IsValid() {
+		return ErrInvalidProductSort
+	}
+
+	switch c.Type {
+	case models.CollectionTypeManual:
+		if len(c.Rules) > 0 {
+			return fmt.Errorf("%w: rules only apply to RULE collections", ErrInvalidCollection)
+		}
+	case models.CollectionTypeRule:
+		if len(c.Rules) == 0 {
+			return fmt.Errorf("%w: RULE collections need at least one rule", ErrInvalidCollection)
+		}
+		for i, rule := range c.Rules {
+			if err := rule.Validate(); err != nil {
+				return fmt.Errorf("%w: rule %d: %v", ErrInvalidCollection, i+1, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: collection_type must be MANUAL or RULE", ErrInvalidCollection)
+	}
+	return nil
+}
+
+func mapCollectionError(err error) error {
+	switch err {
+	case repository.ErrCollectionNotFound:
+		return ErrCollectionNotFound
+	case repository.ErrDuplicateCollection:
+		return ErrDuplicateCollection
+	}
+	return err
+}
+
+func toCollectionRules(rules []dto.CollectionRuleDTO) models.CollectionRules {
+	result := models.CollectionRules{}
+	for _, r := range rules {
+		result = append(result, models.CollectionRule{
+			Field:    strings.ToLower(strings.TrimSpace(r.Field)),
+			Operator: strings.ToLower(strings.TrimSpace(r.Operator)),
+			Value:    r.Value,
+		})
+	}
+	return result
+}
+
+func toCollectionResponse(c models.Collection, now time.Time) dto.CollectionResponse {
+	response := dto.CollectionResponse{
+		ID:             c.ID,
+		Name:           c.Name,
+		Slug:           c.Slug,
+		Description:    c.Description,
+		ImageURL:       c.ImageURL,
+		CollectionType: string(c.Type),
+		SortOrder:      c.SortOrder,
+		IsActive:       c.IsActive,
+		IsVisible:      c.IsVisibleAt(now),
+		StartsAt:       dto.FormatTimePtr(c.StartsAt),
+		EndsAt:         dto.FormatTimePtr(c.EndsAt),
+		CreatedAt:      dto.FormatTime(c.CreatedAt),
+		UpdatedAt:      dto.FormatTime(c.UpdatedAt),
+	}
+
+	if c.Type == models.CollectionTypeManual {
+		count := c.ProductCount
+		response.ProductCount = &count
+	} else {
+		response.MatchType = c.MatchType
+		response.SortBy = string(c.SortBy)
+		for _, r := range c.Rules {
+			response.Rules = append(response.Rules, dto.CollectionRuleDTO{
+				Field:    r.Field,
+				Operator: r.Operator,
+				Value:    r.Value,
+			})
+		}
+	}
+	return response
+}
@@ -74,6 +74,7 @@ type OrderDetailsForReceipt struct {
 	Subtotal        float64               `json:"subtotal"`
 	ShippingCost    float64               `json:"shipping_cost"`
 	Total           float64               `json:"total"`
+	StoreCredit     float64               `json:"store_credit,omitempty"` // Paid with store credit; Amount is the rest
 	CustomerName    string                `json:"customer_name"`
 	CustomerEmail   string                `json:"customer_email"`
 	CustomerPhone   string                `json:"customer_phone"`
@@ -223,6 +224,12 @@ func (s *corePaymentService) CreateVAPayment(orderID int, paymentMethod string)
 		return nil, ErrOrderNotPendingPayment
 	}
 
+	// Store credit or discounts cover the whole order; Midtrans rejects a zero gross amount
+	if order.AmountDue() <= 0 {
+		log.Printf("❌ Order %d has nothing left to pay", orderID)
+		return nil, ErrNothingToPay
+	}
+
 	// Check for existing PENDING payment (idempotency)
 	existingPayment, err := s.orderPaymentRepo.FindPendingByOrderID(orderID)
 	if err != nil && err != repository.ErrPaymentNotFound {
@@ -250,7 +257,7 @@ func (s *corePaymentService) CreateVAPayment(orderID int, paymentMethod string)
 	// Call Midtrans Core API to create VA
 	chargeResp, err := s.midtransClient.ChargeVA(ChargeVARequest{
 		OrderID:       midtransOrderID,
-		GrossAmount:   order.TotalAmount,
+		GrossAmount:   order.AmountDue(),
 		PaymentMethod: method,
 		CustomerName:  order.CustomerName,
 		CustomerEmail: order.CustomerEmail,
@@ -862,6 +869,7 @@ func (s *corePaymentService) buildPaymentResponse(payment *models.OrderPayment,
 		Subtotal:      order.Subtotal,
 		ShippingCost:  order.ShippingCost,
 		Total:         order.TotalAmount,
+		StoreCredit:   order.StoreCreditAmount,
 		CustomerName:  order.CustomerName,
 		CustomerEmail: order.CustomerEmail,
 		CustomerPhone: order.CustomerPhone,
@@ -905,7 +913,7 @@ func (s *corePaymentService) buildPaymentResponse(payment *models.OrderPayment,
 		Bank:             payment.Bank,
 		BankLogo:         s.getBankLogo(payment.Bank),
 		VANumber:         payment.VANumber,
-		Amount:           order.TotalAmount,
+		Amount:           order.AmountDue(),
 		ExpiryTime:       payment.ExpiryTime,
 		RemainingSeconds: payment.GetRemainingSeconds(),
 		Status:           string(payment.PaymentStatus),
@@ -2,11 +2,13 @@ package service
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/smtp"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 	"zavera/models"
@@ -39,8 +41,27 @@ type EmailService interface {
 	
 	// HasSentEmail checks if an email type has already been sent for an order
 	HasSentEmail(orderID int, templateKey string) (bool, error)
+
+	// SendBackInStock tells a user that a wishlisted or subscribed item is available again.
+	// Returns ErrAlertDuplicate or ErrAlertRateLimited without sending when suppressed.
+	SendBackInStock(recipient models.AlertRecipient, item BackInStockItem, reference string) error
+
+	// SendPriceDrop tells a user that a wishlisted product is cheaper than when they added it
+	SendPriceDrop(drop models.PriceDrop) error
+
+	// SendGiftCard sends the code of a gift card bought in an order to its purchaser
+	SendGiftCard(card models.GiftCard) error
 }
 
+// Alert emails are opt-in marketing rather than transactional, so they are deduplicated by
+// reference and capped per user per day
+var (
+	ErrAlertDuplicate   = errors.New("alert already sent")
+	ErrAlertRateLimited = errors.New("daily alert email limit reached")
+)
+
+var alertTemplateKeys = []string{"BACK_IN_STOCK", "PRICE_DROP"}
+
 type emailService struct {
 	emailRepo repository.EmailRepository
 	smtpHost  string
@@ -50,6 +71,9 @@ type emailService struct {
 	fromEmail string
 	fromName  string
 	baseURL   string
+
+	alertDailyLimit  int
+	alertDedupWindow time.Duration
 }
 
 // NewEmailService creates a new email service
@@ -63,6 +87,9 @@ func NewEmailService(emailRepo repository.EmailRepository) EmailService {
 		fromEmail: getEnvOrDefault("SMTP_FROM", "noreply@zavera.com"),  // Match .env
 		fromName:  getEnvOrDefault("SMTP_FROM_NAME", "ZAVERA"),
 		baseURL:   getEnvOrDefault("FRONTEND_URL", "http://localhost:3000"),  // Match .env
+
+		alertDailyLimit:  getEnvIntOrDefault("ALERT_EMAIL_DAILY_LIMIT", 3),
+		alertDedupWindow: time.Duration(getEnvIntOrDefault("ALERT_DEDUP_HOURS", 72)) * time.Hour,
 	}
 }
 
@@ -73,6 +100,13 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
 // OrderCreatedData holds data for order created email
 type OrderCreatedData struct {
 	CustomerName    string
@@ -80,19 +114,28 @@ type OrderCreatedData struct {
 	CreatedAt       string
 	Items           []OrderItemData
 	Subtotal        string
+	Discount        string // Empty without a discount
 	ShippingCost    string
+	TaxLabel        string // PPN row, e.g. "Termasuk PPN 11%"; empty without PPN
+	Tax             string
 	TotalAmount     string
 	Courier         string
 	Service         string
 	ShippingAddress string
 	PaymentURL      string
+	// Pre-order/backorder: the order ships once the stock arrives
+	AwaitingStock    bool
+	ExpectedShipDate string
 }
 
 // OrderItemData holds item data for email
 type OrderItemData struct {
-	ProductName string
-	Quantity    int
-	Subtotal    string
+	ProductName         string
+	Quantity            int
+	Subtotal            string
+	BackorderedQuantity int
+	ExpectedShipDate    string
+	TaxNote             string // The line's PPN, or that it is exempt
 }
 
 // PaymentSuccessData holds data for payment success email
@@ -102,6 +145,9 @@ type PaymentSuccessData struct {
 	TotalAmount   string
 	PaymentMethod string
 	PaidAt        string
+	// Pre-order/backorder: the order ships once the stock arrives
+	AwaitingStock    bool
+	ExpectedShipDate string
 }
 
 // OrderShippedData holds data for order shipped email
@@ -143,6 +189,43 @@ type OrderCancelledData struct {
 	ShopURL            string
 }
 
+// BackInStockItem is the variant that came back in stock
+type BackInStockItem struct {
+	ProductID   int
+	ProductName string
+	VariantName string
+	Price       float64
+}
+
+// BackInStockData holds data for back in stock email
+type BackInStockData struct {
+	CustomerName string
+	ProductName  string
+	VariantName  string
+	Price        string
+	ProductURL   string
+}
+
+// PriceDropData holds data for price drop email
+type PriceDropData struct {
+	CustomerName string
+	ProductName  string
+	OldPrice     string
+	NewPrice     string
+	ProductURL   string
+}
+
+// GiftCardData holds data for gift card email
+type GiftCardData struct {
+	CustomerName string
+	ProductName  string
+	OrderCode    string
+	Amount       string
+	Code         string
+	ExpiresAt    string
+	RedeemURL    string
+}
+
 // OrderRefundedData holds data for order refunded email
 type OrderRefundedData struct {
 	CustomerName  string
@@ -171,9 +254,12 @@ func (s *emailService) SendOrderCreated(order *models.Order, items []models.Orde
 	var itemsData []OrderItemData
 	for _, item := range items {
 		itemsData = append(itemsData, OrderItemData{
-			ProductName: item.ProductName,
-			Quantity:    item.Quantity,
-			Subtotal:    formatCurrency(item.Subtotal),
+			ProductName:         item.ProductName,
+			Quantity:            item.Quantity,
+			Subtotal:            formatCurrency(item.Subtotal),
+			BackorderedQuantity: item.BackorderedQuantity,
+			ExpectedShipDate:    formatShipDate(item.ExpectedShipDate),
+			TaxNote:             itemTaxNote(order, item),
 		})
 	}
 
@@ -184,11 +270,18 @@ func (s *emailService) SendOrderCreated(order *models.Order, items []models.Orde
 		Items:           itemsData,
 		Subtotal:        formatCurrency(order.Subtotal),
 		ShippingCost:    formatCurrency(order.ShippingCost),
+		TaxLabel:        orderTaxLabel(order),
+		Tax:             formatCurrency(order.Tax),
 		TotalAmount:     formatCurrency(order.TotalAmount),
 		Courier:         courier,
 		Service:         service,
 		ShippingAddress: shippingAddress,
 		PaymentURL:      fmt.Sprintf("%s/checkout/payment/%s", s.baseURL, order.OrderCode),
+		AwaitingStock:    order.AwaitingStock,
+		ExpectedShipDate: formatShipDate(order.ExpectedShipDate),
+	}
+	if order.Discount > 0 {
+		data.Discount = formatCurrency(order.Discount)
 	}
 
 	subject := fmt.Sprintf("🛍️ Pesanan ZAVERA #%s telah dibuat", order.OrderCode)
@@ -223,6 +316,8 @@ func (s *emailService) SendPaymentSuccess(order *models.Order, paymentMethod str
 		TotalAmount:   formatCurrency(order.TotalAmount),
 		PaymentMethod: paymentMethod,
 		PaidAt:        paidAt.Format("02 Jan 2006 15:04"),
+		AwaitingStock:    order.AwaitingStock,
+		ExpectedShipDate: formatShipDate(order.ExpectedShipDate),
 	}
 
 	subject := fmt.Sprintf("💳 Pembayaran diterima – Pesanan #%s", order.OrderCode)
@@ -414,6 +509,109 @@ func (s *emailService) SendOrderRefunded(order *models.Order, items []models.Ord
 	return s.sendEmail(order.CustomerEmail, subject, htmlBody, order.ID, "ORDER_REFUNDED")
 }
 
+// SendBackInStock sends email when a wishlisted or subscribed item is available again
+func (s *emailService) SendBackInStock(recipient models.AlertRecipient, item BackInStockItem, reference string) error {
+	if err := s.allowAlert(recipient.UserID, "BACK_IN_STOCK", reference); err != nil {
+		return err
+	}
+
+	data := BackInStockData{
+		CustomerName: recipient.Name,
+		ProductName:  item.ProductName,
+		VariantName:  item.VariantName,
+		Price:        formatCurrency(item.Price),
+		ProductURL:   fmt.Sprintf("%s/product/%d", s.baseURL, item.ProductID),
+	}
+
+	subject := fmt.Sprintf("🔔 %s tersedia kembali", item.ProductName)
+
+	htmlBody, err := s.renderTemplate("BACK_IN_STOCK", data)
+	if err != nil {
+		log.Printf("Warning: failed to render BACK_IN_STOCK template: %v", err)
+		htmlBody = s.getDefaultBackInStockHTML(data)
+	}
+
+	return s.sendUserEmail(recipient, subject, htmlBody, "BACK_IN_STOCK", reference)
+}
+
+// SendPriceDrop sends email when a wishlisted product gets cheaper
+func (s *emailService) SendPriceDrop(drop models.PriceDrop) error {
+	// One email per product and price, a further drop is news again
+	reference := fmt.Sprintf("product:%d@%.0f", drop.ProductID, drop.Price)
+	if err := s.allowAlert(drop.Recipient.UserID, "PRICE_DROP", reference); err != nil {
+		return err
+	}
+
+	data := PriceDropData{
+		CustomerName: drop.Recipient.Name,
+		ProductName:  drop.ProductName,
+		OldPrice:     formatCurrency(drop.PriceSeen),
+		NewPrice:     formatCurrency(drop.Price),
+		ProductURL:   fmt.Sprintf("%s/product/%d", s.baseURL, drop.ProductID),
+	}
+
+	subject := fmt.Sprintf("💸 Harga %s turun", drop.ProductName)
+
+	htmlBody, err := s.renderTemplate("PRICE_DROP", data)
+	if err != nil {
+		log.Printf("Warning: failed to render PRICE_DROP template: %v", err)
+		htmlBody = s.getDefaultPriceDropHTML(data)
+	}
+
+	return s.sendUserEmail(drop.Recipient, subject, htmlBody, "PRICE_DROP", reference)
+}
+
+// SendGiftCard sends email with the code of a gift card once its order is paid
+func (s *emailService) SendGiftCard(card models.GiftCard) error {
+	if card.OrderID == nil {
+		return fmt.Errorf("gift card %d has no order", card.ID)
+	}
+
+	data := GiftCardData{
+		CustomerName: card.PurchaserName,
+		ProductName:  card.ProductName,
+		OrderCode:    card.OrderCode,
+		Amount:       formatCurrency(card.Amount),
+		Code:         models.FormatGiftCardCode(card.Code),
+		RedeemURL:    fmt.Sprintf("%s/account/store-credit", s.baseURL),
+	}
+	if card.ExpiresAt != nil {
+		data.ExpiresAt = card.ExpiresAt.Format("02 January 2006")
+	}
+
+	subject := fmt.Sprintf("🎁 Gift card ZAVERA Rp %s", data.Amount)
+
+	htmlBody, err := s.renderTemplate("GIFT_CARD", data)
+	if err != nil {
+		log.Printf("Warning: failed to render GIFT_CARD template: %v", err)
+		htmlBody = s.getDefaultGiftCardHTML(data)
+	}
+
+	return s.sendEmail(card.RecipientEmail, subject, htmlBody, *card.OrderID, "GIFT_CARD")
+}
+
+// allowAlert applies the dedup window and the per-user daily limit to alert emails
+func (s *emailService) allowAlert(userID int, templateKey, reference string) error {
+	now := time.Now()
+
+	sent, err := s.emailRepo.HasSentReference(userID, templateKey, reference, now.Add(-s.alertDedupWindow))
+	if err != nil {
+		return err
+	}
+	if sent {
+		return ErrAlertDuplicate
+	}
+
+	count, err := s.emailRepo.CountSentToUser(userID, alertTemplateKeys, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= s.alertDailyLimit {
+		return ErrAlertRateLimited
+	}
+	return nil
+}
+
 // renderTemplate renders an email template with data
 func (s *emailService) renderTemplate(templateKey string, data interface{}) (string, error) {
 	// Get template from database
@@ -448,8 +646,27 @@ func (s *emailService) sendEmail(to, subject, htmlBody string, orderID int, temp
 		Status:         models.EmailLogStatusPending,
 	}
 
+	return s.deliver(emailLog, htmlBody)
+}
+
+// sendUserEmail sends an email that belongs to a user rather than an order and logs it
+func (s *emailService) sendUserEmail(recipient models.AlertRecipient, subject, htmlBody, templateKey, reference string) error {
+	emailLog := &models.EmailLog{
+		UserID:         &recipient.UserID,
+		TemplateKey:    templateKey,
+		RecipientEmail: recipient.Email,
+		Subject:        subject,
+		Status:         models.EmailLogStatusPending,
+		Reference:      reference,
+	}
+
+	return s.deliver(emailLog, htmlBody)
+}
+
+// deliver sends a prepared email and records the outcome in its log entry
+func (s *emailService) deliver(emailLog *models.EmailLog, htmlBody string) error {
 	// Try to send email
-	err := s.sendSMTP(to, subject, htmlBody)
+	err := s.sendSMTP(emailLog.RecipientEmail, emailLog.Subject, htmlBody)
 	if err != nil {
 		emailLog.Status = models.EmailLogStatusFailed
 		emailLog.ErrorMessage = err.Error()
@@ -508,6 +725,38 @@ func formatCurrency(amount float64) string {
 	return fmt.Sprintf("%.0f", amount)
 }
 
+// orderTaxLabel names the PPN row of an order email, empty when the order has no PPN
+func orderTaxLabel(order *models.Order) string {
+	if order.Tax <= 0 {
+		return ""
+	}
+	if order.PricesIncludeTax {
+		return fmt.Sprintf("Termasuk PPN %g%%", order.TaxRate)
+	}
+	return fmt.Sprintf("PPN %g%%", order.TaxRate)
+}
+
+// itemTaxNote describes the PPN of an order line, empty when the order has no PPN
+func itemTaxNote(order *models.Order, item models.OrderItem) string {
+	switch {
+	case order.TaxRate <= 0:
+		return ""
+	case item.TaxRate <= 0:
+		return "Bebas PPN"
+	case order.PricesIncludeTax:
+		return fmt.Sprintf("Termasuk PPN %g%%: Rp %s", item.TaxRate, formatCurrency(item.TaxAmount))
+	}
+	return fmt.Sprintf("PPN %g%%: Rp %s", item.TaxRate, formatCurrency(item.TaxAmount))
+}
+
+// formatShipDate formats an expected ship date, empty when there is none
+func formatShipDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("02 Jan 2006")
+}
+
 // Default HTML templates (fallback if database templates fail)
 func (s *emailService) getDefaultOrderCreatedHTML(data OrderCreatedData) string {
 	return fmt.Sprintf(`
@@ -520,11 +769,33 @@ func (s *emailService) getDefaultOrderCreatedHTML(data OrderCreatedData) string
 <p>Halo %s,</p>
 <p>Terima kasih telah berbelanja di ZAVERA. Pesanan Anda telah berhasil dibuat.</p>
 <p><strong>Nomor Pesanan:</strong> %s</p>
-<p><strong>Total:</strong> Rp %s</p>
+<p><strong>Total:</strong> Rp %s%s</p>
 <p><strong>Kurir:</strong> %s - %s</p>
+%s
 <p><a href="%s">Bayar Sekarang</a></p>
 </body>
-</html>`, data.CustomerName, data.OrderCode, data.TotalAmount, data.Courier, data.Service, data.PaymentURL)
+</html>`, data.CustomerName, data.OrderCode, data.TotalAmount, taxTotalNote(data.TaxLabel, data.Tax), data.Courier, data.Service,
+		preorderNoteHTML(data.AwaitingStock, data.ExpectedShipDate), data.PaymentURL)
+}
+
+// taxTotalNote adds the PPN to the total in the fallback email
+func taxTotalNote(label, tax string) string {
+	if label == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s: Rp %s)", label, tax)
+}
+
+// preorderNoteHTML tells the customer that the order ships once pre-ordered items arrive
+func preorderNoteHTML(awaitingStock bool, expectedShipDate string) string {
+	if !awaitingStock {
+		return ""
+	}
+	note := "<p>Pesanan ini berisi produk pre-order/backorder dan akan dikirim setelah stok tersedia."
+	if expectedShipDate != "" {
+		note += fmt.Sprintf(" <strong>Perkiraan Pengiriman:</strong> %s", expectedShipDate)
+	}
+	return note + "</p>"
 }
 
 func (s *emailService) getDefaultPaymentSuccessHTML(data PaymentSuccessData) string {
@@ -540,8 +811,10 @@ func (s *emailService) getDefaultPaymentSuccessHTML(data PaymentSuccessData) str
 <p><strong>Nomor Pesanan:</strong> %s</p>
 <p><strong>Total:</strong> Rp %s</p>
 <p><strong>Metode:</strong> %s</p>
+%s
 </body>
-</html>`, data.CustomerName, data.OrderCode, data.TotalAmount, data.PaymentMethod)
+</html>`, data.CustomerName, data.OrderCode, data.TotalAmount, data.PaymentMethod,
+		preorderNoteHTML(data.AwaitingStock, data.ExpectedShipDate))
 }
 
 func (s *emailService) getDefaultOrderShippedHTML(data OrderShippedData) string {
@@ -619,3 +892,58 @@ func (s *emailService) getDefaultOrderRefundedHTML(data OrderRefundedData) strin
 </body>
 </html>`, data.CustomerName, data.OrderCode, data.RefundCode, data.RefundAmount, data.RefundReason, data.ShopURL)
 }
+
+func (s *emailService) getDefaultBackInStockHTML(data BackInStockData) string {
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+<h1>ZAVERA</h1>
+<h2>🔔 Tersedia Kembali!</h2>
+<p>Halo %s,</p>
+<p>Produk yang Anda tunggu sudah tersedia kembali.</p>
+<p><strong>Produk:</strong> %s %s</p>
+<p><strong>Harga:</strong> Rp %s</p>
+<p><a href="%s">Beli Sekarang</a></p>
+</body>
+</html>`, data.CustomerName, data.ProductName, data.VariantName, data.Price, data.ProductURL)
+}
+
+func (s *emailService) getDefaultPriceDropHTML(data PriceDropData) string {
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+<h1>ZAVERA</h1>
+<h2>💸 Harga Turun!</h2>
+<p>Halo %s,</p>
+<p>Harga produk di wishlist Anda baru saja turun.</p>
+<p><strong>Produk:</strong> %s</p>
+<p><strong>Harga:</strong> <s>Rp %s</s> Rp %s</p>
+<p><a href="%s">Lihat Produk</a></p>
+</body>
+</html>`, data.CustomerName, data.ProductName, data.OldPrice, data.NewPrice, data.ProductURL)
+}
+
+func (s *emailService) getDefaultGiftCardHTML(data GiftCardData) string {
+	expires := ""
+	if data.ExpiresAt != "" {
+		expires = fmt.Sprintf("<p>Berlaku hingga %s</p>", data.ExpiresAt)
+	}
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+<h1>ZAVERA</h1>
+<h2>🎁 Gift Card Rp %s</h2>
+<p>Halo %s,</p>
+<p>Terima kasih telah membeli %s (pesanan %s). Berikan kode di bawah ini kepada penerima, atau tukarkan sendiri menjadi saldo ZAVERA.</p>
+<p style="font-family: monospace; font-size: 20px;"><strong>%s</strong></p>
+%s
+<p><a href="%s">Tukarkan Gift Card</a></p>
+</body>
+</html>`, data.Amount, data.CustomerName, data.ProductName, data.OrderCode, data.Code, expires, data.RedeemURL)
+}
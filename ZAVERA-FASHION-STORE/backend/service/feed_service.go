@@ -0,0 +1,431 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"zavera/models"
+	"zavera/repository"
+)
+
+const (
+	// How often the catalog fingerprint is re-checked; requests in between serve the cache
+	feedFingerprintInterval = time.Minute
+	// Feeds are rebuilt at least this often even if nothing changed
+	feedMaxAge = 6 * time.Hour
+)
+
+const (
+	FeedSitemap     = "sitemap"
+	FeedMerchantXML = "merchant_xml"
+	FeedMerchantTSV = "merchant_tsv"
+)
+
+// GeneratedFeed is a rendered feed document ready to be served
+type GeneratedFeed struct {
+	Body        []byte
+	ContentType string
+	ETag        string
+	GeneratedAt time.Time
+	fingerprint string
+}
+
+// FeedService builds the sitemap and the Google Merchant / Meta catalog feeds.
+// Output is cached in memory and rebuilt when the catalog fingerprint changes.
+type FeedService interface {
+	GetFeed(kind string) (*GeneratedFeed, error)
+}
+
+type feedService struct {
+	productRepo repository.ProductRepository
+	variantRepo *repository.VariantRepository
+
+	mu          sync.Mutex
+	cache       map[string]*GeneratedFeed
+	fingerprint string
+	checkedAt   time.Time
+}
+
+func NewFeedService(productRepo repository.ProductRepository, variantRepo *repository.VariantRepository) FeedService {
+	return &feedService{
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+		cache:       make(map[string]*GeneratedFeed),
+	}
+}
+
+// GetFeed returns a cached feed, regenerating it when products changed since it was built
+func (s *feedService) GetFeed(kind string) (*GeneratedFeed, error) {
+	render, contentType, ok := feedRenderer(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown feed: %s", kind)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached := s.cache[kind]
+
+	if time.Since(s.checkedAt) >= feedFingerprintInterval || s.fingerprint == "" {
+		fingerprint, err := s.productRepo.CatalogFingerprint()
+		if err != nil {
+			if cached != nil {
+				log.Printf("⚠️ Feed fingerprint check failed, serving cached %s: %v", kind, err)
+				return cached, nil
+			}
+			return nil, err
+		}
+		s.fingerprint = fingerprint
+		s.checkedAt = time.Now()
+	}
+
+	if cached != nil && cached.fingerprint == s.fingerprint && time.Since(cached.GeneratedAt) < feedMaxAge {
+		return cached, nil
+	}
+
+	products, err := s.productRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	variants, err := s.variantRepo.GetActiveByProductIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := render(products, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum(body)
+	feed := &GeneratedFeed{
+		Body:        body,
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:8]) + `"`,
+		GeneratedAt: time.Now(),
+		fingerprint: s.fingerprint,
+	}
+	s.cache[kind] = feed
+
+	log.Printf("🗺️ Regenerated %s feed: %d products, %d bytes", kind, len(products), len(body))
+	return feed, nil
+}
+
+type feedRenderFunc func(products []models.Product, variants map[int][]models.ProductVariant) ([]byte, error)
+
+func feedRenderer(kind string) (feedRenderFunc, string, bool) {
+	switch kind {
+	case FeedSitemap:
+		return renderSitemap, "application/xml; charset=utf-8", true
+	case FeedMerchantXML:
+		return func(products []models.Product, variants map[int][]models.ProductVariant) ([]byte, error) {
+			return renderMerchantXML(buildMerchantItems(products, variants))
+		}, "application/xml; charset=utf-8", true
+	case FeedMerchantTSV:
+		return func(products []models.Product, variants map[int][]models.ProductVariant) ([]byte, error) {
+			return renderMerchantTSV(buildMerchantItems(products, variants)), nil
+		}, "text/tab-separated-values; charset=utf-8", true
+	}
+	return nil, "", false
+}
+
+// ============================================
+// SITEMAP
+// ============================================
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+func renderSitemap(products []models.Product, _ map[int][]models.ProductVariant) ([]byte, error) {
+	baseURL := strings.TrimRight(getEnvOrDefault("FRONTEND_URL", "http://localhost:3000"), "/")
+
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	set.URLs = append(set.URLs, sitemapURL{Loc: baseURL + "/", ChangeFreq: "daily", Priority: "1.0"})
+
+	// Category landing pages, dated by their most recently updated product
+	categoryLastMod := make(map[string]time.Time)
+	for _, p := range products {
+		category := strings.ToLower(p.Category)
+		if category == "" {
+			continue
+		}
+		if p.UpdatedAt.After(categoryLastMod[category]) {
+			categoryLastMod[category] = p.UpdatedAt
+		}
+	}
+	categories := make([]string, 0, len(categoryLastMod))
+	for c := range categoryLastMod {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:        baseURL + "/" + c,
+			LastMod:    categoryLastMod[c].Format("2006-01-02"),
+			ChangeFreq: "daily",
+			Priority:   "0.8",
+		})
+	}
+
+	for _, p := range products {
+		loc := p.CanonicalURL
+		if loc == "" {
+			loc = productPageURL(p.ID)
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:        loc,
+			LastMod:    p.UpdatedAt.Format("2006-01-02"),
+			ChangeFreq: "weekly",
+			Priority:   "0.6",
+		})
+	}
+
+	return marshalXMLDocument(set)
+}
+
+// ============================================
+// MERCHANT / CATALOG FEED
+// ============================================
+
+// merchantItem is one feed row in Google Merchant Center attribute naming.
+// Meta catalogs accept the same attributes.
+type merchantItem struct {
+	ID           string `xml:"g:id"`
+	ItemGroupID  string `xml:"g:item_group_id,omitempty"`
+	Title        string `xml:"g:title"`
+	Description  string `xml:"g:description"`
+	Link         string `xml:"g:link"`
+	ImageLink    string `xml:"g:image_link,omitempty"`
+	Availability string `xml:"g:availability"`
+	Price        string `xml:"g:price"`
+	SalePrice    string `xml:"g:sale_price,omitempty"`
+	Brand        string `xml:"g:brand,omitempty"`
+	Color        string `xml:"g:color,omitempty"`
+	Size         string `xml:"g:size,omitempty"`
+	GTIN         string `xml:"g:gtin,omitempty"`
+	MPN          string `xml:"g:mpn,omitempty"`
+	Condition    string `xml:"g:condition"`
+	ProductType  string `xml:"g:product_type,omitempty"`
+}
+
+// buildMerchantItems emits one item per active variant (grouped by product),
+// or a single item for products sold without variants
+func buildMerchantItems(products []models.Product, variants map[int][]models.ProductVariant) []merchantItem {
+	var items []merchantItem
+
+	for _, p := range products {
+		base := merchantItem{
+			Title:       p.Name,
+			Description: feedDescription(p),
+			Link:        p.CanonicalURL,
+			ImageLink:   feedPrimaryImage(p),
+			Brand:       p.Brand,
+			Condition:   "new",
+			ProductType: feedProductType(p),
+		}
+		if base.Link == "" {
+			base.Link = productPageURL(p.ID)
+		}
+		if base.Brand == "" {
+			base.Brand = "ZAVERA"
+		}
+
+		productVariants := variants[p.ID]
+		if len(productVariants) == 0 {
+			item := base
+			item.ID = fmt.Sprintf("ZV-%d", p.ID)
+			item.Price = formatFeedPrice(p.Price)
+			item.Availability = feedAvailability(p.Stock)
+			items = append(items, item)
+			continue
+		}
+
+		for _, v := range productVariants {
+			item := base
+			item.ID = v.SKU
+			item.MPN = v.SKU
+			item.ItemGroupID = fmt.Sprintf("ZV-%d", p.ID)
+
+			var options []string
+			if v.Color != nil && *v.Color != "" {
+				item.Color = *v.Color
+				options = append(options, *v.Color)
+			}
+			if v.Size != nil && *v.Size != "" {
+				item.Size = *v.Size
+				options = append(options, *v.Size)
+			}
+			if len(options) > 0 {
+				item.Title = p.Name + " - " + strings.Join(options, " / ")
+			}
+
+			price := p.Price
+			if v.Price != nil && *v.Price > 0 {
+				price = *v.Price
+			}
+			// compare_at_price is the "was" price; the feed wants it as price with the current one as sale_price
+			if v.CompareAtPrice != nil && *v.CompareAtPrice > price {
+				item.Price = formatFeedPrice(*v.CompareAtPrice)
+				item.SalePrice = formatFeedPrice(price)
+			} else {
+				item.Price = formatFeedPrice(price)
+			}
+
+			item.Availability = feedAvailability(v.StockQuantity - v.ReservedStock)
+			if v.Barcode != nil && isValidGTIN(*v.Barcode) {
+				item.GTIN = *v.Barcode
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+type merchantRSS struct {
+	XMLName xml.Name        `xml:"rss"`
+	Version string          `xml:"version,attr"`
+	XmlnsG  string          `xml:"xmlns:g,attr"`
+	Channel merchantChannel `xml:"channel"`
+}
+
+type merchantChannel struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description"`
+	Items       []merchantItem `xml:"item"`
+}
+
+func renderMerchantXML(items []merchantItem) ([]byte, error) {
+	doc := merchantRSS{
+		Version: "2.0",
+		XmlnsG:  "http://base.google.com/ns/1.0",
+		Channel: merchantChannel{
+			Title:       "ZAVERA Product Feed",
+			Link:        strings.TrimRight(getEnvOrDefault("FRONTEND_URL", "http://localhost:3000"), "/"),
+			Description: "ZAVERA fashion catalog",
+			Items:       items,
+		},
+	}
+	return marshalXMLDocument(doc)
+}
+
+var merchantTSVColumns = []string{
+	"id", "item_group_id", "title", "description", "link", "image_link", "availability",
+	"price", "sale_price", "brand", "color", "size", "gtin", "mpn", "condition", "product_type",
+}
+
+func renderMerchantTSV(items []merchantItem) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(merchantTSVColumns, "\t"))
+	buf.WriteString("\n")
+
+	for _, it := range items {
+		row := []string{
+			it.ID, it.ItemGroupID, it.Title, it.Description, it.Link, it.ImageLink, it.Availability,
+			it.Price, it.SalePrice, it.Brand, it.Color, it.Size, it.GTIN, it.MPN, it.Condition, it.ProductType,
+		}
+		for i, field := range row {
+			row[i] = sanitizeTSVField(field)
+		}
+		buf.WriteString(strings.Join(row, "\t"))
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes()
+}
+
+// ============================================
+// HELPERS
+// ============================================
+
+func marshalXMLDocument(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func formatFeedPrice(price float64) string {
+	return fmt.Sprintf("%.2f IDR", price)
+}
+
+func feedAvailability(available int) string {
+	if available > 0 {
+		return "in_stock"
+	}
+	return "out_of_stock"
+}
+
+func feedDescription(p models.Product) string {
+	description := strings.Join(strings.Fields(p.Description), " ")
+	if description == "" {
+		description = p.Name
+	}
+	return truncateRunes(description, 5000)
+}
+
+// feedProductType builds "Wanita > Dress" from category and subcategory
+func feedProductType(p models.Product) string {
+	var parts []string
+	for _, part := range []string{p.Category, p.Subcategory} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, strings.ToUpper(part[:1])+part[1:])
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func feedPrimaryImage(p models.Product) string {
+	for _, img := range p.Images {
+		if img.IsPrimary {
+			return img.ImageURL
+		}
+	}
+	if len(p.Images) > 0 {
+		return p.Images[0].ImageURL
+	}
+	return ""
+}
+
+// isValidGTIN accepts GTIN-8/12/13/14 digit strings; other barcodes (internal codes) are left out of the feed
+func isValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func sanitizeTSVField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"zavera/models"
+)
+
+func TestBuildMerchantItems(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "https://zavera.id")
+
+	size, color := "M", "Black"
+	variantPrice, compareAt := 199000.0, 249000.0
+	barcode, internalCode := "8991234567890", "ZV-INT-01"
+
+	products := []models.Product{
+		{
+			ID: 1, Name: "Kemeja Linen", Description: "Kemeja\tlinen", Price: 179000, Category: "pria", Subcategory: "kemeja",
+			Images: []models.ProductImage{{ImageURL: "https://cdn/a.jpg"}, {ImageURL: "https://cdn/b.jpg", IsPrimary: true}},
+		},
+		{ID: 2, Name: "Tote Bag", Price: 99000, Stock: 0, Category: "luxury", Brand: "Zara"},
+	}
+	variants := map[int][]models.ProductVariant{
+		1: {
+			{SKU: "KL-M-BLK", Size: &size, Color: &color, Price: &variantPrice, CompareAtPrice: &compareAt, StockQuantity: 5, ReservedStock: 2, Barcode: &barcode},
+			{SKU: "KL-L-BLK", Color: &color, StockQuantity: 3, ReservedStock: 3, Barcode: &internalCode},
+		},
+	}
+
+	items := buildMerchantItems(products, variants)
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+
+	first := items[0]
+	if first.ID != "KL-M-BLK" || first.ItemGroupID != "ZV-1" || first.Title != "Kemeja Linen - Black / M" {
+		t.Errorf("unexpected variant identity: %+v", first)
+	}
+	if first.Price != "249000.00 IDR" || first.SalePrice != "199000.00 IDR" {
+		t.Errorf("compare_at_price should become price with sale_price, got %q / %q", first.Price, first.SalePrice)
+	}
+	if first.Availability != "in_stock" || first.GTIN != barcode || first.ImageLink != "https://cdn/b.jpg" {
+		t.Errorf("unexpected availability/gtin/image: %+v", first)
+	}
+	if first.Link != "https://zavera.id/product/1" || first.ProductType != "Pria > Kemeja" || first.Brand != "ZAVERA" {
+		t.Errorf("unexpected link/type/brand: %+v", first)
+	}
+
+	second := items[1]
+	if second.Availability != "out_of_stock" {
+		t.Errorf("reserved stock must count as unavailable, got %q", second.Availability)
+	}
+	if second.Price != "179000.00 IDR" || second.SalePrice != "" || second.GTIN != "" {
+		t.Errorf("variant without price should inherit product price and skip invalid gtin: %+v", second)
+	}
+
+	third := items[2]
+	if third.ID != "ZV-2" || third.ItemGroupID != "" || third.Availability != "out_of_stock" || third.Brand != "Zara" {
+		t.Errorf("unexpected simple product item: %+v", third)
+	}
+
+	tsv := string(renderMerchantTSV(items))
+	lines := strings.Split(strings.TrimSuffix(tsv, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header + 3 rows, got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		if strings.Count(line, "\t") != len(merchantTSVColumns)-1 {
+			t.Errorf("row has wrong column count: %q", line)
+		}
+	}
+}
+
+func TestRenderMerchantXML_UsesGoogleNamespace(t *testing.T) {
+	body, err := renderMerchantXML([]merchantItem{{ID: "SKU-1", Title: "A & B", Condition: "new"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	xml := string(body)
+	for _, fragment := range []string{`xmlns:g="http://base.google.com/ns/1.0"`, "<g:id>SKU-1</g:id>", "<g:title>A &amp; B</g:title>"} {
+		if !strings.Contains(xml, fragment) {
+			t.Errorf("expected feed to contain %q:\n%s", fragment, xml)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"log"
+	"time"
+	"zavera/repository"
+)
+
+// giftCardBatch is how many issued codes are emailed per run
+const giftCardBatch = 100
+
+// GiftCardJob issues a code for each paid unit of a gift card product and emails it to
+// the purchaser. Codes are issued once per unit, so a run after a crash picks up where the
+// last one stopped.
+type GiftCardJob struct {
+	storeCreditRepo repository.StoreCreditRepository
+	emailService    EmailService
+	validFor        time.Duration
+	ticker          *time.Ticker
+	done            chan bool
+}
+
+func NewGiftCardJob(storeCreditRepo repository.StoreCreditRepository, emailService EmailService) *GiftCardJob {
+	return &GiftCardJob{
+		storeCreditRepo: storeCreditRepo,
+		emailService:    emailService,
+		validFor:        time.Duration(getEnvIntOrDefault("GIFT_CARD_VALID_DAYS", 365)) * 24 * time.Hour,
+		done:            make(chan bool),
+	}
+}
+
+// Start begins the gift card job
+// Runs every minute so codes arrive soon after payment
+func (j *GiftCardJob) Start() {
+	j.ticker = time.NewTicker(1 * time.Minute)
+
+	go j.run()
+
+	go func() {
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-j.ticker.C:
+				j.run()
+			}
+		}
+	}()
+
+	log.Println("🎁 Gift card job started (checks every minute)")
+}
+
+// Stop stops the gift card job
+func (j *GiftCardJob) Stop() {
+	if j.ticker != nil {
+		j.ticker.Stop()
+	}
+	j.done <- true
+	log.Println("🎁 Gift card job stopped")
+}
+
+func (j *GiftCardJob) run() {
+	issued, err := j.storeCreditRepo.IssuePendingGiftCards(j.validFor)
+	if err != nil {
+		log.Printf("⚠️ Failed to issue gift cards: %v", err)
+	} else if len(issued) > 0 {
+		log.Printf("🎁 Issued %d gift cards", len(issued))
+	}
+
+	cards, err := j.storeCreditRepo.FindUndeliveredGiftCards(giftCardBatch)
+	if err != nil {
+		log.Printf("⚠️ Failed to load undelivered gift cards: %v", err)
+		return
+	}
+
+	sent := 0
+	for _, card := range cards {
+		if err := j.emailService.SendGiftCard(card); err != nil {
+			log.Printf("⚠️ Failed to send gift card %d: %v", card.ID, err)
+			continue
+		}
+		if err := j.storeCreditRepo.MarkGiftCardDelivered(card.ID); err != nil {
+			log.Printf("⚠️ Failed to mark gift card %d delivered: %v", card.ID, err)
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("✅ Sent %d gift card emails", sent)
+	}
+}
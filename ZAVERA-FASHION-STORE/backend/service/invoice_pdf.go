@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"zavera/models"
+)
+
+// pdfFont is one of the standard PDF fonts, which every viewer has, so nothing is embedded
+type pdfFont string
+
+const (
+	pdfFontRegular pdfFont = "F1" // Helvetica
+	pdfFontBold    pdfFont = "F2" // Helvetica-Bold
+	pdfFontMono    pdfFont = "F3" // Courier, for amounts that line up on the right
+)
+
+const (
+	pdfPageWidth  = 595.0 // A4 in points
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+)
+
+// pdfDocument writes text and rules on A4 pages: just what an invoice needs, without a PDF library
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.addPage()
+	return d
+}
+
+func (d *pdfDocument) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// text writes s with its baseline at y, measured from the bottom of the page
+func (d *pdfDocument) text(x, y, size float64, font pdfFont, s string) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+// textRight writes s in Courier ending at x. Courier is fixed width, 0.6 of the size per character.
+func (d *pdfDocument) textRight(x, y, size float64, s string) {
+	d.text(x-0.6*size*float64(len([]rune(s))), y, size, pdfFontMono, s)
+}
+
+func (d *pdfDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// bytes assembles the PDF: catalog, page tree, fonts, then a page and its content per page
+func (d *pdfDocument) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 6 // After catalog, page tree and the three fonts
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, name := range []string{"Helvetica", "Helvetica-Bold", "Courier"} {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape writes s in the fonts' WinAnsi encoding, as a PDF string. Latin-1 characters map to
+// themselves; anything else becomes '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// truncateText shortens s to n characters, marking the cut
+func truncateText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// formatInvoiceAmount writes whole rupiah with dots between thousands, e.g. 1.250.000
+func formatInvoiceAmount(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%.0f", math.Round(amount))
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String()
+}
+
+// invoiceSeller is the business issuing the documents, from the INVOICE_SELLER_* settings
+type invoiceSeller struct {
+	Name    string
+	NPWP    string
+	Address string
+}
+
+// invoiceLine is a row of the document's item table
+type invoiceLine struct {
+	Name      string
+	Quantity  int
+	UnitPrice float64
+	Discount  float64
+	TaxRate   float64
+	Tax       float64
+	Amount    float64 // After the discount; with PPN when prices include it
+}
+
+// invoicePDF is what a document is rendered from
+type invoicePDF struct {
+	Invoice  models.Invoice
+	Seller   invoiceSeller
+	Lines    []invoiceLine
+	Shipping *models.ShippingSnapshot // Courier of the order, when known
+	Refund   *models.Refund           // Credit notes only
+}
+
+// render lays out the document: header, seller and buyer, item table, then the totals
+func (p invoicePDF) render() []byte {
+	doc := newPDFDocument()
+	inv := p.Invoice
+	left, right := pdfMargin, pdfPageWidth-pdfMargin
+	y := pdfPageHeight - pdfMargin - 14
+
+	title := "FAKTUR / INVOICE"
+	if inv.DocumentType == models.DocumentTypeCreditNote {
+		title = "NOTA KREDIT / CREDIT NOTE"
+	}
+	doc.text(left, y, 20, pdfFontBold, p.Seller.Name)
+	doc.text(right-0.6*14*float64(len(title)), y, 14, pdfFontBold, title)
+	y -= 16
+	doc.text(left, y, 9, pdfFontRegular, p.Seller.Address)
+	y -= 12
+	if p.Seller.NPWP != "" {
+		doc.text(left, y, 9, pdfFontRegular, "NPWP: "+models.FormatNPWP(p.Seller.NPWP))
+	}
+	y -= 10
+	doc.line(left, y, right, y)
+
+	// Document details on the right, buyer on the left
+	y -= 18
+	details := [][2]string{
+		{"Nomor", inv.InvoiceNumber},
+		{"Tanggal", inv.IssuedAt.Format("02 Jan 2006")},
+		{"Masa Pajak", inv.TaxPeriod},
+		{"Pesanan", inv.OrderCode},
+	}
+	if inv.DocumentType == models.DocumentTypeCreditNote {
+		details = append(details, [2]string{"Atas Faktur", inv.RelatedNumber})
+		if p.Refund != nil {
+			details = append(details, [2]string{"Refund", p.Refund.RefundCode})
+		}
+	}
+	detailY := y
+	for _, d := range details {
+		doc.text(340, detailY, 9, pdfFontBold, d[0])
+		doc.text(420, detailY, 9, pdfFontRegular, d[1])
+		detailY -= 13
+	}
+
+	doc.text(left, y, 9, pdfFontBold, "Kepada")
+	buyerY := y - 13
+	buyer := []string{inv.BuyerName, inv.BuyerEmail}
+	if inv.BuyerNPWP != "" {
+		buyer = []string{inv.BuyerNPWPName, "NPWP: " + models.FormatNPWP(inv.BuyerNPWP)}
+		if inv.BuyerNPWPName == "" {
+			buyer[0] = inv.BuyerName
+		}
+		buyer = append(buyer, wrapText(inv.BuyerNPWPAddress, 55)...)
+		buyer = append(buyer, inv.BuyerEmail)
+	}
+	if p.Shipping != nil && p.Shipping.DestinationAreaName != "" {
+		buyer = append(buyer, wrapText("Dikirim ke: "+p.Shipping.DestinationAreaName, 55)...)
+	}
+	for _, line := range buyer {
+		doc.text(left, buyerY, 9, pdfFontRegular, truncateText(line, 60))
+		buyerY -= 13
+	}
+
+	y = math.Min(detailY, buyerY) - 12
+
+	// Item table
+	columns := []struct {
+		title string
+		x     float64 // Right edge of numeric columns
+	}{
+		{"Produk", left}, {"Qty", 300}, {"Harga", 370}, {"Diskon", 430}, {"PPN", 490}, {"Jumlah", right},
+	}
+	header := func() {
+		doc.line(left, y+12, right, y+12)
+		doc.text(columns[0].x, y, 9, pdfFontBold, columns[0].title)
+		for _, col := range columns[1:] {
+			doc.text(col.x-5.2*float64(len(col.title)), y, 9, pdfFontBold, col.title)
+		}
+		doc.line(left, y-5, right, y-5)
+		y -= 18
+	}
+	header()
+
+	for _, line := range p.Lines {
+		if y < pdfMargin+150 {
+			doc.addPage()
+			y = pdfPageHeight - pdfMargin - 14
+			header()
+		}
+		doc.text(left, y, 9, pdfFontRegular, truncateText(line.Name, 46))
+		doc.textRight(columns[1].x, y, 8, fmt.Sprintf("%d", line.Quantity))
+		doc.textRight(columns[2].x, y, 8, formatInvoiceAmount(line.UnitPrice))
+		doc.textRight(columns[3].x, y, 8, formatInvoiceAmount(line.Discount))
+		doc.textRight(columns[4].x, y, 8, formatInvoiceAmount(line.Tax))
+		doc.textRight(columns[5].x, y, 8, formatInvoiceAmount(line.Amount))
+		y -= 12
+		if line.TaxRate <= 0 && inv.TaxRate > 0 {
+			doc.text(left+8, y, 7, pdfFontRegular, "Bebas PPN")
+			y -= 10
+		}
+	}
+	doc.line(left, y+4, right, y+4)
+	y -= 14
+
+	// Totals
+	var totals [][2]string
+	if inv.DocumentType == models.DocumentTypeInvoice {
+		totals = append(totals, [2]string{"Subtotal", formatInvoiceAmount(inv.Subtotal)})
+		if inv.Discount > 0 {
+			totals = append(totals, [2]string{"Diskon", formatInvoiceAmount(-inv.Discount)})
+		}
+	}
+	if inv.ShippingCost > 0 || inv.DocumentType == models.DocumentTypeInvoice {
+		label := "Ongkos Kirim"
+		if p.Shipping != nil && p.Shipping.Courier != "" {
+			label = fmt.Sprintf("Ongkos Kirim (%s %s)", strings.ToUpper(p.Shipping.Courier), p.Shipping.Service)
+		}
+		totals = append(totals, [2]string{label, formatInvoiceAmount(inv.ShippingCost)})
+	}
+	totals = append(totals, [2]string{"Dasar Pengenaan Pajak (DPP)", formatInvoiceAmount(inv.TaxBase())})
+	taxLabel := fmt.Sprintf("PPN %g%%", inv.TaxRate)
+	if inv.PricesIncludeTax {
+		taxLabel += " (termasuk dalam harga)"
+	}
+	totals = append(totals, [2]string{taxLabel, formatInvoiceAmount(inv.Tax)})
+	for _, t := range totals {
+		doc.text(300, y, 9, pdfFontRegular, t[0])
+		doc.textRight(right, y, 9, t[1])
+		y -= 13
+	}
+
+	totalLabel := "TOTAL"
+	if inv.DocumentType == models.DocumentTypeCreditNote {
+		totalLabel = "TOTAL DIKEMBALIKAN"
+	}
+	doc.line(300, y+9, right, y+9)
+	y -= 4
+	doc.text(300, y, 10, pdfFontBold, totalLabel)
+	doc.textRight(right, y, 10, "Rp "+formatInvoiceAmount(inv.TotalAmount))
+
+	doc.text(left, pdfMargin, 7, pdfFontRegular,
+		fmt.Sprintf("Dokumen ini dibuat secara elektronik oleh %s dan sah tanpa tanda tangan.", p.Seller.Name))
+	return doc.bytes()
+}
+
+// wrapText breaks s into lines of at most n characters at spaces
+func wrapText(s string, n int) []string {
+	var lines []string
+	var current string
+	for _, word := range strings.Fields(s) {
+		if current != "" && len([]rune(current))+1+len([]rune(word)) > n {
+			lines = append(lines, current)
+			current = ""
+		}
+		if current != "" {
+			current += " "
+		}
+		current += word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+	"zavera/models"
+)
+
+func TestFormatInvoiceAmount(t *testing.T) {
+	tests := map[float64]string{
+		0:         "0",
+		999:       "999",
+		1000:      "1.000",
+		1250000:   "1.250.000",
+		-22000:    "-22.000",
+		99999.6:   "100.000",
+		123456789: "123.456.789",
+	}
+	for amount, want := range tests {
+		if got := formatInvoiceAmount(amount); got != want {
+			t.Errorf("formatInvoiceAmount(%v) = %s, want %s", amount, got, want)
+		}
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := map[string]string{
+		"Kemeja (M)":  `Kemeja \(M\)`,
+		`C:\path`:     `C:\\path`,
+		"Café":        `Caf\351`,
+		"Line\nbreak": "Line break",
+		"Tas 👜":       "Tas ?",
+	}
+	for input, want := range tests {
+		if got := pdfEscape(input); got != want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestInvoicePDFStructure(t *testing.T) {
+	doc := invoicePDF{
+		Invoice: models.Invoice{
+			InvoiceNumber: "INV/2026/000001", DocumentType: models.DocumentTypeInvoice,
+			OrderCode: "ZVR-1", TaxPeriod: "2026-10", IssuedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+			BuyerName: "PT Maju", BuyerEmail: "finance@maju.co.id", BuyerNPWP: "012345678901000",
+			BuyerNPWPName: "PT Maju Jaya", BuyerNPWPAddress: "Jl. Pemuda 1, Semarang",
+			Subtotal: 222000, ShippingCost: 20000, TaxRate: 11, Tax: 22000, TotalAmount: 242000, PricesIncludeTax: true,
+		},
+		Seller: invoiceSeller{Name: "ZAVERA", Address: "Semarang"},
+	}
+	// Enough lines to need a second page
+	for i := 0; i < 60; i++ {
+		doc.Lines = append(doc.Lines, invoiceLine{Name: fmt.Sprintf("Kemeja %d", i), Quantity: 1, UnitPrice: 3700, TaxRate: 11, Tax: 367, Amount: 3700})
+	}
+	pdf := doc.render()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("expected two pages")
+	}
+	if !bytes.Contains(pdf, []byte("(NPWP: 01.234.567.8-901.000)")) {
+		t.Error("buyer NPWP not printed")
+	}
+
+	// Every xref entry must point at its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if startxref == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) != 5+2*2 {
+		t.Fatalf("got %d objects, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d does not point at %q", i+1, want)
+		}
+	}
+}
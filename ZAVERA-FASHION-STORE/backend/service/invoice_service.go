@@ -0,0 +1,296 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrInvoiceNotFound   = errors.New("invoice not found")
+	ErrInvalidTaxProfile = errors.New("invalid tax profile")
+	ErrInvalidTaxPeriod  = errors.New("invalid tax period")
+)
+
+// InvoiceService renders the invoices and credit notes the database issues when orders are paid
+// and refunds complete, and manages the NPWP customers want on them
+type InvoiceService interface {
+	GetTaxProfile(userID int) (*models.TaxProfile, error)
+	SaveTaxProfile(userID int, req dto.SaveTaxProfileRequest) (*models.TaxProfile, error)
+
+	// ListOrderDocuments returns the invoice and credit notes of one of the customer's orders
+	ListOrderDocuments(orderCode string, userID int) ([]models.Invoice, error)
+	// RenderForUser renders a document of one of the customer's orders as PDF
+	RenderForUser(id, userID int) (*models.Invoice, []byte, error)
+	Render(id int) (*models.Invoice, []byte, error)
+
+	// ListPeriod returns the documents issued in a YYYY-MM tax period
+	ListPeriod(period string) ([]models.Invoice, error)
+	// ExportPeriod zips the PDFs of a tax period with a CSV summary of them, and names the file
+	ExportPeriod(period string) (string, []byte, error)
+}
+
+type invoiceService struct {
+	invoiceRepo  repository.InvoiceRepository
+	orderRepo    repository.OrderRepository
+	refundRepo   repository.RefundRepository
+	shippingRepo repository.ShippingRepository
+	seller       invoiceSeller
+}
+
+func NewInvoiceService(
+	invoiceRepo repository.InvoiceRepository,
+	orderRepo repository.OrderRepository,
+	refundRepo repository.RefundRepository,
+	shippingRepo repository.ShippingRepository,
+) InvoiceService {
+	sellerNPWP, _ := models.NormalizeNPWP(getEnvOrDefault("INVOICE_SELLER_NPWP", ""))
+	return &invoiceService{
+		invoiceRepo:  invoiceRepo,
+		orderRepo:    orderRepo,
+		refundRepo:   refundRepo,
+		shippingRepo: shippingRepo,
+		seller: invoiceSeller{
+			Name:    getEnvOrDefault("INVOICE_SELLER_NAME", "ZAVERA"),
+			NPWP:    sellerNPWP,
+			Address: getEnvOrDefault("INVOICE_SELLER_ADDRESS", "Semarang, Indonesia"),
+		},
+	}
+}
+
+func (s *invoiceService) GetTaxProfile(userID int) (*models.TaxProfile, error) {
+	profile, err := s.invoiceRepo.GetTaxProfile(userID)
+	if err == repository.ErrUserNotFound {
+		return nil, ErrUserNotFound
+	}
+	return profile, err
+}
+
+func (s *invoiceService) SaveTaxProfile(userID int, req dto.SaveTaxProfileRequest) (*models.TaxProfile, error) {
+	profile := &models.TaxProfile{
+		UserID:  userID,
+		Name:    strings.TrimSpace(req.Name),
+		Address: strings.TrimSpace(req.Address),
+	}
+
+	// An empty NPWP clears the profile: invoices go to the order's name
+	if strings.TrimSpace(req.NPWP) != "" {
+		npwp, ok := models.NormalizeNPWP(req.NPWP)
+		if !ok {
+			return nil, fmt.Errorf("%w: NPWP must have 15 or 16 digits", ErrInvalidTaxProfile)
+		}
+		if profile.Name == "" || profile.Address == "" {
+			return nil, fmt.Errorf("%w: the name and address registered on the NPWP are required", ErrInvalidTaxProfile)
+		}
+		profile.NPWP = npwp
+	} else {
+		profile.Name = ""
+		profile.Address = ""
+	}
+
+	if err := s.invoiceRepo.SaveTaxProfile(profile); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *invoiceService) ListOrderDocuments(orderCode string, userID int) ([]models.Invoice, error) {
+	order, err := s.orderRepo.FindByOrderCode(orderCode)
+	if err != nil || order.UserID == nil || *order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return s.invoiceRepo.FindByOrderID(order.ID)
+}
+
+func (s *invoiceService) RenderForUser(id, userID int) (*models.Invoice, []byte, error) {
+	invoice, err := s.findInvoice(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := s.orderRepo.FindByID(invoice.OrderID)
+	if err != nil || order.UserID == nil || *order.UserID != userID {
+		return nil, nil, ErrInvoiceNotFound
+	}
+
+	pdf, err := s.render(invoice, order)
+	return invoice, pdf, err
+}
+
+func (s *invoiceService) Render(id int) (*models.Invoice, []byte, error) {
+	invoice, err := s.findInvoice(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := s.orderRepo.FindByID(invoice.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pdf, err := s.render(invoice, order)
+	return invoice, pdf, err
+}
+
+func (s *invoiceService) ListPeriod(period string) ([]models.Invoice, error) {
+	period, _, err := models.ParseTaxPeriod(period)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaxPeriod, err)
+	}
+	return s.invoiceRepo.FindByPeriod(period)
+}
+
+func (s *invoiceService) ExportPeriod(period string) (string, []byte, error) {
+	period, _, err := models.ParseTaxPeriod(period)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidTaxPeriod, err)
+	}
+	invoices, err := s.invoiceRepo.FindByPeriod(period)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	summary, err := archive.Create(fmt.Sprintf("ringkasan-%s.csv", period))
+	if err != nil {
+		return "", nil, err
+	}
+	w := csv.NewWriter(summary)
+	w.Write([]string{
+		"invoice_number", "document_type", "issued_at", "order_code", "related_invoice_number",
+		"buyer_name", "buyer_email", "buyer_npwp", "buyer_npwp_name", "buyer_npwp_address",
+		"dpp", "tax_rate", "tax", "shipping_cost", "total_amount",
+	})
+	for _, invoice := range invoices {
+		w.Write([]string{
+			invoice.InvoiceNumber, string(invoice.DocumentType), invoice.IssuedAt.Format("2006-01-02 15:04:05"),
+			invoice.OrderCode, invoice.RelatedNumber,
+			invoice.BuyerName, invoice.BuyerEmail, invoice.BuyerNPWP, invoice.BuyerNPWPName, invoice.BuyerNPWPAddress,
+			fmt.Sprintf("%.0f", invoice.TaxBase()), fmt.Sprintf("%g", invoice.TaxRate), fmt.Sprintf("%.0f", invoice.Tax),
+			fmt.Sprintf("%.0f", invoice.ShippingCost), fmt.Sprintf("%.0f", invoice.TotalAmount),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", nil, err
+	}
+
+	for i := range invoices {
+		order, err := s.orderRepo.FindByID(invoices[i].OrderID)
+		if err != nil {
+			return "", nil, fmt.Errorf("order of %s: %w", invoices[i].InvoiceNumber, err)
+		}
+		pdf, err := s.render(&invoices[i], order)
+		if err != nil {
+			return "", nil, fmt.Errorf("render %s: %w", invoices[i].InvoiceNumber, err)
+		}
+		file, err := archive.Create(invoices[i].FileName())
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := file.Write(pdf); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", nil, err
+	}
+
+	log.Printf("🧾 Exported %d invoices and credit notes of tax period %s", len(invoices), period)
+	return fmt.Sprintf("faktur-%s.zip", period), buf.Bytes(), nil
+}
+
+func (s *invoiceService) findInvoice(id int) (*models.Invoice, error) {
+	invoice, err := s.invoiceRepo.FindByID(id)
+	if err == repository.ErrInvoiceNotFound {
+		return nil, ErrInvoiceNotFound
+	}
+	return invoice, err
+}
+
+// render builds the document's PDF: an invoice lists the order's lines, a credit note the
+// refunded lines, or the refunded amount when the refund was not of particular items
+func (s *invoiceService) render(invoice *models.Invoice, order *models.Order) ([]byte, error) {
+	doc := invoicePDF{Invoice: *invoice, Seller: s.seller}
+	if shipping, err := s.shippingRepo.GetShippingSnapshotByOrderID(order.ID); err == nil {
+		doc.Shipping = shipping
+	}
+
+	if invoice.DocumentType == models.DocumentTypeInvoice {
+		for _, item := range order.Items {
+			doc.Lines = append(doc.Lines, invoiceLine{
+				Name:      item.ProductName,
+				Quantity:  item.Quantity,
+				UnitPrice: item.PricePerUnit,
+				Discount:  item.DiscountAmount,
+				TaxRate:   item.TaxRate,
+				Tax:       item.TaxAmount,
+				Amount:    item.NetAmount(),
+			})
+		}
+		return doc.render(), nil
+	}
+
+	if invoice.RefundID == nil {
+		return nil, fmt.Errorf("credit note %s has no refund", invoice.InvoiceNumber)
+	}
+	refund, err := s.refundRepo.FindByID(*invoice.RefundID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.refundRepo.FindItemsByRefundID(refund.ID)
+	if err != nil {
+		return nil, err
+	}
+	doc.Refund = refund
+
+	for _, item := range items {
+		amount := item.RefundAmount
+		if !invoice.PricesIncludeTax {
+			amount -= item.TaxAmount
+		}
+		taxRate := invoice.TaxRate
+		if item.TaxAmount == 0 {
+			taxRate = 0
+		}
+		doc.Lines = append(doc.Lines, invoiceLine{
+			Name:      item.ProductName,
+			Quantity:  item.Quantity,
+			UnitPrice: item.PricePerUnit,
+			Discount:  math.Max(0, item.PricePerUnit*float64(item.Quantity)-amount),
+			TaxRate:   taxRate,
+			Tax:       item.TaxAmount,
+			Amount:    amount,
+		})
+	}
+	if len(items) == 0 {
+		if amount := invoice.TotalAmount - invoice.ShippingCost; amount > 0 {
+			if !invoice.PricesIncludeTax {
+				amount -= invoice.Tax
+			}
+			doc.Lines = append(doc.Lines, invoiceLine{
+				Name:      fmt.Sprintf("Pengembalian dana pesanan %s", invoice.OrderCode),
+				Quantity:  1,
+				UnitPrice: amount,
+				TaxRate:   invoice.TaxRate,
+				Tax:       invoice.Tax,
+				Amount:    amount,
+			})
+		}
+	}
+	return doc.render(), nil
+}
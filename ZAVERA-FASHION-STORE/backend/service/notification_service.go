@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"time"
+	"zavera/models"
 )
 
 // NotificationSeverity represents the severity level of a notification
@@ -38,6 +39,7 @@ const (
 	NotifDisputeCreated  = "dispute_created"
 	NotifUserRegistered  = "user_registered"
 	NotifUserLogin       = "user_login"
+	NotifHoldExpired     = "reservation_expired"
 )
 
 // Global notification channel
@@ -220,3 +222,24 @@ func NotifyUserLogin(email string, name string) {
 		Read:      false,
 	})
 }
+
+// NotifyReservationExpired sends notification when an expired stock hold goes back on the shelf
+func NotifyReservationExpired(release models.ReservationRelease) {
+	BroadcastNotification(AdminNotification{
+		Type:     NotifHoldExpired,
+		Title:    "⌛ Hold Expired",
+		Message:  fmt.Sprintf("%d x %s released back to stock", release.Quantity, release.SKU),
+		Severity: SeverityInfo,
+		Data: map[string]interface{}{
+			"reservation_id": release.ReservationID,
+			"variant_id":     release.VariantID,
+			"sku":            release.SKU,
+			"warehouse_id":   release.WarehouseID,
+			"quantity":       release.Quantity,
+			"cart_item_id":   release.CartItemID,
+			"ledger_id":      release.LedgerID,
+		},
+		Timestamp: time.Now(),
+		Read:      false,
+	})
+}
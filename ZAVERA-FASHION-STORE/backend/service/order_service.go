@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/repository"
@@ -14,6 +15,8 @@ var (
 	ErrOrderAlreadyFinal   = errors.New("order is in final state, cannot be modified")
 	ErrCartEmpty           = errors.New("cart is empty")
 	ErrInsufficientStock   = errors.New("insufficient stock")
+	// ErrAwaitingStock is returned when packing an order whose pre-order/backorder items have not arrived
+	ErrAwaitingStock = errors.New("order is waiting for pre-order/backorder stock")
 )
 
 type OrderService interface {
@@ -45,17 +48,26 @@ type orderService struct {
 	orderRepo   repository.OrderRepository
 	cartRepo    repository.CartRepository
 	productRepo repository.ProductRepository
+	promotions  PromotionService
+	vouchers    VoucherService
+	taxes       TaxService
 }
 
 func NewOrderService(
 	orderRepo repository.OrderRepository,
 	cartRepo repository.CartRepository,
 	productRepo repository.ProductRepository,
+	promotions PromotionService,
+	vouchers VoucherService,
+	taxes TaxService,
 ) OrderService {
 	return &orderService{
 		orderRepo:   orderRepo,
 		cartRepo:    cartRepo,
 		productRepo: productRepo,
+		promotions:  promotions,
+		vouchers:    vouchers,
+		taxes:       taxes,
 	}
 }
 
@@ -80,7 +92,7 @@ func (s *orderService) CreateOrder(sessionID string, req dto.CheckoutRequest, us
 			return nil, fmt.Errorf("product not found: %w", err)
 		}
 
-		if product.Stock < item.Quantity {
+		if !product.AllowsBackorder && product.Stock+heldInCart(cart, product.ID) < item.Quantity {
 			return nil, fmt.Errorf("%w for product: %s", ErrInsufficientStock, product.Name)
 		}
 
@@ -101,9 +113,24 @@ func (s *orderService) CreateOrder(sessionID string, req dto.CheckoutRequest, us
 
 	// Calculate totals
 	shippingCost := 15000.0 // Fixed shipping for now
-	tax := 0.0
-	discount := 0.0
-	totalAmount := subtotal + shippingCost + tax - discount
+
+	// The destination is not known here, so only free shipping without provinces applies
+	promotions, err := s.promotions.EvaluateCart(cart, &models.PromotionShipping{Cost: shippingCost})
+	if err != nil {
+		return nil, err
+	}
+	discount := promotions.Total()
+	var redemption *models.VoucherRedemption
+	var voucherDiscount models.VoucherDiscount
+	if strings.TrimSpace(req.VoucherCode) != "" {
+		redemption, voucherDiscount, err = s.vouchers.Quote(req.VoucherCode, discountedItems(orderItems, cart, promotions),
+			shippingCost-promotions.ShippingSavings, userID, req.CustomerEmail)
+		if err != nil {
+			return nil, err
+		}
+		discount += voucherDiscount.Total()
+	}
+	setItemDiscounts(orderItems, cart, promotions, voucherDiscount)
 
 	// Create order (stock is reserved atomically in repository)
 	order := &models.Order{
@@ -113,16 +140,28 @@ func (s *orderService) CreateOrder(sessionID string, req dto.CheckoutRequest, us
 		CustomerPhone: req.CustomerPhone,
 		Subtotal:      subtotal,
 		ShippingCost:  shippingCost,
-		Tax:           tax,
 		Discount:      discount,
-		TotalAmount:   totalAmount,
 		Status:        models.OrderStatusPending,
 		Notes:         req.Notes,
+		CartID:        &cart.ID,
+	}
+	order.Metadata = map[string]any{}
+	if len(promotions.Applied) > 0 {
+		order.Metadata["promotions"] = promotions.Applied
+	}
+	if redemption != nil {
+		order.Voucher = redemption
+		order.Metadata["voucher_code"] = redemption.Code
+	}
+
+	// PPN on each line after its discounts, which also sets the total
+	if err := s.taxes.ApplyTax(order, orderItems); err != nil {
+		return nil, err
 	}
 
 	err = s.orderRepo.Create(order, orderItems)
 	if err != nil {
-		return nil, err
+		return nil, voucherRedeemError(err)
 	}
 
 	// Clear cart after successful order creation
@@ -132,6 +171,8 @@ func (s *orderService) CreateOrder(sessionID string, req dto.CheckoutRequest, us
 	response := &dto.CheckoutResponse{
 		OrderID:     order.ID,
 		OrderCode:   order.OrderCode,
+		Discount:    order.Discount,
+		Tax:         order.Tax,
 		TotalAmount: order.TotalAmount,
 		Status:      string(order.Status),
 	}
@@ -420,8 +461,29 @@ func (s *orderService) ValidateOrderTotals(order *models.Order) error {
 		return fmt.Errorf("subtotal mismatch: calculated %.2f, stored %.2f", calculatedSubtotal, order.Subtotal)
 	}
 
-	// Check total calculation
-	expectedTotal := order.Subtotal + order.ShippingCost + order.Tax - order.Discount
+	// Check PPN: each line is taxed on what is paid for it at the order's rate (or exempt),
+	// and the lines add up to the order's tax
+	var lineTax, lineDiscount float64
+	for _, item := range order.Items {
+		if item.TaxRate != 0 && item.TaxRate != order.TaxRate {
+			return fmt.Errorf("tax rate mismatch on %s: line %.2f%%, order %.2f%%", item.ProductName, item.TaxRate, order.TaxRate)
+		}
+		setting := models.TaxSetting{Rate: item.TaxRate, PricesIncludeTax: order.PricesIncludeTax}
+		if expected := setting.LineTax(item.NetAmount()); expected != item.TaxAmount {
+			return fmt.Errorf("tax mismatch on %s: calculated %.2f, stored %.2f", item.ProductName, expected, item.TaxAmount)
+		}
+		lineTax += item.TaxAmount
+		lineDiscount += item.DiscountAmount
+	}
+	if len(order.Items) > 0 && lineTax != order.Tax {
+		return fmt.Errorf("tax mismatch: lines %.2f, stored %.2f", lineTax, order.Tax)
+	}
+	if lineDiscount > order.Discount {
+		return fmt.Errorf("discount mismatch: lines %.2f exceed order discount %.2f", lineDiscount, order.Discount)
+	}
+
+	// Check total calculation: tax is only added when prices exclude it
+	expectedTotal := order.ExpectedTotal()
 	if expectedTotal != order.TotalAmount {
 		return fmt.Errorf("total mismatch: calculated %.2f, stored %.2f", expectedTotal, order.TotalAmount)
 	}
@@ -572,13 +634,17 @@ func (s *orderService) toOrderResponse(order *models.Order) *dto.OrderResponse {
 		Subtotal:      order.Subtotal,
 		ShippingCost:  order.ShippingCost,
 		Tax:           order.Tax,
+		TaxRate:       order.TaxRate,
+		PricesIncludeTax: order.PricesIncludeTax,
 		Discount:      order.Discount,
 		TotalAmount:   order.TotalAmount,
 		Status:        string(order.Status),
 		Resi:          order.Resi,
 		Items:         []dto.OrderItemResponse{},
 		CreatedAt:     order.CreatedAt.Format("2006-01-02 15:04:05"),
+		AwaitingStock: order.AwaitingStock,
 	}
+	response.ExpectedShipDate = dto.FormatDatePtr(order.ExpectedShipDate)
 
 	// Add timestamps
 	if order.PaidAt != nil {
@@ -599,9 +665,36 @@ func (s *orderService) toOrderResponse(order *models.Order) *dto.OrderResponse {
 			Quantity:     item.Quantity,
 			PricePerUnit: item.PricePerUnit,
 			Subtotal:     item.Subtotal,
+			DiscountAmount: item.DiscountAmount,
+			TaxRate:        item.TaxRate,
+			TaxAmount:      item.TaxAmount,
+		}
+		if item.BackorderedQuantity > 0 {
+			itemResponse.BackorderedQuantity = item.BackorderedQuantity
+			itemResponse.ExpectedShipDate = dto.FormatDatePtr(item.ExpectedShipDate)
 		}
+		itemResponse.Components = toOrderItemComponents(item)
 		response.Items = append(response.Items, itemResponse)
 	}
 
 	return response
 }
+
+// toOrderItemComponents lists the components of a bundle line, nil for other lines
+func toOrderItemComponents(item models.OrderItem) []dto.OrderItemComponentResponse {
+	if len(item.Components) == 0 {
+		return nil
+	}
+	components := make([]dto.OrderItemComponentResponse, 0, len(item.Components))
+	for _, c := range item.Components {
+		components = append(components, dto.OrderItemComponentResponse{
+			ID:             c.ID,
+			ProductID:      c.ProductID,
+			ProductName:    c.ProductName,
+			VariantName:    c.VariantName,
+			Quantity:       c.Quantity * item.Quantity,
+			AllocatedPrice: c.AllocatedPrice,
+		})
+	}
+	return components
+}
@@ -25,6 +25,7 @@ var (
 	ErrPaymentAlreadyPaid = errors.New("payment already completed")
 	ErrInvalidSignature   = errors.New("invalid webhook signature")
 	ErrOrderNotPending    = errors.New("order is not in pending status")
+	ErrNothingToPay       = errors.New("order has nothing left to pay")
 )
 
 type PaymentService interface {
@@ -97,6 +98,12 @@ func (s *paymentService) InitiatePayment(orderID int) (string, error) {
 		return "", ErrOrderNotPending
 	}
 
+	// Store credit or discounts cover the whole order; Midtrans rejects a zero gross amount
+	if order.AmountDue() <= 0 {
+		log.Printf("❌ Order %s has nothing left to pay", order.OrderCode)
+		return "", ErrNothingToPay
+	}
+
 	existingPayment, err := s.paymentRepo.FindByOrderID(order.ID)
 	if err == nil && existingPayment != nil {
 		log.Printf("💳 Existing payment found: status=%s", existingPayment.Status)
@@ -140,15 +147,36 @@ func (s *paymentService) InitiatePayment(orderID int) (string, error) {
 		})
 	}
 
-	if order.Tax > 0 {
+	// Tax-inclusive prices already contain the PPN, so it is only a line when added on top
+	if order.Tax > 0 && !order.PricesIncludeTax {
 		tax := int64(order.Tax)
 		itemsTotal += tax
 		items = append(items, midtrans.ItemDetails{
-			ID: "TAX", Name: "Tax",
+			ID: "TAX", Name: fmt.Sprintf("PPN %g%%", order.TaxRate),
 			Price: tax, Qty: 1,
 		})
 	}
 
+	// Voucher discounts go in as a negative line so the items still add up to the total
+	if order.Discount > 0 {
+		discount := int64(order.Discount)
+		itemsTotal -= discount
+		items = append(items, midtrans.ItemDetails{
+			ID: "DISCOUNT", Name: "Voucher Discount",
+			Price: -discount, Qty: 1,
+		})
+	}
+
+	// So is the store credit spent on the order: Midtrans charges what is left
+	if order.StoreCreditAmount > 0 {
+		storeCredit := int64(order.StoreCreditAmount)
+		itemsTotal -= storeCredit
+		items = append(items, midtrans.ItemDetails{
+			ID: "STORE_CREDIT", Name: "Store Credit",
+			Price: -storeCredit, Qty: 1,
+		})
+	}
+
 	// Use calculated items total as gross amount to avoid mismatch
 	grossAmount := itemsTotal
 	log.Printf("💰 Calculated gross amount: %d (order total: %.2f)", grossAmount, order.TotalAmount)
@@ -185,7 +213,7 @@ func (s *paymentService) InitiatePayment(orderID int) (string, error) {
 		OrderID:         order.ID,
 		PaymentMethod:   "midtrans",
 		PaymentProvider: "midtrans_snap",
-		Amount:          order.TotalAmount,
+		Amount:          order.AmountDue(),
 		Status:          models.PaymentStatusPending,
 		ExternalID:      uniqueOrderID, // Store unique ID for webhook matching
 		ProviderResponse: map[string]any{
@@ -249,7 +277,7 @@ func (s *paymentService) ProcessWebhook(notification dto.MidtransNotification) e
 			OrderID:         order.ID,
 			PaymentMethod:   notification.PaymentType,
 			PaymentProvider: "midtrans",
-			Amount:          order.TotalAmount,
+			Amount:          order.AmountDue(),
 			Status:          models.PaymentStatusPending,
 			ExternalID:      notification.OrderID,
 			TransactionID:   notification.TransactionID,
@@ -0,0 +1,1059 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"zavera/dto"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidImportFile = errors.New("import file could not be read")
+
+// maxImportRows caps the data rows of one import file
+const maxImportRows = 5000
+
+// Import diff actions
+const (
+	importActionCreate    = "create"
+	importActionUpdate    = "update"
+	importActionUnchanged = "unchanged"
+)
+
+type sheetColumnKind int
+
+const (
+	sheetText sheetColumnKind = iota
+	sheetInt
+	sheetMoney
+	sheetBool
+	sheetTags     // "|"-separated, normalized like product tags
+	sheetURLs     // "|"-separated absolute http(s) URLs
+	sheetColorHex // #RRGGBB
+)
+
+// sheetColumn describes one column of the product import/export sheet
+type sheetColumn struct {
+	Name     string
+	Kind     sheetColumnKind
+	Variant  bool   // Belongs to the variant (SKU) rather than the product
+	DBColumn string // Column in products / product_variants; empty if handled separately
+	Default  string // Value for new products/variants; a blank cell keeps the current value
+	Nullable bool   // A blank cell is stored as NULL
+	Required bool   // Needed to create a product; a blank cell keeps the current value
+	Positive bool   // Numbers must be greater than zero
+	MaxLen   int
+}
+
+// productSheetColumns is the layout shared by import and export: one row per variant,
+// with the product columns repeated (or left blank) on every row of the same product.
+// A product without variants is a single row with an empty sku; its stock is the product stock.
+var productSheetColumns = []sheetColumn{
+	{Name: "slug", Kind: sheetText, MaxLen: 255},
+	{Name: "name", Kind: sheetText, DBColumn: "name", Required: true, MaxLen: 255},
+	{Name: "description", Kind: sheetText, DBColumn: "description"},
+	{Name: "category", Kind: sheetText, DBColumn: "category", Required: true, MaxLen: 100},
+	{Name: "subcategory", Kind: sheetText, DBColumn: "subcategory", MaxLen: 100},
+	{Name: "brand", Kind: sheetText, DBColumn: "brand", MaxLen: 100},
+	{Name: "material", Kind: sheetText, DBColumn: "material", MaxLen: 100},
+	{Name: "tags", Kind: sheetTags, DBColumn: "tags"},
+	{Name: "price", Kind: sheetMoney, DBColumn: "price", Required: true, Positive: true},
+	{Name: "is_active", Kind: sheetBool, DBColumn: "is_active", Default: "true"},
+	{Name: "weight", Kind: sheetInt, DBColumn: "weight", Default: "500", Positive: true},
+	{Name: "length", Kind: sheetInt, DBColumn: "length", Default: "30", Positive: true},
+	{Name: "width", Kind: sheetInt, DBColumn: "width", Default: "20", Positive: true},
+	{Name: "height", Kind: sheetInt, DBColumn: "height", Default: "5", Positive: true},
+	{Name: "images", Kind: sheetURLs},
+	{Name: "sku", Kind: sheetText, Variant: true, MaxLen: 100},
+	{Name: "size", Kind: sheetText, Variant: true, DBColumn: "size", Nullable: true, MaxLen: 50},
+	{Name: "color", Kind: sheetText, Variant: true, DBColumn: "color", Nullable: true, MaxLen: 50},
+	{Name: "color_hex", Kind: sheetColorHex, Variant: true, DBColumn: "color_hex", Nullable: true},
+	{Name: "variant_price", Kind: sheetMoney, Variant: true, DBColumn: "price", Nullable: true, Positive: true},
+	{Name: "compare_at_price", Kind: sheetMoney, Variant: true, DBColumn: "compare_at_price", Nullable: true},
+	{Name: "cost_per_item", Kind: sheetMoney, Variant: true, DBColumn: "cost_per_item", Nullable: true},
+	{Name: "stock", Kind: sheetInt, Variant: true, DBColumn: "stock_quantity", Default: "0"},
+	{Name: "weight_grams", Kind: sheetInt, Variant: true, DBColumn: "weight_grams", Nullable: true, Positive: true},
+	{Name: "length_cm", Kind: sheetInt, Variant: true, DBColumn: "length_cm", Nullable: true, Positive: true},
+	{Name: "width_cm", Kind: sheetInt, Variant: true, DBColumn: "width_cm", Nullable: true, Positive: true},
+	{Name: "height_cm", Kind: sheetInt, Variant: true, DBColumn: "height_cm", Nullable: true, Positive: true},
+	{Name: "barcode", Kind: sheetText, Variant: true, DBColumn: "barcode", Nullable: true, MaxLen: 100},
+}
+
+var productSheetColumnByName = func() map[string]sheetColumn {
+	m := make(map[string]sheetColumn, len(productSheetColumns))
+	for _, c := range productSheetColumns {
+		m[c.Name] = c
+	}
+	return m
+}()
+
+var colorHexPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// canonical validates a cell and returns it in the form used for diffing and export
+func (c sheetColumn) canonical(raw string) (string, error) {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return "", nil
+	}
+
+	switch c.Kind {
+	case sheetInt:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f != math.Trunc(f) || math.IsInf(f, 0) {
+			return "", errors.New("must be a whole number")
+		}
+		if f < 0 || (c.Positive && f == 0) {
+			return "", errors.New("must be greater than zero")
+		}
+		return strconv.FormatInt(int64(f), 10), nil
+	case sheetMoney:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", errors.New("must be a number without thousand separators (e.g. 249000 or 249000.50)")
+		}
+		if f < 0 || (c.Positive && f == 0) {
+			return "", errors.New("must be greater than zero")
+		}
+		return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64), nil
+	case sheetBool:
+		switch strings.ToLower(v) {
+		case "true", "yes", "y", "1", "ya":
+			return "true", nil
+		case "false", "no", "n", "0", "tidak":
+			return "false", nil
+		}
+		return "", errors.New("must be true or false")
+	case sheetTags:
+		return strings.Join(normalizeTags(strings.Split(v, "|")), "|"), nil
+	case sheetURLs:
+		var urls []string
+		for _, u := range strings.Split(v, "|") {
+			u = strings.TrimSpace(u)
+			if u == "" {
+				continue
+			}
+			if !isValidSEOURL(u) {
+				return "", fmt.Errorf("%q is not an absolute http(s) URL", u)
+			}
+			urls = append(urls, u)
+		}
+		return strings.Join(urls, "|"), nil
+	case sheetColorHex:
+		if !colorHexPattern.MatchString(v) {
+			return "", errors.New("must be a hex color like #1A1A1A")
+		}
+		return strings.ToUpper(v), nil
+	default:
+		if c.MaxLen > 0 && utf8.RuneCountInString(v) > c.MaxLen {
+			return "", fmt.Errorf("must be at most %d characters", c.MaxLen)
+		}
+		return v, nil
+	}
+}
+
+// keepsBlank reports whether a blank cell means "leave as is" rather than "clear"
+func (c sheetColumn) keepsBlank() bool {
+	return c.Required || c.Default != ""
+}
+
+// sqlValue converts a canonical value to a query argument
+func (c sheetColumn) sqlValue(v string) interface{} {
+	if v == "" {
+		v = c.Default
+	}
+	if v == "" && c.Nullable {
+		return nil
+	}
+	switch c.Kind {
+	case sheetInt:
+		n, _ := strconv.Atoi(v)
+		return n
+	case sheetMoney:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case sheetBool:
+		return v == "true"
+	case sheetTags:
+		return pq.Array(splitSheetList(v))
+	default:
+		return v
+	}
+}
+
+func splitSheetList(v string) []string {
+	if v == "" {
+		return []string{}
+	}
+	return strings.Split(v, "|")
+}
+
+// sheetProduct is a product parsed from the import sheet
+type sheetProduct struct {
+	Row      int // First sheet row of the product
+	Slug     string
+	Values   map[string]string // Product columns present in the sheet
+	Variants []*sheetVariant
+
+	// Filled in by planProductImport
+	ID      int
+	Action  string
+	Changes []dto.ImportFieldChange
+}
+
+// sheetVariant is a variant row (non-empty sku) of the import sheet
+type sheetVariant struct {
+	Row    int
+	SKU    string
+	Values map[string]string // Variant columns present in the sheet
+
+	// Filled in by planProductImport
+	ID      int
+	Action  string
+	Changes []dto.ImportFieldChange
+}
+
+// catalogProduct is the current state of a product in the same canonical form as the sheet
+type catalogProduct struct {
+	ID       int
+	Values   map[string]string
+	Variants []catalogVariant
+}
+
+type catalogVariant struct {
+	ID     int
+	SKU    string
+	Values map[string]string
+}
+
+// parseProductSheet validates every row and groups variant rows under their product (by slug).
+// All problems are collected so the whole file can be fixed in one go.
+func parseProductSheet(rows [][]string) ([]*sheetProduct, []dto.ImportRowError) {
+	var errs []dto.ImportRowError
+	if len(rows) < 2 {
+		return nil, []dto.ImportRowError{{Row: 1, Message: "file has no data rows"}}
+	}
+	if len(rows)-1 > maxImportRows {
+		return nil, []dto.ImportRowError{{Row: 1, Message: fmt.Sprintf("file has %d data rows, the maximum is %d", len(rows)-1, maxImportRows)}}
+	}
+
+	header := make([]string, len(rows[0]))
+	present := map[string]bool{}
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case present[name]:
+			errs = append(errs, dto.ImportRowError{Row: 1, Column: name, Message: "duplicate column"})
+		case productSheetColumnByName[name].Name == "":
+			errs = append(errs, dto.ImportRowError{Row: 1, Column: name, Message: "unknown column"})
+		default:
+			header[i] = name
+			present[name] = true
+		}
+	}
+	if !present["slug"] && !present["name"] {
+		errs = append(errs, dto.ImportRowError{Row: 1, Message: "a slug or name column is required"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var products []*sheetProduct
+	bySlug := map[string]*sheetProduct{}
+	skuRows := map[string]int{}
+
+	for i, raw := range rows[1:] {
+		rowNum := i + 2
+		if isBlankRow(raw) {
+			continue
+		}
+
+		values := map[string]string{}
+		for col, name := range header {
+			if name == "" {
+				continue
+			}
+			cell := ""
+			if col < len(raw) {
+				cell = raw[col]
+			}
+			v, err := productSheetColumnByName[name].canonical(cell)
+			if err != nil {
+				errs = append(errs, dto.ImportRowError{Row: rowNum, Column: name, Message: err.Error()})
+				continue
+			}
+			values[name] = v
+		}
+
+		slug := values["slug"]
+		if slug == "" {
+			slug = slugify(values["name"])
+		}
+		if !isValidSlugFormat(slug) {
+			errs = append(errs, dto.ImportRowError{Row: rowNum, Column: "slug", Message: "slug (or a name to generate it from) is required and may only contain a-z, 0-9 and hyphens"})
+			continue
+		}
+		values["slug"] = slug
+		sku := values["sku"]
+
+		product := bySlug[slug]
+		if product == nil {
+			product = &sheetProduct{Row: rowNum, Slug: slug, Values: map[string]string{}}
+			for _, c := range productSheetColumns {
+				if v, ok := values[c.Name]; ok && !c.Variant && !(c.keepsBlank() && v == "") {
+					product.Values[c.Name] = v
+				}
+			}
+			if v := values["stock"]; v != "" && sku == "" {
+				product.Values["stock"] = v
+			}
+			bySlug[slug] = product
+			products = append(products, product)
+		} else {
+			for _, c := range productSheetColumns {
+				if c.Variant || c.Name == "slug" {
+					continue
+				}
+				v, ok := values[c.Name]
+				if ok && strings.TrimSpace(cellFor(raw, header, c.Name)) != "" && v != product.Values[c.Name] {
+					errs = append(errs, dto.ImportRowError{Row: rowNum, Column: c.Name, Message: fmt.Sprintf("differs from row %d of the same product", product.Row)})
+				}
+			}
+		}
+
+		if sku == "" {
+			if product.Row != rowNum {
+				errs = append(errs, dto.ImportRowError{Row: rowNum, Column: "sku", Message: "required on every row after the first row of a product"})
+			}
+			continue
+		}
+		if first, dup := skuRows[sku]; dup {
+			errs = append(errs, dto.ImportRowError{Row: rowNum, Column: "sku", Message: fmt.Sprintf("duplicate SKU, also on row %d", first)})
+			continue
+		}
+		skuRows[sku] = rowNum
+
+		variant := &sheetVariant{Row: rowNum, SKU: sku, Values: map[string]string{}}
+		for _, c := range productSheetColumns {
+			if v, ok := values[c.Name]; ok && c.Variant && !(c.keepsBlank() && v == "") {
+				variant.Values[c.Name] = v
+			}
+		}
+		product.Variants = append(product.Variants, variant)
+	}
+
+	for _, p := range products {
+		if len(p.Variants) > 0 {
+			// Stock of a product with variants is the sum of its SKUs
+			delete(p.Values, "stock")
+		}
+	}
+
+	return products, errs
+}
+
+func cellFor(raw []string, header []string, name string) string {
+	for col, h := range header {
+		if h == name && col < len(raw) {
+			return raw[col]
+		}
+	}
+	return ""
+}
+
+// planProductImport diffs parsed products against the catalog and records the action for each
+// product and variant. skuOwners maps SKUs that already exist to the slug of their product.
+func planProductImport(products []*sheetProduct, existing map[string]*catalogProduct, skuOwners map[string]string) (dto.ProductImportSummary, []dto.ImportRowError) {
+	var summary dto.ProductImportSummary
+	var errs []dto.ImportRowError
+
+	for _, p := range products {
+		current := existing[p.Slug]
+		p.ID, p.Action, p.Changes = 0, importActionCreate, nil
+
+		if current == nil {
+			for _, c := range productSheetColumns {
+				if _, ok := p.Values[c.Name]; c.Required && !ok {
+					errs = append(errs, dto.ImportRowError{Row: p.Row, Column: c.Name, Message: "is required for a new product"})
+				}
+			}
+			summary.ProductsCreated++
+		} else {
+			p.ID = current.ID
+			if _, ok := p.Values["stock"]; ok && len(current.Variants) > 0 {
+				errs = append(errs, dto.ImportRowError{Row: p.Row, Column: "sku", Message: "product has variants, stock must be set per SKU"})
+			}
+			p.Changes = diffSheetValues(p.Values, current.Values, false)
+			if len(p.Changes) > 0 {
+				p.Action = importActionUpdate
+				summary.ProductsUpdated++
+			} else {
+				p.Action = importActionUnchanged
+				summary.ProductsUnchanged++
+			}
+		}
+
+		currentBySKU := map[string]catalogVariant{}
+		combos := map[string]string{} // size|color -> SKU after the import
+		if current != nil {
+			for _, v := range current.Variants {
+				currentBySKU[v.SKU] = v
+				combos[variantCombo(v.Values)] = v.SKU
+			}
+		}
+
+		for _, v := range p.Variants {
+			if owner, ok := skuOwners[v.SKU]; ok && owner != p.Slug {
+				errs = append(errs, dto.ImportRowError{Row: v.Row, Column: "sku", Message: fmt.Sprintf("SKU already belongs to product %q", owner)})
+				continue
+			}
+
+			final := map[string]string{"size": "", "color": ""}
+			cv, exists := currentBySKU[v.SKU]
+			if exists {
+				final["size"], final["color"] = cv.Values["size"], cv.Values["color"]
+				delete(combos, variantCombo(cv.Values))
+			}
+			for _, key := range []string{"size", "color"} {
+				if val, ok := v.Values[key]; ok {
+					final[key] = val
+				}
+			}
+			if other, taken := combos[variantCombo(final)]; taken {
+				errs = append(errs, dto.ImportRowError{Row: v.Row, Column: "size", Message: fmt.Sprintf("size/color combination is already used by SKU %s", other)})
+			}
+			combos[variantCombo(final)] = v.SKU
+
+			if !exists {
+				v.ID, v.Action, v.Changes = 0, importActionCreate, nil
+				summary.VariantsCreated++
+				continue
+			}
+			v.ID = cv.ID
+			v.Changes = diffSheetValues(v.Values, cv.Values, true)
+			if len(v.Changes) > 0 {
+				v.Action = importActionUpdate
+				summary.VariantsUpdated++
+			} else {
+				v.Action = importActionUnchanged
+				summary.VariantsUnchanged++
+			}
+		}
+	}
+
+	return summary, errs
+}
+
+// diffSheetValues lists the columns of one scope (product or variant) whose sheet value
+// differs from the catalog. Columns missing from the sheet are left untouched.
+func diffSheetValues(sheet, current map[string]string, variant bool) []dto.ImportFieldChange {
+	var changes []dto.ImportFieldChange
+	for _, c := range productSheetColumns {
+		if c.Variant != variant || c.Name == "slug" || c.Name == "sku" {
+			continue
+		}
+		if v, ok := sheet[c.Name]; ok && v != current[c.Name] {
+			changes = append(changes, dto.ImportFieldChange{Field: c.Name, From: current[c.Name], To: v})
+		}
+	}
+	// The product-level stock only exists for products without variants
+	if !variant {
+		if v, ok := sheet["stock"]; ok && v != current["stock"] {
+			changes = append(changes, dto.ImportFieldChange{Field: "stock", From: current["stock"], To: v})
+		}
+	}
+	return changes
+}
+
+func variantCombo(values map[string]string) string {
+	return strings.ToLower(values["size"]) + "|" + strings.ToLower(values["color"])
+}
+
+func variantNameFor(size, color, sku string) string {
+	parts := []string{}
+	for _, p := range []string{size, color} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return sku
+	}
+	return strings.Join(parts, " - ")
+}
+
+// ============================================
+// ADMIN PRODUCT SERVICE: IMPORT / EXPORT
+// ============================================
+
+// ImportProducts validates a CSV/XLSX product sheet and diffs it against the catalog.
+// Unless dryRun is set, a file without errors is applied in a single transaction.
+func (s *adminProductService) ImportProducts(format string, data []byte, dryRun bool) (*dto.ProductImportReport, error) {
+	rows, err := readSheet(format, data)
+	if err == ErrUnsupportedSheetFormat {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	report := &dto.ProductImportReport{
+		DryRun:   dryRun,
+		Products: []dto.ProductImportDiff{},
+		Errors:   []dto.ImportRowError{},
+	}
+	if len(rows) > 0 {
+		report.TotalRows = len(rows) - 1
+	}
+
+	products, errs := parseProductSheet(rows)
+	if len(errs) > 0 {
+		report.Errors = errs
+		return report, nil
+	}
+
+	if dryRun {
+		if err := s.planImport(s.db, products, report); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.planImport(tx, products, report); err != nil {
+		return nil, err
+	}
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	for _, p := range products {
+		if err := s.applyImportedProduct(tx, p); err != nil {
+			row := p.Row
+			var rowErr *importRowFailure
+			if errors.As(err, &rowErr) {
+				row = rowErr.row
+			}
+			log.Printf("❌ Product import failed at row %d: %v", row, err)
+			report.Errors = append(report.Errors, dto.ImportRowError{Row: row, Message: err.Error()})
+			return report, nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.Applied = true
+
+	log.Printf("📦 Product import applied: %d created, %d updated, %d variants created, %d variants updated",
+		report.Summary.ProductsCreated, report.Summary.ProductsUpdated, report.Summary.VariantsCreated, report.Summary.VariantsUpdated)
+
+	return report, nil
+}
+
+// ExportProducts writes the whole catalog, inactive products included, in the import layout
+func (s *adminProductService) ExportProducts(format string) ([]byte, error) {
+	if format != SheetFormatCSV && format != SheetFormatXLSX {
+		return nil, ErrUnsupportedSheetFormat
+	}
+
+	products, err := s.loadCatalogProducts(s.db, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return writeSheet(format, "Products", productSheetRows(products))
+}
+
+// productSheetRows renders catalog products as sheet rows, header first
+func productSheetRows(products []*catalogProduct) [][]string {
+	header := make([]string, len(productSheetColumns))
+	for i, c := range productSheetColumns {
+		header[i] = c.Name
+	}
+	rows := [][]string{header}
+
+	for _, p := range products {
+		variants := p.Variants
+		if len(variants) == 0 {
+			// Single row carrying the product stock
+			variants = []catalogVariant{{Values: map[string]string{"stock": p.Values["stock"]}}}
+		}
+		for _, v := range variants {
+			row := make([]string, len(productSheetColumns))
+			for i, c := range productSheetColumns {
+				if c.Variant {
+					row[i] = v.Values[c.Name]
+				} else {
+					row[i] = p.Values[c.Name]
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+type dbQueryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// planImport loads the affected catalog products and fills the report with the diff
+func (s *adminProductService) planImport(q dbQueryer, products []*sheetProduct, report *dto.ProductImportReport) error {
+	slugs := make([]string, 0, len(products))
+	var skus []string
+	for _, p := range products {
+		slugs = append(slugs, p.Slug)
+		for _, v := range p.Variants {
+			skus = append(skus, v.SKU)
+		}
+	}
+
+	current, err := s.loadCatalogProducts(q, "p.slug = ANY($1)", []interface{}{pq.Array(slugs)})
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*catalogProduct, len(current))
+	for _, p := range current {
+		existing[p.Values["slug"]] = p
+	}
+
+	skuOwners := map[string]string{}
+	rows, err := q.Query(`
+		SELECT v.sku, p.slug
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.sku = ANY($1)
+	`, pq.Array(skus))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sku, slug string
+		if err := rows.Scan(&sku, &slug); err != nil {
+			return err
+		}
+		skuOwners[sku] = slug
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	summary, errs := planProductImport(products, existing, skuOwners)
+	report.Summary = summary
+	report.Errors = append(report.Errors, errs...)
+
+	for _, p := range products {
+		diff := dto.ProductImportDiff{
+			Row:     p.Row,
+			Slug:    p.Slug,
+			Name:    p.Values["name"],
+			Action:  p.Action,
+			Changes: p.Changes,
+		}
+		if cur := existing[p.Slug]; cur != nil {
+			id := cur.ID
+			diff.ProductID = &id
+			if diff.Name == "" {
+				diff.Name = cur.Values["name"]
+			}
+		}
+		for _, v := range p.Variants {
+			vd := dto.VariantImportDiff{Row: v.Row, SKU: v.SKU, Action: v.Action, Changes: v.Changes}
+			if v.ID != 0 {
+				id := v.ID
+				vd.VariantID = &id
+			}
+			diff.Variants = append(diff.Variants, vd)
+		}
+		report.Products = append(report.Products, diff)
+	}
+	return nil
+}
+
+// loadCatalogProducts reads products (optionally filtered) with images and variants
+// into the canonical sheet form
+func (s *adminProductService) loadCatalogProducts(q dbQueryer, where string, args []interface{}) ([]*catalogProduct, error) {
+	query := `
+		SELECT p.id, p.slug, p.name, COALESCE(p.description, ''), COALESCE(p.category, ''),
+		       COALESCE(p.subcategory, ''), COALESCE(p.brand, ''), COALESCE(p.material, ''),
+		       COALESCE(p.tags, '{}'), p.price, p.stock, p.is_active,
+		       COALESCE(p.weight, 500), COALESCE(p.length, 30), COALESCE(p.width, 20), COALESCE(p.height, 5)
+		FROM products p
+	`
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY p.id"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*catalogProduct
+	byID := map[int]*catalogProduct{}
+	var ids []int
+	for rows.Next() {
+		var id, stock, weight, length, width, height int
+		var slug, name, description, category, subcategory, brand, material string
+		var tags []string
+		var price float64
+		var isActive bool
+		if err := rows.Scan(&id, &slug, &name, &description, &category, &subcategory, &brand, &material,
+			pq.Array(&tags), &price, &stock, &isActive, &weight, &length, &width, &height); err != nil {
+			return nil, err
+		}
+
+		p := &catalogProduct{ID: id, Values: canonicalSheetValues(map[string]string{
+			"slug":        slug,
+			"name":        name,
+			"description": description,
+			"category":    category,
+			"subcategory": subcategory,
+			"brand":       brand,
+			"material":    material,
+			"tags":        strings.Join(tags, "|"),
+			"price":       strconv.FormatFloat(price, 'f', -1, 64),
+			"is_active":   strconv.FormatBool(isActive),
+			"weight":      strconv.Itoa(weight),
+			"length":      strconv.Itoa(length),
+			"width":       strconv.Itoa(width),
+			"height":      strconv.Itoa(height),
+		})}
+		p.Values["stock"] = strconv.Itoa(stock)
+		products = append(products, p)
+		byID[id] = p
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	imageRows, err := q.Query(`
+		SELECT product_id, image_url FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, display_order, id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer imageRows.Close()
+	images := map[int][]string{}
+	for imageRows.Next() {
+		var productID int
+		var url string
+		if err := imageRows.Scan(&productID, &url); err != nil {
+			return nil, err
+		}
+		images[productID] = append(images[productID], url)
+	}
+	if err := imageRows.Err(); err != nil {
+		return nil, err
+	}
+	for id, urls := range images {
+		byID[id].Values["images"] = strings.Join(urls, "|")
+	}
+
+	variantRows, err := q.Query(`
+		SELECT id, product_id, sku, COALESCE(size, ''), COALESCE(color, ''), COALESCE(color_hex, ''),
+		       price, compare_at_price, cost_per_item, stock_quantity,
+		       weight_grams, length_cm, width_cm, height_cm, COALESCE(barcode, '')
+		FROM product_variants
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position, id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer variantRows.Close()
+	for variantRows.Next() {
+		var id, productID, stock int
+		var sku, size, color, colorHex, barcode string
+		var price, compareAt, cost sql.NullFloat64
+		var weight, length, width, height sql.NullInt64
+		if err := variantRows.Scan(&id, &productID, &sku, &size, &color, &colorHex,
+			&price, &compareAt, &cost, &stock, &weight, &length, &width, &height, &barcode); err != nil {
+			return nil, err
+		}
+
+		byID[productID].Variants = append(byID[productID].Variants, catalogVariant{
+			ID:  id,
+			SKU: sku,
+			Values: canonicalSheetValues(map[string]string{
+				"sku":              sku,
+				"size":             size,
+				"color":            color,
+				"color_hex":        colorHex,
+				"variant_price":    formatNullMoney(price),
+				"compare_at_price": formatNullMoney(compareAt),
+				"cost_per_item":    formatNullMoney(cost),
+				"stock":            strconv.Itoa(stock),
+				"weight_grams":     formatNullInt(weight),
+				"length_cm":        formatNullInt(length),
+				"width_cm":         formatNullInt(width),
+				"height_cm":        formatNullInt(height),
+				"barcode":          barcode,
+			}),
+		})
+	}
+	return products, variantRows.Err()
+}
+
+// canonicalSheetValues normalizes stored values the way import cells are, so legacy data
+// (e.g. lowercase color hex) does not show up as a change. Values that fail validation are kept as-is.
+func canonicalSheetValues(values map[string]string) map[string]string {
+	for name, v := range values {
+		if c, ok := productSheetColumnByName[name]; ok && v != "" {
+			if canonical, err := c.canonical(v); err == nil {
+				values[name] = canonical
+			}
+		}
+	}
+	return values
+}
+
+func formatNullMoney(v sql.NullFloat64) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(v.Float64, 'f', -1, 64)
+}
+
+func formatNullInt(v sql.NullInt64) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatInt(v.Int64, 10)
+}
+
+// importRowFailure ties a database error to the sheet row that caused it
+type importRowFailure struct {
+	row int
+	err error
+}
+
+func (e *importRowFailure) Error() string { return e.err.Error() }
+func (e *importRowFailure) Unwrap() error { return e.err }
+
+// applyImportedProduct writes one planned product and its variants inside the import transaction
+func (s *adminProductService) applyImportedProduct(tx *sql.Tx, p *sheetProduct) error {
+	switch p.Action {
+	case importActionCreate:
+		if err := s.insertImportedProduct(tx, p); err != nil {
+			return err
+		}
+	case importActionUpdate:
+		if err := s.updateImportedProduct(tx, p); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Variants) == 0 {
+		return nil
+	}
+
+	var position int
+	var hasDefault bool
+	err := tx.QueryRow(
+		"SELECT COALESCE(MAX(position) + 1, 0), COALESCE(BOOL_OR(is_default), false) FROM product_variants WHERE product_id = $1", p.ID,
+	).Scan(&position, &hasDefault)
+	if err != nil {
+		return err
+	}
+
+	// Variant stock changes are recorded on the stock ledger by the database, which also keeps the
+	// deprecated product stock in step
+	for _, v := range p.Variants {
+		var err error
+		switch v.Action {
+		case importActionCreate:
+			err = insertImportedVariant(tx, p.ID, v, position, !hasDefault)
+			position++
+			hasDefault = true
+		case importActionUpdate:
+			err = updateImportedVariant(tx, v)
+		}
+		if err != nil {
+			return &importRowFailure{row: v.Row, err: err}
+		}
+	}
+	return nil
+}
+
+func (s *adminProductService) insertImportedProduct(tx *sql.Tx, p *sheetProduct) error {
+	value := func(name string) interface{} {
+		return productSheetColumnByName[name].sqlValue(p.Values[name])
+	}
+
+	categoryID, category, subcategory, err := s.resolveProductCategory(nil, p.Values["category"], p.Values["subcategory"])
+	if err != nil {
+		return err
+	}
+
+	stock, _ := strconv.Atoi(p.Values["stock"])
+	err = tx.QueryRow(`
+		INSERT INTO products (name, slug, description, price, stock, weight, length, width, height,
+		                      category, subcategory, brand, material, is_active, category_id, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id
+	`,
+		p.Values["name"], p.Slug, p.Values["description"], value("price"), stock,
+		value("weight"), value("length"), value("width"), value("height"),
+		category, subcategory, p.Values["brand"], p.Values["material"], value("is_active"),
+		categoryID, value("tags"),
+	).Scan(&p.ID)
+	if err != nil {
+		return err
+	}
+
+	// A new product owning the slug takes precedence over any redirect left by a rename
+	if _, err := tx.Exec("DELETE FROM product_slug_history WHERE old_slug = $1", p.Slug); err != nil {
+		return err
+	}
+
+	return replaceImportedImages(tx, p.ID, splitSheetList(p.Values["images"]))
+}
+
+func (s *adminProductService) updateImportedProduct(tx *sql.Tx, p *sheetProduct) error {
+	sets := []string{}
+	args := []interface{}{}
+	categoryChanged := false
+
+	for _, change := range p.Changes {
+		switch change.Field {
+		case "images":
+			if err := replaceImportedImages(tx, p.ID, splitSheetList(change.To)); err != nil {
+				return err
+			}
+			continue
+		case "stock":
+			args = append(args, productSheetColumnByName["stock"].sqlValue(change.To))
+			sets = append(sets, fmt.Sprintf("stock = $%d", len(args)))
+			continue
+		case "category", "subcategory":
+			categoryChanged = true
+		}
+
+		c := productSheetColumnByName[change.Field]
+		args = append(args, c.sqlValue(change.To))
+		sets = append(sets, fmt.Sprintf("%s = $%d", c.DBColumn, len(args)))
+	}
+
+	if categoryChanged {
+		var category, subcategory string
+		err := tx.QueryRow("SELECT COALESCE(category, ''), COALESCE(subcategory, '') FROM products WHERE id = $1", p.ID).Scan(&category, &subcategory)
+		if err != nil {
+			return err
+		}
+		if v, ok := p.Values["category"]; ok {
+			category = v
+		}
+		if v, ok := p.Values["subcategory"]; ok {
+			subcategory = v
+		}
+		categoryID, _, _, err := s.resolveProductCategory(nil, category, subcategory)
+		if err != nil {
+			return err
+		}
+		args = append(args, categoryID)
+		sets = append(sets, fmt.Sprintf("category_id = $%d", len(args)))
+	}
+
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, p.ID)
+	query := fmt.Sprintf("UPDATE products SET %s, updated_at = NOW() WHERE id = $%d", strings.Join(sets, ", "), len(args))
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+func replaceImportedImages(tx *sql.Tx, productID int, urls []string) error {
+	if _, err := tx.Exec("DELETE FROM product_images WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	for i, url := range urls {
+		_, err := tx.Exec(`
+			INSERT INTO product_images (product_id, image_url, is_primary, display_order)
+			VALUES ($1, $2, $3, $4)
+		`, productID, url, i == 0, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertImportedVariant(tx *sql.Tx, productID int, v *sheetVariant, position int, isDefault bool) error {
+	value := func(name string) interface{} {
+		return productSheetColumnByName[name].sqlValue(v.Values[name])
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO product_variants (
+			product_id, sku, variant_name, size, color, color_hex, price, compare_at_price, cost_per_item,
+			stock_quantity, low_stock_threshold, is_active, is_default,
+			weight_grams, length_cm, width_cm, height_cm, barcode, position
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 5, true, $11, $12, $13, $14, $15, $16, $17)
+	`,
+		productID, v.SKU, variantNameFor(v.Values["size"], v.Values["color"], v.SKU),
+		value("size"), value("color"), value("color_hex"),
+		value("variant_price"), value("compare_at_price"), value("cost_per_item"),
+		value("stock"), isDefault,
+		value("weight_grams"), value("length_cm"), value("width_cm"), value("height_cm"), value("barcode"),
+		position,
+	)
+	return err
+}
+
+func updateImportedVariant(tx *sql.Tx, v *sheetVariant) error {
+	sets := []string{}
+	args := []interface{}{}
+	renamed := false
+
+	for _, change := range v.Changes {
+		c := productSheetColumnByName[change.Field]
+		args = append(args, c.sqlValue(change.To))
+		sets = append(sets, fmt.Sprintf("%s = $%d", c.DBColumn, len(args)))
+		if change.Field == "size" || change.Field == "color" {
+			renamed = true
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	args = append(args, v.ID)
+	idArg := len(args)
+	query := fmt.Sprintf("UPDATE product_variants SET %s, updated_at = NOW() WHERE id = $%d", strings.Join(sets, ", "), idArg)
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	if renamed {
+		_, err := tx.Exec(`
+			UPDATE product_variants
+			SET variant_name = COALESCE(NULLIF(CONCAT_WS(' - ', size, color), ''), sku)
+			WHERE id = $1
+		`, v.ID)
+		return err
+	}
+	return nil
+}
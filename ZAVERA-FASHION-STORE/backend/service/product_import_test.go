@@ -0,0 +1,190 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+var importHeader = []string{"slug", "name", "category", "price", "images", "sku", "size", "color", "stock"}
+
+func TestParseProductSheet_GroupsVariantsAndReportsRowErrors(t *testing.T) {
+	rows := [][]string{
+		importHeader,
+		{"kemeja-linen", "Kemeja Linen", "pria", "249000", "https://img.example.com/a.jpg|https://img.example.com/b.jpg", "KL-M-WHT", "M", "White", "10"},
+		{"kemeja-linen", "", "", "", "", "KL-L-WHT", "L", "White", "4"},
+		{"", "Dress Satin", "wanita", "1.250.000", "", "", "", "", "3"},
+		{"kemeja-linen", "Kemeja Linen Baru", "", "", "", "KL-M-WHT", "M", "White", "x"},
+		{"", "", "", "", "", "", "", "", ""},
+	}
+
+	products, errs := parseProductSheet(rows)
+
+	if len(products) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(products))
+	}
+	shirt := products[0]
+	if shirt.Slug != "kemeja-linen" || len(shirt.Variants) != 2 {
+		t.Fatalf("expected kemeja-linen with 2 variants, got %s with %d", shirt.Slug, len(shirt.Variants))
+	}
+	if _, ok := shirt.Values["stock"]; ok {
+		t.Errorf("product with variants should not carry a product-level stock")
+	}
+	if shirt.Values["images"] != "https://img.example.com/a.jpg|https://img.example.com/b.jpg" {
+		t.Errorf("unexpected images %q", shirt.Values["images"])
+	}
+	if products[1].Slug != "dress-satin" {
+		t.Errorf("expected slug generated from name, got %q", products[1].Slug)
+	}
+
+	got := map[[2]interface{}]bool{}
+	for _, e := range errs {
+		got[[2]interface{}{e.Row, e.Column}] = true
+	}
+	for _, want := range [][2]interface{}{
+		{4, "price"}, // thousand separators
+		{5, "name"},  // differs from row 2
+		{5, "stock"}, // not a number
+		{5, "sku"},   // duplicate SKU
+	} {
+		if !got[want] {
+			t.Errorf("expected error at row %v column %v, got %+v", want[0], want[1], errs)
+		}
+	}
+	if len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %+v", errs)
+	}
+}
+
+func TestParseProductSheet_RejectsUnknownColumns(t *testing.T) {
+	_, errs := parseProductSheet([][]string{{"slug", "harga"}, {"a", "1"}})
+	if len(errs) != 1 || errs[0].Row != 1 || errs[0].Column != "harga" {
+		t.Errorf("expected unknown column error on the header, got %+v", errs)
+	}
+}
+
+func TestPlanProductImport_Diff(t *testing.T) {
+	products, errs := parseProductSheet([][]string{
+		importHeader,
+		{"kemeja-linen", "Kemeja Linen", "pria", "259000", "", "KL-M-WHT", "M", "White", "10"},
+		{"kemeja-linen", "", "", "", "", "KL-L-WHT", "L", "White", "4"},
+		{"kemeja-linen", "", "", "", "", "KL-S-WHT", "M", "white", "1"},
+		{"celana-chino", "", "", "", "", "CC-30", "30", "", "2"},
+		{"topi-bucket", "Topi Bucket", "aksesoris", "99000", "", "TB-01", "", "", "5"},
+	})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parse errors: %+v", errs)
+	}
+
+	existing := map[string]*catalogProduct{
+		"kemeja-linen": {
+			ID:     7,
+			Values: map[string]string{"slug": "kemeja-linen", "name": "Kemeja Linen", "category": "pria", "price": "249000"},
+			Variants: []catalogVariant{
+				{ID: 70, SKU: "KL-M-WHT", Values: map[string]string{"sku": "KL-M-WHT", "size": "M", "color": "White", "stock": "10"}},
+			},
+		},
+	}
+	skuOwners := map[string]string{"KL-M-WHT": "kemeja-linen", "TB-01": "topi-lama"}
+
+	summary, errs := planProductImport(products, existing, skuOwners)
+
+	shirt := products[0]
+	if shirt.Action != importActionUpdate || shirt.ID != 7 {
+		t.Errorf("expected update of product 7, got %s %d", shirt.Action, shirt.ID)
+	}
+	wantChanges := []string{"price"}
+	var gotChanges []string
+	for _, c := range shirt.Changes {
+		gotChanges = append(gotChanges, c.Field)
+	}
+	if !reflect.DeepEqual(gotChanges, wantChanges) {
+		t.Errorf("expected changes %v, got %+v", wantChanges, shirt.Changes)
+	}
+	if shirt.Variants[0].Action != importActionUnchanged || shirt.Variants[1].Action != importActionCreate {
+		t.Errorf("unexpected variant actions %s, %s", shirt.Variants[0].Action, shirt.Variants[1].Action)
+	}
+
+	if summary.ProductsUpdated != 1 || summary.ProductsCreated != 2 || summary.VariantsUnchanged != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	got := map[[2]interface{}]bool{}
+	for _, e := range errs {
+		got[[2]interface{}{e.Row, e.Column}] = true
+	}
+	for _, want := range [][2]interface{}{
+		{4, "size"},     // M/white collides with KL-M-WHT (case-insensitive)
+		{5, "name"},     // new product without a name
+		{5, "category"}, // ... or category
+		{5, "price"},    // ... or price
+		{6, "sku"},      // SKU owned by another product
+	} {
+		if !got[want] {
+			t.Errorf("expected error at row %v column %v, got %+v", want[0], want[1], errs)
+		}
+	}
+}
+
+func TestProductSheetRows_RoundTripsThroughImport(t *testing.T) {
+	catalog := []*catalogProduct{
+		{
+			ID: 1,
+			Values: map[string]string{"slug": "kaos-basic", "name": "Kaos, \"Basic\"", "category": "pria", "price": "99000", "is_active": "true", "stock": "12",
+				"weight": "300", "length": "30", "width": "20", "height": "5"},
+		},
+		{
+			ID: 2,
+			Values: map[string]string{"slug": "dress-satin", "name": "Dress Satin", "category": "wanita", "price": "450000", "is_active": "false", "tags": "satin|pesta",
+				"weight": "500", "length": "30", "width": "20", "height": "5", "images": "https://img.example.com/ds.jpg"},
+			Variants: []catalogVariant{
+				{ID: 20, SKU: "DS-S", Values: map[string]string{"sku": "DS-S", "size": "S", "stock": "3", "barcode": "0012345"}},
+				{ID: 21, SKU: "DS-M", Values: map[string]string{"sku": "DS-M", "size": "M", "stock": "0", "variant_price": "475000"}},
+			},
+		},
+	}
+
+	for _, format := range []string{SheetFormatCSV, SheetFormatXLSX} {
+		data, err := writeSheet(format, "Products", productSheetRows(catalog))
+		if err != nil {
+			t.Fatalf("%s: write failed: %v", format, err)
+		}
+		rows, err := readSheet(format, data)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", format, err)
+		}
+		if len(rows) != 4 {
+			t.Fatalf("%s: expected header + 3 rows, got %d", format, len(rows))
+		}
+
+		products, errs := parseProductSheet(rows)
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %+v", format, errs)
+		}
+
+		existing := map[string]*catalogProduct{}
+		for _, p := range catalog {
+			existing[p.Values["slug"]] = p
+		}
+		summary, errs := planProductImport(products, existing, map[string]string{"DS-S": "dress-satin", "DS-M": "dress-satin"})
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected plan errors %+v", format, errs)
+		}
+		if summary.ProductsUnchanged != 2 || summary.VariantsUnchanged != 2 {
+			for _, p := range products {
+				t.Logf("%s: %s %+v", p.Slug, p.Action, p.Changes)
+			}
+			t.Errorf("%s: expected re-importing an export to change nothing, got %+v", format, summary)
+		}
+	}
+}
+
+func TestXLSXColumnNames(t *testing.T) {
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumnName(col); got != name {
+			t.Errorf("xlsxColumnName(%d) = %s, want %s", col, got, name)
+		}
+		if got, err := xlsxColumnIndex(name + "12"); err != nil || got != col {
+			t.Errorf("xlsxColumnIndex(%s12) = %d, %v, want %d", name, got, err, col)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// ProductScheduleJob applies scheduled publishing and price changes when they fall due
+// and reverts sales when they end. Every step is written to the admin audit log.
+type ProductScheduleJob struct {
+	scheduleService ProductScheduleService
+	ticker          *time.Ticker
+	done            chan bool
+}
+
+func NewProductScheduleJob(scheduleService ProductScheduleService) *ProductScheduleJob {
+	return &ProductScheduleJob{
+		scheduleService: scheduleService,
+		done:            make(chan bool),
+	}
+}
+
+// Start begins the product schedule job
+// Runs every minute so drop launches go live on time
+func (j *ProductScheduleJob) Start() {
+	j.ticker = time.NewTicker(1 * time.Minute)
+
+	// Run immediately on start to catch up on anything missed while down
+	go j.applyDue()
+
+	go func() {
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-j.ticker.C:
+				j.applyDue()
+			}
+		}
+	}()
+
+	log.Println("⏰ Product schedule job started (checks every minute)")
+}
+
+// Stop stops the product schedule job
+func (j *ProductScheduleJob) Stop() {
+	if j.ticker != nil {
+		j.ticker.Stop()
+	}
+	j.done <- true
+	log.Println("⏰ Product schedule job stopped")
+}
+
+func (j *ProductScheduleJob) applyDue() {
+	applied, err := j.scheduleService.ApplyDueSchedules()
+	if err != nil {
+		log.Printf("⚠️ Failed to apply product schedules: %v", err)
+		return
+	}
+
+	if applied > 0 {
+		log.Printf("✅ Applied %d product schedule entries", applied)
+	}
+}
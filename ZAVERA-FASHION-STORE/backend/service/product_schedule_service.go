@@ -0,0 +1,340 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrProductScheduleNotFound = errors.New("product schedule not found")
+	ErrInvalidProductSchedule  = errors.New("invalid product schedule")
+	ErrProductScheduleConflict = errors.New("another sale is scheduled for this product in that period")
+	ErrScheduleNotCancellable  = errors.New("only pending entries and running sales can be cancelled")
+)
+
+// productScheduleActor is recorded as the admin on audit entries written by the job
+const productScheduleActor = "system:product-schedule-job"
+
+// productScheduleBatch caps how many entries one job run applies
+const productScheduleBatch = 100
+
+type ProductScheduleService interface {
+	CreateSchedule(productID int, req dto.CreateProductScheduleRequest, adminEmail string) (*dto.ProductScheduleResponse, error)
+	ListSchedules(filter models.ProductScheduleFilter) ([]dto.ProductScheduleResponse, int, error)
+	CancelSchedule(id int, adminEmail string) (*dto.ProductScheduleResponse, error)
+	ApplyDueSchedules() (int, error)
+}
+
+type productScheduleService struct {
+	db           *sql.DB
+	scheduleRepo repository.ProductScheduleRepository
+	productRepo  repository.ProductRepository
+	auditRepo    repository.AdminAuditRepository
+}
+
+func NewProductScheduleService(db *sql.DB, scheduleRepo repository.ProductScheduleRepository, productRepo repository.ProductRepository, auditRepo repository.AdminAuditRepository) ProductScheduleService {
+	return &productScheduleService{
+		db:           db,
+		scheduleRepo: scheduleRepo,
+		productRepo:  productRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+// CreateSchedule validates and stores a schedule entry; ProductScheduleJob applies it when due
+func (s *productScheduleService) CreateSchedule(productID int, req dto.CreateProductScheduleRequest, adminEmail string) (*dto.ProductScheduleResponse, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	schedule, err := newProductSchedule(productID, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule.CreatedBy = adminEmail
+
+	if schedule.Action.NeedsPrice() {
+		conflict, err := s.scheduleRepo.HasPriceConflict(productID, schedule.RunAt, schedule.EndsAt)
+		if err != nil {
+			return nil, err
+		}
+		if conflict {
+			return nil, ErrProductScheduleConflict
+		}
+	}
+
+	if err := s.scheduleRepo.Create(schedule); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🗓️ Product %d: %s scheduled for %s by %s", productID, schedule.Action, schedule.RunAt.Format("2006-01-02 15:04"), adminEmail)
+
+	return s.getSchedule(schedule.ID)
+}
+
+// ListSchedules returns schedule entries, newest run time first
+func (s *productScheduleService) ListSchedules(filter models.ProductScheduleFilter) ([]dto.ProductScheduleResponse, int, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	schedules, total, err := s.scheduleRepo.List(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	response := make([]dto.ProductScheduleResponse, 0, len(schedules))
+	for _, sched := range schedules {
+		response = append(response, toProductScheduleResponse(sched))
+	}
+	return response, total, nil
+}
+
+// CancelSchedule withdraws a pending entry, or ends a running sale now (prices revert immediately)
+func (s *productScheduleService) CancelSchedule(id int, adminEmail string) (*dto.ProductScheduleResponse, error) {
+	schedule, err := s.scheduleRepo.FindByID(id)
+	if err == repository.ErrProductScheduleNotFound {
+		return nil, ErrProductScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch schedule.Status {
+	case models.ScheduleStatusPending:
+		err = s.scheduleRepo.Cancel(id)
+	case models.ScheduleStatusActive:
+		if err = s.scheduleRepo.EndSaleAt(id, time.Now()); err == nil {
+			_, err = s.applySchedule(id, time.Now())
+		}
+	default:
+		return nil, ErrScheduleNotCancellable
+	}
+	if err == repository.ErrProductScheduleChanged {
+		return nil, ErrScheduleNotCancellable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🗓️ Product schedule %d (%s) cancelled by %s", id, schedule.Action, adminEmail)
+
+	return s.getSchedule(id)
+}
+
+// ApplyDueSchedules applies entries whose run time has passed and reverts sales that ended.
+// Each entry is applied in its own transaction together with its audit log entry.
+func (s *productScheduleService) ApplyDueSchedules() (int, error) {
+	now := time.Now()
+	ids, err := s.scheduleRepo.FindDueIDs(now, productScheduleBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, id := range ids {
+		done, err := s.applySchedule(id, now)
+		if err != nil {
+			log.Printf("⚠️ Failed to apply product schedule %d: %v", id, err)
+			continue
+		}
+		if done {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applySchedule advances one entry. Returns false when the entry was not due or is
+// being handled by another instance.
+func (s *productScheduleService) applySchedule(id int, now time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	schedule, err := s.scheduleRepo.LockForUpdateTx(tx, id)
+	if err == repository.ErrProductScheduleNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	before, slug, err := s.scheduleRepo.LockProductStateTx(tx, schedule.ProductID)
+	if err != nil {
+		return false, err
+	}
+
+	previousStatus := schedule.Status
+	after, status, note, due := schedule.Advance(*before, now)
+	if !due {
+		return false, nil
+	}
+
+	if status != models.ScheduleStatusFailed {
+		if err := s.scheduleRepo.UpdateProductStateTx(tx, schedule.ProductID, after); err != nil {
+			return false, err
+		}
+	}
+
+	schedule.Status = status
+	schedule.ResultNote = note
+	switch status {
+	case models.ScheduleStatusApplied, models.ScheduleStatusActive:
+		schedule.AppliedAt = &now
+	case models.ScheduleStatusCompleted, models.ScheduleStatusFailed:
+		schedule.CompletedAt = &now
+	}
+	if err := s.scheduleRepo.SaveProgressTx(tx, schedule); err != nil {
+		return false, err
+	}
+
+	auditLog := &models.AdminAuditLog{
+		AdminEmail:     productScheduleActor,
+		ActionType:     models.AdminActionProductSchedule,
+		ActionDetail:   describeScheduleStep(schedule, previousStatus),
+		TargetType:     "product",
+		TargetID:       schedule.ProductID,
+		TargetCode:     slug,
+		StateBefore:    pricingStateMap(*before),
+		StateAfter:     pricingStateMap(after),
+		Success:        status != models.ScheduleStatusFailed,
+		IdempotencyKey: fmt.Sprintf("product-schedule-%d-%s", schedule.ID, status),
+		Metadata: map[string]any{
+			"schedule_id":  schedule.ID,
+			"action":       schedule.Action,
+			"status":       status,
+			"scheduled_by": schedule.CreatedBy,
+			"note":         note,
+		},
+	}
+	if status == models.ScheduleStatusFailed {
+		auditLog.ErrorMessage = note
+	}
+	if err := s.auditRepo.CreateWithTx(tx, auditLog); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	log.Printf("🗓️ %s", auditLog.ActionDetail)
+	return true, nil
+}
+
+func (s *productScheduleService) getSchedule(id int) (*dto.ProductScheduleResponse, error) {
+	schedule, err := s.scheduleRepo.FindByID(id)
+	if err == repository.ErrProductScheduleNotFound {
+		return nil, ErrProductScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := toProductScheduleResponse(*schedule)
+	return &response, nil
+}
+
+// newProductSchedule validates a request into a PENDING entry
+func newProductSchedule(productID int, req dto.CreateProductScheduleRequest, now time.Time) (*models.ProductSchedule, error) {
+	action := models.ProductScheduleAction(req.Action)
+	if !action.IsValid() {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidProductSchedule, req.Action)
+	}
+	// A minute of slack for clocks and "now" from the admin UI
+	if req.RunAt.Before(now.Add(-time.Minute)) {
+		return nil, fmt.Errorf("%w: run_at must be in the future", ErrInvalidProductSchedule)
+	}
+
+	if action.NeedsPrice() {
+		if req.NewPrice == nil || *req.NewPrice <= 0 {
+			return nil, fmt.Errorf("%w: new_price must be greater than zero", ErrInvalidProductSchedule)
+		}
+	} else if req.NewPrice != nil {
+		return nil, fmt.Errorf("%w: new_price is only used by PRICE_CHANGE and SALE", ErrInvalidProductSchedule)
+	}
+
+	if action == models.ScheduleActionSale {
+		if req.EndsAt == nil || !req.EndsAt.After(req.RunAt) {
+			return nil, fmt.Errorf("%w: a sale needs ends_at after run_at", ErrInvalidProductSchedule)
+		}
+	} else if req.EndsAt != nil {
+		return nil, fmt.Errorf("%w: ends_at is only used by SALE", ErrInvalidProductSchedule)
+	}
+
+	return &models.ProductSchedule{
+		ProductID: productID,
+		Action:    action,
+		RunAt:     req.RunAt,
+		EndsAt:    req.EndsAt,
+		NewPrice:  req.NewPrice,
+		Status:    models.ScheduleStatusPending,
+		Note:      req.Note,
+	}, nil
+}
+
+// describeScheduleStep is the audit log detail for a job step
+func describeScheduleStep(s *models.ProductSchedule, previousStatus models.ProductScheduleStatus) string {
+	var detail string
+	switch {
+	case s.Status == models.ScheduleStatusFailed:
+		detail = fmt.Sprintf("Scheduled %s #%d failed", s.Action, s.ID)
+	case s.Action == models.ScheduleActionPublish:
+		detail = fmt.Sprintf("Published product %s (schedule #%d)", s.ProductSlug, s.ID)
+	case s.Action == models.ScheduleActionUnpublish:
+		detail = fmt.Sprintf("Unpublished product %s (schedule #%d)", s.ProductSlug, s.ID)
+	case s.Action == models.ScheduleActionPriceChange:
+		detail = fmt.Sprintf("Changed price of %s to %.2f (schedule #%d)", s.ProductSlug, *s.NewPrice, s.ID)
+	case previousStatus == models.ScheduleStatusPending && s.Status == models.ScheduleStatusActive:
+		detail = fmt.Sprintf("Started sale on %s at %.2f (schedule #%d)", s.ProductSlug, *s.NewPrice, s.ID)
+	case previousStatus == models.ScheduleStatusActive:
+		detail = fmt.Sprintf("Ended sale on %s (schedule #%d)", s.ProductSlug, s.ID)
+	default:
+		detail = fmt.Sprintf("Skipped scheduled %s #%d", s.Action, s.ID)
+	}
+	if s.ResultNote != "" {
+		detail += ": " + s.ResultNote
+	}
+	return detail
+}
+
+func pricingStateMap(state models.ProductPricingState) map[string]any {
+	return map[string]any{
+		"price":            state.Price,
+		"compare_at_price": state.CompareAtPrice,
+		"is_active":        state.IsActive,
+	}
+}
+
+func toProductScheduleResponse(s models.ProductSchedule) dto.ProductScheduleResponse {
+	return dto.ProductScheduleResponse{
+		ID:                     s.ID,
+		ProductID:              s.ProductID,
+		ProductName:            s.ProductName,
+		ProductSlug:            s.ProductSlug,
+		Action:                 string(s.Action),
+		Status:                 string(s.Status),
+		RunAt:                  dto.FormatTime(s.RunAt),
+		EndsAt:                 dto.FormatTimePtr(s.EndsAt),
+		NewPrice:               s.NewPrice,
+		PreviousPrice:          s.PreviousPrice,
+		PreviousCompareAtPrice: s.PreviousCompareAtPrice,
+		Note:                   s.Note,
+		ResultNote:             s.ResultNote,
+		CreatedBy:              s.CreatedBy,
+		AppliedAt:              dto.FormatTimePtr(s.AppliedAt),
+		CompletedAt:            dto.FormatTimePtr(s.CompletedAt),
+		CreatedAt:              dto.FormatTime(s.CreatedAt),
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"zavera/models"
+)
+
+func TestToProductSEOResponse_Fallbacks(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "https://zavera.id/")
+
+	p := &models.Product{
+		ID:          7,
+		Name:        "Kemeja Linen",
+		Description: "Kemeja   linen\nadem untuk " + strings.Repeat("cuaca tropis ", 20),
+	}
+
+	seo := toProductSEOResponse(p, "https://cdn.example.com/kemeja.jpg")
+
+	if seo.MetaTitle != "Kemeja Linen | ZAVERA" {
+		t.Errorf("unexpected meta title: %q", seo.MetaTitle)
+	}
+	if len([]rune(seo.MetaDescription)) != 160 || !strings.HasPrefix(seo.MetaDescription, "Kemeja linen adem") {
+		t.Errorf("meta description should be whitespace-collapsed and truncated to 160 runes: %q", seo.MetaDescription)
+	}
+	if seo.CanonicalURL != "https://zavera.id/product/7" {
+		t.Errorf("unexpected canonical URL: %q", seo.CanonicalURL)
+	}
+	if seo.OGImage != "https://cdn.example.com/kemeja.jpg" {
+		t.Errorf("unexpected og image: %q", seo.OGImage)
+	}
+}
+
+func TestToProductSEOResponse_Overrides(t *testing.T) {
+	p := &models.Product{
+		ID:              7,
+		Name:            "Kemeja Linen",
+		MetaTitle:       "Kemeja Linen Pria Terbaik",
+		MetaDescription: "Custom description",
+		CanonicalURL:    "https://zavera.id/kemeja-linen",
+		OGImageURL:      "https://cdn.example.com/og.jpg",
+	}
+
+	seo := toProductSEOResponse(p, "https://cdn.example.com/kemeja.jpg")
+
+	if seo.MetaTitle != p.MetaTitle || seo.MetaDescription != p.MetaDescription ||
+		seo.CanonicalURL != p.CanonicalURL || seo.OGImage != p.OGImageURL {
+		t.Errorf("overrides should be returned as-is, got %+v", seo)
+	}
+}
+
+func TestIsValidSEOURL(t *testing.T) {
+	tests := []struct {
+		raw   string
+		valid bool
+	}{
+		{"", true},
+		{"https://zavera.id/product/1", true},
+		{"http://localhost:3000/x", true},
+		{"/product/1", false},
+		{"javascript:alert(1)", false},
+		{"zavera.id/product/1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isValidSEOURL(tt.raw); got != tt.valid {
+			t.Errorf("isValidSEOURL(%q) = %v, expected %v", tt.raw, got, tt.valid)
+		}
+	}
+}
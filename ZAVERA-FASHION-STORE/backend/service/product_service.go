@@ -1,6 +1,14 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/repository"
@@ -10,17 +18,31 @@ type ProductService interface {
 	GetAllProducts() ([]dto.ProductResponse, error)
 	GetProductsByCategory(category string) ([]dto.ProductResponse, error)
 	GetProductByID(id int) (*dto.ProductResponse, error)
+	GetProductBySlug(slug string) (*dto.ProductResponse, string, error)
+	SearchProducts(filter models.ProductSearchFilter, includeFacets bool) (*dto.ProductSearchResponse, error)
+	ListProducts(query models.ProductListQuery) (*dto.ProductPageResponse, error)
+	GetCollections() ([]dto.CollectionResponse, error)
+	GetCollectionProducts(slug string, limit, offset int) (*dto.CollectionProductsResponse, error)
 }
 
+var (
+	ErrInvalidProductSort   = errors.New("invalid sort option")
+	ErrInvalidProductCursor = errors.New("invalid or expired cursor")
+)
+
 type productService struct {
-	productRepo repository.ProductRepository
-	variantRepo *repository.VariantRepository
+	productRepo     repository.ProductRepository
+	variantRepo     *repository.VariantRepository
+	categoryService CategoryService
+	collectionRepo  repository.CollectionRepository
 }
 
-func NewProductService(productRepo repository.ProductRepository, variantRepo *repository.VariantRepository) ProductService {
+func NewProductService(productRepo repository.ProductRepository, variantRepo *repository.VariantRepository, categoryService CategoryService, collectionRepo repository.CollectionRepository) ProductService {
 	return &productService{
-		productRepo: productRepo,
-		variantRepo: variantRepo,
+		productRepo:     productRepo,
+		variantRepo:     variantRepo,
+		categoryService: categoryService,
+		collectionRepo:  collectionRepo,
 	}
 }
 
@@ -30,54 +52,365 @@ func (s *productService) GetAllProducts() ([]dto.ProductResponse, error) {
 		return nil, err
 	}
 
-	var response []dto.ProductResponse
-	for _, p := range products {
-		productResp := s.toProductResponse(&p)
-		response = append(response, productResp)
+	return s.toProductResponses(products), nil
+}
+
+// GetProductsByCategory lists products in a category (ID or path) and all its subcategories.
+// Unknown values fall back to matching the legacy category string.
+func (s *productService) GetProductsByCategory(category string) ([]dto.ProductResponse, error) {
+	var products []models.Product
+	var err error
+	if categoryIDs := s.resolveCategoryIDs(category); len(categoryIDs) > 0 {
+		products, err = s.productRepo.FindByCategoryIDs(categoryIDs)
+	} else {
+		products, err = s.productRepo.FindByCategory(category)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toProductResponses(products), nil
+}
+
+func (s *productService) GetProductByID(id int) (*dto.ProductResponse, error) {
+	product, err := s.productRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.productRepo.FindVariantSummaries([]int{product.ID})
+	if err != nil {
+		log.Printf("⚠️ Failed to load variant summary for product %d: %v", product.ID, err)
+	}
+
+	response := s.toProductResponse(product, summaries)
+	response.SEO = toProductSEOResponse(product, response.ImageURL)
+	s.attachRatings(&response)
+	return &response, nil
+}
+
+// GetProductBySlug returns an active product by slug.
+// If the slug was retired by a rename, the product is nil and the current slug is returned for a redirect.
+func (s *productService) GetProductBySlug(slug string) (*dto.ProductResponse, string, error) {
+	product, err := s.productRepo.FindBySlug(slug)
+	if err == nil && product.IsActive {
+		summaries, err := s.productRepo.FindVariantSummaries([]int{product.ID})
+		if err != nil {
+			log.Printf("⚠️ Failed to load variant summary for product %d: %v", product.ID, err)
+		}
+
+		response := s.toProductResponse(product, summaries)
+		response.SEO = toProductSEOResponse(product, response.ImageURL)
+		s.attachRatings(&response)
+		return &response, "", nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+
+	currentSlug, err := s.productRepo.FindSlugRedirect(slug)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrProductNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, currentSlug, nil
+}
+
+// SearchProducts runs a catalog search and optionally computes facet counts for the result set
+func (s *productService) SearchProducts(filter models.ProductSearchFilter, includeFacets bool) (*dto.ProductSearchResponse, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Category != "" && len(filter.CategoryIDs) == 0 {
+		filter.CategoryIDs = s.resolveCategoryIDs(filter.Category)
+	}
+
+	products, total, err := s.productRepo.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.ProductSearchResponse{
+		Products:   []dto.ProductResponse{},
+		TotalCount: total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}
+	response.Products = append(response.Products, s.toProductResponses(products)...)
+
+	if includeFacets {
+		facets, err := s.productRepo.SearchFacets(filter)
+		if err != nil {
+			return nil, err
+		}
+		response.Facets = toProductFacetsResponse(facets)
 	}
 
 	return response, nil
 }
 
-func (s *productService) GetProductsByCategory(category string) ([]dto.ProductResponse, error) {
-	products, err := s.productRepo.FindByCategory(category)
+func toProductFacetsResponse(f *models.ProductFacets) *dto.ProductFacetsResponse {
+	toCounts := func(counts []models.FacetCount) []dto.FacetCountResponse {
+		out := make([]dto.FacetCountResponse, 0, len(counts))
+		for _, c := range counts {
+			out = append(out, dto.FacetCountResponse{Value: c.Value, Count: c.Count})
+		}
+		return out
+	}
+
+	buckets := make([]dto.PriceBucketResponse, 0, len(f.PriceBuckets))
+	for _, b := range f.PriceBuckets {
+		buckets = append(buckets, dto.PriceBucketResponse{Label: b.Label, Min: b.Min, Max: b.Max, Count: b.Count})
+	}
+
+	return &dto.ProductFacetsResponse{
+		Brands:       toCounts(f.Brands),
+		Sizes:        toCounts(f.Sizes),
+		Colors:       toCounts(f.Colors),
+		PriceBuckets: buckets,
+	}
+}
+
+// ListProducts returns one page of the catalog using keyset pagination
+func (s *productService) ListProducts(query models.ProductListQuery) (*dto.ProductPageResponse, error) {
+	if query.Sort == "" {
+		query.Sort = models.ProductSortNewest
+	}
+	if !query.Sort.IsValid() {
+		return nil, ErrInvalidProductSort
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	if query.Category != "" && len(query.CategoryIDs) == 0 {
+		query.CategoryIDs = s.resolveCategoryIDs(query.Category)
+	}
+
+	products, nextCursor, err := s.productRepo.ListPage(query)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidProductCursor) {
+			return nil, ErrInvalidProductCursor
+		}
 		return nil, err
 	}
 
-	var response []dto.ProductResponse
-	for _, p := range products {
-		productResp := s.toProductResponse(&p)
-		response = append(response, productResp)
+	return &dto.ProductPageResponse{
+		Products:   append([]dto.ProductResponse{}, s.toProductResponses(products)...),
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+		Sort:       string(query.Sort),
+		Limit:      query.Limit,
+	}, nil
+}
+
+// hiddenCategoryIDs filters a listing by a category no product is in (IDs start at 1)
+var hiddenCategoryIDs = []int{0}
+
+// resolveCategoryIDs maps a category ID or path to its subtree.
+// Returns nil when the category is unknown so callers can fall back to the legacy string match.
+// An inactive category resolves to hiddenCategoryIDs, which matches no products.
+func (s *productService) resolveCategoryIDs(ref string) []int {
+	if s.categoryService == nil || ref == "" {
+		return nil
+	}
+	ids, err := s.categoryService.ResolveCategoryIDs(ref)
+	if err == errCategoryHidden {
+		return hiddenCategoryIDs
+	}
+	if err != nil {
+		if err != ErrCategoryNotFound {
+			log.Printf("⚠️ Failed to resolve category %q: %v", ref, err)
+		}
+		return nil
+	}
+	return ids
+}
+
+// toProductSEOResponse resolves SEO metadata, falling back to product content where no override is set
+func toProductSEOResponse(p *models.Product, primaryImage string) *dto.ProductSEOResponse {
+	seo := &dto.ProductSEOResponse{
+		MetaTitle:       p.MetaTitle,
+		MetaDescription: p.MetaDescription,
+		CanonicalURL:    p.CanonicalURL,
+		OGImage:         p.OGImageURL,
+	}
+
+	if seo.MetaTitle == "" {
+		seo.MetaTitle = p.Name + " | ZAVERA"
+	}
+	if seo.MetaDescription == "" {
+		seo.MetaDescription = truncateRunes(strings.Join(strings.Fields(p.Description), " "), 160)
+	}
+	if seo.CanonicalURL == "" {
+		seo.CanonicalURL = productPageURL(p.ID)
+	}
+	if seo.OGImage == "" {
+		seo.OGImage = primaryImage
+	}
+
+	return seo
+}
+
+// productPageURL is the storefront URL of a product detail page
+func productPageURL(productID int) string {
+	return fmt.Sprintf("%s/product/%d", strings.TrimRight(getEnvOrDefault("FRONTEND_URL", "http://localhost:3000"), "/"), productID)
+}
+
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// GetCollections returns collections currently inside their visibility window
+func (s *productService) GetCollections() ([]dto.CollectionResponse, error) {
+	collections, err := s.collectionRepo.FindAll(true)
+	if err != nil {
+		return nil, err
 	}
 
+	response := make([]dto.CollectionResponse, 0, len(collections))
+	now := time.Now()
+	for _, c := range collections {
+		response = append(response, toPublicCollectionResponse(c, now))
+	}
 	return response, nil
 }
 
-func (s *productService) GetProductByID(id int) (*dto.ProductResponse, error) {
-	product, err := s.productRepo.FindByID(id)
+// GetCollectionProducts returns a page of a visible collection's active products.
+// Inactive or out-of-window collections are reported as not found.
+func (s *productService) GetCollectionProducts(slug string, limit, offset int) (*dto.CollectionProductsResponse, error) {
+	collection, err := s.collectionRepo.FindBySlug(slug)
+	if err != nil {
+		return nil, mapCollectionError(err)
+	}
+	now := time.Now()
+	if !collection.IsVisibleAt(now) {
+		return nil, ErrCollectionNotFound
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	products, total, err := s.productRepo.FindCollectionProducts(collection, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
-	response := s.toProductResponse(product)
-	return &response, nil
+	response := &dto.CollectionProductsResponse{
+		Collection: toPublicCollectionResponse(*collection, now),
+		Products:   s.toProductResponses(products),
+		TotalCount: total,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	if response.Products == nil {
+		response.Products = []dto.ProductResponse{}
+	}
+	return response, nil
 }
 
-func (s *productService) toProductResponse(p *models.Product) dto.ProductResponse {
+// toPublicCollectionResponse hides merchandising rules from storefront responses
+func toPublicCollectionResponse(c models.Collection, now time.Time) dto.CollectionResponse {
+	response := toCollectionResponse(c, now)
+	response.Rules = nil
+	response.MatchType = ""
+	response.ProductCount = nil
+	return response
+}
+
+// toProductResponses converts a page of products, loading variant summaries in one query
+func (s *productService) toProductResponses(products []models.Product) []dto.ProductResponse {
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	summaries, err := s.productRepo.FindVariantSummaries(ids)
+	if err != nil {
+		log.Printf("⚠️ Failed to load variant summaries: %v", err)
+	}
+
+	var response []dto.ProductResponse
+	for i := range products {
+		response = append(response, s.toProductResponse(&products[i], summaries))
+	}
+
+	targets := make([]*dto.ProductResponse, len(response))
+	for i := range response {
+		targets[i] = &response[i]
+	}
+	s.attachRatings(targets...)
+
+	return response
+}
+
+// attachRatings sets the approved-review rating and fit summary, in one query for all responses
+func (s *productService) attachRatings(responses ...*dto.ProductResponse) {
+	if len(responses) == 0 {
+		return
+	}
+
+	ids := make([]int, len(responses))
+	for i, r := range responses {
+		ids[i] = r.ID
+	}
+
+	ratings, err := s.productRepo.FindRatingSummaries(ids)
+	if err != nil {
+		log.Printf("⚠️ Failed to load rating summaries: %v", err)
+		return
+	}
+
+	for _, r := range responses {
+		if summary, ok := ratings[r.ID]; ok {
+			r.Rating = toProductRatingResponse(summary)
+		}
+	}
+}
+
+func toProductRatingResponse(summary models.ProductRatingSummary) *dto.ProductRatingResponse {
+	return &dto.ProductRatingResponse{
+		Average: math.Round(summary.Average*10) / 10,
+		Count:   summary.Count,
+		Fit: dto.ProductFitResponse{
+			RunsSmall:  summary.FitSmall,
+			TrueToSize: summary.FitTrue,
+			RunsLarge:  summary.FitLarge,
+			Verdict:    summary.FitVerdict(),
+		},
+	}
+}
+
+func (s *productService) toProductResponse(p *models.Product, summaries map[int]models.ProductVariantSummary) dto.ProductResponse {
 	response := dto.ProductResponse{
 		ID:          p.ID,
 		Name:        p.Name,
 		Slug:        p.Slug,
 		Description: p.Description,
 		Price:       p.Price,
+		CompareAtPrice: p.CompareAtPrice,
 		Stock:       p.Stock,
 		Weight:      p.Weight,
 		Category:    p.Category,
 		Subcategory: p.Subcategory,
+		CategoryID:  p.CategoryID,
 		Brand:       p.Brand,
 		Material:    p.Material,
 	}
+	response.IsBundle = p.IsBundle
+	response.IsGiftCard = p.IsGiftCard
 
 	// Set primary image URL and all images
 	var images []string
@@ -95,16 +428,16 @@ func (s *productService) toProductResponse(p *models.Product) dto.ProductRespons
 
 	response.Images = images
 
-	// Get available sizes from active variants
-	variants, err := s.variantRepo.GetByProductID(p.ID)
-	if err == nil && len(variants) > 0 {
+	// Price range and available sizes from active variants
+	if summary, ok := summaries[p.ID]; ok {
+		response.MinPrice = summary.MinPrice
+		response.MaxPrice = summary.MaxPrice
+
 		sizeMap := make(map[string]bool)
-		for _, v := range variants {
-			if v.IsActive && v.Size != nil && *v.Size != "" && v.StockQuantity > 0 {
-				sizeMap[*v.Size] = true
-			}
+		for _, size := range summary.AvailableSizes {
+			sizeMap[size] = true
 		}
-		
+
 		// Convert map to sorted slice
 		var sizes []string
 		sizeOrder := []string{"XS", "S", "M", "L", "XL", "XXL"}
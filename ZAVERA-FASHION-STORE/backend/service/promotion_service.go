@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrInvalidPromotion  = errors.New("invalid promotion")
+)
+
+// PromotionService manages automatic promotions and evaluates them for the cart and checkout
+type PromotionService interface {
+	ListPromotions() ([]models.Promotion, error)
+	GetPromotion(id int) (*models.Promotion, error)
+	CreatePromotion(req dto.SavePromotionRequest, adminEmail string) (*models.Promotion, error)
+	UpdatePromotion(id int, req dto.SavePromotionRequest, adminEmail string) (*models.Promotion, error)
+	DeletePromotion(id int, adminEmail string) error
+
+	// EvaluateCart applies the running promotions to the cart. Shipping is nil until checkout
+	// knows the destination and cost.
+	EvaluateCart(cart *models.Cart, shipping *models.PromotionShipping) (*models.PromotionResult, error)
+}
+
+type promotionService struct {
+	promotionRepo repository.PromotionRepository
+	productRepo   repository.ProductRepository
+	categoryRepo  repository.CategoryRepository
+	variantRepo   *repository.VariantRepository
+}
+
+func NewPromotionService(
+	promotionRepo repository.PromotionRepository,
+	productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository,
+	variantRepo *repository.VariantRepository,
+) PromotionService {
+	return &promotionService{
+		promotionRepo: promotionRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		variantRepo:   variantRepo,
+	}
+}
+
+func (s *promotionService) ListPromotions() ([]models.Promotion, error) {
+	return s.promotionRepo.List()
+}
+
+func (s *promotionService) GetPromotion(id int) (*models.Promotion, error) {
+	promotion, err := s.promotionRepo.FindByID(id)
+	if err == repository.ErrPromotionNotFound {
+		return nil, ErrPromotionNotFound
+	}
+	return promotion, err
+}
+
+func (s *promotionService) CreatePromotion(req dto.SavePromotionRequest, adminEmail string) (*models.Promotion, error) {
+	promotion, err := s.buildPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+	promotion.CreatedBy = adminEmail
+
+	if err := s.promotionRepo.Create(promotion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🏷️ Promotion %d (%s) created by %s", promotion.ID, promotion.Name, adminEmail)
+	return promotion, nil
+}
+
+func (s *promotionService) UpdatePromotion(id int, req dto.SavePromotionRequest, adminEmail string) (*models.Promotion, error) {
+	promotion, err := s.buildPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.ID = id
+	if err := s.promotionRepo.Update(promotion); err != nil {
+		if err == repository.ErrPromotionNotFound {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+
+	log.Printf("🏷️ Promotion %d (%s) updated by %s", promotion.ID, promotion.Name, adminEmail)
+	return promotion, nil
+}
+
+func (s *promotionService) DeletePromotion(id int, adminEmail string) error {
+	if err := s.promotionRepo.Delete(id); err != nil {
+		if err == repository.ErrPromotionNotFound {
+			return ErrPromotionNotFound
+		}
+		return err
+	}
+
+	log.Printf("🏷️ Promotion %d deleted by %s", id, adminEmail)
+	return nil
+}
+
+// buildPromotion validates the request against the rules of its type and drops the settings
+// the type does not use
+func (s *promotionService) buildPromotion(req dto.SavePromotionRequest) (*models.Promotion, error) {
+	promotion := &models.Promotion{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Type:        models.PromotionType(strings.ToUpper(strings.TrimSpace(req.Type))),
+		CategoryID:  req.CategoryID,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		Tiers:       models.PromotionTiers{},
+		Provinces:   []string{},
+	}
+	if req.Brand != nil && strings.TrimSpace(*req.Brand) != "" {
+		brand := strings.TrimSpace(*req.Brand)
+		promotion.Brand = &brand
+	}
+
+	if !promotion.Type.IsValid() {
+		return nil, fmt.Errorf("%w: unknown type %s", ErrInvalidPromotion, req.Type)
+	}
+
+	switch promotion.Type {
+	case models.PromotionTypeQuantityDiscount:
+		if req.MinQuantity < 1 || req.Percent <= 0 || req.Percent > 100 {
+			return nil, fmt.Errorf("%w: a minimum quantity and a percentage between 0 and 100 are required", ErrInvalidPromotion)
+		}
+		promotion.MinQuantity = req.MinQuantity
+		promotion.Percent = req.Percent
+	case models.PromotionTypeSpendTier:
+		if len(req.Tiers) == 0 {
+			return nil, fmt.Errorf("%w: at least one tier is required", ErrInvalidPromotion)
+		}
+		seen := map[float64]bool{}
+		for _, tier := range req.Tiers {
+			if tier.MinSpend <= 0 || seen[tier.MinSpend] {
+				return nil, fmt.Errorf("%w: tiers need distinct, positive minimum spends", ErrInvalidPromotion)
+			}
+			if (tier.Percent > 0) == (tier.Amount > 0) || tier.Percent < 0 || tier.Amount < 0 || tier.Percent > 100 {
+				return nil, fmt.Errorf("%w: each tier takes either a percentage or an amount off", ErrInvalidPromotion)
+			}
+			seen[tier.MinSpend] = true
+			promotion.Tiers = append(promotion.Tiers, models.PromotionTier{
+				MinSpend: tier.MinSpend,
+				Percent:  tier.Percent,
+				Amount:   tier.Amount,
+			})
+		}
+		sort.Slice(promotion.Tiers, func(i, j int) bool { return promotion.Tiers[i].MinSpend < promotion.Tiers[j].MinSpend })
+		promotion.MaxDiscount = req.MaxDiscount
+	case models.PromotionTypeFreeShipping:
+		promotion.MinSpend = req.MinSpend
+		promotion.MaxDiscount = req.MaxDiscount
+		for _, province := range req.Provinces {
+			if province = strings.TrimSpace(province); province != "" {
+				promotion.Provinces = append(promotion.Provinces, province)
+			}
+		}
+	case models.PromotionTypeFlashSale:
+		if len(req.VariantPrices) == 0 {
+			return nil, fmt.Errorf("%w: at least one variant sale price is required", ErrInvalidPromotion)
+		}
+		seen := map[int]bool{}
+		for _, price := range req.VariantPrices {
+			if seen[price.VariantID] {
+				return nil, fmt.Errorf("%w: variant %d is listed twice", ErrInvalidPromotion, price.VariantID)
+			}
+			seen[price.VariantID] = true
+			variant, err := s.variantRepo.GetByID(price.VariantID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: variant %d not found", ErrInvalidPromotion, price.VariantID)
+			}
+			if variant.Price != nil && price.SalePrice >= *variant.Price {
+				return nil, fmt.Errorf("%w: sale price of variant %d must be below its price", ErrInvalidPromotion, price.VariantID)
+			}
+			promotion.VariantPrices = append(promotion.VariantPrices, models.PromotionVariantPrice{
+				VariantID: price.VariantID,
+				SalePrice: price.SalePrice,
+			})
+		}
+		// Flash sales are per variant, so category and brand do not apply
+		promotion.CategoryID = nil
+		promotion.Brand = nil
+	}
+
+	if promotion.StartsAt != nil && promotion.EndsAt != nil && !promotion.StartsAt.Before(*promotion.EndsAt) {
+		return nil, fmt.Errorf("%w: the promotion must end after it starts", ErrInvalidPromotion)
+	}
+	if promotion.CategoryID != nil {
+		if _, err := s.categoryRepo.FindByID(*promotion.CategoryID); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+	}
+	return promotion, nil
+}
+
+func (s *promotionService) EvaluateCart(cart *models.Cart, shipping *models.PromotionShipping) (*models.PromotionResult, error) {
+	promotions, err := s.promotionRepo.ListRunning()
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return &models.PromotionResult{Applied: []models.AppliedPromotion{}, LineSavings: map[int]float64{}}, nil
+	}
+
+	for i := range promotions {
+		if promotions[i].CategoryID != nil {
+			ids, err := s.categoryRepo.FindDescendantIDs(*promotions[i].CategoryID, true)
+			if err != nil {
+				return nil, err
+			}
+			promotions[i].ScopeCategoryIDs = ids
+		}
+	}
+
+	lines := make([]models.PromotionLine, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		product, err := s.productRepo.FindByID(item.ProductID)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, models.PromotionLine{
+			Key:        item.ID,
+			ProductID:  item.ProductID,
+			VariantID:  item.VariantID,
+			CategoryID: product.CategoryID,
+			Brand:      product.Brand,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.PriceSnapshot,
+		})
+	}
+
+	result := models.EvaluatePromotions(promotions, lines, shipping, time.Now())
+	return &result, nil
+}
+
+// discountedItems returns the order items priced after their promotion savings, for vouchers
+// to apply on top. Items are in cart order, as checkout builds them.
+func discountedItems(items []models.OrderItem, cart *models.Cart, promotions *models.PromotionResult) []models.OrderItem {
+	discounted := make([]models.OrderItem, len(items))
+	for i, item := range items {
+		discounted[i] = item
+		if i < len(cart.Items) && item.Quantity > 0 {
+			if saving := promotions.LineSavings[cart.Items[i].ID]; saving > 0 {
+				discounted[i].Subtotal = item.Subtotal - saving
+				discounted[i].PricePerUnit = discounted[i].Subtotal / float64(item.Quantity)
+			}
+		}
+	}
+	return discounted
+}
+
+// setItemDiscounts records on each order item its promotion savings and its share of the
+// voucher, the discount PPN is worked out after. Items are in cart order, as for discountedItems.
+func setItemDiscounts(items []models.OrderItem, cart *models.Cart, promotions *models.PromotionResult, voucher models.VoucherDiscount) {
+	for i := range items {
+		items[i].DiscountAmount = 0
+		if i < len(cart.Items) {
+			items[i].DiscountAmount = promotions.LineSavings[cart.Items[i].ID]
+		}
+		if i < len(voucher.LineDiscounts) {
+			items[i].DiscountAmount += voucher.LineDiscounts[i]
+		}
+	}
+}
+
+// applyCartPromotions adds the promotions to a cart response, line by line
+func applyCartPromotions(promotions PromotionService, cart *models.Cart, response *dto.CartResponse) error {
+	result, err := promotions.EvaluateCart(cart, nil)
+	if err != nil {
+		return err
+	}
+
+	for i := range response.Items {
+		item := &response.Items[i]
+		item.Discount = result.LineSavings[item.ID]
+		item.DiscountedSubtotal = item.Subtotal - item.Discount
+	}
+	response.Promotions = result.Applied
+	response.Discount = result.ItemSavings
+	response.Total = response.Subtotal - result.ItemSavings
+	return nil
+}
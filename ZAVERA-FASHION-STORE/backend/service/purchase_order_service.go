@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrSupplierNotFound      = errors.New("supplier not found")
+	ErrDuplicateSupplier     = errors.New("supplier code already exists")
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	ErrInvalidPurchaseOrder  = errors.New("invalid purchase order")
+	// ErrPurchaseOrderStatus is returned for edits, sends, cancels and receipts the PO's status does not allow
+	ErrPurchaseOrderStatus = errors.New("purchase order status does not allow this")
+)
+
+// PurchaseOrderService manages suppliers and purchase orders. Stock only moves when a receipt
+// is booked; until then outstanding quantities show as incoming in the stock summary.
+type PurchaseOrderService interface {
+	ListSuppliers(activeOnly bool) ([]models.Supplier, error)
+	CreateSupplier(req dto.SupplierRequest) (*models.Supplier, error)
+	UpdateSupplier(id int, req dto.SupplierRequest) (*models.Supplier, error)
+
+	ListPurchaseOrders(filter models.PurchaseOrderFilter, limit, offset int) ([]models.PurchaseOrder, int, error)
+	GetPurchaseOrder(id int) (*models.PurchaseOrder, error)
+	CreatePurchaseOrder(req dto.PurchaseOrderRequest, adminEmail string) (*models.PurchaseOrder, error)
+	UpdatePurchaseOrder(id int, req dto.PurchaseOrderRequest) (*models.PurchaseOrder, error)
+	UpdateStatus(id int, status models.PurchaseOrderStatus, adminEmail string) (*models.PurchaseOrder, error)
+	Receive(id int, req dto.ReceivePurchaseOrderRequest, adminEmail string) (*models.PurchaseOrder, error)
+}
+
+type purchaseOrderService struct {
+	supplierRepo  repository.SupplierRepository
+	poRepo        repository.PurchaseOrderRepository
+	warehouseRepo repository.WarehouseRepository
+	variantRepo   *repository.VariantRepository
+}
+
+func NewPurchaseOrderService(
+	supplierRepo repository.SupplierRepository,
+	poRepo repository.PurchaseOrderRepository,
+	warehouseRepo repository.WarehouseRepository,
+	variantRepo *repository.VariantRepository,
+) PurchaseOrderService {
+	return &purchaseOrderService{
+		supplierRepo:  supplierRepo,
+		poRepo:        poRepo,
+		warehouseRepo: warehouseRepo,
+		variantRepo:   variantRepo,
+	}
+}
+
+func (s *purchaseOrderService) ListSuppliers(activeOnly bool) ([]models.Supplier, error) {
+	return s.supplierRepo.FindAll(activeOnly)
+}
+
+func (s *purchaseOrderService) CreateSupplier(req dto.SupplierRequest) (*models.Supplier, error) {
+	supplier := &models.Supplier{IsActive: true}
+	applySupplierRequest(supplier, req)
+
+	if err := s.supplierRepo.Create(supplier); err != nil {
+		if err == repository.ErrDuplicateSupplier {
+			return nil, ErrDuplicateSupplier
+		}
+		return nil, err
+	}
+
+	log.Printf("🏭 Supplier %s (%s) created", supplier.Code, supplier.Name)
+	return supplier, nil
+}
+
+func (s *purchaseOrderService) UpdateSupplier(id int, req dto.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := s.supplierRepo.FindByID(id)
+	if err == repository.ErrSupplierNotFound {
+		return nil, ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	applySupplierRequest(supplier, req)
+	if err := s.supplierRepo.Update(supplier); err != nil {
+		switch err {
+		case repository.ErrSupplierNotFound:
+			return nil, ErrSupplierNotFound
+		case repository.ErrDuplicateSupplier:
+			return nil, ErrDuplicateSupplier
+		}
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func (s *purchaseOrderService) ListPurchaseOrders(filter models.PurchaseOrderFilter, limit, offset int) ([]models.PurchaseOrder, int, error) {
+	return s.poRepo.List(filter, limit, offset)
+}
+
+func (s *purchaseOrderService) GetPurchaseOrder(id int) (*models.PurchaseOrder, error) {
+	po, err := s.poRepo.FindByID(id)
+	if err == repository.ErrPurchaseOrderNotFound {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	return po, err
+}
+
+func (s *purchaseOrderService) CreatePurchaseOrder(req dto.PurchaseOrderRequest, adminEmail string) (*models.PurchaseOrder, error) {
+	po := &models.PurchaseOrder{CreatedBy: adminEmail}
+	if err := s.applyPurchaseOrderRequest(po, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.poRepo.Create(po); err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	log.Printf("🧾 Purchase order %s drafted by %s (%d lines)", po.PONumber, adminEmail, len(po.Items))
+	return s.GetPurchaseOrder(po.ID)
+}
+
+func (s *purchaseOrderService) UpdatePurchaseOrder(id int, req dto.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	po, err := s.GetPurchaseOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if !po.Status.IsEditable() {
+		return nil, fmt.Errorf("%w: only drafts can be edited", ErrPurchaseOrderStatus)
+	}
+
+	if err := s.applyPurchaseOrderRequest(po, req); err != nil {
+		return nil, err
+	}
+	if err := s.poRepo.Update(po); err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+	return s.GetPurchaseOrder(id)
+}
+
+func (s *purchaseOrderService) UpdateStatus(id int, status models.PurchaseOrderStatus, adminEmail string) (*models.PurchaseOrder, error) {
+	po, err := s.GetPurchaseOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if !po.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: %s purchase orders cannot become %s", ErrPurchaseOrderStatus, po.Status, status)
+	}
+
+	if err := s.poRepo.UpdateStatus(id, po.Status, status); err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	log.Printf("🧾 Purchase order %s %s -> %s by %s (Rp %.0f)", po.PONumber, po.Status, status, adminEmail, po.TotalCost())
+	return s.GetPurchaseOrder(id)
+}
+
+func (s *purchaseOrderService) Receive(id int, req dto.ReceivePurchaseOrderRequest, adminEmail string) (*models.PurchaseOrder, error) {
+	po, err := s.GetPurchaseOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if !po.Status.IsIncoming() {
+		return nil, fmt.Errorf("%w: only sent purchase orders can be received", ErrPurchaseOrderStatus)
+	}
+
+	// One line per PO line, in the order given
+	totals := map[int]int{}
+	lines := []models.PurchaseOrderReceiptLine{}
+	for _, line := range req.Lines {
+		if _, seen := totals[line.PurchaseOrderItemID]; !seen {
+			lines = append(lines, models.PurchaseOrderReceiptLine{PurchaseOrderItemID: line.PurchaseOrderItemID})
+		}
+		totals[line.PurchaseOrderItemID] += line.Quantity
+	}
+	for i := range lines {
+		lines[i].Quantity = totals[lines[i].PurchaseOrderItemID]
+	}
+
+	receipt, err := s.poRepo.Receive(id, lines, adminEmail, strings.TrimSpace(req.Notes))
+	if err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	received := 0
+	for _, line := range receipt.Lines {
+		received += line.Quantity
+	}
+	log.Printf("📥 Received %d units against %s into %s (%s)", received, po.PONumber, po.WarehouseCode, adminEmail)
+	return s.GetPurchaseOrder(id)
+}
+
+// applyPurchaseOrderRequest validates the supplier, warehouse and lines and copies them onto po
+func (s *purchaseOrderService) applyPurchaseOrderRequest(po *models.PurchaseOrder, req dto.PurchaseOrderRequest) error {
+	supplier, err := s.supplierRepo.FindByID(req.SupplierID)
+	if err == repository.ErrSupplierNotFound {
+		return ErrSupplierNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !supplier.IsActive {
+		return fmt.Errorf("%w: supplier %s is inactive", ErrInvalidPurchaseOrder, supplier.Code)
+	}
+
+	var warehouse *models.Warehouse
+	if req.WarehouseID > 0 {
+		warehouse, err = s.warehouseRepo.FindByID(req.WarehouseID)
+	} else {
+		warehouse, err = s.warehouseRepo.FindDefault()
+	}
+	if err == repository.ErrWarehouseNotFound {
+		return ErrWarehouseNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !warehouse.IsActive {
+		return fmt.Errorf("%w: warehouse %s is inactive", ErrInvalidPurchaseOrder, warehouse.Code)
+	}
+
+	items := make([]models.PurchaseOrderItem, 0, len(req.Items))
+	seen := map[int]bool{}
+	for _, line := range req.Items {
+		if seen[line.VariantID] {
+			return fmt.Errorf("%w: variant %d is listed twice", ErrInvalidPurchaseOrder, line.VariantID)
+		}
+		seen[line.VariantID] = true
+
+		if _, err := s.variantRepo.GetByID(line.VariantID); err != nil {
+			return ErrVariantNotFound
+		}
+		items = append(items, models.PurchaseOrderItem{
+			VariantID:       line.VariantID,
+			QuantityOrdered: line.Quantity,
+			CostPerItem:     line.CostPerItem,
+			ExpectedAt:      line.ExpectedAt,
+		})
+	}
+
+	po.SupplierID = supplier.ID
+	po.WarehouseID = warehouse.ID
+	po.ExpectedAt = req.ExpectedAt
+	po.Notes = strings.TrimSpace(req.Notes)
+	po.Items = items
+	return nil
+}
+
+func (s *purchaseOrderService) mapRepositoryError(err error) error {
+	switch err {
+	case repository.ErrPurchaseOrderNotFound:
+		return ErrPurchaseOrderNotFound
+	case repository.ErrPurchaseOrderConflict:
+		return fmt.Errorf("%w: it was changed by someone else", ErrPurchaseOrderStatus)
+	case repository.ErrPurchaseOrderItemNotFound, repository.ErrDuplicatePurchaseOrderLine, repository.ErrOverReceipt:
+		return fmt.Errorf("%w: %v", ErrInvalidPurchaseOrder, err)
+	}
+	return err
+}
+
+func applySupplierRequest(supplier *models.Supplier, req dto.SupplierRequest) {
+	supplier.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	supplier.Name = strings.TrimSpace(req.Name)
+	supplier.ContactName = strings.TrimSpace(req.ContactName)
+	supplier.Email = strings.TrimSpace(req.Email)
+	supplier.Phone = strings.TrimSpace(req.Phone)
+	supplier.Address = strings.TrimSpace(req.Address)
+	supplier.LeadTimeDays = req.LeadTimeDays
+	supplier.Notes = strings.TrimSpace(req.Notes)
+	if req.IsActive != nil {
+		supplier.IsActive = *req.IsActive
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// RecommendationRefreshJob periodically rebuilds the "frequently bought together" matrix
+// from order_items co-occurrence. Interval via RECOMMENDATION_REFRESH_HOURS (default 6).
+type RecommendationRefreshJob struct {
+	recommendationService RecommendationService
+	interval              time.Duration
+	ticker                *time.Ticker
+	done                  chan bool
+}
+
+func NewRecommendationRefreshJob(recommendationService RecommendationService) *RecommendationRefreshJob {
+	return &RecommendationRefreshJob{
+		recommendationService: recommendationService,
+		interval:              time.Duration(envInt("RECOMMENDATION_REFRESH_HOURS", 6)) * time.Hour,
+		done:                  make(chan bool),
+	}
+}
+
+// Start begins the recommendation refresh scheduler
+func (j *RecommendationRefreshJob) Start() {
+	j.ticker = time.NewTicker(j.interval)
+
+	// Run immediately on start
+	go j.refresh()
+
+	go func() {
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-j.ticker.C:
+				j.refresh()
+			}
+		}
+	}()
+
+	log.Printf("⏰ Recommendation refresh job started (every %s)", j.interval)
+}
+
+// Stop stops the recommendation refresh scheduler
+func (j *RecommendationRefreshJob) Stop() {
+	if j.ticker != nil {
+		j.ticker.Stop()
+	}
+	j.done <- true
+	log.Println("⏰ Recommendation refresh job stopped")
+}
+
+func (j *RecommendationRefreshJob) refresh() {
+	started := time.Now()
+
+	pairs, err := j.recommendationService.RefreshCoPurchases()
+	if err != nil {
+		log.Printf("⚠️ Failed to refresh co-purchase recommendations: %v", err)
+		return
+	}
+
+	log.Printf("✅ Co-purchase recommendations refreshed: %d pairs in %s", pairs, time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+type RecommendationService interface {
+	GetRecommendations(productID, limit int) (*dto.ProductRecommendationsResponse, error)
+	GetLookLinks(productID int) (*dto.LookLinksResponse, error)
+	SetLookLinks(productID int, linkedIDs []int) (*dto.LookLinksResponse, error)
+	RefreshCoPurchases() (int, error)
+}
+
+type recommendationService struct {
+	recommendationRepo repository.RecommendationRepository
+	productRepo        repository.ProductRepository
+	catalog            *productService
+}
+
+func NewRecommendationService(recommendationRepo repository.RecommendationRepository, productRepo repository.ProductRepository) RecommendationService {
+	return &recommendationService{
+		recommendationRepo: recommendationRepo,
+		productRepo:        productRepo,
+		catalog:            &productService{productRepo: productRepo},
+	}
+}
+
+// GetRecommendations returns the cross-sell sections for a product page.
+// Curated links come first; later sections skip products already shown above them.
+func (s *recommendationService) GetRecommendations(productID, limit int) (*dto.ProductRecommendationsResponse, error) {
+	if limit <= 0 || limit > 20 {
+		limit = 8
+	}
+
+	product, err := s.findActiveProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	shown := map[int]bool{product.ID: true}
+
+	lookIDs, err := s.recommendationRepo.FindLookLinkIDs(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	lookIDs = takeUnseen(lookIDs, shown, limit)
+
+	// Over-fetch so de-duplication still fills the section
+	boughtIDs, err := s.recommendationRepo.FindFrequentlyBoughtTogetherIDs(product.ID, limit+len(shown))
+	if err != nil {
+		log.Printf("⚠️ Failed to load co-purchases for product %d: %v", product.ID, err)
+	}
+	boughtIDs = takeUnseen(boughtIDs, shown, limit)
+
+	similarIDs, err := s.recommendationRepo.FindSimilarProductIDs(product, limit+len(shown))
+	if err != nil {
+		log.Printf("⚠️ Failed to load similar items for product %d: %v", product.ID, err)
+	}
+	similarIDs = takeUnseen(similarIDs, shown, limit)
+
+	// One product query and one variant summary query for all sections
+	allIDs := append(append(append([]int{}, lookIDs...), boughtIDs...), similarIDs...)
+	products, err := s.productRepo.FindByIDs(allIDs)
+	if err != nil {
+		return nil, err
+	}
+	responses := s.catalog.toProductResponses(products)
+	byID := make(map[int]dto.ProductResponse, len(responses))
+	for _, r := range responses {
+		byID[r.ID] = r
+	}
+
+	return &dto.ProductRecommendationsResponse{
+		ProductID:                product.ID,
+		FrequentlyBoughtTogether: pickProductResponses(boughtIDs, byID),
+		SimilarItems:             pickProductResponses(similarIDs, byID),
+		CompleteTheLook:          pickProductResponses(lookIDs, byID),
+	}, nil
+}
+
+// GetLookLinks returns the curated "complete the look" products (active ones only)
+func (s *recommendationService) GetLookLinks(productID int) (*dto.LookLinksResponse, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	ids, err := s.recommendationRepo.FindLookLinkIDs(productID)
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.productRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.LookLinksResponse{
+		ProductID: productID,
+		Products:  s.catalog.toProductResponses(products),
+	}
+	if response.Products == nil {
+		response.Products = []dto.ProductResponse{}
+	}
+	return response, nil
+}
+
+// SetLookLinks replaces the curated links; unknown IDs and the product itself are ignored
+func (s *recommendationService) SetLookLinks(productID int, linkedIDs []int) (*dto.LookLinksResponse, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.recommendationRepo.SetLookLinks(productID, linkedIDs); err != nil {
+		return nil, err
+	}
+
+	return s.GetLookLinks(productID)
+}
+
+// RefreshCoPurchases rebuilds the "frequently bought together" matrix.
+// Tunable via RECOMMENDATION_WINDOW_DAYS (180), RECOMMENDATION_MIN_ORDERS (2)
+// and RECOMMENDATION_PER_PRODUCT (20).
+func (s *recommendationService) RefreshCoPurchases() (int, error) {
+	windowDays := envInt("RECOMMENDATION_WINDOW_DAYS", 180)
+	minOrders := envInt("RECOMMENDATION_MIN_ORDERS", 2)
+	perProduct := envInt("RECOMMENDATION_PER_PRODUCT", 20)
+
+	return s.recommendationRepo.RefreshCoPurchases(windowDays, minOrders, perProduct)
+}
+
+func (s *recommendationService) findActiveProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err == sql.ErrNoRows || (err == nil && !product.IsActive) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
+}
+
+// takeUnseen keeps up to limit IDs not yet in shown, marking them as shown
+func takeUnseen(ids []int, shown map[int]bool, limit int) []int {
+	result := []int{}
+	for _, id := range ids {
+		if len(result) >= limit {
+			break
+		}
+		if shown[id] {
+			continue
+		}
+		shown[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+func pickProductResponses(ids []int, byID map[int]dto.ProductResponse) []dto.ProductResponse {
+	result := []dto.ProductResponse{}
+	for _, id := range ids {
+		if r, ok := byID[id]; ok {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// envInt reads a positive integer setting, falling back to defaultValue
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnvOrDefault(key, ""))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
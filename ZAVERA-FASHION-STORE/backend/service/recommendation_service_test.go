@@ -0,0 +1,43 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"zavera/dto"
+)
+
+func TestTakeUnseen_DedupesAcrossSections(t *testing.T) {
+	shown := map[int]bool{1: true}
+
+	look := takeUnseen([]int{5, 1, 6}, shown, 8)
+	if !reflect.DeepEqual(look, []int{5, 6}) {
+		t.Fatalf("expected current product to be skipped, got %v", look)
+	}
+
+	bought := takeUnseen([]int{6, 7, 8, 9}, shown, 2)
+	if !reflect.DeepEqual(bought, []int{7, 8}) {
+		t.Errorf("expected products shown earlier to be skipped and limit applied, got %v", bought)
+	}
+
+	similar := takeUnseen([]int{9, 8, 10}, shown, 8)
+	if !reflect.DeepEqual(similar, []int{9, 10}) {
+		t.Errorf("expected only unseen similar items, got %v", similar)
+	}
+}
+
+func TestPickProductResponses_KeepsRankOrder(t *testing.T) {
+	byID := map[int]dto.ProductResponse{
+		3: {ID: 3},
+		7: {ID: 7},
+	}
+
+	// 5 was filtered out (inactive) when loading products
+	picked := pickProductResponses([]int{7, 5, 3}, byID)
+	if len(picked) != 2 || picked[0].ID != 7 || picked[1].ID != 3 {
+		t.Errorf("expected [7 3], got %+v", picked)
+	}
+
+	if empty := pickProductResponses(nil, byID); empty == nil || len(empty) != 0 {
+		t.Errorf("expected empty non-nil slice for JSON [], got %#v", empty)
+	}
+}
@@ -416,9 +416,9 @@ func (s *reconciliationService) findStuckPayments(start, end time.Time) (int, []
 }
 
 func (s *reconciliationService) calculateRevenue(start, end time.Time) (expected, actual, variance float64) {
-	// Expected: sum of all paid orders
+	// Expected: sum of all paid orders, less what they paid with store credit
 	s.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0) FROM orders
+		SELECT COALESCE(SUM(total_amount - COALESCE(store_credit_amount, 0)), 0) FROM orders
 		WHERE status IN ('PAID', 'PROCESSING', 'SHIPPED', 'DELIVERED', 'COMPLETED')
 		AND created_at >= $1 AND created_at < $2
 	`, start, end).Scan(&expected)
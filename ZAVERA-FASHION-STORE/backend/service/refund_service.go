@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"strings"
@@ -26,8 +27,13 @@ var (
 	ErrRefundAlreadyFinal   = errors.New("refund is already in final state")
 	ErrPaymentNotSettled    = errors.New("payment not settled, cannot refund")
 	ErrIdempotencyConflict  = errors.New("idempotency key already used")
+	ErrStoreCreditRefundGuest = errors.New("guest orders cannot be refunded to store credit")
+	ErrRefundGiftCardRedeemed = errors.New("a refunded gift card has already been redeemed")
 )
 
+// storeCreditRefundID is the gateway refund ID of refunds paid entirely in store credit
+const storeCreditRefundID = "STORE_CREDIT"
+
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
@@ -65,12 +71,13 @@ type RefundService interface {
 }
 
 type refundService struct {
-	refundRepo  repository.RefundRepository
-	orderRepo   repository.OrderRepository
-	paymentRepo repository.PaymentRepository
-	auditRepo   repository.AdminAuditRepository
-	serverKey   string
-	baseURL     string
+	refundRepo      repository.RefundRepository
+	orderRepo       repository.OrderRepository
+	paymentRepo     repository.PaymentRepository
+	auditRepo       repository.AdminAuditRepository
+	storeCreditRepo repository.StoreCreditRepository
+	serverKey       string
+	baseURL         string
 }
 
 func NewRefundService(
@@ -78,6 +85,7 @@ func NewRefundService(
 	orderRepo repository.OrderRepository,
 	paymentRepo repository.PaymentRepository,
 	auditRepo repository.AdminAuditRepository,
+	storeCreditRepo repository.StoreCreditRepository,
 ) RefundService {
 	baseURL := "https://api.sandbox.midtrans.com"
 	if os.Getenv("MIDTRANS_ENVIRONMENT") == "production" {
@@ -85,12 +93,13 @@ func NewRefundService(
 	}
 	
 	return &refundService{
-		refundRepo:  refundRepo,
-		orderRepo:   orderRepo,
-		paymentRepo: paymentRepo,
-		auditRepo:   auditRepo,
-		serverKey:   os.Getenv("MIDTRANS_SERVER_KEY"),
-		baseURL:     baseURL,
+		refundRepo:      refundRepo,
+		orderRepo:       orderRepo,
+		paymentRepo:     paymentRepo,
+		auditRepo:       auditRepo,
+		storeCreditRepo: storeCreditRepo,
+		serverKey:       os.Getenv("MIDTRANS_SERVER_KEY"),
+		baseURL:         baseURL,
 	}
 }
 
@@ -149,7 +158,7 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 		err = s.paymentRepo.GetDB().QueryRow(query, order.ID).Scan(&corePaymentID, &corePaymentStatus, &corePaymentMethod)
 		if err == nil && corePaymentStatus == "PAID" {
 			// Found Core API payment with PAID status - use this instead of Snap payment
-			corePaymentAmount = order.TotalAmount
+			corePaymentAmount = order.AmountDue()
 			
 			payment = &models.Payment{
 				ID:            0, // Signal to use NULL for payment_id in refunds table
@@ -202,7 +211,7 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 		
 		if err == nil {
 			// Found Core API payment - convert to payment model for compatibility
-			corePaymentAmount = order.TotalAmount // Use order total as payment amount
+			corePaymentAmount = order.AmountDue() // What the order paid through Midtrans
 			
 			// Map Core API status to payment status
 			var mappedStatus models.PaymentStatus
@@ -246,11 +255,19 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 		return nil, err
 	}
 
+	destination := models.RefundDestinationOriginal
+	if req.Destination != "" {
+		destination = models.RefundDestination(req.Destination)
+	}
+	if destination == models.RefundDestinationStoreCredit && order.UserID == nil {
+		return nil, ErrStoreCreditRefundGuest
+	}
+
 	// If no payment exists, this is a manually marked order
 	// Create refund record but skip gateway processing
 	if payment == nil {
 		log.Printf("⚠️ No payment record for order %s - creating manual refund", req.OrderCode)
-		return s.createManualRefund(req, requestedBy, order)
+		return s.createManualRefund(req, requestedBy, order, destination)
 	}
 
 	// Calculate refund amount
@@ -274,18 +291,19 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 	}
 
 	// Check existing refunds WITH LOCK HELD - this is now safe from race conditions
-	var totalRefunded float64
+	var totalRefunded, creditReturned float64
 	err = tx.QueryRow(`
-		SELECT COALESCE(SUM(refund_amount), 0) 
+		SELECT COALESCE(SUM(refund_amount) FILTER (WHERE status IN ('COMPLETED', 'PROCESSING')), 0),
+		       COALESCE(SUM(store_credit_amount) FILTER (WHERE status IN ('PENDING', 'COMPLETED', 'PROCESSING')), 0)
 		FROM refunds 
 		WHERE order_id = $1 
-		AND status IN ('COMPLETED', 'PROCESSING')
-	`, order.ID).Scan(&totalRefunded)
+	`, order.ID).Scan(&totalRefunded, &creditReturned)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing refunds: %w", err)
 	}
 
-	refundableAmount := payment.Amount - totalRefunded
+	// The payment covers what Midtrans charged; the order paid the rest with store credit
+	refundableAmount := payment.Amount + order.StoreCreditAmount - totalRefunded
 	if refundAmount > refundableAmount {
 		return nil, fmt.Errorf("%w: requested %.2f, available %.2f", ErrRefundAmountExceeds, refundAmount, refundableAmount)
 	}
@@ -310,11 +328,24 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 		RefundAmount:   refundAmount,
 		ShippingRefund: shippingRefund,
 		ItemsRefund:    itemsRefund,
+		TaxRefund:      s.calculateTaxRefund(order, req, refundAmount),
+		Destination:    destination,
 		Status:         models.RefundStatusPending,
 		IdempotencyKey: stringPtrIfNotEmpty(req.IdempotencyKey),
 		RequestedBy:    requestedBy,
 	}
 
+	// Store credit refunds complete on the spot; otherwise what the order paid with store
+	// credit goes back there when the refund is processed
+	if destination == models.RefundDestinationStoreCredit {
+		refund.StoreCreditAmount = refundAmount
+		refund.Status = models.RefundStatusCompleted
+		refund.ProcessedBy = requestedBy
+		refund.GatewayRefundID = stringPtr(storeCreditRefundID)
+	} else {
+		refund.StoreCreditAmount = models.StoreCreditRefundShare(refundAmount, order.StoreCreditAmount, creditReturned)
+	}
+
 	// Create refund within transaction
 	if err := s.refundRepo.CreateWithTx(tx, refund); err != nil {
 		return nil, fmt.Errorf("failed to create refund: %w", err)
@@ -328,24 +359,31 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 				continue
 			}
 
-			refundItem := &models.RefundItem{
-				RefundID:     refund.ID,
-				OrderItemID:  item.OrderItemID,
-				ProductID:    orderItem.ProductID,
-				ProductName:  orderItem.ProductName,
-				Quantity:     item.Quantity,
-				PricePerUnit: orderItem.PricePerUnit,
-				RefundAmount: float64(item.Quantity) * orderItem.PricePerUnit,
-				ItemReason:   item.Reason,
-			}
-			if err := s.refundRepo.CreateRefundItemWithTx(tx, refundItem); err != nil {
-				return nil, fmt.Errorf("failed to create refund item: %w", err)
+			for _, refundItem := range refundItemsFor(orderItem, item, order.PricesIncludeTax) {
+				refundItem.RefundID = refund.ID
+				if err := s.refundRepo.CreateRefundItemWithTx(tx, &refundItem); err != nil {
+					return nil, fmt.Errorf("failed to create refund item: %w", err)
+				}
 			}
 		}
 	}
 
+	if err := s.voidRefundedGiftCards(tx, req, order); err != nil {
+		return nil, err
+	}
+
+	if refund.Status == models.RefundStatusCompleted {
+		if err := s.storeCreditRepo.AddEntryTx(tx, storeCreditRefundEntry(refund, *order.UserID)); err != nil {
+			return nil, fmt.Errorf("failed to credit store credit: %w", err)
+		}
+	}
+
 	// Record status change within transaction
-	if err := s.refundRepo.RecordStatusChangeWithTx(tx, refund.ID, "", models.RefundStatusPending, "system", "Refund created"); err != nil {
+	statusNote := "Refund created"
+	if refund.Status == models.RefundStatusCompleted {
+		statusNote = "Refund created and credited to store credit"
+	}
+	if err := s.refundRepo.RecordStatusChangeWithTx(tx, refund.ID, "", refund.Status, "system", statusNote); err != nil {
 		log.Printf("⚠️ Failed to record status change: %v", err)
 		// Don't fail the whole refund creation if status history fails
 	}
@@ -355,6 +393,11 @@ func (s *refundService) CreateRefund(req *dto.RefundRequest, requestedBy *int) (
 	}
 
 	log.Printf("✅ Refund created: %s for order %s, amount: %.2f", refund.RefundCode, order.OrderCode, refundAmount)
+
+	if refund.Status == models.RefundStatusCompleted {
+		s.updateOrderRefundStatus(order.ID)
+		s.restoreRefundedStock(refund)
+	}
 	return refund, nil
 }
 
@@ -372,48 +415,74 @@ func (s *refundService) ProcessRefund(refundID int, processedBy int) error {
 		return fmt.Errorf("refund cannot be processed in status: %s", refund.Status)
 	}
 
-	// Update status to processing
-	s.refundRepo.UpdateStatus(refundID, models.RefundStatusProcessing, nil)
+	// Update status to processing, keeping what an earlier attempt got from Midtrans
+	s.refundRepo.UpdateStatus(refundID, models.RefundStatusProcessing, refund.GatewayResponse)
 	s.refundRepo.RecordStatusChange(refundID, refund.Status, models.RefundStatusProcessing, fmt.Sprintf("user:%d", processedBy), "Processing started")
 
-	// Process with Midtrans
-	resp, err := s.ProcessMidtransRefund(refund)
-	if err != nil {
-		log.Printf("❌ Midtrans refund failed: %v", err)
+	// Midtrans refunds what it charged; a refund entirely in store credit skips it, and so
+	// does a retry after Midtrans refunded but the store credit failed
+	gatewayRefundID := storeCreditRefundID
+	gatewayResponse := map[string]any{"store_credit_amount": refund.StoreCreditAmount}
+	if refunded, ok := midtransRefundedID(refund); ok {
+		gatewayRefundID = refunded
+		gatewayResponse = refund.GatewayResponse
+	} else if refund.GatewayAmount() > 0 {
+		resp, err := s.ProcessMidtransRefund(refund)
+		if err != nil {
+			log.Printf("❌ Midtrans refund failed: %v", err)
 		
-		// Check if error is 418 (settlement time issue)
-		// For this case, we keep status as PENDING with note for manual processing
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "payment provider requires additional settlement time") || 
-		   strings.Contains(errorMsg, "Payment Provider doesn't allow refund within this time") {
-			log.Printf("⚠️ Error 418 detected - keeping as PENDING for manual processing")
+			// Check if error is 418 (settlement time issue)
+			// For this case, we keep status as PENDING with note for manual processing
+			errorMsg := err.Error()
+			if strings.Contains(errorMsg, "payment provider requires additional settlement time") || 
+			   strings.Contains(errorMsg, "Payment Provider doesn't allow refund within this time") {
+				log.Printf("⚠️ Error 418 detected - keeping as PENDING for manual processing")
 			
-			// Keep as PENDING but add note for manual processing
-			approvalNote := "⚠️ REQUIRES MANUAL PROCESSING: Automatic refund failed due to payment provider settlement time. Admin should process manual bank transfer and then mark as completed."
-			s.refundRepo.UpdateStatus(refundID, models.RefundStatusPending, nil)
-			s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusPending, 
-				fmt.Sprintf("user:%d", processedBy), approvalNote)
+				// Keep as PENDING but add note for manual processing
+				approvalNote := "⚠️ REQUIRES MANUAL PROCESSING: Automatic refund failed due to payment provider settlement time. Admin should process manual bank transfer and then mark as completed."
+				s.refundRepo.UpdateStatus(refundID, models.RefundStatusPending, nil)
+				s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusPending, 
+					fmt.Sprintf("user:%d", processedBy), approvalNote)
 			
-			// Return specific error for frontend to show manual processing option
-			return fmt.Errorf("MANUAL_PROCESSING_REQUIRED: Automatic refund failed. Please process manual bank transfer to customer and mark refund as completed after transfer is done")
-		}
+				// Return specific error for frontend to show manual processing option
+				return fmt.Errorf("MANUAL_PROCESSING_REQUIRED: Automatic refund failed. Please process manual bank transfer to customer and mark refund as completed after transfer is done")
+			}
 		
-		// For other errors, mark as failed
-		s.refundRepo.MarkFailed(refundID, err.Error(), nil)
-		s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusFailed, "system", err.Error())
-		return err
+			// For other errors, mark as failed
+			s.refundRepo.MarkFailed(refundID, err.Error(), nil)
+			s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusFailed, "system", err.Error())
+			return err
+		}
+
+		gatewayRefundID = fmt.Sprintf("%d", resp.RefundChargebackID)
+		gatewayResponse[midtransRefundedKey] = gatewayRefundID
+		gatewayResponse["status_code"] = resp.StatusCode
+		gatewayResponse["status_message"] = resp.StatusMessage
+		gatewayResponse["refund_key"] = resp.RefundKey
+		gatewayResponse["refund_amount"] = resp.RefundAmount
+	}
+
+	// Not completed until the store credit part is credited too
+	if err := s.refundToStoreCredit(refund); err != nil {
+		log.Printf("❌ Failed to credit %.2f store credit for refund %s: %v", refund.StoreCreditAmount, refund.RefundCode, err)
+		if gatewayRefundID == storeCreditRefundID {
+			s.refundRepo.MarkFailed(refundID, err.Error(), nil)
+			s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusFailed, "system", err.Error())
+			return err
+		}
+
+		// Midtrans has already paid its part: keep as PENDING so processing again only retries the credit
+		gatewayResponse["store_credit_error"] = err.Error()
+		note := fmt.Sprintf("⚠️ STORE CREDIT NOT CREDITED: Midtrans refunded %.2f (ID %s) but crediting %.2f store credit failed: %v. Process the refund again to retry the store credit.",
+			refund.GatewayAmount(), gatewayRefundID, refund.StoreCreditAmount, err)
+		s.refundRepo.UpdateStatus(refundID, models.RefundStatusPending, gatewayResponse)
+		s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusPending, "system", note)
+		return fmt.Errorf("failed to credit store credit: %w", err)
 	}
 
 	// Mark as completed
-	gatewayResponse := map[string]any{
-		"status_code":    resp.StatusCode,
-		"status_message": resp.StatusMessage,
-		"refund_key":     resp.RefundKey,
-		"refund_amount":  resp.RefundAmount,
-	}
-	
-	s.refundRepo.MarkCompleted(refundID, fmt.Sprintf("%d", resp.RefundChargebackID), gatewayResponse)
-	s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusCompleted, "system", "Refund completed via Midtrans")
+	s.refundRepo.MarkCompleted(refundID, gatewayRefundID, gatewayResponse)
+	s.refundRepo.RecordStatusChange(refundID, models.RefundStatusProcessing, models.RefundStatusCompleted, "system", "Refund completed")
 
 	// Update order refund status
 	s.updateOrderRefundStatus(refund.OrderID)
@@ -421,7 +490,7 @@ func (s *refundService) ProcessRefund(refundID int, processedBy int) error {
 	// Restore stock for refunded items
 	s.restoreRefundedStock(refund)
 
-	log.Printf("✅ Refund completed: %s, gateway ID: %d", refund.RefundCode, resp.RefundChargebackID)
+	log.Printf("✅ Refund completed: %s, gateway ID: %s", refund.RefundCode, gatewayRefundID)
 	return nil
 }
 
@@ -445,7 +514,17 @@ func (s *refundService) MarkRefundCompletedManually(refundID int, processedBy in
 		"completed_at":      time.Now(),
 	}
 	
-	s.refundRepo.MarkCompleted(refundID, "MANUAL_BANK_TRANSFER", gatewayResponse)
+	// The bank transfer covers the Midtrans part; the store credit part is credited as usual
+	if err := s.refundToStoreCredit(refund); err != nil {
+		return fmt.Errorf("failed to credit store credit: %w", err)
+	}
+
+	gatewayRefundID := "MANUAL_BANK_TRANSFER"
+	if refunded, ok := midtransRefundedID(refund); ok {
+		// Midtrans refunded; only the store credit was outstanding
+		gatewayRefundID = refunded
+	}
+	s.refundRepo.MarkCompleted(refundID, gatewayRefundID, gatewayResponse)
 	s.refundRepo.RecordStatusChange(refundID, models.RefundStatusPending, models.RefundStatusCompleted, 
 		fmt.Sprintf("user:%d", processedBy), fmt.Sprintf("Manual refund completed: %s", note))
 
@@ -589,7 +668,7 @@ func (s *refundService) ProcessMidtransRefund(refund *models.Refund) (*dto.Midtr
 	if skipMidtransRefund {
 		log.Printf("⚠️ SKIP_MIDTRANS_REFUND=true - Bypassing Midtrans refund API for testing")
 		log.Printf("   Refund Code: %s", refund.RefundCode)
-		log.Printf("   Amount: %.2f", refund.RefundAmount)
+		log.Printf("   Amount: %.2f", refund.GatewayAmount())
 		log.Printf("   ⚠️ This should ONLY be used in development/testing!")
 		
 		// Return mock successful response
@@ -597,7 +676,7 @@ func (s *refundService) ProcessMidtransRefund(refund *models.Refund) (*dto.Midtr
 			StatusCode:          "200",
 			StatusMessage:       "Success (Development Mode - Midtrans API Bypassed)",
 			RefundChargebackID:  999999, // Mock ID
-			RefundAmount:        fmt.Sprintf("%.2f", refund.RefundAmount),
+			RefundAmount:        fmt.Sprintf("%.2f", refund.GatewayAmount()),
 			RefundKey:           refund.RefundCode,
 		}, nil
 	}
@@ -646,11 +725,11 @@ func (s *refundService) ProcessMidtransRefund(refund *models.Refund) (*dto.Midtr
 	log.Printf("   Order: %s", order.OrderCode)
 	log.Printf("   Midtrans Order ID: %s", orderIDForMidtrans)
 	log.Printf("   Refund Code: %s", refund.RefundCode)
-	log.Printf("   Amount: %.2f", refund.RefundAmount)
+	log.Printf("   Amount: %.2f", refund.GatewayAmount())
 
 	reqBody := dto.MidtransRefundRequest{
 		RefundKey: refund.RefundCode,
-		Amount:    refund.RefundAmount,
+		Amount:    refund.GatewayAmount(),
 		Reason:    string(refund.Reason),
 	}
 
@@ -815,6 +894,19 @@ func (s *refundService) validateRefundRequest(req *dto.RefundRequest, order *mod
 				return fmt.Errorf("item %d: order item ID %d not found in order %s", i, item.OrderItemID, order.OrderCode)
 			}
 			
+			// Single bundle components are counted in units of the component
+			if item.ComponentID != nil {
+				component := findOrderItemComponent(orderItem, *item.ComponentID)
+				if component == nil {
+					return fmt.Errorf("item %d: component %d is not part of order item %d", i, *item.ComponentID, item.OrderItemID)
+				}
+				if ordered := component.Quantity * orderItem.Quantity; item.Quantity > ordered {
+					return fmt.Errorf("item %d: refund quantity %d exceeds ordered quantity %d for %s in %s",
+						i, item.Quantity, ordered, component.ProductName, orderItem.ProductName)
+				}
+				continue
+			}
+
 			// Requirement 15.6: Verify refund quantities do not exceed ordered quantities
 			if item.Quantity > orderItem.Quantity {
 				return fmt.Errorf("item %d: refund quantity %d exceeds ordered quantity %d for product %s", 
@@ -845,7 +937,7 @@ func (s *refundService) calculateRefundAmount(order *models.Order, payment *mode
 	case "FULL":
 		// Requirement 8.1: FULL refund = entire order total amount
 		// Requirement 8.7: Show items_refund and shipping_refund separately
-		return payment.Amount, order.ShippingCost, order.Subtotal, nil
+		return payment.Amount + order.StoreCreditAmount, order.ShippingCost, order.Subtotal, nil
 		
 	case "SHIPPING_ONLY":
 		// Requirement 8.2: SHIPPING_ONLY refund = only shipping cost
@@ -861,11 +953,14 @@ func (s *refundService) calculateRefundAmount(order *models.Order, payment *mode
 		
 	case "ITEM_ONLY":
 		// Requirement 8.4: ITEM_ONLY refund = sum of (quantity × price_per_unit) for selected items
+		// (bundle components at their share of the bundle price)
 		itemsTotal := 0.0
 		for _, item := range req.Items {
 			orderItem := s.findOrderItem(order.Items, item.OrderItemID)
 			if orderItem != nil {
-				itemsTotal += float64(item.Quantity) * orderItem.PricePerUnit
+				for _, refundItem := range refundItemsFor(orderItem, item, order.PricesIncludeTax) {
+					itemsTotal += refundItem.RefundAmount
+				}
 			}
 		}
 		if itemsTotal <= 0 {
@@ -877,6 +972,61 @@ func (s *refundService) calculateRefundAmount(order *models.Order, payment *mode
 	return 0, 0, 0, fmt.Errorf("invalid refund type: %s", req.RefundType)
 }
 
+// calculateTaxRefund works out the PPN contained in a refund: all of it for a full refund, none
+// for shipping, the refunded units' tax for items and a pro-rated share of a partial amount
+func (s *refundService) calculateTaxRefund(order *models.Order, req *dto.RefundRequest, refundAmount float64) float64 {
+	switch req.RefundType {
+	case "FULL":
+		return order.Tax
+	case "PARTIAL":
+		if order.TotalAmount <= 0 {
+			return 0
+		}
+		return math.Round(order.Tax * math.Min(refundAmount/order.TotalAmount, 1))
+	case "ITEM_ONLY":
+		tax := 0.0
+		for _, item := range req.Items {
+			orderItem := s.findOrderItem(order.Items, item.OrderItemID)
+			if orderItem != nil {
+				for _, refundItem := range refundItemsFor(orderItem, item, order.PricesIncludeTax) {
+					tax += refundItem.TaxAmount
+				}
+			}
+		}
+		return tax
+	}
+	return 0
+}
+
+// voidRefundedGiftCards voids the gift card codes of the units a refund covers, in the refund's
+// transaction, so a refunded gift card cannot be redeemed as well
+func (s *refundService) voidRefundedGiftCards(tx *sql.Tx, req *dto.RefundRequest, order *models.Order) error {
+	for _, orderItem := range order.Items {
+		units := 0
+		if req.RefundType == "FULL" {
+			units = orderItem.Quantity
+		} else {
+			for _, item := range req.Items {
+				if item.OrderItemID == orderItem.ID {
+					units += item.Quantity
+				}
+			}
+		}
+		if units == 0 {
+			continue
+		}
+
+		err := s.storeCreditRepo.VoidRefundedGiftCardsTx(tx, orderItem.ID, units)
+		if err == repository.ErrGiftCardRedeemed {
+			return ErrRefundGiftCardRedeemed
+		}
+		if err != nil {
+			return fmt.Errorf("failed to void refunded gift cards: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *refundService) findOrderItem(items []models.OrderItem, itemID int) *models.OrderItem {
 	for _, item := range items {
 		if item.ID == itemID {
@@ -886,6 +1036,65 @@ func (s *refundService) findOrderItem(items []models.OrderItem, itemID int) *mod
 	return nil
 }
 
+func findOrderItemComponent(item *models.OrderItem, componentID int) *models.OrderItemComponent {
+	for i := range item.Components {
+		if item.Components[i].ID == componentID {
+			return &item.Components[i]
+		}
+	}
+	return nil
+}
+
+// refundItemsFor builds the refund items of a requested line. Bundle lines are refunded per
+// component at its share of the bundle price, so each component can be restocked on its own:
+// the whole bundle when no component is named, otherwise just that component. Units are
+// refunded at what was paid for them: their share of the line's discount and PPN.
+func refundItemsFor(orderItem *models.OrderItem, req dto.RefundItemRequest, pricesIncludeTax bool) []models.RefundItem {
+	if len(orderItem.Components) == 0 {
+		paid, tax := orderItem.PaidShare(float64(req.Quantity)*orderItem.PricePerUnit, pricesIncludeTax)
+		return []models.RefundItem{{
+			OrderItemID:  orderItem.ID,
+			ProductID:    orderItem.ProductID,
+			ProductName:  orderItem.ProductName,
+			Quantity:     req.Quantity,
+			PricePerUnit: orderItem.PricePerUnit,
+			RefundAmount: paid,
+			TaxAmount:    tax,
+			ItemReason:   req.Reason,
+		}}
+	}
+
+	var items []models.RefundItem
+	for i := range orderItem.Components {
+		component := orderItem.Components[i]
+		units := req.Quantity * component.Quantity
+		if req.ComponentID != nil {
+			if *req.ComponentID != component.ID {
+				continue
+			}
+			units = req.Quantity
+		}
+
+		name := orderItem.ProductName + " - " + component.ProductName
+		if component.VariantName != "" {
+			name += " (" + component.VariantName + ")"
+		}
+		paid, tax := orderItem.PaidShare(component.RefundAmount(units), pricesIncludeTax)
+		items = append(items, models.RefundItem{
+			OrderItemID:          orderItem.ID,
+			OrderItemComponentID: &component.ID,
+			ProductID:            component.ProductID,
+			ProductName:          name,
+			Quantity:             units,
+			PricePerUnit:         component.RefundAmount(1),
+			RefundAmount:         paid,
+			TaxAmount:            tax,
+			ItemReason:           req.Reason,
+		})
+	}
+	return items
+}
+
 func (s *refundService) updateOrderRefundStatus(orderID int) {
 	refunds, err := s.refundRepo.FindByOrderID(orderID)
 	if err != nil {
@@ -952,35 +1161,38 @@ func (s *refundService) updateOrderRefundStatusWithAmount(orderID int, orderTota
 func (s *refundService) restoreRefundedStock(refund *models.Refund) {
 	if refund.RefundType == models.RefundTypeFull {
 		// Restore all stock via order
-		s.orderRepo.RestoreStock(refund.OrderID)
+		s.orderRepo.RestockRefundedOrder(refund.OrderID)
 		return
 	}
 
 	// Restore specific items
+	stockRepo := repository.NewStockRepository(s.refundRepo.GetDB())
 	items, _ := s.refundRepo.FindItemsByRefundID(refund.ID)
 	for _, item := range items {
 		if item.StockRestored {
 			continue
 		}
 		
-		query := `UPDATE products SET stock = stock + $1 WHERE id = $2`
-		_, err := s.refundRepo.GetDB().Exec(query, item.Quantity, item.ProductID)
-		if err == nil {
-			s.refundRepo.MarkItemStockRestored(item.ID)
+		if err := stockRepo.RestockRefundItem(item.ID, "system"); err != nil && err != repository.ErrStockAlreadyRestored {
+			log.Printf("⚠️ Failed to restock refund item %d: %v", item.ID, err)
 		}
 	}
 }
 
 // createManualRefund creates a refund for orders without payment records (manually marked as paid)
 // Validates: Requirements 2.7, 13.2, 13.3, 13.4, 13.5
-func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *int, order *models.Order) (*models.Refund, error) {
+func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *int, order *models.Order, destination models.RefundDestination) (*models.Refund, error) {
 	// Check existing refunds to prevent over-refunding
 	existingRefunds, _ := s.refundRepo.FindByOrderID(order.ID)
 	totalRefunded := 0.0
+	creditReturned := 0.0
 	for _, r := range existingRefunds {
 		if r.Status == models.RefundStatusCompleted || r.Status == models.RefundStatusProcessing {
 			totalRefunded += r.RefundAmount
 		}
+		if r.Status == models.RefundStatusCompleted || r.Status == models.RefundStatusProcessing || r.Status == models.RefundStatusPending {
+			creditReturned += r.StoreCreditAmount
+		}
 	}
 	
 	// Calculate refund amount based on order totals (Requirement 13.5)
@@ -1012,7 +1224,9 @@ func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *
 		for _, item := range req.Items {
 			orderItem := s.findOrderItem(order.Items, item.OrderItemID)
 			if orderItem != nil {
-				itemsTotal += float64(item.Quantity) * orderItem.PricePerUnit
+				for _, refundItem := range refundItemsFor(orderItem, item, order.PricesIncludeTax) {
+					itemsTotal += refundItem.RefundAmount
+				}
 			}
 		}
 		if itemsTotal <= 0 {
@@ -1051,8 +1265,10 @@ func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *
 		RefundAmount:    refundAmount,
 		ShippingRefund:  shippingRefund,
 		ItemsRefund:     itemsRefund,
+		TaxRefund:       s.calculateTaxRefund(order, req, refundAmount),
 		Reason:          models.RefundReason(req.Reason),
 		ReasonDetail:    req.ReasonDetail,
+		Destination:     destination,
 		Status:          models.RefundStatusCompleted, // Requirement 13.3: Auto-complete
 		RequestedBy:     requestedBy,
 		IdempotencyKey:  stringPtrIfNotEmpty(req.IdempotencyKey),
@@ -1060,10 +1276,27 @@ func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *
 		GatewayRefundID: stringPtr("MANUAL_REFUND"), // Requirement 13.4
 	}
 
+	// Orders paid entirely with store credit have no payment record either; it goes back there
+	if destination == models.RefundDestinationStoreCredit {
+		refund.StoreCreditAmount = refundAmount
+		refund.GatewayRefundID = stringPtr(storeCreditRefundID)
+	} else {
+		refund.StoreCreditAmount = models.StoreCreditRefundShare(refundAmount, order.StoreCreditAmount, creditReturned)
+	}
+
 	if err := s.refundRepo.CreateWithTx(tx, refund); err != nil {
 		return nil, fmt.Errorf("failed to create manual refund: %w", err)
 	}
 
+	if refund.StoreCreditAmount > 0 {
+		if order.UserID == nil {
+			return nil, ErrStoreCreditRefundGuest
+		}
+		if err := s.storeCreditRepo.AddEntryTx(tx, storeCreditRefundEntry(refund, *order.UserID)); err != nil {
+			return nil, fmt.Errorf("failed to credit store credit: %w", err)
+		}
+	}
+
 	// Create refund items if item refund
 	if req.RefundType == "ITEM_ONLY" {
 		for _, item := range req.Items {
@@ -1072,22 +1305,19 @@ func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *
 				continue
 			}
 
-			refundItem := &models.RefundItem{
-				RefundID:     refund.ID,
-				OrderItemID:  item.OrderItemID,
-				ProductID:    orderItem.ProductID,
-				ProductName:  orderItem.ProductName,
-				Quantity:     item.Quantity,
-				PricePerUnit: orderItem.PricePerUnit,
-				RefundAmount: float64(item.Quantity) * orderItem.PricePerUnit,
-				ItemReason:   item.Reason,
-			}
-			if err := s.refundRepo.CreateRefundItemWithTx(tx, refundItem); err != nil {
-				return nil, fmt.Errorf("failed to create refund item: %w", err)
+			for _, refundItem := range refundItemsFor(orderItem, item, order.PricesIncludeTax) {
+				refundItem.RefundID = refund.ID
+				if err := s.refundRepo.CreateRefundItemWithTx(tx, &refundItem); err != nil {
+					return nil, fmt.Errorf("failed to create refund item: %w", err)
+				}
 			}
 		}
 	}
 
+	if err := s.voidRefundedGiftCards(tx, req, order); err != nil {
+		return nil, err
+	}
+
 	// Record status change within transaction
 	if err := s.refundRepo.RecordStatusChangeWithTx(tx, refund.ID, "", models.RefundStatusCompleted, "system", "Manual refund created and completed"); err != nil {
 		log.Printf("⚠️ Failed to record status change: %v", err)
@@ -1111,3 +1341,50 @@ func (s *refundService) createManualRefund(req *dto.RefundRequest, requestedBy *
 	
 	return refund, nil
 }
+
+// storeCreditRefundEntry is the ledger entry crediting a refund's store credit part
+func storeCreditRefundEntry(refund *models.Refund, userID int) *models.StoreCreditEntry {
+	return &models.StoreCreditEntry{
+		UserID:    userID,
+		EntryType: models.StoreCreditEntryRefund,
+		Amount:    refund.StoreCreditAmount,
+		OrderID:   &refund.OrderID,
+		RefundID:  &refund.ID,
+		Note:      "Refund " + refund.RefundCode,
+		CreatedBy: "system",
+	}
+}
+
+// midtransRefundedKey marks in a refund's gateway response that Midtrans has already refunded
+// its part, so a refund left PENDING by a failed store credit is not refunded twice
+const midtransRefundedKey = "midtrans_refund_id"
+
+func midtransRefundedID(refund *models.Refund) (string, bool) {
+	id, ok := refund.GatewayResponse[midtransRefundedKey].(string)
+	return id, ok && id != ""
+}
+
+// refundToStoreCredit credits the refund's store credit part. It is recorded once per refund,
+// so completing a refund again does not credit it twice.
+func (s *refundService) refundToStoreCredit(refund *models.Refund) error {
+	if refund.StoreCreditAmount <= 0 {
+		return nil
+	}
+
+	order, err := s.orderRepo.FindByID(refund.OrderID)
+	if err != nil {
+		return fmt.Errorf("order not found: %w", err)
+	}
+	if order.UserID == nil {
+		return ErrStoreCreditRefundGuest
+	}
+
+	err = s.storeCreditRepo.AddEntry(storeCreditRefundEntry(refund, *order.UserID))
+	if err == repository.ErrStoreCreditEntryExists {
+		return nil
+	}
+	if err == nil {
+		log.Printf("💳 Credited %.2f store credit to user %d for refund %s", refund.StoreCreditAmount, *order.UserID, refund.RefundCode)
+	}
+	return err
+}
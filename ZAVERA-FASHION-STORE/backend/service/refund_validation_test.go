@@ -377,6 +377,63 @@ func TestCalculateRefundAmount(t *testing.T) {
 	}
 }
 
+// Test that refunds pro-rate the PPN that was paid
+func TestCalculateTaxRefund(t *testing.T) {
+	service := &refundService{}
+
+	// Tax-inclusive order: 2 x 111,000 with 22,200 off, PPN 11%; exempt second line
+	order := &models.Order{
+		Subtotal:         322000,
+		ShippingCost:     20000,
+		Discount:         22200,
+		Tax:              19800,
+		TaxRate:          11,
+		PricesIncludeTax: true,
+		TotalAmount:      319800,
+		Items: []models.OrderItem{
+			{ID: 1, ProductID: 1, Quantity: 2, PricePerUnit: 111000, Subtotal: 222000, DiscountAmount: 22200, TaxRate: 11, TaxAmount: 19800},
+			{ID: 2, ProductID: 2, Quantity: 1, PricePerUnit: 100000, Subtotal: 100000},
+		},
+	}
+
+	oneUnit := &dto.RefundRequest{
+		RefundType: "ITEM_ONLY",
+		Items:      []dto.RefundItemRequest{{OrderItemID: 1, Quantity: 1}},
+	}
+	total, _, _, err := service.calculateRefundAmount(order, &models.Payment{Amount: order.TotalAmount}, oneUnit)
+	if err != nil || total != 99900 {
+		t.Errorf("Expected one unit refunded at 99900 paid, got %.2f (%v)", total, err)
+	}
+	if tax := service.calculateTaxRefund(order, oneUnit, total); tax != 9900 {
+		t.Errorf("Expected 9900 PPN in the unit refund, got %.2f", tax)
+	}
+
+	exempt := &dto.RefundRequest{
+		RefundType: "ITEM_ONLY",
+		Items:      []dto.RefundItemRequest{{OrderItemID: 2, Quantity: 1}},
+	}
+	if tax := service.calculateTaxRefund(order, exempt, 100000); tax != 0 {
+		t.Errorf("Expected no PPN refunded on an exempt line, got %.2f", tax)
+	}
+
+	full := &dto.RefundRequest{RefundType: "FULL"}
+	if tax := service.calculateTaxRefund(order, full, order.TotalAmount); tax != order.Tax {
+		t.Errorf("Expected the whole PPN refunded, got %.2f", tax)
+	}
+
+	partial := &dto.RefundRequest{RefundType: "PARTIAL"}
+	if tax := service.calculateTaxRefund(order, partial, 159900); tax != 9900 {
+		t.Errorf("Expected half the PPN in half the total, got %.2f", tax)
+	}
+
+	// Tax-exclusive prices: the unit's PPN is paid on top and refunded with it
+	order.PricesIncludeTax = false
+	total, _, _, _ = service.calculateRefundAmount(order, &models.Payment{Amount: 339600}, oneUnit)
+	if total != 109800 {
+		t.Errorf("Expected one unit refunded at 109800 with PPN, got %.2f", total)
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 || 
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"log"
+	"time"
+	"zavera/repository"
+)
+
+// reservationSweepBatch is how many expired holds are released per transaction
+const reservationSweepBatch = 200
+
+// ReservationSweeperJob puts expired stock holds (cart holds and checkout reservations) back on
+// the shelf. Each release is a RELEASE entry on the stock ledger and an SSE event for the admin.
+type ReservationSweeperJob struct {
+	reservationRepo repository.ReservationRepository
+	ticker          *time.Ticker
+	done            chan bool
+}
+
+func NewReservationSweeperJob(reservationRepo repository.ReservationRepository) *ReservationSweeperJob {
+	return &ReservationSweeperJob{
+		reservationRepo: reservationRepo,
+		done:            make(chan bool),
+	}
+}
+
+// Start begins the reservation sweeper
+// Runs every 30 seconds, cart holds only last a few minutes
+func (j *ReservationSweeperJob) Start() {
+	j.ticker = time.NewTicker(30 * time.Second)
+
+	// Run immediately on start to release holds that expired while down
+	go j.sweep()
+
+	go func() {
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-j.ticker.C:
+				j.sweep()
+			}
+		}
+	}()
+
+	log.Println("⌛ Reservation sweeper started (checks every 30 seconds)")
+}
+
+// Stop stops the reservation sweeper
+func (j *ReservationSweeperJob) Stop() {
+	if j.ticker != nil {
+		j.ticker.Stop()
+	}
+	j.done <- true
+	log.Println("⌛ Reservation sweeper stopped")
+}
+
+func (j *ReservationSweeperJob) sweep() {
+	total := 0
+	for {
+		releases, err := j.reservationRepo.ExpireReservations(reservationSweepBatch)
+		if err != nil {
+			log.Printf("⚠️ Failed to release expired reservations: %v", err)
+			return
+		}
+
+		for _, release := range releases {
+			NotifyReservationExpired(release)
+		}
+		total += len(releases)
+
+		if len(releases) < reservationSweepBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("✅ Released %d expired stock holds", total)
+	}
+}
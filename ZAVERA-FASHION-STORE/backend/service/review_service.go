@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrReviewNotFound         = errors.New("review not found")
+	ErrReviewNotAllowed       = errors.New("only delivered items from your own orders can be reviewed")
+	ErrAlreadyReviewed        = errors.New("this item has already been reviewed")
+	ErrInvalidReviewPhoto     = errors.New("review photos must be uploaded through /api/user/reviews/photos")
+	ErrPhotoUploadUnavailable = errors.New("photo upload is not configured")
+)
+
+// maxReviewPhotos is the number of photos allowed per review
+const maxReviewPhotos = 5
+
+// reviewView controls how much of a review a caller sees
+type reviewView int
+
+const (
+	reviewViewPublic reviewView = iota // Storefront: masked name, no moderation details
+	reviewViewAuthor                   // The reviewer: status and moderation note
+	reviewViewAdmin                    // Moderators: everything
+)
+
+type ReviewService interface {
+	GetReviewableItems(userID int) ([]dto.ReviewableItemResponse, error)
+	CreateReview(userID int, req dto.CreateReviewRequest) (*dto.ReviewResponse, error)
+	GetUserReviews(userID int) ([]dto.ReviewResponse, error)
+	UploadReviewPhoto(file multipart.File, fileHeader *multipart.FileHeader) (string, error)
+	GetProductReviews(productID int, filter models.ReviewListFilter) (*dto.ProductReviewsResponse, error)
+	ListReviewsAdmin(filter models.ReviewListFilter) ([]dto.ReviewResponse, int, error)
+	ModerateReview(id int, req dto.ModerateReviewRequest, adminEmail string) (*dto.ReviewResponse, error)
+}
+
+type reviewService struct {
+	reviewRepo  repository.ReviewRepository
+	productRepo repository.ProductRepository
+	userRepo    repository.UserRepository
+	cloudinary  CloudinaryService // nil when Cloudinary is not configured
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepository, productRepo repository.ProductRepository, userRepo repository.UserRepository, cloudinary CloudinaryService) ReviewService {
+	return &reviewService{
+		reviewRepo:  reviewRepo,
+		productRepo: productRepo,
+		userRepo:    userRepo,
+		cloudinary:  cloudinary,
+	}
+}
+
+// GetReviewableItems lists delivered order items the user has not reviewed yet
+func (s *reviewService) GetReviewableItems(userID int) ([]dto.ReviewableItemResponse, error) {
+	items, err := s.reviewRepo.FindReviewableItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ReviewableItemResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, dto.ReviewableItemResponse{
+			OrderItemID:  item.OrderItemID,
+			OrderCode:    item.OrderCode,
+			ProductID:    item.ProductID,
+			ProductName:  item.ProductName,
+			ProductImage: item.ProductImage,
+			DeliveredAt:  dto.FormatTime(item.DeliveredAt),
+		})
+	}
+	return response, nil
+}
+
+// CreateReview stores a review for an order item of a DELIVERED or COMPLETED order owned by the user.
+// New reviews wait for moderation before they count towards the product rating.
+func (s *reviewService) CreateReview(userID int, req dto.CreateReviewRequest) (*dto.ReviewResponse, error) {
+	item, ownerID, status, err := s.reviewRepo.FindOrderItemForReview(req.OrderItemID)
+	if err == repository.ErrReviewItemNotFound {
+		return nil, ErrReviewNotAllowed
+	}
+	if err == repository.ErrOrderItemReviewed {
+		return nil, ErrAlreadyReviewed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canReviewOrder(ownerID, status, userID) {
+		return nil, ErrReviewNotAllowed
+	}
+
+	if len(req.PhotoURLs) > maxReviewPhotos {
+		return nil, ErrInvalidReviewPhoto
+	}
+	for _, url := range req.PhotoURLs {
+		if !isReviewPhotoURL(url) {
+			return nil, ErrInvalidReviewPhoto
+		}
+	}
+
+	reviewerName := ""
+	if user, err := s.userRepo.FindByID(userID); err == nil {
+		reviewerName = user.FirstName
+		if reviewerName == "" {
+			reviewerName = user.Name
+		}
+	}
+
+	review := &models.ProductReview{
+		ProductID:          item.ProductID,
+		VariantID:          item.VariantID,
+		OrderID:            &item.OrderID,
+		OrderItemID:        &item.OrderItemID,
+		UserID:             &userID,
+		ReviewerName:       strings.TrimSpace(reviewerName),
+		Rating:             req.Rating,
+		Title:              strings.TrimSpace(req.Title),
+		Body:               strings.TrimSpace(req.Body),
+		IsVerifiedPurchase: true,
+		Status:             models.ReviewStatusPending,
+		Photos:             req.PhotoURLs,
+	}
+	if req.Fit != "" {
+		fit := models.ReviewFit(req.Fit)
+		review.Fit = &fit
+	}
+
+	if err := s.reviewRepo.Create(review); err != nil {
+		if err == repository.ErrOrderItemReviewed {
+			return nil, ErrAlreadyReviewed
+		}
+		return nil, err
+	}
+
+	log.Printf("📝 Review %d submitted for product %d (order item %d)", review.ID, review.ProductID, req.OrderItemID)
+
+	return s.getReview(review.ID, reviewViewAuthor)
+}
+
+// GetUserReviews lists the user's own reviews including pending and rejected ones
+func (s *reviewService) GetUserReviews(userID int) ([]dto.ReviewResponse, error) {
+	reviews, err := s.reviewRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ReviewResponse, 0, len(reviews))
+	for _, r := range reviews {
+		response = append(response, toReviewResponse(r, reviewViewAuthor))
+	}
+	return response, nil
+}
+
+// UploadReviewPhoto validates and uploads a review photo to Cloudinary
+func (s *reviewService) UploadReviewPhoto(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
+	if s.cloudinary == nil {
+		return "", ErrPhotoUploadUnavailable
+	}
+	if err := ValidateImageFile(fileHeader); err != nil {
+		return "", err
+	}
+	return s.cloudinary.UploadImageToFolder(file, fileHeader.Filename, CloudinaryFolderReviews)
+}
+
+// GetProductReviews returns a page of approved reviews with the product's rating breakdown
+func (s *reviewService) GetProductReviews(productID int, filter models.ReviewListFilter) (*dto.ProductReviewsResponse, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil || !product.IsActive {
+		return nil, ErrProductNotFound
+	}
+
+	filter.ProductID = productID
+	filter.Status = models.ReviewStatusApproved
+	normalizeReviewPage(&filter)
+
+	reviews, total, err := s.reviewRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.ProductReviewsResponse{
+		ProductID:    productID,
+		Distribution: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0},
+		Reviews:      make([]dto.ReviewResponse, 0, len(reviews)),
+		TotalCount:   total,
+		Limit:        filter.Limit,
+		Offset:       filter.Offset,
+	}
+
+	ratings, err := s.productRepo.FindRatingSummaries([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if summary, ok := ratings[productID]; ok {
+		response.Rating = toProductRatingResponse(summary)
+		for i, count := range summary.Distribution {
+			response.Distribution[strconv.Itoa(i+1)] = count
+		}
+	}
+
+	for _, r := range reviews {
+		response.Reviews = append(response.Reviews, toReviewResponse(r, reviewViewPublic))
+	}
+	return response, nil
+}
+
+// ListReviewsAdmin returns reviews for the moderation queue
+func (s *reviewService) ListReviewsAdmin(filter models.ReviewListFilter) ([]dto.ReviewResponse, int, error) {
+	normalizeReviewPage(&filter)
+	if filter.Sort == "" && filter.Status == models.ReviewStatusPending {
+		// Oldest first so the queue is worked in order
+		filter.Sort = "oldest"
+	}
+
+	reviews, total, err := s.reviewRepo.List(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	response := make([]dto.ReviewResponse, 0, len(reviews))
+	for _, r := range reviews {
+		response = append(response, toReviewResponse(r, reviewViewAdmin))
+	}
+	return response, total, nil
+}
+
+// ModerateReview approves or rejects a review; only approved reviews are public and counted
+func (s *reviewService) ModerateReview(id int, req dto.ModerateReviewRequest, adminEmail string) (*dto.ReviewResponse, error) {
+	status := models.ReviewStatus(req.Status)
+	if status != models.ReviewStatusApproved && status != models.ReviewStatusRejected {
+		return nil, errors.New("status must be APPROVED or REJECTED")
+	}
+
+	err := s.reviewRepo.Moderate(id, status, strings.TrimSpace(req.Note), adminEmail)
+	if err == repository.ErrReviewNotFound {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🛡️ Review %d %s by %s", id, status, adminEmail)
+
+	return s.getReview(id, reviewViewAdmin)
+}
+
+func (s *reviewService) getReview(id int, view reviewView) (*dto.ReviewResponse, error) {
+	review, err := s.reviewRepo.FindByID(id)
+	if err == repository.ErrReviewNotFound {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := toReviewResponse(*review, view)
+	return &response, nil
+}
+
+// canReviewOrder reports whether userID may review items of an order with the given owner and status
+func canReviewOrder(ownerID *int, status models.OrderStatus, userID int) bool {
+	if ownerID == nil || *ownerID != userID {
+		return false
+	}
+	return status == models.OrderStatusDelivered || status == models.OrderStatusCompleted
+}
+
+// isReviewPhotoURL accepts only images from our Cloudinary reviews folder
+func isReviewPhotoURL(url string) bool {
+	return strings.HasPrefix(url, "https://res.cloudinary.com/") &&
+		strings.Contains(url, "/"+CloudinaryFolderReviews+"/")
+}
+
+func normalizeReviewPage(filter *models.ReviewListFilter) {
+	if filter.Limit <= 0 || filter.Limit > 50 {
+		filter.Limit = 10
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+}
+
+// toReviewResponse converts a review for the given audience
+func toReviewResponse(r models.ProductReview, view reviewView) dto.ReviewResponse {
+	response := dto.ReviewResponse{
+		ID:                 r.ID,
+		ProductID:          r.ProductID,
+		ProductName:        r.ProductName,
+		VariantName:        r.VariantName,
+		ReviewerName:       models.MaskReviewerName(r.ReviewerName),
+		Rating:             r.Rating,
+		Title:              r.Title,
+		Body:               r.Body,
+		IsVerifiedPurchase: r.IsVerifiedPurchase,
+		Photos:             r.Photos,
+		CreatedAt:          dto.FormatTime(r.CreatedAt),
+	}
+	if response.Photos == nil {
+		response.Photos = []string{}
+	}
+	if r.Fit != nil {
+		response.Fit = string(*r.Fit)
+	}
+
+	if view >= reviewViewAuthor {
+		response.ReviewerName = r.ReviewerName
+		response.Status = string(r.Status)
+		response.ModerationNote = r.ModerationNote
+		response.ModeratedAt = dto.FormatTimePtr(r.ModeratedAt)
+		response.OrderID = r.OrderID
+	}
+	if view == reviewViewAdmin {
+		response.ModeratedBy = r.ModeratedBy
+		response.UserID = r.UserID
+	}
+	return response
+}
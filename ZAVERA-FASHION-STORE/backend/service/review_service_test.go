@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"zavera/models"
+)
+
+func TestCanReviewOrder(t *testing.T) {
+	owner := 7
+	other := 8
+
+	tests := []struct {
+		name    string
+		ownerID *int
+		status  models.OrderStatus
+		allowed bool
+	}{
+		{"delivered own order", &owner, models.OrderStatusDelivered, true},
+		{"completed own order", &owner, models.OrderStatusCompleted, true},
+		{"shipped but not delivered", &owner, models.OrderStatusShipped, false},
+		{"refunded", &owner, models.OrderStatusRefunded, false},
+		{"someone else's order", &other, models.OrderStatusDelivered, false},
+		{"guest order", nil, models.OrderStatusDelivered, false},
+	}
+
+	for _, tt := range tests {
+		if got := canReviewOrder(tt.ownerID, tt.status, owner); got != tt.allowed {
+			t.Errorf("%s: canReviewOrder = %v, expected %v", tt.name, got, tt.allowed)
+		}
+	}
+}
+
+func TestIsReviewPhotoURL(t *testing.T) {
+	valid := "https://res.cloudinary.com/demo/image/upload/v1700000000/zavera/reviews/1700000000_photo.jpg"
+	if !isReviewPhotoURL(valid) {
+		t.Errorf("expected %s to be accepted", valid)
+	}
+
+	for _, url := range []string{
+		"https://res.cloudinary.com/demo/image/upload/v1/zavera/products/shirt.jpg",
+		"https://example.com/zavera/reviews/photo.jpg",
+		"http://res.cloudinary.com/demo/image/upload/v1/zavera/reviews/photo.jpg",
+	} {
+		if isReviewPhotoURL(url) {
+			t.Errorf("expected %s to be rejected", url)
+		}
+	}
+}
+
+func TestToReviewResponse_Views(t *testing.T) {
+	userID := 7
+	review := models.ProductReview{
+		ID:             1,
+		ReviewerName:   "Rina Sari",
+		Rating:         5,
+		Status:         models.ReviewStatusRejected,
+		ModerationNote: "Contains a phone number",
+		ModeratedBy:    "admin@zavera.id",
+		UserID:         &userID,
+		CreatedAt:      time.Now(),
+	}
+
+	public := toReviewResponse(review, reviewViewPublic)
+	if public.ReviewerName != "Rina S." || public.Status != "" || public.ModerationNote != "" {
+		t.Errorf("public view leaks moderation details: %+v", public)
+	}
+	if public.Photos == nil {
+		t.Errorf("expected photos to serialize as []")
+	}
+
+	author := toReviewResponse(review, reviewViewAuthor)
+	if author.Status != "REJECTED" || author.ModerationNote == "" {
+		t.Errorf("author should see status and note: %+v", author)
+	}
+	if author.ModeratedBy != "" || author.UserID != nil {
+		t.Errorf("author should not see moderator identity: %+v", author)
+	}
+
+	admin := toReviewResponse(review, reviewViewAdmin)
+	if admin.ModeratedBy != "admin@zavera.id" || admin.UserID == nil {
+		t.Errorf("admin should see everything: %+v", admin)
+	}
+}
@@ -100,6 +100,7 @@ type shippingService struct {
 	cartRepo     repository.CartRepository
 	productRepo  repository.ProductRepository
 	orderRepo    repository.OrderRepository
+	warehouses   WarehouseService
 	biteship     *BiteshipClient
 }
 
@@ -108,12 +109,14 @@ func NewShippingService(
 	cartRepo repository.CartRepository,
 	productRepo repository.ProductRepository,
 	orderRepo repository.OrderRepository,
+	warehouses WarehouseService,
 ) ShippingService {
 	return &shippingService{
 		shippingRepo: shippingRepo,
 		cartRepo:     cartRepo,
 		productRepo:  productRepo,
 		orderRepo:    orderRepo,
+		warehouses:   warehouses,
 		biteship:     NewBiteshipClient(),
 	}
 }
@@ -177,6 +180,60 @@ func (s *shippingService) SearchAreas(query string) ([]dto.AreaResponse, error)
 // Default origin postal code (Pedurungan, Semarang)
 const DefaultOriginPostalCode = 50113
 
+// defaultOriginWarehouse mirrors the default warehouse seeded by migrate_warehouses.sql;
+// used when the warehouses table cannot be read
+func defaultOriginWarehouse() *models.Warehouse {
+	return &models.Warehouse{
+		Code:         "SMG",
+		Name:         "Semarang",
+		Address:      "Jl. Pedurungan Tengah, Pedurungan, Semarang",
+		CityName:     "Kota Semarang",
+		CityID:       DefaultOriginCityID,
+		PostalCode:   strconv.Itoa(DefaultOriginPostalCode),
+		AreaID:       DefaultOriginAreaID,
+		ContactName:  "ZAVERA Fashion Store",
+		ContactPhone: "081234567890",
+		IsDefault:    true,
+		IsActive:     true,
+	}
+}
+
+// originPostalCode returns the warehouse postal code as Biteship expects it
+func originPostalCode(w *models.Warehouse) int {
+	if postalCode, err := strconv.Atoi(w.PostalCode); err == nil && postalCode > 0 {
+		return postalCode
+	}
+	return DefaultOriginPostalCode
+}
+
+// originCityID returns the warehouse's legacy city ID, or the default origin's when it has none
+func originCityID(w *models.Warehouse) string {
+	if w.CityID != "" {
+		return w.CityID
+	}
+	return DefaultOriginCityID
+}
+
+// quoteOrigin is the warehouse rates are quoted from: the one the order would be routed to, or
+// the default warehouse when no single warehouse has everything (checkout then refuses the order)
+func quoteOrigin(warehouses WarehouseService, items []models.CartItem, destinationAreaID, destinationPostalCode string) *models.Warehouse {
+	warehouse, err := warehouses.RouteOrder(cartStockLines(items), cartHolds(items), destinationAreaID, destinationPostalCode)
+	if err == nil {
+		return warehouse
+	}
+	log.Printf("⚠️ Warehouse routing for rates failed, quoting from default warehouse: %v", err)
+
+	all, err := warehouses.ListWarehouses(true)
+	if err == nil {
+		for i := range all {
+			if all[i].IsDefault {
+				return &all[i]
+			}
+		}
+	}
+	return defaultOriginWarehouse()
+}
+
 // GetBiteshipRatesForUser gets shipping rates for a specific user (PRIMARY METHOD)
 // This method properly handles logged-in users by finding their cart
 func (s *shippingService) GetBiteshipRatesForUser(sessionID string, userID *int, destinationAreaID string, destinationPostalCode string) (*dto.GetRatesResponse, error) {
@@ -287,16 +344,19 @@ func (s *shippingService) calculateRatesForItems(items []models.CartItem, destin
 	// Standard e-commerce practice: show ALL available couriers, let customer choose
 	// Biteship courier codes: https://biteship.com/id/docs/api/couriers
 	allCouriers := "jne,tiki,ninja,lion,sicepat,jnt,idexpress,rpx,wahana,pos,anteraja,sap,paxel,borzo,lalamove,grab,gojek,deliveree"
+
+	// Quote from the warehouse the order would ship from
+	origin := quoteOrigin(s.warehouses, items, destinationAreaID, strconv.Itoa(destPostalCode))
 	
 	biteshipReq := GetRatesRequest{
-		OriginPostalCode:      DefaultOriginPostalCode,
+		OriginPostalCode:      originPostalCode(origin),
 		DestinationPostalCode: destPostalCode,
 		Couriers:              allCouriers,
 		Items:                 biteshipItems,
 	}
 
-	log.Printf("📦 Biteship rates request: origin_postal=%d, destination_postal=%d, weight=%dg", 
-		DefaultOriginPostalCode, destPostalCode, totalWeight)
+	log.Printf("📦 Biteship rates request: origin=%s postal=%d, destination_postal=%d, weight=%dg", 
+		origin.Code, biteshipReq.OriginPostalCode, destPostalCode, totalWeight)
 
 	// Call Biteship API
 	rates, err := s.biteship.GetRates(biteshipReq)
@@ -350,7 +410,7 @@ func (s *shippingService) calculateRatesForItems(items []models.CartItem, destin
 		GroupedRates:  groupedRates,
 		TotalWeight:   totalWeight,
 		TotalWeightKg: weightKg,
-		OriginCity:    "Semarang",
+		OriginCity:    origin.Name,
 	}, nil
 }
 
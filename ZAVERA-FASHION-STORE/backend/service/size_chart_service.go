@@ -0,0 +1,257 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrSizeChartNotFound    = errors.New("size chart not found")
+	ErrInvalidSizeChart     = errors.New("invalid size chart")
+	ErrFitProfileNotFound   = errors.New("fit profile not found")
+	ErrNoSizeRecommendation = errors.New("not enough fit profile data to recommend a size")
+)
+
+// SizeChartService manages size charts and customer fit profiles, and recommends a size of a
+// product from the chart that applies to it
+type SizeChartService interface {
+	ListCharts() ([]models.SizeChart, error)
+	GetChart(id int) (*models.SizeChart, error)
+	CreateChart(req dto.SaveSizeChartRequest, adminEmail string) (*models.SizeChart, error)
+	UpdateChart(id int, req dto.SaveSizeChartRequest, adminEmail string) (*models.SizeChart, error)
+	DeleteChart(id int, adminEmail string) error
+	GetProductChart(productID int) (*models.SizeChart, error)
+
+	GetFitProfile(userID int) (*models.FitProfile, error)
+	SaveFitProfile(userID int, req dto.SaveFitProfileRequest) (*models.FitProfile, error)
+	Recommend(userID, productID int) (*dto.SizeRecommendationResponse, error)
+}
+
+type sizeChartService struct {
+	sizeChartRepo repository.SizeChartRepository
+	productRepo   repository.ProductRepository
+	categoryRepo  repository.CategoryRepository
+}
+
+func NewSizeChartService(sizeChartRepo repository.SizeChartRepository, productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository) SizeChartService {
+	return &sizeChartService{
+		sizeChartRepo: sizeChartRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+	}
+}
+
+func (s *sizeChartService) ListCharts() ([]models.SizeChart, error) {
+	return s.sizeChartRepo.List(false)
+}
+
+func (s *sizeChartService) GetChart(id int) (*models.SizeChart, error) {
+	chart, err := s.sizeChartRepo.FindByID(id)
+	if err == repository.ErrSizeChartNotFound {
+		return nil, ErrSizeChartNotFound
+	}
+	return chart, err
+}
+
+func (s *sizeChartService) CreateChart(req dto.SaveSizeChartRequest, adminEmail string) (*models.SizeChart, error) {
+	chart, err := s.buildChart(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sizeChartRepo.Create(chart); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📏 Size chart %d (%s) created by %s", chart.ID, chart.Name, adminEmail)
+	return chart, nil
+}
+
+func (s *sizeChartService) UpdateChart(id int, req dto.SaveSizeChartRequest, adminEmail string) (*models.SizeChart, error) {
+	chart, err := s.buildChart(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chart.ID = id
+	if err := s.sizeChartRepo.Update(chart); err != nil {
+		if err == repository.ErrSizeChartNotFound {
+			return nil, ErrSizeChartNotFound
+		}
+		return nil, err
+	}
+
+	log.Printf("📏 Size chart %d (%s) updated by %s", chart.ID, chart.Name, adminEmail)
+	return chart, nil
+}
+
+func (s *sizeChartService) DeleteChart(id int, adminEmail string) error {
+	if err := s.sizeChartRepo.Delete(id); err != nil {
+		if err == repository.ErrSizeChartNotFound {
+			return ErrSizeChartNotFound
+		}
+		return err
+	}
+
+	log.Printf("📏 Size chart %d deleted by %s", id, adminEmail)
+	return nil
+}
+
+// buildChart validates the request: a scope, unique size labels, and min <= max per measurement
+func (s *sizeChartService) buildChart(req dto.SaveSizeChartRequest) (*models.SizeChart, error) {
+	chart := &models.SizeChart{
+		Name:       strings.TrimSpace(req.Name),
+		CategoryID: req.CategoryID,
+		Notes:      strings.TrimSpace(req.Notes),
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if req.Brand != nil && strings.TrimSpace(*req.Brand) != "" {
+		brand := strings.TrimSpace(*req.Brand)
+		chart.Brand = &brand
+	}
+
+	if chart.CategoryID == nil && chart.Brand == nil {
+		return nil, fmt.Errorf("%w: a chart needs a category, a brand, or both", ErrInvalidSizeChart)
+	}
+	if chart.CategoryID != nil {
+		if _, err := s.categoryRepo.FindByID(*chart.CategoryID); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, r := range req.Rows {
+		label := strings.TrimSpace(r.SizeLabel)
+		if label == "" {
+			return nil, fmt.Errorf("%w: size label is required", ErrInvalidSizeChart)
+		}
+		if seen[strings.ToUpper(label)] {
+			return nil, fmt.Errorf("%w: size %s is listed twice", ErrInvalidSizeChart, label)
+		}
+		seen[strings.ToUpper(label)] = true
+
+		ranges := []struct {
+			name     string
+			min, max *float64
+		}{
+			{"chest", r.ChestMinCm, r.ChestMaxCm},
+			{"waist", r.WaistMinCm, r.WaistMaxCm},
+			{"hip", r.HipMinCm, r.HipMaxCm},
+			{"height", r.HeightMinCm, r.HeightMaxCm},
+			{"weight", r.WeightMinKg, r.WeightMaxKg},
+		}
+		for _, m := range ranges {
+			if (m.min != nil && *m.min <= 0) || (m.max != nil && *m.max <= 0) {
+				return nil, fmt.Errorf("%w: %s of size %s must be positive", ErrInvalidSizeChart, m.name, label)
+			}
+			if m.min != nil && m.max != nil && *m.min > *m.max {
+				return nil, fmt.Errorf("%w: %s minimum of size %s is above its maximum", ErrInvalidSizeChart, m.name, label)
+			}
+		}
+
+		chart.Rows = append(chart.Rows, models.SizeChartRow{
+			SizeLabel:   label,
+			ChestMinCm:  r.ChestMinCm,
+			ChestMaxCm:  r.ChestMaxCm,
+			WaistMinCm:  r.WaistMinCm,
+			WaistMaxCm:  r.WaistMaxCm,
+			HipMinCm:    r.HipMinCm,
+			HipMaxCm:    r.HipMaxCm,
+			HeightMinCm: r.HeightMinCm,
+			HeightMaxCm: r.HeightMaxCm,
+			WeightMinKg: r.WeightMinKg,
+			WeightMaxKg: r.WeightMaxKg,
+			LengthCm:    r.LengthCm,
+		})
+	}
+	return chart, nil
+}
+
+// GetProductChart returns the chart that applies to the product, or ErrSizeChartNotFound
+func (s *sizeChartService) GetProductChart(productID int) (*models.SizeChart, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	chart, err := s.sizeChartRepo.FindForProduct(productID)
+	if err == repository.ErrSizeChartNotFound {
+		return nil, ErrSizeChartNotFound
+	}
+	return chart, err
+}
+
+func (s *sizeChartService) GetFitProfile(userID int) (*models.FitProfile, error) {
+	profile, err := s.sizeChartRepo.GetFitProfile(userID)
+	if err == repository.ErrFitProfileNotFound {
+		return nil, ErrFitProfileNotFound
+	}
+	return profile, err
+}
+
+func (s *sizeChartService) SaveFitProfile(userID int, req dto.SaveFitProfileRequest) (*models.FitProfile, error) {
+	profile := &models.FitProfile{
+		UserID:     userID,
+		HeightCm:   req.HeightCm,
+		WeightKg:   req.WeightKg,
+		ChestCm:    req.ChestCm,
+		WaistCm:    req.WaistCm,
+		HipCm:      req.HipCm,
+		UsualSizes: map[string]string{},
+	}
+	// Brands are matched case-insensitively
+	for brand, size := range req.UsualSizes {
+		brand = strings.ToLower(strings.TrimSpace(brand))
+		size = strings.TrimSpace(size)
+		if brand != "" && size != "" {
+			profile.UsualSizes[brand] = size
+		}
+	}
+
+	if err := s.sizeChartRepo.SaveFitProfile(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *sizeChartService) Recommend(userID, productID int) (*dto.SizeRecommendationResponse, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	chart, err := s.sizeChartRepo.FindForProduct(productID)
+	if err == repository.ErrSizeChartNotFound {
+		return nil, ErrSizeChartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.GetFitProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendation := models.RecommendSize(chart.Rows, *profile, product.Brand)
+	if recommendation == nil {
+		return nil, ErrNoSizeRecommendation
+	}
+
+	response := &dto.SizeRecommendationResponse{
+		SizeRecommendation: *recommendation,
+		ProductID:          productID,
+		ChartID:            chart.ID,
+		VariantIDs:         []int{},
+	}
+	for _, row := range chart.Rows {
+		if row.ID == recommendation.RowID && row.VariantIDs != nil {
+			response.VariantIDs = row.VariantIDs
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Spreadsheet formats accepted by bulk import and produced by export
+const (
+	SheetFormatCSV  = "csv"
+	SheetFormatXLSX = "xlsx"
+)
+
+var ErrUnsupportedSheetFormat = errors.New("unsupported file format, use csv or xlsx")
+
+// readSheet parses the first worksheet of a CSV or XLSX file into rows of cells.
+// Trailing empty rows are dropped; short rows are not padded.
+func readSheet(format string, data []byte) ([][]string, error) {
+	var rows [][]string
+	var err error
+
+	switch format {
+	case SheetFormatCSV:
+		rows, err = readCSV(data)
+	case SheetFormatXLSX:
+		rows, err = readXLSX(data)
+	default:
+		return nil, ErrUnsupportedSheetFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for len(rows) > 0 && isBlankRow(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// writeSheet renders rows as a CSV or single-sheet XLSX file
+func writeSheet(format, sheetName string, rows [][]string) ([]byte, error) {
+	switch format {
+	case SheetFormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case SheetFormatXLSX:
+		return writeXLSX(sheetName, rows)
+	default:
+		return nil, ErrUnsupportedSheetFormat
+	}
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Excel saves "CSV UTF-8" with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ============================================
+// XLSX (Office Open XML) - only what a plain data sheet needs:
+// shared/inline strings and numbers from the first worksheet.
+// ============================================
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string        `xml:"r,attr"`
+			Type   string        `xml:"t,attr"`
+			Value  string        `xml:"v"`
+			Inline *xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, fmt.Errorf("invalid xlsx shared strings: %w", err)
+		}
+	}
+
+	sheetFile, ok := files[firstWorksheetPath(files)]
+	if !ok {
+		return nil, errors.New("invalid xlsx: no worksheet found")
+	}
+	var sheet xlsxWorksheet
+	if err := decodeZipXML(sheetFile, &sheet); err != nil {
+		return nil, fmt.Errorf("invalid xlsx worksheet: %w", err)
+	}
+
+	var rows [][]string
+	for _, r := range sheet.Rows {
+		rowIdx := r.Index - 1
+		if r.Index == 0 {
+			rowIdx = len(rows)
+		}
+		for len(rows) <= rowIdx {
+			rows = append(rows, nil)
+		}
+
+		row := rows[rowIdx]
+		for i, c := range r.Cells {
+			col := i
+			if c.Ref != "" {
+				if col, err = xlsxColumnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+
+			var value string
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid xlsx: bad shared string in cell %s", c.Ref)
+				}
+				value = shared.Items[idx].String()
+			case "inlineStr":
+				if c.Inline != nil {
+					value = c.Inline.String()
+				}
+			case "b":
+				value = "false"
+				if c.Value == "1" {
+					value = "true"
+				}
+			default:
+				value = c.Value
+			}
+
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = value
+		}
+		rows[rowIdx] = row
+	}
+
+	return rows, nil
+}
+
+// firstWorksheetPath resolves the first sheet of the workbook to its part name
+func firstWorksheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var wb xlsxWorkbook
+	var rels xlsxRelationships
+	wbFile, ok1 := files["xl/workbook.xml"]
+	relFile, ok2 := files["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 || decodeZipXML(wbFile, &wb) != nil || decodeZipXML(relFile, &rels) != nil || len(wb.Sheets) == 0 {
+		return fallback
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// xlsxColumnIndex converts a cell reference such as "AB12" to a zero-based column index
+func xlsxColumnIndex(ref string) (int, error) {
+	col := 0
+	for i, ch := range ref {
+		if ch >= 'A' && ch <= 'Z' {
+			col = col*26 + int(ch-'A'+1)
+			continue
+		}
+		if i == 0 {
+			break
+		}
+		return col - 1, nil
+	}
+	return 0, fmt.Errorf("invalid xlsx: bad cell reference %q", ref)
+}
+
+// xlsxColumnName converts a zero-based column index to its letters (0 -> "A", 27 -> "AB")
+func xlsxColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	xlsxWorkbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+)
+
+// writeXLSX writes every cell as an inline string so SKUs and barcodes keep leading zeros
+func writeXLSX(sheetName string, rows [][]string) ([]byte, error) {
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&sheet, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, xlsxColumnName(c), r+1)
+			if err := xml.EscapeText(&sheet, []byte(value)); err != nil {
+				return nil, err
+			}
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var escapedName bytes.Buffer
+	if err := xml.EscapeText(&escapedName, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+
+	parts := []struct {
+		name string
+		body []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(fmt.Sprintf(xlsxWorkbookTemplate, escapedName.String()))},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/worksheets/sheet1.xml", sheet.Bytes()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, bytes.NewReader(part.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
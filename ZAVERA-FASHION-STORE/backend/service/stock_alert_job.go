@@ -0,0 +1,168 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"zavera/repository"
+)
+
+// stockAlertBatch is how many subscriptions and price drops are handled per run
+const stockAlertBatch = 200
+
+// StockAlertJob emails back-in-stock and price-drop alerts for wishlisted and subscribed items.
+// Variant subscriptions are one-shot and stay pending while the user is rate-limited, as do the
+// wishlist alerts queued on the transition from sold out to available; price drops fire on each
+// new lower price.
+type StockAlertJob struct {
+	alertRepo    repository.StockAlertRepository
+	emailService EmailService
+	ticker       *time.Ticker
+	done         chan bool
+}
+
+func NewStockAlertJob(alertRepo repository.StockAlertRepository, emailService EmailService) *StockAlertJob {
+	return &StockAlertJob{
+		alertRepo:    alertRepo,
+		emailService: emailService,
+		done:         make(chan bool),
+	}
+}
+
+// Start begins the stock alert job
+// Runs every 5 minutes
+func (j *StockAlertJob) Start() {
+	j.ticker = time.NewTicker(5 * time.Minute)
+
+	go j.run()
+
+	go func() {
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-j.ticker.C:
+				j.run()
+			}
+		}
+	}()
+
+	log.Println("🔔 Stock alert job started (checks every 5 minutes)")
+}
+
+// Stop stops the stock alert job
+func (j *StockAlertJob) Stop() {
+	if j.ticker != nil {
+		j.ticker.Stop()
+	}
+	j.done <- true
+	log.Println("🔔 Stock alert job stopped")
+}
+
+func (j *StockAlertJob) run() {
+	sent := j.sendSubscriptionAlerts() + j.sendWishlistRestockAlerts() + j.sendPriceDropAlerts()
+	if sent > 0 {
+		log.Printf("✅ Sent %d stock alert emails", sent)
+	}
+}
+
+func (j *StockAlertJob) sendSubscriptionAlerts() int {
+	due, err := j.alertRepo.FindDue(stockAlertBatch)
+	if err != nil {
+		log.Printf("⚠️ Failed to load due stock alerts: %v", err)
+		return 0
+	}
+
+	sent := 0
+	for _, alert := range due {
+		item := BackInStockItem{
+			ProductID:   alert.ProductID,
+			ProductName: alert.ProductName,
+			VariantName: alert.VariantName,
+			Price:       alert.Price,
+		}
+		err := j.emailService.SendBackInStock(alert.Recipient, item, fmt.Sprintf("variant:%d", alert.VariantID))
+		if err == ErrAlertRateLimited {
+			continue // try again on a later run
+		}
+		if err != nil && err != ErrAlertDuplicate {
+			log.Printf("⚠️ Failed to send back-in-stock alert %d: %v", alert.ID, err)
+			continue
+		}
+		if err == nil {
+			sent++
+		}
+		if err := j.alertRepo.MarkNotified(alert.ID); err != nil {
+			log.Printf("⚠️ Failed to mark stock alert %d notified: %v", alert.ID, err)
+		}
+	}
+	return sent
+}
+
+func (j *StockAlertJob) sendWishlistRestockAlerts() int {
+	// Queues alerts for the wishlists of restocked variants; a failure here leaves the state
+	// unchanged, so the restock is seen again on the next run
+	if _, err := j.alertRepo.SyncAvailability(); err != nil {
+		log.Printf("⚠️ Failed to check variant availability: %v", err)
+	}
+
+	due, err := j.alertRepo.FindDueWishlistRestocks(stockAlertBatch)
+	if err != nil {
+		log.Printf("⚠️ Failed to load due wishlist alerts: %v", err)
+		return 0
+	}
+
+	sent := 0
+	for _, alert := range due {
+		item := BackInStockItem{
+			ProductID:   alert.ProductID,
+			ProductName: alert.ProductName,
+			VariantName: alert.VariantName,
+			Price:       alert.Price,
+		}
+		// Keyed by product so several sizes restocked together make one email
+		err := j.emailService.SendBackInStock(alert.Recipient, item, fmt.Sprintf("product:%d", alert.ProductID))
+		if err == ErrAlertRateLimited {
+			continue // try again on a later run
+		}
+		if err != nil && err != ErrAlertDuplicate {
+			log.Printf("⚠️ Failed to send back-in-stock alert to user %d: %v", alert.Recipient.UserID, err)
+			continue
+		}
+		if err == nil {
+			sent++
+		}
+		if err := j.alertRepo.MarkWishlistRestockSent(alert.ID); err != nil {
+			log.Printf("⚠️ Failed to mark wishlist alert %d sent: %v", alert.ID, err)
+		}
+	}
+	return sent
+}
+
+func (j *StockAlertJob) sendPriceDropAlerts() int {
+	drops, err := j.alertRepo.FindPriceDrops(stockAlertBatch)
+	if err != nil {
+		log.Printf("⚠️ Failed to load price drops: %v", err)
+		return 0
+	}
+
+	sent := 0
+	for _, drop := range drops {
+		err := j.emailService.SendPriceDrop(drop)
+		if err == ErrAlertRateLimited {
+			continue // try again on a later run
+		}
+		if err != nil && err != ErrAlertDuplicate {
+			log.Printf("⚠️ Failed to send price-drop alert for wishlist %d: %v", drop.WishlistID, err)
+			continue
+		}
+		if err == nil {
+			sent++
+		}
+		// The new price is what the user has now been told about
+		if err := j.alertRepo.UpdatePriceSeen(drop.WishlistID, drop.Price); err != nil {
+			log.Printf("⚠️ Failed to update wishlist %d price: %v", drop.WishlistID, err)
+		}
+	}
+	return sent
+}
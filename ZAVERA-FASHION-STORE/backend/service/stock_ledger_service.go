@@ -0,0 +1,60 @@
+package service
+
+import (
+	"log"
+	"zavera/models"
+	"zavera/repository"
+)
+
+// maxBalanceBreaks caps how many broken balance_after entries a reconciliation reports
+const maxBalanceBreaks = 500
+
+type StockLedgerService interface {
+	ListLedger(filter models.StockLedgerFilter, limit, offset int) ([]models.StockLedgerEntry, int, error)
+
+	// Reconcile compares on-hand counts with the ledger. With apply, warehouse_stock, variant totals
+	// and the product mirror are rebuilt from the ledger; the ledger itself is never changed.
+	Reconcile(apply bool) (*models.StockReconciliation, error)
+}
+
+type stockLedgerService struct {
+	stockRepo repository.StockRepository
+}
+
+func NewStockLedgerService(stockRepo repository.StockRepository) StockLedgerService {
+	return &stockLedgerService{stockRepo: stockRepo}
+}
+
+func (s *stockLedgerService) ListLedger(filter models.StockLedgerFilter, limit, offset int) ([]models.StockLedgerEntry, int, error) {
+	return s.stockRepo.ListLedger(filter, limit, offset)
+}
+
+func (s *stockLedgerService) Reconcile(apply bool) (*models.StockReconciliation, error) {
+	discrepancies, err := s.stockRepo.FindDiscrepancies()
+	if err != nil {
+		return nil, err
+	}
+	breaks, err := s.stockRepo.FindBalanceBreaks(maxBalanceBreaks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.StockReconciliation{
+		Discrepancies: discrepancies,
+		BalanceBreaks: breaks,
+	}
+	if !apply {
+		return result, nil
+	}
+
+	rows, products, err := s.stockRepo.RebuildOnHand()
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	result.RowsRebuilt = rows
+	result.ProductsUpdated = products
+
+	log.Printf("📒 Stock rebuilt from ledger: %d warehouse rows, %d product counters", rows, products)
+	return result, nil
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrStockTakeNotFound = errors.New("stock take not found")
+	ErrStockTakeOpen     = errors.New("warehouse already has an open stock take")
+	ErrInvalidStockTake  = errors.New("invalid stock take")
+	// ErrStockTakeStatus is returned for counts, approvals and posts the session's status does not allow
+	ErrStockTakeStatus = errors.New("stock take status does not allow this")
+)
+
+// StockTakeService runs cycle counts: start (freeze expected quantities), count, review, post.
+// Posting writes one ADJUSTMENT ledger entry and one MANUAL_ADJUSTMENT audit entry per variance.
+type StockTakeService interface {
+	Start(req dto.StartStockTakeRequest, adminEmail string) (*models.StockTakeReport, error)
+	List(warehouseID int, status models.StockTakeStatus, limit, offset int) ([]models.StockTake, int, error)
+	GetReport(id int, variancesOnly bool) (*models.StockTakeReport, error)
+
+	RecordCounts(id int, req dto.StockTakeCountsRequest, adminEmail string) ([]models.StockTakeLine, error)
+	Scan(id int, req dto.StockTakeScanRequest, adminEmail string) (*models.StockTakeLine, error)
+	SetApproval(id int, req dto.StockTakeApprovalRequest) (int, error)
+	UpdateStatus(id int, status models.StockTakeStatus, adminEmail string) (*models.StockTake, error)
+	Post(id int, adminEmail string) (*models.StockTakeReport, error)
+}
+
+type stockTakeService struct {
+	db            *sql.DB
+	takeRepo      repository.StockTakeRepository
+	warehouseRepo repository.WarehouseRepository
+	stockRepo     repository.StockRepository
+	auditRepo     repository.AdminAuditRepository
+}
+
+func NewStockTakeService(
+	db *sql.DB,
+	takeRepo repository.StockTakeRepository,
+	warehouseRepo repository.WarehouseRepository,
+	stockRepo repository.StockRepository,
+	auditRepo repository.AdminAuditRepository,
+) StockTakeService {
+	return &stockTakeService{
+		db:            db,
+		takeRepo:      takeRepo,
+		warehouseRepo: warehouseRepo,
+		stockRepo:     stockRepo,
+		auditRepo:     auditRepo,
+	}
+}
+
+func (s *stockTakeService) Start(req dto.StartStockTakeRequest, adminEmail string) (*models.StockTakeReport, error) {
+	var warehouse *models.Warehouse
+	var err error
+	if req.WarehouseID > 0 {
+		warehouse, err = s.warehouseRepo.FindByID(req.WarehouseID)
+	} else {
+		warehouse, err = s.warehouseRepo.FindDefault()
+	}
+	if err == repository.ErrWarehouseNotFound {
+		return nil, ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !warehouse.IsActive {
+		return nil, fmt.Errorf("%w: warehouse %s is inactive", ErrInvalidStockTake, warehouse.Code)
+	}
+
+	take := &models.StockTake{
+		WarehouseID: warehouse.ID,
+		Notes:       strings.TrimSpace(req.Notes),
+		CreatedBy:   adminEmail,
+	}
+	if err := s.takeRepo.Create(take, req.ProductIDs); err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	report, err := s.GetReport(take.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("📋 Stock take %s started at %s by %s (%d variants)", take.Code, warehouse.Code, adminEmail, report.Summary.Lines)
+	return report, nil
+}
+
+func (s *stockTakeService) List(warehouseID int, status models.StockTakeStatus, limit, offset int) ([]models.StockTake, int, error) {
+	return s.takeRepo.List(warehouseID, status, limit, offset)
+}
+
+// GetReport returns the session with its variance report. The summary always covers every
+// line; variancesOnly only trims the lines returned.
+func (s *stockTakeService) GetReport(id int, variancesOnly bool) (*models.StockTakeReport, error) {
+	take, err := s.takeRepo.FindByID(id)
+	if err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	lines, err := s.takeRepo.FindLines(id, false)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.StockTakeReport{StockTake: *take, Summary: models.SummarizeStockTake(lines), Lines: lines}
+
+	if variancesOnly {
+		report.Lines = []models.StockTakeLine{}
+		for _, line := range lines {
+			if line.Variance != nil && *line.Variance != 0 {
+				report.Lines = append(report.Lines, line)
+			}
+		}
+	}
+	return report, nil
+}
+
+func (s *stockTakeService) RecordCounts(id int, req dto.StockTakeCountsRequest, adminEmail string) ([]models.StockTakeLine, error) {
+	counts := make([]models.StockTakeCount, 0, len(req.Counts))
+	for _, c := range req.Counts {
+		if c.VariantID == 0 && strings.TrimSpace(c.Barcode) == "" && strings.TrimSpace(c.SKU) == "" {
+			return nil, fmt.Errorf("%w: each count needs a variant_id, barcode or sku", ErrInvalidStockTake)
+		}
+		counts = append(counts, models.StockTakeCount{
+			VariantID: c.VariantID,
+			Barcode:   strings.TrimSpace(c.Barcode),
+			SKU:       strings.TrimSpace(c.SKU),
+			Quantity:  c.CountedQuantity,
+		})
+	}
+	return s.recordCounts(id, counts, adminEmail)
+}
+
+// Scan adds one scanned barcode (Quantity units, default 1) to its line's count
+func (s *stockTakeService) Scan(id int, req dto.StockTakeScanRequest, adminEmail string) (*models.StockTakeLine, error) {
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	lines, err := s.recordCounts(id, []models.StockTakeCount{{
+		Barcode:  strings.TrimSpace(req.Barcode),
+		Quantity: quantity,
+		Add:      true,
+	}}, adminEmail)
+	if err != nil {
+		return nil, err
+	}
+	return &lines[0], nil
+}
+
+func (s *stockTakeService) recordCounts(id int, counts []models.StockTakeCount, adminEmail string) ([]models.StockTakeLine, error) {
+	lines, err := s.takeRepo.RecordCounts(id, counts, adminEmail)
+	if err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+	for i := range lines {
+		lines[i].Calculate()
+	}
+	return lines, nil
+}
+
+func (s *stockTakeService) SetApproval(id int, req dto.StockTakeApprovalRequest) (int, error) {
+	updated, err := s.takeRepo.SetApproval(id, req.VariantIDs, *req.Approved)
+	if err != nil {
+		return 0, s.mapRepositoryError(err)
+	}
+	return updated, nil
+}
+
+func (s *stockTakeService) UpdateStatus(id int, status models.StockTakeStatus, adminEmail string) (*models.StockTake, error) {
+	take, err := s.takeRepo.FindByID(id)
+	if err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+	if !take.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: %s stock takes cannot become %s", ErrStockTakeStatus, take.Status, status)
+	}
+
+	if err := s.takeRepo.UpdateStatus(id, take.Status, status); err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	log.Printf("📋 Stock take %s %s -> %s by %s", take.Code, take.Status, status, adminEmail)
+	return s.takeRepo.FindByID(id)
+}
+
+// Post applies every approved, counted variance to the warehouse's on-hand stock. Variances are
+// deltas, so sales made since the snapshot stay on the books. All or nothing.
+func (s *stockTakeService) Post(id int, adminEmail string) (*models.StockTakeReport, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	take, err := s.takeRepo.LockForPostingTx(tx, id)
+	if err == repository.ErrStockTakeConflict {
+		return nil, fmt.Errorf("%w: submit the count for review before posting", ErrStockTakeStatus)
+	}
+	if err != nil {
+		return nil, s.mapRepositoryError(err)
+	}
+
+	lines, err := s.takeRepo.FindLines(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	posted := 0
+	for i := range lines {
+		line := &lines[i]
+		if !line.NeedsAdjustment() {
+			continue
+		}
+		line.Calculate()
+
+		entry := &models.StockLedgerEntry{
+			VariantID:    line.VariantID,
+			WarehouseID:  &take.WarehouseID,
+			MovementType: models.StockMovementAdjustment,
+			Quantity:     *line.Variance,
+			Reference:    take.Code,
+			Notes:        fmt.Sprintf("Stock take: expected %d, counted %d", line.ExpectedQuantity, *line.CountedQuantity),
+			Actor:        adminEmail,
+		}
+		err := s.stockRepo.ApplyMovementTx(tx, entry)
+		if err == repository.ErrInsufficientStock {
+			return nil, fmt.Errorf("%w: %s would go below zero at %s; recount it or leave it unapproved",
+				ErrInvalidStockTake, line.SKU, take.WarehouseCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.auditRepo.CreateWithTx(tx, stockTakeAuditLog(take, line, entry, adminEmail)); err != nil {
+			return nil, err
+		}
+		if err := s.takeRepo.MarkLinePostedTx(tx, line.ID, entry.ID); err != nil {
+			return nil, err
+		}
+		posted++
+	}
+
+	if err := s.takeRepo.MarkPostedTx(tx, id, adminEmail); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📋 Stock take %s posted by %s: %d adjustments", take.Code, adminEmail, posted)
+	return s.GetReport(id, true)
+}
+
+func stockTakeAuditLog(take *models.StockTake, line *models.StockTakeLine, entry *models.StockLedgerEntry, adminEmail string) *models.AdminAuditLog {
+	metadata := map[string]any{
+		"stock_take_id":     take.ID,
+		"stock_take_code":   take.Code,
+		"warehouse_id":      take.WarehouseID,
+		"expected_quantity": line.ExpectedQuantity,
+		"counted_quantity":  *line.CountedQuantity,
+		"variance":          *line.Variance,
+		"ledger_id":         entry.ID,
+	}
+	if line.ValueImpact != nil {
+		metadata["unit_cost"] = *line.UnitCost
+		metadata["value_impact"] = *line.ValueImpact
+	}
+
+	return &models.AdminAuditLog{
+		AdminEmail:     adminEmail,
+		ActionType:     models.AdminActionManualAdjustment,
+		ActionDetail:   fmt.Sprintf("Stock take %s: %s at %s adjusted by %+d", take.Code, line.SKU, take.WarehouseCode, *line.Variance),
+		TargetType:     "product_variant",
+		TargetID:       line.VariantID,
+		TargetCode:     line.SKU,
+		StateBefore:    map[string]any{"on_hand": entry.BalanceAfter - entry.Quantity},
+		StateAfter:     map[string]any{"on_hand": entry.BalanceAfter},
+		Success:        true,
+		IdempotencyKey: fmt.Sprintf("stock-take-%d-%d", take.ID, line.VariantID),
+		Metadata:       metadata,
+	}
+}
+
+func (s *stockTakeService) mapRepositoryError(err error) error {
+	switch {
+	case err == repository.ErrStockTakeNotFound:
+		return ErrStockTakeNotFound
+	case err == repository.ErrStockTakeOpen:
+		return ErrStockTakeOpen
+	case err == repository.ErrStockTakeConflict:
+		return fmt.Errorf("%w: counts are taken while COUNTING and approved while in REVIEW", ErrStockTakeStatus)
+	case errors.Is(err, repository.ErrStockTakeLineNotFound):
+		return fmt.Errorf("%w: %v", ErrInvalidStockTake, err)
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrStoreCreditRequiresLogin = errors.New("sign in to pay with store credit")
+	ErrInsufficientStoreCredit  = errors.New("insufficient store credit")
+	ErrGiftCardNotFound         = errors.New("gift card not found")
+	ErrGiftCardUnavailable      = errors.New("gift card cannot be redeemed")
+	ErrInvalidAdjustment        = errors.New("invalid store credit adjustment")
+	ErrGiftCardProduct          = errors.New("product not found or is a bundle")
+)
+
+// storeCreditEntriesShown is how many ledger entries the wallet shows
+const storeCreditEntriesShown = 50
+
+// StoreCreditService manages customers' store credit: gift cards redeemed into it, spending it
+// at checkout and admin adjustments. Refunds credit it through RefundService.
+type StoreCreditService interface {
+	GetWallet(userID int) (*dto.StoreCreditResponse, error)
+	RedeemGiftCard(userID int, code string) (*dto.RedeemGiftCardResponse, error)
+
+	// QuoteCheckout works out how much of an order total the customer's store credit pays;
+	// the balance is only debited when the order is created
+	QuoteCheckout(userID *int, total, requested float64) (float64, error)
+	// OrderPaid tells the admin dashboard and the customer about an order with nothing left to
+	// pay after store credit and discounts, which the order repository marks paid when it is created
+	OrderPaid(order *models.Order)
+
+	AdjustBalance(userID int, req dto.AdjustStoreCreditRequest, adminEmail string) (*models.StoreCreditEntry, error)
+	ListGiftCards(status string, page, pageSize int) (*dto.GiftCardListResponse, error)
+	VoidGiftCard(id int) error
+	SetGiftCardProduct(productID int, isGiftCard bool, adminEmail string) error
+}
+
+type storeCreditService struct {
+	storeCreditRepo repository.StoreCreditRepository
+	orderRepo       repository.OrderRepository
+	emailService    EmailService
+}
+
+func NewStoreCreditService(
+	storeCreditRepo repository.StoreCreditRepository,
+	orderRepo repository.OrderRepository,
+	emailService EmailService,
+) StoreCreditService {
+	return &storeCreditService{
+		storeCreditRepo: storeCreditRepo,
+		orderRepo:       orderRepo,
+		emailService:    emailService,
+	}
+}
+
+func (s *storeCreditService) GetWallet(userID int) (*dto.StoreCreditResponse, error) {
+	balance, err := s.storeCreditRepo.GetBalance(userID)
+	if err == repository.ErrUserNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.storeCreditRepo.ListEntries(userID, storeCreditEntriesShown)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.StoreCreditResponse{Balance: balance, Entries: entries}, nil
+}
+
+func (s *storeCreditService) RedeemGiftCard(userID int, code string) (*dto.RedeemGiftCardResponse, error) {
+	card, entry, err := s.storeCreditRepo.RedeemGiftCard(models.NormalizeGiftCardCode(code), userID)
+	switch err {
+	case nil:
+	case repository.ErrGiftCardNotFound:
+		return nil, ErrGiftCardNotFound
+	case repository.ErrGiftCardUnavailable, repository.ErrGiftCardExpired:
+		return nil, fmt.Errorf("%w: %v", ErrGiftCardUnavailable, err)
+	case repository.ErrUserNotFound:
+		return nil, ErrUserNotFound
+	default:
+		return nil, err
+	}
+
+	log.Printf("🎁 Gift card %d redeemed by user %d: +%.0f store credit", card.ID, userID, card.Amount)
+	return &dto.RedeemGiftCardResponse{Amount: card.Amount, Balance: entry.BalanceAfter}, nil
+}
+
+func (s *storeCreditService) QuoteCheckout(userID *int, total, requested float64) (float64, error) {
+	if userID == nil {
+		return 0, ErrStoreCreditRequiresLogin
+	}
+
+	balance, err := s.storeCreditRepo.GetBalance(*userID)
+	if err == repository.ErrUserNotFound {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if balance <= 0 {
+		return 0, ErrInsufficientStoreCredit
+	}
+	return models.StoreCreditToApply(total, balance, requested), nil
+}
+
+func (s *storeCreditService) OrderPaid(order *models.Order) {
+	paymentMethod := "store_credit"
+	if order.StoreCreditAmount == 0 {
+		paymentMethod = "discount"
+	}
+	log.Printf("💳 Order %s paid with %.0f store credit", order.OrderCode, order.StoreCreditAmount)
+	NotifyPaymentReceived(order.OrderCode, paymentMethod, order.TotalAmount)
+
+	if s.emailService != nil {
+		go func() {
+			paid, err := s.orderRepo.FindByID(order.ID)
+			if err != nil {
+				log.Printf("⚠️ Failed to reload order for email: %v", err)
+				return
+			}
+			if err := s.emailService.SendPaymentSuccess(paid, paymentMethod); err != nil {
+				log.Printf("⚠️ Failed to send payment success email: %v", err)
+			}
+		}()
+	}
+}
+
+func (s *storeCreditService) AdjustBalance(userID int, req dto.AdjustStoreCreditRequest, adminEmail string) (*models.StoreCreditEntry, error) {
+	if req.Amount == 0 {
+		return nil, fmt.Errorf("%w: amount must not be zero", ErrInvalidAdjustment)
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: a note is required", ErrInvalidAdjustment)
+	}
+
+	entry := &models.StoreCreditEntry{
+		UserID:    userID,
+		EntryType: models.StoreCreditEntryAdjustment,
+		Amount:    req.Amount,
+		Note:      note,
+		CreatedBy: adminEmail,
+	}
+	switch err := s.storeCreditRepo.AddEntry(entry); err {
+	case nil:
+	case repository.ErrUserNotFound:
+		return nil, ErrUserNotFound
+	case repository.ErrInsufficientStoreCredit:
+		return nil, fmt.Errorf("%w: the balance cannot go below zero", ErrInvalidAdjustment)
+	default:
+		return nil, err
+	}
+
+	log.Printf("💳 Store credit of user %d adjusted by %.0f by %s: %s", userID, req.Amount, adminEmail, note)
+	return entry, nil
+}
+
+func (s *storeCreditService) ListGiftCards(status string, page, pageSize int) (*dto.GiftCardListResponse, error) {
+	cards, total, err := s.storeCreditRepo.ListGiftCards(status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	// Unredeemed codes are as good as cash
+	for i := range cards {
+		cards[i].Code = models.MaskGiftCardCode(cards[i].Code)
+	}
+	return &dto.GiftCardListResponse{GiftCards: cards, TotalCount: total, Page: page, PageSize: pageSize}, nil
+}
+
+func (s *storeCreditService) VoidGiftCard(id int) error {
+	switch err := s.storeCreditRepo.VoidGiftCard(id); err {
+	case repository.ErrGiftCardNotFound:
+		return ErrGiftCardNotFound
+	case repository.ErrGiftCardUnavailable:
+		return fmt.Errorf("%w: %v", ErrGiftCardUnavailable, err)
+	default:
+		return err
+	}
+}
+
+func (s *storeCreditService) SetGiftCardProduct(productID int, isGiftCard bool, adminEmail string) error {
+	err := s.storeCreditRepo.SetGiftCardProduct(productID, isGiftCard)
+	if err == repository.ErrGiftCardProduct {
+		return ErrGiftCardProduct
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🎁 Product %d gift card=%t set by %s", productID, isGiftCard, adminEmail)
+	return nil
+}
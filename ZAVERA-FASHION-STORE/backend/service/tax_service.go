@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrTaxSettingNotFound = errors.New("tax setting not found")
+	ErrInvalidTaxSetting  = errors.New("invalid tax setting")
+)
+
+// TaxService manages the PPN settings and works out the tax of new orders
+type TaxService interface {
+	GetSettings() (*dto.TaxSettingsResponse, error)
+	ScheduleSetting(req dto.ScheduleTaxSettingRequest, adminEmail string) (*models.TaxSetting, error)
+	// DeleteSetting cancels a scheduled setting; settings in effect are kept for past orders
+	DeleteSetting(id int, adminEmail string) error
+	ListExemptProducts() ([]models.TaxExemptProduct, error)
+	SetProductExempt(productID int, exempt bool, adminEmail string) error
+
+	// ApplyTax sets the PPN of the order and its lines from the setting in effect now. The
+	// lines' discounts must be set; the order total is updated to match.
+	ApplyTax(order *models.Order, items []models.OrderItem) error
+}
+
+type taxService struct {
+	taxRepo repository.TaxRepository
+}
+
+func NewTaxService(taxRepo repository.TaxRepository) TaxService {
+	return &taxService{taxRepo: taxRepo}
+}
+
+func (s *taxService) GetSettings() (*dto.TaxSettingsResponse, error) {
+	settings, err := s.taxRepo.ListSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TaxSettingsResponse{
+		Current:  models.TaxSettingAt(settings, time.Now()),
+		Settings: settings,
+	}, nil
+}
+
+func (s *taxService) ScheduleSetting(req dto.ScheduleTaxSettingRequest, adminEmail string) (*models.TaxSetting, error) {
+	if !req.EffectiveFrom.After(time.Now()) {
+		return nil, fmt.Errorf("%w: a new rate must start in the future", ErrInvalidTaxSetting)
+	}
+
+	setting := &models.TaxSetting{
+		Rate:             *req.Rate,
+		PricesIncludeTax: *req.PricesIncludeTax,
+		EffectiveFrom:    req.EffectiveFrom,
+		Notes:            strings.TrimSpace(req.Notes),
+		CreatedBy:        adminEmail,
+	}
+	if err := s.taxRepo.CreateSetting(setting); err != nil {
+		if err == repository.ErrTaxSettingDateTaken {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTaxSetting, err)
+		}
+		return nil, err
+	}
+
+	log.Printf("🧾 PPN %.2f%% (prices include tax: %v) scheduled from %s by %s",
+		setting.Rate, setting.PricesIncludeTax, setting.EffectiveFrom.Format("02 Jan 2006 15:04"), adminEmail)
+	return setting, nil
+}
+
+func (s *taxService) DeleteSetting(id int, adminEmail string) error {
+	if err := s.taxRepo.DeleteSetting(id); err != nil {
+		if err == repository.ErrTaxSettingNotFound {
+			return ErrTaxSettingNotFound
+		}
+		return err
+	}
+
+	log.Printf("🧾 Scheduled tax setting %d deleted by %s", id, adminEmail)
+	return nil
+}
+
+func (s *taxService) ListExemptProducts() ([]models.TaxExemptProduct, error) {
+	return s.taxRepo.ListExemptProducts()
+}
+
+func (s *taxService) SetProductExempt(productID int, exempt bool, adminEmail string) error {
+	if err := s.taxRepo.SetProductExempt(productID, exempt); err != nil {
+		if err == repository.ErrTaxProductNotFound {
+			return ErrProductNotFound
+		}
+		return err
+	}
+
+	log.Printf("🧾 Product %d tax exempt set to %v by %s", productID, exempt, adminEmail)
+	return nil
+}
+
+func (s *taxService) ApplyTax(order *models.Order, items []models.OrderItem) error {
+	settings, err := s.taxRepo.ListSettings()
+	if err != nil {
+		return err
+	}
+
+	setting := models.TaxSettingAt(settings, time.Now())
+	if setting == nil {
+		// No PPN before the first setting takes effect
+		setting = &models.TaxSetting{PricesIncludeTax: true}
+	}
+
+	productIDs := make([]int, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+	exempt, err := s.taxRepo.ExemptProductIDs(productIDs)
+	if err != nil {
+		return err
+	}
+
+	order.TaxRate = setting.Rate
+	order.PricesIncludeTax = setting.PricesIncludeTax
+	order.Tax = setting.ApplyTax(items, exempt)
+	order.TotalAmount = order.ExpectedTotal()
+	return nil
+}
@@ -20,9 +20,10 @@ func NewTrackingJobRunner(
 	cartRepo repository.CartRepository,
 	productRepo repository.ProductRepository,
 	orderRepo repository.OrderRepository,
+	warehouseService WarehouseService,
 ) *TrackingJobRunner {
 	return &TrackingJobRunner{
-		shippingService: NewShippingService(shippingRepo, cartRepo, productRepo, orderRepo),
+		shippingService: NewShippingService(shippingRepo, cartRepo, productRepo, orderRepo, warehouseService),
 		interval:        30 * time.Minute, // Run every 30 minutes
 		stopChan:        make(chan struct{}),
 		running:         false,
@@ -1,21 +1,27 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"zavera/models"
 	"zavera/repository"
 )
 
+var ErrInvalidVariantOptions = errors.New("invalid variant options")
+
 type VariantService struct {
-	variantRepo *repository.VariantRepository
-	productRepo repository.ProductRepository
+	variantRepo   *repository.VariantRepository
+	productRepo   repository.ProductRepository
+	sizeChartRepo repository.SizeChartRepository
 }
 
-func NewVariantService(variantRepo *repository.VariantRepository, productRepo repository.ProductRepository) *VariantService {
+func NewVariantService(variantRepo *repository.VariantRepository, productRepo repository.ProductRepository, sizeChartRepo repository.SizeChartRepository) *VariantService {
 	return &VariantService{
-		variantRepo: variantRepo,
-		productRepo: productRepo,
+		variantRepo:   variantRepo,
+		productRepo:   productRepo,
+		sizeChartRepo: sizeChartRepo,
 	}
 }
 
@@ -39,6 +45,10 @@ func (s *VariantService) CreateVariant(variant *models.ProductVariant) error {
 		variant.VariantName = s.generateVariantName(variant)
 	}
 
+	if err := validateInventoryPolicy(variant); err != nil {
+		return err
+	}
+
 	return s.variantRepo.Create(variant)
 }
 
@@ -53,9 +63,33 @@ func (s *VariantService) UpdateVariant(variant *models.ProductVariant) error {
 		return fmt.Errorf("cannot change SKU of existing variant")
 	}
 
+	if err := validateInventoryPolicy(variant); err != nil {
+		return err
+	}
+
 	return s.variantRepo.Update(variant)
 }
 
+// validateInventoryPolicy defaults the policy to STOCK and drops the backorder settings of
+// variants that only sell what is on hand
+func validateInventoryPolicy(variant *models.ProductVariant) error {
+	if variant.InventoryPolicy == "" {
+		variant.InventoryPolicy = models.InventoryPolicyStock
+	}
+	if !variant.InventoryPolicy.IsValid() {
+		return fmt.Errorf("invalid inventory policy: %s", variant.InventoryPolicy)
+	}
+	if !variant.InventoryPolicy.AllowsBackorder() {
+		variant.BackorderLimit = nil
+		variant.ExpectedShipDate = nil
+		return nil
+	}
+	if variant.BackorderLimit != nil && *variant.BackorderLimit < 0 {
+		return fmt.Errorf("backorder limit cannot be negative")
+	}
+	return nil
+}
+
 func (s *VariantService) GetVariant(id int) (*models.ProductVariant, error) {
 	return s.variantRepo.GetByID(id)
 }
@@ -72,64 +106,121 @@ func (s *VariantService) DeleteVariant(id int) error {
 	return s.variantRepo.Delete(id)
 }
 
-func (s *VariantService) BulkGenerateVariants(productID int, sizes, colors []string, basePrice float64, stockPerVariant, weight, length, width, height int) error {
+// BulkGenerateVariants creates one variant per combination of the option dimensions (e.g.
+// size x color x length). Dimensions must be active variant attributes; size, color and the
+// other column options are stored in their columns, the rest in custom attributes.
+func (s *VariantService) BulkGenerateVariants(productID int, dimensions []models.VariantOptionDimension, basePrice float64, stockPerVariant, weight, length, width, height int) error {
 	product, err := s.productRepo.FindByID(productID)
 	if err != nil {
 		return fmt.Errorf("product not found")
 	}
 
-	variants := []models.ProductVariant{}
-	position := 0
-
-	for _, size := range sizes {
-		for _, color := range colors {
-			sku := fmt.Sprintf("%s-%s-%s", s.sanitizeSKU(product.Name), s.sanitizeSKU(size), s.sanitizeSKU(color))
-			variantName := fmt.Sprintf("%s - %s", size, color)
-
-			variant := models.ProductVariant{
-				ProductID:         productID,
-				SKU:               sku,
-				VariantName:       variantName,
-				Size:              &size,
-				Color:             &color,
-				StockQuantity:     stockPerVariant,
-				LowStockThreshold: 5,
-				IsActive:          true,
-				IsDefault:         position == 0,
-				Position:          position,
-			}
+	dimensions, err = s.validateDimensions(dimensions)
+	if err != nil {
+		return err
+	}
 
-			if basePrice > 0 {
-				variant.Price = &basePrice
-			}
+	variants := []models.ProductVariant{}
+	for position, combination := range models.CartesianOptions(dimensions) {
+		skuParts := []string{s.sanitizeSKU(product.Name)}
+		nameParts := []string{}
+
+		variant := models.ProductVariant{
+			ProductID:         productID,
+			StockQuantity:     stockPerVariant,
+			LowStockThreshold: 5,
+			IsActive:          true,
+			IsDefault:         position == 0,
+			Position:          position,
+			InventoryPolicy:   models.InventoryPolicyStock,
+		}
+		for _, d := range dimensions {
+			value := combination[d.Name]
+			variant.SetOption(d.Name, value)
+			skuParts = append(skuParts, s.sanitizeSKU(value))
+			nameParts = append(nameParts, value)
+		}
+		variant.SKU = strings.Join(skuParts, "-")
+		variant.VariantName = strings.Join(nameParts, " - ")
 
-			// Apply default dimensions if provided
-			// Create new variables to avoid pointer issues
-			if weight > 0 {
-				w := weight
-				variant.WeightGrams = &w
-			}
-			if length > 0 {
-				l := length
-				variant.LengthCm = &l
-			}
-			if width > 0 {
-				w := width
-				variant.WidthCm = &w
-			}
-			if height > 0 {
-				h := height
-				variant.HeightCm = &h
-			}
+		if basePrice > 0 {
+			variant.Price = &basePrice
+		}
 
-			variants = append(variants, variant)
-			position++
+		// Apply default dimensions if provided
+		// Create new variables to avoid pointer issues
+		if weight > 0 {
+			w := weight
+			variant.WeightGrams = &w
+		}
+		if length > 0 {
+			l := length
+			variant.LengthCm = &l
+		}
+		if width > 0 {
+			w := width
+			variant.WidthCm = &w
+		}
+		if height > 0 {
+			h := height
+			variant.HeightCm = &h
 		}
+
+		variants = append(variants, variant)
 	}
 
 	return s.variantRepo.BulkCreate(variants)
 }
 
+// validateDimensions normalises option names, drops blank and repeated values, and checks the
+// names against the active variant attributes and the size of the matrix
+func (s *VariantService) validateDimensions(dimensions []models.VariantOptionDimension) ([]models.VariantOptionDimension, error) {
+	attributes, err := s.variantRepo.GetAttributes()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(attributes))
+	for _, attr := range attributes {
+		known[models.NormalizeOptionName(attr.Name)] = true
+	}
+
+	result := make([]models.VariantOptionDimension, 0, len(dimensions))
+	names := make(map[string]bool)
+	for _, d := range dimensions {
+		name := models.NormalizeOptionName(d.Name)
+		if !known[name] {
+			return nil, fmt.Errorf("%w: unknown variant attribute %q", ErrInvalidVariantOptions, d.Name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidVariantOptions, name)
+		}
+		names[name] = true
+
+		values := []string{}
+		seen := make(map[string]bool)
+		for _, value := range d.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[strings.ToUpper(value)] {
+				continue
+			}
+			seen[strings.ToUpper(value)] = true
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: %s needs at least one value", ErrInvalidVariantOptions, name)
+		}
+		result = append(result, models.VariantOptionDimension{Name: name, Values: values})
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one option is required", ErrInvalidVariantOptions)
+	}
+	if count := models.CombinationCount(result); count > models.MaxVariantCombinations {
+		return nil, fmt.Errorf("%w: more than %d combinations", ErrInvalidVariantOptions, models.MaxVariantCombinations)
+	}
+	return result, nil
+}
+
 func (s *VariantService) AddVariantImage(image *models.VariantImage) error {
 	// Validate variant exists
 	_, err := s.variantRepo.GetByID(image.VariantID)
@@ -162,15 +253,41 @@ func (s *VariantService) ReorderImages(variantID int, imageIDs []int) error {
 	return nil
 }
 
-func (s *VariantService) CheckAvailability(variantID, quantity int) (bool, error) {
-	available, err := s.variantRepo.GetAvailableStock(variantID)
+// CheckAvailability reports whether quantity can be ordered, counting what pre-order and
+// backorder variants may sell beyond their stock
+func (s *VariantService) CheckAvailability(variantID, quantity int) (*models.VariantAvailability, error) {
+	variant, err := s.variantRepo.GetByID(variantID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return available >= quantity, nil
+	available := 0
+	if variant.AvailableStock != nil {
+		available = *variant.AvailableStock
+	}
+
+	outstanding := 0
+	if variant.InventoryPolicy.AllowsBackorder() {
+		if outstanding, err = s.variantRepo.GetBackorderedQuantity(variantID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, backordered, ok := models.SplitBackorder(variant.InventoryPolicy, quantity, available, outstanding, variant.BackorderLimit)
+	result := &models.VariantAvailability{
+		Available:         ok,
+		AvailableStock:    available,
+		BackorderQuantity: backordered,
+		InventoryPolicy:   variant.InventoryPolicy,
+	}
+	if backordered > 0 {
+		result.ExpectedShipDate = variant.ExpectedShipDate
+	}
+	return result, nil
 }
 
-func (s *VariantService) ReserveStock(variantID, customerID int, sessionID string, quantity int) (int, error) {
+// ReserveStock holds stock at one warehouse; a nil warehouseID lets the database pick
+// the active warehouse with the most available stock
+func (s *VariantService) ReserveStock(variantID, customerID int, sessionID string, quantity int, warehouseID *int) (int, error) {
 	// Check availability first
 	available, err := s.variantRepo.GetAvailableStock(variantID)
 	if err != nil {
@@ -182,7 +299,7 @@ func (s *VariantService) ReserveStock(variantID, customerID int, sessionID strin
 	}
 
 	// Reserve with 15 minute timeout
-	return s.variantRepo.ReserveStock(variantID, customerID, sessionID, quantity, 15)
+	return s.variantRepo.ReserveStock(variantID, customerID, sessionID, quantity, 15, warehouseID)
 }
 
 func (s *VariantService) CompleteReservation(reservationID, orderID int) error {
@@ -193,15 +310,19 @@ func (s *VariantService) CancelReservation(reservationID int) error {
 	return s.variantRepo.CancelReservation(reservationID)
 }
 
-func (s *VariantService) UpdateStock(variantID, quantity int) error {
+// UpdateStock and AdjustStock are recorded on the stock ledger as adjustments by adminEmail
+func (s *VariantService) UpdateStock(variantID, quantity int, reason, adminEmail string) error {
 	if quantity < 0 {
 		return fmt.Errorf("stock quantity cannot be negative")
 	}
-	return s.variantRepo.UpdateStock(variantID, quantity)
+	return s.variantRepo.UpdateStock(variantID, quantity, adminEmail, reason)
 }
 
-func (s *VariantService) AdjustStock(variantID, delta int) error {
-	return s.variantRepo.AdjustStock(variantID, delta)
+func (s *VariantService) AdjustStock(variantID, delta int, reason, adminEmail string) error {
+	if delta == 0 {
+		return fmt.Errorf("stock adjustment cannot be zero")
+	}
+	return s.variantRepo.AdjustStock(variantID, delta, adminEmail, reason)
 }
 
 func (s *VariantService) GetLowStockVariants() ([]models.LowStockVariant, error) {
@@ -216,47 +337,102 @@ func (s *VariantService) GetVariantAttributes() ([]models.VariantAttribute, erro
 	return s.variantRepo.GetAttributes()
 }
 
+// reservedOptionNames are keys of the options response that an attribute cannot take
+var reservedOptionNames = map[string]bool{"dimensions": true, "unavailable": true, "size_chart": true}
+
+// CreateVariantAttribute adds an option variants can differ on; its values are stored in the
+// variants' custom attributes under name
+func (s *VariantService) CreateVariantAttribute(name, displayName, attrType string, options []string, sortOrder int) (*models.VariantAttribute, error) {
+	name = models.NormalizeOptionName(name)
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' {
+			return nil, fmt.Errorf("%w: attribute name may only use letters, digits and underscores", ErrInvalidVariantOptions)
+		}
+	}
+	if name == "" || reservedOptionNames[name] {
+		return nil, fmt.Errorf("%w: %q cannot be used as an attribute name", ErrInvalidVariantOptions, name)
+	}
+
+	if options == nil {
+		options = []string{}
+	}
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	attr := &models.VariantAttribute{
+		Name:        name,
+		DisplayName: strings.TrimSpace(displayName),
+		Type:        attrType,
+		Options:     encoded,
+		SortOrder:   sortOrder,
+	}
+	created, err := s.variantRepo.CreateAttribute(attr)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: attribute %s already exists", ErrInvalidVariantOptions, name)
+	}
+	return attr, nil
+}
+
 func (s *VariantService) GetProductWithVariants(productID int) (*models.ProductWithVariants, error) {
 	return s.variantRepo.GetProductWithVariants(productID)
 }
 
-func (s *VariantService) GetAvailableOptions(productID int) (map[string][]string, error) {
-	options := make(map[string][]string)
-
-	fields := []string{"size", "color", "material", "pattern", "fit", "sleeve"}
-	for _, field := range fields {
-		values, err := s.variantRepo.GetUniqueValues(productID, field)
-		if err == nil && len(values) > 0 {
-			options[field] = values
-		}
+// GetAvailableOptions returns the option dimensions of the product's active variants and the
+// combinations that cannot be bought, for greying out the option picker
+func (s *VariantService) GetAvailableOptions(productID int) (*models.VariantOptionMatrix, error) {
+	variants, err := s.variantRepo.GetByProductID(productID)
+	if err != nil {
+		return nil, err
 	}
 
-	return options, nil
+	matrix := models.BuildOptionMatrix(variants, s.optionOrder())
+	return &matrix, nil
 }
 
-func (s *VariantService) FindVariantByAttributes(productID int, size, color string) (*models.ProductVariant, error) {
-	filters := map[string]interface{}{
-		"product_id": productID,
-		"is_active":  true,
+// optionOrder returns option names in the display order of the variant attributes
+func (s *VariantService) optionOrder() []string {
+	attributes, err := s.variantRepo.GetAttributes()
+	if err != nil || len(attributes) == 0 {
+		return models.VariantOptionColumns
 	}
 
-	if size != "" {
-		filters["size"] = size
+	order := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		order = append(order, attr.Name)
 	}
-	if color != "" {
-		filters["color"] = color
+	return order
+}
+
+// GetSizeChart returns the size chart shown next to the size picker, with the product's variants
+// on their rows; nil when no chart applies to the product
+func (s *VariantService) GetSizeChart(productID int) (*models.SizeChart, error) {
+	chart, err := s.sizeChartRepo.FindForProduct(productID)
+	if err == repository.ErrSizeChartNotFound {
+		return nil, nil
 	}
+	return chart, err
+}
 
-	variants, err := s.variantRepo.Search(filters)
+// FindVariantByAttributes returns the first active variant having every selected option value
+// (e.g. {"size": "M", "color": "Black", "length": "Long"})
+func (s *VariantService) FindVariantByAttributes(productID int, options map[string]string) (*models.ProductVariant, error) {
+	variants, err := s.variantRepo.GetByProductID(productID)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(variants) == 0 {
-		return nil, fmt.Errorf("variant not found")
+	for i := range variants {
+		if variants[i].IsActive && variants[i].MatchesOptions(options) {
+			return &variants[i], nil
+		}
 	}
 
-	return &variants[0], nil
+	return nil, fmt.Errorf("variant not found")
 }
 
 func (s *VariantService) CleanupExpiredReservations() error {
@@ -271,12 +447,8 @@ func (s *VariantService) generateSKU(variant *models.ProductVariant) (string, er
 	}
 
 	parts := []string{s.sanitizeSKU(product.Name)}
-
-	if variant.Size != nil && *variant.Size != "" {
-		parts = append(parts, s.sanitizeSKU(*variant.Size))
-	}
-	if variant.Color != nil && *variant.Color != "" {
-		parts = append(parts, s.sanitizeSKU(*variant.Color))
+	for _, value := range variant.OrderedOptionValues(s.optionOrder()) {
+		parts = append(parts, s.sanitizeSKU(value))
 	}
 
 	baseSKU := strings.Join(parts, "-")
@@ -330,20 +502,7 @@ func (s *VariantService) sanitizeSKU(input string) string {
 }
 
 func (s *VariantService) generateVariantName(variant *models.ProductVariant) string {
-	parts := []string{}
-
-	if variant.Size != nil && *variant.Size != "" {
-		parts = append(parts, *variant.Size)
-	}
-	if variant.Color != nil && *variant.Color != "" {
-		parts = append(parts, *variant.Color)
-	}
-	if variant.Material != nil && *variant.Material != "" {
-		parts = append(parts, *variant.Material)
-	}
-	if variant.Pattern != nil && *variant.Pattern != "" {
-		parts = append(parts, *variant.Pattern)
-	}
+	parts := variant.OrderedOptionValues(s.optionOrder())
 
 	if len(parts) == 0 {
 		return "Default"
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrVoucherNotFound      = errors.New("voucher not found")
+	ErrVoucherNotApplicable = errors.New("voucher cannot be applied")
+	ErrInvalidVoucher       = errors.New("invalid voucher")
+	ErrVoucherCodeTaken     = errors.New("voucher code already exists")
+)
+
+// VoucherService manages vouchers and works out what a code takes off a cart or order. The
+// redemption itself is recorded by the order repository together with the order.
+type VoucherService interface {
+	ListVouchers() ([]models.Voucher, error)
+	GetVoucher(id int) (*dto.VoucherDetailResponse, error)
+	CreateVoucher(req dto.SaveVoucherRequest, adminEmail string) (*models.Voucher, error)
+	UpdateVoucher(id int, req dto.SaveVoucherRequest, adminEmail string) (*models.Voucher, error)
+
+	// Preview applies the code to the session's (or user's) cart, after its promotions, without
+	// redeeming it
+	Preview(sessionID string, userID *int, req dto.ValidateVoucherRequest) (*dto.VoucherPreviewResponse, error)
+	// Quote checks every condition of the code against the order items and returns the
+	// redemption to attach to the order (models.Order.Voucher)
+	Quote(code string, items []models.OrderItem, shippingCost float64, userID *int, email string) (*models.VoucherRedemption, models.VoucherDiscount, error)
+}
+
+type voucherService struct {
+	voucherRepo  repository.VoucherRepository
+	cartRepo     repository.CartRepository
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	promotions   PromotionService
+}
+
+func NewVoucherService(
+	voucherRepo repository.VoucherRepository,
+	cartRepo repository.CartRepository,
+	productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository,
+	promotions PromotionService,
+) VoucherService {
+	return &voucherService{
+		voucherRepo:  voucherRepo,
+		cartRepo:     cartRepo,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		promotions:   promotions,
+	}
+}
+
+func (s *voucherService) ListVouchers() ([]models.Voucher, error) {
+	return s.voucherRepo.List()
+}
+
+func (s *voucherService) GetVoucher(id int) (*dto.VoucherDetailResponse, error) {
+	voucher, err := s.voucherRepo.FindByID(id)
+	if err == repository.ErrVoucherNotFound {
+		return nil, ErrVoucherNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, err := s.voucherRepo.ListRedemptions(id)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.VoucherDetailResponse{Voucher: *voucher, Redemptions: redemptions}, nil
+}
+
+func (s *voucherService) CreateVoucher(req dto.SaveVoucherRequest, adminEmail string) (*models.Voucher, error) {
+	voucher, err := s.buildVoucher(req)
+	if err != nil {
+		return nil, err
+	}
+	voucher.CreatedBy = adminEmail
+
+	if err := s.voucherRepo.Create(voucher); err != nil {
+		if err == repository.ErrVoucherCodeTaken {
+			return nil, ErrVoucherCodeTaken
+		}
+		return nil, err
+	}
+
+	log.Printf("🎟️ Voucher %s (%s) created by %s", voucher.Code, voucher.Type, adminEmail)
+	return voucher, nil
+}
+
+func (s *voucherService) UpdateVoucher(id int, req dto.SaveVoucherRequest, adminEmail string) (*models.Voucher, error) {
+	voucher, err := s.buildVoucher(req)
+	if err != nil {
+		return nil, err
+	}
+
+	voucher.ID = id
+	if err := s.voucherRepo.Update(voucher); err != nil {
+		switch err {
+		case repository.ErrVoucherNotFound:
+			return nil, ErrVoucherNotFound
+		case repository.ErrVoucherCodeTaken:
+			return nil, ErrVoucherCodeTaken
+		}
+		return nil, err
+	}
+
+	log.Printf("🎟️ Voucher %s updated by %s", voucher.Code, adminEmail)
+	return voucher, nil
+}
+
+// buildVoucher validates the request against the rules of its type
+func (s *voucherService) buildVoucher(req dto.SaveVoucherRequest) (*models.Voucher, error) {
+	voucher := &models.Voucher{
+		Code:           models.NormalizeVoucherCode(req.Code),
+		Name:           strings.TrimSpace(req.Name),
+		Description:    strings.TrimSpace(req.Description),
+		Type:           models.VoucherType(strings.ToUpper(strings.TrimSpace(req.Type))),
+		Value:          req.Value,
+		MaxDiscount:    req.MaxDiscount,
+		MinSpend:       req.MinSpend,
+		CategoryID:     req.CategoryID,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		FirstOrderOnly: req.FirstOrderOnly,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		IsActive:       req.IsActive == nil || *req.IsActive,
+	}
+	if req.Brand != nil && strings.TrimSpace(*req.Brand) != "" {
+		brand := strings.TrimSpace(*req.Brand)
+		voucher.Brand = &brand
+	}
+
+	if voucher.Code == "" || strings.ContainsAny(voucher.Code, " \t") {
+		return nil, fmt.Errorf("%w: code must not be empty or contain spaces", ErrInvalidVoucher)
+	}
+	if !voucher.Type.IsValid() {
+		return nil, fmt.Errorf("%w: unknown type %s", ErrInvalidVoucher, req.Type)
+	}
+
+	switch voucher.Type {
+	case models.VoucherTypePercentage:
+		if voucher.Value <= 0 || voucher.Value > 100 {
+			return nil, fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidVoucher)
+		}
+	case models.VoucherTypeFixedAmount:
+		if voucher.Value <= 0 {
+			return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidVoucher)
+		}
+		voucher.MaxDiscount = nil
+	case models.VoucherTypeFreeShipping:
+		voucher.Value = 0
+	case models.VoucherTypeBuyXGetY:
+		if req.BuyQuantity <= 0 || req.GetQuantity <= 0 {
+			return nil, fmt.Errorf("%w: buy and get quantities are required", ErrInvalidVoucher)
+		}
+		if voucher.Value == 0 {
+			voucher.Value = 100
+		}
+		if voucher.Value > 100 {
+			return nil, fmt.Errorf("%w: percentage off the free items must be at most 100", ErrInvalidVoucher)
+		}
+		voucher.BuyQuantity = req.BuyQuantity
+		voucher.GetQuantity = req.GetQuantity
+		voucher.MaxDiscount = nil
+	}
+
+	if voucher.StartsAt != nil && voucher.EndsAt != nil && !voucher.StartsAt.Before(*voucher.EndsAt) {
+		return nil, fmt.Errorf("%w: validity must end after it starts", ErrInvalidVoucher)
+	}
+	if voucher.CategoryID != nil {
+		if _, err := s.categoryRepo.FindByID(*voucher.CategoryID); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+	}
+	return voucher, nil
+}
+
+func (s *voucherService) Preview(sessionID string, userID *int, req dto.ValidateVoucherRequest) (*dto.VoucherPreviewResponse, error) {
+	// Same cart checkout would use: the user's, falling back to the session's
+	var cart *models.Cart
+	var err error
+	if userID != nil && *userID > 0 {
+		cart, err = s.cartRepo.FindByUserID(*userID)
+		if err != nil || cart == nil || len(cart.Items) == 0 {
+			cart, err = s.cartRepo.FindOrCreateBySessionID(sessionID)
+		}
+	} else {
+		cart, err = s.cartRepo.FindOrCreateBySessionID(sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	var subtotal float64
+	items := make([]models.OrderItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		subtotal += item.PriceSnapshot * float64(item.Quantity)
+		items = append(items, models.OrderItem{
+			ProductID:    item.ProductID,
+			Quantity:     item.Quantity,
+			PricePerUnit: item.PriceSnapshot,
+		})
+	}
+
+	// Vouchers apply on top of the promotions, as at checkout. The destination is not known yet,
+	// so free shipping limited to some provinces is left out.
+	promotions, err := s.promotions.EvaluateCart(cart, &models.PromotionShipping{Cost: req.ShippingCost})
+	if err != nil {
+		return nil, err
+	}
+
+	redemption, discount, err := s.Quote(req.Code, discountedItems(items, cart, promotions),
+		req.ShippingCost-promotions.ShippingSavings, userID, req.CustomerEmail)
+	if err != nil {
+		return nil, err
+	}
+	voucher, err := s.voucherRepo.FindByID(redemption.VoucherID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.VoucherPreviewResponse{
+		VoucherDiscount:   discount,
+		Code:              voucher.Code,
+		Name:              voucher.Name,
+		Type:              string(voucher.Type),
+		Subtotal:          subtotal,
+		PromotionDiscount: promotions.Total(),
+		Discount:          discount.Total(),
+		TotalAmount:       subtotal + req.ShippingCost - promotions.Total() - discount.Total(),
+	}, nil
+}
+
+func (s *voucherService) Quote(code string, items []models.OrderItem, shippingCost float64, userID *int, email string) (*models.VoucherRedemption, models.VoucherDiscount, error) {
+	voucher, err := s.voucherRepo.FindByCode(code)
+	if err == repository.ErrVoucherNotFound {
+		return nil, models.VoucherDiscount{}, ErrVoucherNotFound
+	}
+	if err != nil {
+		return nil, models.VoucherDiscount{}, err
+	}
+
+	lines, err := s.voucherLines(voucher, items)
+	if err != nil {
+		return nil, models.VoucherDiscount{}, err
+	}
+	discount, reason := voucher.Apply(lines, shippingCost, time.Now())
+	if reason != "" {
+		return nil, models.VoucherDiscount{}, fmt.Errorf("%w: %s", ErrVoucherNotApplicable, reason)
+	}
+
+	// Customer conditions; guests without an email are checked when they check out
+	hasCustomer := (userID != nil && *userID > 0) || strings.TrimSpace(email) != ""
+	if hasCustomer && voucher.PerUserLimit != nil {
+		used, err := s.voucherRepo.CountCustomerRedemptions(voucher.ID, userID, email)
+		if err != nil {
+			return nil, models.VoucherDiscount{}, err
+		}
+		if used >= *voucher.PerUserLimit {
+			return nil, models.VoucherDiscount{}, fmt.Errorf("%w: you have already used this voucher", ErrVoucherNotApplicable)
+		}
+	}
+	if hasCustomer && voucher.FirstOrderOnly {
+		placed, err := s.voucherRepo.HasPlacedOrder(userID, email, 0)
+		if err != nil {
+			return nil, models.VoucherDiscount{}, err
+		}
+		if placed {
+			return nil, models.VoucherDiscount{}, fmt.Errorf("%w: this voucher is only valid on your first order", ErrVoucherNotApplicable)
+		}
+	}
+
+	redemption := &models.VoucherRedemption{
+		VoucherID:      voucher.ID,
+		UserID:         userID,
+		CustomerEmail:  strings.TrimSpace(email),
+		Code:           voucher.Code,
+		DiscountAmount: discount.Total(),
+	}
+	return redemption, discount, nil
+}
+
+// voucherLines marks which items fall in the voucher's category (or below it) and brand
+func (s *voucherService) voucherLines(voucher *models.Voucher, items []models.OrderItem) ([]models.VoucherLine, error) {
+	var categories map[int]bool
+	if voucher.CategoryID != nil {
+		ids, err := s.categoryRepo.FindDescendantIDs(*voucher.CategoryID, true)
+		if err != nil {
+			return nil, err
+		}
+		categories = make(map[int]bool, len(ids))
+		for _, id := range ids {
+			categories[id] = true
+		}
+	}
+
+	lines := make([]models.VoucherLine, 0, len(items))
+	for _, item := range items {
+		line := models.VoucherLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.PricePerUnit,
+			InScope:   true,
+		}
+		if voucher.CategoryID != nil || voucher.Brand != nil {
+			product, err := s.productRepo.FindByID(item.ProductID)
+			if err != nil {
+				return nil, fmt.Errorf("product not found: %w", err)
+			}
+			if categories != nil && (product.CategoryID == nil || !categories[*product.CategoryID]) {
+				line.InScope = false
+			}
+			if voucher.Brand != nil && !strings.EqualFold(strings.TrimSpace(product.Brand), *voucher.Brand) {
+				line.InScope = false
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// voucherRedeemError maps a voucher that ran out between the quote and the order being written
+func voucherRedeemError(err error) error {
+	switch err {
+	case repository.ErrVoucherNotFound, repository.ErrVoucherUnavailable, repository.ErrVoucherUsageLimit,
+		repository.ErrVoucherPerUserLimit, repository.ErrVoucherFirstOrderOnly:
+		return fmt.Errorf("%w: %v", ErrVoucherNotApplicable, err)
+	}
+	return err
+}
+
+// voucherCode is the code redeemed on an order, or "" without a voucher
+func voucherCode(redemption *models.VoucherRedemption) string {
+	if redemption == nil {
+		return ""
+	}
+	return redemption.Code
+}
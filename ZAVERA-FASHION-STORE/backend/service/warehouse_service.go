@@ -0,0 +1,282 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"zavera/dto"
+	"zavera/models"
+	"zavera/repository"
+)
+
+var (
+	ErrWarehouseNotFound          = errors.New("warehouse not found")
+	ErrDuplicateWarehouse         = errors.New("warehouse code already exists")
+	ErrInvalidWarehouse           = errors.New("invalid warehouse")
+	ErrInsufficientWarehouseStock = errors.New("insufficient stock at warehouse")
+	ErrVariantNotFound            = errors.New("variant not found")
+	// ErrNoWarehouseCanFulfil is returned at checkout when stock is spread over warehouses
+	// so that no single one holds every item
+	ErrNoWarehouseCanFulfil = errors.New("no single warehouse has every item in stock")
+)
+
+type WarehouseService interface {
+	ListWarehouses(activeOnly bool) ([]models.Warehouse, error)
+	CreateWarehouse(req dto.WarehouseRequest) (*models.Warehouse, error)
+	UpdateWarehouse(id int, req dto.WarehouseRequest) (*models.Warehouse, error)
+
+	ListStock(warehouseID int, search string, limit, offset int) ([]models.WarehouseStock, int, error)
+	SetStock(warehouseID int, req dto.SetWarehouseStockRequest, adminEmail string) error
+	TransferStock(req dto.TransferStockRequest, adminEmail string) error
+
+	// RouteOrder picks the warehouse an order ships from (see models.RouteWarehouse).
+	// held are the cart's own limited-drop holds, which are off the shelf but belong to the order.
+	RouteOrder(lines []models.StockLine, held []models.StockReservation, destinationAreaID, destinationPostalCode string) (*models.Warehouse, error)
+}
+
+type warehouseService struct {
+	warehouseRepo repository.WarehouseRepository
+	variantRepo   *repository.VariantRepository
+	bundleRepo    repository.BundleRepository
+}
+
+func NewWarehouseService(warehouseRepo repository.WarehouseRepository, variantRepo *repository.VariantRepository, bundleRepo repository.BundleRepository) WarehouseService {
+	return &warehouseService{
+		warehouseRepo: warehouseRepo,
+		variantRepo:   variantRepo,
+		bundleRepo:    bundleRepo,
+	}
+}
+
+func (s *warehouseService) ListWarehouses(activeOnly bool) ([]models.Warehouse, error) {
+	return s.warehouseRepo.FindAll(activeOnly)
+}
+
+func (s *warehouseService) CreateWarehouse(req dto.WarehouseRequest) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{IsActive: true}
+	applyWarehouseRequest(warehouse, req)
+	if warehouse.IsDefault && !warehouse.IsActive {
+		return nil, fmt.Errorf("%w: the default warehouse must be active", ErrInvalidWarehouse)
+	}
+
+	if err := s.warehouseRepo.Create(warehouse); err != nil {
+		if err == repository.ErrDuplicateWarehouse {
+			return nil, ErrDuplicateWarehouse
+		}
+		return nil, err
+	}
+
+	log.Printf("🏬 Warehouse %s (%s) created", warehouse.Code, warehouse.CityName)
+	return warehouse, nil
+}
+
+func (s *warehouseService) UpdateWarehouse(id int, req dto.WarehouseRequest) (*models.Warehouse, error) {
+	warehouse, err := s.warehouseRepo.FindByID(id)
+	if err == repository.ErrWarehouseNotFound {
+		return nil, ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wasDefault := warehouse.IsDefault
+	applyWarehouseRequest(warehouse, req)
+
+	// Legacy stock writes land in the default warehouse, so there must always be one
+	if wasDefault && !warehouse.IsDefault {
+		return nil, fmt.Errorf("%w: make another warehouse the default instead", ErrInvalidWarehouse)
+	}
+	if warehouse.IsDefault && !warehouse.IsActive {
+		return nil, fmt.Errorf("%w: the default warehouse must be active", ErrInvalidWarehouse)
+	}
+
+	if err := s.warehouseRepo.Update(warehouse); err != nil {
+		switch err {
+		case repository.ErrWarehouseNotFound:
+			return nil, ErrWarehouseNotFound
+		case repository.ErrDuplicateWarehouse:
+			return nil, ErrDuplicateWarehouse
+		}
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func (s *warehouseService) ListStock(warehouseID int, search string, limit, offset int) ([]models.WarehouseStock, int, error) {
+	if _, err := s.warehouseRepo.FindByID(warehouseID); err != nil {
+		if err == repository.ErrWarehouseNotFound {
+			return nil, 0, ErrWarehouseNotFound
+		}
+		return nil, 0, err
+	}
+	return s.warehouseRepo.ListStock(warehouseID, search, limit, offset)
+}
+
+func (s *warehouseService) SetStock(warehouseID int, req dto.SetWarehouseStockRequest, adminEmail string) error {
+	if _, err := s.warehouseRepo.FindByID(warehouseID); err != nil {
+		if err == repository.ErrWarehouseNotFound {
+			return ErrWarehouseNotFound
+		}
+		return err
+	}
+	if _, err := s.variantRepo.GetByID(req.VariantID); err != nil {
+		return ErrVariantNotFound
+	}
+
+	if err := s.warehouseRepo.SetStock(warehouseID, req.VariantID, req.Quantity, adminEmail); err != nil {
+		return err
+	}
+
+	log.Printf("🏬 Stock of variant %d at warehouse %d set to %d by %s", req.VariantID, warehouseID, req.Quantity, adminEmail)
+	return nil
+}
+
+func (s *warehouseService) TransferStock(req dto.TransferStockRequest, adminEmail string) error {
+	if req.FromWarehouseID == req.ToWarehouseID {
+		return fmt.Errorf("%w: source and destination are the same warehouse", ErrInvalidWarehouse)
+	}
+	for _, id := range []int{req.FromWarehouseID, req.ToWarehouseID} {
+		if _, err := s.warehouseRepo.FindByID(id); err != nil {
+			if err == repository.ErrWarehouseNotFound {
+				return ErrWarehouseNotFound
+			}
+			return err
+		}
+	}
+	if _, err := s.variantRepo.GetByID(req.VariantID); err != nil {
+		return ErrVariantNotFound
+	}
+
+	err := s.warehouseRepo.TransferStock(req.FromWarehouseID, req.ToWarehouseID, req.VariantID, req.Quantity, adminEmail)
+	if err == repository.ErrInsufficientWarehouseStock {
+		return ErrInsufficientWarehouseStock
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🏬 Moved %d of variant %d from warehouse %d to %d (%s)",
+		req.Quantity, req.VariantID, req.FromWarehouseID, req.ToWarehouseID, adminEmail)
+	return nil
+}
+
+func (s *warehouseService) RouteOrder(lines []models.StockLine, held []models.StockReservation, destinationAreaID, destinationPostalCode string) (*models.Warehouse, error) {
+	warehouses, err := s.warehouseRepo.FindAll(true)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err = s.expandBundles(lines)
+	if err != nil {
+		return nil, err
+	}
+	lines = models.MergeStockLines(lines)
+	variantIDs := make([]int, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+	available, err := s.warehouseRepo.GetAvailability(variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, hold := range held {
+		if hold.WarehouseID != nil {
+			available.AddHeld(*hold.WarehouseID, hold.VariantID, hold.Quantity)
+		}
+	}
+
+	warehouse := models.RouteWarehouse(warehouses, available, lines, destinationAreaID, destinationPostalCode)
+	if warehouse == nil {
+		// Pre-order/backorder lines may wait for stock, so route on the other lines alone
+		backorderable, err := s.variantRepo.FindBackorderable(variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(backorderable) > 0 {
+			inStock := make([]models.StockLine, 0, len(lines))
+			for _, line := range lines {
+				if !backorderable[line.VariantID] {
+					inStock = append(inStock, line)
+				}
+			}
+			warehouse = models.RouteWarehouse(warehouses, available, inStock, destinationAreaID, destinationPostalCode)
+		}
+	}
+	if warehouse == nil {
+		return nil, ErrNoWarehouseCanFulfil
+	}
+	return warehouse, nil
+}
+
+// expandBundles replaces product lines of bundles with their components, which is what the
+// order reserves. Other lines without a variant are dropped.
+func (s *warehouseService) expandBundles(lines []models.StockLine) ([]models.StockLine, error) {
+	productIDs := []int{}
+	for _, line := range lines {
+		if line.VariantID <= 0 && line.ProductID > 0 {
+			productIDs = append(productIDs, line.ProductID)
+		}
+	}
+	if len(productIDs) == 0 {
+		return lines, nil
+	}
+
+	bundles, err := s.bundleRepo.GetComponentsByBundleIDs(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	expanded := make([]models.StockLine, 0, len(lines))
+	for _, line := range lines {
+		if line.VariantID > 0 {
+			expanded = append(expanded, line)
+			continue
+		}
+		for _, c := range bundles[line.ProductID] {
+			expanded = append(expanded, models.StockLine{VariantID: c.VariantID, Quantity: c.Quantity * line.Quantity})
+		}
+	}
+	return expanded, nil
+}
+
+// cartStockLines converts cart items to routing lines; items without a variant name their
+// product, so bundles can be routed on their components
+func cartStockLines(items []models.CartItem) []models.StockLine {
+	lines := make([]models.StockLine, 0, len(items))
+	for _, item := range items {
+		if item.VariantID != nil {
+			lines = append(lines, models.StockLine{VariantID: *item.VariantID, Quantity: item.Quantity})
+		} else {
+			lines = append(lines, models.StockLine{ProductID: item.ProductID, Quantity: item.Quantity})
+		}
+	}
+	return lines
+}
+
+// cartHolds returns the active limited-drop holds of cart items
+func cartHolds(items []models.CartItem) []models.StockReservation {
+	holds := []models.StockReservation{}
+	for _, item := range items {
+		if item.Hold != nil {
+			holds = append(holds, *item.Hold)
+		}
+	}
+	return holds
+}
+
+func applyWarehouseRequest(w *models.Warehouse, req dto.WarehouseRequest) {
+	w.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	w.Name = strings.TrimSpace(req.Name)
+	w.Address = strings.TrimSpace(req.Address)
+	w.CityName = strings.TrimSpace(req.CityName)
+	w.CityID = strings.TrimSpace(req.CityID)
+	w.PostalCode = strings.TrimSpace(req.PostalCode)
+	w.AreaID = strings.TrimSpace(req.AreaID)
+	w.ContactName = strings.TrimSpace(req.ContactName)
+	w.ContactPhone = strings.TrimSpace(req.ContactPhone)
+	w.Priority = req.Priority
+	w.IsDefault = req.IsDefault
+	if req.IsActive != nil {
+		w.IsActive = *req.IsActive
+	}
+}
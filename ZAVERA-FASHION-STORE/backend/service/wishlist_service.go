@@ -1,7 +1,9 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
+	"log"
 	"zavera/dto"
 	"zavera/models"
 	"zavera/repository"
@@ -13,23 +15,42 @@ type WishlistService interface {
 	RemoveFromWishlist(userID int, productID int) (*dto.WishlistResponse, error)
 	MoveToCart(userID int, productID int, sessionID string) (*dto.CartResponse, error)
 	IsInWishlist(userID int, productID int) (bool, error)
+
+	// Back-in-stock subscriptions to a sold-out size/color
+	SubscribeToVariant(userID int, variantID int) (*models.StockAlert, error)
+	GetStockAlerts(userID int) ([]models.StockAlert, error)
+	CancelStockAlert(userID int, alertID int) error
 }
 
+var (
+	ErrVariantInStock     = errors.New("variant is in stock")
+	ErrStockAlertNotFound = errors.New("stock alert not found")
+)
+
 type wishlistService struct {
 	wishlistRepo repository.WishlistRepository
 	productRepo  repository.ProductRepository
 	cartRepo     repository.CartRepository
+	variantRepo  *repository.VariantRepository
+	alertRepo    repository.StockAlertRepository
+	promotions   PromotionService
 }
 
 func NewWishlistService(
 	wishlistRepo repository.WishlistRepository,
 	productRepo repository.ProductRepository,
 	cartRepo repository.CartRepository,
+	variantRepo *repository.VariantRepository,
+	alertRepo repository.StockAlertRepository,
+	promotions PromotionService,
 ) WishlistService {
 	return &wishlistService{
 		wishlistRepo: wishlistRepo,
 		productRepo:  productRepo,
 		cartRepo:     cartRepo,
+		variantRepo:  variantRepo,
+		alertRepo:    alertRepo,
+		promotions:   promotions,
 	}
 }
 
@@ -172,6 +193,37 @@ func (s *wishlistService) IsInWishlist(userID int, productID int) (bool, error)
 	return s.wishlistRepo.IsInWishlist(userID, productID)
 }
 
+// SubscribeToVariant asks to be emailed when a sold-out variant is available again
+func (s *wishlistService) SubscribeToVariant(userID int, variantID int) (*models.StockAlert, error) {
+	variant, err := s.variantRepo.GetByID(variantID)
+	if err == sql.ErrNoRows || (err == nil && !variant.IsActive) {
+		return nil, ErrVariantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if variant.AvailableStock != nil && *variant.AvailableStock > 0 {
+		return nil, ErrVariantInStock
+	}
+
+	return s.alertRepo.Subscribe(userID, variantID)
+}
+
+// GetStockAlerts returns the user's pending and sent back-in-stock subscriptions
+func (s *wishlistService) GetStockAlerts(userID int) ([]models.StockAlert, error) {
+	return s.alertRepo.FindByUser(userID)
+}
+
+// CancelStockAlert stops a pending subscription
+func (s *wishlistService) CancelStockAlert(userID int, alertID int) error {
+	err := s.alertRepo.Cancel(alertID, userID)
+	if err == repository.ErrStockAlertNotFound {
+		return ErrStockAlertNotFound
+	}
+	return err
+}
+
 // Helper to convert cart to response
 func (s *wishlistService) toCartResponse(cart *models.Cart) (*dto.CartResponse, error) {
 	response := &dto.CartResponse{
@@ -222,6 +274,12 @@ func (s *wishlistService) toCartResponse(cart *models.Cart) (*dto.CartResponse,
 
 	response.Subtotal = subtotal
 	response.ItemCount = itemCount
+	response.Total = subtotal
+	response.Promotions = []models.AppliedPromotion{}
+
+	if err := applyCartPromotions(s.promotions, cart, response); err != nil {
+		log.Printf("⚠️ Failed to evaluate promotions for cart %d: %v", cart.ID, err)
+	}
 
 	return response, nil
 }
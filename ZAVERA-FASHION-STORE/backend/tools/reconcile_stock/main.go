@@ -0,0 +1,59 @@
+// Command reconcile_stock compares on-hand stock with the stock ledger and, with --apply, rebuilds
+// warehouse_stock, product_variants.stock_quantity and products.stock from it.
+//
+//	go run ./tools/reconcile_stock           # report only
+//	go run ./tools/reconcile_stock --apply   # rebuild on-hand counts
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"zavera/config"
+	"zavera/repository"
+	"zavera/service"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "rebuild on-hand counts from the ledger")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ Warning: .env file not found")
+	}
+
+	db, err := config.ConnectDatabase()
+	if err != nil {
+		log.Fatal("❌ Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	ledger := service.NewStockLedgerService(repository.NewStockRepository(db))
+	result, err := ledger.Reconcile(*apply)
+	if err != nil {
+		log.Fatal("❌ Reconciliation failed:", err)
+	}
+
+	for _, d := range result.Discrepancies {
+		fmt.Printf("warehouse %d  variant %d (%s): on hand %d, ledger %d\n",
+			d.WarehouseID, d.VariantID, d.SKU, d.OnHand, d.LedgerBalance)
+	}
+	for _, b := range result.BalanceBreaks {
+		fmt.Printf("ledger entry %d (warehouse %d, variant %d): balance_after %d, running total %d\n",
+			b.EntryID, b.WarehouseID, b.VariantID, b.BalanceAfter, b.RunningTotal)
+	}
+	fmt.Printf("\n%d discrepancies, %d balance breaks\n", len(result.Discrepancies), len(result.BalanceBreaks))
+
+	if result.Applied {
+		fmt.Printf("Rebuilt %d warehouse rows and %d product counters from the ledger\n",
+			result.RowsRebuilt, result.ProductsUpdated)
+		return
+	}
+	if len(result.Discrepancies) > 0 {
+		fmt.Println("Run with --apply to rebuild on-hand counts from the ledger")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,125 @@
+-- Migration: Product bundles
+-- Date: 2026-10-16
+-- Description: A bundle is a product sold at its own price that is made of component variants
+--              (e.g. top + bottom + accessory). It has no stock of its own: products.stock of a
+--              bundle mirrors how many complete bundles the components make up, and an order line
+--              for a bundle reserves and deducts each component. The components are snapshotted on
+--              the order line with their share of the bundle price, which item refunds pay out.
+
+-- ============================================
+-- 1. BUNDLE PRODUCTS AND COMPONENTS
+-- ============================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN products.is_bundle IS 'Sold as a set of component variants; stock comes from the components';
+
+CREATE TABLE IF NOT EXISTS product_bundle_components (
+    id SERIAL PRIMARY KEY,
+    bundle_product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE RESTRICT,
+    quantity INT NOT NULL DEFAULT 1,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_bundle_component UNIQUE (bundle_product_id, variant_id),
+    CONSTRAINT chk_bundle_component_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_components_bundle ON product_bundle_components(bundle_product_id, position);
+CREATE INDEX IF NOT EXISTS idx_bundle_components_variant ON product_bundle_components(variant_id);
+
+COMMENT ON TABLE product_bundle_components IS 'Variants that make up a bundle product';
+COMMENT ON COLUMN product_bundle_components.quantity IS 'Units of the variant in one bundle';
+
+-- ============================================
+-- 2. BUNDLE STOCK MIRROR
+-- ============================================
+-- Complete bundles the components' stock makes up; 0 without components or with an inactive one
+CREATE OR REPLACE FUNCTION get_bundle_available_stock(p_bundle_product_id INT)
+RETURNS INT AS $$
+    SELECT COALESCE(MIN(
+        CASE WHEN pv.is_active THEN GREATEST(pv.stock_quantity, 0) / bc.quantity ELSE 0 END
+    ), 0)::INT
+    FROM product_bundle_components bc
+    JOIN product_variants pv ON pv.id = bc.variant_id
+    WHERE bc.bundle_product_id = p_bundle_product_id;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION refresh_bundle_stock(p_bundle_product_id INT)
+RETURNS void AS $$
+DECLARE
+    v_guard TEXT;
+BEGIN
+    v_guard := current_setting('zavera.stock_ledger', true);
+    PERFORM set_config('zavera.stock_ledger', 'on', true);
+
+    UPDATE products
+    SET stock = get_bundle_available_stock(id)
+    WHERE id = p_bundle_product_id AND is_bundle = true;
+
+    PERFORM set_config('zavera.stock_ledger', COALESCE(v_guard, ''), true);
+END;
+$$ LANGUAGE plpgsql;
+
+-- products.stock is a mirror of the product's active variants, and bundles containing one of
+-- them follow along
+CREATE OR REPLACE FUNCTION refresh_product_stock(p_product_id INT)
+RETURNS void AS $$
+DECLARE
+    v_guard TEXT;
+BEGIN
+    v_guard := current_setting('zavera.stock_ledger', true);
+    PERFORM set_config('zavera.stock_ledger', 'on', true);
+
+    UPDATE products
+    SET stock = (
+        SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants
+        WHERE product_id = p_product_id AND is_active = true
+    )
+    WHERE id = p_product_id AND is_bundle = false;
+
+    UPDATE products b
+    SET stock = get_bundle_available_stock(b.id)
+    WHERE b.is_bundle = true AND b.id IN (
+        SELECT bc.bundle_product_id
+        FROM product_bundle_components bc
+        JOIN product_variants pv ON pv.id = bc.variant_id
+        WHERE pv.product_id = p_product_id
+    );
+
+    PERFORM set_config('zavera.stock_ledger', COALESCE(v_guard, ''), true);
+END;
+$$ LANGUAGE plpgsql;
+
+-- ============================================
+-- 3. BUNDLE LINES ON ORDERS
+-- ============================================
+CREATE TABLE IF NOT EXISTS order_item_components (
+    id SERIAL PRIMARY KEY,
+    order_item_id INT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL,                    -- No FK: orders outlive deleted variants
+    product_id INT NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    variant_name VARCHAR(255) NOT NULL DEFAULT '',
+    quantity INT NOT NULL,
+    allocated_price DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_order_item_component_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_components_item ON order_item_components(order_item_id);
+
+COMMENT ON TABLE order_item_components IS 'Component variants of a bundle order line, as sold';
+COMMENT ON COLUMN order_item_components.quantity IS 'Units of the variant in one bundle; the line reserves quantity x order_items.quantity';
+COMMENT ON COLUMN order_item_components.allocated_price IS 'Share of one bundle''s price; the shares add up to order_items.price_per_unit';
+
+ALTER TABLE refund_items
+ADD COLUMN IF NOT EXISTS order_item_component_id INT REFERENCES order_item_components(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN refund_items.order_item_component_id IS 'Bundle component refunded; quantity is in units of the component';
+
+-- Verify
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_name IN ('product_bundle_components', 'order_item_components')
+   OR (table_name = 'products' AND column_name = 'is_bundle')
+   OR (table_name = 'refund_items' AND column_name = 'order_item_component_id');
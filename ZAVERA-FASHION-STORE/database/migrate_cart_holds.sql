@@ -0,0 +1,236 @@
+-- Migration: Reservation expiry and cart holds
+-- Date: 2026-10-16
+-- Description: Stock reservations now take their units off the shelf through the stock ledger
+--              (RESERVE) and put them back when they expire or are cancelled (RELEASE), so a hold
+--              can never outlive its expiry by more than one sweep. Limited-drop products get a
+--              short hold per cart item; the hold is released when the item leaves the cart or the
+--              order is placed (the order then takes the stock itself).
+
+-- Limited drops hold stock while they sit in a cart
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_limited_drop BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN products.is_limited_drop IS 'Adding to cart holds the stock for a few minutes';
+
+-- held_in_ledger: the reservation's units were moved off warehouse_stock by a RESERVE entry and go
+-- back with a RELEASE entry. Older reservations only counted against availability.
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS held_in_ledger BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS cart_item_id INT REFERENCES cart_items(id) ON DELETE SET NULL;
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS released_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_active_cart_item
+    ON stock_reservations(cart_item_id) WHERE status = 'active' AND cart_item_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reservations_active_expiry
+    ON stock_reservations(expires_at) WHERE status = 'active';
+
+COMMENT ON COLUMN stock_reservations.held_in_ledger IS 'Units were taken off warehouse_stock (RESERVE) and are returned on expiry/cancel (RELEASE)';
+COMMENT ON COLUMN stock_reservations.cart_item_id IS 'Cart item a limited-drop hold belongs to';
+
+-- Availability only subtracts reservations that are not already off the shelf
+CREATE OR REPLACE FUNCTION get_available_stock(p_variant_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_stock INT;
+    v_reserved INT;
+BEGIN
+    SELECT stock_quantity INTO v_stock
+    FROM product_variants
+    WHERE id = p_variant_id;
+
+    SELECT COALESCE(SUM(quantity), 0) INTO v_reserved
+    FROM stock_reservations
+    WHERE variant_id = p_variant_id
+    AND status = 'active'
+    AND held_in_ledger = false
+    AND expires_at > CURRENT_TIMESTAMP;
+
+    RETURN GREATEST(COALESCE(v_stock, 0) - v_reserved, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION get_warehouse_available_stock(p_warehouse_id INT, p_variant_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_stock INT;
+    v_reserved INT;
+BEGIN
+    SELECT COALESCE(quantity, 0) INTO v_stock
+    FROM warehouse_stock
+    WHERE warehouse_id = p_warehouse_id AND variant_id = p_variant_id;
+
+    SELECT COALESCE(SUM(quantity), 0) INTO v_reserved
+    FROM stock_reservations
+    WHERE warehouse_id = p_warehouse_id
+    AND variant_id = p_variant_id
+    AND status = 'active'
+    AND held_in_ledger = false
+    AND expires_at > CURRENT_TIMESTAMP;
+
+    RETURN GREATEST(COALESCE(v_stock, 0) - v_reserved, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Ends an active reservation with the given status, returning held units to the warehouse.
+-- Returns the RELEASE ledger entry, or NULL when there was nothing to put back.
+CREATE OR REPLACE FUNCTION release_reservation(p_reservation_id INT, p_status VARCHAR, p_order_id INT DEFAULT NULL)
+RETURNS stock_ledger AS $$
+DECLARE
+    v_reservation stock_reservations;
+    v_entry stock_ledger;
+BEGIN
+    SELECT * INTO v_reservation
+    FROM stock_reservations
+    WHERE id = p_reservation_id AND status = 'active'
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE stock_reservations
+    SET status = p_status,
+        order_id = COALESCE(p_order_id, order_id),
+        released_at = CURRENT_TIMESTAMP
+    WHERE id = p_reservation_id;
+
+    IF v_reservation.held_in_ledger THEN
+        v_entry := apply_stock_movement(
+            v_reservation.variant_id, v_reservation.warehouse_id, 'RELEASE', v_reservation.quantity,
+            p_order_id, 'reservation:' || p_reservation_id, 'Hold ' || p_status, 'system'
+        );
+    END IF;
+
+    RETURN v_entry;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Reservations take their units off the shelf; p_cart_item_id ties a limited-drop hold to the cart
+DROP FUNCTION IF EXISTS reserve_stock(INT, INT, VARCHAR, INT, INT, INT);
+
+CREATE OR REPLACE FUNCTION reserve_stock(
+    p_variant_id INT,
+    p_customer_id INT,
+    p_session_id VARCHAR,
+    p_quantity INT,
+    p_timeout_minutes INT DEFAULT 15,
+    p_warehouse_id INT DEFAULT NULL,
+    p_cart_item_id INT DEFAULT NULL
+)
+RETURNS INT AS $$
+DECLARE
+    v_warehouse_id INT := p_warehouse_id;
+    v_available INT;
+    v_reservation_id INT;
+BEGIN
+    -- Clean expired reservations first
+    PERFORM clean_expired_reservations();
+
+    IF v_warehouse_id IS NULL THEN
+        SELECT w.id INTO v_warehouse_id
+        FROM warehouses w
+        WHERE w.is_active = true
+        ORDER BY get_warehouse_available_stock(w.id, p_variant_id) DESC, w.is_default DESC, w.priority, w.id
+        LIMIT 1;
+    END IF;
+
+    v_available := get_warehouse_available_stock(v_warehouse_id, p_variant_id);
+
+    IF v_available < p_quantity THEN
+        RAISE EXCEPTION 'Insufficient stock. Available: %, Requested: %', v_available, p_quantity
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    INSERT INTO stock_reservations (
+        variant_id, customer_id, session_id, quantity, expires_at, warehouse_id, cart_item_id, held_in_ledger
+    ) VALUES (
+        p_variant_id, p_customer_id, p_session_id, p_quantity,
+        CURRENT_TIMESTAMP + (p_timeout_minutes || ' minutes')::INTERVAL, v_warehouse_id, p_cart_item_id, true
+    ) RETURNING id INTO v_reservation_id;
+
+    PERFORM apply_stock_movement(
+        p_variant_id, v_warehouse_id, 'RESERVE', -p_quantity,
+        NULL, 'reservation:' || v_reservation_id,
+        CASE WHEN p_cart_item_id IS NULL THEN 'Stock held' ELSE 'Cart hold' END, 'system'
+    );
+
+    RETURN v_reservation_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION cancel_reservation(p_reservation_id INT)
+RETURNS void AS $$
+BEGIN
+    PERFORM release_reservation(p_reservation_id, 'cancelled');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION clean_expired_reservations()
+RETURNS void AS $$
+DECLARE
+    v_id INT;
+BEGIN
+    FOR v_id IN
+        SELECT id FROM stock_reservations
+        WHERE status = 'active' AND expires_at < CURRENT_TIMESTAMP
+        ORDER BY id
+        FOR UPDATE SKIP LOCKED
+    LOOP
+        PERFORM release_reservation(v_id, 'expired');
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- A held reservation already took its stock; completing it only hands it to the order
+CREATE OR REPLACE FUNCTION complete_reservation(p_reservation_id INT, p_order_id INT)
+RETURNS void AS $$
+DECLARE
+    v_variant_id INT;
+    v_quantity INT;
+    v_warehouse_id INT;
+    v_held BOOLEAN;
+BEGIN
+    SELECT variant_id, quantity, warehouse_id, held_in_ledger INTO v_variant_id, v_quantity, v_warehouse_id, v_held
+    FROM stock_reservations
+    WHERE id = p_reservation_id AND status = 'active';
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Reservation not found or already processed';
+    END IF;
+
+    IF NOT v_held THEN
+        PERFORM apply_stock_movement(
+            v_variant_id, v_warehouse_id, 'RESERVE', -v_quantity,
+            p_order_id, 'reservation:' || p_reservation_id, 'Reservation completed', 'system'
+        );
+    END IF;
+
+    UPDATE stock_reservations
+    SET status = 'completed', order_id = p_order_id, released_at = CURRENT_TIMESTAMP
+    WHERE id = p_reservation_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Removing an item from a cart (or clearing/merging the cart) gives its hold back straight away.
+-- BEFORE so it runs ahead of the ON DELETE SET NULL on cart_item_id.
+CREATE OR REPLACE FUNCTION release_cart_item_hold()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_id INT;
+BEGIN
+    FOR v_id IN
+        SELECT id FROM stock_reservations WHERE cart_item_id = OLD.id AND status = 'active'
+    LOOP
+        PERFORM release_reservation(v_id, 'cancelled');
+    END LOOP;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_cart_item_release_hold ON cart_items;
+CREATE TRIGGER trigger_cart_item_release_hold
+BEFORE DELETE ON cart_items
+FOR EACH ROW
+EXECUTE FUNCTION release_cart_item_hold();
+
+-- Verify
+SELECT column_name FROM information_schema.columns
+WHERE table_name = 'stock_reservations' AND column_name IN ('held_in_ledger', 'cart_item_id', 'released_at');
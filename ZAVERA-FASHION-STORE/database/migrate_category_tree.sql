@@ -0,0 +1,83 @@
+-- Migration: Hierarchical product categories
+-- Date: 2026-10-16
+-- Description: Category tree (parent/child, ordering, images, active flag) and products.category_id,
+--              populated from the free-text category/subcategory columns added by
+--              migrate_categories.sql, which must run first
+
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL,
+    path VARCHAR(500) NOT NULL UNIQUE,      -- e.g. 'wanita/dress', maintained by the application
+    depth INT NOT NULL DEFAULT 0,
+    sort_order INT NOT NULL DEFAULT 0,
+    image_url TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_category_not_own_parent CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+-- Sibling slugs are unique; root slugs are unique among roots
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_parent_slug ON categories(COALESCE(parent_id, 0), slug);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_path_prefix ON categories(path varchar_pattern_ops);
+
+COMMENT ON TABLE categories IS 'Product category tree; path is the slash-joined slug chain from the root';
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
+
+COMMENT ON COLUMN products.category_id IS 'Deepest category; category/subcategory strings are kept in sync for legacy readers';
+
+-- ============================================
+-- Convert existing category/subcategory strings
+-- ============================================
+
+-- Root categories in the storefront's menu order
+INSERT INTO categories (name, slug, path, depth, sort_order)
+SELECT INITCAP(c.slug), c.slug, c.slug, 0,
+       COALESCE(array_position(ARRAY['wanita', 'pria', 'anak', 'sports', 'luxury', 'beauty'], c.slug), 100)
+FROM (
+    SELECT DISTINCT LOWER(TRIM(category)) AS slug
+    FROM products
+    WHERE COALESCE(TRIM(category), '') <> ''
+) c
+ON CONFLICT (path) DO NOTHING;
+
+-- Subcategories under their root
+INSERT INTO categories (parent_id, name, slug, path, depth, sort_order)
+SELECT parent.id, s.name, s.slug, parent.path || '/' || s.slug, 1, 0
+FROM (
+    SELECT DISTINCT ON (LOWER(TRIM(category)), slug)
+           LOWER(TRIM(category)) AS root_slug,
+           TRIM(subcategory) AS name,
+           TRIM(BOTH '-' FROM regexp_replace(LOWER(TRIM(subcategory)), '[^a-z0-9]+', '-', 'g')) AS slug
+    FROM products
+    WHERE COALESCE(TRIM(category), '') <> '' AND COALESCE(TRIM(subcategory), '') <> ''
+) s
+JOIN categories parent ON parent.path = s.root_slug
+WHERE s.slug <> ''
+ON CONFLICT (path) DO NOTHING;
+
+-- Link products to the deepest matching category
+UPDATE products p
+SET category_id = c.id
+FROM categories c
+WHERE p.category_id IS NULL
+  AND c.path = LOWER(TRIM(p.category)) || '/' ||
+      TRIM(BOTH '-' FROM regexp_replace(LOWER(TRIM(COALESCE(p.subcategory, ''))), '[^a-z0-9]+', '-', 'g'));
+
+UPDATE products p
+SET category_id = c.id
+FROM categories c
+WHERE p.category_id IS NULL
+  AND c.path = LOWER(TRIM(p.category));
+
+-- Verify
+SELECT c.path, c.depth, COUNT(p.id) AS products
+FROM categories c
+LEFT JOIN products p ON p.category_id = c.id
+GROUP BY c.path, c.depth
+ORDER BY c.path;
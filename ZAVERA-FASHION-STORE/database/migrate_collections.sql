@@ -0,0 +1,50 @@
+-- Migration: Product collections
+-- Date: 2026-10-16
+-- Description: Manual and rule-based merchandising collections with visibility windows,
+--              plus free-form product tags used by collection rules
+
+-- Product tags (e.g. 'ramadan', 'best-seller')
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_products_tags ON products USING gin(tags);
+
+COMMENT ON COLUMN products.tags IS 'Lowercase merchandising tags matched by collection rules';
+
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,
+    slug VARCHAR(150) NOT NULL UNIQUE,
+    description TEXT,
+    image_url TEXT,
+    collection_type VARCHAR(20) NOT NULL DEFAULT 'MANUAL',
+    rules JSONB NOT NULL DEFAULT '[]',       -- [{"field": "price", "operator": "lt", "value": 200000}]
+    match_type VARCHAR(10) NOT NULL DEFAULT 'ALL',
+    sort_by VARCHAR(30) NOT NULL DEFAULT 'newest',
+    sort_order INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_collection_type CHECK (collection_type IN ('MANUAL', 'RULE')),
+    CONSTRAINT chk_collection_match_type CHECK (match_type IN ('ALL', 'ANY')),
+    CONSTRAINT chk_collection_window CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_visible ON collections(is_active, starts_at, ends_at);
+
+COMMENT ON TABLE collections IS 'Merchandising lists; MANUAL uses collection_products, RULE evaluates rules at read time';
+
+-- Ordered members of manual collections
+CREATE TABLE IF NOT EXISTS collection_products (
+    collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_products_position ON collection_products(collection_id, position);
+
+-- Verify
+SELECT table_name FROM information_schema.tables
+WHERE table_name IN ('collections', 'collection_products');
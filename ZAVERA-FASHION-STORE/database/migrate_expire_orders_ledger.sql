@@ -0,0 +1,73 @@
+-- Migration: Ledger-aware order expiry
+-- Date: 2026-10-16
+-- Description: expire_pending_orders() releases what the order actually reserved, as the
+--              order expiry job does: backordered units were never taken, and bundle lines are
+--              released as their components. The order stops waiting for stock as well.
+--              Run after migrate_stock_ledger.sql, migrate_bundles.sql and migrate_preorders.sql.
+
+CREATE OR REPLACE FUNCTION expire_pending_orders()
+RETURNS INTEGER AS $$
+DECLARE
+    expired_count INTEGER;
+    order_record RECORD;
+    item_record RECORD;
+BEGIN
+    expired_count := 0;
+
+    FOR order_record IN
+        SELECT id, warehouse_id, order_code FROM orders
+        WHERE status = 'PENDING'
+        AND created_at < NOW() - INTERVAL '24 hours'
+        AND COALESCE(stock_reserved, true) = true
+        FOR UPDATE
+    LOOP
+        -- Bundle lines come back as their components; deleted variants fall back to the
+        -- product's default variant
+        FOR item_record IN
+            SELECT oi.product_id, oi.variant_id, oi.quantity - COALESCE(oi.backordered_quantity, 0) AS quantity
+            FROM order_items oi
+            WHERE oi.order_id = order_record.id
+            AND NOT EXISTS (SELECT 1 FROM order_item_components c WHERE c.order_item_id = oi.id)
+            UNION ALL
+            SELECT c.product_id, pv.id, c.quantity * oi.quantity
+            FROM order_item_components c
+            JOIN order_items oi ON oi.id = c.order_item_id
+            LEFT JOIN product_variants pv ON pv.id = c.variant_id
+            WHERE oi.order_id = order_record.id
+        LOOP
+            IF item_record.quantity <= 0 THEN
+                CONTINUE;
+            END IF;
+
+            PERFORM apply_stock_movement(
+                COALESCE(item_record.variant_id, ensure_default_variant(item_record.product_id)),
+                order_record.warehouse_id, 'RELEASE', item_record.quantity,
+                order_record.id, order_record.order_code, 'Stock released on expire', 'system'
+            );
+        END LOOP;
+
+        UPDATE order_items SET backordered_quantity = 0
+        WHERE order_id = order_record.id AND backordered_quantity > 0;
+
+        UPDATE orders
+        SET status = 'EXPIRED',
+            stock_reserved = false,
+            awaiting_stock = false,
+            updated_at = NOW()
+        WHERE id = order_record.id;
+
+        INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+        VALUES (order_record.id, 'PENDING', 'EXPIRED', 'system', 'Payment timeout - 24 hours');
+
+        expired_count := expired_count + 1;
+    END LOOP;
+
+    RETURN expired_count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION expire_pending_orders() IS
+    'Expires unpaid orders older than 24 hours and releases their reserved stock through the ledger';
+
+-- Verify
+SELECT proname FROM pg_proc WHERE proname = 'expire_pending_orders';
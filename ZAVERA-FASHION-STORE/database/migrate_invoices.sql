@@ -0,0 +1,227 @@
+-- Migration: Invoices and credit notes
+-- Date: 2026-10-16
+-- Description: Every order gets a numbered invoice when it is paid and every completed refund
+--              a numbered credit note against that invoice. Both are issued by triggers, so the
+--              many paths that mark an order paid or a refund completed cannot miss one.
+--              Numbers run per document type and year (INV/2026/000001, CN/2026/000001) from a
+--              counter row that is locked until the issuing transaction commits, so concurrent
+--              payments never share a number or leave a gap. The buyer's NPWP is copied onto the
+--              document when it is issued; the PDF is rendered from the document and the order.
+
+-- ============================================
+-- 1. BUYER NPWP ON THE PROFILE
+-- ============================================
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS npwp VARCHAR(16),
+ADD COLUMN IF NOT EXISTS npwp_name VARCHAR(255),
+ADD COLUMN IF NOT EXISTS npwp_address TEXT;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_npwp;
+ALTER TABLE users ADD CONSTRAINT chk_users_npwp CHECK (npwp IS NULL OR npwp ~ '^[0-9]{15,16}$');
+
+COMMENT ON COLUMN users.npwp IS 'Tax ID for invoices, digits only (15, or 16 for NIK-based NPWP)';
+COMMENT ON COLUMN users.npwp_name IS 'Name registered on the NPWP';
+COMMENT ON COLUMN users.npwp_address IS 'Address registered on the NPWP';
+
+-- ============================================
+-- 2. DOCUMENT NUMBERING
+-- ============================================
+CREATE TABLE IF NOT EXISTS document_sequences (
+    document_type VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    last_number INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (document_type, year)
+);
+
+COMMENT ON TABLE document_sequences IS 'Last number issued per document type and year';
+
+-- The upsert locks the counter row until the caller commits: a concurrent caller waits and
+-- gets the next number, and a rolled back caller releases its number
+CREATE OR REPLACE FUNCTION next_document_number(p_type VARCHAR, p_prefix VARCHAR, p_at TIMESTAMP)
+RETURNS VARCHAR AS $$
+DECLARE
+    v_year INTEGER := EXTRACT(YEAR FROM p_at)::INTEGER;
+    v_number INTEGER;
+BEGIN
+    INSERT INTO document_sequences (document_type, year, last_number)
+    VALUES (p_type, v_year, 1)
+    ON CONFLICT (document_type, year)
+    DO UPDATE SET last_number = document_sequences.last_number + 1
+    RETURNING last_number INTO v_number;
+
+    RETURN p_prefix || '/' || v_year || '/' || LPAD(v_number::TEXT, 6, '0');
+END;
+$$ LANGUAGE plpgsql;
+
+-- ============================================
+-- 3. INVOICES AND CREDIT NOTES
+-- ============================================
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(30) NOT NULL UNIQUE,
+    document_type VARCHAR(20) NOT NULL,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE RESTRICT,
+    refund_id INTEGER UNIQUE REFERENCES refunds(id) ON DELETE RESTRICT,       -- Credit notes
+    related_invoice_id INTEGER REFERENCES invoices(id) ON DELETE RESTRICT,    -- Invoice a credit note corrects
+    tax_period CHAR(7) NOT NULL,                                               -- YYYY-MM of issue
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Buyer as on the day of issue
+    buyer_name VARCHAR(255) NOT NULL,
+    buyer_email VARCHAR(255) NOT NULL,
+    buyer_npwp VARCHAR(16),
+    buyer_npwp_name VARCHAR(255),
+    buyer_npwp_address TEXT,
+
+    -- Amounts; for a credit note, what is returned
+    subtotal DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    discount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    shipping_cost DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    tax_rate DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    tax DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(12, 2) NOT NULL,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT true,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS chk_invoices_document_type;
+ALTER TABLE invoices ADD CONSTRAINT chk_invoices_document_type CHECK (
+    (document_type = 'INVOICE' AND refund_id IS NULL AND related_invoice_id IS NULL)
+    OR (document_type = 'CREDIT_NOTE' AND refund_id IS NOT NULL AND related_invoice_id IS NOT NULL)
+);
+
+-- One invoice per order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_order_invoice ON invoices(order_id) WHERE document_type = 'INVOICE';
+CREATE INDEX IF NOT EXISTS idx_invoices_order ON invoices(order_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_tax_period ON invoices(tax_period, issued_at);
+
+COMMENT ON TABLE invoices IS 'Numbered invoices of paid orders and credit notes of completed refunds';
+
+-- ============================================
+-- 4. ISSUING
+-- ============================================
+CREATE OR REPLACE FUNCTION issue_order_invoice(p_order_id INTEGER, p_at TIMESTAMP)
+RETURNS INTEGER AS $$
+DECLARE
+    v_id INTEGER;
+BEGIN
+    SELECT id INTO v_id FROM invoices WHERE order_id = p_order_id AND document_type = 'INVOICE';
+    IF v_id IS NOT NULL THEN
+        RETURN v_id;
+    END IF;
+
+    INSERT INTO invoices (
+        invoice_number, document_type, order_id, tax_period, issued_at,
+        buyer_name, buyer_email, buyer_npwp, buyer_npwp_name, buyer_npwp_address,
+        subtotal, discount, shipping_cost, tax_rate, tax, total_amount, prices_include_tax
+    )
+    SELECT next_document_number('INVOICE', 'INV', p_at), 'INVOICE', o.id, TO_CHAR(p_at, 'YYYY-MM'), p_at,
+           o.customer_name, o.customer_email, u.npwp, u.npwp_name, u.npwp_address,
+           o.subtotal, COALESCE(o.discount, 0), COALESCE(o.shipping_cost, 0), o.tax_rate,
+           COALESCE(o.tax, 0), o.total_amount, o.prices_include_tax
+    FROM orders o
+    LEFT JOIN users u ON u.id = o.user_id
+    WHERE o.id = p_order_id
+    RETURNING id INTO v_id;
+
+    RETURN v_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION issue_credit_note(p_refund_id INTEGER, p_at TIMESTAMP)
+RETURNS INTEGER AS $$
+DECLARE
+    v_id INTEGER;
+    v_order_id INTEGER;
+    v_invoice_id INTEGER;
+BEGIN
+    SELECT id INTO v_id FROM invoices WHERE refund_id = p_refund_id;
+    IF v_id IS NOT NULL THEN
+        RETURN v_id;
+    END IF;
+
+    SELECT order_id INTO v_order_id FROM refunds WHERE id = p_refund_id;
+    -- A refund is of a paid order; older orders may predate invoicing
+    v_invoice_id := issue_order_invoice(v_order_id, p_at);
+
+    INSERT INTO invoices (
+        invoice_number, document_type, order_id, refund_id, related_invoice_id, tax_period, issued_at,
+        buyer_name, buyer_email, buyer_npwp, buyer_npwp_name, buyer_npwp_address,
+        subtotal, shipping_cost, tax_rate, tax, total_amount, prices_include_tax
+    )
+    SELECT next_document_number('CREDIT_NOTE', 'CN', p_at), 'CREDIT_NOTE', i.order_id, r.id, i.id,
+           TO_CHAR(p_at, 'YYYY-MM'), p_at,
+           i.buyer_name, i.buyer_email, i.buyer_npwp, i.buyer_npwp_name, i.buyer_npwp_address,
+           COALESCE(r.items_refund, 0), COALESCE(r.shipping_refund, 0), i.tax_rate,
+           COALESCE(r.tax_refund, 0), r.refund_amount, i.prices_include_tax
+    FROM refunds r
+    JOIN invoices i ON i.id = v_invoice_id
+    WHERE r.id = p_refund_id
+    RETURNING id INTO v_id;
+
+    RETURN v_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION issue_invoice_on_payment()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM issue_order_invoice(NEW.id, COALESCE(NEW.paid_at, CURRENT_TIMESTAMP));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_issue_invoice_on_payment ON orders;
+CREATE TRIGGER trigger_issue_invoice_on_payment
+AFTER UPDATE OF status ON orders
+FOR EACH ROW
+WHEN (NEW.status::text = 'PAID' AND OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION issue_invoice_on_payment();
+
+CREATE OR REPLACE FUNCTION issue_credit_note_on_refund()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM issue_credit_note(NEW.id, COALESCE(NEW.completed_at, CURRENT_TIMESTAMP));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Manual refunds are inserted already completed
+DROP TRIGGER IF EXISTS trigger_issue_credit_note_on_refund ON refunds;
+CREATE TRIGGER trigger_issue_credit_note_on_refund
+AFTER INSERT OR UPDATE OF status ON refunds
+FOR EACH ROW
+WHEN (NEW.status::text = 'COMPLETED')
+EXECUTE FUNCTION issue_credit_note_on_refund();
+
+-- ============================================
+-- 5. BACKFILL
+-- ============================================
+-- Orders paid and refunds completed before invoicing, numbered in the order they happened
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT o.id, o.paid_at FROM orders o
+        WHERE o.paid_at IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.order_id = o.id AND i.document_type = 'INVOICE')
+        ORDER BY o.paid_at, o.id
+    LOOP
+        PERFORM issue_order_invoice(r.id, r.paid_at);
+    END LOOP;
+
+    FOR r IN
+        SELECT rf.id, COALESCE(rf.completed_at, rf.updated_at) AS completed_at FROM refunds rf
+        WHERE rf.status::text = 'COMPLETED'
+          AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.refund_id = rf.id)
+        ORDER BY 2, rf.id
+    LOOP
+        PERFORM issue_credit_note(r.id, r.completed_at);
+    END LOOP;
+END $$;
+
+-- Verify
+SELECT document_type, year, last_number FROM document_sequences ORDER BY document_type, year;
+SELECT document_type, tax_period, COUNT(*) FROM invoices GROUP BY document_type, tax_period ORDER BY tax_period;
@@ -0,0 +1,191 @@
+-- Migration: Pre-order and backorder variants
+-- Date: 2026-10-16
+-- Description: Variants can be sold beyond their stock, either as a pre-order for an upcoming drop
+--              or as a backorder of a sold out item, with an optional cap and an expected ship
+--              date. Order lines record how many units are still waiting for stock; such orders
+--              are held from packing until the units arrive and are reserved.
+
+-- ============================================
+-- 1. VARIANT INVENTORY POLICY
+-- ============================================
+ALTER TABLE product_variants
+ADD COLUMN IF NOT EXISTS inventory_policy VARCHAR(20) NOT NULL DEFAULT 'STOCK',
+ADD COLUMN IF NOT EXISTS backorder_limit INT,
+ADD COLUMN IF NOT EXISTS expected_ship_date DATE;
+
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS chk_variant_inventory_policy;
+ALTER TABLE product_variants ADD CONSTRAINT chk_variant_inventory_policy
+    CHECK (inventory_policy IN ('STOCK', 'BACKORDER', 'PREORDER'));
+
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS chk_variant_backorder_limit;
+ALTER TABLE product_variants ADD CONSTRAINT chk_variant_backorder_limit
+    CHECK (backorder_limit IS NULL OR backorder_limit >= 0);
+
+COMMENT ON COLUMN product_variants.inventory_policy IS 'STOCK = only what is on hand; BACKORDER/PREORDER = may be ordered beyond stock';
+COMMENT ON COLUMN product_variants.backorder_limit IS 'Max units waiting for stock across open orders; NULL = no cap';
+COMMENT ON COLUMN product_variants.expected_ship_date IS 'When backordered/pre-ordered units are expected to ship';
+
+-- ============================================
+-- 2. BACKORDERED ORDER LINES
+-- ============================================
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS backordered_quantity INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS expected_ship_date DATE;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_backordered
+    ON order_items(variant_id) WHERE backordered_quantity > 0;
+
+COMMENT ON COLUMN order_items.backordered_quantity IS 'Units not yet reserved from stock; 0 once allocated, released or refunded';
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS awaiting_stock BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS expected_ship_date DATE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_awaiting_stock ON orders(created_at) WHERE awaiting_stock = true;
+
+COMMENT ON COLUMN orders.awaiting_stock IS 'Has backordered/pre-ordered lines; cannot be packed until they are allocated';
+COMMENT ON COLUMN orders.expected_ship_date IS 'Latest expected ship date of the backordered lines';
+
+-- ============================================
+-- 3. EXPECTED SHIP DATE IN ORDER EMAILS
+-- ============================================
+INSERT INTO email_templates (template_key, name, subject_template, html_template, is_active) VALUES
+(
+    'ORDER_CREATED',
+    'Order Created',
+    '🛍️ Pesanan ZAVERA #{{.OrderCode}} telah dibuat',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .order-info { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .preorder-info { background: #FFF8E1; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .items-table { width: 100%; border-collapse: collapse; margin: 15px 0; }
+        .items-table th, .items-table td { padding: 10px; border-bottom: 1px solid #eee; text-align: left; }
+        .total-row { font-weight: bold; background: #f0f0f0; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+        .btn { display: inline-block; background: #000; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <h2>Pesanan Anda Telah Dibuat!</h2>
+        <p>Halo {{.CustomerName}},</p>
+        <p>Terima kasih telah berbelanja di ZAVERA. Pesanan Anda telah berhasil dibuat.</p>
+
+        <div class="order-info">
+            <strong>Nomor Pesanan:</strong> {{.OrderCode}}<br>
+            <strong>Tanggal:</strong> {{.CreatedAt}}<br>
+            <strong>Status:</strong> Menunggu Pembayaran
+        </div>
+
+        {{if .AwaitingStock}}
+        <div class="preorder-info">
+            Pesanan ini berisi produk pre-order/backorder dan akan dikirim setelah stok tersedia.
+            {{if .ExpectedShipDate}}<br><strong>Perkiraan Pengiriman:</strong> {{.ExpectedShipDate}}{{end}}
+        </div>
+        {{end}}
+
+        <h3>Detail Pesanan</h3>
+        <table class="items-table">
+            <tr><th>Produk</th><th>Qty</th><th>Harga</th></tr>
+            {{range .Items}}
+            <tr><td>{{.ProductName}}{{if .BackorderedQuantity}}<br><small>Pre-order {{.BackorderedQuantity}} pcs{{if .ExpectedShipDate}}, dikirim ± {{.ExpectedShipDate}}{{end}}</small>{{end}}</td><td>{{.Quantity}}</td><td>Rp {{.Subtotal}}</td></tr>
+            {{end}}
+            <tr><td colspan="2">Subtotal</td><td>Rp {{.Subtotal}}</td></tr>
+            <tr><td colspan="2">Ongkir ({{.Courier}} - {{.Service}})</td><td>Rp {{.ShippingCost}}</td></tr>
+            <tr class="total-row"><td colspan="2">Total</td><td>Rp {{.TotalAmount}}</td></tr>
+        </table>
+
+        <h3>Alamat Pengiriman</h3>
+        <p>{{.ShippingAddress}}</p>
+
+        <h3>Instruksi Pembayaran</h3>
+        <p>Silakan selesaikan pembayaran dalam waktu 24 jam untuk memproses pesanan Anda.</p>
+        <p style="text-align: center; margin: 20px 0;">
+            <a href="{{.PaymentURL}}" class="btn">Bayar Sekarang</a>
+        </p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+        <p>Jika ada pertanyaan, hubungi kami di support@zavera.com</p>
+    </div>
+</body>
+</html>',
+    true
+),
+(
+    'PAYMENT_SUCCESS',
+    'Payment Success',
+    '💳 Pembayaran diterima – Pesanan #{{.OrderCode}}',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .success-badge { background: #4CAF50; color: #fff; padding: 15px; border-radius: 5px; text-align: center; margin: 15px 0; }
+        .order-info { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .preorder-info { background: #FFF8E1; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <div class="success-badge">
+            <h2>✓ Pembayaran Berhasil!</h2>
+        </div>
+        <p>Halo {{.CustomerName}},</p>
+        {{if .AwaitingStock}}
+        <p>Pembayaran untuk pesanan Anda telah kami terima. Pesanan Anda berisi produk pre-order/backorder dan akan dikirim setelah stok tersedia.</p>
+        {{else}}
+        <p>Pembayaran untuk pesanan Anda telah kami terima. Pesanan Anda sedang diproses dan akan segera dikirim.</p>
+        {{end}}
+
+        <div class="order-info">
+            <strong>Nomor Pesanan:</strong> {{.OrderCode}}<br>
+            <strong>Total Pembayaran:</strong> Rp {{.TotalAmount}}<br>
+            <strong>Metode Pembayaran:</strong> {{.PaymentMethod}}<br>
+            <strong>Waktu Pembayaran:</strong> {{.PaidAt}}
+        </div>
+
+        {{if and .AwaitingStock .ExpectedShipDate}}
+        <div class="preorder-info">
+            <strong>Perkiraan Pengiriman:</strong> {{.ExpectedShipDate}}
+        </div>
+        {{end}}
+
+        <p>Kami akan mengirimkan email konfirmasi pengiriman setelah pesanan Anda dikirim.</p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+    </div>
+</body>
+</html>',
+    true
+)
+ON CONFLICT (template_key) DO UPDATE SET
+    name = EXCLUDED.name,
+    subject_template = EXCLUDED.subject_template,
+    html_template = EXCLUDED.html_template,
+    is_active = EXCLUDED.is_active,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Verify
+SELECT column_name, data_type, is_nullable
+FROM information_schema.columns
+WHERE (table_name = 'product_variants' AND column_name IN ('inventory_policy', 'backorder_limit', 'expected_ship_date'))
+   OR (table_name = 'order_items' AND column_name IN ('backordered_quantity', 'expected_ship_date'))
+   OR (table_name = 'orders' AND column_name IN ('awaiting_stock', 'expected_ship_date'));
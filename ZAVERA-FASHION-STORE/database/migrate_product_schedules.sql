@@ -0,0 +1,52 @@
+-- Migration: Scheduled product publishing and price changes
+-- Date: 2026-10-16
+-- Description: Future-dated publish/unpublish, permanent price changes and time-boxed sales.
+--              Sales set products.compare_at_price to the regular price and revert when they end.
+--              Applied by ProductScheduleJob, which writes every change to admin_audit_log.
+
+-- Strike-through price shown while a product is on sale
+ALTER TABLE products ADD COLUMN IF NOT EXISTS compare_at_price DECIMAL(12, 2);
+
+COMMENT ON COLUMN products.compare_at_price IS 'Regular price shown struck through while on sale; NULL when not on sale';
+
+-- Audit action for schedule entries applied by the job
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_enum WHERE enumlabel = 'PRODUCT_SCHEDULE' AND enumtypid = 'admin_action_type'::regtype) THEN
+        ALTER TYPE admin_action_type ADD VALUE 'PRODUCT_SCHEDULE';
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS product_schedules (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,                -- PUBLISH, UNPUBLISH, PRICE_CHANGE, SALE
+    run_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,                          -- SALE only: when the regular price comes back
+    new_price DECIMAL(12, 2),                   -- PRICE_CHANGE and SALE
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    previous_price DECIMAL(12, 2),              -- Captured when applied
+    previous_compare_at_price DECIMAL(12, 2),
+    note TEXT,
+    result_note TEXT,                           -- Set by the job, e.g. why a sale was not reverted
+    created_by VARCHAR(255),
+    applied_at TIMESTAMP,
+    completed_at TIMESTAMP,                     -- SALE reverted, or entry skipped/failed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_product_schedule_action CHECK (action IN ('PUBLISH', 'UNPUBLISH', 'PRICE_CHANGE', 'SALE')),
+    CONSTRAINT chk_product_schedule_status CHECK (status IN ('PENDING', 'ACTIVE', 'APPLIED', 'COMPLETED', 'CANCELLED', 'FAILED')),
+    CONSTRAINT chk_product_schedule_price CHECK (action NOT IN ('PRICE_CHANGE', 'SALE') OR new_price > 0),
+    CONSTRAINT chk_product_schedule_window CHECK (action <> 'SALE' OR (ends_at IS NOT NULL AND ends_at > run_at))
+);
+
+-- Job lookups: due entries and running sales
+CREATE INDEX IF NOT EXISTS idx_product_schedules_due ON product_schedules(run_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_product_schedules_active ON product_schedules(ends_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_product_schedules_product ON product_schedules(product_id, run_at DESC);
+
+COMMENT ON TABLE product_schedules IS 'Future-dated product changes applied by ProductScheduleJob';
+COMMENT ON COLUMN product_schedules.status IS 'PENDING -> APPLIED (one-off) or ACTIVE -> COMPLETED (sale); CANCELLED by admin; FAILED on error';
+
+-- Verify
+SELECT table_name FROM information_schema.tables WHERE table_name = 'product_schedules';
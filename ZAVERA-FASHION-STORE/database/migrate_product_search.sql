@@ -0,0 +1,35 @@
+-- Migration: Full-text product search
+-- Date: 2026-10-16
+-- Description: Search vector and trigram indexes for GET /api/products search and facets
+
+-- Trigram matching for typo-tolerant name/brand search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Weighted search document: name > brand > material > description
+-- 'simple' config is used because product copy mixes Indonesian and English
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS search_vector tsvector
+GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(brand, '')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(material, '')), 'C') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'D')
+) STORED;
+
+COMMENT ON COLUMN products.search_vector IS 'Weighted full-text document built from name, brand, material and description';
+
+-- Indexes for search
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING gin(search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin(name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_brand_trgm ON products USING gin(brand gin_trgm_ops);
+
+-- Indexes for facet filters
+CREATE INDEX IF NOT EXISTS idx_products_subcategory ON products(subcategory);
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price);
+CREATE INDEX IF NOT EXISTS idx_variants_size ON product_variants(size) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_variants_color ON product_variants(color) WHERE is_active = true;
+
+-- Verify
+SELECT column_name, data_type
+FROM information_schema.columns
+WHERE table_name = 'products' AND column_name = 'search_vector';
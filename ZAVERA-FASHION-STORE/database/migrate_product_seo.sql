@@ -0,0 +1,31 @@
+-- Migration: Product SEO metadata and slug history
+-- Date: 2026-10-16
+-- Description: Admin-editable SEO fields and old-slug redirects for GET /api/products/slug/:slug
+
+-- SEO fields (empty = storefront falls back to name/description/primary image)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS meta_title VARCHAR(255);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS meta_description TEXT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS canonical_url TEXT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS og_image_url TEXT;
+
+COMMENT ON COLUMN products.meta_title IS 'SEO <title> override';
+COMMENT ON COLUMN products.meta_description IS 'SEO meta description override';
+COMMENT ON COLUMN products.canonical_url IS 'Absolute canonical URL override';
+COMMENT ON COLUMN products.og_image_url IS 'Open Graph image override';
+
+-- Slug history: every slug a product used to have, so old links keep working after a rename
+CREATE TABLE IF NOT EXISTS product_slug_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_slug VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_history_product ON product_slug_history(product_id);
+
+COMMENT ON TABLE product_slug_history IS 'Previous product slugs, resolved to the current slug with a 301 redirect';
+
+-- Verify
+SELECT column_name, data_type
+FROM information_schema.columns
+WHERE table_name = 'products' AND column_name IN ('meta_title', 'meta_description', 'canonical_url', 'og_image_url');
@@ -0,0 +1,62 @@
+-- Migration: Automatic cart promotions
+-- Date: 2026-10-16
+-- Description: Promotions apply without a code whenever their conditions are met: percent off
+--              once enough items are in the cart, tiered spend discounts, free shipping over a
+--              spend (optionally to some provinces) and flash-sale prices per variant. The cart
+--              and checkout evaluate them the same way; vouchers apply on top.
+
+-- ============================================
+-- 1. PROMOTIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS promotions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,                  -- Shown to customers, e.g. "Buy 2, get 10% off"
+    description TEXT,
+    type VARCHAR(20) NOT NULL,
+    min_quantity INT NOT NULL DEFAULT 0,         -- QUANTITY_DISCOUNT: items in scope needed
+    percent DECIMAL(5, 2) NOT NULL DEFAULT 0,    -- QUANTITY_DISCOUNT
+    min_spend DECIMAL(12, 2) NOT NULL DEFAULT 0, -- FREE_SHIPPING
+    max_discount DECIMAL(12, 2),                 -- Cap for SPEND_TIER percentages and FREE_SHIPPING
+    tiers JSONB NOT NULL DEFAULT '[]',           -- SPEND_TIER: [{"min_spend": 500000, "percent": 5}, {"min_spend": 1000000, "amount": 100000}]
+    category_id INT REFERENCES categories(id) ON DELETE SET NULL, -- Includes subcategories
+    brand VARCHAR(100),
+    provinces TEXT[] NOT NULL DEFAULT '{}',      -- FREE_SHIPPING destinations; empty = everywhere
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE promotions DROP CONSTRAINT IF EXISTS chk_promotion_type;
+ALTER TABLE promotions ADD CONSTRAINT chk_promotion_type
+    CHECK (type IN ('QUANTITY_DISCOUNT', 'SPEND_TIER', 'FREE_SHIPPING', 'FLASH_SALE'));
+
+ALTER TABLE promotions DROP CONSTRAINT IF EXISTS chk_promotion_values;
+ALTER TABLE promotions ADD CONSTRAINT chk_promotion_values CHECK (
+    min_quantity >= 0 AND percent >= 0 AND percent <= 100 AND min_spend >= 0
+    AND (max_discount IS NULL OR max_discount > 0)
+    AND (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions(type) WHERE is_active = true;
+
+COMMENT ON TABLE promotions IS 'Cart promotions applied automatically, without a code';
+
+-- ============================================
+-- 2. FLASH-SALE PRICES
+-- ============================================
+CREATE TABLE IF NOT EXISTS promotion_variant_prices (
+    promotion_id INT NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    sale_price DECIMAL(12, 2) NOT NULL CHECK (sale_price >= 0),
+    PRIMARY KEY (promotion_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_variant_prices_variant ON promotion_variant_prices(variant_id);
+
+-- Verify
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_name IN ('promotions', 'promotion_variant_prices');
@@ -0,0 +1,117 @@
+-- Migration: Suppliers, purchase orders and inbound receiving
+-- Date: 2026-10-16
+-- Description: Restocking goes through purchase orders to suppliers. Each receipt (partial or full)
+--              adds stock to the PO's warehouse with a RECEIPT entry on the stock ledger and feeds
+--              the line cost into product_variants.cost_per_item as a moving average.
+
+CREATE TABLE IF NOT EXISTS suppliers (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(150) NOT NULL,
+    contact_name VARCHAR(100),
+    email VARCHAR(255),
+    phone VARCHAR(30),
+    address TEXT,
+    lead_time_days INT NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+    notes TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN suppliers.lead_time_days IS 'Usual days from sending a PO to delivery; used as the default expected date';
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id SERIAL PRIMARY KEY,
+    po_number VARCHAR(30) UNIQUE,
+    supplier_id INT NOT NULL REFERENCES suppliers(id),
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'DRAFT',
+    expected_at DATE,
+    notes TEXT,
+    created_by VARCHAR(255) NOT NULL,
+    sent_at TIMESTAMP,
+    received_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_purchase_order_status CHECK (status IN (
+        'DRAFT', 'SENT', 'PARTIALLY_RECEIVED', 'RECEIVED', 'CANCELLED'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_status ON purchase_orders(status, expected_at);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_supplier ON purchase_orders(supplier_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS purchase_order_items (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id),
+    quantity_ordered INT NOT NULL CHECK (quantity_ordered > 0),
+    quantity_received INT NOT NULL DEFAULT 0 CHECK (quantity_received >= 0),
+    cost_per_item DECIMAL(12,2) NOT NULL CHECK (cost_per_item >= 0),
+    expected_at DATE,
+    CONSTRAINT uq_purchase_order_variant UNIQUE (purchase_order_id, variant_id),
+    CONSTRAINT chk_purchase_order_item_received CHECK (quantity_received <= quantity_ordered)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_order_items_variant ON purchase_order_items(variant_id);
+
+COMMENT ON COLUMN purchase_order_items.expected_at IS 'Overrides the PO expected date for this line';
+
+CREATE TABLE IF NOT EXISTS purchase_order_receipts (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_orders(id),
+    received_by VARCHAR(255) NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_receipt_items (
+    id SERIAL PRIMARY KEY,
+    receipt_id INT NOT NULL REFERENCES purchase_order_receipts(id) ON DELETE CASCADE,
+    purchase_order_item_id INT NOT NULL REFERENCES purchase_order_items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    ledger_id BIGINT NOT NULL REFERENCES stock_ledger(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_order_receipts_po ON purchase_order_receipts(purchase_order_id);
+
+-- Receipts are their own ledger movement
+ALTER TABLE stock_ledger DROP CONSTRAINT IF EXISTS chk_stock_ledger_type;
+ALTER TABLE stock_ledger ADD CONSTRAINT chk_stock_ledger_type CHECK (movement_type IN (
+    'OPENING', 'RESERVE', 'RELEASE', 'RESTOCK', 'ADJUSTMENT', 'TRANSFER_IN', 'TRANSFER_OUT', 'RECEIPT'
+));
+
+-- Incoming stock: ordered but not yet received on POs that are out with the supplier
+CREATE OR REPLACE VIEW variant_stock_summary AS
+SELECT
+    pv.id as variant_id,
+    pv.product_id,
+    p.name as product_name,
+    pv.sku,
+    pv.variant_name,
+    pv.stock_quantity,
+    COALESCE(SUM(CASE WHEN sr.status = 'active' AND sr.expires_at > CURRENT_TIMESTAMP THEN sr.quantity ELSE 0 END), 0) as reserved_quantity,
+    get_available_stock(pv.id) as available_quantity,
+    COALESCE(incoming.quantity, 0)::INT as incoming_quantity,
+    incoming.next_expected_at
+FROM product_variants pv
+JOIN products p ON p.id = pv.product_id
+-- Holds already on the ledger are out of stock_quantity; counting them again would double them
+LEFT JOIN stock_reservations sr ON sr.variant_id = pv.id AND NOT sr.held_in_ledger
+LEFT JOIN (
+    SELECT poi.variant_id,
+           SUM(poi.quantity_ordered - poi.quantity_received) as quantity,
+           MIN(COALESCE(poi.expected_at, po.expected_at)) as next_expected_at
+    FROM purchase_order_items poi
+    JOIN purchase_orders po ON po.id = poi.purchase_order_id
+    WHERE po.status IN ('SENT', 'PARTIALLY_RECEIVED')
+    AND poi.quantity_received < poi.quantity_ordered
+    GROUP BY poi.variant_id
+) incoming ON incoming.variant_id = pv.id
+GROUP BY pv.id, p.id, p.name, incoming.quantity, incoming.next_expected_at;
+
+-- Verify
+SELECT table_name FROM information_schema.tables
+WHERE table_name IN ('suppliers', 'purchase_orders', 'purchase_order_items', 'purchase_order_receipts', 'purchase_order_receipt_items');
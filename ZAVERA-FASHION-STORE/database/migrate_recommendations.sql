@@ -0,0 +1,37 @@
+-- Migration: Product recommendations
+-- Date: 2026-10-16
+-- Description: Precomputed "frequently bought together" pairs from order_items co-occurrence
+--              and admin-curated "complete the look" links
+
+-- Co-purchase matrix (rebuilt by the recommendation refresh job)
+CREATE TABLE IF NOT EXISTS product_co_purchases (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    order_count INT NOT NULL,               -- Orders containing both products
+    score NUMERIC(8, 6) NOT NULL,           -- order_count / sqrt(orders(product) * orders(related))
+    refreshed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, related_product_id),
+    CONSTRAINT chk_co_purchase_distinct CHECK (product_id <> related_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_co_purchases_rank ON product_co_purchases(product_id, score DESC);
+
+COMMENT ON TABLE product_co_purchases IS 'Frequently bought together pairs; both directions stored, top N per product';
+
+-- Curated "complete the look" links
+CREATE TABLE IF NOT EXISTS product_look_links (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    linked_product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, linked_product_id),
+    CONSTRAINT chk_look_link_distinct CHECK (product_id <> linked_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_look_links_position ON product_look_links(product_id, position);
+
+COMMENT ON TABLE product_look_links IS 'Admin-curated outfit pairings shown as "complete the look"';
+
+-- Verify
+SELECT table_name FROM information_schema.tables
+WHERE table_name IN ('product_co_purchases', 'product_look_links');
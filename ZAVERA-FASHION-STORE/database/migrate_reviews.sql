@@ -0,0 +1,49 @@
+-- Migration: Product reviews
+-- Date: 2026-10-16
+-- Description: Star ratings, text and fit feedback per delivered order item, with photos
+--              and admin moderation. Only APPROVED reviews count towards product ratings.
+
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL,
+    order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    order_item_id INTEGER UNIQUE REFERENCES order_items(id) ON DELETE SET NULL, -- One review per purchased item
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewer_name VARCHAR(255) NOT NULL,
+    rating SMALLINT NOT NULL,
+    title VARCHAR(150),
+    body TEXT,
+    fit VARCHAR(10),                        -- SMALL, TRUE, LARGE (runs small / true to size / runs large)
+    is_verified_purchase BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    moderation_note TEXT,
+    moderated_by VARCHAR(255),
+    moderated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_review_rating CHECK (rating BETWEEN 1 AND 5),
+    CONSTRAINT chk_review_fit CHECK (fit IS NULL OR fit IN ('SMALL', 'TRUE', 'LARGE')),
+    CONSTRAINT chk_review_status CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product ON product_reviews(product_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status ON product_reviews(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_user ON product_reviews(user_id);
+
+COMMENT ON TABLE product_reviews IS 'Customer reviews of delivered order items; moderated before publishing';
+COMMENT ON COLUMN product_reviews.is_verified_purchase IS 'Order belonged to the reviewer and was DELIVERED or COMPLETED when reviewed';
+
+CREATE TABLE IF NOT EXISTS product_review_photos (
+    id SERIAL PRIMARY KEY,
+    review_id INTEGER NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    image_url TEXT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_review_photos_review ON product_review_photos(review_id, position);
+
+-- Verify
+SELECT table_name FROM information_schema.tables
+WHERE table_name IN ('product_reviews', 'product_review_photos');
@@ -0,0 +1,155 @@
+-- Migration: Size charts and fit profiles
+-- Date: 2026-10-16
+-- Description: Size charts hold body measurements per size (cm; weight in kg) and apply to a
+--              category (and its subcategories), a brand, or a brand within a category. The most
+--              specific active chart wins: brand before category, deeper categories before their
+--              parents. Variants are linked to the chart row of their size, kept current by
+--              triggers. Customers keep a fit profile used to recommend a size.
+
+-- ============================================
+-- 1. SIZE CHARTS
+-- ============================================
+CREATE TABLE IF NOT EXISTS size_charts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    brand VARCHAR(100),
+    notes TEXT,                                 -- e.g. how to measure, fit of the cut
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_size_chart_scope CHECK (category_id IS NOT NULL OR brand IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_size_charts_category ON size_charts(category_id) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_size_charts_brand ON size_charts(LOWER(brand)) WHERE is_active = true;
+
+COMMENT ON TABLE size_charts IS 'Measurements per size for a category, a brand, or a brand within a category';
+
+CREATE TABLE IF NOT EXISTS size_chart_rows (
+    id SERIAL PRIMARY KEY,
+    chart_id INT NOT NULL REFERENCES size_charts(id) ON DELETE CASCADE,
+    size_label VARCHAR(20) NOT NULL,
+    position INT NOT NULL DEFAULT 0,            -- Smallest first
+    chest_min_cm DECIMAL(5, 1),
+    chest_max_cm DECIMAL(5, 1),
+    waist_min_cm DECIMAL(5, 1),
+    waist_max_cm DECIMAL(5, 1),
+    hip_min_cm DECIMAL(5, 1),
+    hip_max_cm DECIMAL(5, 1),
+    height_min_cm DECIMAL(5, 1),
+    height_max_cm DECIMAL(5, 1),
+    weight_min_kg DECIMAL(5, 1),
+    weight_max_kg DECIMAL(5, 1),
+    length_cm DECIMAL(5, 1),                    -- Garment length, for display
+    CONSTRAINT uq_size_chart_row UNIQUE (chart_id, size_label)
+);
+
+CREATE INDEX IF NOT EXISTS idx_size_chart_rows_chart ON size_chart_rows(chart_id, position);
+
+COMMENT ON COLUMN size_chart_rows.size_label IS 'Matched case-insensitively against product_variants.size';
+
+-- ============================================
+-- 2. VARIANT SIZE -> CHART ROW
+-- ============================================
+ALTER TABLE product_variants
+ADD COLUMN IF NOT EXISTS size_chart_row_id INT REFERENCES size_chart_rows(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN product_variants.size_chart_row_id IS 'Row of the product''s size chart for this size; maintained by triggers';
+
+-- The chart that applies to a product, NULL when none does
+CREATE OR REPLACE FUNCTION resolve_size_chart(p_product_id INT)
+RETURNS INT AS $$
+    SELECT sc.id
+    FROM products p
+    LEFT JOIN categories pc ON pc.id = p.category_id
+    JOIN size_charts sc ON sc.is_active = true
+    LEFT JOIN categories cc ON cc.id = sc.category_id
+    WHERE p.id = p_product_id
+    AND (sc.brand IS NULL OR LOWER(sc.brand) = LOWER(COALESCE(p.brand, '')))
+    AND (sc.category_id IS NULL OR pc.path = cc.path OR pc.path LIKE cc.path || '/%')
+    ORDER BY (sc.brand IS NOT NULL) DESC, COALESCE(cc.depth, -1) DESC, sc.id
+    LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION resolve_size_chart_row(p_product_id INT, p_size VARCHAR)
+RETURNS INT AS $$
+    SELECT r.id FROM size_chart_rows r
+    WHERE r.chart_id = resolve_size_chart(p_product_id)
+    AND UPPER(r.size_label) = UPPER(TRIM(p_size));
+$$ LANGUAGE sql STABLE;
+
+-- Relinks the variants of one product, or of every product when p_product_id is NULL.
+-- Returns the number of variants whose link changed.
+CREATE OR REPLACE FUNCTION link_variant_sizes(p_product_id INT DEFAULT NULL)
+RETURNS INT AS $$
+DECLARE
+    v_count INT;
+BEGIN
+    UPDATE product_variants pv
+    SET size_chart_row_id = t.row_id
+    FROM (
+        SELECT id, resolve_size_chart_row(product_id, size) AS row_id
+        FROM product_variants
+        WHERE p_product_id IS NULL OR product_id = p_product_id
+    ) t
+    WHERE pv.id = t.id AND pv.size_chart_row_id IS DISTINCT FROM t.row_id;
+
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_variant_size_chart_row()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.size_chart_row_id := resolve_size_chart_row(NEW.product_id, NEW.size);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_variant_size_chart_row ON product_variants;
+CREATE TRIGGER trigger_variant_size_chart_row
+BEFORE INSERT OR UPDATE OF size, product_id ON product_variants
+FOR EACH ROW
+EXECUTE FUNCTION set_variant_size_chart_row();
+
+-- A product moving brand or category may fall under another chart
+CREATE OR REPLACE FUNCTION relink_product_variant_sizes()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM link_variant_sizes(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_product_size_chart ON products;
+CREATE TRIGGER trigger_product_size_chart
+AFTER UPDATE OF brand, category_id ON products
+FOR EACH ROW
+WHEN (OLD.brand IS DISTINCT FROM NEW.brand OR OLD.category_id IS DISTINCT FROM NEW.category_id)
+EXECUTE FUNCTION relink_product_variant_sizes();
+
+-- ============================================
+-- 3. CUSTOMER FIT PROFILES
+-- ============================================
+CREATE TABLE IF NOT EXISTS customer_fit_profiles (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    height_cm DECIMAL(5, 1),
+    weight_kg DECIMAL(5, 1),
+    chest_cm DECIMAL(5, 1),
+    waist_cm DECIMAL(5, 1),
+    hip_cm DECIMAL(5, 1),
+    usual_sizes JSONB NOT NULL DEFAULT '{}',    -- {"brand": "M"}, brand in lowercase
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE customer_fit_profiles IS 'Body measurements and usual size per brand, for size recommendations';
+
+SELECT link_variant_sizes(NULL);
+
+-- Verify
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_name IN ('size_charts', 'size_chart_rows', 'customer_fit_profiles')
+   OR (table_name = 'product_variants' AND column_name = 'size_chart_row_id');
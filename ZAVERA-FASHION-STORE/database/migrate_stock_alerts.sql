@@ -0,0 +1,161 @@
+-- Migration: Back-in-stock and price-drop alerts
+-- Date: 2026-10-16
+-- Description: Wishlisted products email their owners when a variant comes back in stock or the
+--              price falls below what they saw when wishlisting. Users can also subscribe to a
+--              sold-out size/color directly. Alert emails are logged in email_logs with a
+--              reference so they can be deduplicated and rate-limited per user.
+
+-- ============================================
+-- 1. PRICE SEEN WHEN WISHLISTING
+-- ============================================
+ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS price_seen DECIMAL(12,2);
+
+UPDATE wishlists w
+SET price_seen = p.price
+FROM products p
+WHERE p.id = w.product_id AND w.price_seen IS NULL;
+
+COMMENT ON COLUMN wishlists.price_seen IS 'Product price when wishlisted; lowered after each price-drop email';
+
+-- ============================================
+-- 2. VARIANT SUBSCRIPTIONS (sold-out size/color)
+-- ============================================
+CREATE TABLE IF NOT EXISTS stock_alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    notified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_stock_alert_status CHECK (status IN ('ACTIVE', 'NOTIFIED', 'CANCELLED'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_alerts_active
+    ON stock_alerts(user_id, variant_id) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_stock_alerts_variant ON stock_alerts(variant_id) WHERE status = 'ACTIVE';
+
+COMMENT ON TABLE stock_alerts IS 'One-shot back-in-stock subscriptions to a variant; NOTIFIED once emailed';
+
+-- ============================================
+-- 3. LAST SEEN AVAILABILITY PER VARIANT
+-- ============================================
+-- Stock changes through many paths (ledger, reservations, holds), so the alert job compares
+-- get_available_stock() against the previous run instead of hooking each of them.
+CREATE TABLE IF NOT EXISTS variant_availability_state (
+    variant_id INT PRIMARY KEY REFERENCES product_variants(id) ON DELETE CASCADE,
+    last_available INT NOT NULL,
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================
+-- 4. EMAIL LOG REFERENCE (dedup + rate limit)
+-- ============================================
+ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS reference VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_email_logs_user_template
+    ON email_logs(user_id, template_key, created_at DESC) WHERE user_id IS NOT NULL;
+
+COMMENT ON COLUMN email_logs.reference IS 'What a non-order email was about, e.g. variant:12 or product:5@150000';
+
+-- ============================================
+-- 5. ALERT EMAIL TEMPLATES
+-- ============================================
+INSERT INTO email_templates (template_key, name, subject_template, html_template, is_active) VALUES
+(
+    'BACK_IN_STOCK',
+    'Back In Stock',
+    '🔔 {{.ProductName}} tersedia kembali',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .product-info { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+        .btn { display: inline-block; background: #000; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <p>Halo {{.CustomerName}},</p>
+        <p>Kabar baik! Produk yang Anda tunggu sudah tersedia kembali. Stok terbatas, jangan sampai kehabisan lagi.</p>
+
+        <div class="product-info">
+            <strong>{{.ProductName}}</strong><br>
+            {{if .VariantName}}Varian: {{.VariantName}}<br>{{end}}
+            Harga: Rp {{.Price}}
+        </div>
+
+        <p style="text-align: center; margin: 20px 0;">
+            <a href="{{.ProductURL}}" class="btn">Beli Sekarang</a>
+        </p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+        <p>Anda menerima email ini karena produk ini ada di wishlist atau notifikasi stok Anda.</p>
+    </div>
+</body>
+</html>',
+    true
+),
+(
+    'PRICE_DROP',
+    'Price Drop',
+    '💸 Harga {{.ProductName}} turun',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .product-info { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .old-price { text-decoration: line-through; color: #999; }
+        .new-price { color: #DC2626; font-weight: bold; font-size: 18px; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+        .btn { display: inline-block; background: #000; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <p>Halo {{.CustomerName}},</p>
+        <p>Harga produk di wishlist Anda baru saja turun.</p>
+
+        <div class="product-info">
+            <strong>{{.ProductName}}</strong><br>
+            <span class="old-price">Rp {{.OldPrice}}</span>
+            <span class="new-price">Rp {{.NewPrice}}</span>
+        </div>
+
+        <p style="text-align: center; margin: 20px 0;">
+            <a href="{{.ProductURL}}" class="btn">Lihat Produk</a>
+        </p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+        <p>Anda menerima email ini karena produk ini ada di wishlist Anda.</p>
+    </div>
+</body>
+</html>',
+    true
+)
+ON CONFLICT (template_key) DO UPDATE SET
+    name = EXCLUDED.name,
+    subject_template = EXCLUDED.subject_template,
+    html_template = EXCLUDED.html_template,
+    is_active = EXCLUDED.is_active,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Verify
+SELECT table_name FROM information_schema.tables WHERE table_name IN ('stock_alerts', 'variant_availability_state');
+SELECT template_key FROM email_templates WHERE template_key IN ('BACK_IN_STOCK', 'PRICE_DROP');
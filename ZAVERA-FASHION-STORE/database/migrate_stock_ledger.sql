@@ -0,0 +1,397 @@
+-- Migration: Variant-level stock ledger
+-- Date: 2026-10-16
+-- Description: One append-only ledger keyed by variant and warehouse that every stock change writes to
+--              (checkout reservation, release on cancel/expire, refund restock, manual adjustment,
+--              transfers). apply_stock_movement() is the only writer of warehouse_stock; it records the
+--              ledger entry with the resulting balance and refreshes the derived counters:
+--                warehouse_stock.quantity       = SUM(ledger) per warehouse and variant
+--                product_variants.stock_quantity = SUM(warehouse_stock) per variant
+--                products.stock                  = SUM(active variants) per product (deprecated mirror)
+--              Products without variants get a 'Default' variant so their stock lives in the ledger too.
+--              Legacy direct writes to stock_quantity / products.stock are turned into ADJUSTMENT entries.
+--              stock_movements (product-keyed) is kept for history but no longer written.
+
+CREATE TABLE IF NOT EXISTS stock_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    variant_id INT NOT NULL,                    -- No FK: history outlives deleted variants
+    product_id INT NOT NULL,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    movement_type VARCHAR(20) NOT NULL,
+    quantity INT NOT NULL,                      -- Signed change to on-hand stock
+    balance_after INT NOT NULL,                 -- On hand at the warehouse after this entry
+    order_id INT,                               -- No FK: entries are never updated
+    reference VARCHAR(100),                     -- e.g. refund code, transfer pair
+    notes TEXT,
+    actor VARCHAR(255) NOT NULL DEFAULT 'system',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_stock_ledger_type CHECK (movement_type IN (
+        'OPENING', 'RESERVE', 'RELEASE', 'RESTOCK', 'ADJUSTMENT', 'TRANSFER_IN', 'TRANSFER_OUT'
+    )),
+    CONSTRAINT chk_stock_ledger_quantity CHECK (quantity <> 0),
+    CONSTRAINT chk_stock_ledger_balance CHECK (balance_after >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_ledger_variant ON stock_ledger(variant_id, warehouse_id, id);
+CREATE INDEX IF NOT EXISTS idx_stock_ledger_product ON stock_ledger(product_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_stock_ledger_order ON stock_ledger(order_id) WHERE order_id IS NOT NULL;
+
+COMMENT ON TABLE stock_ledger IS 'Append-only stock ledger; on-hand stock is the sum of quantity per warehouse and variant';
+COMMENT ON COLUMN stock_ledger.balance_after IS 'Running total of quantity for the same warehouse and variant, including this entry';
+COMMENT ON TABLE stock_movements IS 'Deprecated: product-level stock history, superseded by stock_ledger';
+COMMENT ON COLUMN products.stock IS 'Deprecated: mirror of the active variants total, maintained by apply_stock_movement()';
+
+-- Append-only
+CREATE OR REPLACE FUNCTION reject_stock_ledger_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_ledger is append-only; record a correcting entry instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_stock_ledger_append_only ON stock_ledger;
+CREATE TRIGGER trigger_stock_ledger_append_only
+BEFORE UPDATE OR DELETE ON stock_ledger
+FOR EACH ROW
+EXECUTE FUNCTION reject_stock_ledger_change();
+
+-- The variant that carries a product's stock when an order line or a stock update names only the
+-- product: its default variant, else its first variant, else a new 'Default' variant
+CREATE OR REPLACE FUNCTION ensure_default_variant(p_product_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_variant_id INT;
+BEGIN
+    SELECT id INTO v_variant_id
+    FROM product_variants
+    WHERE product_id = p_product_id
+    ORDER BY is_default DESC, is_active DESC, position, id
+    LIMIT 1;
+
+    IF v_variant_id IS NULL THEN
+        INSERT INTO product_variants (product_id, sku, variant_name, stock_quantity, is_active, is_default)
+        VALUES (p_product_id, 'ZV-' || p_product_id || '-DEFAULT', 'Default', 0, true, true)
+        RETURNING id INTO v_variant_id;
+    END IF;
+
+    RETURN v_variant_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- products.stock is a mirror of the product's active variants
+CREATE OR REPLACE FUNCTION refresh_product_stock(p_product_id INT)
+RETURNS void AS $$
+DECLARE
+    v_guard TEXT;
+BEGIN
+    v_guard := current_setting('zavera.stock_ledger', true);
+    PERFORM set_config('zavera.stock_ledger', 'on', true);
+
+    UPDATE products
+    SET stock = (
+        SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants
+        WHERE product_id = p_product_id AND is_active = true
+    )
+    WHERE id = p_product_id;
+
+    PERFORM set_config('zavera.stock_ledger', COALESCE(v_guard, ''), true);
+END;
+$$ LANGUAGE plpgsql;
+
+-- The single writer of on-hand stock. Moves warehouse_stock by p_quantity (NULL warehouse = default),
+-- appends the ledger entry and refreshes the variant total and the product mirror.
+-- Raises check_violation when the warehouse would go negative.
+CREATE OR REPLACE FUNCTION apply_stock_movement(
+    p_variant_id INT,
+    p_warehouse_id INT,
+    p_movement_type VARCHAR,
+    p_quantity INT,
+    p_order_id INT DEFAULT NULL,
+    p_reference VARCHAR DEFAULT NULL,
+    p_notes TEXT DEFAULT NULL,
+    p_actor VARCHAR DEFAULT 'system'
+)
+RETURNS stock_ledger AS $$
+DECLARE
+    v_warehouse_id INT := p_warehouse_id;
+    v_product_id INT;
+    v_balance INT;
+    v_guard TEXT;
+    v_entry stock_ledger;
+BEGIN
+    IF p_quantity = 0 THEN
+        RAISE EXCEPTION 'Stock movement quantity must not be zero';
+    END IF;
+
+    SELECT product_id INTO v_product_id FROM product_variants WHERE id = p_variant_id;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Variant % not found', p_variant_id;
+    END IF;
+
+    IF v_warehouse_id IS NULL THEN
+        SELECT id INTO v_warehouse_id FROM warehouses WHERE is_default = true;
+        IF v_warehouse_id IS NULL THEN
+            RAISE EXCEPTION 'No default warehouse';
+        END IF;
+    END IF;
+
+    -- Let the legacy triggers below know this write is already on the ledger
+    v_guard := current_setting('zavera.stock_ledger', true);
+    PERFORM set_config('zavera.stock_ledger', 'on', true);
+
+    INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+    VALUES (v_warehouse_id, p_variant_id, 0)
+    ON CONFLICT (warehouse_id, variant_id) DO NOTHING;
+
+    SELECT quantity INTO v_balance
+    FROM warehouse_stock
+    WHERE warehouse_id = v_warehouse_id AND variant_id = p_variant_id
+    FOR UPDATE;
+
+    v_balance := v_balance + p_quantity;
+    IF v_balance < 0 THEN
+        RAISE EXCEPTION 'Insufficient stock for variant % at warehouse %: on hand %, requested %',
+            p_variant_id, v_warehouse_id, v_balance - p_quantity, -p_quantity
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    UPDATE warehouse_stock
+    SET quantity = v_balance, updated_at = CURRENT_TIMESTAMP
+    WHERE warehouse_id = v_warehouse_id AND variant_id = p_variant_id;
+
+    INSERT INTO stock_ledger (
+        variant_id, product_id, warehouse_id, movement_type, quantity, balance_after,
+        order_id, reference, notes, actor
+    ) VALUES (
+        p_variant_id, v_product_id, v_warehouse_id, p_movement_type, p_quantity, v_balance,
+        p_order_id, p_reference, p_notes, COALESCE(NULLIF(p_actor, ''), 'system')
+    ) RETURNING * INTO v_entry;
+
+    UPDATE product_variants
+    SET stock_quantity = (SELECT COALESCE(SUM(quantity), 0) FROM warehouse_stock WHERE variant_id = p_variant_id),
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_variant_id;
+
+    PERFORM refresh_product_stock(v_product_id);
+
+    PERFORM set_config('zavera.stock_ledger', COALESCE(v_guard, ''), true);
+    RETURN v_entry;
+END;
+$$ LANGUAGE plpgsql;
+
+-- warehouse_stock is derived from the ledger; only apply_stock_movement() (and the reconciliation
+-- rebuild) may write it. Deletes are allowed so deleting a variant still cascades.
+CREATE OR REPLACE FUNCTION guard_warehouse_stock_write()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('zavera.stock_ledger', true) IS DISTINCT FROM 'on' THEN
+        RAISE EXCEPTION 'warehouse_stock is written through apply_stock_movement()';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_warehouse_stock_sync_variant ON warehouse_stock;
+DROP FUNCTION IF EXISTS sync_variant_stock_from_warehouses();
+
+-- Legacy direct writes to product_variants.stock_quantity become ledger entries at the default warehouse
+CREATE OR REPLACE FUNCTION apply_variant_stock_to_default_warehouse()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_delta INT;
+BEGIN
+    IF current_setting('zavera.stock_ledger', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    v_delta := NEW.stock_quantity - CASE WHEN TG_OP = 'INSERT' THEN 0 ELSE OLD.stock_quantity END;
+    IF v_delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM apply_stock_movement(
+        NEW.id, NULL,
+        CASE WHEN TG_OP = 'INSERT' THEN 'OPENING' ELSE 'ADJUSTMENT' END,
+        v_delta, NULL, NULL, 'Direct stock_quantity write', 'system'
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Legacy direct writes to products.stock land on the product's default variant
+CREATE OR REPLACE FUNCTION apply_product_stock_to_ledger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_delta INT;
+BEGIN
+    IF current_setting('zavera.stock_ledger', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    v_delta := NEW.stock - CASE WHEN TG_OP = 'INSERT' THEN 0 ELSE OLD.stock END;
+    IF v_delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM apply_stock_movement(
+        ensure_default_variant(NEW.id), NULL,
+        CASE WHEN TG_OP = 'INSERT' THEN 'OPENING' ELSE 'ADJUSTMENT' END,
+        v_delta, NULL, NULL, 'Direct products.stock write', 'system'
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- complete_reservation deducts through the ledger
+CREATE OR REPLACE FUNCTION complete_reservation(p_reservation_id INT, p_order_id INT)
+RETURNS void AS $$
+DECLARE
+    v_variant_id INT;
+    v_quantity INT;
+    v_warehouse_id INT;
+BEGIN
+    SELECT variant_id, quantity, warehouse_id INTO v_variant_id, v_quantity, v_warehouse_id
+    FROM stock_reservations
+    WHERE id = p_reservation_id AND status = 'active';
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Reservation not found or already processed';
+    END IF;
+
+    -- Reservations made before warehouses existed (NULL) come out of the default warehouse
+    PERFORM apply_stock_movement(
+        v_variant_id, v_warehouse_id, 'RESERVE', -v_quantity,
+        p_order_id, 'reservation:' || p_reservation_id, 'Reservation completed', 'system'
+    );
+
+    -- Mark reservation as completed
+    UPDATE stock_reservations
+    SET status = 'completed', order_id = p_order_id
+    WHERE id = p_reservation_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- expire_pending_orders returns stock through the ledger
+CREATE OR REPLACE FUNCTION expire_pending_orders()
+RETURNS INTEGER AS $$
+DECLARE
+    expired_count INTEGER;
+    order_record RECORD;
+    item_record RECORD;
+BEGIN
+    expired_count := 0;
+
+    FOR order_record IN
+        SELECT id, warehouse_id FROM orders
+        WHERE status = 'PENDING'
+        AND created_at < NOW() - INTERVAL '24 hours'
+        AND stock_reserved = true
+    LOOP
+        FOR item_record IN
+            SELECT COALESCE(variant_id, ensure_default_variant(product_id)) AS variant_id, quantity
+            FROM order_items
+            WHERE order_id = order_record.id
+        LOOP
+            PERFORM apply_stock_movement(
+                item_record.variant_id, order_record.warehouse_id, 'RELEASE', item_record.quantity,
+                order_record.id, NULL, 'Stock released on expire', 'system'
+            );
+        END LOOP;
+
+        UPDATE orders
+        SET status = 'EXPIRED',
+            stock_reserved = false,
+            updated_at = NOW()
+        WHERE id = order_record.id;
+
+        INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+        VALUES (order_record.id, 'PENDING', 'EXPIRED', 'system', 'Payment timeout - 24 hours');
+
+        expired_count := expired_count + 1;
+    END LOOP;
+
+    RETURN expired_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Opening balances, with the guard on so the legacy triggers do not count anything twice
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    PERFORM set_config('zavera.stock_ledger', 'on', true);
+
+    -- Current warehouse stock
+    INSERT INTO stock_ledger (variant_id, product_id, warehouse_id, movement_type, quantity, balance_after, notes, actor)
+    SELECT ws.variant_id, pv.product_id, ws.warehouse_id, 'OPENING', ws.quantity, ws.quantity,
+           'Opening balance', 'system:migration'
+    FROM warehouse_stock ws
+    JOIN product_variants pv ON pv.id = ws.variant_id
+    WHERE ws.quantity > 0
+    AND NOT EXISTS (
+        SELECT 1 FROM stock_ledger sl
+        WHERE sl.variant_id = ws.variant_id AND sl.warehouse_id = ws.warehouse_id
+    );
+
+    -- Products without variants: their product stock moves to a default variant
+    FOR r IN
+        SELECT p.id, p.stock FROM products p
+        WHERE NOT EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = p.id)
+    LOOP
+        IF r.stock > 0 THEN
+            PERFORM apply_stock_movement(
+                ensure_default_variant(r.id), NULL, 'OPENING', r.stock,
+                NULL, NULL, 'Opening balance (product stock)', 'system:migration'
+            );
+        ELSE
+            PERFORM ensure_default_variant(r.id);
+        END IF;
+    END LOOP;
+
+    -- The product counter becomes a mirror of its variants
+    UPDATE products p
+    SET stock = (
+        SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants
+        WHERE product_id = p.id AND is_active = true
+    );
+
+    PERFORM set_config('zavera.stock_ledger', '', true);
+END $$;
+
+DROP TRIGGER IF EXISTS trigger_warehouse_stock_guard ON warehouse_stock;
+CREATE TRIGGER trigger_warehouse_stock_guard
+BEFORE INSERT OR UPDATE ON warehouse_stock
+FOR EACH ROW
+EXECUTE FUNCTION guard_warehouse_stock_write();
+
+DROP TRIGGER IF EXISTS trigger_variant_stock_to_default_warehouse ON product_variants;
+CREATE TRIGGER trigger_variant_stock_to_default_warehouse
+AFTER INSERT OR UPDATE OF stock_quantity ON product_variants
+FOR EACH ROW
+EXECUTE FUNCTION apply_variant_stock_to_default_warehouse();
+
+DROP TRIGGER IF EXISTS trigger_product_stock_to_ledger ON products;
+CREATE TRIGGER trigger_product_stock_to_ledger
+AFTER INSERT OR UPDATE OF stock ON products
+FOR EACH ROW
+EXECUTE FUNCTION apply_product_stock_to_ledger();
+
+-- Activating, deactivating or deleting a variant changes the product mirror without a stock movement
+CREATE OR REPLACE FUNCTION refresh_product_stock_on_variant_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_product_stock(CASE WHEN TG_OP = 'DELETE' THEN OLD.product_id ELSE NEW.product_id END);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_variant_refresh_product_stock ON product_variants;
+CREATE TRIGGER trigger_variant_refresh_product_stock
+AFTER UPDATE OF is_active OR DELETE ON product_variants
+FOR EACH ROW
+EXECUTE FUNCTION refresh_product_stock_on_variant_change();
+
+COMMENT ON FUNCTION apply_stock_movement(INT, INT, VARCHAR, INT, INT, VARCHAR, TEXT, VARCHAR) IS
+    'Only writer of on-hand stock: appends a stock_ledger entry and refreshes warehouse, variant and product counters';
+
+-- Verify
+SELECT movement_type, COUNT(*), SUM(quantity) FROM stock_ledger GROUP BY movement_type;
@@ -0,0 +1,48 @@
+-- Migration: Stock-take (cycle count) sessions
+-- Date: 2026-10-16
+-- Description: A stock take freezes the expected on-hand quantity of each variant at one warehouse,
+--              collects counted quantities (typed or scanned by barcode), and posts the approved
+--              variances as ADJUSTMENT entries on the stock ledger. Variances are applied as deltas,
+--              so sales made while counting are kept.
+
+CREATE TABLE IF NOT EXISTS stock_takes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(30) UNIQUE,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'COUNTING',
+    notes TEXT,
+    created_by VARCHAR(255) NOT NULL,
+    submitted_at TIMESTAMP,
+    posted_by VARCHAR(255),
+    posted_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_stock_take_status CHECK (status IN ('COUNTING', 'REVIEW', 'POSTED', 'CANCELLED'))
+);
+
+-- Two open counts of the same warehouse would post the same variance twice
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_takes_open_warehouse
+    ON stock_takes(warehouse_id) WHERE status IN ('COUNTING', 'REVIEW');
+
+CREATE TABLE IF NOT EXISTS stock_take_lines (
+    id SERIAL PRIMARY KEY,
+    stock_take_id INT NOT NULL REFERENCES stock_takes(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id),
+    expected_quantity INT NOT NULL,              -- Frozen when the session starts
+    counted_quantity INT CHECK (counted_quantity >= 0),
+    unit_cost DECIMAL(12,2),                     -- cost_per_item when the session starts
+    approved BOOLEAN NOT NULL DEFAULT true,
+    counted_by VARCHAR(255),
+    counted_at TIMESTAMP,
+    ledger_id BIGINT REFERENCES stock_ledger(id),
+    CONSTRAINT uq_stock_take_variant UNIQUE (stock_take_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_take_lines_variant ON stock_take_lines(variant_id);
+
+COMMENT ON COLUMN stock_take_lines.counted_quantity IS 'NULL until counted; uncounted lines are not adjusted';
+COMMENT ON COLUMN stock_take_lines.ledger_id IS 'ADJUSTMENT entry written when the variance was posted';
+
+-- Verify
+SELECT table_name FROM information_schema.tables WHERE table_name IN ('stock_takes', 'stock_take_lines');
@@ -0,0 +1,206 @@
+-- Migration: Gift cards and store credit
+-- Date: 2026-10-16
+-- Description: A per-user store credit balance with a ledger of signed entries. Gift cards are
+--              products; a code is issued per unit once the order is paid and redeemed into
+--              store credit. Checkout can pay with store credit alone or split with Midtrans,
+--              and refunds can be returned as store credit instead of through Midtrans.
+
+-- ============================================
+-- 1. GIFT CARD PRODUCTS
+-- ============================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_gift_card BOOLEAN NOT NULL DEFAULT false;
+
+-- ============================================
+-- 2. STORE CREDIT BALANCE AND LEDGER
+-- ============================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS store_credit_balance DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_store_credit_balance;
+ALTER TABLE users ADD CONSTRAINT chk_users_store_credit_balance CHECK (store_credit_balance >= 0);
+
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,             -- Uppercase, without separators
+    amount DECIMAL(12, 2) NOT NULL,
+    order_id INT REFERENCES orders(id) ON DELETE SET NULL,
+    order_item_id INT REFERENCES order_items(id) ON DELETE SET NULL,
+    purchaser_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    recipient_email VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    redeemed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    redeemed_at TIMESTAMP,
+    delivered_at TIMESTAMP,                       -- NULL until the code is emailed
+    expires_at TIMESTAMP,                         -- NULL = never
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE gift_cards DROP CONSTRAINT IF EXISTS chk_gift_card_status;
+ALTER TABLE gift_cards ADD CONSTRAINT chk_gift_card_status
+    CHECK (status IN ('ACTIVE', 'REDEEMED', 'VOID'));
+
+ALTER TABLE gift_cards DROP CONSTRAINT IF EXISTS chk_gift_card_amount;
+ALTER TABLE gift_cards ADD CONSTRAINT chk_gift_card_amount CHECK (amount > 0);
+
+CREATE INDEX IF NOT EXISTS idx_gift_cards_order_item ON gift_cards(order_item_id);
+CREATE INDEX IF NOT EXISTS idx_gift_cards_undelivered ON gift_cards(created_at) WHERE delivered_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS store_credit_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entry_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,               -- Credit > 0, debit < 0
+    balance_after DECIMAL(12, 2) NOT NULL,
+    order_id INT REFERENCES orders(id) ON DELETE SET NULL,
+    refund_id INT REFERENCES refunds(id) ON DELETE SET NULL,
+    gift_card_id INT REFERENCES gift_cards(id) ON DELETE SET NULL,
+    note TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE store_credit_entries DROP CONSTRAINT IF EXISTS chk_store_credit_entry_type;
+ALTER TABLE store_credit_entries ADD CONSTRAINT chk_store_credit_entry_type
+    CHECK (entry_type IN ('GIFT_CARD', 'ORDER_PAYMENT', 'ORDER_RELEASE', 'REFUND', 'ADJUSTMENT'));
+
+ALTER TABLE store_credit_entries DROP CONSTRAINT IF EXISTS chk_store_credit_entry_amount;
+ALTER TABLE store_credit_entries ADD CONSTRAINT chk_store_credit_entry_amount
+    CHECK (amount <> 0 AND balance_after >= 0
+           AND (entry_type = 'ADJUSTMENT' OR (entry_type = 'ORDER_PAYMENT') = (amount < 0)));
+
+CREATE INDEX IF NOT EXISTS idx_store_credit_entries_user ON store_credit_entries(user_id, created_at DESC);
+
+-- An order, refund or gift card moves the balance once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_store_credit_entries_order
+    ON store_credit_entries(order_id, entry_type) WHERE entry_type IN ('ORDER_PAYMENT', 'ORDER_RELEASE');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_store_credit_entries_refund
+    ON store_credit_entries(refund_id) WHERE entry_type = 'REFUND';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_store_credit_entries_gift_card
+    ON store_credit_entries(gift_card_id) WHERE entry_type = 'GIFT_CARD';
+
+-- ============================================
+-- 3. PAYING AND REFUNDING WITH STORE CREDIT
+-- ============================================
+-- Part of total_amount paid with store credit; Midtrans charges the rest
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS store_credit_amount DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_store_credit_amount;
+ALTER TABLE orders ADD CONSTRAINT chk_orders_store_credit_amount
+    CHECK (store_credit_amount >= 0 AND store_credit_amount <= total_amount);
+
+-- Part of refund_amount returned as store credit; Midtrans refunds the rest
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS refund_destination VARCHAR(20) NOT NULL DEFAULT 'ORIGINAL_PAYMENT';
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS store_credit_amount DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE refunds DROP CONSTRAINT IF EXISTS chk_refunds_destination;
+ALTER TABLE refunds ADD CONSTRAINT chk_refunds_destination
+    CHECK (refund_destination IN ('ORIGINAL_PAYMENT', 'STORE_CREDIT'));
+
+ALTER TABLE refunds DROP CONSTRAINT IF EXISTS chk_refunds_store_credit_amount;
+ALTER TABLE refunds ADD CONSTRAINT chk_refunds_store_credit_amount
+    CHECK (store_credit_amount >= 0 AND store_credit_amount <= refund_amount);
+
+-- ============================================
+-- 4. RELEASE ON CANCEL/EXPIRE/FAIL
+-- ============================================
+-- Store credit spent on an order that is never paid goes back to the customer
+CREATE OR REPLACE FUNCTION release_order_store_credit()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_user_id INT;
+    v_amount DECIMAL(12, 2);
+    v_balance DECIMAL(12, 2);
+BEGIN
+    SELECT user_id, -amount INTO v_user_id, v_amount
+    FROM store_credit_entries
+    WHERE order_id = NEW.id AND entry_type = 'ORDER_PAYMENT';
+
+    IF NOT FOUND OR EXISTS (
+        SELECT 1 FROM store_credit_entries WHERE order_id = NEW.id AND entry_type = 'ORDER_RELEASE'
+    ) THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE users
+    SET store_credit_balance = store_credit_balance + v_amount, updated_at = CURRENT_TIMESTAMP
+    WHERE id = v_user_id
+    RETURNING store_credit_balance INTO v_balance;
+
+    INSERT INTO store_credit_entries (user_id, entry_type, amount, balance_after, order_id, note, created_by)
+    VALUES (v_user_id, 'ORDER_RELEASE', v_amount, v_balance, NEW.id,
+            'Order ' || NEW.order_code || ' ' || lower(NEW.status::text), 'system');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_release_order_store_credit ON orders;
+CREATE TRIGGER trigger_release_order_store_credit
+AFTER UPDATE OF status ON orders
+FOR EACH ROW
+WHEN (NEW.status::text IN ('CANCELLED', 'EXPIRED', 'FAILED', 'KADALUARSA', 'DIBATALKAN')
+      AND OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION release_order_store_credit();
+
+-- ============================================
+-- 5. GIFT CARD EMAIL
+-- ============================================
+INSERT INTO email_templates (template_key, name, subject_template, html_template, is_active) VALUES
+(
+    'GIFT_CARD',
+    'Gift Card',
+    '🎁 Gift card ZAVERA Rp {{.Amount}}',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .card { background: #f9f9f9; padding: 20px; border-radius: 5px; margin: 15px 0; text-align: center; }
+        .amount { font-size: 24px; font-weight: bold; }
+        .code { font-family: monospace; font-size: 20px; letter-spacing: 2px; background: #fff; border: 1px dashed #999; padding: 10px; margin-top: 10px; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+        .btn { display: inline-block; background: #000; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <p>Halo {{.CustomerName}},</p>
+        <p>Terima kasih telah membeli {{.ProductName}} (pesanan {{.OrderCode}}). Berikan kode di bawah ini kepada penerima, atau tukarkan sendiri menjadi saldo ZAVERA.</p>
+
+        <div class="card">
+            <div class="amount">Rp {{.Amount}}</div>
+            <div class="code">{{.Code}}</div>
+            {{if .ExpiresAt}}<p>Berlaku hingga {{.ExpiresAt}}</p>{{end}}
+        </div>
+
+        <p style="text-align: center; margin: 20px 0;">
+            <a href="{{.RedeemURL}}" class="btn">Tukarkan Gift Card</a>
+        </p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+        <p>Jaga kerahasiaan kode ini. Siapa pun yang memiliki kode dapat menukarkannya.</p>
+    </div>
+</body>
+</html>',
+    true
+)
+ON CONFLICT (template_key) DO UPDATE SET
+    name = EXCLUDED.name,
+    subject_template = EXCLUDED.subject_template,
+    html_template = EXCLUDED.html_template,
+    is_active = EXCLUDED.is_active,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Verify
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_name IN ('gift_cards', 'store_credit_entries')
+   OR (table_name = 'users' AND column_name = 'store_credit_balance')
+   OR (table_name = 'orders' AND column_name = 'store_credit_amount')
+   OR (table_name = 'refunds' AND column_name IN ('refund_destination', 'store_credit_amount'));
@@ -0,0 +1,167 @@
+-- Migration: PPN (VAT) on orders
+-- Date: 2026-10-16
+-- Description: PPN is worked out at checkout from the rate in effect when the order is placed.
+--              Rates change by law (11% from April 2022, 12% later), so every change is a new
+--              setting with its own effective date and orders keep the rate they were charged.
+--              Catalog prices either include PPN (the tax is the rate's share of the price) or
+--              exclude it (the tax is added on top). Products can be exempt. Each order line
+--              records its discount and tax so refunds can return the tax that was paid.
+
+-- ============================================
+-- 1. TAX SETTINGS
+-- ============================================
+CREATE TABLE IF NOT EXISTS tax_settings (
+    id SERIAL PRIMARY KEY,
+    rate DECIMAL(5, 2) NOT NULL,                      -- Percent, e.g. 11.00
+    prices_include_tax BOOLEAN NOT NULL DEFAULT true, -- Catalog prices already contain PPN
+    effective_from TIMESTAMP NOT NULL UNIQUE,
+    notes TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE tax_settings DROP CONSTRAINT IF EXISTS chk_tax_settings_rate;
+ALTER TABLE tax_settings ADD CONSTRAINT chk_tax_settings_rate CHECK (rate >= 0 AND rate <= 100);
+
+COMMENT ON TABLE tax_settings IS 'PPN rate and pricing mode; the latest setting whose effective_from has passed applies';
+
+-- Catalog prices have always included PPN, so existing totals do not change
+INSERT INTO tax_settings (rate, prices_include_tax, effective_from, notes, created_by)
+VALUES (11.00, true, '2022-04-01 00:00:00', 'PPN 11% (UU HPP)', 'system')
+ON CONFLICT (effective_from) DO NOTHING;
+
+-- ============================================
+-- 2. EXEMPT PRODUCTS
+-- ============================================
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS tax_exempt BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_products_tax_exempt ON products(id) WHERE tax_exempt = true;
+
+COMMENT ON COLUMN products.tax_exempt IS 'Not subject to PPN';
+
+-- ============================================
+-- 3. TAX ON ORDERS AND ORDER LINES
+-- ============================================
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5, 2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN orders.tax_rate IS 'PPN rate in effect when the order was placed';
+COMMENT ON COLUMN orders.prices_include_tax IS 'orders.tax is contained in the subtotal rather than added to the total';
+
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5, 2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN order_items.discount_amount IS 'Promotion and voucher discount on the line; PPN is on subtotal - discount_amount';
+COMMENT ON COLUMN order_items.tax_rate IS 'PPN rate charged on the line; 0 for exempt products';
+COMMENT ON COLUMN order_items.tax_amount IS 'PPN on the line; the lines add up to orders.tax';
+
+-- ============================================
+-- 4. TAX ON REFUNDS
+-- ============================================
+ALTER TABLE refunds
+ADD COLUMN IF NOT EXISTS tax_refund DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE refund_items
+ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN refunds.tax_refund IS 'PPN contained in refund_amount';
+COMMENT ON COLUMN refund_items.tax_amount IS 'PPN contained in refund_amount';
+
+-- ============================================
+-- 5. PER-LINE TAX IN THE ORDER EMAIL
+-- ============================================
+INSERT INTO email_templates (template_key, name, subject_template, html_template, is_active) VALUES
+(
+    'ORDER_CREATED',
+    'Order Created',
+    '🛍️ Pesanan ZAVERA #{{.OrderCode}} telah dibuat',
+    '<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
+        .header { background: #000; color: #fff; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .order-info { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .preorder-info { background: #FFF8E1; padding: 15px; border-radius: 5px; margin: 15px 0; }
+        .items-table { width: 100%; border-collapse: collapse; margin: 15px 0; }
+        .items-table th, .items-table td { padding: 10px; border-bottom: 1px solid #eee; text-align: left; }
+        .tax-note { color: #777; }
+        .total-row { font-weight: bold; background: #f0f0f0; }
+        .footer { background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
+        .btn { display: inline-block; background: #000; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>ZAVERA</h1>
+    </div>
+    <div class="content">
+        <h2>Pesanan Anda Telah Dibuat!</h2>
+        <p>Halo {{.CustomerName}},</p>
+        <p>Terima kasih telah berbelanja di ZAVERA. Pesanan Anda telah berhasil dibuat.</p>
+
+        <div class="order-info">
+            <strong>Nomor Pesanan:</strong> {{.OrderCode}}<br>
+            <strong>Tanggal:</strong> {{.CreatedAt}}<br>
+            <strong>Status:</strong> Menunggu Pembayaran
+        </div>
+
+        {{if .AwaitingStock}}
+        <div class="preorder-info">
+            Pesanan ini berisi produk pre-order/backorder dan akan dikirim setelah stok tersedia.
+            {{if .ExpectedShipDate}}<br><strong>Perkiraan Pengiriman:</strong> {{.ExpectedShipDate}}{{end}}
+        </div>
+        {{end}}
+
+        <h3>Detail Pesanan</h3>
+        <table class="items-table">
+            <tr><th>Produk</th><th>Qty</th><th>Harga</th></tr>
+            {{range .Items}}
+            <tr><td>{{.ProductName}}{{if .BackorderedQuantity}}<br><small>Pre-order {{.BackorderedQuantity}} pcs{{if .ExpectedShipDate}}, dikirim ± {{.ExpectedShipDate}}{{end}}</small>{{end}}{{if .TaxNote}}<br><small class="tax-note">{{.TaxNote}}</small>{{end}}</td><td>{{.Quantity}}</td><td>Rp {{.Subtotal}}</td></tr>
+            {{end}}
+            <tr><td colspan="2">Subtotal</td><td>Rp {{.Subtotal}}</td></tr>
+            {{if .Discount}}<tr><td colspan="2">Diskon</td><td>- Rp {{.Discount}}</td></tr>{{end}}
+            <tr><td colspan="2">Ongkir ({{.Courier}} - {{.Service}})</td><td>Rp {{.ShippingCost}}</td></tr>
+            {{if .TaxLabel}}<tr><td colspan="2">{{.TaxLabel}}</td><td>Rp {{.Tax}}</td></tr>{{end}}
+            <tr class="total-row"><td colspan="2">Total</td><td>Rp {{.TotalAmount}}</td></tr>
+        </table>
+
+        <h3>Alamat Pengiriman</h3>
+        <p>{{.ShippingAddress}}</p>
+
+        <h3>Instruksi Pembayaran</h3>
+        <p>Silakan selesaikan pembayaran dalam waktu 24 jam untuk memproses pesanan Anda.</p>
+        <p style="text-align: center; margin: 20px 0;">
+            <a href="{{.PaymentURL}}" class="btn">Bayar Sekarang</a>
+        </p>
+    </div>
+    <div class="footer">
+        <p>© 2026 ZAVERA. All rights reserved.</p>
+        <p>Jika ada pertanyaan, hubungi kami di support@zavera.com</p>
+    </div>
+</body>
+</html>',
+    true
+)
+ON CONFLICT (template_key) DO UPDATE SET
+    name = EXCLUDED.name,
+    subject_template = EXCLUDED.subject_template,
+    html_template = EXCLUDED.html_template,
+    is_active = EXCLUDED.is_active,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Verify
+SELECT id, rate, prices_include_tax, effective_from FROM tax_settings ORDER BY effective_from;
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE (table_name = 'products' AND column_name = 'tax_exempt')
+   OR (table_name = 'orders' AND column_name IN ('tax_rate', 'prices_include_tax'))
+   OR (table_name = 'order_items' AND column_name IN ('discount_amount', 'tax_rate', 'tax_amount'))
+   OR (table_name = 'refunds' AND column_name = 'tax_refund')
+   OR (table_name = 'refund_items' AND column_name = 'tax_amount');
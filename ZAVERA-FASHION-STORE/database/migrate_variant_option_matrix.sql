@@ -0,0 +1,42 @@
+-- Migration: Variant option matrix
+-- Date: 2026-10-16
+-- Description: Variants may differ on any option, not only size and color. Options beyond the
+--              size/color/material/pattern/fit/sleeve columns are stored in custom_attributes
+--              under a variant_attributes name (e.g. {"length": "Long"}). Uniqueness moves from
+--              (size, color) to the full option set.
+
+-- ============================================
+-- 1. OPTION KEY
+-- ============================================
+-- Every option in one comparable string; jsonb text has its keys sorted, so equal option sets
+-- give equal keys
+ALTER TABLE product_variants
+ADD COLUMN IF NOT EXISTS option_key TEXT GENERATED ALWAYS AS (
+    LOWER(
+        COALESCE(size, '') || '|' || COALESCE(color, '') || '|' ||
+        COALESCE(material, '') || '|' || COALESCE(pattern, '') || '|' ||
+        COALESCE(fit, '') || '|' || COALESCE(sleeve, '') || '|' ||
+        COALESCE(NULLIF(custom_attributes, 'null'::jsonb), '{}'::jsonb)::text
+    )
+) STORED;
+
+COMMENT ON COLUMN product_variants.option_key IS 'All option values of the variant; unique per product';
+
+-- ============================================
+-- 2. UNIQUENESS ACROSS ALL OPTIONS
+-- ============================================
+-- (size, color) would reject S/Black/Regular next to S/Black/Long
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS unique_variant_combination;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_variant_options ON product_variants(product_id, option_key);
+
+-- ============================================
+-- 3. EXAMPLE EXTRA OPTION
+-- ============================================
+INSERT INTO variant_attributes (name, display_name, type, options, sort_order) VALUES
+('length', 'Length', 'select', '["Cropped", "Regular", "Long"]'::jsonb, 7)
+ON CONFLICT (name) DO NOTHING;
+
+-- Verify
+SELECT indexname, indexdef FROM pg_indexes WHERE tablename = 'product_variants' AND indexname = 'uq_variant_options';
+SELECT name, display_name, sort_order FROM variant_attributes ORDER BY sort_order;
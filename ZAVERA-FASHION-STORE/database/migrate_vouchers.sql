@@ -0,0 +1,114 @@
+-- Migration: Vouchers and promo codes
+-- Date: 2026-10-16
+-- Description: Voucher codes applied at checkout: percentage, fixed amount, free shipping and
+--              buy-X-get-Y, with minimum spend, category/brand scope, usage limits, validity
+--              windows and first-order-only. A redemption is recorded in the same transaction as
+--              the order and released when the order is cancelled, expires or fails.
+
+-- ============================================
+-- 1. VOUCHERS
+-- ============================================
+CREATE TABLE IF NOT EXISTS vouchers (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,                   -- Stored in uppercase
+    name VARCHAR(150) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL,
+    value DECIMAL(12, 2) NOT NULL DEFAULT 0,     -- Percent, rupiah, or percent off the free units (BUY_X_GET_Y)
+    max_discount DECIMAL(12, 2),                 -- Cap for PERCENTAGE and FREE_SHIPPING
+    min_spend DECIMAL(12, 2) NOT NULL DEFAULT 0, -- On the items in scope
+    buy_quantity INT,
+    get_quantity INT,
+    category_id INT REFERENCES categories(id) ON DELETE SET NULL, -- Includes subcategories
+    brand VARCHAR(100),
+    usage_limit INT,                             -- Across all customers; NULL = unlimited
+    per_user_limit INT,                          -- Per account, or per email for guests
+    used_count INT NOT NULL DEFAULT 0,
+    first_order_only BOOLEAN NOT NULL DEFAULT false,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE vouchers DROP CONSTRAINT IF EXISTS chk_voucher_type;
+ALTER TABLE vouchers ADD CONSTRAINT chk_voucher_type
+    CHECK (type IN ('PERCENTAGE', 'FIXED_AMOUNT', 'FREE_SHIPPING', 'BUY_X_GET_Y'));
+
+ALTER TABLE vouchers DROP CONSTRAINT IF EXISTS chk_voucher_values;
+ALTER TABLE vouchers ADD CONSTRAINT chk_voucher_values CHECK (
+    value >= 0 AND min_spend >= 0 AND used_count >= 0
+    AND (type <> 'PERCENTAGE' OR value <= 100)
+    AND (type <> 'BUY_X_GET_Y' OR (buy_quantity > 0 AND get_quantity > 0 AND value <= 100))
+    AND (usage_limit IS NULL OR usage_limit > 0)
+    AND (per_user_limit IS NULL OR per_user_limit > 0)
+    AND (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_vouchers_code ON vouchers(UPPER(code));
+
+COMMENT ON TABLE vouchers IS 'Promo codes applied at checkout';
+COMMENT ON COLUMN vouchers.used_count IS 'Redemptions on orders that were not cancelled, expired or failed';
+
+-- ============================================
+-- 2. REDEMPTIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS voucher_redemptions (
+    id SERIAL PRIMARY KEY,
+    voucher_id INT NOT NULL REFERENCES vouchers(id) ON DELETE RESTRICT,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    customer_email VARCHAR(255) NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    discount_amount DECIMAL(12, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'APPLIED',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP,
+    CONSTRAINT uq_voucher_redemption_order UNIQUE (order_id)
+);
+
+ALTER TABLE voucher_redemptions DROP CONSTRAINT IF EXISTS chk_voucher_redemption_status;
+ALTER TABLE voucher_redemptions ADD CONSTRAINT chk_voucher_redemption_status
+    CHECK (status IN ('APPLIED', 'RELEASED'));
+
+CREATE INDEX IF NOT EXISTS idx_voucher_redemptions_voucher ON voucher_redemptions(voucher_id, status);
+CREATE INDEX IF NOT EXISTS idx_voucher_redemptions_user ON voucher_redemptions(voucher_id, user_id) WHERE status = 'APPLIED';
+CREATE INDEX IF NOT EXISTS idx_voucher_redemptions_email ON voucher_redemptions(voucher_id, LOWER(customer_email)) WHERE status = 'APPLIED';
+
+-- ============================================
+-- 3. RELEASE ON CANCEL / EXPIRE
+-- ============================================
+-- Every cancel and expiry path updates orders.status, so the release lives here rather than in
+-- each of them
+CREATE OR REPLACE FUNCTION release_order_voucher()
+RETURNS TRIGGER AS $$
+BEGIN
+    WITH released AS (
+        UPDATE voucher_redemptions
+        SET status = 'RELEASED', released_at = CURRENT_TIMESTAMP
+        WHERE order_id = NEW.id AND status = 'APPLIED'
+        RETURNING voucher_id
+    )
+    UPDATE vouchers v
+    SET used_count = GREATEST(v.used_count - 1, 0), updated_at = CURRENT_TIMESTAMP
+    FROM released r
+    WHERE v.id = r.voucher_id;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_release_order_voucher ON orders;
+CREATE TRIGGER trigger_release_order_voucher
+AFTER UPDATE OF status ON orders
+FOR EACH ROW
+WHEN (NEW.status::text IN ('CANCELLED', 'EXPIRED', 'FAILED', 'KADALUARSA', 'DIBATALKAN')
+      AND OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION release_order_voucher();
+
+-- Verify
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_name IN ('vouchers', 'voucher_redemptions');
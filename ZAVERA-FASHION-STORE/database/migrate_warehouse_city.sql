@@ -0,0 +1,14 @@
+-- Migration: Warehouse city ID
+-- Date: 2026-10-16
+-- Description: The legacy city ID recorded as the origin of shipments and shipping snapshots,
+--              which now comes from the fulfilling warehouse instead of a hard-coded Semarang.
+
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS city_id VARCHAR(20);
+
+COMMENT ON COLUMN warehouses.city_id IS 'Legacy city ID stored as shipments.origin_city_id';
+
+-- The original Semarang origin
+UPDATE warehouses SET city_id = '399' WHERE code = 'SMG' AND city_id IS NULL;
+
+-- Verify
+SELECT code, city_name, city_id, postal_code FROM warehouses ORDER BY priority, code;
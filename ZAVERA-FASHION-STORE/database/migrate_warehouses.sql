@@ -0,0 +1,241 @@
+-- Migration: Multi-warehouse inventory
+-- Date: 2026-10-16
+-- Description: Warehouses as entities, stock per variant per warehouse, reservations that pick a
+--              warehouse, and the fulfilling warehouse on orders (chosen at checkout: nearest
+--              warehouse to the destination that holds every line in full).
+--              product_variants.stock_quantity stays as the total across warehouses, kept in sync
+--              by triggers so existing readers keep working.
+
+CREATE TABLE IF NOT EXISTS warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,           -- Short code, e.g. SMG, SBY
+    name VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL,
+    city_name VARCHAR(100) NOT NULL,
+    postal_code VARCHAR(10) NOT NULL,           -- Biteship origin_postal_code
+    area_id VARCHAR(100),                       -- Biteship area ID, e.g. IDNP10IDNC393IDND4700
+    contact_name VARCHAR(100) NOT NULL,
+    contact_phone VARCHAR(30) NOT NULL,
+    priority INT NOT NULL DEFAULT 0,            -- Tie-break when two warehouses are equally near (lower first)
+    is_default BOOLEAN NOT NULL DEFAULT false,  -- Receives stock from legacy single-number stock updates
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_single_default ON warehouses(is_default) WHERE is_default = true;
+
+COMMENT ON TABLE warehouses IS 'Stock locations; orders ship from the warehouse chosen at checkout';
+
+-- Existing origin (previously hard-coded in ShippingService) becomes the default warehouse
+INSERT INTO warehouses (code, name, address, city_name, postal_code, area_id, contact_name, contact_phone, is_default)
+VALUES ('SMG', 'Semarang', 'Jl. Pedurungan Tengah, Pedurungan, Semarang', 'Kota Semarang', '50113',
+        'IDNP10IDNC393IDND4700', 'ZAVERA Fashion Store', '081234567890', true)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS warehouse_stock (
+    warehouse_id INT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (warehouse_id, variant_id),
+    CONSTRAINT chk_warehouse_stock_quantity CHECK (quantity >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_stock_variant ON warehouse_stock(variant_id);
+
+COMMENT ON TABLE warehouse_stock IS 'On-hand stock per variant per warehouse; product_variants.stock_quantity is the sum';
+
+-- All current stock sits in the default warehouse
+INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+SELECT w.id, pv.id, pv.stock_quantity
+FROM product_variants pv
+CROSS JOIN warehouses w
+WHERE w.is_default = true
+ON CONFLICT (warehouse_id, variant_id) DO NOTHING;
+
+-- Keep product_variants.stock_quantity equal to the sum over warehouses.
+-- Each trigger only acts on direct writes (depth 1) so the two never loop.
+CREATE OR REPLACE FUNCTION sync_variant_stock_from_warehouses()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_variant_id INT;
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NULL;
+    END IF;
+
+    v_variant_id := CASE WHEN TG_OP = 'DELETE' THEN OLD.variant_id ELSE NEW.variant_id END;
+
+    UPDATE product_variants
+    SET stock_quantity = (SELECT COALESCE(SUM(quantity), 0) FROM warehouse_stock WHERE variant_id = v_variant_id),
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = v_variant_id;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_warehouse_stock_sync_variant ON warehouse_stock;
+CREATE TRIGGER trigger_warehouse_stock_sync_variant
+AFTER INSERT OR UPDATE OF quantity OR DELETE ON warehouse_stock
+FOR EACH ROW
+EXECUTE FUNCTION sync_variant_stock_from_warehouses();
+
+-- Legacy writers (variant stock endpoints, import, complete_reservation) still set stock_quantity;
+-- apply their change to the default warehouse
+CREATE OR REPLACE FUNCTION apply_variant_stock_to_default_warehouse()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_delta INT;
+    v_default_id INT;
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NULL;
+    END IF;
+
+    v_delta := NEW.stock_quantity - CASE WHEN TG_OP = 'INSERT' THEN 0 ELSE OLD.stock_quantity END;
+    IF v_delta = 0 AND TG_OP = 'UPDATE' THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT id INTO v_default_id FROM warehouses WHERE is_default = true;
+    IF v_default_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+    VALUES (v_default_id, NEW.id, v_delta)
+    ON CONFLICT (warehouse_id, variant_id) DO UPDATE
+    SET quantity = warehouse_stock.quantity + v_delta, updated_at = CURRENT_TIMESTAMP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_variant_stock_to_default_warehouse ON product_variants;
+CREATE TRIGGER trigger_variant_stock_to_default_warehouse
+AFTER INSERT OR UPDATE OF stock_quantity ON product_variants
+FOR EACH ROW
+EXECUTE FUNCTION apply_variant_stock_to_default_warehouse();
+
+-- Fulfilling warehouse on orders (NULL for orders placed before this migration)
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS warehouse_id INT REFERENCES warehouses(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_warehouse ON orders(warehouse_id) WHERE warehouse_id IS NOT NULL;
+
+COMMENT ON COLUMN orders.warehouse_id IS 'Warehouse the order ships from; its stock was deducted at checkout';
+
+-- Reservations hold stock at one warehouse
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS warehouse_id INT REFERENCES warehouses(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reservations_warehouse ON stock_reservations(warehouse_id, variant_id, status);
+
+-- Available stock for a variant at one warehouse (on hand minus active reservations there)
+CREATE OR REPLACE FUNCTION get_warehouse_available_stock(p_warehouse_id INT, p_variant_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_stock INT;
+    v_reserved INT;
+BEGIN
+    SELECT COALESCE(quantity, 0) INTO v_stock
+    FROM warehouse_stock
+    WHERE warehouse_id = p_warehouse_id AND variant_id = p_variant_id;
+
+    SELECT COALESCE(SUM(quantity), 0) INTO v_reserved
+    FROM stock_reservations
+    WHERE warehouse_id = p_warehouse_id
+    AND variant_id = p_variant_id
+    AND status = 'active'
+    AND expires_at > CURRENT_TIMESTAMP;
+
+    RETURN GREATEST(COALESCE(v_stock, 0) - v_reserved, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+-- reserve_stock gains a warehouse; without one, the active warehouse with the most available stock is used
+DROP FUNCTION IF EXISTS reserve_stock(INT, INT, VARCHAR, INT, INT);
+
+CREATE OR REPLACE FUNCTION reserve_stock(
+    p_variant_id INT,
+    p_customer_id INT,
+    p_session_id VARCHAR,
+    p_quantity INT,
+    p_timeout_minutes INT DEFAULT 15,
+    p_warehouse_id INT DEFAULT NULL
+)
+RETURNS INT AS $$
+DECLARE
+    v_warehouse_id INT := p_warehouse_id;
+    v_available INT;
+    v_reservation_id INT;
+BEGIN
+    -- Clean expired reservations first
+    PERFORM clean_expired_reservations();
+
+    IF v_warehouse_id IS NULL THEN
+        SELECT w.id INTO v_warehouse_id
+        FROM warehouses w
+        WHERE w.is_active = true
+        ORDER BY get_warehouse_available_stock(w.id, p_variant_id) DESC, w.is_default DESC, w.priority, w.id
+        LIMIT 1;
+    END IF;
+
+    -- Check available stock at the warehouse
+    v_available := get_warehouse_available_stock(v_warehouse_id, p_variant_id);
+
+    IF v_available < p_quantity THEN
+        RAISE EXCEPTION 'Insufficient stock. Available: %, Requested: %', v_available, p_quantity;
+    END IF;
+
+    -- Create reservation
+    INSERT INTO stock_reservations (
+        variant_id, customer_id, session_id, quantity, expires_at, warehouse_id
+    ) VALUES (
+        p_variant_id, p_customer_id, p_session_id, p_quantity,
+        CURRENT_TIMESTAMP + (p_timeout_minutes || ' minutes')::INTERVAL, v_warehouse_id
+    ) RETURNING id INTO v_reservation_id;
+
+    RETURN v_reservation_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- complete_reservation deducts from the reserved warehouse (the trigger updates the variant total)
+CREATE OR REPLACE FUNCTION complete_reservation(p_reservation_id INT, p_order_id INT)
+RETURNS void AS $$
+DECLARE
+    v_variant_id INT;
+    v_quantity INT;
+    v_warehouse_id INT;
+BEGIN
+    SELECT variant_id, quantity, warehouse_id INTO v_variant_id, v_quantity, v_warehouse_id
+    FROM stock_reservations
+    WHERE id = p_reservation_id AND status = 'active';
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Reservation not found or already processed';
+    END IF;
+
+    IF v_warehouse_id IS NULL THEN
+        -- Reservation made before warehouses existed
+        UPDATE product_variants
+        SET stock_quantity = stock_quantity - v_quantity,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = v_variant_id;
+    ELSE
+        UPDATE warehouse_stock
+        SET quantity = quantity - v_quantity,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE warehouse_id = v_warehouse_id AND variant_id = v_variant_id;
+    END IF;
+
+    -- Mark reservation as completed
+    UPDATE stock_reservations
+    SET status = 'completed', order_id = p_order_id
+    WHERE id = p_reservation_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Verify
+SELECT table_name FROM information_schema.tables
+WHERE table_name IN ('warehouses', 'warehouse_stock');
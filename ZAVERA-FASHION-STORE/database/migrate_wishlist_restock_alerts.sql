@@ -0,0 +1,17 @@
+-- Migration: Pending wishlist back-in-stock alerts
+-- Date: 2026-10-16
+-- Description: A wishlist's back-in-stock alert is queued when the variant availability check
+--              sees a variant come back, and removed once the email is sent. Alerts held back by
+--              the per-user rate limit or a failed send go out on a later run instead of being lost.
+
+CREATE TABLE IF NOT EXISTS wishlist_restock_alerts (
+    id SERIAL PRIMARY KEY,
+    wishlist_id INT NOT NULL UNIQUE REFERENCES wishlists(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE wishlist_restock_alerts IS 'Back-in-stock emails owed to wishlists; one per wishlist, for the variant restocked last';
+
+-- Verify
+SELECT table_name FROM information_schema.tables WHERE table_name = 'wishlist_restock_alerts';